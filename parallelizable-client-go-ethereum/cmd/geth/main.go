@@ -76,6 +76,17 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolParallelReadOnlyFlag,
+		utils.TxPoolParallelStrictTagsFlag,
+		utils.TxPoolParallelPendingLifetimeFlag,
+		utils.TxPoolParallelFutureSlotsFlag,
+		utils.TxPoolParallelGapTimeoutFlag,
+		utils.TxPoolParallelMaxTxSizeFlag,
+		utils.TxPoolParallelSignaturesFlag,
+		utils.TxPoolParallelAdminFlag,
+		utils.TxPoolParallelQuarantinePanicsFlag,
+		utils.TxPoolParallelModeFlag,
+		utils.TxPoolParallelLocalsFlag,
 		utils.BlobPoolDataDirFlag,
 		utils.BlobPoolDataCapFlag,
 		utils.BlobPoolPriceBumpFlag,
@@ -120,6 +131,8 @@ var (
 		utils.MinerExtraDataFlag,
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerPendingFeeRecipientFlag,
+		utils.MinerParallelAuditRateFlag,
+		utils.MinerParallelSequentialTailFlag,
 		utils.MinerNewPayloadTimeoutFlag, // deprecated
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
@@ -389,6 +389,61 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolParallelReadOnlyFlag = &cli.BoolFlag{
+		Name:     "txpool.parallel.readonly",
+		Usage:    "Accept, batch and relay parallel transactions without executing batches (non-mining RPC nodes)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelStrictTagsFlag = &cli.Uint64Flag{
+		Name:     "txpool.parallel.stricttags",
+		Usage:    "Timestamp from which parallel transactions tagged by a calldata prefix are rejected",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelPendingLifetimeFlag = &cli.DurationFlag{
+		Name:     "txpool.parallel.pendinglifetime",
+		Usage:    "Maximum amount of time executable parallel transactions are kept (at least txpool.lifetime)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelFutureSlotsFlag = &cli.Uint64Flag{
+		Name:     "txpool.parallel.futureslots",
+		Usage:    "Maximum number of parallel transactions per account waiting behind a nonce gap (default 32)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelGapTimeoutFlag = &cli.DurationFlag{
+		Name:     "txpool.parallel.gaptimeout",
+		Usage:    "Maximum amount of time batched parallel transactions wait behind a nonce gap before moving to the queue (default 10m)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelMaxTxSizeFlag = &cli.Uint64Flag{
+		Name:     "txpool.parallel.maxtxsize",
+		Usage:    "Maximum size in bytes of a batched parallel transaction, larger ones are executed sequentially (default 131072)",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelSignaturesFlag = &flags.DirectoryFlag{
+		Name:     "txpool.parallel.signatures",
+		Usage:    "4-byte signature database (JSON) naming methods for the parallel calldata classifier",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelAdminFlag = &cli.BoolFlag{
+		Name:     "txpool.parallel.admin",
+		Usage:    "Enable the parallel_exportPool and parallel_importPool RPC methods, accessing files on the node",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelQuarantinePanicsFlag = &cli.BoolFlag{
+		Name:     "txpool.parallel.quarantinepanics",
+		Usage:    "Quarantine parallel transactions whose execution panicked, persisting them for bug reports",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelModeFlag = &cli.StringFlag{
+		Name:     "txpool.parallel.mode",
+		Usage:    "Run the parallel pool as a native subpool (\"subpool\", default) or standalone like the legacy pool (\"legacy\")",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolParallelLocalsFlag = &cli.StringFlag{
+		Name:     "txpool.parallel.locals",
+		Usage:    "Comma separated accounts whose parallel transactions are exempt from eviction and lifetime expiry",
+		Category: flags.TxPoolCategory,
+	}
 	// Blob transaction pool settings
 	BlobPoolDataDirFlag = &cli.StringFlag{
 		Name:     "blobpool.datadir",
@@ -496,6 +551,17 @@ var (
 		Usage:    "0x prefixed public address for the pending block producer (not used for actual block production)",
 		Category: flags.MinerCategory,
 	}
+	MinerParallelAuditRateFlag = &cli.Float64Flag{
+		Name:     "miner.parallel.auditrate",
+		Usage:    "Fraction of the blocks built out of parallel batches re-executed sequentially in the background to audit their determinism (0 = disabled)",
+		Category: flags.MinerCategory,
+	}
+	MinerParallelSequentialTailFlag = &cli.Uint64Flag{
+		Name:     "miner.parallel.sequentialtail",
+		Usage:    "Percentage of the block gas withheld from parallel batches for pending ordinary transactions (0 = disabled)",
+		Value:    ethconfig.Defaults.Miner.ParallelSequentialTail,
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	PasswordFileFlag = &cli.PathFlag{
@@ -1498,6 +1564,20 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 		log.Warn("The flag --miner.newpayload-timeout is deprecated and will be removed, please use --miner.recommit")
 		cfg.Recommit = ctx.Duration(MinerNewPayloadTimeoutFlag.Name)
 	}
+	if ctx.IsSet(MinerParallelAuditRateFlag.Name) {
+		rate := ctx.Float64(MinerParallelAuditRateFlag.Name)
+		if rate < 0 || rate > 1 {
+			Fatalf("Invalid --%s: %v, must be between 0 and 1", MinerParallelAuditRateFlag.Name, rate)
+		}
+		cfg.ParallelAuditRate = rate
+	}
+	if ctx.IsSet(MinerParallelSequentialTailFlag.Name) {
+		tail := ctx.Uint64(MinerParallelSequentialTailFlag.Name)
+		if tail > 100 {
+			Fatalf("Invalid --%s: %d, must be between 0 and 100", MinerParallelSequentialTailFlag.Name, tail)
+		}
+		cfg.ParallelSequentialTail = tail
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1580,6 +1660,46 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	setGPO(ctx, &cfg.GPO)
 	setTxPool(ctx, &cfg.TxPool)
 	setBlobPool(ctx, &cfg.BlobPool)
+	if ctx.IsSet(TxPoolParallelReadOnlyFlag.Name) {
+		cfg.ParallelReadOnly = ctx.Bool(TxPoolParallelReadOnlyFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelStrictTagsFlag.Name) {
+		v := ctx.Uint64(TxPoolParallelStrictTagsFlag.Name)
+		cfg.ParallelStrictTagsTime = &v
+	}
+	if ctx.IsSet(TxPoolParallelPendingLifetimeFlag.Name) {
+		cfg.ParallelPendingLifetime = ctx.Duration(TxPoolParallelPendingLifetimeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelFutureSlotsFlag.Name) {
+		cfg.ParallelFutureSlots = ctx.Uint64(TxPoolParallelFutureSlotsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelGapTimeoutFlag.Name) {
+		cfg.ParallelGapTimeout = ctx.Duration(TxPoolParallelGapTimeoutFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelMaxTxSizeFlag.Name) {
+		cfg.ParallelMaxTxSize = ctx.Uint64(TxPoolParallelMaxTxSizeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelSignaturesFlag.Name) {
+		cfg.ParallelSignatureDB = ctx.String(TxPoolParallelSignaturesFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelAdminFlag.Name) {
+		cfg.ParallelAdminAPI = ctx.Bool(TxPoolParallelAdminFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelQuarantinePanicsFlag.Name) {
+		cfg.ParallelQuarantinePanics = ctx.Bool(TxPoolParallelQuarantinePanicsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelModeFlag.Name) {
+		cfg.ParallelMode = ctx.String(TxPoolParallelModeFlag.Name)
+	}
+	if ctx.IsSet(TxPoolParallelLocalsFlag.Name) {
+		for _, account := range strings.Split(ctx.String(TxPoolParallelLocalsFlag.Name), ",") {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --txpool.parallel.locals: %s", trimmed)
+			} else {
+				cfg.ParallelLocals = append(cfg.ParallelLocals, common.HexToAddress(trimmed))
+			}
+		}
+	}
 	setMiner(ctx, &cfg.Miner)
 	setRequiredBlocks(ctx, cfg)
 	setLes(ctx, cfg)
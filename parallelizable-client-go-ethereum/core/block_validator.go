@@ -92,6 +92,11 @@ func (v *BlockValidator) ValidateBody(block *types.Block) error {
 			return fmt.Errorf("unexpected blob sidecar in transaction at index %d", i)
 		}
 
+		// Parallel transactions may only be present once they activated.
+		if tx.Type() == types.ParallelTxType && !v.config.IsParallelTx(header.Time) {
+			return fmt.Errorf("parallel transaction at index %d before activation", i)
+		}
+
 		// The individual checks for blob validity (version-check + not empty)
 		// happens in state transition.
 	}
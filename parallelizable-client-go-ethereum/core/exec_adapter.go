@@ -0,0 +1,150 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip4844"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// ExecAdapter wraps the construction of the EVM environment needed to execute
+// transactions outside of regular block processing, such as speculative runs in
+// the transaction pool or batch execution in the miner. It derives the basefee
+// and fork rules from the header it executes on, so callers never need to
+// reach into the StateDB for block level parameters.
+type ExecAdapter struct {
+	config *params.ChainConfig
+	chain  ChainContext
+	header *types.Header
+	author *common.Address
+	signer types.Signer
+}
+
+// NewExecAdapter creates an execution adapter running transactions in the
+// context of the given header. If author is nil, the coinbase is resolved from
+// the header through the consensus engine.
+func NewExecAdapter(config *params.ChainConfig, chain ChainContext, header *types.Header, author *common.Address) *ExecAdapter {
+	return &ExecAdapter{
+		config: config,
+		chain:  chain,
+		header: header,
+		author: author,
+		signer: types.MakeSigner(config, header.Number, header.Time),
+	}
+}
+
+// NewPendingExecAdapter creates an execution adapter running transactions in the
+// context of a block built on top of the given parent, with the basefee derived
+// from the parent according to EIP-1559.
+func NewPendingExecAdapter(config *params.ChainConfig, chain ChainContext, parent *types.Header) *ExecAdapter {
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		Time:       parent.Time + 1,
+		Difficulty: new(big.Int),
+	}
+	if config.IsLondon(header.Number) {
+		header.BaseFee = eip1559.CalcBaseFee(config, parent)
+	}
+	// Blob transactions are priced against the excess blob gas of the block
+	if config.IsCancun(header.Number, header.Time) {
+		var excessBlobGas uint64
+		if config.IsCancun(parent.Number, parent.Time) {
+			excessBlobGas = eip4844.CalcExcessBlobGas(config, parent, header.Time)
+		}
+		header.ExcessBlobGas = &excessBlobGas
+	}
+	return NewExecAdapter(config, chain, header, &common.Address{})
+}
+
+// Header returns the header transactions are executed in the context of.
+func (a *ExecAdapter) Header() *types.Header {
+	return a.header
+}
+
+// BaseFee returns the basefee transactions are executed with, or nil before
+// the London fork.
+func (a *ExecAdapter) BaseFee() *big.Int {
+	if a.header.BaseFee == nil {
+		return nil
+	}
+	return new(big.Int).Set(a.header.BaseFee)
+}
+
+// Signer returns the transaction signer matching the execution context.
+func (a *ExecAdapter) Signer() types.Signer {
+	return a.signer
+}
+
+// Rules returns the fork rules active in the execution context.
+func (a *ExecAdapter) Rules() params.Rules {
+	return a.config.Rules(a.header.Number, a.header.Difficulty.Sign() == 0, a.header.Time)
+}
+
+// Message converts a transaction into a message executable in this context.
+func (a *ExecAdapter) Message(tx *types.Transaction) (*Message, error) {
+	return TransactionToMessage(tx, a.signer, a.header.BaseFee)
+}
+
+// NewEVM creates an EVM operating on the given state in this context, with an
+// optional tracer attached. The state is hooked to the tracer, so that its
+// state change hooks fire as during block processing.
+func (a *ExecAdapter) NewEVM(statedb *state.StateDB, tracer *tracing.Hooks) *vm.EVM {
+	var (
+		blockCtx            = NewEVMBlockContext(a.header, a.chain, a.author)
+		evmState vm.StateDB = statedb
+	)
+	if tracer != nil {
+		evmState = state.NewHookedState(statedb, tracer)
+	}
+	return vm.NewEVM(blockCtx, evmState, a.config, vm.Config{Tracer: tracer})
+}
+
+// Apply executes a transaction on top of the given state as the index-th
+// transaction of the block. The state is prepared with the transaction context
+// so that logs and access lists are attributed correctly; the state transition
+// itself takes care of the EIP-2929 access list preparation.
+func (a *ExecAdapter) Apply(statedb *state.StateDB, tx *types.Transaction, index int, gp *GasPool, tracer *tracing.Hooks) (*ExecutionResult, error) {
+	msg, err := a.Message(tx)
+	if err != nil {
+		return nil, err
+	}
+	statedb.SetTxContext(tx.Hash(), index)
+	return ApplyMessage(a.NewEVM(statedb, tracer), msg, gp)
+}
+
+// ApplyView executes a transaction like Apply, but with the EVM operating on a
+// view of the state instead of the state itself, allowing callers to intercept
+// the state accesses of the execution. Preparing the transaction context of the
+// state backing the view is left to the caller.
+func (a *ExecAdapter) ApplyView(view vm.StateDB, tx *types.Transaction, gp *GasPool) (*ExecutionResult, error) {
+	msg, err := a.Message(tx)
+	if err != nil {
+		return nil, err
+	}
+	blockCtx := NewEVMBlockContext(a.header, a.chain, a.author)
+	return ApplyMessage(vm.NewEVM(blockCtx, view, a.config, vm.Config{}), msg, gp)
+}
@@ -0,0 +1,87 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/holiman/uint256"
+)
+
+// adapterTestChain is a minimal chain context without any ancestry.
+type adapterTestChain struct{}
+
+func (adapterTestChain) Engine() consensus.Engine                    { return ethash.NewFaker() }
+func (adapterTestChain) GetHeader(common.Hash, uint64) *types.Header { return nil }
+func (adapterTestChain) Config() *params.ChainConfig                 { return params.TestChainConfig }
+
+// Tests that the pending execution adapter derives the basefee from the parent
+// header and executes transactions against the supplied state.
+func TestPendingExecAdapter(t *testing.T) {
+	var (
+		key, _ = crypto.GenerateKey()
+		from   = crypto.PubkeyToAddress(key.PublicKey)
+		to     = common.Address{0xaa}
+		config = params.TestChainConfig
+		parent = &types.Header{
+			Number:     big.NewInt(1),
+			GasLimit:   30_000_000,
+			GasUsed:    30_000_000,
+			BaseFee:    big.NewInt(params.InitialBaseFee),
+			Difficulty: new(big.Int),
+		}
+	)
+	adapter := NewPendingExecAdapter(config, adapterTestChain{}, parent)
+	if want := eip1559.CalcBaseFee(config, parent); adapter.BaseFee().Cmp(want) != 0 {
+		t.Fatalf("basefee mismatch: have %v, want %v", adapter.BaseFee(), want)
+	}
+	if adapter.Header().Number.Uint64() != 2 {
+		t.Fatalf("pending number mismatch: have %v, want 2", adapter.Header().Number)
+	}
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	statedb.SetBalance(from, uint256.NewInt(params.Ether), tracing.BalanceChangeUnspecified)
+
+	tx := types.MustSignNewTx(key, adapter.Signer(), &types.DynamicFeeTx{
+		ChainID:   config.ChainID,
+		Nonce:     0,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: new(big.Int).Mul(adapter.BaseFee(), big.NewInt(2)),
+		Gas:       params.TxGas,
+		To:        &to,
+		Value:     big.NewInt(1),
+	})
+	res, err := adapter.Apply(statedb, tx, 0, new(GasPool).AddGas(tx.Gas()), nil)
+	if err != nil {
+		t.Fatalf("failed to apply transaction: %v", err)
+	}
+	if res.UsedGas != params.TxGas {
+		t.Errorf("gas used mismatch: have %d, want %d", res.UsedGas, params.TxGas)
+	}
+	if balance := statedb.GetBalance(to); balance.Uint64() != 1 {
+		t.Errorf("recipient balance mismatch: have %v, want 1", balance)
+	}
+}
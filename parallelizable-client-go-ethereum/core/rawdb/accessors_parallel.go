@@ -0,0 +1,68 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReadParallelSummary retrieves the encoded parallel execution summary recorded
+// by the local miner for the block with the given number.
+func ReadParallelSummary(db ethdb.KeyValueReader, number uint64) []byte {
+	data, _ := db.Get(parallelSummaryKey(number))
+	return data
+}
+
+// WriteParallelSummary stores the encoded parallel execution summary of the
+// block with the given number.
+func WriteParallelSummary(db ethdb.KeyValueWriter, number uint64, summary []byte) {
+	if err := db.Put(parallelSummaryKey(number), summary); err != nil {
+		log.Crit("Failed to store parallel block summary", "err", err)
+	}
+}
+
+// DeleteParallelSummary removes the parallel execution summary of the block
+// with the given number.
+func DeleteParallelSummary(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Delete(parallelSummaryKey(number)); err != nil {
+		log.Crit("Failed to delete parallel block summary", "err", err)
+	}
+}
+
+// ReadParallelOrdering retrieves the encoded batch ordering log signed by the
+// local sequencer for the block with the given number.
+func ReadParallelOrdering(db ethdb.KeyValueReader, number uint64) []byte {
+	data, _ := db.Get(parallelOrderingKey(number))
+	return data
+}
+
+// WriteParallelOrdering stores the encoded batch ordering log of the block with
+// the given number.
+func WriteParallelOrdering(db ethdb.KeyValueWriter, number uint64, ordering []byte) {
+	if err := db.Put(parallelOrderingKey(number), ordering); err != nil {
+		log.Crit("Failed to store parallel ordering log", "err", err)
+	}
+}
+
+// DeleteParallelOrdering removes the batch ordering log of the block with the
+// given number.
+func DeleteParallelOrdering(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Delete(parallelOrderingKey(number)); err != nil {
+		log.Crit("Failed to delete parallel ordering log", "err", err)
+	}
+}
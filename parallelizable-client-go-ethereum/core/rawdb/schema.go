@@ -141,6 +141,9 @@ var (
 
 	CliqueSnapshotPrefix = []byte("clique-")
 
+	ParallelSummaryPrefix  = []byte("parallel-summary-")  // ParallelSummaryPrefix + num (uint64 big endian) -> parallel execution summary
+	ParallelOrderingPrefix = []byte("parallel-ordering-") // ParallelOrderingPrefix + num (uint64 big endian) -> signed batch ordering log
+
 	BestUpdateKey         = []byte("update-")    // bigEndian64(syncPeriod) -> RLP(types.LightClientUpdate)  (nextCommittee only referenced by root hash)
 	FixedCommitteeRootKey = []byte("fixedRoot-") // bigEndian64(syncPeriod) -> committee root hash
 	SyncCommitteeKey      = []byte("committee-") // bigEndian64(syncPeriod) -> serialized committee
@@ -267,6 +270,16 @@ func stateIDKey(root common.Hash) []byte {
 	return append(stateIDPrefix, root.Bytes()...)
 }
 
+// parallelSummaryKey = ParallelSummaryPrefix + num (uint64 big endian)
+func parallelSummaryKey(number uint64) []byte {
+	return append(ParallelSummaryPrefix, encodeBlockNumber(number)...)
+}
+
+// parallelOrderingKey = ParallelOrderingPrefix + num (uint64 big endian)
+func parallelOrderingKey(number uint64) []byte {
+	return append(ParallelOrderingPrefix, encodeBlockNumber(number)...)
+}
+
 // accountTrieNodeKey = TrieNodeAccountPrefix + nodePath.
 func accountTrieNodeKey(path []byte) []byte {
 	return append(TrieNodeAccountPrefix, path...)
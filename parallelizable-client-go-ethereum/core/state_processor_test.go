@@ -18,8 +18,10 @@ package core
 
 import (
 	"crypto/ecdsa"
+	"errors"
 	"math"
 	"math/big"
+	"strings"
 	"testing"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -357,6 +359,44 @@ func TestStateProcessorErrors(t *testing.T) {
 	}
 }
 
+// Tests that parallel transactions are rejected in blocks, and by the state
+// transition, before they are scheduled to activate.
+func TestParallelTxActivation(t *testing.T) {
+	var (
+		key, _  = crypto.GenerateKey()
+		from    = crypto.PubkeyToAddress(key.PublicKey)
+		config  = *params.MergedTestChainConfig
+		gspec   = &Genesis{Config: &config, Alloc: types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}}}
+		engine  = beacon.New(ethash.NewFaker())
+		genesis = gspec.ToBlock()
+	)
+	config.ParallelTxTime = u64(genesis.Time() + 100)
+
+	tx := types.MustSignNewTx(key, types.LatestSigner(&config), &types.ParallelTx{
+		ChainID:   config.ChainID,
+		Gas:       params.TxGas,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(params.InitialBaseFee),
+		To:        &common.Address{0xaa},
+		Value:     big.NewInt(0),
+	})
+	blockchain, _ := NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, engine, vm.Config{}, nil)
+	defer blockchain.Stop()
+
+	block := GenerateBadBlock(genesis, engine, types.Transactions{tx}, gspec.Config, false)
+	_, err := blockchain.InsertChain(types.Blocks{block})
+	if want := "parallel transaction at index 0 before activation"; err == nil || !strings.Contains(err.Error(), want) {
+		t.Fatalf("pre-activation block error mismatch: have %v, want %q", err, want)
+	}
+	// Messages of parallel transactions are refused by the state transition as well
+	statedb, _ := blockchain.State()
+	msg := &Message{From: from, To: &common.Address{0xaa}, Value: new(big.Int), GasLimit: params.TxGas, GasPrice: new(big.Int), GasFeeCap: new(big.Int), GasTipCap: new(big.Int), Parallel: true, SkipNonceChecks: true}
+	evm := vm.NewEVM(NewEVMBlockContext(block.Header(), blockchain, nil), statedb, &config, vm.Config{NoBaseFee: true})
+	if _, err := ApplyMessage(evm, msg, new(GasPool).AddGas(params.TxGas)); !errors.Is(err, ErrTxTypeNotSupported) {
+		t.Fatalf("pre-activation message error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
 // GenerateBadBlock constructs a "block" which contains the transactions. The transactions are not expected to be
 // valid, and no proper post-state can be made. But from the perspective of the blockchain, the block is sufficiently
 // valid to be considered for import:
@@ -157,6 +157,10 @@ type Message struct {
 	BlobHashes            []common.Hash
 	SetCodeAuthorizations []types.SetCodeAuthorization
 
+	// Parallel marks messages of parallel transactions, which are only valid
+	// once parallel transactions activated.
+	Parallel bool
+
 	// When SkipNonceChecks is true, the message nonce is not checked against the
 	// account nonce in state.
 	// This field will be set to true for operations like RPC eth_call.
@@ -179,6 +183,7 @@ func TransactionToMessage(tx *types.Transaction, s types.Signer, baseFee *big.In
 		Data:                  tx.Data(),
 		AccessList:            tx.AccessList(),
 		SetCodeAuthorizations: tx.SetCodeAuthorizations(),
+		Parallel:              tx.Type() == types.ParallelTxType,
 		SkipNonceChecks:       false,
 		SkipFromEOACheck:      false,
 		BlobHashes:            tx.BlobHashes(),
@@ -351,6 +356,10 @@ func (st *stateTransition) preCheck() error {
 			}
 		}
 	}
+	// Make sure parallel transactions are active
+	if msg.Parallel && !st.evm.ChainConfig().IsParallelTx(st.evm.Context.Time) {
+		return fmt.Errorf("%w: parallel transaction before activation, address %v", ErrTxTypeNotSupported, msg.From.Hex())
+	}
 	// Check the blob version validity
 	if msg.BlobHashes != nil {
 		// The to field of a blob tx type is mandatory, and a `BlobTx` transaction internally
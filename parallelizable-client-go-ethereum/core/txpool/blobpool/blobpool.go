@@ -1533,44 +1533,75 @@ func (p *BlobPool) Pending(filter txpool.PendingFilter) map[common.Address][]*tx
 
 	pending := make(map[common.Address][]*txpool.LazyTransaction, len(p.index))
 	for addr, txs := range p.index {
-		lazies := make([]*txpool.LazyTransaction, 0, len(txs))
-		for _, tx := range txs {
-			// If transaction filtering was requested, discard badly priced ones
-			if filter.MinTip != nil && filter.BaseFee != nil {
-				if tx.execFeeCap.Lt(filter.BaseFee) {
-					break // basefee too low, cannot be included, discard rest of txs from the account
-				}
-				tip := new(uint256.Int).Sub(tx.execFeeCap, filter.BaseFee)
-				if tip.Gt(tx.execTipCap) {
-					tip = tx.execTipCap
-				}
-				if tip.Lt(filter.MinTip) {
-					break // allowed or remaining tip too low, cannot be included, discard rest of txs from the account
-				}
-			}
-			if filter.BlobFee != nil {
-				if tx.blobFeeCap.Lt(filter.BlobFee) {
-					break // blobfee too low, cannot be included, discard rest of txs from the account
-				}
-			}
-			// Transaction was accepted according to the filter, append to the pending list
-			lazies = append(lazies, &txpool.LazyTransaction{
-				Pool:      p,
-				Hash:      tx.hash,
-				Time:      execStart, // TODO(karalabe): Maybe save these and use that?
-				GasFeeCap: tx.execFeeCap,
-				GasTipCap: tx.execTipCap,
-				Gas:       tx.execGas,
-				BlobGas:   tx.blobGas,
-			})
+		if lazies := p.lazies(txs, filter, execStart); len(lazies) > 0 {
+			pending[addr] = lazies
 		}
-		if len(lazies) > 0 {
+	}
+	return pending
+}
+
+// PendingFrom retrieves the currently processable transactions of the given
+// accounts, grouped by origin account and sorted by nonce, filtered like Pending.
+func (p *BlobPool) PendingFrom(addrs []common.Address, filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
+	if filter.OnlyPlainTxs {
+		return nil
+	}
+	pendStart := time.Now()
+	p.lock.RLock()
+	pendwaitHist.Update(time.Since(pendStart).Nanoseconds())
+	defer p.lock.RUnlock()
+
+	execStart := time.Now()
+	defer func() {
+		pendtimeHist.Update(time.Since(execStart).Nanoseconds())
+	}()
+
+	pending := make(map[common.Address][]*txpool.LazyTransaction, len(addrs))
+	for _, addr := range addrs {
+		if lazies := p.lazies(p.index[addr], filter, execStart); len(lazies) > 0 {
 			pending[addr] = lazies
 		}
 	}
 	return pending
 }
 
+// lazies wraps the transactions of an account into lazy ones, up to the first
+// one rejected by the filter.
+func (p *BlobPool) lazies(txs []*blobTxMeta, filter txpool.PendingFilter, now time.Time) []*txpool.LazyTransaction {
+	lazies := make([]*txpool.LazyTransaction, 0, len(txs))
+	for _, tx := range txs {
+		// If transaction filtering was requested, discard badly priced ones
+		if filter.MinTip != nil && filter.BaseFee != nil {
+			if tx.execFeeCap.Lt(filter.BaseFee) {
+				break // basefee too low, cannot be included, discard rest of txs from the account
+			}
+			tip := new(uint256.Int).Sub(tx.execFeeCap, filter.BaseFee)
+			if tip.Gt(tx.execTipCap) {
+				tip = tx.execTipCap
+			}
+			if tip.Lt(filter.MinTip) {
+				break // allowed or remaining tip too low, cannot be included, discard rest of txs from the account
+			}
+		}
+		if filter.BlobFee != nil {
+			if tx.blobFeeCap.Lt(filter.BlobFee) {
+				break // blobfee too low, cannot be included, discard rest of txs from the account
+			}
+		}
+		// Transaction was accepted according to the filter, append to the pending list
+		lazies = append(lazies, &txpool.LazyTransaction{
+			Pool:      p,
+			Hash:      tx.hash,
+			Time:      now, // TODO(karalabe): Maybe save these and use that?
+			GasFeeCap: tx.execFeeCap,
+			GasTipCap: tx.execTipCap,
+			Gas:       tx.execGas,
+			BlobGas:   tx.blobGas,
+		})
+	}
+	return lazies
+}
+
 // updateStorageMetrics retrieves a bunch of stats from the data store and pushes
 // them out as metrics.
 func (p *BlobPool) updateStorageMetrics() {
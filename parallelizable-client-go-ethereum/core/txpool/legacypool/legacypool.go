@@ -527,37 +527,73 @@ func (pool *LegacyPool) Pending(filter txpool.PendingFilter) map[common.Address]
 	}
 	pending := make(map[common.Address][]*txpool.LazyTransaction, len(pool.pending))
 	for addr, list := range pool.pending {
-		txs := list.Flatten()
+		if lazies := pool.lazies(list.Flatten(), minTipBig, baseFeeBig); len(lazies) > 0 {
+			pending[addr] = lazies
+		}
+	}
+	return pending
+}
 
-		// If the miner requests tip enforcement, cap the lists now
-		if minTipBig != nil {
-			for i, tx := range txs {
-				if tx.EffectiveGasTipIntCmp(minTipBig, baseFeeBig) < 0 {
-					txs = txs[:i]
-					break
-				}
-			}
+// PendingFrom retrieves the currently processable transactions of the given
+// accounts, grouped by origin account and sorted by nonce, filtered like Pending.
+func (pool *LegacyPool) PendingFrom(addrs []common.Address, filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
+	if filter.OnlyBlobTxs {
+		return nil
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	var (
+		minTipBig  *big.Int
+		baseFeeBig *big.Int
+	)
+	if filter.MinTip != nil {
+		minTipBig = filter.MinTip.ToBig()
+	}
+	if filter.BaseFee != nil {
+		baseFeeBig = filter.BaseFee.ToBig()
+	}
+	pending := make(map[common.Address][]*txpool.LazyTransaction, len(addrs))
+	for _, addr := range addrs {
+		list := pool.pending[addr]
+		if list == nil {
+			continue
 		}
-		if len(txs) > 0 {
-			lazies := make([]*txpool.LazyTransaction, len(txs))
-			for i := 0; i < len(txs); i++ {
-				lazies[i] = &txpool.LazyTransaction{
-					Pool:      pool,
-					Hash:      txs[i].Hash(),
-					Tx:        txs[i],
-					Time:      txs[i].Time(),
-					GasFeeCap: uint256.MustFromBig(txs[i].GasFeeCap()),
-					GasTipCap: uint256.MustFromBig(txs[i].GasTipCap()),
-					Gas:       txs[i].Gas(),
-					BlobGas:   txs[i].BlobGas(),
-				}
-			}
+		if lazies := pool.lazies(list.Flatten(), minTipBig, baseFeeBig); len(lazies) > 0 {
 			pending[addr] = lazies
 		}
 	}
 	return pending
 }
 
+// lazies wraps the pending transactions of an account into lazy ones, capping
+// the list at the first one paying less than the minimum tip, if requested.
+func (pool *LegacyPool) lazies(txs []*types.Transaction, minTipBig, baseFeeBig *big.Int) []*txpool.LazyTransaction {
+	// If the miner requests tip enforcement, cap the lists now
+	if minTipBig != nil {
+		for i, tx := range txs {
+			if tx.EffectiveGasTipIntCmp(minTipBig, baseFeeBig) < 0 {
+				txs = txs[:i]
+				break
+			}
+		}
+	}
+	lazies := make([]*txpool.LazyTransaction, len(txs))
+	for i := 0; i < len(txs); i++ {
+		lazies[i] = &txpool.LazyTransaction{
+			Pool:      pool,
+			Hash:      txs[i].Hash(),
+			Tx:        txs[i],
+			Time:      txs[i].Time(),
+			GasFeeCap: uint256.MustFromBig(txs[i].GasFeeCap()),
+			GasTipCap: uint256.MustFromBig(txs[i].GasTipCap()),
+			Gas:       txs[i].Gas(),
+			BlobGas:   txs[i].BlobGas(),
+		}
+	}
+	return lazies
+}
+
 // validateTxBasics checks whether a transaction is valid according to the consensus
 // rules, but does not check state-dependent validation such as sufficient balance.
 // This check is meant as an early check which only needs to be performed once,
@@ -447,6 +447,36 @@ func TestQueue2(t *testing.T) {
 	}
 }
 
+// Tests that the pending transactions of selected accounts can be retrieved,
+// filtered by tip like the whole pending set.
+func TestPendingFrom(t *testing.T) {
+	t.Parallel()
+
+	pool, key := setupPool()
+	defer pool.Close()
+
+	other, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, from, big.NewInt(1000000000))
+	testAddBalance(pool, crypto.PubkeyToAddress(other.PublicKey), big.NewInt(1000000000))
+
+	pool.addRemotesSync([]*types.Transaction{
+		pricedTransaction(0, 100000, big.NewInt(1), key),
+		pricedTransaction(1, 100000, big.NewInt(3), key),
+		pricedTransaction(0, 100000, big.NewInt(1), other),
+	})
+	pending := pool.PendingFrom([]common.Address{from}, txpool.PendingFilter{})
+	if len(pending) != 1 || len(pending[from]) != 2 {
+		t.Fatalf("pending mismatch: have %d accounts, %d transactions, want 1 and 2", len(pending), len(pending[from]))
+	}
+	if pending = pool.PendingFrom([]common.Address{from}, txpool.PendingFilter{MinTip: uint256.NewInt(2)}); len(pending) != 0 {
+		t.Errorf("tip filtered pending mismatch: have %d accounts, want 0", len(pending))
+	}
+	if pending = pool.PendingFrom([]common.Address{from}, txpool.PendingFilter{OnlyBlobTxs: true}); len(pending) != 0 {
+		t.Errorf("blob filtered pending mismatch: have %d accounts, want 0", len(pending))
+	}
+}
+
 func TestNegativeValue(t *testing.T) {
 	t.Parallel()
 
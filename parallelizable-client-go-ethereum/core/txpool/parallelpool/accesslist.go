@@ -0,0 +1,190 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers/logger"
+)
+
+// maxDeclaredFootprints is the number of footprints recorded by access list
+// generation that are remembered until their transactions arrive.
+const maxDeclaredFootprints = 1024
+
+// AccessListResult is the outcome of generating the access list of a parallel
+// transaction: the EIP-2930 access list, the read and write sets it splits into,
+// and the unsigned transaction carrying the access list.
+type AccessListResult struct {
+	AccessList  types.AccessList `json:"accessList"`
+	ReadSet     types.AccessList `json:"readSet"`
+	WriteSet    types.AccessList `json:"writeSet"`
+	GasUsed     hexutil.Uint64   `json:"gasUsed"`
+	Failed      bool             `json:"failed"` // Execution reverted
+	Transaction hexutil.Bytes    `json:"transaction"`
+}
+
+// footprintCache remembers the read and write sets recorded while generating
+// access lists, keyed by the signing hash of the transactions they were made
+// for. The signing hash covers the access list and survives signing, so pooling
+// the signed transaction finds the footprint without simulating it again.
+type footprintCache struct {
+	sets lru.BasicLRU[common.Hash, *rwSet]
+	lock sync.Mutex
+}
+
+// newFootprintCache creates a cache holding up to the given number of footprints.
+func newFootprintCache(size int) *footprintCache {
+	return &footprintCache{sets: lru.NewBasicLRU[common.Hash, *rwSet](size)}
+}
+
+// add remembers the footprint of a transaction by its signing hash.
+func (c *footprintCache) add(sighash common.Hash, set *rwSet) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.sets.Add(sighash, set)
+}
+
+// take returns and forgets the footprint of a transaction, if any.
+func (c *footprintCache) take(sighash common.Hash) *rwSet {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	set, ok := c.sets.Get(sighash)
+	if ok {
+		c.sets.Remove(sighash)
+	}
+	return set
+}
+
+// CreateAccessList executes an unsigned parallel transaction of the given sender
+// on top of the current head and generates the EIP-2930 access list it produces,
+// returning the transaction with the access list attached. As with
+// eth_createAccessList, the execution is repeated with the access list applied
+// until it no longer changes. Nonce and fee checks are skipped, as with eth_call,
+// but the transaction has to carry enough gas.
+//
+// The read and write sets of the final execution are remembered, so once the
+// returned transaction is signed and pooled, its conflicts are detected without
+// simulating it again.
+func (p *ParallelPool) CreateAccessList(tx *types.Transaction, from common.Address) (*AccessListResult, error) {
+	head := p.chain.CurrentBlock()
+	statedb, err := p.stateAt(head.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for access list: %v", err)
+	}
+	var (
+		adapter     = core.NewPendingExecAdapter(p.chainconfig, p.chain, head)
+		precompiles = vm.ActivePrecompiles(adapter.Rules())
+		to          common.Address
+	)
+	if tx.To() != nil {
+		to = *tx.To()
+	} else {
+		to = crypto.CreateAddress(from, tx.Nonce())
+	}
+	prev := logger.NewAccessListTracer(nil, from, to, precompiles)
+	for {
+		list := prev.AccessList()
+		msg := &core.Message{
+			From:             from,
+			To:               tx.To(),
+			Nonce:            tx.Nonce(),
+			Value:            tx.Value(),
+			GasLimit:         tx.Gas(),
+			GasPrice:         new(big.Int),
+			GasFeeCap:        new(big.Int),
+			GasTipCap:        new(big.Int),
+			Data:             tx.Data(),
+			AccessList:       list,
+			SkipNonceChecks:  true,
+			SkipFromEOACheck: true,
+		}
+		// Feed the execution to both the access list tracer, and the footprint
+		// tracer splitting the accesses into reads and writes
+		var (
+			acl      = logger.NewAccessListTracer(list, from, to, precompiles)
+			tracer   = newFootprintTracer(precompiles)
+			hooks    = tracer.hooks()
+			onOpcode = hooks.OnOpcode
+		)
+		hooks.OnOpcode = func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+			onOpcode(pc, op, gas, cost, scope, rData, depth, err)
+			acl.OnOpcode(pc, op, gas, cost, scope, rData, depth, err)
+		}
+		res, err := p.applyTraced(adapter, statedb.Copy(), msg, hooks, true)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply transaction: %v", err)
+		}
+		if !acl.Equal(prev) {
+			prev = acl
+			continue
+		}
+		// The access list is stable, attach it to the transaction, sorted so that
+		// identical requests produce identical transactions
+		accesses := make(footprintSet)
+		for _, tuple := range acl.AccessList() {
+			accesses.addAddress(tuple.Address)
+			for _, slot := range tuple.StorageKeys {
+				accesses.addSlot(tuple.Address, slot)
+			}
+		}
+		inner := &types.ParallelTx{
+			ChainID:      tx.ChainId(),
+			Nonce:        tx.Nonce(),
+			GasTipCap:    tx.GasTipCap(),
+			GasFeeCap:    tx.GasFeeCap(),
+			Gas:          tx.Gas(),
+			To:           tx.To(),
+			Value:        tx.Value(),
+			Data:         tx.Data(),
+			AccessList:   accesses.accessList(),
+			ParallelType: tx.ParallelType(),
+			Dependencies: tx.Dependencies(),
+			BatchID:      tx.BatchID(),
+		}
+		tagged := types.NewTx(inner)
+		raw, err := tagged.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		p.declared.add(p.signer.Hash(tagged), &rwSet{reads: tracer.reads, writes: tracer.writes, accounts: tracer.accounts})
+
+		return &AccessListResult{
+			AccessList:  inner.AccessList,
+			ReadSet:     tracer.reads.accessList(),
+			WriteSet:    tracer.writes.accessList(),
+			GasUsed:     hexutil.Uint64(res.UsedGas),
+			Failed:      res.Failed(),
+			Transaction: raw,
+		}, nil
+	}
+}
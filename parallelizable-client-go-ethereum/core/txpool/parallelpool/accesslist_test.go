@@ -0,0 +1,137 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"context"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that access list generation attaches the accessed slots to the tagged
+// transaction, splits them into read and write sets, and that the signed
+// transaction is admitted by the recorded sets without being simulated again.
+func TestCreateAccessList(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	var (
+		contract = common.Address{0xcc}
+		// SLOAD(1); SSTORE(2, 7); STOP
+		code = common.FromHex("0x60015450600760025500")
+	)
+	config := *params.MergedTestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			from:     {Balance: big.NewInt(params.Ether)},
+			contract: {Code: code},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, beacon.New(ethash.NewFaker()), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	var (
+		gas      = hexutil.Uint64(100000)
+		parallel = true
+	)
+	result, err := NewParallelTxPoolAPI(pool).CreateAccessList(context.Background(), TagTransactionRequest{
+		From:     from,
+		To:       &contract,
+		Gas:      &gas,
+		GasPrice: (*hexutil.Big)(big.NewInt(2 * params.InitialBaseFee)),
+		Parallel: &parallel,
+	})
+	if err != nil {
+		t.Fatalf("failed to create access list: %v", err)
+	}
+	if result.Failed {
+		t.Fatalf("access list execution failed: %+v", result)
+	}
+	slot := func(n byte) common.Hash { return common.Hash{31: n} }
+
+	// The sender is left out of the access list, the called contract keeps its
+	// accessed slots
+	want := types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot(1), slot(2)}}}
+	if !equalAccessLists(result.AccessList, want) {
+		t.Errorf("access list mismatch: have %v, want %v", result.AccessList, want)
+	}
+	if want := (types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot(1)}}}); !equalAccessLists(result.ReadSet, want) {
+		t.Errorf("read set mismatch: have %v, want %v", result.ReadSet, want)
+	}
+	wantWrites := types.AccessList{
+		{Address: from, StorageKeys: []common.Hash{}},
+		{Address: contract, StorageKeys: []common.Hash{slot(2)}},
+	}
+	slices.SortFunc(wantWrites, func(a, b types.AccessTuple) int { return a.Address.Cmp(b.Address) })
+	if !equalAccessLists(result.WriteSet, wantWrites) {
+		t.Errorf("write set mismatch: have %v, want %v", result.WriteSet, wantWrites)
+	}
+	// The returned transaction carries the access list and is admitted by the
+	// recorded footprint once signed
+	unsigned := new(types.Transaction)
+	if err := unsigned.UnmarshalBinary(result.Transaction); err != nil {
+		t.Fatalf("failed to decode transaction: %v", err)
+	}
+	if !equalAccessLists(unsigned.AccessList(), want) || unsigned.ParallelType() != types.ParallelTypeIndependent {
+		t.Fatalf("transaction mismatch: access list %v, type %d", unsigned.AccessList(), unsigned.ParallelType())
+	}
+	tx, err := types.SignTx(unsigned, types.LatestSigner(&config), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := pool.Add([]*types.Transaction{tx}, false)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if have := pool.metrics.declaredFootprint.Snapshot().Count(); have != 1 {
+		t.Errorf("declared footprint count mismatch: have %d, want 1", have)
+	}
+	if have := pool.metrics.speculativeGas.Snapshot().Count(); have != 0 {
+		t.Errorf("transaction simulated again, spending %d gas", have)
+	}
+	pool.batchMu.RLock()
+	set := pool.footprints[tx.Hash()]
+	pool.batchMu.RUnlock()
+	if set == nil {
+		t.Fatalf("no footprint recorded")
+	}
+	if _, ok := set.writes[contract][slot(2)]; !ok {
+		t.Errorf("recorded footprint misses the written slot")
+	}
+}
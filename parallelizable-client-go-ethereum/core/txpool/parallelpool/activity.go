@@ -0,0 +1,117 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// Account activity types, following a transaction through the pool.
+const (
+	ActivityAdded    = "added"    // Admitted to the pool
+	ActivityPromoted = "promoted" // Moved from the queue to pending, its nonce gap closed
+	ActivityDemoted  = "demoted"  // Moved from batching to the queue, stuck behind a nonce gap
+	ActivityBatched  = "batched"  // Placed into a batch, or moved to another one by a rebuild
+	ActivityExecuted = "executed" // Executed as a batch member, see the error if it failed
+	ActivityIncluded = "included" // Left the pool as its nonce was used up by the chain
+	ActivityDropped  = "dropped"  // Left the pool without being executed, see the reason
+)
+
+// Reasons for dropping a transaction from the pool.
+const (
+	DropReplaced    = "replaced"    // Replaced by a fee bumped transaction
+	DropEvicted     = "evicted"     // Displaced from the full pool by a better scoring transaction
+	DropExpired     = "expired"     // Pooled for longer than the configured lifetime
+	DropRateLimit   = "ratelimit"   // Over the slot limits of the account or the pool
+	DropNoFunds     = "nofunds"     // No longer affordable by the sender
+	DropUnderpriced = "underpriced" // Below a raised gas tip threshold
+	DropDeadline    = "deadline"    // Past its inclusion deadline
+	DropDependency  = "dependency"  // A transaction it depends on was dropped
+	DropReserved    = "reserved"    // Its sender was reserved by another subpool
+)
+
+// AccountActivity is posted as a transaction of an account moves through the
+// pool, from its admission to its execution, inclusion or drop.
+type AccountActivity struct {
+	Account common.Address `json:"account"`
+	Type    string         `json:"type"`
+	Hash    common.Hash    `json:"hash"`
+	Nonce   uint64         `json:"nonce"`
+	BatchID *uint64        `json:"batchID,omitempty"` // Batch of the transaction, for batched and executed ones
+	Reason  string         `json:"reason,omitempty"`  // Why the transaction was dropped
+	Error   string         `json:"error,omitempty"`   // Why the execution failed
+}
+
+// SubscribeAccountActivity registers a subscription for the activity of every
+// pooled transaction. Subscribers filter the accounts they track themselves.
+func (p *ParallelPool) SubscribeAccountActivity(ch chan<- AccountActivity) event.Subscription {
+	return p.scope.Track(p.activityFeed.Subscribe(ch))
+}
+
+// newActivity creates an activity of the given type for a transaction.
+func (p *ParallelPool) newActivity(tx *types.Transaction, kind string) AccountActivity {
+	from, _ := types.Sender(p.signer, tx)
+	return AccountActivity{Account: from, Type: kind, Hash: tx.Hash(), Nonce: tx.Nonce()}
+}
+
+// postActivity posts an activity of the given type for a transaction.
+func (p *ParallelPool) postActivity(tx *types.Transaction, kind string) {
+	p.activityFeed.Send(p.newActivity(tx, kind))
+}
+
+// dropTx removes a transaction from the pool without it being executed or
+// included, posting the reason for its sender. The caller must hold the pool
+// lock.
+func (p *ParallelPool) dropTx(hash common.Hash, reason string) {
+	if tx := p.all[hash]; tx != nil {
+		p.postDrop(tx, reason)
+	}
+	p.removeTx(hash, true, true)
+}
+
+// postDrop posts the drop of a transaction for the given reason.
+func (p *ParallelPool) postDrop(tx *types.Transaction, reason string) {
+	activity := p.newActivity(tx, ActivityDropped)
+	activity.Reason = reason
+	p.activityFeed.Send(activity)
+}
+
+// batchedActivity returns the activities of the transactions that were batched
+// or moved to another batch by a rebuild. The caller must hold the batch lock.
+func (p *ParallelPool) batchedActivity(changes []BatchChange, batches []TxBatch) []AccountActivity {
+	moved := make(map[common.Hash]struct{}, len(changes))
+	for _, change := range changes {
+		if change.NewBatch != nil {
+			moved[change.Hash] = struct{}{}
+		}
+	}
+	var activities []AccountActivity
+	for _, batch := range batches {
+		for _, tx := range batch.Transactions {
+			if _, ok := moved[tx.Hash()]; !ok {
+				continue
+			}
+			id := batch.BatchID
+			activity := p.newActivity(tx, ActivityBatched)
+			activity.BatchID = &id
+			activities = append(activities, activity)
+		}
+	}
+	return activities
+}
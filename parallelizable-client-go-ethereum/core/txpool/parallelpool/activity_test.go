@@ -0,0 +1,144 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the transactions of an account are followed through the pool:
+// admitted, promoted once their nonce gap closes, batched, replaced and
+// executed.
+func TestAccountActivity(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	activities := make(chan AccountActivity, 64)
+	sub := pool.SubscribeAccountActivity(activities)
+	defer sub.Unsubscribe()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, tip int64, parallelType uint8) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(2 * tip * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: parallelType,
+		})
+	}
+	type step struct {
+		kind   string
+		tx     *types.Transaction
+		reason string
+	}
+	expect := func(want ...step) {
+		t.Helper()
+		for i, w := range want {
+			var have AccountActivity
+			select {
+			case have = <-activities:
+			default:
+				t.Fatalf("activity %d missing, want %s of %x", i, w.kind, w.tx.Hash())
+			}
+			if have.Type != w.kind || have.Hash != w.tx.Hash() || have.Reason != w.reason {
+				t.Fatalf("activity %d mismatch: have %s of %x (%q), want %s of %x (%q)", i, have.Type, have.Hash, have.Reason, w.kind, w.tx.Hash(), w.reason)
+			}
+			if from, _ := types.Sender(signer, w.tx); have.Account != from || have.Nonce != w.tx.Nonce() {
+				t.Fatalf("activity %d account mismatch: have %x/%d, want %x/%d", i, have.Account, have.Nonce, from, w.tx.Nonce())
+			}
+			if (have.Type == ActivityBatched || have.Type == ActivityExecuted) && have.BatchID == nil {
+				t.Fatalf("activity %d lacks its batch", i)
+			}
+		}
+		select {
+		case have := <-activities:
+			t.Fatalf("unexpected activity: %s of %x", have.Type, have.Hash)
+		default:
+		}
+	}
+	// A parallel transaction is admitted and batched
+	parallel := newTx(keys[0], 0, 1, types.ParallelTypeIndependent)
+	if err := pool.Add([]*types.Transaction{parallel}, false)[0]; err != nil {
+		t.Fatalf("failed to add parallel transaction: %v", err)
+	}
+	expect(step{ActivityAdded, parallel, ""}, step{ActivityBatched, parallel, ""})
+
+	// A gapped sequential transaction is promoted once the gap closes
+	gapped := newTx(keys[1], 1, 1, types.ParallelTypeSequential)
+	if err := pool.Add([]*types.Transaction{gapped}, false)[0]; err != nil {
+		t.Fatalf("failed to add gapped transaction: %v", err)
+	}
+	expect(step{ActivityAdded, gapped, ""})
+
+	pool.mu.Lock()
+	pool.pendingState.SetNonce(crypto.PubkeyToAddress(keys[1].PublicKey), 1, tracing.NonceChangeUnspecified)
+	pool.dirty[crypto.PubkeyToAddress(keys[1].PublicKey)] = struct{}{}
+	pool.promoteExecutables()
+	pool.mu.Unlock()
+
+	expect(step{ActivityPromoted, gapped, ""})
+
+	// Replacing the parallel transaction drops it, the replacement is batched
+	replacement := newTx(keys[0], 0, 10, types.ParallelTypeIndependent)
+	if err := pool.Add([]*types.Transaction{replacement}, false)[0]; err != nil {
+		t.Fatalf("failed to add replacement: %v", err)
+	}
+	expect(step{ActivityDropped, parallel, DropReplaced}, step{ActivityAdded, replacement, ""}, step{ActivityBatched, replacement, ""})
+
+	// Executing the batch reports the outcome of its member
+	batches := pool.GetBatches()
+	if len(batches) != 1 {
+		t.Fatalf("batch count mismatch: have %d, want 1", len(batches))
+	}
+	if _, err := pool.ExecuteBatch(batches[0]); err != nil {
+		t.Fatalf("failed to execute batch: %v", err)
+	}
+	expect(step{ActivityExecuted, replacement, ""})
+}
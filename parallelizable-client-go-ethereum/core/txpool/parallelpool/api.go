@@ -17,17 +17,28 @@
 package parallelpool
 
 import (
+	"compress/gzip"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"math/big"
+	"os"
+	"slices"
 	"sort"
+	"strings"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/holiman/uint256"
 )
 
 // ParallelTxPoolAPI offers an API for working with parallel transactions
@@ -44,39 +55,33 @@ func NewParallelTxPoolAPI(pool *ParallelPool) *ParallelTxPoolAPI {
 
 // Status returns the current status of the parallel transaction pool
 type ParallelPoolStatus struct {
-	Pending             int `json:"pending"`             // Count of pending transactions
-	Queued              int `json:"queued"`              // Count of queued transactions
-	Parallelizable      int `json:"parallelizable"`      // Count of parallelizable transactions
-	Batches             int `json:"batches"`             // Count of batches
-	BatchSize           int `json:"batchSize"`           // Current batch size
-	TotalProcessed      int `json:"totalProcessed"`      // Total transactions processed
-	SuccessfullyBatched int `json:"successfullyBatched"` // Successfully batched transactions
+	Pending             int  `json:"pending"`             // Count of pending transactions
+	Queued              int  `json:"queued"`              // Count of queued transactions
+	Parallelizable      int  `json:"parallelizable"`      // Count of parallelizable transactions
+	Batches             int  `json:"batches"`             // Count of batches
+	BatchSize           int  `json:"batchSize"`           // Current batch size
+	TotalProcessed      int  `json:"totalProcessed"`      // Total transactions processed
+	SuccessfullyBatched int  `json:"successfullyBatched"` // Successfully batched transactions
+	ReadOnly            bool `json:"readOnly"`            // Whether batch execution is disabled
 }
 
-// Status returns the current status of the parallel transaction pool
+// Status returns the current status of the parallel transaction pool. It is
+// served from the pool's counters, without locking the pool, so that it
+// can be scraped frequently.
 func (api *ParallelTxPoolAPI) Status() ParallelPoolStatus {
-	// Get batch stats
-	var batchCount, txCount, batchSize int
-	if api.pool != nil {
-		batches := api.pool.GetBatches()
-		batchCount = len(batches)
-
-		// Count transactions in all batches
-		for _, batch := range batches {
-			txCount += len(batch.Transactions)
-		}
+	defer api.track("status", time.Now(), nil)
 
-		batchSize = api.pool.batchSize
-	}
+	counters := api.pool.Counters()
 
 	return ParallelPoolStatus{
-		Pending:             len(api.pool.pending),
-		Queued:              len(api.pool.queue),
-		Parallelizable:      txCount,
-		Batches:             batchCount,
-		BatchSize:           batchSize,
-		TotalProcessed:      0, // Would need to track this in the pool
-		SuccessfullyBatched: 0, // Would need to track this in the pool
+		Pending:             counters.Pending,
+		Queued:              counters.Queued,
+		Parallelizable:      counters.Batched,
+		Batches:             counters.Batches,
+		BatchSize:           counters.BatchSize,
+		TotalProcessed:      counters.Processed,
+		SuccessfullyBatched: counters.Executed,
+		ReadOnly:            api.pool.config.ReadOnly,
 	}
 }
 
@@ -89,11 +94,41 @@ type TagTransactionRequest struct {
 	Value    *hexutil.Big    `json:"value"`
 	Data     hexutil.Bytes   `json:"data"`
 	Nonce    *hexutil.Uint64 `json:"nonce"`
-	Parallel bool            `json:"parallel"`
+	Parallel *bool           `json:"parallel"` // Omitted to tag by the calldata classifier
+
+	Dependencies []common.Hash `json:"dependencies"` // Transactions to run after, making a parallel transaction dependent
 }
 
 // TagTransaction adds parallelization tags to a transaction
-func (api *ParallelTxPoolAPI) TagTransaction(ctx context.Context, args TagTransactionRequest) (hexutil.Bytes, error) {
+func (api *ParallelTxPoolAPI) TagTransaction(ctx context.Context, args TagTransactionRequest) (_ hexutil.Bytes, err error) {
+	defer api.track("tagTransaction", time.Now(), &err)
+
+	tx, err := api.newTaggedTx(args)
+	if err != nil {
+		return nil, err
+	}
+	// Return raw transaction - it still needs to be signed
+	txBytes, err := tx.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal transaction: %v", err)
+	}
+
+	return txBytes, nil
+}
+
+// newTaggedTx assembles the unsigned parallel transaction of a tagging request,
+// filling in the fields left out.
+func (api *ParallelTxPoolAPI) newTaggedTx(args TagTransactionRequest) (*types.Transaction, error) {
+	inner, err := api.newTaggedTxData(args)
+	if err != nil {
+		return nil, err
+	}
+	return types.NewTx(inner), nil
+}
+
+// newTaggedTxData assembles the payload of the unsigned parallel transaction of
+// a tagging request, filling in the fields left out.
+func (api *ParallelTxPoolAPI) newTaggedTxData(args TagTransactionRequest) (*types.ParallelTx, error) {
 	// Extract transaction data
 	var (
 		data  []byte
@@ -147,49 +182,126 @@ func (api *ParallelTxPoolAPI) TagTransaction(ctx context.Context, args TagTransa
 		value = (*big.Int)(args.Value)
 	}
 
-	// Add parallel tag to data
-	if args.Parallel {
-		data = append([]byte(ParallelizableTag), args.Data...)
+	// Route the transaction through the typed ParallelType field, dropping any
+	// deprecated calldata tag so the payload reaches the contract intact
+	data = args.Data
+	if tag := legacyTag(data); tag != "" {
+		data = data[len(tag):]
+	} else {
+		api.pool.metrics.tagUpgraded.Mark(1)
+	}
+	// Without an explicit tag, fall back to the calldata classifier. Contract
+	// creations are always sequential.
+	parallel := args.Parallel != nil && *args.Parallel
+	if args.Parallel == nil && args.To != nil {
+		class := api.pool.classifier.Classify(data)
+		parallel = class.Parallel
+		api.pool.metrics.tagClassified.Mark(1)
+		log.Debug("Classified untagged transaction", "from", args.From, "kind", class.Kind, "method", class.Method, "parallel", parallel, "confidence", class.Confidence)
+	}
+	parallelType := uint8(types.ParallelTypeSequential)
+	if parallel {
+		parallelType = types.ParallelTypeIndependent
+		if len(args.Dependencies) > 0 {
+			parallelType = types.ParallelTypeDependent
+		}
 		log.Debug("Tagged transaction as parallelizable", "from", args.From, "to", args.To)
 	} else {
-		data = append([]byte(SequentialTag), args.Data...)
 		log.Debug("Tagged transaction as sequential", "from", args.From, "to", args.To)
 	}
+	// Create transaction with the parallel transaction type, a nil recipient
+	// meaning contract creation
+	return &types.ParallelTx{
+		ChainID:      api.pool.chainconfig.ChainID,
+		Nonce:        nonce,
+		GasTipCap:    price,
+		GasFeeCap:    price,
+		Gas:          gas,
+		To:           args.To,
+		Value:        value,
+		Data:         data,
+		ParallelType: parallelType,
+		Dependencies: slices.Clone(args.Dependencies),
+	}, nil
+}
 
-	// Create transaction with the parallel transaction type
-	var tx *types.Transaction
-	if args.To == nil {
-		// Contract creation
-		tx = types.NewTx(&types.LegacyTx{
-			Nonce:    nonce,
-			GasPrice: price,
-			Gas:      gas,
-			Value:    value,
-			Data:     data,
-		})
-	} else {
-		// Regular transaction
-		tx = types.NewTx(&types.LegacyTx{
-			Nonce:    nonce,
-			GasPrice: price,
-			Gas:      gas,
-			To:       args.To,
-			Value:    value,
-			Data:     data,
-		})
-	}
+// CreateAccessList assembles the transaction of a tagging request like
+// TagTransaction, executes it on top of the current head and returns the
+// EIP-2930 access list it produces, split into read and write sets, along with
+// the unsigned transaction carrying the access list. Once signed and submitted,
+// the pool admits the transaction by the recorded read and write sets instead of
+// simulating it again.
+func (api *ParallelTxPoolAPI) CreateAccessList(ctx context.Context, args TagTransactionRequest) (_ *AccessListResult, err error) {
+	defer api.track("createAccessList", time.Now(), &err)
 
-	// Return raw transaction - it still needs to be signed
-	txBytes, err := tx.MarshalBinary()
+	tx, err := api.newTaggedTx(args)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal transaction: %v", err)
+		return nil, err
 	}
+	return api.pool.CreateAccessList(tx, args.From)
+}
 
-	return txBytes, nil
+// CreateWorkflow assembles the unsigned transactions of a multi-step workflow,
+// such as an approval followed by a swap, from an ordered list of tagging
+// requests. Each step after the first declares a dependency on the one before
+// it, by the signing hash of its unsigned transaction, as the final hash is
+// only known once signed. The pool resolves such dependencies against the
+// pooled transactions, so the signed steps have to be submitted in order. Steps
+// of the same sender without a nonce are numbered consecutively. Nothing is
+// signed: the encoded transactions are returned in step order.
+func (api *ParallelTxPoolAPI) CreateWorkflow(ctx context.Context, steps []TagTransactionRequest) (_ []hexutil.Bytes, err error) {
+	defer api.track("createWorkflow", time.Now(), &err)
+
+	if len(steps) == 0 {
+		return nil, errors.New("empty workflow")
+	}
+	if len(steps) > maxWorkflowSteps {
+		return nil, fmt.Errorf("workflow of %d steps exceeds limit of %d", len(steps), maxWorkflowSteps)
+	}
+	var (
+		encoded = make([]hexutil.Bytes, len(steps))
+		nonces  = make(map[common.Address]uint64)
+		prev    *types.Transaction
+	)
+	for i, step := range steps {
+		if next, ok := nonces[step.From]; ok && step.Nonce == nil {
+			step.Nonce = (*hexutil.Uint64)(&next)
+		}
+		inner, err := api.newTaggedTxData(step)
+		if err != nil {
+			return nil, fmt.Errorf("step %d: %v", i, err)
+		}
+		// Chain the step to the previous one on top of the dependencies it
+		// declares, turning independent steps into dependent ones. Sequential
+		// steps are never batched, but declare the dependency all the same.
+		if prev != nil {
+			inner.Dependencies = append(inner.Dependencies, api.pool.signer.Hash(prev))
+			if inner.ParallelType == types.ParallelTypeIndependent {
+				inner.ParallelType = types.ParallelTypeDependent
+			}
+		}
+		tx := types.NewTx(inner)
+		if encoded[i], err = tx.MarshalBinary(); err != nil {
+			return nil, fmt.Errorf("failed to marshal step %d: %v", i, err)
+		}
+		nonces[step.From] = tx.Nonce() + 1
+		prev = tx
+	}
+	return encoded, nil
+}
+
+// SuggestTip advises whether calls to the given contract are worth tagging
+// PARALLEL, and what tip is competitive for them within the parallel lane.
+func (api *ParallelTxPoolAPI) SuggestTip(contract common.Address) *TipSuggestion {
+	defer api.track("suggestTip", time.Now(), nil)
+
+	return api.pool.SuggestTip(contract)
 }
 
 // SetBatchSize updates the batch size for parallel processing
-func (api *ParallelTxPoolAPI) SetBatchSize(size int) error {
+func (api *ParallelTxPoolAPI) SetBatchSize(size int) (err error) {
+	defer api.track("setBatchSize", time.Now(), &err)
+
 	if size <= 0 {
 		return errors.New("batch size must be greater than zero")
 	}
@@ -202,8 +314,25 @@ func (api *ParallelTxPoolAPI) SetBatchSize(size int) error {
 	return nil
 }
 
+// SetParallelism resizes the worker pool executing the members of batches for
+// executions, simulations and estimates. Zero restores the default of one
+// worker per CPU.
+func (api *ParallelTxPoolAPI) SetParallelism(workers int) (err error) {
+	defer api.track("setParallelism", time.Now(), &err)
+
+	if workers == 0 {
+		workers = DefaultParallelism()
+	}
+	return api.pool.SetParallelism(workers)
+}
+
 // ExecuteBatches triggers execution of all current batches
-func (api *ParallelTxPoolAPI) ExecuteBatches() ([]common.Hash, error) {
+func (api *ParallelTxPoolAPI) ExecuteBatches() (_ []common.Hash, err error) {
+	defer api.track("executeBatches", time.Now(), &err)
+
+	if api.pool.config.ReadOnly {
+		return nil, ErrReadOnly
+	}
 	batches := api.pool.GetBatches()
 	if len(batches) == 0 {
 		return nil, nil
@@ -223,23 +352,102 @@ func (api *ParallelTxPoolAPI) ExecuteBatches() ([]common.Hash, error) {
 	return allExecuted, nil
 }
 
+// SimulateBatches simulates all current batches without executing them, on the
+// standby shadow state if the pool is configured with one.
+func (api *ParallelTxPoolAPI) SimulateBatches() (_ map[uint64][]*BatchSimulation, err error) {
+	defer api.track("simulateBatches", time.Now(), &err)
+
+	results := make(map[uint64][]*BatchSimulation)
+	for _, batch := range api.pool.GetBatches() {
+		res, err := api.pool.SimulateBatch(batch)
+		if err != nil {
+			return nil, err
+		}
+		results[batch.BatchID] = res
+	}
+	return results, nil
+}
+
+// EstimateBatchGas estimates the gas usage of a batch of raw signed transactions
+// on top of the current head, along with the critical path of its dependent
+// members, so that batches can be sized before submission.
+func (api *ParallelTxPoolAPI) EstimateBatchGas(raws []hexutil.Bytes) (_ *BatchGasEstimate, err error) {
+	defer api.track("estimateBatchGas", time.Now(), &err)
+
+	txs := make([]*types.Transaction, len(raws))
+	for i, raw := range raws {
+		txs[i] = new(types.Transaction)
+		if err := txs[i].UnmarshalBinary(raw); err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i, err)
+		}
+	}
+	return api.pool.EstimateBatchGas(txs)
+}
+
+// SetParallelPreference stores a signed preference of an account to opt out of
+// (or back into) parallel handling of its transactions.
+func (api *ParallelTxPoolAPI) SetParallelPreference(pref ParallelPreference) (err error) {
+	defer api.track("setParallelPreference", time.Now(), &err)
+
+	return api.pool.SetParallelPreference(&pref)
+}
+
+// GetParallelPreference returns the stored parallel preference of an account.
+func (api *ParallelTxPoolAPI) GetParallelPreference(addr common.Address) *ParallelPreference {
+	defer api.track("getParallelPreference", time.Now(), nil)
+
+	return api.pool.ParallelPreference(addr)
+}
+
+// ReserveNonceRange stores a signed reservation of a range of future nonces of
+// an account, admitting its transactions within the range even into a full
+// pool. A reservation of zero nonces releases the held one.
+func (api *ParallelTxPoolAPI) ReserveNonceRange(r NonceRange) (err error) {
+	defer api.track("reserveNonceRange", time.Now(), &err)
+
+	return api.pool.ReserveNonceRange(&r)
+}
+
+// GetNonceRange returns the nonce range reservation held by an account.
+func (api *ParallelTxPoolAPI) GetNonceRange(addr common.Address) *NonceRange {
+	defer api.track("getNonceRange", time.Now(), nil)
+
+	return api.pool.NonceRange(addr)
+}
+
+// Quarantine returns the batches quarantined after repeatedly failing execution,
+// along with their failure context.
+func (api *ParallelTxPoolAPI) Quarantine() []*QuarantinedBatch {
+	defer api.track("quarantine", time.Now(), nil)
+
+	return api.pool.Quarantine()
+}
+
+// ReleaseQuarantine manually releases a quarantined batch by its key.
+func (api *ParallelTxPoolAPI) ReleaseQuarantine(key common.Hash) (err error) {
+	defer api.track("releaseQuarantine", time.Now(), &err)
+
+	return api.pool.ReleaseQuarantine(key)
+}
+
 // IsParallelizable checks if a transaction is tagged as parallelizable
-func (api *ParallelTxPoolAPI) IsParallelizable(txHash common.Hash) (map[string]interface{}, error) {
+func (api *ParallelTxPoolAPI) IsParallelizable(txHash common.Hash) (_ map[string]interface{}, err error) {
+	defer api.track("isParallelizable", time.Now(), &err)
+
 	tx := api.pool.all[txHash]
 	if tx == nil {
 		return nil, errors.New("transaction not found")
 	}
 
-	// Check transaction data for tag
-	txData := tx.Data()
+	// Check the routing tag of the transaction
 	result := make(map[string]interface{})
 	result["hash"] = txHash.Hex()
 
-	if len(txData) > 8 {
-		tag := string(txData[:8])
+	if tag, legacy := TxTag(tx); tag != "" {
 		isParallel := tag == ParallelizableTag
 		result["isParallelizable"] = isParallel
 		result["tag"] = tag
+		result["legacyTag"] = legacy
 
 		// Get additional info
 		from, err := types.Sender(api.pool.signer, tx)
@@ -292,6 +500,8 @@ func (api *ParallelTxPoolAPI) IsParallelizable(txHash common.Hash) (map[string]i
 
 // BatchStatistics returns detailed information about the current batches
 func (api *ParallelTxPoolAPI) BatchStatistics() map[string]interface{} {
+	defer api.track("batchStatistics", time.Now(), nil)
+
 	stats := make(map[string]interface{})
 
 	api.pool.batchMu.RLock()
@@ -315,6 +525,7 @@ func (api *ParallelTxPoolAPI) BatchStatistics() map[string]interface{} {
 		batchInfo := make(map[string]interface{})
 		batchInfo["batchID"] = batch.BatchID
 		batchInfo["txCount"] = batchSize
+		batchInfo["level"] = batch.Level
 
 		// Get unique senders in this batch
 		senders := make(map[common.Address]bool)
@@ -329,13 +540,12 @@ func (api *ParallelTxPoolAPI) BatchStatistics() map[string]interface{} {
 
 		// Calculate gas statistics for this batch
 		if batchSize > 0 {
-			totalGas := uint64(0)
+			totalGas := batch.TotalGas
 			minGas := batch.Transactions[0].Gas()
 			maxGas := minGas
 
 			for _, tx := range batch.Transactions {
 				gas := tx.Gas()
-				totalGas += gas
 				if gas < minGas {
 					minGas = gas
 				}
@@ -379,65 +589,548 @@ func (api *ParallelTxPoolAPI) BatchStatistics() map[string]interface{} {
 
 // AnalyzeTransactionData examines transaction data to suggest whether it would be suitable for parallelization
 func (api *ParallelTxPoolAPI) AnalyzeTransactionData(data hexutil.Bytes) map[string]interface{} {
+	defer api.track("analyzeTransactionData", time.Now(), nil)
+
 	result := make(map[string]interface{})
 
 	// Basic data analysis
 	dataLen := len(data)
 	result["dataLength"] = dataLen
 
-	// Check if already has tag
-	if dataLen >= 8 {
-		prefix := string(data[:8])
-		if prefix == ParallelizableTag {
-			result["isTagged"] = true
-			result["tag"] = "PARALLEL"
-			result["recommendation"] = "Transaction is already tagged as parallelizable"
-			return result
-		} else if prefix == SequentialTag {
-			result["isTagged"] = true
-			result["tag"] = "SEQUENTIAL"
-			result["recommendation"] = "Transaction is already tagged as sequential"
-			return result
-		}
+	// Check if already has a deprecated calldata tag
+	switch legacyTag(data) {
+	case ParallelizableTag:
+		result["isTagged"] = true
+		result["tag"] = ParallelizableTag
+		result["recommendation"] = "Transaction carries a deprecated calldata tag, set the ParallelType field instead"
+		return result
+	case SequentialTag:
+		result["isTagged"] = true
+		result["tag"] = SequentialTag
+		result["recommendation"] = "Transaction carries a deprecated calldata tag, set the ParallelType field instead"
+		return result
 	}
 
 	result["isTagged"] = false
 
-	// Analyze transaction data to determine if it's likely parallelizable
-	// This is a simplified analysis that could be expanded with more sophisticated logic
+	// Classify the call by its method, with the same rules tagging untagged
+	// transactions
+	class := api.pool.classifier.Classify(data)
+	if class.Selector != "" {
+		result["methodSignature"] = class.Selector
+	}
+	if class.Method != "" {
+		result["method"] = class.Method
+	}
+	result["methodType"] = class.Kind
+	result["parallelRecommendation"] = class.Parallel
+	result["confidence"] = class.Confidence
+	result["recommendation"] = class.Reason
+
+	return result
+}
+
+// ExportBatches returns descriptors of the currently prepared batches for external
+// consumers. If the node has a builder key configured, each descriptor is signed
+// over its batch hash.
+func (api *ParallelTxPoolAPI) ExportBatches() (_ []*SubmittedBatch, err error) {
+	defer api.track("exportBatches", time.Now(), &err)
+
+	return api.pool.ExportBatches()
+}
 
-	// Method signature detection (first 4 bytes of data for contract calls)
-	if dataLen >= 4 {
-		methodSignature := hexutil.Encode(data[:4])
-		result["methodSignature"] = methodSignature
+// GetMembershipProof returns the Merkle proof of a transaction's membership in
+// a prepared batch, verifiable against the members root of the batch descriptor.
+func (api *ParallelTxPoolAPI) GetMembershipProof(batchID hexutil.Uint64, hash common.Hash) (_ *MembershipProof, err error) {
+	defer api.track("getMembershipProof", time.Now(), &err)
 
-		// Known parallel-safe method signatures could be checked here
-		// For example: simple token transfers, read-only operations, etc.
+	return api.pool.MembershipProof(uint64(batchID), hash)
+}
+
+// IngestionStats returns per-origin (local, remote, journal) counts of
+// accepted and rejected transactions, to help separate spam from organic traffic.
+func (api *ParallelTxPoolAPI) IngestionStats() map[string]OriginStats {
+	defer api.track("ingestionStats", time.Now(), nil)
+
+	return api.pool.IngestionStats()
+}
+
+// GetDependencyClosure returns the transitive dependency ancestors ("ancestors")
+// or dependents ("dependents") of a pooled transaction, optionally limited in
+// depth, showing what a stuck transaction waits on and what waits on it.
+func (api *ParallelTxPoolAPI) GetDependencyClosure(hash common.Hash, direction string, depth *hexutil.Uint) (_ *DependencyClosure, err error) {
+	defer api.track("getDependencyClosure", time.Now(), &err)
+
+	var limit int
+	if depth != nil {
+		limit = int(*depth)
+	}
+	return api.pool.DependencyClosure(hash, direction, limit)
+}
+
+// GetDependents returns the hashes of the pooled transactions declaring a direct
+// dependency on the given transaction.
+func (api *ParallelTxPoolAPI) GetDependents(hash common.Hash) []common.Hash {
+	defer api.track("getDependents", time.Now(), nil)
+
+	dependents := api.pool.Dependents(hash)
+	if dependents == nil {
+		dependents = []common.Hash{}
+	}
+	return dependents
+}
+
+// ConflictMatrix returns the conflict graph of the transactions ready for
+// batching, listing which pairs conflict and on which account or slot.
+func (api *ParallelTxPoolAPI) ConflictMatrix() *ConflictMatrix {
+	defer api.track("conflictMatrix", time.Now(), nil)
+
+	return api.pool.ConflictMatrix()
+}
+
+// InclusionDeadlineArgs are the arguments of an inclusion deadline, relative to
+// the current head. At least one bound must be set.
+type InclusionDeadlineArgs struct {
+	Blocks  *hexutil.Uint64 `json:"blocks"`  // Number of blocks the transaction should be included within
+	Seconds *hexutil.Uint64 `json:"seconds"` // Number of seconds the transaction should be included within
+}
+
+// SetInclusionDeadline attaches a soft inclusion deadline to a pooled parallel
+// transaction. The batcher prioritises the transaction as the deadline
+// approaches, and drops it once the deadline passed.
+func (api *ParallelTxPoolAPI) SetInclusionDeadline(hash common.Hash, args InclusionDeadlineArgs) (_ InclusionDeadline, err error) {
+	defer api.track("setInclusionDeadline", time.Now(), &err)
+
+	var blocks, seconds uint64
+	if args.Blocks != nil {
+		blocks = uint64(*args.Blocks)
+	}
+	if args.Seconds != nil {
+		seconds = uint64(*args.Seconds)
+	}
+	return api.pool.SetInclusionDeadline(hash, blocks, seconds)
+}
 
-		// Check for common ERC20 transfer method (0xa9059cbb)
-		if methodSignature == "0xa9059cbb" && dataLen >= 68 {
-			result["methodType"] = "ERC20 Transfer"
-			result["parallelRecommendation"] = true
-			result["confidence"] = "high"
-			result["recommendation"] = "This appears to be an ERC20 transfer which is typically parallelizable"
-			return result
+// GetNonceRepair returns the missing nonces holding back the pooled transactions
+// of an account, with template transactions filling them, allowing wallets to
+// unstick the transactions in one go.
+func (api *ParallelTxPoolAPI) GetNonceRepair(addr common.Address) *NonceRepair {
+	defer api.track("getNonceRepair", time.Now(), nil)
+
+	return api.pool.NonceRepair(addr)
+}
+
+// FootprintArgs are the arguments of a footprint trace: either a raw signed
+// transaction, or call arguments as accepted by eth_call.
+type FootprintArgs struct {
+	Raw   hexutil.Bytes   `json:"raw"`
+	From  common.Address  `json:"from"`
+	To    *common.Address `json:"to"`
+	Gas   *hexutil.Uint64 `json:"gas"`
+	Value *hexutil.Big    `json:"value"`
+	Data  hexutil.Bytes   `json:"data"`
+}
+
+// TraceFootprint executes a raw transaction or a call on top of the current
+// head and returns the exact read and write sets it produced (accounts, storage
+// slots and transient storage), to craft ReadSet/WriteSet declarations from.
+func (api *ParallelTxPoolAPI) TraceFootprint(ctx context.Context, args FootprintArgs) (_ *Footprint, err error) {
+	defer api.track("traceFootprint", time.Now(), &err)
+
+	head := api.pool.chain.CurrentBlock()
+
+	var msg *core.Message
+	if len(args.Raw) > 0 {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(args.Raw); err != nil {
+			return nil, err
 		}
+		signer := types.MakeSigner(api.pool.chainconfig, new(big.Int).Add(head.Number, common.Big1), head.Time)
+		m, err := core.TransactionToMessage(tx, signer, nil)
+		if err != nil {
+			return nil, err
+		}
+		msg = m
+	} else {
+		msg = &core.Message{
+			From:             args.From,
+			To:               args.To,
+			Value:            new(big.Int),
+			GasLimit:         head.GasLimit,
+			GasPrice:         new(big.Int),
+			GasFeeCap:        new(big.Int),
+			GasTipCap:        new(big.Int),
+			Data:             args.Data,
+			SkipFromEOACheck: true,
+		}
+		if args.Value != nil {
+			msg.Value = args.Value.ToInt()
+		}
+		if args.Gas != nil {
+			msg.GasLimit = uint64(*args.Gas)
+		}
+	}
+	return api.pool.TraceFootprint(msg)
+}
+
+// SendUserOpBundle submits a raw account abstraction bundle transaction along
+// with the footprints of its user operations, letting bundles of independent
+// user operations be batched together.
+func (api *ParallelTxPoolAPI) SendUserOpBundle(ctx context.Context, raw hexutil.Bytes, bundle UserOpBundle) (_ common.Hash, err error) {
+	defer api.track("sendUserOpBundle", time.Now(), &err)
+
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return common.Hash{}, err
+	}
+	if err := api.pool.AddUserOpBundle(tx, &bundle); err != nil {
+		return common.Hash{}, err
+	}
+	return tx.Hash(), nil
+}
+
+// GetTxDiagnostics returns diagnostic information about a parallel transaction,
+// including the conflicting counterparties (address/slot) that caused it to be
+// aborted, reordered or serialized.
+func (api *ParallelTxPoolAPI) GetTxDiagnostics(hash common.Hash) *TxDiagnostics {
+	defer api.track("getTxDiagnostics", time.Now(), nil)
+
+	return api.pool.TxDiagnostics(hash)
+}
+
+// Content returns the pending and queued transactions of the parallel pool,
+// grouped by account and keyed by nonce, in the format of txpool_content.
+// Pending transactions include the ones awaiting batch execution.
+func (api *ParallelTxPoolAPI) Content() map[string]map[string]map[string]*ethapi.RPCTransaction {
+	defer api.track("content", time.Now(), nil)
+
+	content := map[string]map[string]map[string]*ethapi.RPCTransaction{
+		"pending": make(map[string]map[string]*ethapi.RPCTransaction),
+		"queued":  make(map[string]map[string]*ethapi.RPCTransaction),
+	}
+	pending, queue := api.pool.Content()
+	head := api.pool.chain.CurrentBlock()
+
+	for account, txs := range pending {
+		content["pending"][account.Hex()] = api.dump(txs, head)
+	}
+	for account, txs := range queue {
+		content["queued"][account.Hex()] = api.dump(txs, head)
+	}
+	return content
+}
+
+// ContentFrom returns the pending and queued transactions of an account in the
+// parallel pool, keyed by nonce, in the format of txpool_contentFrom.
+func (api *ParallelTxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*ethapi.RPCTransaction {
+	defer api.track("contentFrom", time.Now(), nil)
+
+	pending, queue := api.pool.ContentFrom(addr)
+	head := api.pool.chain.CurrentBlock()
+
+	return map[string]map[string]*ethapi.RPCTransaction{
+		"pending": api.dump(pending, head),
+		"queued":  api.dump(queue, head),
+	}
+}
+
+// PendingFrom returns the executable transactions of the given accounts, keyed
+// by account and nonce, including the ones awaiting batch execution. If a
+// minimum tip is given, each account's transactions are cut off at the first
+// one paying less at the current base fee.
+func (api *ParallelTxPoolAPI) PendingFrom(addrs []common.Address, minTip *hexutil.Big) (content map[string]map[string]*ethapi.RPCTransaction, err error) {
+	defer api.track("pendingFrom", time.Now(), &err)
+
+	head := api.pool.chain.CurrentBlock()
 
-		// Check for simple ETH transfers (empty or very small data)
-		if dataLen <= 4 {
-			result["methodType"] = "ETH Transfer"
-			result["parallelRecommendation"] = true
-			result["confidence"] = "high"
-			result["recommendation"] = "This appears to be a simple ETH transfer which is typically parallelizable"
-			return result
+	var filter txpool.PendingFilter
+	if minTip != nil {
+		tip, overflow := uint256.FromBig((*big.Int)(minTip))
+		if minTip.ToInt().Sign() < 0 || overflow {
+			return nil, fmt.Errorf("invalid minimum tip %v", minTip)
 		}
+		filter.MinTip = tip
+		if head.BaseFee != nil {
+			filter.BaseFee = uint256.MustFromBig(head.BaseFee)
+		}
+	}
+	content = make(map[string]map[string]*ethapi.RPCTransaction)
+	for addr, lazies := range api.pool.PendingFrom(addrs, filter) {
+		txs := make([]*types.Transaction, len(lazies))
+		for i, lazy := range lazies {
+			txs[i] = lazy.Tx
+		}
+		content[addr.Hex()] = api.dump(txs, head)
+	}
+	return content, nil
+}
 
-		// Default guidance for unknown methods
-		result["methodType"] = "Unknown Contract Interaction"
-		result["parallelRecommendation"] = false
-		result["confidence"] = "low"
-		result["recommendation"] = "Contract interactions with unknown methods should be treated as sequential by default for safety"
+// dump flattens transactions into a nonce-keyed map of RPC transactions.
+func (api *ParallelTxPoolAPI) dump(txs []*types.Transaction, head *types.Header) map[string]*ethapi.RPCTransaction {
+	dump := make(map[string]*ethapi.RPCTransaction, len(txs))
+	for _, tx := range txs {
+		rpcTx := ethapi.NewRPCPendingTransaction(tx, head, api.pool.chainconfig)
+		rpcTx.Parallel = true
+		dump[fmt.Sprintf("%d", tx.Nonce())] = rpcTx
 	}
+	return dump
+}
 
-	return result
+// Inspect retrieves the content of the parallel pool and flattens it into an
+// easily inspectable list, in the format of txpool_inspect. Transactions are
+// annotated with their routing tag.
+func (api *ParallelTxPoolAPI) Inspect() map[string]map[string]map[string]string {
+	defer api.track("inspect", time.Now(), nil)
+
+	content := map[string]map[string]map[string]string{
+		"pending": make(map[string]map[string]string),
+		"queued":  make(map[string]map[string]string),
+	}
+	pending, queue := api.pool.Content()
+
+	// Define a formatter to flatten a transaction into a string
+	format := func(tx *types.Transaction) string {
+		tag, _ := TxTag(tx)
+		if to := tx.To(); to != nil {
+			return fmt.Sprintf("%s: %v wei + %v gas × %v wei (%s)", to.Hex(), tx.Value(), tx.Gas(), tx.GasPrice(), tag)
+		}
+		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei (%s)", tx.Value(), tx.Gas(), tx.GasPrice(), tag)
+	}
+	for section, accounts := range map[string]map[common.Address][]*types.Transaction{"pending": pending, "queued": queue} {
+		for account, txs := range accounts {
+			dump := make(map[string]string, len(txs))
+			for _, tx := range txs {
+				dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+			}
+			content[section][account.Hex()] = dump
+		}
+	}
+	return content
+}
+
+// BatchChanges creates a subscription that fires for every transaction moving
+// between batches as the pool rebuilds them.
+func (api *ParallelTxPoolAPI) BatchChanges(ctx context.Context) (_ *rpc.Subscription, err error) {
+	defer api.track("batchChanges", time.Now(), &err)
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan BatchChangeEvent, 16)
+		sub := api.pool.SubscribeBatchChangeEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				for _, change := range ev.Changes {
+					notifier.Notify(rpcSub.ID, change)
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// BatchEvents creates a subscription that fires as batches are created,
+// executed or invalidated.
+func (api *ParallelTxPoolAPI) BatchEvents(ctx context.Context) (_ *rpc.Subscription, err error) {
+	defer api.track("batchEvents", time.Now(), &err)
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan BatchEvent, 16)
+		sub := api.pool.SubscribeBatchEvents(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				notifier.Notify(rpcSub.ID, ev)
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// DeadlineDrops creates a subscription that fires with the hash of every
+// transaction dropped for missing its inclusion deadline.
+func (api *ParallelTxPoolAPI) DeadlineDrops(ctx context.Context) (_ *rpc.Subscription, err error) {
+	defer api.track("deadlineDrops", time.Now(), &err)
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		events := make(chan DeadlineDropEvent, 16)
+		sub := api.pool.SubscribeDeadlineDropEvent(events)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case ev := <-events:
+				for _, tx := range ev.Txs {
+					notifier.Notify(rpcSub.ID, tx.Hash())
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// AccountActivity creates a subscription that fires as the transactions of an
+// account move through the pool: admitted, promoted, batched, executed, included
+// or dropped along with the reason.
+func (api *ParallelTxPoolAPI) AccountActivity(ctx context.Context, account common.Address) (_ *rpc.Subscription, err error) {
+	defer api.track("accountActivity", time.Now(), &err)
+
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		activities := make(chan AccountActivity, 16)
+		sub := api.pool.SubscribeAccountActivity(activities)
+		defer sub.Unsubscribe()
+
+		for {
+			select {
+			case activity := <-activities:
+				if activity.Account == account {
+					notifier.Notify(rpcSub.ID, activity)
+				}
+			case <-sub.Err():
+				return
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// ParallelEthAPI serves the parallel pool subscriptions offered under the eth
+// namespace, for wallets subscribing through eth_subscribe.
+type ParallelEthAPI struct {
+	api *ParallelTxPoolAPI
+}
+
+// NewParallelEthAPI creates the eth namespace API of a parallel pool.
+func NewParallelEthAPI(pool *ParallelPool) *ParallelEthAPI {
+	return &ParallelEthAPI{api: NewParallelTxPoolAPI(pool)}
+}
+
+// ParallelAccountActivity creates a subscription that fires as the transactions
+// of an account move through the parallel pool, see AccountActivity.
+func (api *ParallelEthAPI) ParallelAccountActivity(ctx context.Context, account common.Address) (*rpc.Subscription, error) {
+	return api.api.AccountActivity(ctx, account)
+}
+
+// ExportPool writes a snapshot of the pool content into a file on the node,
+// gzipped if the file name ends in .gz, returning the number of exported
+// transactions. It requires the admin methods to be enabled.
+func (api *ParallelTxPoolAPI) ExportPool(file string) (_ int, err error) {
+	defer api.track("exportPool", time.Now(), &err)
+
+	if !api.pool.config.AdminAPI {
+		return 0, ErrAdminDisabled
+	}
+	if _, err := os.Stat(file); err == nil {
+		// File already exists. Allowing overwrite could be a DoS vector,
+		// since the 'file' may point to arbitrary paths on the drive.
+		return 0, errors.New("location would overwrite an existing file")
+	}
+	out, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return 0, err
+	}
+	var (
+		writer io.Writer = out
+		zipper *gzip.Writer
+	)
+	if strings.HasSuffix(file, ".gz") {
+		zipper = gzip.NewWriter(writer)
+		writer = zipper
+	}
+	n, err := api.pool.Export(writer)
+
+	// The gzip writer only flushes its buffered data and trailer on close, and
+	// the file may only report a failed write then, so both errors count
+	if zipper != nil {
+		if cerr := zipper.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+// Mode returns the mode the pool is running in, either "subpool" or "legacy".
+func (api *ParallelTxPoolAPI) Mode() string {
+	defer api.track("mode", time.Now(), nil)
+
+	return api.pool.Mode()
+}
+
+// SetMode switches the pool between the subpool and the legacy mode while it
+// keeps running, migrating the reservations of the pooled accounts. It requires
+// the admin methods to be enabled.
+func (api *ParallelTxPoolAPI) SetMode(mode string) (_ *ModeSwitch, err error) {
+	defer api.track("setMode", time.Now(), &err)
+
+	if !api.pool.config.AdminAPI {
+		return nil, ErrAdminDisabled
+	}
+	return api.pool.SetMode(mode)
+}
+
+// ImportPool adds the transactions of a pool snapshot file on the node, written
+// by ExportPool, to the pool. It requires the admin methods to be enabled.
+func (api *ParallelTxPoolAPI) ImportPool(file string) (_ *ImportResult, err error) {
+	defer api.track("importPool", time.Now(), &err)
+
+	if !api.pool.config.AdminAPI {
+		return nil, ErrAdminDisabled
+	}
+	in, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer in.Close()
+
+	var reader io.Reader = in
+	if strings.HasSuffix(file, ".gz") {
+		if reader, err = gzip.NewReader(reader); err != nil {
+			return nil, err
+		}
+	}
+	return api.pool.Import(reader)
 }
@@ -0,0 +1,218 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the content endpoints report the pending and queued transactions
+// of the parallel pool in the txpool namespace formats.
+func TestContentAPI(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	for _, nonce := range []uint64{0, 2} {
+		tx := types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeSequential,
+		})
+		if err := pool.Add([]*types.Transaction{tx}, false)[0]; err != nil {
+			t.Fatalf("failed to add transaction %d: %v", nonce, err)
+		}
+	}
+	api := NewParallelTxPoolAPI(pool)
+
+	content := api.Content()
+	if tx := content["pending"][from.Hex()]["0"]; tx == nil || !tx.Parallel {
+		t.Errorf("pending transaction missing or unflagged: %+v", tx)
+	}
+	if tx := content["queued"][from.Hex()]["2"]; tx == nil || !tx.Parallel {
+		t.Errorf("queued transaction missing or unflagged: %+v", tx)
+	}
+	contentFrom := api.ContentFrom(from)
+	if len(contentFrom["pending"]) != 1 || len(contentFrom["queued"]) != 1 {
+		t.Errorf("account content mismatch: have %d pending, %d queued, want 1 each", len(contentFrom["pending"]), len(contentFrom["queued"]))
+	}
+	pendingFrom, err := api.PendingFrom([]common.Address{from, {0x01}}, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve account pending: %v", err)
+	}
+	if len(pendingFrom) != 1 || len(pendingFrom[from.Hex()]) != 1 || pendingFrom[from.Hex()]["0"] == nil {
+		t.Errorf("account pending mismatch: have %v", pendingFrom)
+	}
+	if pendingFrom, err = api.PendingFrom([]common.Address{from}, (*hexutil.Big)(common.Big2)); err != nil || len(pendingFrom) != 0 {
+		t.Errorf("tip filtered account pending mismatch: have %v, %v", pendingFrom, err)
+	}
+	inspect := api.Inspect()
+	if have := inspect["pending"][from.Hex()]["0"]; !strings.HasSuffix(have, "("+SequentialTag+")") {
+		t.Errorf("inspect entry mismatch: have %q", have)
+	}
+	if status := api.Status(); status.Pending != 1 || status.Queued != 1 || status.BatchSize != DefaultBatchSize {
+		t.Errorf("status mismatch: have %d pending, %d queued, batch size %d, want 1, 1, %d", status.Pending, status.Queued, status.BatchSize, DefaultBatchSize)
+	}
+}
+
+// Tests that workflows are assembled into unsigned transactions each depending
+// on the previous one, and that the dependencies resolve once the signed steps
+// are pooled in order.
+func TestCreateWorkflow(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	api := NewParallelTxPoolAPI(pool)
+	if _, err := api.CreateWorkflow(context.Background(), nil); err == nil {
+		t.Fatalf("empty workflow accepted")
+	}
+	// Approve and swap from one account, then settle from another
+	var (
+		parallel = true
+		steps    = []TagTransactionRequest{
+			{From: crypto.PubkeyToAddress(keys[0].PublicKey), To: &common.Address{0xaa}, Parallel: &parallel},
+			{From: crypto.PubkeyToAddress(keys[0].PublicKey), To: &common.Address{0xbb}, Parallel: &parallel},
+			{From: crypto.PubkeyToAddress(keys[1].PublicKey), To: &common.Address{0xcc}, Parallel: &parallel},
+		}
+		signers = []*ecdsa.PrivateKey{keys[0], keys[0], keys[1]}
+		signer  = types.LatestSigner(&config)
+	)
+	encoded, err := api.CreateWorkflow(context.Background(), steps)
+	if err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if len(encoded) != len(steps) {
+		t.Fatalf("workflow length mismatch: have %d, want %d", len(encoded), len(steps))
+	}
+	var (
+		nonces = []uint64{0, 1, 0}
+		prev   *types.Transaction
+		signed []*types.Transaction
+	)
+	for i, raw := range encoded {
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			t.Fatalf("step %d: failed to decode: %v", i, err)
+		}
+		if tx.Nonce() != nonces[i] {
+			t.Errorf("step %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), nonces[i])
+		}
+		switch {
+		case prev == nil:
+			if len(tx.Dependencies()) != 0 || tx.ParallelType() != types.ParallelTypeIndependent {
+				t.Errorf("step %d: first step chained: type %d, dependencies %v", i, tx.ParallelType(), tx.Dependencies())
+			}
+		default:
+			if deps := tx.Dependencies(); len(deps) != 1 || deps[0] != signer.Hash(prev) || tx.ParallelType() != types.ParallelTypeDependent {
+				t.Errorf("step %d: step not chained: type %d, dependencies %v", i, tx.ParallelType(), deps)
+			}
+		}
+		prev = tx
+
+		sig, err := types.SignTx(tx, signer, signers[i])
+		if err != nil {
+			t.Fatalf("step %d: failed to sign: %v", i, err)
+		}
+		signed = append(signed, sig)
+	}
+	// Dependencies declared by a step are kept, with the chained one appended
+	declared := []common.Hash{{0x01}}
+	chained, err := api.CreateWorkflow(context.Background(), []TagTransactionRequest{
+		steps[0],
+		{From: steps[1].From, To: steps[1].To, Parallel: &parallel, Dependencies: declared},
+	})
+	if err != nil {
+		t.Fatalf("failed to create workflow with declared dependencies: %v", err)
+	}
+	var first, second types.Transaction
+	if err := first.UnmarshalBinary(chained[0]); err != nil {
+		t.Fatalf("failed to decode first step: %v", err)
+	}
+	if err := second.UnmarshalBinary(chained[1]); err != nil {
+		t.Fatalf("failed to decode second step: %v", err)
+	}
+	if deps := second.Dependencies(); len(deps) != 2 || deps[0] != declared[0] || deps[1] != signer.Hash(&first) {
+		t.Errorf("declared dependencies not kept: have %v", deps)
+	}
+	// Submitted in order, the dependencies resolve to the signed predecessors
+	for i, tx := range signed {
+		if err := pool.Add([]*types.Transaction{tx}, true)[0]; err != nil {
+			t.Fatalf("step %d: failed to add: %v", i, err)
+		}
+	}
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	for i := 1; i < len(signed); i++ {
+		if deps := pool.dependencies[signed[i].Hash()]; len(deps) != 1 || deps[0] != signed[i-1].Hash() {
+			t.Errorf("step %d: resolved dependencies mismatch: have %v, want %v", i, deps, signed[i-1].Hash())
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// apiMetricsPrefix is the prefix of the per-method metrics of the parallel RPC
+// API.
+const apiMetricsPrefix = "parallel/api"
+
+// track records a served call of an RPC method: its latency in the method's
+// duration timer, and its outcome in the success or failure meter. Methods that
+// cannot fail pass a nil error pointer. It is meant to be deferred at the start
+// of the method, with the error pointing to its named error result.
+func (api *ParallelTxPoolAPI) track(method string, start time.Time, err *error) {
+	var (
+		registry = api.pool.config.MetricsRegistry
+		prefix   = apiMetricsPrefix + "/" + method
+	)
+	metrics.GetOrRegisterTimer(prefix+"/duration", registry).UpdateSince(start)
+	if err != nil && *err != nil {
+		metrics.GetOrRegisterMeter(prefix+"/failure", registry).Mark(1)
+	} else {
+		metrics.GetOrRegisterMeter(prefix+"/success", registry).Mark(1)
+	}
+}
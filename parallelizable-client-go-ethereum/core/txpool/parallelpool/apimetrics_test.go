@@ -0,0 +1,58 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that served RPC calls are timed per method and counted by outcome.
+func TestAPIMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	api := NewParallelTxPoolAPI(&ParallelPool{config: Config{MetricsRegistry: registry}})
+
+	api.SetBatchSize(0)
+	api.ExportBatches()
+	api.ExportBatches()
+
+	// Timer samples are only taken with metrics enabled, check the registration
+	for _, name := range []string{"parallel/api/setBatchSize/duration", "parallel/api/exportBatches/duration"} {
+		if _, ok := registry.Get(name).(*metrics.Timer); !ok {
+			t.Errorf("timer %s not registered", name)
+		}
+	}
+	for name, want := range map[string]int64{
+		"parallel/api/setBatchSize/failure":  1,
+		"parallel/api/exportBatches/success": 2,
+	} {
+		meter, ok := registry.Get(name).(*metrics.Meter)
+		if !ok {
+			t.Errorf("meter %s not registered", name)
+			continue
+		}
+		if have := meter.Snapshot().Count(); have != want {
+			t.Errorf("meter %s count mismatch: have %d, want %d", name, have, want)
+		}
+	}
+	for _, name := range []string{"parallel/api/setBatchSize/success", "parallel/api/exportBatches/failure"} {
+		if registry.Get(name) != nil {
+			t.Errorf("metric %s registered without calls", name)
+		}
+	}
+}
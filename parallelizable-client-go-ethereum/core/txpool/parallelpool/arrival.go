@@ -0,0 +1,92 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// arrivalTimes records when pooled transactions were admitted by the pool. The
+// time carried by a transaction is when it was decoded, which for transactions
+// restored from the journal, the batch log or a snapshot is when the node read
+// them back rather than when they first arrived.
+//
+// Arrival times are read while building batches and sorting prices, under locks
+// other than the pool lock, so they are guarded on their own.
+type arrivalTimes struct {
+	times map[common.Hash]time.Time
+	lock  sync.RWMutex
+}
+
+// newArrivalTimes creates an empty arrival time record.
+func newArrivalTimes() *arrivalTimes {
+	return &arrivalTimes{times: make(map[common.Hash]time.Time)}
+}
+
+// record sets the arrival time of a transaction, keeping the first one seen.
+func (a *arrivalTimes) record(hash common.Hash, t time.Time) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	if _, ok := a.times[hash]; !ok {
+		a.times[hash] = t
+	}
+}
+
+// forget drops the arrival time of a transaction leaving the pool.
+func (a *arrivalTimes) forget(hash common.Hash) {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.times, hash)
+}
+
+// reset drops all arrival times.
+func (a *arrivalTimes) reset() {
+	if a == nil {
+		return
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	a.times = make(map[common.Hash]time.Time)
+}
+
+// get returns the arrival time of a transaction, falling back to the time it
+// was decoded if the pool did not admit it.
+func (a *arrivalTimes) get(tx *types.Transaction) time.Time {
+	if a == nil {
+		return tx.Time()
+	}
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if t, ok := a.times[tx.Hash()]; ok {
+		return t
+	}
+	return tx.Time()
+}
@@ -0,0 +1,73 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"container/heap"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that admission times, rather than decoding times, order transactions
+// first-in-first-out within batches and break price ties in the priced list,
+// the oldest arrivals being evicted last.
+func TestArrivalOrdering(t *testing.T) {
+	var (
+		arrivals = newArrivalTimes()
+		all      = make(map[common.Hash]*types.Transaction)
+		txs      []*types.Transaction
+		start    = time.Now()
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, 1, key)
+		all[tx.Hash()] = tx
+		txs = append(txs, tx)
+	}
+	// Admit the transactions out of their decoding order, re-admissions keeping
+	// the first arrival
+	arrivals.record(txs[1].Hash(), start)
+	arrivals.record(txs[0].Hash(), start.Add(time.Second))
+	arrivals.record(txs[2].Hash(), start.Add(2*time.Second))
+	arrivals.record(txs[1].Hash(), start.Add(3*time.Second))
+
+	want := []*types.Transaction{txs[1], txs[0], txs[2]}
+	for i, tx := range orderTransactions(testSigner, txs, OrderByArrival, arrivals) {
+		if tx != want[i] {
+			t.Errorf("fifo: tx %d mismatch: have %x, want %x", i, tx.Hash(), want[i].Hash())
+		}
+	}
+	priced := newParallelPricedList(all, arrivals)
+	for _, tx := range txs {
+		priced.Put(tx)
+	}
+	want = []*types.Transaction{txs[2], txs[0], txs[1]}
+	for i := range want {
+		if tx := heap.Pop(&priced.heap).(*types.Transaction); tx != want[i] {
+			t.Errorf("priced: tx %d mismatch: have %x, want %x", i, tx.Hash(), want[i].Hash())
+		}
+	}
+	// Transactions not admitted fall back to their decoding time
+	arrivals.forget(txs[1].Hash())
+	if have := arrivals.get(txs[1]); !have.Equal(txs[1].Time()) {
+		t.Errorf("arrival fallback mismatch: have %v, want %v", have, txs[1].Time())
+	}
+}
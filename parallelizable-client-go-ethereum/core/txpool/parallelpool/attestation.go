@@ -0,0 +1,130 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// ErrBatchNotSigned is returned when verifying a batch descriptor that
+	// carries no builder attestation.
+	ErrBatchNotSigned = errors.New("batch descriptor not signed")
+
+	// ErrInvalidBatchSignature is returned if the attestation on a batch
+	// descriptor does not recover to the declared builder.
+	ErrInvalidBatchSignature = errors.New("invalid batch signature")
+)
+
+// SubmittedBatch is the descriptor of a prepared batch as handed out to external
+// consumers (relays, AVS operators). If the pool was configured with a builder
+// key, the descriptor carries an attestation over its hash so that consumers can
+// attribute the batch to the node that built it. The attestation commits to the
+// Merkle root over the members rather than the member list, so the member list
+// may be stripped and membership proven against the root instead.
+type SubmittedBatch struct {
+	BatchID     uint64            `json:"batchID"`
+	TxHashes    []common.Hash     `json:"txHashes"`
+	MembersRoot common.Hash       `json:"membersRoot"`
+	Hashing     MembershipHashing `json:"hashing"`
+	Hash        common.Hash       `json:"hash"`
+	Builder     *common.Address   `json:"builder,omitempty"`
+	Signature   hexutil.Bytes     `json:"signature,omitempty"`
+}
+
+// newSubmittedBatch creates an unsigned descriptor for the given batch.
+func newSubmittedBatch(batch TxBatch, hashing MembershipHashing) *SubmittedBatch {
+	hashes := make([]common.Hash, len(batch.Transactions))
+	for i, tx := range batch.Transactions {
+		hashes[i] = tx.Hash()
+	}
+	desc := &SubmittedBatch{
+		BatchID:     batch.BatchID,
+		TxHashes:    hashes,
+		MembersRoot: membersRoot(hashes, hashing),
+		Hashing:     hashing,
+	}
+	desc.Hash = desc.SigHash()
+	return desc
+}
+
+// SigHash returns the hash the builder signs, committing to the batch identifier
+// and the root over the ordered list of member transactions.
+func (b *SubmittedBatch) SigHash() common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{b.BatchID, uint8(b.Hashing), b.MembersRoot})
+	return crypto.Keccak256Hash(enc)
+}
+
+// sign attaches a builder attestation to the descriptor.
+func (b *SubmittedBatch) sign(key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(b.Hash[:], key)
+	if err != nil {
+		return err
+	}
+	builder := crypto.PubkeyToAddress(key.PublicKey)
+	b.Builder = &builder
+	b.Signature = sig
+	return nil
+}
+
+// Verify checks that the descriptor hash matches its content and that the
+// attestation was produced by the declared builder, returning its address. The
+// member list, if not stripped, must match the members root.
+func (b *SubmittedBatch) Verify() (common.Address, error) {
+	if len(b.Signature) == 0 || b.Builder == nil {
+		return common.Address{}, ErrBatchNotSigned
+	}
+	if len(b.TxHashes) > 0 && membersRoot(b.TxHashes, b.Hashing) != b.MembersRoot {
+		return common.Address{}, ErrInvalidBatchSignature
+	}
+	hash := b.SigHash()
+	if hash != b.Hash {
+		return common.Address{}, ErrInvalidBatchSignature
+	}
+	pub, err := crypto.SigToPub(hash[:], b.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != *b.Builder {
+		return common.Address{}, ErrInvalidBatchSignature
+	}
+	return *b.Builder, nil
+}
+
+// ExportBatches returns descriptors of all currently prepared batches, signed
+// with the builder key if one was configured.
+func (p *ParallelPool) ExportBatches() ([]*SubmittedBatch, error) {
+	batches := p.GetBatches()
+
+	descs := make([]*SubmittedBatch, 0, len(batches))
+	for _, batch := range batches {
+		desc := newSubmittedBatch(batch, p.config.MembershipHashing)
+		if p.builderKey != nil {
+			if err := desc.sign(p.builderKey); err != nil {
+				return nil, err
+			}
+		}
+		descs = append(descs, desc)
+	}
+	return descs, nil
+}
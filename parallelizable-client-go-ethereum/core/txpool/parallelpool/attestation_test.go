@@ -0,0 +1,67 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that signed batch descriptors verify against the builder key and that
+// any tampering with the member list is detected.
+func TestSubmittedBatchAttestation(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	builder := crypto.PubkeyToAddress(key.PublicKey)
+
+	desc := &SubmittedBatch{
+		BatchID:  1,
+		TxHashes: []common.Hash{{0x01}, {0x02}},
+	}
+	desc.MembersRoot = membersRoot(desc.TxHashes, desc.Hashing)
+	desc.Hash = desc.SigHash()
+
+	if _, err := desc.Verify(); !errors.Is(err, ErrBatchNotSigned) {
+		t.Fatalf("unsigned descriptor error mismatch: have %v, want %v", err, ErrBatchNotSigned)
+	}
+	if err := desc.sign(key); err != nil {
+		t.Fatalf("failed to sign descriptor: %v", err)
+	}
+	signer, err := desc.Verify()
+	if err != nil {
+		t.Fatalf("failed to verify descriptor: %v", err)
+	}
+	if signer != builder {
+		t.Fatalf("builder mismatch: have %x, want %x", signer, builder)
+	}
+	// Light consumers may strip the member list and still verify the attestation
+	light := *desc
+	light.TxHashes = nil
+	if _, err := light.Verify(); err != nil {
+		t.Fatalf("failed to verify stripped descriptor: %v", err)
+	}
+	desc.TxHashes = append(desc.TxHashes, common.Hash{0x03})
+	if _, err := desc.Verify(); !errors.Is(err, ErrInvalidBatchSignature) {
+		t.Fatalf("tampered descriptor error mismatch: have %v, want %v", err, ErrInvalidBatchSignature)
+	}
+	light.MembersRoot = membersRoot(desc.TxHashes, desc.Hashing)
+	if _, err := light.Verify(); !errors.Is(err, ErrInvalidBatchSignature) {
+		t.Fatalf("tampered root error mismatch: have %v, want %v", err, ErrInvalidBatchSignature)
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// fallbackAged moves the transactions that awaited batch execution for at least
+// the configured number of blocks as of the given head to the sequential path.
+// Parallelizable transactions may otherwise bounce between rebuilt batches
+// forever, while the sequential path guarantees their ordering. The caller
+// must hold the pool lock.
+func (p *ParallelPool) fallbackAged(head *types.Header) {
+	number := head.Number.Uint64()
+
+	p.batchMu.RLock()
+	var aged []common.Hash
+	for hash, since := range p.batchedAt {
+		if number >= since+p.config.MaxBatchAge {
+			aged = append(aged, hash)
+		}
+	}
+	p.batchMu.RUnlock()
+
+	for _, hash := range aged {
+		p.demote(hash)
+		p.ReportConflict(hash, ConflictReport{Action: ConflictAged})
+	}
+	if len(aged) > 0 {
+		log.Debug("Moved aged parallel transactions to the sequential path", "count", len(aged), "number", number)
+		p.metrics.batchAged.Mark(int64(len(aged)))
+	}
+}
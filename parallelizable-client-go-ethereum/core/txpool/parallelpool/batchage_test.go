@@ -0,0 +1,85 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that transactions awaiting batch execution for too many blocks fall back
+// to the sequential path, while younger ones stay batched.
+func TestBatchAgeFallback(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	pool := &ParallelPool{
+		config:            Config{}.sanitize(),
+		signer:            testSigner,
+		all:               make(map[common.Hash]*types.Transaction),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dirty:             make(map[common.Address]struct{}),
+		beats:             make(map[common.Address]time.Time),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		footprints:        make(map[common.Hash]*rwSet),
+		batchedAt:         make(map[common.Hash]uint64),
+		conflictReports:   lru.NewBasicLRU[common.Hash, []ConflictReport](maxConflictReports),
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	var txs []*types.Transaction
+	for i, since := range []uint64{0, 10} {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, int64(i+1), key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		pool.batchedAt[tx.Hash()] = since
+		txs = append(txs, tx)
+	}
+	pool.fallbackAged(&types.Header{Number: big.NewInt(int64(defaultBatchAge) - 1)})
+	if len(pool.parallelizableTxs) != 2 {
+		t.Fatalf("transactions moved before their batch age: %d batched", len(pool.parallelizableTxs))
+	}
+	pool.currentHead = &types.Header{Number: big.NewInt(defaultBatchAge)}
+	if have := pool.TxDiagnostics(txs[1].Hash()).BatchAge; have != defaultBatchAge-10 {
+		t.Errorf("batch age mismatch: have %d, want %d", have, defaultBatchAge-10)
+	}
+	pool.fallbackAged(pool.currentHead)
+
+	from, _ := types.Sender(testSigner, txs[0])
+	if pool.pending[from] == nil || pool.pending[from].GetByHash(txs[0].Hash()) == nil {
+		t.Errorf("aged transaction not moved to the sequential path")
+	}
+	if len(pool.parallelizableTxs) != 1 || pool.batchedAt[txs[1].Hash()] != 10 {
+		t.Errorf("young transaction not kept batched")
+	}
+	diag := pool.TxDiagnostics(txs[0].Hash())
+	if diag.Parallelizable || len(diag.Conflicts) != 1 || diag.Conflicts[0].Action != ConflictAged {
+		t.Errorf("aged transaction diagnostics mismatch: %+v", diag)
+	}
+	if have := pool.metrics.batchAged.Snapshot().Count(); have != 1 {
+		t.Errorf("aged meter mismatch: have %d, want 1", have)
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Reasons for a transaction to change batches.
+const (
+	BatchChangeAdded       = "added"       // Batched for the first time
+	BatchChangeRebalanced  = "rebalanced"  // Moved to another batch by a rebuild
+	BatchChangeInvalidated = "invalidated" // Left batching but still pooled (demoted, quarantined)
+	BatchChangeRemoved     = "removed"     // Left the pool (executed, replaced, evicted)
+)
+
+// Batch lifecycle event types.
+const (
+	BatchCreated     = "created"     // Assembled by a rebuild
+	BatchExecuting   = "executing"   // Execution started
+	BatchExecuted    = "executed"    // Execution finished, see the member results
+	BatchInvalidated = "invalidated" // Dissolved by a rebuild without being executed
+)
+
+// BatchTxResult is the outcome of executing a member of a batch.
+type BatchTxResult struct {
+	Hash    common.Hash `json:"hash"`
+	Success bool        `json:"success"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// BatchEvent is posted as a batch goes through its lifecycle: created by a
+// rebuild, executed, or dissolved by a later rebuild. Batches are identified by
+// their members, so a rebuild changing the members of a batch invalidates it
+// and creates another.
+type BatchEvent struct {
+	Type     string          `json:"type"`
+	BatchID  uint64          `json:"batchID"`
+	Level    int             `json:"level"`
+	TxHashes []common.Hash   `json:"txHashes"`
+	Results  []BatchTxResult `json:"results,omitempty"` // Outcome per member, for executed batches
+}
+
+// newBatchEvent creates a lifecycle event of the given type for a batch.
+func newBatchEvent(kind string, batch TxBatch) BatchEvent {
+	hashes := make([]common.Hash, len(batch.Transactions))
+	for i, tx := range batch.Transactions {
+		hashes[i] = tx.Hash()
+	}
+	return BatchEvent{Type: kind, BatchID: batch.BatchID, Level: batch.Level, TxHashes: hashes}
+}
+
+// batchLifecycle returns the events of the batches created and invalidated by a
+// rebuild, in this order. Batches executed since the last rebuild are gone for
+// good rather than invalidated. The caller must hold the batch lock.
+func (p *ParallelPool) batchLifecycle(old, batches []TxBatch) []BatchEvent {
+	before := make(map[uint64]struct{}, len(old))
+	for _, batch := range old {
+		before[batch.BatchID] = struct{}{}
+	}
+	var events []BatchEvent
+	for _, batch := range batches {
+		if _, ok := before[batch.BatchID]; ok {
+			delete(before, batch.BatchID)
+			continue
+		}
+		events = append(events, newBatchEvent(BatchCreated, batch))
+	}
+	for _, batch := range old {
+		if _, ok := before[batch.BatchID]; !ok {
+			continue
+		}
+		if _, ok := p.executedBatches[batch.BatchID]; ok {
+			continue
+		}
+		events = append(events, newBatchEvent(BatchInvalidated, batch))
+	}
+	clear(p.executedBatches)
+	return events
+}
+
+// postExecuted posts the execution outcome of a batch, given the errors of its
+// failed members.
+func (p *ParallelPool) postExecuted(batch TxBatch, failed map[common.Hash]error) {
+	event := newBatchEvent(BatchExecuted, batch)
+	event.Results = make([]BatchTxResult, len(batch.Transactions))
+	for i, hash := range event.TxHashes {
+		event.Results[i] = BatchTxResult{Hash: hash, Success: true}
+		if err := failed[hash]; err != nil {
+			event.Results[i] = BatchTxResult{Hash: hash, Error: err.Error()}
+		}
+	}
+	p.lifecycleFeed.Send(event)
+
+	for i, tx := range batch.Transactions {
+		activity := p.newActivity(tx, ActivityExecuted)
+		activity.BatchID = &event.BatchID
+		activity.Error = event.Results[i].Error
+		p.activityFeed.Send(activity)
+	}
+}
+
+// BatchChange describes a single transaction moving between batches. A nil
+// batch identifier means the transaction was not, or is no longer, batched.
+type BatchChange struct {
+	Hash     common.Hash `json:"hash"`
+	OldBatch *uint64     `json:"oldBatch"`
+	NewBatch *uint64     `json:"newBatch"`
+	Reason   string      `json:"reason"`
+}
+
+// BatchChangeEvent is posted when rebuilding the batches changed the batch of
+// any transaction, carrying all changes of the rebuild.
+type BatchChangeEvent struct {
+	Changes []BatchChange
+}
+
+// batchChanges returns the changes in batch membership between the old and the
+// new batches. Batch identifiers are derived from the members, so transactions
+// of batches surviving a rebuild unchanged don't move. The caller must hold the
+// batch lock.
+func (p *ParallelPool) batchChanges(old, batches []TxBatch) []BatchChange {
+	before := make(map[common.Hash]uint64)
+	for _, batch := range old {
+		for _, tx := range batch.Transactions {
+			before[tx.Hash()] = batch.BatchID
+		}
+	}
+	var changes []BatchChange
+	for _, batch := range batches {
+		for _, tx := range batch.Transactions {
+			newID := batch.BatchID
+
+			oldID, ok := before[tx.Hash()]
+			delete(before, tx.Hash())
+			switch {
+			case !ok:
+				changes = append(changes, BatchChange{Hash: tx.Hash(), NewBatch: &newID, Reason: BatchChangeAdded})
+			case oldID != newID:
+				changes = append(changes, BatchChange{Hash: tx.Hash(), OldBatch: &oldID, NewBatch: &newID, Reason: BatchChangeRebalanced})
+			}
+		}
+	}
+	// Whatever was batched before but not anymore left batching or the pool
+	for hash, oldID := range before {
+		reason := BatchChangeRemoved
+		if p.all[hash] != nil {
+			reason = BatchChangeInvalidated
+		}
+		changes = append(changes, BatchChange{Hash: hash, OldBatch: &oldID, Reason: reason})
+	}
+	return changes
+}
@@ -0,0 +1,173 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that batch rebuilds post the membership changes they cause, keeping
+// the identifiers of unchanged batches stable.
+func TestBatchChangeEvents(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	all := make(map[common.Hash]*types.Transaction)
+	pool := &ParallelPool{
+		signer:            testSigner,
+		all:               all,
+		priced:            newParallelPricedList(all, nil),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		hintIndex:         make(map[DependencyHint][]common.Hash),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		quarantine:        newQuarantine("", 0),
+		batchSize:         1,
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	events := make(chan BatchChangeEvent, 16)
+	sub := pool.SubscribeBatchChangeEvent(events)
+	defer sub.Unsubscribe()
+
+	var txs []*types.Transaction
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, int64(i+1), key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		txs = append(txs, tx)
+	}
+	expect := func(want map[common.Hash]string) {
+		t.Helper()
+		if len(want) == 0 {
+			select {
+			case ev := <-events:
+				t.Fatalf("unexpected batch changes: %+v", ev.Changes)
+			default:
+				return
+			}
+		}
+		ev := <-events
+		if len(ev.Changes) != len(want) {
+			t.Fatalf("change count mismatch: have %d, want %d", len(ev.Changes), len(want))
+		}
+		for _, change := range ev.Changes {
+			if reason := want[change.Hash]; change.Reason != reason {
+				t.Errorf("change reason mismatch for %x: have %q, want %q", change.Hash, change.Reason, reason)
+			}
+		}
+	}
+	pool.prepareBatches()
+	expect(map[common.Hash]string{txs[0].Hash(): BatchChangeAdded, txs[1].Hash(): BatchChangeAdded, txs[2].Hash(): BatchChangeAdded})
+
+	// Rebuilding without any change keeps every transaction in its batch
+	pool.prepareBatches()
+	expect(nil)
+
+	// Removing a transaction from the pool and demoting another
+	pool.removeTx(txs[0].Hash(), true, true)
+	pool.demote(txs[1].Hash())
+	pool.prepareBatches()
+	expect(map[common.Hash]string{txs[0].Hash(): BatchChangeRemoved, txs[1].Hash(): BatchChangeInvalidated})
+}
+
+// Tests that batch rebuilds post the creation and invalidation of batches, and
+// that batches executed in between are not reported as invalidated.
+func TestBatchLifecycleEvents(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	all := make(map[common.Hash]*types.Transaction)
+	pool := &ParallelPool{
+		signer:            testSigner,
+		all:               all,
+		priced:            newParallelPricedList(all, nil),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		hintIndex:         make(map[DependencyHint][]common.Hash),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		executedBatches:   make(map[uint64]struct{}),
+		quarantine:        newQuarantine("", 0),
+		batchSize:         1,
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	events := make(chan BatchEvent, 16)
+	sub := pool.SubscribeBatchEvents(events)
+	defer sub.Unsubscribe()
+
+	var txs []*types.Transaction
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, int64(i+1), key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		txs = append(txs, tx)
+	}
+	expect := func(want map[common.Hash]string) {
+		t.Helper()
+		for i := 0; i < len(want); i++ {
+			ev := <-events
+			if len(ev.TxHashes) != 1 {
+				t.Fatalf("batch %d member count mismatch: have %d, want 1", ev.BatchID, len(ev.TxHashes))
+			}
+			if kind := want[ev.TxHashes[0]]; ev.Type != kind {
+				t.Errorf("event type mismatch for %x: have %q, want %q", ev.TxHashes[0], ev.Type, kind)
+			}
+		}
+		select {
+		case ev := <-events:
+			t.Fatalf("unexpected batch event: %+v", ev)
+		default:
+		}
+	}
+	batchOf := func(hash common.Hash) uint64 {
+		for _, batch := range pool.batchedTxs {
+			if batch.Transactions[0].Hash() == hash {
+				return batch.BatchID
+			}
+		}
+		t.Fatalf("transaction %x not batched", hash)
+		return 0
+	}
+	pool.prepareBatches()
+	expect(map[common.Hash]string{txs[0].Hash(): BatchCreated, txs[1].Hash(): BatchCreated, txs[2].Hash(): BatchCreated})
+
+	// Rebuilding without any change keeps every batch alive
+	pool.prepareBatches()
+	expect(nil)
+
+	// Dropping a transaction invalidates its batch, executing one does not
+	pool.executedBatches[batchOf(txs[1].Hash())] = struct{}{}
+	pool.removeTx(txs[0].Hash(), true, true)
+	pool.removeTx(txs[1].Hash(), true, true)
+	pool.prepareBatches()
+	expect(map[common.Hash]string{txs[0].Hash(): BatchInvalidated})
+
+	if len(pool.executedBatches) != 0 {
+		t.Errorf("executed batches not reset: %d left", len(pool.executedBatches))
+	}
+}
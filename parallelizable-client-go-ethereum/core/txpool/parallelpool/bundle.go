@@ -0,0 +1,156 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// maxBundleUserOps is the maximum number of user operations a bundle may declare
+// footprints for.
+const maxBundleUserOps = 256
+
+// handleOpsSelectors are the function selectors of the EntryPoint bundle entry
+// points, as of ERC-4337 v0.6 and v0.7.
+var handleOpsSelectors = [][]byte{
+	crypto.Keccak256([]byte("handleOps((address,uint256,bytes,bytes,uint256,uint256,uint256,uint256,uint256,bytes,bytes)[],address)"))[:4],
+	crypto.Keccak256([]byte("handleOps((address,uint256,bytes,bytes,bytes32,uint256,bytes32,bytes,bytes)[],address)"))[:4],
+}
+
+// ErrInvalidUserOpBundle is returned if a bundle declaration doesn't describe
+// the transaction it is submitted with.
+var ErrInvalidUserOpBundle = errors.New("invalid user operation bundle")
+
+// UserOpFootprint declares the state a user operation of a bundle accesses. The
+// write set lists the storage slots the operation modifies; an entry without
+// slots stands for a write to the account itself, such as a balance change.
+type UserOpFootprint struct {
+	Sender   common.Address   `json:"sender"`
+	ReadSet  types.AccessList `json:"readSet"`
+	WriteSet types.AccessList `json:"writeSet"`
+}
+
+// UserOpBundle describes an account abstraction bundle: an EntryPoint handleOps
+// call executing a number of user operations on behalf of their senders.
+type UserOpBundle struct {
+	EntryPoint  common.Address    `json:"entryPoint"`
+	Beneficiary common.Address    `json:"beneficiary"`
+	UserOps     []UserOpFootprint `json:"userOps"`
+}
+
+// validate checks that the bundle declaration describes the transaction.
+func (b *UserOpBundle) validate(tx *types.Transaction) error {
+	if tag, _ := TxTag(tx); tag != ParallelizableTag {
+		return fmt.Errorf("%w: transaction not parallelizable", ErrInvalidUserOpBundle)
+	}
+	if to := tx.To(); to == nil || *to != b.EntryPoint {
+		return fmt.Errorf("%w: transaction not sent to entry point %v", ErrInvalidUserOpBundle, b.EntryPoint)
+	}
+	data := tx.Data()
+	if len(data) < 4 {
+		return fmt.Errorf("%w: missing handleOps call", ErrInvalidUserOpBundle)
+	}
+	var known bool
+	for _, selector := range handleOpsSelectors {
+		if bytes.Equal(data[:4], selector) {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return fmt.Errorf("%w: unknown selector %x", ErrInvalidUserOpBundle, data[:4])
+	}
+	if len(b.UserOps) == 0 || len(b.UserOps) > maxBundleUserOps {
+		return fmt.Errorf("%w: %d user operations, want 1-%d", ErrInvalidUserOpBundle, len(b.UserOps), maxBundleUserOps)
+	}
+	return nil
+}
+
+// footprint assembles the read and write set of the bundle from the declared
+// user operation footprints. The EntryPoint is only recorded as read: deposits
+// and nonces it keeps for the senders are expected in the declared sets, so
+// that bundles of distinct senders don't all conflict on the EntryPoint.
+func (b *UserOpBundle) footprint(from common.Address) *rwSet {
+	set := &rwSet{
+		reads:    make(footprintSet),
+		writes:   make(footprintSet),
+		accounts: make(footprintSet),
+	}
+	// The bundler pays for the gas and the beneficiary collects the fees
+	for _, addr := range []common.Address{from, b.Beneficiary} {
+		set.writes.addAddress(addr)
+		set.accounts.addAddress(addr)
+	}
+	set.reads.addAddress(b.EntryPoint)
+
+	for _, op := range b.UserOps {
+		set.reads.addAddress(op.Sender)
+		for _, tuple := range op.ReadSet {
+			set.reads.addAddress(tuple.Address)
+			for _, slot := range tuple.StorageKeys {
+				set.reads.addSlot(tuple.Address, slot)
+			}
+		}
+		for _, tuple := range op.WriteSet {
+			set.writes.addAddress(tuple.Address)
+			if len(tuple.StorageKeys) == 0 {
+				set.accounts.addAddress(tuple.Address)
+			}
+			for _, slot := range tuple.StorageKeys {
+				set.writes.addSlot(tuple.Address, slot)
+			}
+		}
+	}
+	return set
+}
+
+// AddUserOpBundle adds an account abstraction bundle transaction to the pool,
+// along with the footprints of its user operations. The declared footprint is
+// used in place of simulating the transaction, so that bundles of independent
+// user operations can share a batch even though they call the same EntryPoint.
+func (p *ParallelPool) AddUserOpBundle(tx *types.Transaction, bundle *UserOpBundle) error {
+	if err := bundle.validate(tx); err != nil {
+		return err
+	}
+	hash := tx.Hash()
+
+	p.mu.Lock()
+	if p.all[hash] != nil {
+		p.mu.Unlock()
+		return txpool.ErrAlreadyKnown
+	}
+	p.bundles[hash] = bundle
+	p.mu.Unlock()
+
+	if err := p.addTxs([]*types.Transaction{tx}, true)[0]; err != nil {
+		p.mu.Lock()
+		if p.all[hash] == nil {
+			delete(p.bundles, hash)
+		}
+		p.mu.Unlock()
+		return err
+	}
+	p.metrics.bundles.Mark(1)
+	return nil
+}
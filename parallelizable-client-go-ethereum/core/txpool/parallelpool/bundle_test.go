@@ -0,0 +1,144 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that bundles of independent user operations share a batch despite
+// calling the same EntryPoint, that bundles declaring contended EntryPoint
+// state are kept apart, and that malformed declarations are rejected.
+func TestUserOpBundles(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	var (
+		signer     = types.LatestSigner(&config)
+		entryPoint = common.Address{0xe4}
+	)
+	newTx := func(key *ecdsa.PrivateKey, to common.Address, selector []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          100_000,
+			To:           &to,
+			Value:        common.Big0,
+			Data:         append(common.CopyBytes(selector), make([]byte, 64)...),
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	newBundle := func(sender common.Address, slot common.Hash) *UserOpBundle {
+		return &UserOpBundle{
+			EntryPoint:  entryPoint,
+			Beneficiary: sender,
+			UserOps: []UserOpFootprint{{
+				Sender:   sender,
+				WriteSet: types.AccessList{{Address: entryPoint, StorageKeys: []common.Hash{slot}}, {Address: sender}},
+			}},
+		}
+	}
+	// Malformed declarations are refused
+	valid := newTx(keys[0], entryPoint, handleOpsSelectors[0])
+	invalid := []struct {
+		tx     *types.Transaction
+		bundle *UserOpBundle
+	}{
+		{newTx(keys[0], common.Address{0xaa}, handleOpsSelectors[0]), newBundle(common.Address{0x01}, common.Hash{0x01})},
+		{newTx(keys[0], entryPoint, []byte{0xde, 0xad, 0xbe, 0xef}), newBundle(common.Address{0x01}, common.Hash{0x01})},
+		{valid, &UserOpBundle{EntryPoint: entryPoint}},
+	}
+	for i, tt := range invalid {
+		if err := pool.AddUserOpBundle(tt.tx, tt.bundle); !errors.Is(err, ErrInvalidUserOpBundle) {
+			t.Errorf("invalid bundle %d: error mismatch: have %v, want %v", i, err, ErrInvalidUserOpBundle)
+		}
+	}
+	// Bundles of distinct senders share a batch, unless they contend on a slot
+	txs := []*types.Transaction{
+		valid,
+		newTx(keys[1], entryPoint, handleOpsSelectors[1]),
+		newTx(keys[2], entryPoint, handleOpsSelectors[0]),
+	}
+	bundles := []*UserOpBundle{
+		newBundle(common.Address{0x01}, common.Hash{0x01}),
+		newBundle(common.Address{0x02}, common.Hash{0x02}),
+		newBundle(common.Address{0x03}, common.Hash{0x01}),
+	}
+	for i := range txs {
+		if err := pool.AddUserOpBundle(txs[i], bundles[i]); err != nil {
+			t.Fatalf("bundle %d refused: %v", i, err)
+		}
+	}
+	if have := pool.metrics.bundles.Snapshot().Count(); have != 3 {
+		t.Errorf("bundle meter mismatch: have %d, want 3", have)
+	}
+	batchOf := make(map[common.Hash]int)
+	for i, batch := range pool.GetBatches() {
+		for _, tx := range batch.Transactions {
+			batchOf[tx.Hash()] = i
+		}
+	}
+	for i, tx := range txs {
+		if _, ok := batchOf[tx.Hash()]; !ok {
+			t.Fatalf("bundle %d not batched", i)
+		}
+	}
+	if batchOf[txs[0].Hash()] == batchOf[txs[2].Hash()] {
+		t.Errorf("contending bundles share a batch")
+	}
+	if batchOf[txs[0].Hash()] != batchOf[txs[1].Hash()] && batchOf[txs[2].Hash()] != batchOf[txs[1].Hash()] {
+		t.Errorf("independent bundles batched apart")
+	}
+	// Dropping a bundle forgets its declaration
+	pool.mu.Lock()
+	pool.removeTx(txs[1].Hash(), true, true)
+	_, ok := pool.bundles[txs[1].Hash()]
+	pool.mu.Unlock()
+	if ok {
+		t.Errorf("declaration of dropped bundle retained")
+	}
+}
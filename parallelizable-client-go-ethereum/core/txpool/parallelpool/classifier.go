@@ -0,0 +1,212 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Selector is the 4-byte method identifier prefixed to contract calldata.
+type Selector [4]byte
+
+// NewSelector derives the selector of a method from its text signature, such as
+// "transfer(address,uint256)".
+func NewSelector(signature string) Selector {
+	var sel Selector
+	copy(sel[:], crypto.Keccak256([]byte(signature)))
+	return sel
+}
+
+// String implements fmt.Stringer, returning the 0x prefixed hex selector.
+func (s Selector) String() string {
+	return "0x" + hex.EncodeToString(s[:])
+}
+
+// ClassifierRule classifies the calls of a single contract method.
+type ClassifierRule struct {
+	Signature  string  // Text signature of the method, e.g. "transfer(address,uint256)"
+	Standard   string  // Standard or protocol the method belongs to, e.g. "ERC-20"
+	Words      int     // Minimum number of 32 byte argument words a well formed call carries
+	Parallel   bool    // Whether calls are expected to be parallelizable
+	Confidence float64 // Confidence in the classification, between 0 and 1
+	Reason     string  // Rationale behind the classification
+}
+
+// Classification is the parallelizability verdict for a transaction's calldata.
+type Classification struct {
+	Selector   string  `json:"selector,omitempty"` // Hex selector of the called method, if any
+	Method     string  `json:"method,omitempty"`   // Text signature of the called method, if known
+	Kind       string  `json:"kind"`               // Standard, protocol or kind of the interaction
+	Parallel   bool    `json:"parallel"`           // Whether the transaction is likely parallelizable
+	Confidence float64 `json:"confidence"`         // Confidence in the verdict, between 0 and 1
+	Reason     string  `json:"reason"`             // Rationale behind the verdict
+}
+
+// Confidence levels of the classifications not derived from rules.
+const (
+	transferConfidence = 0.9 // Plain value transfers, barring recipient contracts
+	fallbackConfidence = 0.6 // Calls too short to carry a selector, hitting fallbacks
+	knownConfidence    = 0.5 // Calls of methods only known by their signature
+	unknownConfidence  = 0.2 // Calls of unknown methods
+)
+
+// DefaultClassifierRules are the rules classifiers start out with: the token
+// standards, whose methods only touch the balances and allowances of the
+// accounts involved, and the common DEX routers, whose swaps all contend on the
+// reserves of shared pools.
+var DefaultClassifierRules = []ClassifierRule{
+	// ERC-20, and ERC-721 where the signatures coincide
+	{Signature: "transfer(address,uint256)", Standard: "ERC-20", Words: 2, Parallel: true, Confidence: 0.9, Reason: "Token transfers only touch the balances of the sender and the recipient"},
+	{Signature: "transferFrom(address,address,uint256)", Standard: "ERC-20/ERC-721", Words: 3, Parallel: true, Confidence: 0.8, Reason: "Delegated transfers only touch the balances and allowance of the accounts involved"},
+	{Signature: "approve(address,uint256)", Standard: "ERC-20/ERC-721", Words: 2, Parallel: true, Confidence: 0.9, Reason: "Approvals only touch the allowance of the owner"},
+	{Signature: "increaseAllowance(address,uint256)", Standard: "ERC-20", Words: 2, Parallel: true, Confidence: 0.9, Reason: "Allowance changes only touch the allowance of the owner"},
+	{Signature: "decreaseAllowance(address,uint256)", Standard: "ERC-20", Words: 2, Parallel: true, Confidence: 0.9, Reason: "Allowance changes only touch the allowance of the owner"},
+
+	// ERC-721
+	{Signature: "safeTransferFrom(address,address,uint256)", Standard: "ERC-721", Words: 3, Parallel: true, Confidence: 0.7, Reason: "Token transfers only touch the token, but notify recipient contracts"},
+	{Signature: "safeTransferFrom(address,address,uint256,bytes)", Standard: "ERC-721", Words: 4, Parallel: true, Confidence: 0.7, Reason: "Token transfers only touch the token, but notify recipient contracts"},
+	{Signature: "setApprovalForAll(address,bool)", Standard: "ERC-721/ERC-1155", Words: 2, Parallel: true, Confidence: 0.9, Reason: "Operator approvals only touch the approvals of the owner"},
+
+	// ERC-1155
+	{Signature: "safeTransferFrom(address,address,uint256,uint256,bytes)", Standard: "ERC-1155", Words: 5, Parallel: true, Confidence: 0.7, Reason: "Token transfers only touch the balances involved, but notify recipient contracts"},
+	{Signature: "safeBatchTransferFrom(address,address,uint256[],uint256[],bytes)", Standard: "ERC-1155", Words: 5, Parallel: true, Confidence: 0.6, Reason: "Batch transfers only touch the balances involved, but notify recipient contracts"},
+
+	// Uniswap V2 style routers
+	{Signature: "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)", Standard: "DEX router", Words: 5, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "swapTokensForExactTokens(uint256,uint256,address[],address,uint256)", Standard: "DEX router", Words: 5, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "swapExactETHForTokens(uint256,address[],address,uint256)", Standard: "DEX router", Words: 4, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "swapETHForExactTokens(uint256,address[],address,uint256)", Standard: "DEX router", Words: 4, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "swapExactTokensForETH(uint256,uint256,address[],address,uint256)", Standard: "DEX router", Words: 5, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "swapTokensForExactETH(uint256,uint256,address[],address,uint256)", Standard: "DEX router", Words: 5, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "addLiquidity(address,address,uint256,uint256,uint256,uint256,address,uint256)", Standard: "DEX router", Words: 8, Confidence: 0.9, Reason: "Liquidity changes contend on the reserves of shared pools"},
+	{Signature: "addLiquidityETH(address,uint256,uint256,uint256,address,uint256)", Standard: "DEX router", Words: 6, Confidence: 0.9, Reason: "Liquidity changes contend on the reserves of shared pools"},
+	{Signature: "removeLiquidity(address,address,uint256,uint256,uint256,address,uint256)", Standard: "DEX router", Words: 7, Confidence: 0.9, Reason: "Liquidity changes contend on the reserves of shared pools"},
+	{Signature: "removeLiquidityETH(address,uint256,uint256,uint256,address,uint256)", Standard: "DEX router", Words: 6, Confidence: 0.9, Reason: "Liquidity changes contend on the reserves of shared pools"},
+
+	// Uniswap V3 style routers
+	{Signature: "exactInputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))", Standard: "DEX router", Words: 8, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "exactOutputSingle((address,address,uint24,address,uint256,uint256,uint256,uint160))", Standard: "DEX router", Words: 8, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "exactInput((bytes,address,uint256,uint256,uint256))", Standard: "DEX router", Words: 1, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "exactOutput((bytes,address,uint256,uint256,uint256))", Standard: "DEX router", Words: 1, Confidence: 0.9, Reason: "Swaps contend on the reserves of shared pools"},
+	{Signature: "multicall(bytes[])", Standard: "DEX router", Words: 1, Confidence: 0.8, Reason: "Router multicalls bundle swaps contending on shared pools"},
+	{Signature: "multicall(uint256,bytes[])", Standard: "DEX router", Words: 2, Confidence: 0.8, Reason: "Router multicalls bundle swaps contending on shared pools"},
+	{Signature: "execute(bytes,bytes[],uint256)", Standard: "DEX router", Words: 3, Confidence: 0.8, Reason: "Universal router commands bundle swaps contending on shared pools"},
+	{Signature: "execute(bytes,bytes[])", Standard: "DEX router", Words: 2, Confidence: 0.8, Reason: "Universal router commands bundle swaps contending on shared pools"},
+}
+
+// Classifier statically classifies transactions as parallelizable or not by the
+// method their calldata calls, without executing them. Methods are classified
+// by a registry of rules, and methods without a rule may still be named through
+// a 4-byte signature database.
+type Classifier struct {
+	rules      map[Selector]ClassifierRule
+	signatures map[Selector]string // Text signatures of methods without rules
+	mu         sync.RWMutex
+}
+
+// NewClassifier creates a classifier with the default rules.
+func NewClassifier() *Classifier {
+	c := &Classifier{
+		rules:      make(map[Selector]ClassifierRule),
+		signatures: make(map[Selector]string),
+	}
+	for _, rule := range DefaultClassifierRules {
+		c.Register(rule)
+	}
+	return c
+}
+
+// Register adds a rule to the classifier, replacing any rule of the same
+// method.
+func (c *Classifier) Register(rule ClassifierRule) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.rules[NewSelector(rule.Signature)] = rule
+}
+
+// LoadSignatures loads a 4-byte signature database from a JSON file mapping hex
+// selectors to text signatures, such as {"a9059cbb": "transfer(address,uint256)"}.
+// The loaded methods are named in classifications, but not classified by a rule.
+// The number of signatures loaded is returned.
+func (c *Classifier) LoadSignatures(path string) (int, error) {
+	blob, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var db map[string]string
+	if err := json.Unmarshal(blob, &db); err != nil {
+		return 0, err
+	}
+	signatures := make(map[Selector]string, len(db))
+	for key, signature := range db {
+		raw, err := hex.DecodeString(strings.TrimPrefix(key, "0x"))
+		if err != nil || len(raw) != len(Selector{}) {
+			return 0, fmt.Errorf("invalid selector %q", key)
+		}
+		signatures[Selector(raw)] = signature
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for sel, signature := range signatures {
+		c.signatures[sel] = signature
+	}
+	return len(signatures), nil
+}
+
+// Classify classifies a contract call or transfer by its calldata.
+func (c *Classifier) Classify(data []byte) *Classification {
+	switch {
+	case len(data) == 0:
+		return &Classification{Kind: "ETH transfer", Parallel: true, Confidence: transferConfidence, Reason: "Plain transfers only touch the balances of the sender and the recipient"}
+	case len(data) < len(Selector{}):
+		return &Classification{Kind: "Fallback call", Parallel: true, Confidence: fallbackConfidence, Reason: "Calldata too short for a method call, likely a transfer hitting a fallback"}
+	}
+	sel := Selector(data[:len(Selector{})])
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if rule, ok := c.rules[sel]; ok {
+		result := &Classification{
+			Selector:   sel.String(),
+			Method:     rule.Signature,
+			Kind:       rule.Standard,
+			Parallel:   rule.Parallel,
+			Confidence: rule.Confidence,
+			Reason:     rule.Reason,
+		}
+		// Malformed calls likely revert or hit another method, trust them less
+		if len(data) < len(Selector{})+32*rule.Words {
+			result.Confidence /= 2
+			result.Reason += ", although the calldata is too short for the method"
+		}
+		return result
+	}
+	if signature, ok := c.signatures[sel]; ok {
+		return &Classification{Selector: sel.String(), Method: signature, Kind: "Known method", Confidence: knownConfidence, Reason: "Method has no parallelizability rule, treated as sequential for safety"}
+	}
+	return &Classification{Selector: sel.String(), Kind: "Unknown contract interaction", Confidence: unknownConfidence, Reason: "Contract interactions with unknown methods are treated as sequential for safety"}
+}
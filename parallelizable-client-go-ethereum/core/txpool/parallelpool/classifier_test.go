@@ -0,0 +1,138 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that calldata is classified by the method rules, falling back to the
+// signature database and to sequential execution for unknown methods.
+func TestClassifier(t *testing.T) {
+	c := NewClassifier()
+
+	// call assembles calldata of the given selector and number of argument words
+	call := func(selector string, words int) []byte {
+		return append(common.FromHex(selector), make([]byte, 32*words)...)
+	}
+	tests := []struct {
+		data       []byte
+		method     string
+		parallel   bool
+		confidence float64
+	}{
+		{nil, "", true, transferConfidence},
+		{[]byte{0x01}, "", true, fallbackConfidence},
+		{call("0xa9059cbb", 2), "transfer(address,uint256)", true, 0},
+		{call("0xa9059cbb", 1), "transfer(address,uint256)", true, 0},
+		{call("0x095ea7b3", 2), "approve(address,uint256)", true, 0},
+		{call("0x38ed1739", 5), "swapExactTokensForTokens(uint256,uint256,address[],address,uint256)", false, 0},
+		{call("0xdeadbeef", 1), "", false, unknownConfidence},
+	}
+	for i, tt := range tests {
+		class := c.Classify(tt.data)
+		if class.Method != tt.method || class.Parallel != tt.parallel {
+			t.Errorf("test %d: classification mismatch: have %q parallel %v, want %q parallel %v", i, class.Method, class.Parallel, tt.method, tt.parallel)
+		}
+		if tt.confidence != 0 && class.Confidence != tt.confidence {
+			t.Errorf("test %d: confidence mismatch: have %v, want %v", i, class.Confidence, tt.confidence)
+		}
+	}
+	// Truncated calls are trusted less than well formed ones
+	if full, short := c.Classify(call("0xa9059cbb", 2)), c.Classify(call("0xa9059cbb", 1)); short.Confidence >= full.Confidence {
+		t.Errorf("truncated call confidence %v not below well formed %v", short.Confidence, full.Confidence)
+	}
+	// Methods named by the signature database stay sequential
+	path := filepath.Join(t.TempDir(), "signatures.json")
+	if err := os.WriteFile(path, []byte(`{"0xdeadbeef": "frob(uint256)", "cafebabe": "nudge()"}`), 0600); err != nil {
+		t.Fatalf("failed to write signature database: %v", err)
+	}
+	if n, err := c.LoadSignatures(path); err != nil || n != 2 {
+		t.Fatalf("signature loading mismatch: have %d (%v), want 2", n, err)
+	}
+	if class := c.Classify(call("0xdeadbeef", 1)); class.Method != "frob(uint256)" || class.Parallel || class.Confidence != knownConfidence {
+		t.Errorf("known method classification mismatch: %+v", class)
+	}
+	if err := os.WriteFile(path, []byte(`{"0xdead": "short()"}`), 0600); err != nil {
+		t.Fatalf("failed to write signature database: %v", err)
+	}
+	if _, err := c.LoadSignatures(path); err == nil {
+		t.Errorf("malformed selector accepted")
+	}
+	// Registered rules replace the defaults
+	c.Register(ClassifierRule{Signature: "transfer(address,uint256)", Standard: "Custom", Words: 2, Confidence: 0.7})
+	if class := c.Classify(call(NewSelector("transfer(address,uint256)").String(), 2)); class.Kind != "Custom" || class.Parallel {
+		t.Errorf("registered rule classification mismatch: %+v", class)
+	}
+}
+
+// Tests that transactions tagged without an explicit verdict are classified by
+// their calldata, contract creations staying sequential.
+func TestTagTransactionClassified(t *testing.T) {
+	registry := metrics.NewRegistry()
+	pool := &ParallelPool{
+		config:      Config{MetricsRegistry: registry},
+		chainconfig: params.TestChainConfig,
+		gasPrice:    common.Big1,
+		metrics:     newPoolMetrics("", registry),
+		classifier:  NewClassifier(),
+	}
+	api := NewParallelTxPoolAPI(pool)
+
+	var (
+		to       = common.Address{0xaa}
+		nonce    = hexutil.Uint64(0)
+		parallel = false
+	)
+	tests := []struct {
+		to       *common.Address
+		data     []byte
+		parallel *bool
+		want     uint8
+	}{
+		{&to, nil, nil, types.ParallelTypeIndependent},
+		{&to, common.FromHex("0xa9059cbb"), nil, types.ParallelTypeIndependent},
+		{&to, common.FromHex("0x38ed1739"), nil, types.ParallelTypeSequential},
+		{&to, nil, &parallel, types.ParallelTypeSequential},
+		{nil, nil, nil, types.ParallelTypeSequential},
+	}
+	for i, tt := range tests {
+		raw, err := api.TagTransaction(context.Background(), TagTransactionRequest{To: tt.to, Data: tt.data, Nonce: &nonce, Parallel: tt.parallel})
+		if err != nil {
+			t.Fatalf("test %d: failed to tag transaction: %v", i, err)
+		}
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(raw); err != nil {
+			t.Fatalf("test %d: failed to decode transaction: %v", i, err)
+		}
+		if tx.ParallelType() != tt.want {
+			t.Errorf("test %d: parallel type mismatch: have %d, want %d", i, tx.ParallelType(), tt.want)
+		}
+	}
+	if have := pool.metrics.tagClassified.Snapshot().Count(); have != 3 {
+		t.Errorf("classified tag count mismatch: have %d, want 3", have)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// rwSet is the read and write set of a pooled parallel transaction, recorded by
+// simulating it at admission. Transient storage is left out, as it does not
+// outlive the transaction.
+type rwSet struct {
+	reads    footprintSet // Accessed accounts and read storage slots
+	writes   footprintSet // Written accounts and storage slots
+	accounts footprintSet // Accounts whose balance, nonce or code was written
+}
+
+// conflict looks for state written by either of two transactions and accessed
+// by the other. It returns the contended account and, unless the conflict is on
+// the account itself, the contended storage slot.
+func (s *rwSet) conflict(other *rwSet) (common.Address, *common.Hash, bool) {
+	if addr, slot, ok := s.writeConflict(other); ok {
+		return addr, slot, ok
+	}
+	return other.writeConflict(s)
+}
+
+// writeConflict looks for state written by s and accessed by other. Writes to
+// an account's balance, nonce or code conservatively conflict with any access
+// to the account.
+func (s *rwSet) writeConflict(other *rwSet) (common.Address, *common.Hash, bool) {
+	for addr := range s.accounts {
+		if _, ok := other.reads[addr]; ok {
+			return addr, nil, true
+		}
+		if _, ok := other.writes[addr]; ok {
+			return addr, nil, true
+		}
+	}
+	for addr, slots := range s.writes {
+		for slot := range slots {
+			if _, ok := other.reads[addr][slot]; ok {
+				return addr, &slot, true
+			}
+			if _, ok := other.writes[addr][slot]; ok {
+				return addr, &slot, true
+			}
+		}
+	}
+	return common.Address{}, nil, false
+}
+
+// detectConflicts simulates the transaction on top of the current state and
+// records its read and write set. It returns reports against the pooled
+// transactions whose sets intersect with it, which must not share a batch with
+// it. If the simulation fails, nothing is recorded and false is returned: the
+// footprint of the transaction remains unknown.
+//
+// The caller must hold the pool lock.
+func (p *ParallelPool) detectConflicts(tx *types.Transaction) ([]ConflictReport, bool) {
+	adapter := core.NewPendingExecAdapter(p.chainconfig, p.chain, p.currentHead)
+	msg, err := adapter.Message(tx)
+	if err != nil {
+		return nil, false
+	}
+	// Simulate transaction execution on a throwaway copy of the state
+	tracer, _, err := p.traceMessage(adapter, p.currentState.Copy(), msg, false)
+	if err != nil {
+		return nil, false
+	}
+	set := &rwSet{reads: tracer.reads, writes: tracer.writes, accounts: tracer.accounts}
+	return p.recordFootprint(tx.Hash(), set), true
+}
+
+// recordFootprint records the read and write set of a transaction and returns
+// reports against the pooled transactions whose sets intersect with it. The
+// caller must hold the pool lock.
+func (p *ParallelPool) recordFootprint(txHash common.Hash, set *rwSet) []ConflictReport {
+	p.batchMu.Lock()
+	p.footprints[txHash] = set
+	p.batchMu.Unlock()
+
+	// Compare the footprint with those of the pooled transactions
+	var conflicts []ConflictReport
+	for hash, other := range p.footprints {
+		if hash == txHash {
+			continue
+		}
+		if addr, slot, ok := set.conflict(other); ok {
+			conflicts = append(conflicts, ConflictReport{
+				Action:       ConflictReordered,
+				Counterparty: hash,
+				Address:      &addr,
+				Slot:         slot,
+			})
+		}
+	}
+	return conflicts
+}
+
+// conflictsWith reports whether a transaction conflicts with any of the given
+// batch members. Transactions without recorded footprints never conflict, they
+// are isolated into batches of their own instead. The caller must hold the
+// batch lock.
+func (p *ParallelPool) conflictsWith(tx *types.Transaction, members []*types.Transaction) bool {
+	set := p.footprints[tx.Hash()]
+	if set == nil {
+		return false
+	}
+	for _, member := range members {
+		if other := p.footprints[member.Hash()]; other != nil {
+			if _, _, ok := set.conflict(other); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
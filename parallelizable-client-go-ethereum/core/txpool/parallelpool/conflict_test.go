@@ -0,0 +1,199 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// newTestRWSet creates a read and write set from the given slot accesses and
+// account writes.
+func newTestRWSet(reads, writes map[common.Address][]common.Hash, accounts ...common.Address) *rwSet {
+	set := &rwSet{reads: make(footprintSet), writes: make(footprintSet), accounts: make(footprintSet)}
+	for addr, slots := range reads {
+		set.reads.addAddress(addr)
+		for _, slot := range slots {
+			set.reads.addSlot(addr, slot)
+		}
+	}
+	for addr, slots := range writes {
+		set.writes.addAddress(addr)
+		for _, slot := range slots {
+			set.writes.addSlot(addr, slot)
+		}
+	}
+	for _, addr := range accounts {
+		set.writes.addAddress(addr)
+		set.accounts.addAddress(addr)
+	}
+	return set
+}
+
+// Tests that read and write sets only conflict if one writes state the other
+// accesses, shared reads being harmless.
+func TestRWSetConflict(t *testing.T) {
+	var (
+		token  = common.Address{0x01}
+		sender = common.Address{0x02}
+		slotA  = common.Hash{0x0a}
+		slotB  = common.Hash{0x0b}
+	)
+	tests := []struct {
+		a, b     *rwSet
+		conflict bool
+		slot     *common.Hash
+	}{
+		// Shared reads
+		{newTestRWSet(map[common.Address][]common.Hash{token: {slotA}}, nil), newTestRWSet(map[common.Address][]common.Hash{token: {slotA}}, nil), false, nil},
+		// Disjoint writes to the same contract
+		{newTestRWSet(nil, map[common.Address][]common.Hash{token: {slotA}}), newTestRWSet(nil, map[common.Address][]common.Hash{token: {slotB}}), false, nil},
+		// Write-read on a slot, in both directions
+		{newTestRWSet(nil, map[common.Address][]common.Hash{token: {slotA}}), newTestRWSet(map[common.Address][]common.Hash{token: {slotA}}, nil), true, &slotA},
+		{newTestRWSet(map[common.Address][]common.Hash{token: {slotA}}, nil), newTestRWSet(nil, map[common.Address][]common.Hash{token: {slotA}}), true, &slotA},
+		// Write-write on a slot
+		{newTestRWSet(nil, map[common.Address][]common.Hash{token: {slotB}}), newTestRWSet(nil, map[common.Address][]common.Hash{token: {slotB}}), true, &slotB},
+		// Account write against an account access
+		{newTestRWSet(nil, nil, sender), newTestRWSet(map[common.Address][]common.Hash{sender: nil}, nil), true, nil},
+		{newTestRWSet(nil, nil, sender), newTestRWSet(nil, nil, token), false, nil},
+	}
+	for i, tt := range tests {
+		_, slot, ok := tt.a.conflict(tt.b)
+		if ok != tt.conflict {
+			t.Errorf("test %d: conflict mismatch: have %v, want %v", i, ok, tt.conflict)
+			continue
+		}
+		if (slot == nil) != (tt.slot == nil) || (slot != nil && *slot != *tt.slot) {
+			t.Errorf("test %d: contended slot mismatch: have %v, want %v", i, slot, tt.slot)
+		}
+	}
+}
+
+// Tests that conflicting transactions are never placed into the same batch,
+// while non-conflicting ones still share batches.
+func TestBatchConflictSeparation(t *testing.T) {
+	var (
+		token = common.Address{0x01}
+		slot  = common.Hash{0x0a}
+		pool  = &ParallelPool{
+			signer:            testSigner,
+			all:               make(map[common.Hash]*types.Transaction),
+			parallelizableTxs: make(map[common.Address][]*types.Transaction),
+			footprints:        make(map[common.Hash]*rwSet),
+			quarantine:        newQuarantine("", 0),
+			batchSize:         DefaultBatchSize,
+			metrics:           newPoolMetrics("", metrics.NewRegistry()),
+		}
+		txs []*types.Transaction
+	)
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, int64(3-i), key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		txs = append(txs, tx)
+	}
+	// The first two transactions write the same slot, the third only reads
+	// another one
+	pool.footprints[txs[0].Hash()] = newTestRWSet(nil, map[common.Address][]common.Hash{token: {slot}})
+	pool.footprints[txs[1].Hash()] = newTestRWSet(nil, map[common.Address][]common.Hash{token: {slot}})
+	pool.footprints[txs[2].Hash()] = newTestRWSet(map[common.Address][]common.Hash{token: {{0x0b}}}, nil)
+
+	pool.buildBatches()
+	if len(pool.batchedTxs) != 2 {
+		t.Fatalf("batch count mismatch: have %d, want 2", len(pool.batchedTxs))
+	}
+	batchOf := make(map[common.Hash]int)
+	for i, batch := range pool.batchedTxs {
+		for _, tx := range batch.Transactions {
+			batchOf[tx.Hash()] = i
+		}
+	}
+	if batchOf[txs[0].Hash()] == batchOf[txs[1].Hash()] {
+		t.Errorf("conflicting transactions share a batch")
+	}
+	if batchOf[txs[0].Hash()] != batchOf[txs[2].Hash()] {
+		t.Errorf("non-conflicting transaction not batched by price priority")
+	}
+}
+
+// Tests that the conflict matrix lists every conflicting pair of the ready set
+// once, with the contended slot, and flags transactions of unknown footprint.
+func TestConflictMatrix(t *testing.T) {
+	var (
+		token = common.Address{0x01}
+		slot  = common.Hash{0x0a}
+		pool  = &ParallelPool{
+			signer:            testSigner,
+			parallelizableTxs: make(map[common.Address][]*types.Transaction),
+			footprints:        make(map[common.Hash]*rwSet),
+			quarantine:        newQuarantine("", 0),
+		}
+		txs []*types.Transaction
+	)
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, 1, key)
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		txs = append(txs, tx)
+	}
+	// The first two transactions write the same slot, the third reads it and
+	// the footprint of the last one is unknown
+	pool.footprints[txs[0].Hash()] = newTestRWSet(nil, map[common.Address][]common.Hash{token: {slot}})
+	pool.footprints[txs[1].Hash()] = newTestRWSet(nil, map[common.Address][]common.Hash{token: {slot}})
+	pool.footprints[txs[2].Hash()] = newTestRWSet(map[common.Address][]common.Hash{token: {slot}}, nil)
+
+	matrix := pool.ConflictMatrix()
+	if len(matrix.Txs) != len(txs) {
+		t.Fatalf("ready set size mismatch: have %d, want %d", len(matrix.Txs), len(txs))
+	}
+	index := make(map[common.Hash]int)
+	for i, hash := range matrix.Txs {
+		index[hash] = i
+	}
+	if len(matrix.Unknown) != 1 || matrix.Unknown[0] != index[txs[3].Hash()] {
+		t.Errorf("unknown footprints mismatch: have %v, want [%d]", matrix.Unknown, index[txs[3].Hash()])
+	}
+	pairs := make(map[[2]int]bool)
+	for _, edge := range matrix.Edges {
+		if edge.From >= edge.To {
+			t.Errorf("edge %d-%d not ordered", edge.From, edge.To)
+		}
+		if edge.Address != token || edge.Slot == nil || *edge.Slot != slot {
+			t.Errorf("edge %d-%d contention mismatch: have %x/%v", edge.From, edge.To, edge.Address, edge.Slot)
+		}
+		pairs[[2]int{edge.From, edge.To}] = true
+	}
+	for _, pair := range [][2]*types.Transaction{{txs[0], txs[1]}, {txs[0], txs[2]}, {txs[1], txs[2]}} {
+		a, b := index[pair[0].Hash()], index[pair[1].Hash()]
+		if !pairs[[2]int{min(a, b), max(a, b)}] {
+			t.Errorf("missing conflict between %d and %d", a, b)
+		}
+	}
+	if len(matrix.Edges) != 3 {
+		t.Errorf("edge count mismatch: have %d, want 3", len(matrix.Edges))
+	}
+	// Quarantined transactions are left out of the ready set
+	pool.quarantine.recordPanic(txs[0], &ExecutionPanic{Tx: txs[0].Hash(), Value: "test"})
+	if matrix := pool.ConflictMatrix(); len(matrix.Txs) != 3 || len(matrix.Edges) != 1 {
+		t.Errorf("quarantined matrix mismatch: have %d txs and %d edges, want 3 and 1", len(matrix.Txs), len(matrix.Edges))
+	}
+}
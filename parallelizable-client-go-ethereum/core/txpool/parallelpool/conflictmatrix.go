@@ -0,0 +1,90 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// ConflictEdge is a conflict between two transactions of a conflict matrix,
+// referenced by their index, on an account or one of its storage slots.
+type ConflictEdge struct {
+	From    int            `json:"from"` // Index of the lower hash transaction
+	To      int            `json:"to"`   // Index of the higher hash transaction
+	Address common.Address `json:"address"`
+	Slot    *common.Hash   `json:"slot,omitempty"` // Contended slot, unset for conflicts on the account itself
+}
+
+// ConflictMatrix is the conflict graph of the transactions ready for batching,
+// as a compressed adjacency list. Transactions are referenced by their index in
+// Txs, sorted by hash, and each conflicting pair is listed once. Transactions
+// with an unknown footprint can't be checked for conflicts and are batched on
+// their own instead.
+type ConflictMatrix struct {
+	Txs     []common.Hash  `json:"txs"`
+	Unknown []int          `json:"unknown"` // Indices of the transactions with an unknown footprint
+	Edges   []ConflictEdge `json:"edges"`
+}
+
+// ConflictMatrix computes the conflict graph of the parallelizable transactions
+// awaiting a batch, leaving out the quarantined ones, from their recorded read
+// and write sets.
+func (p *ParallelPool) ConflictMatrix() *ConflictMatrix {
+	p.batchMu.RLock()
+	defer p.batchMu.RUnlock()
+
+	var ready []common.Hash
+	for _, txs := range p.parallelizableTxs {
+		for _, tx := range txs {
+			if !p.quarantine.contains(tx.Hash()) {
+				ready = append(ready, tx.Hash())
+			}
+		}
+	}
+	slices.SortFunc(ready, func(a, b common.Hash) int { return a.Cmp(b) })
+
+	matrix := &ConflictMatrix{
+		Txs:     ready,
+		Unknown: []int{},
+		Edges:   []ConflictEdge{},
+	}
+	if matrix.Txs == nil {
+		matrix.Txs = []common.Hash{}
+	}
+	sets := make([]*rwSet, len(ready))
+	for i, hash := range ready {
+		if sets[i] = p.footprints[hash]; sets[i] == nil {
+			matrix.Unknown = append(matrix.Unknown, i)
+		}
+	}
+	for i := range sets {
+		if sets[i] == nil {
+			continue
+		}
+		for j := i + 1; j < len(sets); j++ {
+			if sets[j] == nil {
+				continue
+			}
+			if addr, slot, ok := sets[i].conflict(sets[j]); ok {
+				matrix.Edges = append(matrix.Edges, ConflictEdge{From: i, To: j, Address: addr, Slot: slot})
+			}
+		}
+	}
+	return matrix
+}
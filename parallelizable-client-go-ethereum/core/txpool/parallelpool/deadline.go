@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// deadlineUrgencyBlocks is the number of blocks before its deadline from
+	// which a transaction is prioritised by the batcher.
+	deadlineUrgencyBlocks = 2
+
+	// deadlineUrgencyTime is the time before its deadline from which a
+	// transaction is prioritised by the batcher.
+	deadlineUrgencyTime = 24 * time.Second
+)
+
+var (
+	// ErrUnknownTransaction is returned if an operation targets a transaction
+	// that is not in the pool.
+	ErrUnknownTransaction = errors.New("unknown transaction")
+
+	// ErrInvalidDeadline is returned if an inclusion deadline sets neither a
+	// block nor a time bound.
+	ErrInvalidDeadline = errors.New("deadline needs a block or time bound")
+)
+
+// InclusionDeadline is a soft deadline for the inclusion of a pooled transaction.
+// Transactions nearing it are prioritised by the batcher, transactions missing
+// it are dropped. Zero bounds are unset.
+type InclusionDeadline struct {
+	Block uint64 `json:"block"` // Last block the transaction should be included in
+	Time  uint64 `json:"time"`  // Unix time the transaction should be included by
+}
+
+// expired reports whether the deadline passed, given the current head.
+func (d InclusionDeadline) expired(head *types.Header, now time.Time) bool {
+	if d.Block != 0 && head.Number.Uint64() >= d.Block {
+		return true
+	}
+	return d.Time != 0 && uint64(now.Unix()) >= d.Time
+}
+
+// urgent reports whether the deadline is close, given the current head.
+func (d InclusionDeadline) urgent(head *types.Header, now time.Time) bool {
+	if d.Block != 0 && head.Number.Uint64()+deadlineUrgencyBlocks >= d.Block {
+		return true
+	}
+	return d.Time != 0 && uint64(now.Add(deadlineUrgencyTime).Unix()) >= d.Time
+}
+
+// DeadlineDropEvent is posted when transactions are dropped from the pool for
+// missing their inclusion deadline.
+type DeadlineDropEvent struct {
+	Txs []*types.Transaction
+}
+
+// SetInclusionDeadline attaches a soft inclusion deadline to a pooled
+// transaction, relative to the current head: the transaction should be included
+// within the given number of blocks and/or seconds. Zero leaves a bound unset.
+func (p *ParallelPool) SetInclusionDeadline(hash common.Hash, blocks, seconds uint64) (InclusionDeadline, error) {
+	if blocks == 0 && seconds == 0 {
+		return InclusionDeadline{}, ErrInvalidDeadline
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.all[hash] == nil {
+		return InclusionDeadline{}, ErrUnknownTransaction
+	}
+	var deadline InclusionDeadline
+	if blocks != 0 {
+		deadline.Block = p.currentHead.Number.Uint64() + blocks
+	}
+	if seconds != 0 {
+		deadline.Time = uint64(time.Now().Unix()) + seconds
+	}
+	p.batchMu.Lock()
+	p.deadlines[hash] = deadline
+	p.batchMu.Unlock()
+
+	// The transaction may already be urgent, reprioritise the batches
+	p.prepareBatches()
+	return deadline, nil
+}
+
+// SubscribeDeadlineDropEvent registers a subscription for transactions dropped
+// for missing their inclusion deadline.
+func (p *ParallelPool) SubscribeDeadlineDropEvent(ch chan<- DeadlineDropEvent) event.Subscription {
+	return p.scope.Track(p.deadlineFeed.Subscribe(ch))
+}
+
+// expireDeadlines drops the transactions whose inclusion deadline passed as of
+// the given head, posting a drop event. The caller must hold the pool lock.
+func (p *ParallelPool) expireDeadlines(head *types.Header) {
+	now := time.Now()
+
+	p.batchMu.RLock()
+	var expired []common.Hash
+	for hash, deadline := range p.deadlines {
+		if deadline.expired(head, now) {
+			expired = append(expired, hash)
+		}
+	}
+	p.batchMu.RUnlock()
+
+	var dropped []*types.Transaction
+	for _, hash := range expired {
+		if tx := p.all[hash]; tx != nil {
+			dropped = append(dropped, tx)
+			p.dropTx(hash, DropDeadline)
+		}
+	}
+	if len(dropped) == 0 {
+		return
+	}
+	log.Debug("Dropped parallel transactions past their inclusion deadline", "count", len(dropped), "number", head.Number)
+	p.metrics.deadlineExpired.Mark(int64(len(dropped)))
+	p.deadlineFeed.Send(DeadlineDropEvent{Txs: dropped})
+}
+
+// prioritize orders transactions for batch assignment: those nearing their
+// inclusion deadline first, then the rest, each by price. The caller must hold
+// the batch lock.
+func (p *ParallelPool) prioritize(txs []*types.Transaction) []*types.Transaction {
+	if len(p.deadlines) == 0 {
+		return orderTransactions(p.signer, txs, OrderByPrice, p.arrivals)
+	}
+	var (
+		now          = time.Now()
+		urgent, rest []*types.Transaction
+	)
+	for _, tx := range txs {
+		if deadline, ok := p.deadlines[tx.Hash()]; ok && deadline.urgent(p.currentHead, now) {
+			urgent = append(urgent, tx)
+		} else {
+			rest = append(rest, tx)
+		}
+	}
+	return append(orderTransactions(p.signer, urgent, OrderByPrice, p.arrivals), orderTransactions(p.signer, rest, OrderByPrice, p.arrivals)...)
+}
@@ -0,0 +1,105 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that transactions nearing their inclusion deadline are batched ahead of
+// better paying ones, and are dropped with an event once the deadline passed.
+func TestInclusionDeadline(t *testing.T) {
+	all := make(map[common.Hash]*types.Transaction)
+	pool := &ParallelPool{
+		signer:            testSigner,
+		currentHead:       &types.Header{Number: big.NewInt(10)},
+		all:               all,
+		priced:            newParallelPricedList(all, nil),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		hintIndex:         make(map[DependencyHint][]common.Hash),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		deadlines:         make(map[common.Hash]InclusionDeadline),
+		quarantine:        newQuarantine("", 0),
+		batchSize:         1,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	var txs []*types.Transaction
+	for i := 0; i < 3; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, int64(3-i), key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		txs = append(txs, tx)
+	}
+	if _, err := pool.SetInclusionDeadline(txs[0].Hash(), 0, 0); !errors.Is(err, ErrInvalidDeadline) {
+		t.Fatalf("empty deadline error mismatch: have %v, want %v", err, ErrInvalidDeadline)
+	}
+	if _, err := pool.SetInclusionDeadline(common.Hash{0x01}, 1, 0); !errors.Is(err, ErrUnknownTransaction) {
+		t.Fatalf("unknown transaction error mismatch: have %v, want %v", err, ErrUnknownTransaction)
+	}
+	// A distant deadline leaves the price order untouched, a close one moves the
+	// cheapest transaction to the first batch
+	if _, err := pool.SetInclusionDeadline(txs[1].Hash(), 100, 0); err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	deadline, err := pool.SetInclusionDeadline(txs[2].Hash(), deadlineUrgencyBlocks, 0)
+	if err != nil {
+		t.Fatalf("failed to set deadline: %v", err)
+	}
+	if deadline.Block != 10+deadlineUrgencyBlocks || deadline.Time != 0 {
+		t.Fatalf("deadline mismatch: have %+v", deadline)
+	}
+	batches := pool.GetBatches()
+	if len(batches) != 3 {
+		t.Fatalf("batch count mismatch: have %d, want 3", len(batches))
+	}
+	for i, want := range []*types.Transaction{txs[2], txs[0], txs[1]} {
+		if have := batches[i].Transactions[0]; have != want {
+			t.Errorf("batch %d member mismatch: have %x, want %x", i, have.Hash(), want.Hash())
+		}
+	}
+	// Moving past the deadline block drops the urgent transaction only
+	events := make(chan DeadlineDropEvent, 1)
+	sub := pool.SubscribeDeadlineDropEvent(events)
+	defer sub.Unsubscribe()
+
+	pool.expireDeadlines(&types.Header{Number: big.NewInt(int64(deadline.Block))})
+	select {
+	case ev := <-events:
+		if len(ev.Txs) != 1 || ev.Txs[0] != txs[2] {
+			t.Fatalf("dropped transactions mismatch: have %d", len(ev.Txs))
+		}
+	default:
+		t.Fatalf("no drop event posted")
+	}
+	if pool.all[txs[2].Hash()] != nil || pool.all[txs[1].Hash()] == nil {
+		t.Errorf("wrong transactions dropped")
+	}
+	if _, ok := pool.deadlines[txs[2].Hash()]; ok {
+		t.Errorf("deadline of dropped transaction retained")
+	}
+}
@@ -0,0 +1,328 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// DependencyHintLength is the number of leading hash bytes carried by a
+// compressed dependency hint.
+const DependencyHintLength = types.DependencyHintLength
+
+// maxDependencyClosureDepth is the maximum number of dependency levels walked
+// when computing a dependency closure.
+const maxDependencyClosureDepth = 64
+
+// maxWorkflowSteps is the maximum number of transactions assembled into a single
+// workflow.
+const maxWorkflowSteps = 32
+
+// Directions in which a dependency closure can be computed.
+const (
+	DependencyAncestors  = "ancestors"  // Transactions the root is waiting on
+	DependencyDependents = "dependents" // Transactions waiting on the root
+)
+
+var (
+	// ErrUnresolvedDependencyHint is returned if a compressed dependency hint
+	// does not match any transaction in the pool.
+	ErrUnresolvedDependencyHint = errors.New("dependency hint matches no pooled transaction")
+
+	// ErrAmbiguousDependencyHint is returned if a compressed dependency hint
+	// matches more than one pooled transaction. The submitter must fall back to
+	// declaring the dependency by its full hash.
+	ErrAmbiguousDependencyHint = errors.New("ambiguous dependency hint, use full hash")
+
+	// ErrUnknownDependencyDirection is returned if a dependency closure is
+	// requested in a direction other than ancestors or dependents.
+	ErrUnknownDependencyDirection = errors.New("unknown dependency direction")
+
+	// ErrDependencyCycle is returned if the dependencies of a transaction lead
+	// back to itself, directly or through other pooled transactions, so that
+	// none of the transactions on the cycle could ever be batched.
+	ErrDependencyCycle = errors.New("circular transaction dependency")
+)
+
+// DependencyHint is a compressed dependency reference consisting of the first
+// DependencyHintLength bytes of the referenced transaction hash.
+type DependencyHint = types.DependencyHint
+
+// ShortHash returns the dependency hint referencing the given transaction hash.
+func ShortHash(hash common.Hash) DependencyHint {
+	return types.ShortHash(hash)
+}
+
+// indexHint records a pooled transaction hash in the short-hash index.
+func (p *ParallelPool) indexHint(hash common.Hash) {
+	hint := ShortHash(hash)
+	p.hintIndex[hint] = append(p.hintIndex[hint], hash)
+}
+
+// unindexHint drops a pooled transaction hash from the short-hash index.
+func (p *ParallelPool) unindexHint(hash common.Hash) {
+	hint := ShortHash(hash)
+
+	hashes := p.hintIndex[hint]
+	for i, h := range hashes {
+		if h == hash {
+			hashes = append(hashes[:i], hashes[i+1:]...)
+			break
+		}
+	}
+	if len(hashes) == 0 {
+		delete(p.hintIndex, hint)
+	} else {
+		p.hintIndex[hint] = hashes
+	}
+}
+
+// indexSigningHash records a pooled transaction in the signing-hash index, so
+// that dependents assembled before it was signed can reference it.
+func (p *ParallelPool) indexSigningHash(tx *types.Transaction) {
+	p.signingIndex[p.signer.Hash(tx)] = tx.Hash()
+}
+
+// unindexSigningHash drops a pooled transaction from the signing-hash index.
+func (p *ParallelPool) unindexSigningHash(tx *types.Transaction) {
+	delete(p.signingIndex, p.signer.Hash(tx))
+}
+
+// setDependencies records the resolved dependencies of a pooled transaction,
+// indexing the transaction as a dependent of each of them.
+func (p *ParallelPool) setDependencies(hash common.Hash, deps []common.Hash) {
+	p.dependencies[hash] = deps
+	for _, dep := range deps {
+		p.dependents[dep] = append(p.dependents[dep], hash)
+	}
+}
+
+// unsetDependencies forgets the dependencies of a transaction, dropping it from
+// the dependents of each of them. The transaction's own dependents are kept, as
+// their edges to it remain declared.
+func (p *ParallelPool) unsetDependencies(hash common.Hash) {
+	for _, dep := range p.dependencies[hash] {
+		dependents := slices.DeleteFunc(p.dependents[dep], func(h common.Hash) bool { return h == hash })
+		if len(dependents) == 0 {
+			delete(p.dependents, dep)
+		} else {
+			p.dependents[dep] = dependents
+		}
+	}
+	delete(p.dependencies, hash)
+}
+
+// Dependents returns the hashes of the pooled transactions declaring a direct
+// dependency on the given transaction, pooled or not.
+func (p *ParallelPool) Dependents(hash common.Hash) []common.Hash {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return slices.Clone(p.dependents[hash])
+}
+
+// resolveDependencies expands the compressed dependency hints of a transaction
+// into full hashes and merges them with the explicitly declared dependencies.
+// Resolution is strict: a hint must match exactly one pooled transaction.
+//
+// Explicit dependencies matching the signing hash of a pooled transaction, as
+// declared by the steps of a workflow, are resolved to its transaction hash.
+func (p *ParallelPool) resolveDependencies(data *ParallelTxData) ([]common.Hash, error) {
+	deps := make([]common.Hash, 0, len(data.Dependencies)+len(data.DependencyHints))
+	for _, dep := range data.Dependencies {
+		if hash, ok := p.signingIndex[dep]; ok {
+			dep = hash
+		}
+		deps = append(deps, dep)
+	}
+	if len(data.DependencyHints) == 0 {
+		return deps, nil
+	}
+
+	for _, hint := range data.DependencyHints {
+		switch matches := p.hintIndex[hint]; len(matches) {
+		case 0:
+			return nil, ErrUnresolvedDependencyHint
+		case 1:
+			deps = append(deps, matches[0])
+		default:
+			return nil, ErrAmbiguousDependencyHint
+		}
+	}
+	return deps, nil
+}
+
+// checkDependencyCycle rejects the dependencies of a transaction if they lead
+// back to the transaction itself, either by declaring it directly or through
+// the dependencies of pooled transactions, which may reference transactions
+// before they are pooled.
+func (p *ParallelPool) checkDependencyCycle(hash common.Hash, deps []common.Hash) error {
+	var (
+		visited = make(map[common.Hash]struct{})
+		stack   = slices.Clone(deps)
+	)
+	for len(stack) > 0 {
+		dep := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if dep == hash {
+			return ErrDependencyCycle
+		}
+		if _, ok := visited[dep]; ok {
+			continue
+		}
+		visited[dep] = struct{}{}
+		stack = append(stack, p.dependencies[dep]...)
+	}
+	return nil
+}
+
+// demoteDependents detaches the transactions depending on a replaced transaction.
+// Dependencies are declared by hash, so the edges cannot be re-pointed to the
+// replacement: the ordering the submitter relied on is no longer guaranteed.
+// Instead the edge is dropped, the dependent is moved to the sequential path of
+// its sender and a conflict is recorded against the replacement.
+func (p *ParallelPool) demoteDependents(replaced, replacement common.Hash) {
+	for _, hash := range slices.Clone(p.dependents[replaced]) {
+		deps := slices.DeleteFunc(slices.Clone(p.dependencies[hash]), func(dep common.Hash) bool { return dep == replaced })
+		p.unsetDependencies(hash)
+		if len(deps) > 0 {
+			p.setDependencies(hash, deps)
+		}
+		p.demote(hash)
+		p.ReportConflict(hash, ConflictReport{Action: ConflictDemoted, Counterparty: replacement})
+
+		log.Debug("Demoted dependent of replaced transaction", "hash", hash, "replaced", replaced, "replacement", replacement)
+		p.metrics.dependencyDemoted.Mark(1)
+	}
+}
+
+// dropDependents drops the transactions depending on a dropped transaction, and
+// in turn those depending on them. Unlike an included or replaced dependency, a
+// dropped one will never run ahead of its dependents, so the ordering they were
+// submitted with can't be honored anymore. The caller must hold the pool lock.
+func (p *ParallelPool) dropDependents(dropped common.Hash) {
+	for _, hash := range slices.Clone(p.dependents[dropped]) {
+		if p.all[hash] == nil {
+			continue // dropped in cascade already
+		}
+		p.ReportConflict(hash, ConflictReport{Action: ConflictOrphaned, Counterparty: dropped})
+
+		log.Debug("Dropped dependent of dropped transaction", "hash", hash, "dependency", dropped)
+		p.metrics.dependencyCascade.Mark(1)
+		p.dropTx(hash, DropDependency)
+	}
+}
+
+// demote moves a pooled transaction awaiting batching to the sequential path.
+func (p *ParallelPool) demote(hash common.Hash) {
+	tx := p.all[hash]
+	if tx == nil {
+		return
+	}
+	from, _ := types.Sender(p.signer, tx)
+
+	p.batchMu.Lock()
+	txs := p.parallelizableTxs[from]
+	idx := slices.IndexFunc(txs, func(ptx *types.Transaction) bool { return ptx.Hash() == hash })
+	if idx >= 0 {
+		if txs = slices.Delete(txs, idx, idx+1); len(txs) == 0 {
+			delete(p.parallelizableTxs, from)
+		} else {
+			p.parallelizableTxs[from] = txs
+		}
+		delete(p.unknownFootprint, hash)
+		delete(p.footprints, hash)
+		delete(p.batchedAt, hash)
+		p.counters.pending.Add(-1)
+	}
+	p.batchMu.Unlock()
+
+	if idx >= 0 {
+		p.enqueueSequential(from, tx)
+	}
+}
+
+// DependencyNode is a transaction reached while walking a dependency closure.
+type DependencyNode struct {
+	Hash   common.Hash `json:"hash"`
+	Depth  int         `json:"depth"`  // Number of edges from the root
+	Pooled bool        `json:"pooled"` // Whether the transaction is still in the pool
+}
+
+// DependencyClosure is the transitive set of ancestors or dependents of a pooled
+// transaction, ordered breadth first.
+type DependencyClosure struct {
+	Root      common.Hash      `json:"root"`
+	Direction string           `json:"direction"`
+	Nodes     []DependencyNode `json:"nodes"`
+	Truncated bool             `json:"truncated"` // Whether the depth limit cut the walk short
+}
+
+// DependencyClosure walks the declared dependency edges of a transaction in the
+// given direction, up to the given depth (zero or above the maximum meaning the
+// maximum). Ancestors that already left the pool are reported as not pooled, as
+// a dependency on them is either satisfied or unsatisfiable.
+func (p *ParallelPool) DependencyClosure(hash common.Hash, direction string, depth int) (*DependencyClosure, error) {
+	if depth <= 0 || depth > maxDependencyClosureDepth {
+		depth = maxDependencyClosureDepth
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var edges map[common.Hash][]common.Hash
+	switch direction {
+	case DependencyAncestors:
+		edges = p.dependencies
+	case DependencyDependents:
+		edges = p.dependents
+	default:
+		return nil, ErrUnknownDependencyDirection
+	}
+	closure := &DependencyClosure{Root: hash, Direction: direction, Nodes: []DependencyNode{}}
+
+	visited := map[common.Hash]struct{}{hash: {}}
+	frontier := []common.Hash{hash}
+	for level := 1; len(frontier) > 0 && level <= depth; level++ {
+		var next []common.Hash
+		for _, current := range frontier {
+			for _, linked := range edges[current] {
+				if _, ok := visited[linked]; ok {
+					continue
+				}
+				visited[linked] = struct{}{}
+				closure.Nodes = append(closure.Nodes, DependencyNode{Hash: linked, Depth: level, Pooled: p.all[linked] != nil})
+				next = append(next, linked)
+			}
+		}
+		frontier = next
+	}
+	// Anything still reachable from the deepest level was cut by the limit
+	for _, current := range frontier {
+		for _, linked := range edges[current] {
+			if _, ok := visited[linked]; !ok {
+				closure.Truncated = true
+			}
+		}
+	}
+	return closure, nil
+}
@@ -0,0 +1,258 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that compressed dependency hints resolve to the unique pooled hash they
+// prefix, and that ambiguous or unknown hints are rejected.
+func TestResolveDependencyHints(t *testing.T) {
+	pool := &ParallelPool{hintIndex: make(map[DependencyHint][]common.Hash)}
+
+	var (
+		unique = common.Hash{0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, 0xaa}
+		clashA = common.Hash{0xee, 0xee, 0xee, 0xee, 0xee, 0xee, 0xee, 0xee, 0x01}
+		clashB = common.Hash{0xee, 0xee, 0xee, 0xee, 0xee, 0xee, 0xee, 0xee, 0x02}
+	)
+	for _, hash := range []common.Hash{unique, clashA, clashB} {
+		pool.indexHint(hash)
+	}
+	full := common.Hash{0x42}
+
+	deps, err := pool.resolveDependencies(&ParallelTxData{
+		Dependencies:    []common.Hash{full},
+		DependencyHints: []DependencyHint{ShortHash(unique)},
+	})
+	if err != nil {
+		t.Fatalf("failed to resolve unique hint: %v", err)
+	}
+	if len(deps) != 2 || deps[0] != full || deps[1] != unique {
+		t.Fatalf("resolved dependencies mismatch: have %v", deps)
+	}
+	if _, err := pool.resolveDependencies(&ParallelTxData{DependencyHints: []DependencyHint{ShortHash(clashA)}}); !errors.Is(err, ErrAmbiguousDependencyHint) {
+		t.Fatalf("ambiguous hint error mismatch: have %v, want %v", err, ErrAmbiguousDependencyHint)
+	}
+	if _, err := pool.resolveDependencies(&ParallelTxData{DependencyHints: []DependencyHint{{0x99}}}); !errors.Is(err, ErrUnresolvedDependencyHint) {
+		t.Fatalf("unknown hint error mismatch: have %v, want %v", err, ErrUnresolvedDependencyHint)
+	}
+	// Dropping one side of the collision makes the hint unambiguous again
+	pool.unindexHint(clashB)
+	if deps, err := pool.resolveDependencies(&ParallelTxData{DependencyHints: []DependencyHint{ShortHash(clashA)}}); err != nil || deps[0] != clashA {
+		t.Fatalf("post-removal resolution mismatch: have %v, %v", deps, err)
+	}
+}
+
+// Tests that self-dependencies and dependencies leading back to a transaction
+// through pooled ones are rejected as cycles, while acyclic chains and diamonds
+// are accepted.
+func TestCheckDependencyCycle(t *testing.T) {
+	var (
+		a, b, c, d = common.Hash{0x0a}, common.Hash{0x0b}, common.Hash{0x0c}, common.Hash{0x0d}
+		pool       = &ParallelPool{dependencies: map[common.Hash][]common.Hash{
+			b: {a},    // b depends on a, which is not pooled yet
+			c: {b},    // c depends on b
+			d: {b, c}, // d depends on both, forming a diamond
+		}}
+	)
+	tests := []struct {
+		hash common.Hash
+		deps []common.Hash
+		err  error
+	}{
+		{a, nil, nil},
+		{a, []common.Hash{a}, ErrDependencyCycle},     // Self-dependency
+		{a, []common.Hash{b}, ErrDependencyCycle},     // a -> b -> a
+		{a, []common.Hash{d}, ErrDependencyCycle},     // a -> d -> c -> b -> a
+		{common.Hash{0xee}, []common.Hash{d}, nil},    // Diamond without cycle
+		{common.Hash{0xee}, []common.Hash{c, d}, nil}, // Shared ancestors
+	}
+	for i, tt := range tests {
+		if err := pool.checkDependencyCycle(tt.hash, tt.deps); !errors.Is(err, tt.err) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.err)
+		}
+	}
+}
+
+// Tests that replacing a transaction detaches its dependents: the dangling edge
+// is dropped and the dependent is demoted to the sequential path.
+func TestDemoteDependents(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	pool := &ParallelPool{
+		signer:            testSigner,
+		all:               make(map[common.Hash]*types.Transaction),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		conflictReports:   lru.NewBasicLRU[common.Hash, []ConflictReport](maxConflictReports),
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	var (
+		replaced    = common.Hash{0x01}
+		replacement = common.Hash{0x02}
+		unrelated   = common.Hash{0x03}
+		dependent   = pricedTransaction(0, 1, key)
+	)
+	pool.all[dependent.Hash()] = dependent
+	pool.parallelizableTxs[from] = []*types.Transaction{dependent}
+	pool.setDependencies(dependent.Hash(), []common.Hash{replaced, unrelated})
+
+	pool.demoteDependents(replaced, replacement)
+
+	if deps := pool.dependencies[dependent.Hash()]; len(deps) != 1 || deps[0] != unrelated {
+		t.Errorf("dependency edges mismatch: have %v, want [%x]", deps, unrelated)
+	}
+	if dependents := pool.Dependents(replaced); len(dependents) != 0 {
+		t.Errorf("replaced transaction dependents mismatch: have %v, want none", dependents)
+	}
+	if dependents := pool.Dependents(unrelated); !slices.Equal(dependents, []common.Hash{dependent.Hash()}) {
+		t.Errorf("unrelated transaction dependents mismatch: have %v, want [%x]", dependents, dependent.Hash())
+	}
+	if len(pool.parallelizableTxs[from]) != 0 {
+		t.Errorf("dependent still awaiting batching")
+	}
+	if list := pool.pending[from]; list == nil || list.Get(0) != dependent {
+		t.Errorf("dependent not moved to the sequential path")
+	}
+	reports := pool.TxDiagnostics(dependent.Hash()).Conflicts
+	if len(reports) != 1 || reports[0].Action != ConflictDemoted || reports[0].Counterparty != replacement {
+		t.Errorf("conflict report mismatch: have %+v", reports)
+	}
+	if have := pool.metrics.tagConflicts.Snapshot().Count(); have != 1 {
+		t.Errorf("conflicting tag meter mismatch: have %d, want 1", have)
+	}
+}
+
+// Tests that dropping a transaction drops its dependents in cascade, while an
+// included one leaves them pooled.
+func TestDropDependents(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	all := make(map[common.Hash]*types.Transaction)
+	pool := &ParallelPool{
+		signer:            testSigner,
+		all:               all,
+		priced:            newParallelPricedList(all, nil),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		hintIndex:         make(map[DependencyHint][]common.Hash),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		conflictReports:   lru.NewBasicLRU[common.Hash, []ConflictReport](maxConflictReports),
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	// Chain three transactions up, leave one independent, and depend the last
+	// one on a transaction that gets included rather than dropped
+	var txs []*types.Transaction
+	for i := 0; i < 6; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, int64(i+1), key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+		pool.indexHint(tx.Hash())
+		txs = append(txs, tx)
+	}
+	pool.setDependencies(txs[1].Hash(), []common.Hash{txs[0].Hash()})
+	pool.setDependencies(txs[2].Hash(), []common.Hash{txs[1].Hash()})
+	pool.setDependencies(txs[5].Hash(), []common.Hash{txs[4].Hash()})
+
+	pool.removeTx(txs[0].Hash(), true, true)
+	pool.removeTx(txs[4].Hash(), false, true)
+
+	for i, want := range []bool{false, false, false, true, false, true} {
+		if have := pool.all[txs[i].Hash()] != nil; have != want {
+			t.Errorf("tx %d: pooled mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	// Dropped dependents leave the index, those of included transactions stay
+	if len(pool.dependents) != 1 || !slices.Equal(pool.Dependents(txs[4].Hash()), []common.Hash{txs[5].Hash()}) {
+		t.Errorf("dependents index mismatch: have %v", pool.dependents)
+	}
+	if have := pool.metrics.dependencyCascade.Snapshot().Count(); have != 2 {
+		t.Errorf("cascade meter mismatch: have %d, want 2", have)
+	}
+	reports := pool.TxDiagnostics(txs[2].Hash()).Conflicts
+	if len(reports) != 1 || reports[0].Action != ConflictOrphaned || reports[0].Counterparty != txs[1].Hash() {
+		t.Errorf("conflict report mismatch: have %+v", reports)
+	}
+}
+
+// Tests that dependency closures are walked transitively in both directions,
+// survive cycles and report truncation at the depth limit.
+func TestDependencyClosure(t *testing.T) {
+	var (
+		a = common.Hash{0x0a}
+		b = common.Hash{0x0b}
+		c = common.Hash{0x0c}
+		d = common.Hash{0x0d}
+	)
+	// d -> c -> b -> a, with b also depending on d to form a cycle
+	pool := &ParallelPool{
+		all:          make(map[common.Hash]*types.Transaction),
+		dependencies: make(map[common.Hash][]common.Hash),
+		dependents:   make(map[common.Hash][]common.Hash),
+	}
+	pool.setDependencies(b, []common.Hash{a, d})
+	pool.setDependencies(c, []common.Hash{b})
+	pool.setDependencies(d, []common.Hash{c})
+	pool.all[b] = new(types.Transaction)
+	pool.all[c] = new(types.Transaction)
+	pool.all[d] = new(types.Transaction)
+
+	closure, err := pool.DependencyClosure(c, DependencyAncestors, 0)
+	if err != nil {
+		t.Fatalf("failed to compute ancestors: %v", err)
+	}
+	want := []DependencyNode{{Hash: b, Depth: 1, Pooled: true}, {Hash: a, Depth: 2}, {Hash: d, Depth: 2, Pooled: true}}
+	if !slices.Equal(closure.Nodes, want) || closure.Truncated {
+		t.Fatalf("ancestors mismatch: have %+v, want %+v", closure.Nodes, want)
+	}
+	closure, err = pool.DependencyClosure(a, DependencyDependents, 0)
+	if err != nil {
+		t.Fatalf("failed to compute dependents: %v", err)
+	}
+	want = []DependencyNode{{Hash: b, Depth: 1, Pooled: true}, {Hash: c, Depth: 2, Pooled: true}, {Hash: d, Depth: 3, Pooled: true}}
+	if !slices.Equal(closure.Nodes, want) || closure.Truncated {
+		t.Fatalf("dependents mismatch: have %+v, want %+v", closure.Nodes, want)
+	}
+	closure, _ = pool.DependencyClosure(a, DependencyDependents, 2)
+	if len(closure.Nodes) != 2 || !closure.Truncated {
+		t.Fatalf("depth limited dependents mismatch: have %+v, truncated %v", closure.Nodes, closure.Truncated)
+	}
+	if _, err := pool.DependencyClosure(a, "sideways", 0); !errors.Is(err, ErrUnknownDependencyDirection) {
+		t.Fatalf("direction error mismatch: have %v, want %v", err, ErrUnknownDependencyDirection)
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxConflictReports is the number of transactions for which conflict reports
+// are retained. Reports outlive the transactions themselves so that senders can
+// inspect them after inclusion.
+const maxConflictReports = 4096
+
+// Conflict actions taken against a transaction.
+const (
+	ConflictSerialized = "serialized" // Routed to the sequential path
+	ConflictAborted    = "aborted"    // Parallel execution aborted and retried
+	ConflictReordered  = "reordered"  // Moved to a later batch
+	ConflictDemoted    = "demoted"    // Dependency replaced, moved to the sequential path
+	ConflictAged       = "aged"       // Batched for too long, moved to the sequential path
+	ConflictOrphaned   = "orphaned"   // Dependency dropped, dropped along with it
+)
+
+// ConflictReport describes a conflict that caused a parallel transaction to be
+// aborted, reordered or serialized, together with the counterparty responsible.
+type ConflictReport struct {
+	Action       string          `json:"action"`
+	Counterparty common.Hash     `json:"counterparty"`
+	Address      *common.Address `json:"address,omitempty"`
+	Slot         *common.Hash    `json:"slot,omitempty"`
+	Time         time.Time       `json:"time"`
+}
+
+// TxDiagnostics is the diagnostic view of a transaction in the parallel pool.
+type TxDiagnostics struct {
+	Hash             common.Hash      `json:"hash"`
+	Known            bool             `json:"known"`
+	Parallelizable   bool             `json:"parallelizable"`
+	UnknownFootprint bool             `json:"unknownFootprint"`
+	Arrival          *time.Time       `json:"arrival,omitempty"` // Time the pool admitted the transaction at
+	BatchAge         uint64           `json:"batchAge"`          // Blocks spent awaiting batch execution
+	EvictionScore    float64          `json:"evictionScore"`     // Eviction order once the pool is full, highest first
+	Conflicts        []ConflictReport `json:"conflicts"`
+}
+
+// ReportConflict records that the given transaction was aborted, reordered or
+// serialized because of a conflict. It is used by the pool during admission as
+// well as by batch executors when merging results.
+func (p *ParallelPool) ReportConflict(hash common.Hash, report ConflictReport) {
+	if report.Time.IsZero() {
+		report.Time = time.Now()
+	}
+	p.conflictMu.Lock()
+	defer p.conflictMu.Unlock()
+
+	reports, _ := p.conflictReports.Get(hash)
+	if len(reports) == 0 {
+		p.metrics.tagConflicts.Mark(1)
+	}
+	p.conflictReports.Add(hash, append(reports, report))
+}
+
+// TxDiagnostics returns diagnostic information about a transaction, including
+// any recorded conflicts explaining why it did not execute in parallel.
+func (p *ParallelPool) TxDiagnostics(hash common.Hash) *TxDiagnostics {
+	diag := &TxDiagnostics{Hash: hash}
+
+	p.mu.RLock()
+	tx, known := p.all[hash]
+	if diag.Known = known; known {
+		from, _ := types.Sender(p.signer, tx) // already validated
+		ctx := p.newEvictionContext()
+		diag.EvictionScore = p.evictionScore(ctx, tx, from, ctx.lane(hash), true)
+
+		arrived := p.arrivals.get(tx)
+		diag.Arrival = &arrived
+	}
+	head := p.currentHead
+	p.mu.RUnlock()
+
+	p.batchMu.RLock()
+	for _, txs := range p.parallelizableTxs {
+		for _, tx := range txs {
+			if tx.Hash() == hash {
+				diag.Parallelizable = true
+				break
+			}
+		}
+	}
+	_, diag.UnknownFootprint = p.unknownFootprint[hash]
+	if since, ok := p.batchedAt[hash]; ok && head != nil {
+		diag.BatchAge = head.Number.Uint64() - since
+	}
+	p.batchMu.RUnlock()
+
+	p.conflictMu.Lock()
+	if reports, ok := p.conflictReports.Peek(hash); ok {
+		diag.Conflicts = append([]ConflictReport(nil), reports...)
+	}
+	p.conflictMu.Unlock()
+	return diag
+}
@@ -0,0 +1,66 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that the conflicts reported by batch executors are kept in order with
+// their counterparty and location, and outlive the transactions they concern.
+func TestTxDiagnosticsConflicts(t *testing.T) {
+	pool := &ParallelPool{
+		all:             make(map[common.Hash]*types.Transaction),
+		conflictReports: lru.NewBasicLRU[common.Hash, []ConflictReport](maxConflictReports),
+		metrics:         newPoolMetrics("", metrics.NewRegistry()),
+	}
+	var (
+		hash         = common.Hash{0x01}
+		counterparty = common.Hash{0x02}
+		addr         = common.Address{0xaa}
+		slot         = common.Hash{0x03}
+	)
+	pool.ReportConflict(hash, ConflictReport{Action: ConflictAborted, Counterparty: counterparty, Address: &addr, Slot: &slot})
+	pool.ReportConflict(hash, ConflictReport{Action: ConflictSerialized, Counterparty: counterparty})
+
+	diag := pool.TxDiagnostics(hash)
+	if diag.Known {
+		t.Errorf("unpooled transaction reported known")
+	}
+	if len(diag.Conflicts) != 2 {
+		t.Fatalf("conflict count mismatch: have %d, want 2", len(diag.Conflicts))
+	}
+	aborted := diag.Conflicts[0]
+	if aborted.Action != ConflictAborted || aborted.Counterparty != counterparty || aborted.Time.IsZero() {
+		t.Errorf("aborted report mismatch: have %+v", aborted)
+	}
+	if aborted.Address == nil || *aborted.Address != addr || aborted.Slot == nil || *aborted.Slot != slot {
+		t.Errorf("aborted location mismatch: address %v, slot %v", aborted.Address, aborted.Slot)
+	}
+	if diag.Conflicts[1].Action != ConflictSerialized {
+		t.Errorf("second report mismatch: have %s, want %s", diag.Conflicts[1].Action, ConflictSerialized)
+	}
+	// Transactions are counted once, however many conflicts they ran into
+	if have := pool.metrics.tagConflicts.Snapshot().Count(); have != 1 {
+		t.Errorf("conflicted transaction count mismatch: have %d, want 1", have)
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BatchGasEstimate is the estimated gas usage of a batch of transactions.
+type BatchGasEstimate struct {
+	Gas          uint64           `json:"gas"`          // Gas used by all members, the block gas the batch takes
+	CriticalPath uint64           `json:"criticalPath"` // Gas used by the longest chain of dependent members
+	Txs          []*TxGasEstimate `json:"txs"`
+}
+
+// TxGasEstimate is the estimated gas usage of a single batch member.
+type TxGasEstimate struct {
+	Hash    common.Hash `json:"hash"`
+	GasUsed uint64      `json:"gasUsed"`
+	Path    uint64      `json:"path"`   // Gas used by the longest dependency chain ending at the member
+	Failed  bool        `json:"failed"` // Execution reverted
+}
+
+// EstimateParallelGas estimates the gas a batch of transactions uses when
+// executed on top of the current head, implementing ParallelExecutor.
+func (p *ParallelPool) EstimateParallelGas(txs []*types.Transaction) (uint64, error) {
+	estimate, err := p.EstimateBatchGas(txs)
+	if err != nil {
+		return 0, err
+	}
+	return estimate.Gas, nil
+}
+
+// EstimateBatchGas simulates a batch of transactions on top of the current head
+// and estimates its gas usage. Members depending on each other, by declared
+// dependency or by sharing a sender, are chained: each chain is executed
+// serially on its own copy of the state, while independent chains are executed
+// concurrently. Besides the total gas, the gas of the longest chain is reported
+// as the critical path bounding the parallel execution time of the batch.
+func (p *ParallelPool) EstimateBatchGas(txs []*types.Transaction) (*BatchGasEstimate, error) {
+	order, preds, err := p.estimationOrder(txs)
+	if err != nil {
+		return nil, err
+	}
+	parent := p.chain.CurrentBlock()
+	statedb, err := p.stateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for gas estimation: %v", err)
+	}
+	// Group the members into chains of dependent ones, each kept in dependency
+	// order
+	var (
+		chainOf = make([]int, len(txs))
+		chains  [][]int
+	)
+	for i := range chainOf {
+		chainOf[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if chainOf[i] != i {
+			chainOf[i] = find(chainOf[i])
+		}
+		return chainOf[i]
+	}
+	for i, deps := range preds {
+		for _, dep := range deps {
+			chainOf[find(i)] = find(dep)
+		}
+	}
+	index := make(map[int]int)
+	for _, i := range order {
+		root := find(i)
+		if _, ok := index[root]; !ok {
+			index[root] = len(chains)
+			chains = append(chains, nil)
+		}
+		chains[index[root]] = append(chains[index[root]], i)
+	}
+	// Execute the chains concurrently, the members of each one serially
+	var (
+		adapter = core.NewPendingExecAdapter(p.chainconfig, p.chain, parent)
+		results = make([]*core.ExecutionResult, len(txs))
+		errs    = make([]error, len(txs))
+		wg      sync.WaitGroup
+	)
+	for _, chain := range chains {
+		wg.Add(1)
+
+		// Copy the state serially, concurrent copies of a StateDB are unsafe
+		statedb := statedb.Copy()
+		p.workers.Go(func() {
+			defer wg.Done()
+
+			for _, i := range chain {
+				errs[i] = p.workers.Guard(txs[i], func() (err error) {
+					results[i], err = adapter.Apply(statedb, txs[i], i, new(core.GasPool).AddGas(txs[i].Gas()), nil)
+					return err
+				})
+				if errs[i] != nil {
+					return
+				}
+				statedb.Finalise(true)
+			}
+		})
+	}
+	wg.Wait()
+
+	// Accumulate the gas along the dependency edges, in dependency order. Errors
+	// abort the rest of their chain, but precede it in the order too.
+	estimate := &BatchGasEstimate{Txs: make([]*TxGasEstimate, len(txs))}
+	for _, i := range order {
+		if errs[i] != nil {
+			return nil, fmt.Errorf("transaction %x: %w", txs[i].Hash(), errs[i])
+		}
+		member := &TxGasEstimate{Hash: txs[i].Hash(), GasUsed: results[i].UsedGas, Failed: results[i].Failed()}
+		for _, dep := range preds[i] {
+			member.Path = max(member.Path, estimate.Txs[dep].Path)
+		}
+		member.Path += member.GasUsed
+
+		estimate.Txs[i] = member
+		estimate.Gas += member.GasUsed
+		estimate.CriticalPath = max(estimate.CriticalPath, member.Path)
+	}
+	log.Debug("Estimated parallel batch gas", "txs", len(txs), "chains", len(chains), "gas", estimate.Gas, "critical", estimate.CriticalPath)
+	return estimate, nil
+}
+
+// estimationOrder returns the members of a batch in dependency order, along with
+// the members each one directly depends on: those it declares a dependency on,
+// in full, by signing hash or as a hint, and the preceding nonce of its sender. Dependencies on
+// transactions outside the batch are ignored, they are assumed to be included
+// already.
+func (p *ParallelPool) estimationOrder(txs []*types.Transaction) ([]int, [][]int, error) {
+	var (
+		byHash = make(map[common.Hash]int, len(txs))
+		byHint = make(map[DependencyHint][]int, len(txs))
+		nonces = make(map[common.Address][]int)
+		preds  = make([][]int, len(txs))
+	)
+	for i, tx := range txs {
+		byHash[tx.Hash()] = i
+		byHash[p.signer.Hash(tx)] = i
+		byHint[ShortHash(tx.Hash())] = append(byHint[ShortHash(tx.Hash())], i)
+
+		from, err := types.Sender(p.signer, tx)
+		if err != nil {
+			return nil, nil, fmt.Errorf("transaction %x: %w", tx.Hash(), err)
+		}
+		nonces[from] = append(nonces[from], i)
+	}
+	for _, members := range nonces {
+		sort.SliceStable(members, func(a, b int) bool { return txs[members[a]].Nonce() < txs[members[b]].Nonce() })
+		for j := 1; j < len(members); j++ {
+			preds[members[j]] = append(preds[members[j]], members[j-1])
+		}
+	}
+	for i, tx := range txs {
+		for _, dep := range tx.Dependencies() {
+			if j, ok := byHash[dep]; ok && j != i {
+				preds[i] = append(preds[i], j)
+			}
+		}
+		for _, hint := range tx.DependencyHints() {
+			if matches := byHint[hint]; len(matches) == 1 && matches[0] != i {
+				preds[i] = append(preds[i], matches[0])
+			}
+		}
+	}
+	// Order the members topologically, keeping the batch order where possible
+	var (
+		pending = make([]int, len(txs))
+		succs   = make([][]int, len(txs))
+		order   = make([]int, 0, len(txs))
+	)
+	for i, deps := range preds {
+		pending[i] = len(deps)
+		for _, dep := range deps {
+			succs[dep] = append(succs[dep], i)
+		}
+	}
+	for len(order) < len(txs) {
+		next := -1
+		for i := range txs {
+			if pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, nil, ErrDependencyCycle
+		}
+		pending[next] = -1
+		order = append(order, next)
+		for _, succ := range succs[next] {
+			pending[succ]--
+		}
+	}
+	return order, preds, nil
+}
@@ -0,0 +1,117 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that batch gas estimates chain the members sharing a sender or declaring
+// dependencies on each other, reporting the longest chain as critical path, and
+// that members failing to apply abort the estimate.
+func TestEstimateBatchGas(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, deps ...common.Hash) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big1,
+			ParallelType: types.ParallelTypeIndependent,
+			Dependencies: deps,
+		})
+	}
+	// Two chains: the first sender's nonces, and the third sender depending on
+	// the second one, listed ahead of its dependency
+	var (
+		a0 = newTx(keys[0], 0)
+		a1 = newTx(keys[0], 1)
+		b0 = newTx(keys[1], 0)
+		c0 = newTx(keys[2], 0, b0.Hash())
+	)
+	estimate, err := pool.EstimateBatchGas([]*types.Transaction{a1, c0, a0, b0})
+	if err != nil {
+		t.Fatalf("failed to estimate batch gas: %v", err)
+	}
+	if have, want := estimate.Gas, 4*params.TxGas; have != want {
+		t.Errorf("batch gas mismatch: have %d, want %d", have, want)
+	}
+	if have, want := estimate.CriticalPath, 2*params.TxGas; have != want {
+		t.Errorf("critical path mismatch: have %d, want %d", have, want)
+	}
+	for i, want := range []uint64{2 * params.TxGas, 2 * params.TxGas, params.TxGas, params.TxGas} {
+		if have := estimate.Txs[i].Path; have != want {
+			t.Errorf("member %d path mismatch: have %d, want %d", i, have, want)
+		}
+	}
+	if have, err := pool.EstimateParallelGas([]*types.Transaction{a0, b0}); err != nil || have != 2*params.TxGas {
+		t.Errorf("parallel gas mismatch: have %d (%v), want %d", have, err, 2*params.TxGas)
+	}
+	// A nonce gap within the batch fails its chain and the estimate
+	if _, err := pool.EstimateBatchGas([]*types.Transaction{a1, b0}); !errors.Is(err, core.ErrNonceTooHigh) {
+		t.Errorf("nonce gap error mismatch: have %v, want %v", err, core.ErrNonceTooHigh)
+	}
+	// The same estimate is served over RPC from raw transactions
+	var raws []hexutil.Bytes
+	for _, tx := range []*types.Transaction{a0, a1} {
+		raw, _ := tx.MarshalBinary()
+		raws = append(raws, raw)
+	}
+	estimate, err = NewParallelTxPoolAPI(pool).EstimateBatchGas(raws)
+	if err != nil {
+		t.Fatalf("failed to estimate batch gas over RPC: %v", err)
+	}
+	if estimate.Gas != 2*params.TxGas || estimate.CriticalPath != 2*params.TxGas {
+		t.Errorf("RPC estimate mismatch: gas %d, critical path %d", estimate.Gas, estimate.CriticalPath)
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// EvictionWeights are the relative weights of the factors a transaction is
+// scored by for eviction when the pool is full. Each factor ranges from zero to
+// one, the weighted average being the eviction score: transactions with the
+// highest score are evicted first.
+type EvictionWeights struct {
+	Tip   float64 // Weight of a low effective tip, relative to the best paying transaction
+	Age   float64 // Weight of the time spent in the pool, relative to the queue lifetime
+	Share float64 // Weight of the share of the pool taken by the sender
+	Lane  float64 // Weight of the share of the pool taken by the lane, parallel or sequential
+}
+
+// DefaultEvictionWeights favors evicting underpaying transactions, followed by
+// those of senders hogging the pool.
+var DefaultEvictionWeights = EvictionWeights{Tip: 4, Age: 1, Share: 2, Lane: 1}
+
+// total returns the sum of the weights.
+func (w EvictionWeights) total() float64 {
+	return w.Tip + w.Age + w.Share + w.Lane
+}
+
+// Lanes transactions are executed through.
+const (
+	laneParallel = iota
+	laneSequential
+	laneCount
+)
+
+// evictionContext holds the pool-wide figures transactions are scored against.
+type evictionContext struct {
+	baseFee  *big.Int
+	maxTip   *big.Int
+	senders  map[common.Address]int
+	lanes    [laneCount]int
+	parallel map[common.Hash]struct{}
+	total    int
+}
+
+// newEvictionContext gathers the figures to score the pooled transactions by.
+// The caller must hold the pool lock.
+func (p *ParallelPool) newEvictionContext() *evictionContext {
+	ctx := &evictionContext{
+		maxTip:   new(big.Int),
+		senders:  make(map[common.Address]int),
+		parallel: make(map[common.Hash]struct{}),
+		total:    len(p.all),
+	}
+	if p.currentHead != nil {
+		ctx.baseFee = p.currentHead.BaseFee
+	}
+	p.batchMu.RLock()
+	for _, txs := range p.parallelizableTxs {
+		for _, tx := range txs {
+			ctx.parallel[tx.Hash()] = struct{}{}
+		}
+	}
+	p.batchMu.RUnlock()
+
+	for hash, tx := range p.all {
+		from, _ := types.Sender(p.signer, tx) // already validated
+		ctx.senders[from]++
+		ctx.lanes[ctx.lane(hash)]++
+
+		if tip := tx.EffectiveGasTipValue(ctx.baseFee); tip.Cmp(ctx.maxTip) > 0 {
+			ctx.maxTip = tip
+		}
+	}
+	return ctx
+}
+
+// lane returns the lane of a pooled transaction.
+func (ctx *evictionContext) lane(hash common.Hash) int {
+	if _, ok := ctx.parallel[hash]; ok {
+		return laneParallel
+	}
+	return laneSequential
+}
+
+// evictionScore computes the eviction score of a transaction taking the given
+// lane. If the transaction is not pooled yet, it is scored as if it was.
+func (p *ParallelPool) evictionScore(ctx *evictionContext, tx *types.Transaction, from common.Address, lane int, pooled bool) float64 {
+	weights := p.config.EvictionWeights
+	if weights.total() <= 0 {
+		return 0
+	}
+	var (
+		senders = ctx.senders[from]
+		lanes   = ctx.lanes[lane]
+		total   = ctx.total
+	)
+	if !pooled {
+		senders, lanes, total = senders+1, lanes+1, total+1
+	}
+	var score float64
+
+	// Transactions paying less than the best ones are evicted first
+	if ctx.maxTip.Sign() > 0 {
+		tip := tx.EffectiveGasTipValue(ctx.baseFee)
+		if tip.Sign() < 0 {
+			tip = new(big.Int)
+		}
+		ratio, _ := new(big.Rat).SetFrac(tip, ctx.maxTip).Float64()
+		score += weights.Tip * max(0, 1-ratio)
+	}
+	// Transactions lingering in the pool are evicted before fresh ones
+	if pooled {
+		age := float64(time.Since(p.arrivals.get(tx))) / float64(p.config.Lifetime)
+		score += weights.Age * min(1, max(0, age))
+	}
+	// Senders and lanes taking a large share of the pool give way to others
+	score += weights.Share * float64(senders) / float64(total)
+	score += weights.Lane * float64(lanes) / float64(total)
+
+	return score / weights.total()
+}
+
+// discard picks a pooled remote transaction to evict in favour of an incoming
+// one, taking the given lane, when the pool is full. Only the highest nonce
+// transaction of each account is a candidate, so that eviction leaves no nonce
+// gaps behind, and the sender of the incoming transaction is never evicted for
+// it. False is returned if the incoming transaction would score at least as
+// high as every candidate, being the first to go itself. The caller must hold
+// the pool lock.
+func (p *ParallelPool) discard(tx *types.Transaction, from common.Address, lane int) (*types.Transaction, bool) {
+	ctx := p.newEvictionContext()
+
+	var (
+		victim      *types.Transaction
+		victimScore float64
+	)
+	for addr := range ctx.senders {
+		if addr == from || p.exempt(addr) {
+			continue
+		}
+		var highest *types.Transaction
+		for _, atx := range p.accountTxs(addr) {
+			if highest == nil || atx.Nonce() > highest.Nonce() {
+				highest = atx
+			}
+		}
+		score := p.evictionScore(ctx, highest, addr, ctx.lane(highest.Hash()), true)
+		if victim == nil || score > victimScore || (score == victimScore && highest.Hash().Cmp(victim.Hash()) < 0) {
+			victim, victimScore = highest, score
+		}
+	}
+	if victim == nil || p.evictionScore(ctx, tx, from, lane, false) >= victimScore {
+		return nil, false
+	}
+	return victim, true
+}
+
+// expireQueued drops the queued transactions of remote accounts that were not
+// active for longer than the queue lifetime. The caller must hold the pool lock.
+func (p *ParallelPool) expireQueued() {
+	var evicted int64
+	for addr, list := range p.queue {
+		// Skip local and reserving accounts from the eviction mechanism
+		if p.exempt(addr) {
+			continue
+		}
+		// Any old enough should be removed
+		if time.Since(p.beats[addr]) > p.config.Lifetime {
+			txs := list.Flatten()
+			for _, tx := range txs {
+				p.dropTx(tx.Hash(), DropExpired)
+			}
+			evicted += int64(len(txs))
+		}
+	}
+	p.metrics.evicted.Mark(evicted)
+}
+
+// expirePending drops the pending transactions, batched and sequential alike, of
+// remote accounts whose newest pending transaction arrived longer than the
+// pending lifetime ago, as they are most likely priced out for good. All pending
+// transactions of an account go at once, leaving no nonce gaps behind. The
+// caller must hold the pool lock.
+func (p *ParallelPool) expirePending() {
+	newest := make(map[common.Address]time.Time)
+	seen := func(addr common.Address, txs []*types.Transaction) {
+		for _, tx := range txs {
+			if arrived := p.arrivals.get(tx); arrived.After(newest[addr]) {
+				newest[addr] = arrived
+			}
+		}
+	}
+	for addr, list := range p.pending {
+		seen(addr, list.Flatten())
+	}
+	p.batchMu.RLock()
+	for addr, txs := range p.parallelizableTxs {
+		seen(addr, txs)
+	}
+	p.batchMu.RUnlock()
+
+	var evicted int64
+	for addr, last := range newest {
+		if p.exempt(addr) || time.Since(last) <= p.config.PendingLifetime {
+			continue
+		}
+		var txs []*types.Transaction
+		if list := p.pending[addr]; list != nil {
+			txs = list.Flatten()
+		}
+		p.batchMu.RLock()
+		txs = append(txs, p.parallelizableTxs[addr]...)
+		p.batchMu.RUnlock()
+
+		for _, tx := range txs {
+			p.dropTx(tx.Hash(), DropExpired)
+		}
+		evicted += int64(len(txs))
+	}
+	p.metrics.pendingEvicted.Mark(evicted)
+}
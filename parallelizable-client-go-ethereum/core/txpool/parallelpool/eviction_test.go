@@ -0,0 +1,281 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a full pool evicts by weighted score rather than by price alone:
+// the highest nonce transaction of a sender hogging the pool is displaced by a
+// newcomer paying the same, unless the weights only consider the tip.
+func TestFairEviction(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	hog := []*types.Transaction{newTx(keys[0], 0, 1), newTx(keys[0], 1, 1), newTx(keys[0], 2, 1)}
+	payer := newTx(keys[1], 0, 10)
+	newcomer := newTx(keys[2], 0, 1)
+
+	tests := []struct {
+		weights EvictionWeights
+		evicted bool
+	}{
+		{EvictionWeights{}, true},        // Default weights account for the hogged share
+		{EvictionWeights{Tip: 1}, false}, // Equally paying newcomer scores no better
+	}
+	for i, tt := range tests {
+		pool := newTestPool(t, Config{
+			AccountSlots:    3,
+			GlobalSlots:     3,
+			GlobalQueue:     1,
+			EvictionWeights: tt.weights,
+			MetricsRegistry: metrics.NewRegistry(),
+		}, chain)
+
+		for _, err := range pool.Add(append(hog, payer), false) {
+			if err != nil {
+				t.Fatalf("test %d: failed to fill pool: %v", i, err)
+			}
+		}
+		if have, want := pool.TxDiagnostics(hog[1].Hash()).EvictionScore, pool.TxDiagnostics(payer.Hash()).EvictionScore; have <= want {
+			t.Errorf("test %d: hogging transaction scores below paying one: %v <= %v", i, have, want)
+		}
+		err := pool.Add([]*types.Transaction{newcomer}, false)[0]
+		if tt.evicted {
+			if err != nil {
+				t.Errorf("test %d: newcomer refused: %v", i, err)
+			}
+			if pool.Has(hog[2].Hash()) || !pool.Has(hog[1].Hash()) || !pool.Has(payer.Hash()) {
+				t.Errorf("test %d: eviction mismatch, want highest nonce of hogging sender evicted", i)
+			}
+		} else if !errors.Is(err, ErrTxPoolOverflow) {
+			t.Errorf("test %d: newcomer error mismatch: have %v, want %v", i, err, ErrTxPoolOverflow)
+		}
+		pool.Close()
+	}
+}
+
+// Tests that the transactions of configured local accounts are neither evicted
+// from a full pool nor expired, even when they arrive as remote ones, and that
+// they are counted by the local gauge.
+func TestLocalAccounts(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	local := crypto.PubkeyToAddress(keys[0].PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{
+		Locals:          []common.Address{local},
+		AccountSlots:    2,
+		GlobalSlots:     2,
+		GlobalQueue:     1,
+		Lifetime:        time.Hour,
+		PendingLifetime: time.Hour,
+		MetricsRegistry: metrics.NewRegistry(),
+	}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, tip int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	locals := []*types.Transaction{newTx(keys[0], 0, 1), newTx(keys[0], 1, 1), newTx(keys[0], 2, 1)}
+	for _, err := range pool.Add(locals, false) {
+		if err != nil {
+			t.Fatalf("failed to add local transaction: %v", err)
+		}
+	}
+	// A better paying remote transaction can't displace any of the local ones
+	if err := pool.Add([]*types.Transaction{newTx(keys[1], 0, 10)}, false)[0]; !errors.Is(err, ErrTxPoolOverflow) {
+		t.Errorf("remote transaction error mismatch: have %v, want %v", err, ErrTxPoolOverflow)
+	}
+	// While local ones are still admitted to the full pool
+	locals = append(locals, newTx(keys[0], 3, 1))
+	if err := pool.Add(locals[3:], false)[0]; err != nil {
+		t.Errorf("local transaction refused by full pool: %v", err)
+	}
+	if have := pool.metrics.local.Snapshot().Value(); have != int64(len(locals)) {
+		t.Errorf("local gauge mismatch: have %d, want %d", have, len(locals))
+	}
+	// Local transactions outlive both lifetimes
+	pool.mu.Lock()
+	for addr := range pool.beats {
+		pool.beats[addr] = time.Now().Add(-3 * time.Hour)
+	}
+	for hash := range pool.all {
+		pool.arrivals.times[hash] = time.Now().Add(-3 * time.Hour)
+	}
+	pool.expireQueued()
+	pool.expirePending()
+	pool.mu.Unlock()
+
+	for i, tx := range locals {
+		if !pool.Has(tx.Hash()) {
+			t.Errorf("local tx %d dropped", i)
+		}
+	}
+}
+
+// Tests that queued transactions expire after the queue lifetime of their
+// sender, while pending ones, batched and sequential alike, are only dropped
+// after the longer pending lifetime.
+func TestLifetimes(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{
+		Lifetime:        time.Hour,
+		PendingLifetime: 2 * time.Hour,
+		MetricsRegistry: metrics.NewRegistry(),
+	}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, parallelType uint8) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: parallelType,
+		})
+	}
+	var (
+		batched    = newTx(keys[0], 0, types.ParallelTypeIndependent)
+		sequential = newTx(keys[1], 0, types.ParallelTypeSequential)
+		queued     = newTx(keys[1], 2, types.ParallelTypeSequential)
+	)
+	for _, err := range pool.Add([]*types.Transaction{batched, sequential, queued}, false) {
+		if err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+	}
+	// expire ages the senders and their transactions, running an eviction round
+	expire := func(age time.Duration) {
+		pool.mu.Lock()
+		defer pool.mu.Unlock()
+
+		for addr := range pool.beats {
+			pool.beats[addr] = time.Now().Add(-age)
+		}
+		for hash := range pool.all {
+			pool.arrivals.times[hash] = time.Now().Add(-age)
+		}
+		pool.expireQueued()
+		pool.expirePending()
+	}
+	expire(90 * time.Minute)
+	if pool.Has(queued.Hash()) {
+		t.Errorf("queued transaction outlived the queue lifetime")
+	}
+	if !pool.Has(batched.Hash()) || !pool.Has(sequential.Hash()) {
+		t.Errorf("pending transactions dropped before the pending lifetime")
+	}
+	expire(3 * time.Hour)
+	if pool.Has(batched.Hash()) || pool.Has(sequential.Hash()) {
+		t.Errorf("pending transactions outlived the pending lifetime")
+	}
+	if have := pool.metrics.evicted.Snapshot().Count(); have != 1 {
+		t.Errorf("queued eviction count mismatch: have %d, want 1", have)
+	}
+	if have := pool.metrics.pendingEvicted.Snapshot().Count(); have != 2 {
+		t.Errorf("pending eviction count mismatch: have %d, want 2", have)
+	}
+	// Pending lifetimes shorter than the queue lifetime are raised to it
+	if have := (Config{Lifetime: time.Hour, PendingLifetime: time.Minute}).sanitize().PendingLifetime; have != time.Hour {
+		t.Errorf("sanitized pending lifetime mismatch: have %v, want %v", have, time.Hour)
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// poolDumpVersion is the version of the pool snapshot format written by Export.
+const poolDumpVersion = 1
+
+var (
+	// ErrAdminDisabled is returned if a pool administration method is called
+	// without the admin methods being enabled.
+	ErrAdminDisabled = errors.New("parallel txpool admin methods disabled")
+
+	// ErrUnsupportedDump is returned if a pool snapshot of an unknown format
+	// version is imported.
+	ErrUnsupportedDump = errors.New("unsupported parallel txpool snapshot version")
+)
+
+// poolDumpHeader leads a pool snapshot, identifying its format and the chain
+// head the pool content was valid at.
+type poolDumpHeader struct {
+	Version uint64
+	Head    common.Hash
+	Number  uint64
+	Count   uint64 // Number of entries following the header
+}
+
+// poolDumpEntry is a pooled transaction in a pool snapshot, along with the pool
+// metadata that is not part of the transaction itself.
+type poolDumpEntry struct {
+	Tx           *types.Transaction
+	Local        bool
+	Dependencies []common.Hash // Resolved dependencies, hints expanded
+	Batched      bool
+	BatchID      uint64 // Batch the transaction was assigned to, if batched
+}
+
+// ImportResult summarizes the outcome of importing a pool snapshot.
+type ImportResult struct {
+	Imported  int `json:"imported"`  // Transactions admitted into the pool
+	Known     int `json:"known"`     // Transactions already pooled
+	Dropped   int `json:"dropped"`   // Transactions refused by the pool, e.g. included meanwhile
+	Rebatched int `json:"rebatched"` // Imported transactions whose dependencies or batch assignment changed
+}
+
+// Export writes a snapshot of all pooled transactions to the given writer, in
+// RLP: a header followed by an entry per transaction, carrying the parallel
+// metadata the pool derived for it. Batched transactions come first, in batch
+// order, so that the dependencies of a transaction precede it; orphans still
+// waiting for their dependencies come last. It returns the number of exported
+// transactions.
+func (p *ParallelPool) Export(w io.Writer) (int, error) {
+	p.mu.RLock()
+	header, entries := p.dump()
+	p.mu.RUnlock()
+
+	if err := rlp.Encode(w, header); err != nil {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if err := rlp.Encode(w, entry); err != nil {
+			return 0, err
+		}
+	}
+	log.Info("Exported parallel transaction pool", "transactions", len(entries), "head", header.Number)
+	return len(entries), nil
+}
+
+// dump collects the snapshot of the pool content. The caller must hold the pool
+// lock.
+func (p *ParallelPool) dump() (*poolDumpHeader, []*poolDumpEntry) {
+	var (
+		entries = make([]*poolDumpEntry, 0, len(p.all)+len(p.orphans))
+		seen    = make(map[common.Hash]struct{}, len(p.all))
+	)
+	add := func(tx *types.Transaction, local bool) *poolDumpEntry {
+		seen[tx.Hash()] = struct{}{}
+		entry := &poolDumpEntry{
+			Tx:           tx,
+			Local:        local,
+			Dependencies: p.dependencies[tx.Hash()],
+		}
+		entries = append(entries, entry)
+		return entry
+	}
+	isLocal := func(tx *types.Transaction) bool {
+		from, _ := types.Sender(p.signer, tx) // already validated
+		return p.locals.contains(from)
+	}
+	p.batchMu.RLock()
+	for _, batch := range p.batchedTxs {
+		for _, tx := range batch.Transactions {
+			entry := add(tx, isLocal(tx))
+			entry.Batched, entry.BatchID = true, batch.BatchID
+		}
+	}
+	p.batchMu.RUnlock()
+
+	// Export the remaining transactions per sender, in nonce order
+	var rest []*types.Transaction
+	for hash, tx := range p.all {
+		if _, ok := seen[hash]; !ok {
+			rest = append(rest, tx)
+		}
+	}
+	sort.Slice(rest, func(i, j int) bool {
+		fi, _ := types.Sender(p.signer, rest[i])
+		fj, _ := types.Sender(p.signer, rest[j])
+		if fi != fj {
+			return fi.Cmp(fj) < 0
+		}
+		return rest[i].Nonce() < rest[j].Nonce()
+	})
+	for _, tx := range rest {
+		add(tx, isLocal(tx))
+	}
+	for _, orphan := range p.orphans {
+		add(orphan.tx, orphan.local)
+	}
+	header := &poolDumpHeader{Version: poolDumpVersion, Count: uint64(len(entries))}
+	if p.currentHead != nil {
+		header.Head, header.Number = p.currentHead.Hash(), p.currentHead.Number.Uint64()
+	}
+	return header, entries
+}
+
+// Import reads a pool snapshot written by Export and adds its transactions to
+// the pool. The transactions are validated against the current state like any
+// other, so those included or invalidated since the snapshot was taken are
+// dropped. Dependencies are resolved and batches rebuilt anew; the transactions
+// whose dependencies or batch assignment differ from the snapshot are reported.
+func (p *ParallelPool) Import(r io.Reader) (*ImportResult, error) {
+	stream := rlp.NewStream(r, 0)
+
+	header := new(poolDumpHeader)
+	if err := stream.Decode(header); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshot header: %v", err)
+	}
+	if header.Version != poolDumpVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedDump, header.Version)
+	}
+	var (
+		result  = new(ImportResult)
+		entries = make([]*poolDumpEntry, 0, header.Count)
+	)
+	for {
+		entry := new(poolDumpEntry)
+		if err := stream.Decode(entry); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("entry %d: failed to parse: %v", len(entries), err)
+		}
+		entries = append(entries, entry)
+	}
+	// Add the transactions in snapshot order, keeping dependencies ahead of
+	// their dependents, switching between the local and remote path as needed
+	imported := make([]*poolDumpEntry, 0, len(entries))
+	for start := 0; start < len(entries); {
+		end := start + 1
+		for end < len(entries) && end-start < 1024 && entries[end].Local == entries[start].Local {
+			end++
+		}
+		txs := make([]*types.Transaction, 0, end-start)
+		for _, entry := range entries[start:end] {
+			txs = append(txs, entry.Tx)
+		}
+		for i, err := range p.addTxs(txs, entries[start].Local) {
+			switch {
+			case err == nil:
+				imported = append(imported, entries[start+i])
+			case errors.Is(err, txpool.ErrAlreadyKnown):
+				result.Known++
+			default:
+				log.Debug("Failed to import parallel transaction", "hash", txs[i].Hash(), "err", err)
+				result.Dropped++
+			}
+		}
+		start = end
+	}
+	result.Imported = len(imported)
+
+	// Compare the rebuilt pool metadata against the snapshot
+	p.mu.RLock()
+	p.batchMu.RLock()
+	batches := make(map[common.Hash]uint64)
+	for _, batch := range p.batchedTxs {
+		for _, tx := range batch.Transactions {
+			batches[tx.Hash()] = batch.BatchID
+		}
+	}
+	for _, entry := range imported {
+		id, batched := batches[entry.Tx.Hash()]
+		if batched != entry.Batched || id != entry.BatchID || !slices.Equal(p.dependencies[entry.Tx.Hash()], entry.Dependencies) {
+			result.Rebatched++
+		}
+	}
+	p.batchMu.RUnlock()
+	p.mu.RUnlock()
+
+	log.Info("Imported parallel transaction pool", "imported", result.Imported, "known", result.Known, "dropped", result.Dropped, "rebatched", result.Rebatched, "head", header.Number)
+	return result, nil
+}
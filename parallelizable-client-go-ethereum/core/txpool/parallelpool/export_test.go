@@ -0,0 +1,162 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// Tests that a pool snapshot restores the transactions, their senders' locality
+// and their batch assignments into a fresh pool, and that snapshots of unknown
+// versions are refused.
+func TestExportImport(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, parallelType uint8) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: parallelType,
+		})
+	}
+	var (
+		remotes = []*types.Transaction{
+			newTx(keys[0], 0, types.ParallelTypeIndependent),
+			newTx(keys[1], 0, types.ParallelTypeIndependent),
+			newTx(keys[1], 1, types.ParallelTypeSequential),
+		}
+		local = newTx(keys[2], 0, types.ParallelTypeIndependent)
+	)
+	source := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer source.Close()
+
+	for i, err := range source.Add(remotes, false) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if err := source.addTxs([]*types.Transaction{local}, true)[0]; err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	var dump bytes.Buffer
+	if n, err := source.Export(&dump); err != nil || n != 4 {
+		t.Fatalf("export mismatch: have %d (%v), want 4", n, err)
+	}
+	// Import the snapshot into an empty pool, twice
+	target := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer target.Close()
+
+	result, err := target.Import(bytes.NewReader(dump.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to import snapshot: %v", err)
+	}
+	if *result != (ImportResult{Imported: 4}) {
+		t.Errorf("import result mismatch: have %+v, want 4 imported", result)
+	}
+	for i, tx := range append(remotes, local) {
+		if have, want := target.Status(tx.Hash()), source.Status(tx.Hash()); have != want {
+			t.Errorf("tx %d: status mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if !target.locals.contains(crypto.PubkeyToAddress(keys[2].PublicKey)) {
+		t.Errorf("local sender not restored")
+	}
+	if target.locals.contains(crypto.PubkeyToAddress(keys[0].PublicKey)) {
+		t.Errorf("remote sender restored as local")
+	}
+	result, err = target.Import(bytes.NewReader(dump.Bytes()))
+	if err != nil {
+		t.Fatalf("failed to reimport snapshot: %v", err)
+	}
+	if *result != (ImportResult{Known: 4}) {
+		t.Errorf("reimport result mismatch: have %+v, want 4 known", result)
+	}
+	// Snapshots of other versions are refused
+	var future bytes.Buffer
+	rlp.Encode(&future, &poolDumpHeader{Version: poolDumpVersion + 1})
+	if _, err := target.Import(&future); !errors.Is(err, ErrUnsupportedDump) {
+		t.Errorf("future snapshot error mismatch: have %v, want %v", err, ErrUnsupportedDump)
+	}
+	if have := target.Status(remotes[0].Hash()); have != txpool.TxStatusPending {
+		t.Errorf("status mismatch after refused import: have %v, want pending", have)
+	}
+}
+
+// Tests that the RPC methods accessing files on the node need the admin methods
+// enabled, and round trip the pool through gzipped files otherwise.
+func TestExportPoolAPI(t *testing.T) {
+	pool := &ParallelPool{
+		config:  Config{MetricsRegistry: metrics.NewRegistry()},
+		metrics: newPoolMetrics("", metrics.NewRegistry()),
+	}
+	file := filepath.Join(t.TempDir(), "pool.rlp.gz")
+
+	api := NewParallelTxPoolAPI(pool)
+	if _, err := api.ExportPool(file); !errors.Is(err, ErrAdminDisabled) {
+		t.Errorf("export error mismatch: have %v, want %v", err, ErrAdminDisabled)
+	}
+	if _, err := api.ImportPool(file); !errors.Is(err, ErrAdminDisabled) {
+		t.Errorf("import error mismatch: have %v, want %v", err, ErrAdminDisabled)
+	}
+	pool.config.AdminAPI = true
+	if n, err := api.ExportPool(file); err != nil || n != 0 {
+		t.Fatalf("export mismatch: have %d (%v), want 0", n, err)
+	}
+	if _, err := api.ExportPool(file); err == nil {
+		t.Errorf("existing file overwritten")
+	}
+	if result, err := api.ImportPool(file); err != nil || *result != (ImportResult{}) {
+		t.Errorf("import mismatch: have %+v (%v), want empty", result, err)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// Footprint is the state accessed by a single execution, split into read and
+// write sets in the shape of access lists. Accounts accessed without touching
+// storage are listed without storage keys.
+type Footprint struct {
+	ReadSet         types.AccessList `json:"readSet"`
+	WriteSet        types.AccessList `json:"writeSet"`
+	TransientReads  types.AccessList `json:"transientReads"`
+	TransientWrites types.AccessList `json:"transientWrites"`
+	GasUsed         uint64           `json:"gasUsed"`
+	Failed          bool             `json:"failed"`          // Execution reverted
+	Error           string           `json:"error,omitempty"` // Message could not be applied
+}
+
+// footprintSet is a set of accessed accounts and their accessed slots.
+type footprintSet map[common.Address]map[common.Hash]struct{}
+
+// addAddress records an account access without any storage.
+func (s footprintSet) addAddress(addr common.Address) {
+	if _, ok := s[addr]; !ok {
+		s[addr] = make(map[common.Hash]struct{})
+	}
+}
+
+// addSlot records a storage slot access of an account.
+func (s footprintSet) addSlot(addr common.Address, slot common.Hash) {
+	s.addAddress(addr)
+	s[addr][slot] = struct{}{}
+}
+
+// accessList converts the set into an access list, sorted by address and slot
+// so that results are stable across runs.
+func (s footprintSet) accessList() types.AccessList {
+	list := make(types.AccessList, 0, len(s))
+	for addr, slots := range s {
+		tuple := types.AccessTuple{Address: addr, StorageKeys: make([]common.Hash, 0, len(slots))}
+		for slot := range slots {
+			tuple.StorageKeys = append(tuple.StorageKeys, slot)
+		}
+		slices.SortFunc(tuple.StorageKeys, func(a, b common.Hash) int { return a.Cmp(b) })
+		list = append(list, tuple)
+	}
+	slices.SortFunc(list, func(a, b types.AccessTuple) int { return a.Address.Cmp(b.Address) })
+	return list
+}
+
+// footprintTracer records the read and write sets of an execution. Unlike the
+// access list tracer it distinguishes reads from writes and tracks transient
+// storage, which is what parallel transaction declarations need.
+type footprintTracer struct {
+	reads, writes                   footprintSet
+	transientReads, transientWrites footprintSet
+	accounts                        footprintSet                // Accounts whose balance, nonce or code was written
+	excl                            map[common.Address]struct{} // Precompiles, never part of a footprint
+}
+
+// newFootprintTracer creates a tracer ignoring accesses to the given precompiles.
+func newFootprintTracer(precompiles []common.Address) *footprintTracer {
+	excl := make(map[common.Address]struct{}, len(precompiles))
+	for _, addr := range precompiles {
+		excl[addr] = struct{}{}
+	}
+	return &footprintTracer{
+		reads:           make(footprintSet),
+		writes:          make(footprintSet),
+		transientReads:  make(footprintSet),
+		transientWrites: make(footprintSet),
+		accounts:        make(footprintSet),
+		excl:            excl,
+	}
+}
+
+// hooks returns the tracing hooks feeding the tracer.
+func (t *footprintTracer) hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnEnter:  t.onEnter,
+		OnOpcode: t.onOpcode,
+		OnBalanceChange: func(addr common.Address, prev, new *big.Int, reason tracing.BalanceChangeReason) {
+			// The tip credited to the (zero) coinbase is an artifact of the
+			// simulation context, not part of the footprint
+			if reason != tracing.BalanceIncreaseRewardTransactionFee {
+				t.writeAccount(addr)
+			}
+		},
+		OnNonceChange: func(addr common.Address, prev, new uint64) {
+			t.writeAccount(addr)
+		},
+		OnCodeChange: func(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+			t.writeAccount(addr)
+		},
+	}
+}
+
+// writeAccount records a write to the balance, nonce or code of an account.
+func (t *footprintTracer) writeAccount(addr common.Address) {
+	t.writes.addAddress(addr)
+	t.accounts.addAddress(addr)
+}
+
+// onEnter records the accounts entered by calls and creations.
+func (t *footprintTracer) onEnter(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	if !t.excluded(to) {
+		t.reads.addAddress(to)
+	}
+}
+
+// onOpcode records storage, transient storage and account accesses.
+func (t *footprintTracer) onOpcode(pc uint64, opcode byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	stack := scope.StackData()
+	if len(stack) == 0 {
+		return
+	}
+	top := stack[len(stack)-1]
+
+	switch vm.OpCode(opcode) {
+	case vm.SLOAD:
+		t.reads.addSlot(scope.Address(), top.Bytes32())
+	case vm.SSTORE:
+		t.writes.addSlot(scope.Address(), top.Bytes32())
+	case vm.TLOAD:
+		t.transientReads.addSlot(scope.Address(), top.Bytes32())
+	case vm.TSTORE:
+		t.transientWrites.addSlot(scope.Address(), top.Bytes32())
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH:
+		if addr := common.Address(top.Bytes20()); !t.excluded(addr) {
+			t.reads.addAddress(addr)
+		}
+	case vm.SELFBALANCE:
+		t.reads.addAddress(scope.Address())
+	}
+}
+
+// excluded reports whether an account is left out of footprints.
+func (t *footprintTracer) excluded(addr common.Address) bool {
+	_, ok := t.excl[addr]
+	return ok
+}
+
+// traceMessage applies a message on top of the given state in the context of
+// the adapter, recording its footprint. The state is modified by the execution.
+func (p *ParallelPool) traceMessage(adapter *core.ExecAdapter, statedb *state.StateDB, msg *core.Message, noBaseFee bool) (*footprintTracer, *core.ExecutionResult, error) {
+	tracer := newFootprintTracer(vm.ActivePrecompiles(adapter.Rules()))
+	res, err := p.applyTraced(adapter, statedb, msg, tracer.hooks(), noBaseFee)
+	return tracer, res, err
+}
+
+// applyTraced applies a message on top of the given state in the context of the
+// adapter, feeding the execution to the given hooks.
+func (p *ParallelPool) applyTraced(adapter *core.ExecAdapter, statedb *state.StateDB, msg *core.Message, hooks *tracing.Hooks, noBaseFee bool) (*core.ExecutionResult, error) {
+	var (
+		blockCtx = core.NewEVMBlockContext(adapter.Header(), p.chain, &common.Address{})
+		evm      = vm.NewEVM(blockCtx, state.NewHookedState(statedb, hooks), p.chainconfig, vm.Config{Tracer: hooks, NoBaseFee: noBaseFee})
+	)
+	return core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.GasLimit))
+}
+
+// TraceFootprint executes a message on top of the current head state without
+// modifying it, and returns the exact state it read and wrote. Nonce and fee
+// checks are skipped, as with eth_call.
+func (p *ParallelPool) TraceFootprint(msg *core.Message) (*Footprint, error) {
+	head := p.chain.CurrentBlock()
+	statedb, err := p.stateAt(head.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for footprint trace: %v", err)
+	}
+	msg.SkipNonceChecks = true
+
+	footprint := new(Footprint)
+	tracer, res, err := p.traceMessage(core.NewPendingExecAdapter(p.chainconfig, p.chain, head), statedb, msg, true)
+	if err != nil {
+		footprint.Error = err.Error()
+	} else {
+		footprint.GasUsed, footprint.Failed = res.UsedGas, res.Failed()
+	}
+	footprint.ReadSet = tracer.reads.accessList()
+	footprint.WriteSet = tracer.writes.accessList()
+	footprint.TransientReads = tracer.transientReads.accessList()
+	footprint.TransientWrites = tracer.transientWrites.accessList()
+	return footprint, nil
+}
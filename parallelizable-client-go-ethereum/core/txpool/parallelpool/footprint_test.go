@@ -0,0 +1,100 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/beacon"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that footprint traces separate storage reads from writes, report
+// transient storage on its own and leave out simulation artifacts.
+func TestTraceFootprint(t *testing.T) {
+	var (
+		caller   = common.Address{0x01}
+		contract = common.Address{0xcc}
+		// SLOAD(1); SSTORE(2, 7); TSTORE(3, 7); TLOAD(4); STOP
+		code = common.FromHex("0x600154506007600255600760035d60045c5000")
+	)
+	gspec := &core.Genesis{
+		Config: params.MergedTestChainConfig,
+		Alloc:  types.GenesisAlloc{contract: {Code: code}},
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, beacon.New(ethash.NewFaker()), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	footprint, err := NewParallelTxPoolAPI(pool).TraceFootprint(context.Background(), FootprintArgs{From: caller, To: &contract})
+	if err != nil {
+		t.Fatalf("failed to trace footprint: %v", err)
+	}
+	if footprint.Error != "" || footprint.Failed {
+		t.Fatalf("traced call failed: %+v", footprint)
+	}
+	slot := func(n byte) common.Hash { return common.Hash{31: n} }
+
+	wantReads := types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot(1)}}}
+	if !equalAccessLists(footprint.ReadSet, wantReads) {
+		t.Errorf("read set mismatch: have %v, want %v", footprint.ReadSet, wantReads)
+	}
+	// The caller's nonce is bumped by the call, the zero coinbase is not touched
+	wantWrites := types.AccessList{
+		{Address: caller, StorageKeys: []common.Hash{}},
+		{Address: contract, StorageKeys: []common.Hash{slot(2)}},
+	}
+	if !equalAccessLists(footprint.WriteSet, wantWrites) {
+		t.Errorf("write set mismatch: have %v, want %v", footprint.WriteSet, wantWrites)
+	}
+	if want := (types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot(4)}}}); !equalAccessLists(footprint.TransientReads, want) {
+		t.Errorf("transient reads mismatch: have %v, want %v", footprint.TransientReads, want)
+	}
+	if want := (types.AccessList{{Address: contract, StorageKeys: []common.Hash{slot(3)}}}); !equalAccessLists(footprint.TransientWrites, want) {
+		t.Errorf("transient writes mismatch: have %v, want %v", footprint.TransientWrites, want)
+	}
+}
+
+// equalAccessLists reports whether two access lists are identical.
+func equalAccessLists(a, b types.AccessList) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Address != b[i].Address || len(a[i].StorageKeys) != len(b[i].StorageKeys) {
+			return false
+		}
+		for j := range a[i].StorageKeys {
+			if a[i].StorageKeys[j] != b[i].StorageKeys[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ErrFutureTxLimit is returned if a transaction would wait behind a nonce gap
+// while its sender already has the maximum number of such transactions pooled.
+// The gap has to be filled first, see parallel_nonceRepair.
+var ErrFutureTxLimit = errors.New("too many transactions behind a nonce gap")
+
+// futureTxs returns the pooled transactions of an account waiting behind a
+// nonce gap, in nonce order, along with the first nonce missing. The caller
+// must hold the pool lock.
+func (p *ParallelPool) futureTxs(addr common.Address) ([]*types.Transaction, uint64) {
+	pooled := p.accountTxs(addr)
+	slices.SortFunc(pooled, func(a, b *types.Transaction) int { return cmp.Compare(a.Nonce(), b.Nonce()) })
+
+	next := p.currentState.GetNonce(addr)
+	for i, tx := range pooled {
+		switch {
+		case tx.Nonce() < next:
+			continue
+		case tx.Nonce() == next:
+			next++
+		default:
+			return pooled[i:], next
+		}
+	}
+	return nil, next
+}
+
+// checkFutureLimit refuses a new transaction of an account if it would wait
+// behind a nonce gap and the account already has the maximum number of such
+// transactions pooled. The caller must hold the pool lock.
+func (p *ParallelPool) checkFutureLimit(from common.Address, tx *types.Transaction) error {
+	future, next := p.futureTxs(from)
+	if tx.Nonce() <= next || uint64(len(future)) < p.config.FutureSlots {
+		return nil
+	}
+	p.metrics.futureLimited.Mark(1)
+	return fmt.Errorf("%w: %d pooled behind missing nonce %d, limit %d", ErrFutureTxLimit, len(future), next, p.config.FutureSlots)
+}
+
+// demoteStuck moves the batched transactions of accounts stuck behind a nonce
+// gap for longer than the gap timeout to the queue. There they wait for the gap
+// to be filled, instead of failing batch after batch, and expire with the queue
+// lifetime if it never is. Exempt accounts, reserving ones in particular, keep
+// their future transactions batched. The caller must hold the pool lock.
+func (p *ParallelPool) demoteStuck() {
+	now := time.Now()
+
+	batched := make(map[common.Hash]struct{})
+	p.batchMu.RLock()
+	for _, txs := range p.parallelizableTxs {
+		for _, tx := range txs {
+			batched[tx.Hash()] = struct{}{}
+		}
+	}
+	accounts := make([]common.Address, 0, len(p.parallelizableTxs))
+	for addr := range p.parallelizableTxs {
+		accounts = append(accounts, addr)
+	}
+	p.batchMu.RUnlock()
+
+	var (
+		gapped  = make(map[common.Address]struct{})
+		demoted int
+	)
+	for _, addr := range accounts {
+		if p.exempt(addr) {
+			continue
+		}
+		future, next := p.futureTxs(addr)
+		stuck := slices.DeleteFunc(future, func(tx *types.Transaction) bool {
+			_, ok := batched[tx.Hash()]
+			return !ok
+		})
+		if len(stuck) == 0 {
+			continue
+		}
+		gapped[addr] = struct{}{}
+
+		since, ok := p.gaps[addr]
+		if !ok {
+			p.gaps[addr] = now
+			continue
+		}
+		if now.Sub(since) < p.config.GapTimeout {
+			continue
+		}
+		log.Debug("Demoting parallel transactions behind a nonce gap", "account", addr, "missing", next, "count", len(stuck), "since", since)
+		for _, tx := range stuck {
+			p.demote(tx.Hash())
+			p.postActivity(tx, ActivityDemoted)
+		}
+		demoted += len(stuck)
+		delete(p.gaps, addr)
+	}
+	// Forget the accounts whose gaps were filled or whose transactions left
+	for addr := range p.gaps {
+		if _, ok := gapped[addr]; !ok {
+			delete(p.gaps, addr)
+		}
+	}
+	if demoted > 0 {
+		p.metrics.futureDemoted.Mark(int64(demoted))
+		p.prepareBatches()
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that remote accounts may only pool a limited number of transactions
+// behind a nonce gap, and that batched ones are moved to the queue once the gap
+// persisted past the gap timeout.
+func TestFutureLimits(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	remote := crypto.PubkeyToAddress(keys[0].PublicKey)
+	local := crypto.PubkeyToAddress(keys[1].PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	pool := newTestPool(t, Config{
+		FutureSlots:     2,
+		GapTimeout:      time.Hour,
+		MetricsRegistry: metrics.NewRegistry(),
+	}, chain)
+	defer pool.Close()
+
+	// Fill the nonce-gapped slots of the remote account, further gapped
+	// transactions must be refused while those closing the gap are accepted
+	for i, err := range pool.Add([]*types.Transaction{newTx(keys[0], 2), newTx(keys[0], 3)}, false) {
+		if err != nil {
+			t.Fatalf("gapped transaction %d refused: %v", i, err)
+		}
+	}
+	if err := pool.Add([]*types.Transaction{newTx(keys[0], 4)}, false)[0]; !errors.Is(err, ErrFutureTxLimit) {
+		t.Fatalf("future limit error mismatch: have %v, want %v", err, ErrFutureTxLimit)
+	}
+	if have := pool.metrics.futureLimited.Snapshot().Count(); have != 1 {
+		t.Errorf("future limit meter mismatch: have %d, want 1", have)
+	}
+	if err := pool.Add([]*types.Transaction{newTx(keys[0], 0)}, false)[0]; err != nil {
+		t.Fatalf("gap closing transaction refused: %v", err)
+	}
+	// Local accounts are not limited
+	for nonce := uint64(1); nonce <= 3; nonce++ {
+		if err := pool.Process(newTx(keys[1], nonce), true); err != nil {
+			t.Fatalf("local gapped transaction %d refused: %v", nonce, err)
+		}
+	}
+	// The first pass only notes the gap, batched transactions are demoted once
+	// it persisted past the timeout
+	pool.mu.Lock()
+	pool.demoteStuck()
+	if _, ok := pool.gaps[remote]; !ok {
+		t.Fatalf("nonce gap of remote account not tracked")
+	}
+	if _, ok := pool.gaps[local]; ok {
+		t.Errorf("nonce gap of local account tracked")
+	}
+	if have := len(pool.parallelizableTxs[remote]); have != 3 {
+		t.Fatalf("batched transaction count mismatch: have %d, want 3", have)
+	}
+	pool.gaps[remote] = time.Now().Add(-2 * time.Hour)
+	pool.demoteStuck()
+
+	batched := pool.parallelizableTxs[remote]
+	queued := pool.queue[remote]
+	_, tracked := pool.gaps[remote]
+	pool.mu.Unlock()
+
+	if len(batched) != 1 || batched[0].Nonce() != 0 {
+		t.Errorf("batched transactions mismatch after demotion: have %d", len(batched))
+	}
+	if queued == nil || queued.Len() != 2 {
+		t.Errorf("gapped transactions not moved to the queue")
+	}
+	if tracked {
+		t.Errorf("nonce gap still tracked after demotion")
+	}
+	if have := pool.metrics.futureDemoted.Snapshot().Count(); have != 2 {
+		t.Errorf("future demotion meter mismatch: have %d, want 2", have)
+	}
+	// The limit counts the demoted transactions all the same
+	if err := pool.Add([]*types.Transaction{newTx(keys[0], 5)}, false)[0]; !errors.Is(err, ErrFutureTxLimit) {
+		t.Errorf("future limit error mismatch after demotion: have %v, want %v", err, ErrFutureTxLimit)
+	}
+}
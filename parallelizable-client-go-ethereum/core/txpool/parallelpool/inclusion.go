@@ -0,0 +1,145 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+const (
+	// maxDependencyWatches is the maximum number of dependencies watched for
+	// inclusion on behalf of remote peers.
+	maxDependencyWatches = 4096
+
+	// dependencyWatchBlocks is the number of blocks a dependency is watched for
+	// before the watch expires. It also bounds the blocks scanned on a reorg.
+	dependencyWatchBlocks = 64
+)
+
+// DependencyInclusion announces the inclusion of a transaction that remote
+// transactions depend on.
+type DependencyInclusion struct {
+	Hash   common.Hash `json:"hash"`   // Hash of the included transaction
+	Block  common.Hash `json:"block"`  // Hash of the block including it
+	Number uint64      `json:"number"` // Number of the block including it
+}
+
+// DependencyInclusionEvent is posted when transactions that a peer announced
+// dependents of were included, batching all inclusions for that peer into a
+// single announcement.
+type DependencyInclusionEvent struct {
+	Peer       string
+	Inclusions []DependencyInclusion
+}
+
+// dependencyWatch tracks the peers waiting for a dependency to be included.
+type dependencyWatch struct {
+	peers  map[string]struct{} // Peers that announced dependents
+	expiry uint64              // Block number after which the watch is dropped
+}
+
+// AddRemote adds transactions announced by a remote peer, and watches the
+// dependencies of the admitted ones to announce their inclusion back to the
+// peer, instead of leaving it waiting on block propagation.
+func (p *ParallelPool) AddRemote(peer string, txs []*types.Transaction) []error {
+	errs := p.Add(txs, false)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for i, tx := range txs {
+		if errs[i] == nil {
+			p.watchDependencies(peer, p.dependencies[tx.Hash()])
+		}
+	}
+	return errs
+}
+
+// SubscribeDependencyInclusionEvent registers a subscription for inclusions of
+// transactions that remote peers announced dependents of.
+func (p *ParallelPool) SubscribeDependencyInclusionEvent(ch chan<- DependencyInclusionEvent) event.Subscription {
+	return p.scope.Track(p.inclusionFeed.Subscribe(ch))
+}
+
+// watchDependencies starts watching the given dependencies for inclusion on
+// behalf of a peer. The caller must hold the pool lock.
+func (p *ParallelPool) watchDependencies(peer string, deps []common.Hash) {
+	for _, dep := range deps {
+		watch := p.watches[dep]
+		if watch == nil {
+			if len(p.watches) >= maxDependencyWatches {
+				p.metrics.watchOverflow.Mark(1)
+				continue
+			}
+			watch = &dependencyWatch{peers: make(map[string]struct{})}
+			p.watches[dep] = watch
+		}
+		watch.peers[peer] = struct{}{}
+		watch.expiry = p.currentHead.Number.Uint64() + dependencyWatchBlocks
+	}
+}
+
+// announceInclusions posts the inclusions of watched dependencies in the blocks
+// between the old and new heads, one event per waiting peer, and expires stale
+// watches. The caller must hold the pool lock.
+func (p *ParallelPool) announceInclusions(oldHead, newHead *types.Header) {
+	if len(p.watches) == 0 {
+		return
+	}
+	// Collect the inclusions of watched dependencies per waiting peer, walking
+	// back from the new head. Blocks of the old chain are not revisited.
+	inclusions := make(map[string][]DependencyInclusion)
+
+	hash, number := newHead.Hash(), newHead.Number.Uint64()
+	for i := 0; i < dependencyWatchBlocks && number > oldHead.Number.Uint64(); i++ {
+		block := p.chain.GetBlock(hash, number)
+		if block == nil {
+			break
+		}
+		for _, tx := range block.Transactions() {
+			watch := p.watches[tx.Hash()]
+			if watch == nil {
+				continue
+			}
+			for peer := range watch.peers {
+				inclusions[peer] = append(inclusions[peer], DependencyInclusion{Hash: tx.Hash(), Block: hash, Number: number})
+			}
+			delete(p.watches, tx.Hash())
+		}
+		hash, number = block.ParentHash(), number-1
+	}
+	// Drop the watches of dependencies that stayed out of the chain for too long
+	for dep, watch := range p.watches {
+		if watch.expiry < newHead.Number.Uint64() {
+			delete(p.watches, dep)
+		}
+	}
+	peers := make([]string, 0, len(inclusions))
+	for peer := range inclusions {
+		peers = append(peers, peer)
+	}
+	sort.Strings(peers)
+
+	for _, peer := range peers {
+		p.metrics.dependencyAnnounce.Mark(int64(len(inclusions[peer])))
+		p.inclusionFeed.Send(DependencyInclusionEvent{Peer: peer, Inclusions: inclusions[peer]})
+	}
+}
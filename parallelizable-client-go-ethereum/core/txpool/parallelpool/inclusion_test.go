@@ -0,0 +1,110 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the inclusion of watched dependencies is announced once per waiting
+// peer, batching all inclusions of the new blocks, and that unincluded
+// dependencies stay watched.
+func TestAnnounceInclusions(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	gspec := &core.Genesis{
+		Config:  params.TestChainConfig,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	txs := []*types.Transaction{
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &common.Address{0xaa}, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)}),
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 1, To: &common.Address{0xaa}, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)}),
+	}
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, gen *core.BlockGen) {
+		gen.AddTx(txs[i])
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	events := make(chan DependencyInclusionEvent, 4)
+	sub := pool.SubscribeDependencyInclusionEvent(events)
+	defer sub.Unsubscribe()
+
+	unknown := common.Hash{0x01}
+	pool.mu.Lock()
+	pool.watchDependencies("a", []common.Hash{txs[0].Hash(), txs[1].Hash()})
+	pool.watchDependencies("b", []common.Hash{txs[0].Hash(), unknown})
+	pool.mu.Unlock()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	pool.Reset(chain.GetHeaderByNumber(0), chain.CurrentBlock())
+
+	want := map[string][]DependencyInclusion{
+		"a": {
+			{Hash: txs[1].Hash(), Block: blocks[1].Hash(), Number: 2},
+			{Hash: txs[0].Hash(), Block: blocks[0].Hash(), Number: 1},
+		},
+		"b": {
+			{Hash: txs[0].Hash(), Block: blocks[0].Hash(), Number: 1},
+		},
+	}
+	for len(want) > 0 {
+		select {
+		case ev := <-events:
+			if len(ev.Inclusions) != len(want[ev.Peer]) {
+				t.Fatalf("peer %s: inclusion count mismatch: have %d, want %d", ev.Peer, len(ev.Inclusions), len(want[ev.Peer]))
+			}
+			for i, inclusion := range ev.Inclusions {
+				if inclusion != want[ev.Peer][i] {
+					t.Errorf("peer %s: inclusion %d mismatch: have %+v, want %+v", ev.Peer, i, inclusion, want[ev.Peer][i])
+				}
+			}
+			delete(want, ev.Peer)
+		default:
+			t.Fatalf("missing announcements for %d peers", len(want))
+		}
+	}
+	select {
+	case ev := <-events:
+		t.Fatalf("unexpected announcement: %+v", ev)
+	default:
+	}
+	if len(pool.watches) != 1 || pool.watches[unknown] == nil {
+		t.Errorf("watches mismatch: have %d, want only the unincluded dependency", len(pool.watches))
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// defaultRejournal is the interval the journal is regenerated at if none is
+// configured.
+const defaultRejournal = time.Hour
+
+// errNoActiveJournal is returned if a transaction is attempted to be inserted
+// into the journal, but no such file is currently open.
+var errNoActiveJournal = errors.New("no active journal")
+
+// devNull is a WriteCloser that just discards anything written into it, used
+// while loading the journal so that replayed transactions are not re-journaled.
+type devNull struct{}
+
+func (*devNull) Write(p []byte) (n int, err error) { return len(p), nil }
+func (*devNull) Close() error                      { return nil }
+
+// journal is a rotating log of transactions with the aim of storing locally
+// submitted parallel transactions to allow non-executed ones to survive node
+// restarts.
+type journal struct {
+	path   string         // Filesystem path to store the transactions at
+	writer io.WriteCloser // Output stream to write new transactions into
+}
+
+// newTxJournal creates a new transaction journal at the given path.
+func newTxJournal(path string) *journal {
+	return &journal{
+		path: path,
+	}
+}
+
+// load parses a transaction journal dump from disk, loading its contents into
+// the pool through the given callback.
+func (journal *journal) load(add func([]*types.Transaction) []error) error {
+	// Open the journal for loading any past transactions
+	input, err := os.Open(journal.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Skip the parsing if the journal file doesn't exist at all
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer input.Close()
+
+	// Temporarily discard any journal additions (don't double add on load)
+	journal.writer = new(devNull)
+	defer func() { journal.writer = nil }()
+
+	// Inject all transactions from the journal into the pool, in small-ish
+	// batches to bound the memory use
+	var (
+		stream  = rlp.NewStream(input, 0)
+		total   int
+		dropped int
+		failure error
+		batch   types.Transactions
+	)
+	loadBatch := func(txs types.Transactions) {
+		for _, err := range add(txs) {
+			if err != nil {
+				log.Debug("Failed to add journaled parallel transaction", "err", err)
+				dropped++
+			}
+		}
+	}
+	for {
+		// Parse the next transaction and terminate on error
+		tx := new(types.Transaction)
+		if err = stream.Decode(tx); err != nil {
+			if err != io.EOF {
+				failure = err
+			}
+			if batch.Len() > 0 {
+				loadBatch(batch)
+			}
+			break
+		}
+		total++
+
+		if batch = append(batch, tx); batch.Len() > 1024 {
+			loadBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	log.Info("Loaded local parallel transaction journal", "transactions", total, "dropped", dropped)
+
+	return failure
+}
+
+// insert adds the specified transaction to the local disk journal.
+func (journal *journal) insert(tx *types.Transaction) error {
+	if journal.writer == nil {
+		return errNoActiveJournal
+	}
+	return rlp.Encode(journal.writer, tx)
+}
+
+// rotate regenerates the transaction journal based on the current local
+// contents of the pool.
+func (journal *journal) rotate(all map[common.Address]types.Transactions) error {
+	// Close the current journal (if any is open)
+	if journal.writer != nil {
+		if err := journal.writer.Close(); err != nil {
+			return err
+		}
+		journal.writer = nil
+	}
+	// Generate a new journal with the contents of the current pool
+	replacement, err := os.OpenFile(journal.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	journaled := 0
+	for _, txs := range all {
+		for _, tx := range txs {
+			if err = rlp.Encode(replacement, tx); err != nil {
+				replacement.Close()
+				return err
+			}
+		}
+		journaled += len(txs)
+	}
+	replacement.Close()
+
+	// Replace the live journal with the newly generated one
+	if err = os.Rename(journal.path+".new", journal.path); err != nil {
+		return err
+	}
+	sink, err := os.OpenFile(journal.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	journal.writer = sink
+
+	logger := log.Info
+	if len(all) == 0 {
+		logger = log.Debug
+	}
+	logger("Regenerated local parallel transaction journal", "transactions", journaled, "accounts", len(all))
+
+	return nil
+}
+
+// close flushes the transaction journal contents to disk and closes the file.
+func (journal *journal) close() error {
+	var err error
+
+	if journal.writer != nil {
+		err = journal.writer.Close()
+		journal.writer = nil
+	}
+	return err
+}
+
+// addJournaled re-injects transactions loaded from the journal into the pool,
+// marking their senders as local.
+func (p *ParallelPool) addJournaled(txs []*types.Transaction) []error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		errs[i] = p.add(tx, true)
+		p.metrics.markOrigin(originJournal, errs[i])
+
+		if errs[i] == nil {
+			if from, err := types.Sender(p.signer, tx); err == nil {
+				p.locals.add(from)
+			}
+		}
+	}
+	return errs
+}
+
+// journalTx adds a local transaction to the journal, if journaling is enabled.
+// The caller must hold the pool lock.
+func (p *ParallelPool) journalTx(tx *types.Transaction) {
+	if p.journal == nil {
+		return
+	}
+	if err := p.journal.insert(tx); err != nil {
+		log.Warn("Failed to journal local parallel transaction", "err", err)
+	}
+}
+
+// local retrieves all the pooled transactions of local accounts, grouped by
+// account. The caller must hold the pool lock.
+func (p *ParallelPool) local() map[common.Address]types.Transactions {
+	txs := make(map[common.Address]types.Transactions)
+
+	p.batchMu.RLock()
+	defer p.batchMu.RUnlock()
+
+	for _, addr := range p.locals.addresses() {
+		for _, list := range []*parallelList{p.pending[addr], p.queue[addr]} {
+			if list != nil {
+				txs[addr] = append(txs[addr], list.flatten()...)
+			}
+		}
+		txs[addr] = append(txs[addr], p.parallelizableTxs[addr]...)
+		if len(txs[addr]) == 0 {
+			delete(txs, addr)
+		}
+	}
+	return txs
+}
+
+// journalLoop periodically regenerates the journal from the local transactions
+// still in the pool, dropping those that were included or evicted.
+func (p *ParallelPool) journalLoop() {
+	defer p.wg.Done()
+
+	interval := p.config.Rejournal
+	if interval <= 0 {
+		interval = defaultRejournal
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			p.mu.Lock()
+			if err := p.journal.rotate(p.local()); err != nil {
+				log.Warn("Failed to rotate local parallel transaction journal", "err", err)
+			}
+			p.mu.Unlock()
+
+		case <-p.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that journaled transactions are replayed on load, that rotation drops
+// the ones no longer pooled, and that loading does not re-journal.
+func TestJournalReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "parallel-transactions.rlp")
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	txs := []*types.Transaction{pricedTransaction(0, 1, key), pricedTransaction(1, 1, key), pricedTransaction(2, 1, key)}
+
+	journal := newTxJournal(path)
+	if err := journal.insert(txs[0]); !errors.Is(err, errNoActiveJournal) {
+		t.Fatalf("insert before rotation error mismatch: have %v, want %v", err, errNoActiveJournal)
+	}
+	if err := journal.rotate(map[common.Address]types.Transactions{addr: txs[:1]}); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	for _, tx := range txs[1:] {
+		if err := journal.insert(tx); err != nil {
+			t.Fatalf("failed to journal transaction: %v", err)
+		}
+	}
+	journal.close()
+
+	// Replay the journal, rejecting one transaction, and make sure nothing is
+	// journaled while loading
+	var loaded []*types.Transaction
+	journal = newTxJournal(path)
+	err := journal.load(func(txs []*types.Transaction) []error {
+		errs := make([]error, len(txs))
+		for i, tx := range txs {
+			if err := journal.insert(tx); err != nil {
+				t.Errorf("failed to discard journal write on load: %v", err)
+			}
+			loaded = append(loaded, tx)
+			if i == 1 {
+				errs[i] = errors.New("rejected")
+			}
+		}
+		return errs
+	})
+	if err != nil {
+		t.Fatalf("failed to load journal: %v", err)
+	}
+	if len(loaded) != len(txs) {
+		t.Fatalf("loaded transaction count mismatch: have %d, want %d", len(loaded), len(txs))
+	}
+	for i, tx := range txs {
+		if loaded[i].Hash() != tx.Hash() {
+			t.Errorf("loaded transaction %d mismatch", i)
+		}
+	}
+	// Rotating with only part of the transactions pooled shrinks the journal
+	if err := journal.rotate(map[common.Address]types.Transactions{addr: txs[2:]}); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	journal.close()
+
+	loaded = loaded[:0]
+	journal.load(func(txs []*types.Transaction) []error {
+		loaded = append(loaded, txs...)
+		return make([]error, len(txs))
+	})
+	if len(loaded) != 1 || loaded[0].Hash() != txs[2].Hash() {
+		t.Fatalf("rotated journal content mismatch: have %d transactions", len(loaded))
+	}
+}
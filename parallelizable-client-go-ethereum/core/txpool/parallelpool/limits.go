@@ -0,0 +1,153 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/prque"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// full reports whether every transaction slot of the pool is taken, in which
+// case new remote transactions are refused.
+func (p *ParallelPool) full() bool {
+	return uint64(len(p.all)) >= p.config.GlobalSlots+p.config.GlobalQueue
+}
+
+// truncate brings the pool back within its configured slot limits. The caller
+// must hold the pool lock.
+func (p *ParallelPool) truncate() {
+	p.truncatePending()
+	p.truncateQueue()
+}
+
+// highestPending returns the executable transaction of an account with the
+// highest nonce, be it sequential or awaiting batch execution.
+func (p *ParallelPool) highestPending(addr common.Address) *types.Transaction {
+	var highest *types.Transaction
+	if list := p.pending[addr]; list != nil {
+		if txs := list.flatten(); len(txs) > 0 {
+			highest = txs[len(txs)-1]
+		}
+	}
+	p.batchMu.RLock()
+	defer p.batchMu.RUnlock()
+
+	for _, tx := range p.parallelizableTxs[addr] {
+		if highest == nil || tx.Nonce() > highest.Nonce() {
+			highest = tx
+		}
+	}
+	return highest
+}
+
+// truncatePending drops executable transactions if the pool is above the global
+// slot limit. Remote accounts exceeding their guaranteed slots are penalized,
+// largest first, by dropping their highest nonce transactions until they are
+// equalized or the pool fits again.
+func (p *ParallelPool) truncatePending() {
+	var (
+		counts  = make(map[common.Address]uint64)
+		pending uint64
+	)
+	for addr, list := range p.pending {
+		counts[addr] += uint64(list.Len())
+	}
+	p.batchMu.RLock()
+	for addr, txs := range p.parallelizableTxs {
+		counts[addr] += uint64(len(txs))
+	}
+	p.batchMu.RUnlock()
+
+	for _, count := range counts {
+		pending += count
+	}
+	if pending <= p.config.GlobalSlots {
+		return
+	}
+	// Assemble a spam order to penalize large transactors first
+	spammers := prque.New[int64, common.Address](nil)
+	for addr, count := range counts {
+		if count > p.config.AccountSlots && !p.exempt(addr) {
+			spammers.Push(addr, int64(count))
+		}
+	}
+	var dropped int64
+	for pending > p.config.GlobalSlots && !spammers.Empty() {
+		offender, count := spammers.Pop()
+
+		p.dropTx(p.highestPending(offender).Hash(), DropRateLimit)
+		pending--
+		dropped++
+
+		if uint64(count-1) > p.config.AccountSlots {
+			spammers.Push(offender, count-1)
+		}
+	}
+	p.metrics.pendingRateLimit.Mark(dropped)
+}
+
+// truncateQueue drops non-executable transactions of remote accounts above the
+// per-account queue limit, and then those of the least recently active accounts
+// if the pool is above the global queue limit.
+func (p *ParallelPool) truncateQueue() {
+	var dropped int64
+	for addr, list := range p.queue {
+		if p.exempt(addr) {
+			continue
+		}
+		if over := list.Len() - int(p.config.AccountQueue); over > 0 {
+			txs := list.Flatten()
+			for _, tx := range txs[len(txs)-over:] {
+				p.dropTx(tx.Hash(), DropRateLimit)
+			}
+			dropped += int64(over)
+		}
+	}
+	var queued uint64
+	for _, list := range p.queue {
+		queued += uint64(list.Len())
+	}
+	if queued > p.config.GlobalQueue {
+		// Sort the remote accounts with queued transactions by heartbeat
+		type heartbeat struct {
+			addr common.Address
+			beat time.Time
+		}
+		beats := make([]heartbeat, 0, len(p.queue))
+		for addr := range p.queue {
+			if !p.exempt(addr) {
+				beats = append(beats, heartbeat{addr, p.beats[addr]})
+			}
+		}
+		slices.SortFunc(beats, func(a, b heartbeat) int { return a.beat.Compare(b.beat) })
+
+		// Drop transactions of the oldest accounts until the total is below the limit
+		for drop := queued - p.config.GlobalQueue; drop > 0 && len(beats) > 0; beats = beats[1:] {
+			txs := p.queue[beats[0].addr].Flatten()
+			for i := len(txs) - 1; i >= 0 && drop > 0; i-- {
+				p.dropTx(txs[i].Hash(), DropRateLimit)
+				drop--
+				dropped++
+			}
+		}
+	}
+	p.metrics.queuedRateLimit.Mark(dropped)
+}
@@ -0,0 +1,145 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the per-account queue cap, the global executable slot limit and
+// the overall pool capacity are enforced, updating the rate-limit, discard and
+// overflow meters.
+func TestSlotLimits(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 5)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, parallelType uint8) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: parallelType,
+		})
+	}
+	pool := newTestPool(t, Config{
+		AccountSlots:    2,
+		GlobalSlots:     4,
+		AccountQueue:    2,
+		GlobalQueue:     4,
+		MetricsRegistry: metrics.NewRegistry(),
+	}, chain)
+	defer pool.Close()
+
+	// Queue a gapped run beyond the account queue cap, the highest nonces must
+	// be dropped
+	var queued []*types.Transaction
+	for nonce := uint64(1); nonce <= 4; nonce++ {
+		queued = append(queued, newTx(keys[0], nonce, types.ParallelTypeSequential))
+	}
+	pool.Add(queued, false)
+	if _, have := pool.Stats(); have != 2 {
+		t.Fatalf("queued count mismatch: have %d, want 2", have)
+	}
+	if have := pool.metrics.queuedRateLimit.Snapshot().Count(); have != 2 {
+		t.Errorf("queued rate-limit meter mismatch: have %d, want 2", have)
+	}
+	// Exceed the global executable slots, the largest account must be brought
+	// down to its guaranteed slots
+	var executable []*types.Transaction
+	for nonce := uint64(0); nonce < 4; nonce++ {
+		executable = append(executable, newTx(keys[1], nonce, types.ParallelTypeIndependent))
+	}
+	executable = append(executable, newTx(keys[2], 0, types.ParallelTypeIndependent), newTx(keys[2], 1, types.ParallelTypeIndependent))
+	pool.Add(executable, false)
+	if have, _ := pool.Stats(); have != 4 {
+		t.Fatalf("pending count mismatch: have %d, want 4", have)
+	}
+	for i, tx := range executable[:4] {
+		if have, want := pool.Has(tx.Hash()), i < 2; have != want {
+			t.Errorf("transaction %d presence mismatch: have %v, want %v", i, have, want)
+		}
+	}
+	if have := pool.metrics.pendingRateLimit.Snapshot().Count(); have != 2 {
+		t.Errorf("pending rate-limit meter mismatch: have %d, want 2", have)
+	}
+	// Underpriced replacements are discarded
+	replacement := types.MustSignNewTx(keys[1], signer, &types.ParallelTx{
+		ChainID:   config.ChainID,
+		GasTipCap: common.Big1,
+		GasFeeCap: big.NewInt(2 * params.InitialBaseFee),
+		Gas:       params.TxGas,
+		To:        &common.Address{0xbb},
+		Value:     common.Big0,
+	})
+	if err := pool.Add([]*types.Transaction{replacement}, false)[0]; !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+		t.Errorf("replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+	}
+	if have := pool.metrics.pendingDiscard.Snapshot().Count(); have != 1 {
+		t.Errorf("pending discard meter mismatch: have %d, want 1", have)
+	}
+	// Fill the remaining slots, further remote transactions must be refused
+	// unless they score better than a pooled one, while local ones are still
+	// accepted
+	pool.Add([]*types.Transaction{newTx(keys[3], 1, types.ParallelTypeSequential), newTx(keys[3], 2, types.ParallelTypeSequential)}, false)
+	if err := pool.Add([]*types.Transaction{newTx(keys[3], 3, types.ParallelTypeSequential)}, false)[0]; !errors.Is(err, ErrTxPoolOverflow) {
+		t.Errorf("overflow error mismatch: have %v, want %v", err, ErrTxPoolOverflow)
+	}
+	if have := pool.metrics.overflowed.Snapshot().Count(); have != 1 {
+		t.Errorf("overflow meter mismatch: have %d, want 1", have)
+	}
+	if err := pool.Add([]*types.Transaction{newTx(keys[4], 0, types.ParallelTypeIndependent)}, false)[0]; err != nil {
+		t.Errorf("displacing transaction refused: %v", err)
+	}
+	if have := pool.metrics.discarded.Snapshot().Count(); have != 1 {
+		t.Errorf("discard meter mismatch: have %d, want 1", have)
+	}
+	if err := pool.Process(newTx(keys[4], 1, types.ParallelTypeIndependent), true); err != nil {
+		t.Errorf("local transaction refused: %v", err)
+	}
+}
@@ -17,6 +17,7 @@
 package parallelpool
 
 import (
+	"math/big"
 	"sort"
 	"sync"
 
@@ -29,6 +30,10 @@ type parallelList struct {
 	items map[uint64]*types.Transaction // Transactions indexed by nonce
 	txs   map[common.Hash]*types.Transaction
 	mu    sync.RWMutex
+
+	cache   []*types.Transaction // Cache of the transactions already sorted by nonce
+	ready   []*types.Transaction // Cache of the transactions already sorted for execution
+	cacheMu sync.Mutex
 }
 
 // newParallelList creates a new list to store parallel transactions
@@ -40,8 +45,10 @@ func newParallelList() *parallelList {
 }
 
 // Add adds a new transaction to the list, returning whether the transaction was
-// added and if an old was removed
-func (l *parallelList) Add(tx *types.Transaction) bool {
+// added and the old transaction it replaced, if any. A transaction with the same
+// nonce as a listed one only replaces it if it bumps both its fee cap and tip by
+// at least priceBump percent.
+func (l *parallelList) Add(tx *types.Transaction, priceBump uint64) (bool, *types.Transaction) {
 	hash := tx.Hash()
 	nonce := tx.Nonce()
 
@@ -49,24 +56,38 @@ func (l *parallelList) Add(tx *types.Transaction) bool {
 	defer l.mu.Unlock()
 
 	if _, ok := l.txs[hash]; ok {
-		return false
+		return false, nil
 	}
-
-	if old, ok := l.items[nonce]; ok {
-		// There's already a transaction with this nonce
-		// Replace if the new one has a higher gas price
-		if tx.GasPrice().Cmp(old.GasPrice()) > 0 {
-			l.items[nonce] = tx
-			l.txs[hash] = tx
-			delete(l.txs, old.Hash())
-			return true
+	old := l.items[nonce]
+	if old != nil {
+		if !replaces(old, tx, priceBump) {
+			return false, nil
 		}
-		return false
+		delete(l.txs, old.Hash())
 	}
-
 	l.items[nonce] = tx
 	l.txs[hash] = tx
-	return true
+	l.invalidate()
+	return true, old
+}
+
+// replaces reports whether a transaction may replace an old one with the same
+// nonce, bumping both its fee cap and tip by at least priceBump percent.
+func replaces(old, tx *types.Transaction, priceBump uint64) bool {
+	// Have to ensure that the new gas price is strictly higher than the old one,
+	// so that a zero price bump doesn't allow replacing with the same prices
+	if old.GasFeeCapCmp(tx) >= 0 || old.GasTipCapCmp(tx) >= 0 {
+		return false
+	}
+	var (
+		a        = big.NewInt(100 + int64(priceBump))
+		aFeeCap  = new(big.Int).Mul(a, old.GasFeeCap())
+		aTip     = a.Mul(a, old.GasTipCap())
+		b        = big.NewInt(100)
+		feeCapTh = aFeeCap.Div(aFeeCap, b)
+		tipTh    = aTip.Div(aTip, b)
+	)
+	return tx.GasFeeCapIntCmp(feeCapTh) >= 0 && tx.GasTipCapIntCmp(tipTh) >= 0
 }
 
 // Get returns a transaction if it exists in the list
@@ -96,6 +117,15 @@ func (l *parallelList) Remove(hash common.Hash) {
 	}
 	delete(l.items, tx.Nonce())
 	delete(l.txs, hash)
+	l.invalidate()
+}
+
+// invalidate drops the cached orderings after the list was modified. The caller
+// must hold the write lock.
+func (l *parallelList) invalidate() {
+	l.cacheMu.Lock()
+	l.cache, l.ready = nil, nil
+	l.cacheMu.Unlock()
 }
 
 // Ready returns a nonce-sorted slice of transactions that are ready to be executed.
@@ -110,6 +140,20 @@ func (l *parallelList) Ready() []*types.Transaction {
 	if len(l.items) == 0 {
 		return nil
 	}
+	l.cacheMu.Lock()
+	defer l.cacheMu.Unlock()
+
+	// If the ordering was not cached yet, create and cache it
+	if l.ready == nil {
+		l.ready = l.sortReady()
+	}
+	// Copy the cache to prevent accidental modification
+	return append([]*types.Transaction(nil), l.ready...)
+}
+
+// sortReady orders the transactions for execution. The caller must hold the
+// read lock.
+func (l *parallelList) sortReady() []*types.Transaction {
 
 	// First, get all transactions
 	txs := make([]*types.Transaction, 0, len(l.items))
@@ -171,24 +215,41 @@ func isParallelizableTx(tx *types.Transaction, txData *ParallelTxData) bool {
 	return len(txData.Dependencies) == 0
 }
 
-// Flatten returns a nonce-sorted slice of transactions
-func (l *parallelList) Flatten() []*types.Transaction {
+// flatten returns a nonce-sorted slice of transactions, shared with the cache.
+// The returned slice must not be modified nor retained beyond the pool lock.
+func (l *parallelList) flatten() []*types.Transaction {
 	l.mu.RLock()
 	defer l.mu.RUnlock()
 
 	if len(l.items) == 0 {
 		return nil
 	}
-
-	txs := make([]*types.Transaction, 0, len(l.items))
-	for _, tx := range l.items {
-		txs = append(txs, tx)
+	l.cacheMu.Lock()
+	defer l.cacheMu.Unlock()
+
+	// If the sorting was not cached yet, create and cache it
+	if l.cache == nil {
+		l.cache = make([]*types.Transaction, 0, len(l.items))
+		for _, tx := range l.items {
+			l.cache = append(l.cache, tx)
+		}
+		sort.Slice(l.cache, func(i, j int) bool {
+			return l.cache[i].Nonce() < l.cache[j].Nonce()
+		})
 	}
+	return l.cache
+}
 
-	sort.Slice(txs, func(i, j int) bool {
-		return txs[i].Nonce() < txs[j].Nonce()
-	})
-
+// Flatten returns a nonce-sorted slice of transactions, safe for the caller to
+// retain and modify.
+func (l *parallelList) Flatten() []*types.Transaction {
+	cache := l.flatten()
+	if cache == nil {
+		return nil
+	}
+	// Copy the cache to prevent accidental modification
+	txs := make([]*types.Transaction, len(cache))
+	copy(txs, cache)
 	return txs
 }
 
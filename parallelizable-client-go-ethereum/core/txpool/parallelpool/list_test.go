@@ -0,0 +1,74 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that the flattened list is cached across calls, invalidated on every
+// mutation, and that returned copies do not alias the cache.
+func TestListFlattenCache(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	list := newParallelList()
+
+	list.Add(pricedTransaction(1, 1, key), 0)
+	list.Add(pricedTransaction(0, 1, key), 0)
+
+	first, second := list.flatten(), list.flatten()
+	if len(first) != 2 || &first[0] != &second[0] {
+		t.Fatalf("flattened list not cached")
+	}
+	if first[0].Nonce() != 0 || first[1].Nonce() != 1 {
+		t.Fatalf("flattened list not nonce sorted")
+	}
+	// Modifying a returned copy must not corrupt the cache
+	flat := list.Flatten()
+	flat[0] = nil
+	if list.flatten()[0] == nil {
+		t.Fatalf("returned copy aliases the cache")
+	}
+	ready := list.Ready()
+	ready[0] = nil
+	if list.Ready()[0] == nil {
+		t.Fatalf("returned ready list aliases the cache")
+	}
+	// Additions, replacements and removals must invalidate the caches
+	list.Add(pricedTransaction(2, 1, key), 0)
+	if n := len(list.Flatten()); n != 3 {
+		t.Fatalf("flattened length mismatch after add: have %d, want 3", n)
+	}
+	if n := len(list.Ready()); n != 3 {
+		t.Fatalf("ready length mismatch after add: have %d, want 3", n)
+	}
+	replacement := pricedTransaction(1, 2, key)
+	list.Add(replacement, 0)
+	if tx := list.Flatten()[1]; tx.Hash() != replacement.Hash() {
+		t.Fatalf("flattened list not refreshed after replacement")
+	}
+	list.Remove(replacement.Hash())
+	if n := len(list.Flatten()); n != 2 {
+		t.Fatalf("flattened length mismatch after remove: have %d, want 2", n)
+	}
+	list.Remove(list.Get(0).Hash())
+	list.Remove(list.Get(2).Hash())
+	if txs := list.Flatten(); txs != nil {
+		t.Fatalf("empty list flattened to %d transactions", len(txs))
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+var (
+	// ErrUnknownBatch is returned when requesting a membership proof for a batch
+	// that is not currently prepared.
+	ErrUnknownBatch = errors.New("unknown batch")
+
+	// ErrNotBatchMember is returned when requesting a membership proof for a
+	// transaction that is not a member of the batch.
+	ErrNotBatchMember = errors.New("transaction not a batch member")
+
+	// ErrInvalidMembershipProof is returned if a membership proof does not lead
+	// from the transaction to the batch root.
+	ErrInvalidMembershipProof = errors.New("invalid batch membership proof")
+)
+
+// MembershipHashing is the hash function the Merkle roots over batch members are
+// built with, selectable so that consumers can verify proofs with whatever hash
+// is cheapest on their side (e.g. SHA-256 precompiles on other chains).
+type MembershipHashing uint8
+
+const (
+	// HashKeccak256 builds the member trees with Keccak-256.
+	HashKeccak256 MembershipHashing = iota
+
+	// HashSHA256 builds the member trees with SHA-256.
+	HashSHA256
+)
+
+// String implements fmt.Stringer.
+func (h MembershipHashing) String() string {
+	switch h {
+	case HashKeccak256:
+		return "keccak256"
+	case HashSHA256:
+		return "sha256"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (h MembershipHashing) MarshalText() ([]byte, error) {
+	if h > HashSHA256 {
+		return nil, fmt.Errorf("unknown membership hashing %d", uint8(h))
+	}
+	return []byte(h.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (h *MembershipHashing) UnmarshalText(input []byte) error {
+	switch string(input) {
+	case "keccak256":
+		*h = HashKeccak256
+	case "sha256":
+		*h = HashSHA256
+	default:
+		return fmt.Errorf("unknown membership hashing %q", input)
+	}
+	return nil
+}
+
+// node hashes two sibling nodes of a member tree into their parent.
+func (h MembershipHashing) node(left, right common.Hash) common.Hash {
+	if h == HashSHA256 {
+		return sha256.Sum256(append(left[:], right[:]...))
+	}
+	return crypto.Keccak256Hash(left[:], right[:])
+}
+
+// membersRoot returns the root of the binary Merkle tree over the ordered member
+// hashes of a batch. The member hashes are the leaves; a node without a sibling
+// is carried up a level unchanged, so a single member is its own root. The root
+// of an empty batch is the zero hash.
+func membersRoot(hashes []common.Hash, hashing MembershipHashing) common.Hash {
+	if len(hashes) == 0 {
+		return common.Hash{}
+	}
+	level := append([]common.Hash(nil), hashes...)
+	for len(level) > 1 {
+		level = nextLevel(level, hashing)
+	}
+	return level[0]
+}
+
+// nextLevel hashes a level of a member tree into the one above it.
+func nextLevel(level []common.Hash, hashing MembershipHashing) []common.Hash {
+	next := make([]common.Hash, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+		} else {
+			next = append(next, hashing.node(level[i], level[i+1]))
+		}
+	}
+	return next
+}
+
+// MembershipProof proves that a transaction is a member of a batch, allowing
+// light consumers holding only the batch root (e.g. from a signed descriptor)
+// to verify membership without the member list.
+type MembershipProof struct {
+	BatchID  uint64            `json:"batchID"`
+	TxHash   common.Hash       `json:"txHash"`
+	Index    hexutil.Uint      `json:"index"`    // Position of the transaction in the batch
+	Members  hexutil.Uint      `json:"members"`  // Number of members of the batch, fixing the tree shape
+	Siblings []common.Hash     `json:"siblings"` // Sibling nodes from the leaf up to the root
+	Hashing  MembershipHashing `json:"hashing"`
+	Root     common.Hash       `json:"root"`
+}
+
+// newMembershipProof creates the membership proof of the member at the given
+// index of a batch.
+func newMembershipProof(batchID uint64, hashes []common.Hash, index int, hashing MembershipHashing) *MembershipProof {
+	proof := &MembershipProof{
+		BatchID: batchID,
+		TxHash:  hashes[index],
+		Index:   hexutil.Uint(index),
+		Members: hexutil.Uint(len(hashes)),
+		Hashing: hashing,
+		Root:    membersRoot(hashes, hashing),
+	}
+	level := hashes
+	for pos := index; len(level) > 1; pos /= 2 {
+		if sibling := pos ^ 1; sibling < len(level) {
+			proof.Siblings = append(proof.Siblings, level[sibling])
+		}
+		level = nextLevel(level, hashing)
+	}
+	return proof
+}
+
+// Verify checks that the proof leads from the transaction to the batch root.
+func (p *MembershipProof) Verify() error {
+	if p.Members == 0 || p.Index >= p.Members {
+		return ErrInvalidMembershipProof
+	}
+	var (
+		node     = p.TxHash
+		siblings = p.Siblings
+	)
+	for pos, width := int(p.Index), int(p.Members); width > 1; pos, width = pos/2, (width+1)/2 {
+		sibling := pos ^ 1
+		if sibling >= width {
+			continue // Carried up unchanged
+		}
+		if len(siblings) == 0 {
+			return ErrInvalidMembershipProof
+		}
+		if pos%2 == 0 {
+			node = p.Hashing.node(node, siblings[0])
+		} else {
+			node = p.Hashing.node(siblings[0], node)
+		}
+		siblings = siblings[1:]
+	}
+	if len(siblings) != 0 || node != p.Root {
+		return ErrInvalidMembershipProof
+	}
+	return nil
+}
+
+// MembershipProof returns the proof of a transaction's membership in one of the
+// currently prepared batches, built with the configured membership hashing.
+func (p *ParallelPool) MembershipProof(batchID uint64, hash common.Hash) (*MembershipProof, error) {
+	for _, batch := range p.GetBatches() {
+		if batch.BatchID != batchID {
+			continue
+		}
+		hashes := make([]common.Hash, len(batch.Transactions))
+		index := -1
+		for i, tx := range batch.Transactions {
+			hashes[i] = tx.Hash()
+			if hashes[i] == hash {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, ErrNotBatchMember
+		}
+		return newMembershipProof(batchID, hashes, index, p.config.MembershipHashing), nil
+	}
+	return nil, ErrUnknownBatch
+}
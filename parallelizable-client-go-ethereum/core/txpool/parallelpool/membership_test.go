@@ -0,0 +1,120 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that membership proofs of every member of batches of various sizes verify
+// against the members root with both hash functions, and that tampered proofs
+// are rejected.
+func TestMembershipProofs(t *testing.T) {
+	for _, hashing := range []MembershipHashing{HashKeccak256, HashSHA256} {
+		for size := 1; size <= 9; size++ {
+			hashes := make([]common.Hash, size)
+			for i := range hashes {
+				hashes[i] = common.Hash{byte(i + 1)}
+			}
+			root := membersRoot(hashes, hashing)
+			for i := range hashes {
+				proof := newMembershipProof(1, hashes, i, hashing)
+				if proof.Root != root {
+					t.Fatalf("%v/%d/%d: root mismatch: have %x, want %x", hashing, size, i, proof.Root, root)
+				}
+				if err := proof.Verify(); err != nil {
+					t.Fatalf("%v/%d/%d: failed to verify proof: %v", hashing, size, i, err)
+				}
+				forged := *proof
+				forged.TxHash = common.Hash{0xff}
+				if err := forged.Verify(); !errors.Is(err, ErrInvalidMembershipProof) {
+					t.Errorf("%v/%d/%d: forged member error mismatch: have %v, want %v", hashing, size, i, err, ErrInvalidMembershipProof)
+				}
+				if size > 1 {
+					forged = *proof
+					forged.Index ^= 1
+					if int(forged.Index) < size {
+						if err := forged.Verify(); !errors.Is(err, ErrInvalidMembershipProof) {
+							t.Errorf("%v/%d/%d: forged index error mismatch: have %v, want %v", hashing, size, i, err, ErrInvalidMembershipProof)
+						}
+					}
+				}
+			}
+		}
+	}
+	// The hash functions must actually yield different roots
+	hashes := []common.Hash{{0x01}, {0x02}}
+	if membersRoot(hashes, HashKeccak256) == membersRoot(hashes, HashSHA256) {
+		t.Errorf("keccak256 and sha256 roots match")
+	}
+}
+
+// Tests that membership proofs are served for prepared batches only and that the
+// hash function round-trips through JSON.
+func TestPoolMembershipProof(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	batch := TxBatch{Transactions: []*types.Transaction{
+		pricedTransaction(0, 1, key),
+		pricedTransaction(1, 1, key),
+		pricedTransaction(2, 1, key),
+	}}
+	batch.BatchID = batchID(batch)
+
+	pool := &ParallelPool{
+		config:     Config{MembershipHashing: HashSHA256},
+		batchedTxs: []TxBatch{batch},
+	}
+
+	member := batch.Transactions[1].Hash()
+	proof, err := pool.MembershipProof(batch.BatchID, member)
+	if err != nil {
+		t.Fatalf("failed to retrieve proof: %v", err)
+	}
+	descs, err := pool.ExportBatches()
+	if err != nil {
+		t.Fatalf("failed to export batches: %v", err)
+	}
+	if proof.Root != descs[0].MembersRoot {
+		t.Fatalf("proof root mismatch: have %x, want %x", proof.Root, descs[0].MembersRoot)
+	}
+	blob, err := json.Marshal(proof)
+	if err != nil {
+		t.Fatalf("failed to encode proof: %v", err)
+	}
+	var decoded MembershipProof
+	if err := json.Unmarshal(blob, &decoded); err != nil {
+		t.Fatalf("failed to decode proof: %v", err)
+	}
+	if decoded.Hashing != HashSHA256 {
+		t.Fatalf("hashing mismatch: have %v, want %v", decoded.Hashing, HashSHA256)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Fatalf("failed to verify decoded proof: %v", err)
+	}
+	if _, err := pool.MembershipProof(batch.BatchID+1, member); !errors.Is(err, ErrUnknownBatch) {
+		t.Errorf("unknown batch error mismatch: have %v, want %v", err, ErrUnknownBatch)
+	}
+	if _, err := pool.MembershipProof(batch.BatchID, common.Hash{0x01}); !errors.Is(err, ErrNotBatchMember) {
+		t.Errorf("non-member error mismatch: have %v, want %v", err, ErrNotBatchMember)
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// defaultMetricsNamespace is the prefix of the per-instance metrics of pools
+// created without an explicit namespace.
+const defaultMetricsNamespace = "parallel/txpool"
+
+// poolMetrics are the gauges and meters reflecting the state of a single pool
+// instance. They are namespaced per pool so that several pools can coexist in
+// one process (tests, multi-chain nodes) without clobbering each other.
+type poolMetrics struct {
+	pending        *metrics.Gauge // Accounts with pending sequential transactions
+	queued         *metrics.Gauge // Accounts with queued sequential transactions
+	local          *metrics.Gauge // Local transactions
+	slots          *metrics.Gauge // Occupied transaction slots
+	batchSize      *metrics.Gauge // Configured batch size
+	batchCount     *metrics.Gauge // Number of prepared batches
+	parallelizable *metrics.Gauge // Accounts with parallelizable transactions
+	orphans        *metrics.Gauge // Transactions held back for unresolved dependencies
+	executed       *metrics.Meter // Transactions executed through batches
+
+	addWait     *metrics.Timer // Time insertions wait for the pool lock
+	addHold     *metrics.Timer // Time insertions hold the pool lock
+	resetTime   *metrics.Timer // Time taken to switch the pool over to a new head
+	promoteTime *metrics.Timer // Time taken to promote queued transactions to pending
+	prepareTime *metrics.Timer // Time the batches are rebuilt for, holding the batch lock
+
+	taggedParallel   *metrics.Meter // Admitted transactions tagged PARALLEL
+	taggedSequential *metrics.Meter // Admitted transactions tagged SEQUENTIAL
+	untagged         *metrics.Meter // Admitted transactions without a tag
+	tagConflicts     *metrics.Meter // PARALLEL-tagged transactions later found conflicting
+	tagUpgraded      *metrics.Meter // Untagged payloads upgraded to tagged transactions
+	tagLegacy        *metrics.Meter // Admitted transactions routed by a deprecated calldata tag
+	tagRejected      *metrics.Meter // Transactions rejected for a deprecated calldata tag in strict mode
+	tagClassified    *metrics.Meter // Transactions tagged by the calldata classifier for lack of a tag
+	tagOversized     *metrics.Meter // PARALLEL-tagged transactions too large to batch, handled sequentially
+
+	known           *metrics.Meter // Transactions rejected as already pooled
+	underpriced     *metrics.Meter // Transactions dropped below a raised gas tip
+	nofunds         *metrics.Meter // Transactions dropped as no longer affordable
+	evicted         *metrics.Meter // Queued transactions dropped after the queue lifetime
+	overflowed      *metrics.Meter // Transactions refused as the pool is full
+	discarded       *metrics.Meter // Transactions evicted for new ones as the pool is full
+	reheaps         *metrics.Meter // Priced list rebuilds on base fee changes
+	reserveRejected *metrics.Meter // Transactions refused as their sender is reserved by another subpool
+
+	pendingDiscard   *metrics.Meter // Underpriced replacements of pending transactions
+	pendingReplace   *metrics.Meter // Pending transactions replaced by fee bumped ones
+	pendingRateLimit *metrics.Meter // Pending transactions dropped over the global slot limit
+	pendingEvicted   *metrics.Meter // Pending transactions dropped after the pending lifetime
+	pendingDemoted   *metrics.Meter // Pending transactions moved back to the queue behind a nonce gap
+	queuedDiscard    *metrics.Meter // Underpriced replacements of queued transactions
+	queuedReplace    *metrics.Meter // Queued transactions replaced by fee bumped ones
+	queuedRateLimit  *metrics.Meter // Queued transactions dropped over the queue limits
+	futureLimited    *metrics.Meter // Transactions refused over the nonce-gapped slots of their sender
+	futureDemoted    *metrics.Meter // Batched transactions moved to the queue behind a persisting nonce gap
+
+	deadlineExpired    *metrics.Meter // Transactions dropped past their inclusion deadline
+	dependencyDemoted  *metrics.Meter // Dependents demoted after their dependency was replaced
+	dependencyCascade  *metrics.Meter // Dependents dropped after their dependency was dropped
+	dependencyAnnounce *metrics.Meter // Dependency inclusions announced to peers
+	watchOverflow      *metrics.Meter // Dependencies not watched as the watch limit was reached
+	dependencyCycle    *metrics.Meter // Transactions rejected for circular dependencies
+	bundles            *metrics.Meter // Account abstraction bundles admitted with declared footprints
+	orphanAdded        *metrics.Meter // Transactions held back for unresolved dependencies
+	orphanPromoted     *metrics.Meter // Orphans admitted after their dependencies arrived
+	orphanEvicted      *metrics.Meter // Orphans dropped on overflow, expiry or failed admission
+	quarantined        *metrics.Meter // Batches quarantined after repeated failures
+	panics             *metrics.Meter // Transaction executions recovered from a panic
+	batchAged          *metrics.Meter // Transactions moved to the sequential path after batching too long
+	rebroadcast        *metrics.Meter // Local transactions re-announced to peers
+
+	speculativeGas     *metrics.Meter // Gas spent simulating incoming transactions
+	speculativeSkipped *metrics.Meter // Transactions admitted with unknown footprint
+	declaredFootprint  *metrics.Meter // Transactions admitted with the footprint of their generated access list
+
+	originAccepted [originCount]*metrics.Meter // Admitted transactions per origin
+	originRejected [originCount]*metrics.Meter // Refused transactions per origin
+
+	exec *ExecMetrics // Latencies of the batches executed by the pool
+}
+
+// newPoolMetrics creates, or retrieves if already registered, the metrics of a
+// pool under the given namespace in the given registry. An empty namespace uses
+// the default one and a nil registry the global default registry.
+func newPoolMetrics(namespace string, registry metrics.Registry) *poolMetrics {
+	if namespace == "" {
+		namespace = defaultMetricsNamespace
+	}
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	m := &poolMetrics{
+		pending:        metrics.GetOrRegisterGauge(namespace+"/pending", registry),
+		queued:         metrics.GetOrRegisterGauge(namespace+"/queued", registry),
+		local:          metrics.GetOrRegisterGauge(namespace+"/local", registry),
+		slots:          metrics.GetOrRegisterGauge(namespace+"/slots", registry),
+		batchSize:      metrics.GetOrRegisterGauge(namespace+"/batchsize", registry),
+		batchCount:     metrics.GetOrRegisterGauge(namespace+"/batchcount", registry),
+		parallelizable: metrics.GetOrRegisterGauge(namespace+"/parallelizable", registry),
+		orphans:        metrics.GetOrRegisterGauge(namespace+"/orphans", registry),
+		executed:       metrics.GetOrRegisterMeter(namespace+"/executed", registry),
+
+		addWait:     metrics.GetOrRegisterTimer(namespace+"/timing/add/wait", registry),
+		addHold:     metrics.GetOrRegisterTimer(namespace+"/timing/add/hold", registry),
+		resetTime:   metrics.GetOrRegisterTimer(namespace+"/timing/reset", registry),
+		promoteTime: metrics.GetOrRegisterTimer(namespace+"/timing/promote", registry),
+		prepareTime: metrics.GetOrRegisterTimer(namespace+"/timing/batchprep", registry),
+
+		taggedParallel:   metrics.GetOrRegisterMeter(namespace+"/tag/parallel", registry),
+		taggedSequential: metrics.GetOrRegisterMeter(namespace+"/tag/sequential", registry),
+		untagged:         metrics.GetOrRegisterMeter(namespace+"/tag/none", registry),
+		tagConflicts:     metrics.GetOrRegisterMeter(namespace+"/tag/conflicting", registry),
+		tagUpgraded:      metrics.GetOrRegisterMeter(namespace+"/tag/upgraded", registry),
+		tagLegacy:        metrics.GetOrRegisterMeter(namespace+"/tag/legacy", registry),
+		tagRejected:      metrics.GetOrRegisterMeter(namespace+"/tag/rejected", registry),
+		tagClassified:    metrics.GetOrRegisterMeter(namespace+"/tag/classified", registry),
+		tagOversized:     metrics.GetOrRegisterMeter(namespace+"/tag/oversized", registry),
+
+		known:           metrics.GetOrRegisterMeter(namespace+"/known", registry),
+		underpriced:     metrics.GetOrRegisterMeter(namespace+"/underpriced", registry),
+		nofunds:         metrics.GetOrRegisterMeter(namespace+"/pending/nofunds", registry),
+		evicted:         metrics.GetOrRegisterMeter(namespace+"/queued/eviction", registry),
+		overflowed:      metrics.GetOrRegisterMeter(namespace+"/overflowed", registry),
+		discarded:       metrics.GetOrRegisterMeter(namespace+"/discard", registry),
+		reheaps:         metrics.GetOrRegisterMeter(namespace+"/priced/reheaps", registry),
+		reserveRejected: metrics.GetOrRegisterMeter(namespace+"/reserve/rejected", registry),
+
+		pendingDiscard:   metrics.GetOrRegisterMeter(namespace+"/pending/discard", registry),
+		pendingReplace:   metrics.GetOrRegisterMeter(namespace+"/pending/replace", registry),
+		pendingRateLimit: metrics.GetOrRegisterMeter(namespace+"/pending/ratelimit", registry),
+		pendingEvicted:   metrics.GetOrRegisterMeter(namespace+"/pending/eviction", registry),
+		pendingDemoted:   metrics.GetOrRegisterMeter(namespace+"/pending/demoted", registry),
+		queuedDiscard:    metrics.GetOrRegisterMeter(namespace+"/queued/discard", registry),
+		queuedReplace:    metrics.GetOrRegisterMeter(namespace+"/queued/replace", registry),
+		queuedRateLimit:  metrics.GetOrRegisterMeter(namespace+"/queued/ratelimit", registry),
+		futureLimited:    metrics.GetOrRegisterMeter(namespace+"/future/limited", registry),
+		futureDemoted:    metrics.GetOrRegisterMeter(namespace+"/future/demoted", registry),
+
+		deadlineExpired:    metrics.GetOrRegisterMeter(namespace+"/deadline/expired", registry),
+		dependencyDemoted:  metrics.GetOrRegisterMeter(namespace+"/dependency/demoted", registry),
+		dependencyCascade:  metrics.GetOrRegisterMeter(namespace+"/dependency/cascade", registry),
+		dependencyAnnounce: metrics.GetOrRegisterMeter(namespace+"/dependency/announced", registry),
+		watchOverflow:      metrics.GetOrRegisterMeter(namespace+"/dependency/watchoverflow", registry),
+		dependencyCycle:    metrics.GetOrRegisterMeter(namespace+"/dependency/cycle", registry),
+		bundles:            metrics.GetOrRegisterMeter(namespace+"/aa/bundles", registry),
+		orphanAdded:        metrics.GetOrRegisterMeter(namespace+"/orphan/added", registry),
+		orphanPromoted:     metrics.GetOrRegisterMeter(namespace+"/orphan/promoted", registry),
+		orphanEvicted:      metrics.GetOrRegisterMeter(namespace+"/orphan/evicted", registry),
+		quarantined:        metrics.GetOrRegisterMeter(namespace+"/quarantine", registry),
+		panics:             metrics.GetOrRegisterMeter(namespace+"/execution/panic", registry),
+		batchAged:          metrics.GetOrRegisterMeter(namespace+"/batch/aged", registry),
+		rebroadcast:        metrics.GetOrRegisterMeter(namespace+"/rebroadcast", registry),
+
+		speculativeGas:     metrics.GetOrRegisterMeter(namespace+"/speculative/gas", registry),
+		speculativeSkipped: metrics.GetOrRegisterMeter(namespace+"/speculative/skipped", registry),
+		declaredFootprint:  metrics.GetOrRegisterMeter(namespace+"/speculative/accesslist", registry),
+	}
+	m.exec = NewExecMetrics(namespace, registry)
+	for origin, name := range originNames {
+		m.originAccepted[origin] = metrics.GetOrRegisterMeter(namespace+"/origin/"+name+"/accepted", registry)
+		m.originRejected[origin] = metrics.GetOrRegisterMeter(namespace+"/origin/"+name+"/rejected", registry)
+	}
+	return m
+}
+
+// markOrigin records the outcome of an insertion attempt from the given origin.
+func (m *poolMetrics) markOrigin(origin txOrigin, err error) {
+	if origin < 0 || origin >= originCount {
+		return
+	}
+	if err != nil {
+		m.originRejected[origin].Mark(1)
+	} else {
+		m.originAccepted[origin].Mark(1)
+	}
+}
+
+// markTag accounts an admitted transaction in the tag distribution meters.
+func (m *poolMetrics) markTag(tag string) {
+	switch tag {
+	case ParallelizableTag:
+		m.taggedParallel.Mark(1)
+	case SequentialTag:
+		m.taggedSequential.Mark(1)
+	default:
+		m.untagged.Mark(1)
+	}
+}
+
+// ExecMetrics are the timers and histograms trending the efficiency of parallel
+// batch execution, registered under the exec/ prefix of a pool namespace. The
+// pool and the miner's batch executor of a chain register them in the same
+// namespace, so that they cover the batches executed by either.
+type ExecMetrics struct {
+	batchTime     *metrics.Timer    // Wall time of a batch execution
+	txTime        *metrics.Timer    // Execution time of a single batch member
+	detectionTime *metrics.Timer    // Time spent detecting the conflicts of an incoming transaction
+	conflicts     metrics.Histogram // Conflicts found between the members of a batch
+	reexecutions  metrics.Histogram // Member executions repeated after conflicts, per batch
+}
+
+// NewExecMetrics creates, or retrieves if already registered, the execution
+// metrics of the pool namespace in the given registry. An empty namespace uses
+// the default one and a nil registry the global default registry.
+func NewExecMetrics(namespace string, registry metrics.Registry) *ExecMetrics {
+	if namespace == "" {
+		namespace = defaultMetricsNamespace
+	}
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	namespace += "/exec"
+	return &ExecMetrics{
+		batchTime:     metrics.GetOrRegisterTimer(namespace+"/batch", registry),
+		txTime:        metrics.GetOrRegisterTimer(namespace+"/tx", registry),
+		detectionTime: metrics.GetOrRegisterTimer(namespace+"/conflictdetection", registry),
+		conflicts:     metrics.GetOrRegisterHistogram(namespace+"/conflicts", registry, metrics.NewExpDecaySample(1028, 0.015)),
+		reexecutions:  metrics.GetOrRegisterHistogram(namespace+"/reexecutions", registry, metrics.NewExpDecaySample(1028, 0.015)),
+	}
+}
+
+// UpdateBatch records the wall time of a batch execution, along with the number
+// of conflicts found between its members and the number of member executions
+// repeated because of them.
+func (m *ExecMetrics) UpdateBatch(elapsed time.Duration, conflicts, reexecutions int) {
+	m.batchTime.Update(elapsed)
+	m.conflicts.Update(int64(conflicts))
+	m.reexecutions.Update(int64(reexecutions))
+}
+
+// UpdateTx records the execution time of a single batch member.
+func (m *ExecMetrics) UpdateTx(elapsed time.Duration) {
+	m.txTime.Update(elapsed)
+}
+
+// UpdateConflictDetection records the time spent detecting the conflicts of a
+// transaction with the pooled ones.
+func (m *ExecMetrics) UpdateConflictDetection(elapsed time.Duration) {
+	m.detectionTime.Update(elapsed)
+}
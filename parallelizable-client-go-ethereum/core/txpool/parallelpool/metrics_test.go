@@ -0,0 +1,138 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that per-instance pool metrics are isolated by namespace and registry,
+// and that recreating metrics under an existing namespace does not fail.
+func TestPoolMetricsNamespacing(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	a := newPoolMetrics("chain/a/txpool", registry)
+	b := newPoolMetrics("chain/b/txpool", registry)
+	a.pending.Update(1)
+	b.pending.Update(2)
+
+	if have := a.pending.Snapshot().Value(); have != 1 {
+		t.Errorf("namespace a pending mismatch: have %d, want 1", have)
+	}
+	if have := b.pending.Snapshot().Value(); have != 2 {
+		t.Errorf("namespace b pending mismatch: have %d, want 2", have)
+	}
+	// Recreating under an existing namespace must reuse the registered metrics
+	if again := newPoolMetrics("chain/a/txpool", registry); again.pending != a.pending {
+		t.Errorf("metrics not reused for duplicate namespace")
+	}
+	// Custom registries must not leak into the default one
+	if metrics.DefaultRegistry.Get("chain/a/txpool/pending") != nil {
+		t.Errorf("custom registry metrics registered in default registry")
+	}
+}
+
+// Tests that the execution metrics are registered under the exec prefix of the
+// pool namespace, shared by every user of the namespace.
+func TestExecMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+
+	pool := newPoolMetrics("", registry)
+	if shared := NewExecMetrics("", registry); shared.batchTime != pool.exec.batchTime {
+		t.Errorf("execution metrics not shared within the namespace")
+	}
+	// Samples are only taken with metrics enabled, check the registration
+	for _, name := range []string{"parallel/txpool/exec/batch", "parallel/txpool/exec/tx", "parallel/txpool/exec/conflictdetection"} {
+		if _, ok := registry.Get(name).(*metrics.Timer); !ok {
+			t.Errorf("timer %s not registered", name)
+		}
+	}
+	for _, name := range []string{"parallel/txpool/exec/conflicts", "parallel/txpool/exec/reexecutions"} {
+		if _, ok := registry.Get(name).(metrics.Histogram); !ok {
+			t.Errorf("histogram %s not registered", name)
+		}
+	}
+	if NewExecMetrics("chain/a/txpool", registry).batchTime == pool.exec.batchTime {
+		t.Errorf("execution metrics shared across namespaces")
+	}
+}
+
+// Tests that the timers of the pool's hot paths are registered under the timing
+// prefix of the pool namespace.
+func TestTimingMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	newPoolMetrics("chain/a/txpool", registry)
+
+	for _, name := range []string{"add/wait", "add/hold", "reset", "promote", "batchprep"} {
+		if _, ok := registry.Get("chain/a/txpool/timing/" + name).(*metrics.Timer); !ok {
+			t.Errorf("timer %s not registered", name)
+		}
+	}
+}
+
+// Tests that legacy routing tags are recognized only when followed by a payload.
+func TestLegacyTag(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"", ""},
+		{"PARALLEL", ""},
+		{"PARALLEL\x01", ParallelizableTag},
+		{"SEQUENTIAL", ""},
+		{"SEQUENTIAL\x01", SequentialTag},
+		{"SEQUENTIA\x01", ""},
+		{"\x01PARALLEL", ""},
+	}
+	for i, tt := range tests {
+		if have := legacyTag([]byte(tt.data)); have != tt.want {
+			t.Errorf("test %d: tag mismatch: have %q, want %q", i, have, tt.want)
+		}
+	}
+}
+
+// Tests that transactions are routed by their typed parallel metadata, falling
+// back to legacy calldata tags only if no path was opted for explicitly.
+func TestTxTag(t *testing.T) {
+	newTx := func(typed bool, parallelType uint8, data string) *types.Transaction {
+		if !typed {
+			return types.NewTx(&types.LegacyTx{Data: []byte(data)})
+		}
+		return types.NewTx(&types.ParallelTx{ParallelType: parallelType, Data: []byte(data)})
+	}
+	tests := []struct {
+		tx     *types.Transaction
+		tag    string
+		legacy bool
+	}{
+		{newTx(true, types.ParallelTypeIndependent, ""), ParallelizableTag, false},
+		{newTx(true, types.ParallelTypeDependent, "\x01"), ParallelizableTag, false},
+		{newTx(true, types.ParallelTypeSequential, ""), SequentialTag, false},
+		{newTx(true, types.ParallelTypeSequential, "PARALLEL\x01"), SequentialTag, false},
+		{newTx(true, types.ParallelTypeIndependent, "SEQUENTIAL\x01"), SequentialTag, true},
+		{newTx(false, 0, "PARALLEL\x01"), ParallelizableTag, true},
+		{newTx(false, 0, "\x01"), "", false},
+	}
+	for i, tt := range tests {
+		if tag, legacy := TxTag(tt.tx); tag != tt.tag || legacy != tt.legacy {
+			t.Errorf("test %d: tag mismatch: have %q (legacy %v), want %q (legacy %v)", i, tag, legacy, tt.tag, tt.legacy)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// ModeSubPool runs the pool as a native txpool.SubPool, holding exclusive
+	// reservations on the accounts it pools transactions of.
+	ModeSubPool = "subpool"
+
+	// ModeLegacy runs the pool standalone like the original parallel pool did,
+	// without reserving accounts, so senders may also have transactions pooled
+	// by the other subpools. The transaction pool merges their sets by nonce.
+	ModeLegacy = "legacy"
+)
+
+// ErrUnknownMode is returned if the pool is switched to an unknown mode.
+var ErrUnknownMode = errors.New("unknown parallel txpool mode")
+
+// validMode reports whether the pool can run in the given mode.
+func validMode(mode string) bool {
+	return mode == ModeSubPool || mode == ModeLegacy
+}
+
+// ModeSwitch reports the outcome of switching the pool between modes.
+type ModeSwitch struct {
+	From     string           `json:"from"`
+	To       string           `json:"to"`
+	Accounts int              `json:"accounts"`          // Accounts whose reservations were taken or released
+	Evicted  []common.Address `json:"evicted,omitempty"` // Accounts dropped for being reserved by another subpool
+}
+
+// Mode returns the mode the pool is running in.
+func (p *ParallelPool) Mode() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.mode
+}
+
+// reserving reports whether the pool holds reservations on the accounts it
+// pools transactions of. The caller must hold the pool lock.
+func (p *ParallelPool) reserving() bool {
+	return p.reserve != nil && p.mode == ModeSubPool
+}
+
+// SetMode switches the pool between modes without interrupting it, migrating
+// the pooled accounts: switching to the legacy mode releases their reservations,
+// switching to the subpool mode takes them. Accounts reserved by another subpool
+// in the meantime are evicted, as only one subpool may pool their transactions.
+func (p *ParallelPool) SetMode(mode string) (*ModeSwitch, error) {
+	if !validMode(mode) {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownMode, mode)
+	}
+	p.mu.Lock()
+
+	result := &ModeSwitch{From: p.mode, To: mode}
+	if p.mode == mode {
+		p.mu.Unlock()
+		return result, nil
+	}
+	content := p.content()
+	if p.reserve != nil {
+		for addr, txs := range content {
+			if mode == ModeLegacy {
+				p.reserve(addr, false)
+				result.Accounts++
+				continue
+			}
+			if err := p.reserve(addr, true); err != nil {
+				log.Debug("Evicting parallel pool account reserved elsewhere", "addr", addr, "err", err)
+				for _, tx := range txs {
+					p.postDrop(tx, DropReserved)
+					p.removeTx(tx.Hash(), true, false)
+				}
+				result.Evicted = append(result.Evicted, addr)
+				continue
+			}
+			result.Accounts++
+		}
+	}
+	p.mode = mode
+	p.mu.Unlock()
+
+	if len(result.Evicted) > 0 {
+		p.prepareBatches()
+	}
+	log.Info("Switched parallel transaction pool mode", "from", result.From, "to", result.To, "accounts", result.Accounts, "evicted", len(result.Evicted))
+	return result, nil
+}
@@ -0,0 +1,227 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that switching the pool between modes migrates the reservations of the
+// pooled accounts, evicting those another subpool took in the meantime.
+func TestModeSwitch(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	addrA := crypto.PubkeyToAddress(keyA.PublicKey)
+	addrB := crypto.PubkeyToAddress(keyB.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			addrA: {Balance: big.NewInt(params.Ether)},
+			addrB: {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Track the reservations of all subpools, refusing to hand out one twice
+	reserved := make(map[common.Address]struct{})
+	reserve := func(addr common.Address, exclusive bool) error {
+		if !exclusive {
+			delete(reserved, addr)
+			return nil
+		}
+		if _, ok := reserved[addr]; ok {
+			return txpool.ErrAlreadyReserved
+		}
+		reserved[addr] = struct{}{}
+		return nil
+	}
+	pool := New(Config{}, chain)
+	if err := pool.Init(0, chain.CurrentBlock(), reserve); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer pool.Close()
+
+	if mode := pool.Mode(); mode != ModeSubPool {
+		t.Fatalf("default mode mismatch: have %q, want %q", mode, ModeSubPool)
+	}
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeSequential,
+		})
+	}
+	txA, txB := newTx(keyA), newTx(keyB)
+	if err := pool.addTxs([]*types.Transaction{txA}, false)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if _, ok := reserved[addrA]; !ok {
+		t.Fatalf("pooled account not reserved in subpool mode")
+	}
+	// Switching to the legacy mode releases the reservations, new accounts are
+	// no longer reserved either
+	result, err := pool.SetMode(ModeLegacy)
+	if err != nil {
+		t.Fatalf("failed to switch to legacy mode: %v", err)
+	}
+	if result.Accounts != 1 || len(reserved) != 0 {
+		t.Fatalf("reservations not released: migrated %d, left %d", result.Accounts, len(reserved))
+	}
+	if err := pool.addTxs([]*types.Transaction{txB}, false)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if len(reserved) != 0 {
+		t.Fatalf("account reserved in legacy mode")
+	}
+	// Another subpool takes one of the accounts, switching back evicts it
+	reserved[addrA] = struct{}{}
+
+	if result, err = pool.SetMode(ModeSubPool); err != nil {
+		t.Fatalf("failed to switch to subpool mode: %v", err)
+	}
+	if result.Accounts != 1 || len(result.Evicted) != 1 || result.Evicted[0] != addrA {
+		t.Fatalf("migration mismatch: have %d accounts, evicted %v, want 1, [%x]", result.Accounts, result.Evicted, addrA)
+	}
+	if pool.Has(txA.Hash()) || !pool.Has(txB.Hash()) {
+		t.Fatalf("pool content mismatch: have A %v, B %v, want false, true", pool.Has(txA.Hash()), pool.Has(txB.Hash()))
+	}
+	if _, ok := reserved[addrB]; !ok {
+		t.Fatalf("pooled account not reserved after switching back")
+	}
+	if _, err := pool.SetMode("sharded"); !errors.Is(err, ErrUnknownMode) {
+		t.Fatalf("unknown mode error mismatch: have %v, want %v", err, ErrUnknownMode)
+	}
+}
+
+// Tests that in the legacy mode, where the pool shares accounts with the other
+// subpools, the transactions of an account pooled by several subpools are all
+// retrieved through the transaction pool, merged by nonce.
+func TestModeLegacySharedAccounts(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{addr: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	legacyConfig := legacypool.DefaultConfig
+	legacyConfig.Journal = ""
+
+	parallel := New(Config{}, chain)
+	pool, err := txpool.New(0, chain, []txpool.SubPool{legacypool.New(legacyConfig, chain), parallel})
+	if err != nil {
+		t.Fatalf("failed to create transaction pool: %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := parallel.SetMode(ModeLegacy); err != nil {
+		t.Fatalf("failed to switch to legacy mode: %v", err)
+	}
+
+	var (
+		signer = types.LatestSigner(&config)
+		to     = common.Address{0xaa}
+		price  = big.NewInt(2 * params.InitialBaseFee)
+	)
+	newParallelTx := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    price,
+			GasFeeCap:    price,
+			Gas:          params.TxGas,
+			To:           &to,
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	// The legacy pool holds the first transaction of the account, the parallel
+	// pool, batching regardless of the other subpools, the same nonce and the
+	// next one
+	legacyTx := types.MustSignNewTx(key, signer, &types.LegacyTx{Gas: params.TxGas, GasPrice: price, To: &to})
+	txs := []*types.Transaction{legacyTx, newParallelTx(0), newParallelTx(1)}
+	for i, err := range pool.Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	want := []common.Hash{legacyTx.Hash(), txs[2].Hash()}
+
+	var pending []common.Hash
+	for _, tx := range pool.Pending(txpool.PendingFilter{})[addr] {
+		pending = append(pending, tx.Hash)
+	}
+	if !slices.Equal(pending, want) {
+		t.Errorf("pending mismatch: have %x, want %x", pending, want)
+	}
+	runnable, _ := pool.Content()
+	if have := txHashes(runnable[addr]); !slices.Equal(have, want) {
+		t.Errorf("content mismatch: have %x, want %x", have, want)
+	}
+	runnableFrom, _ := pool.ContentFrom(addr)
+	if have := txHashes(runnableFrom); !slices.Equal(have, want) {
+		t.Errorf("account content mismatch: have %x, want %x", have, want)
+	}
+}
+
+// txHashes returns the hashes of the given transactions.
+func txHashes(txs []*types.Transaction) []common.Hash {
+	hashes := make([]common.Hash, len(txs))
+	for i, tx := range txs {
+		hashes[i] = tx.Hash()
+	}
+	return hashes
+}
@@ -0,0 +1,127 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"cmp"
+	"math/big"
+	"slices"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// maxNonceRepairGaps is the maximum number of missing nonces reported for an
+// account, bounding the response for accounts with huge nonce jumps.
+const maxNonceRepairGaps = 64
+
+// NonceFiller is a template transaction filling a nonce gap: a zero value
+// self-transfer, priced to be accepted and mined ahead of the stuck transactions.
+// It is to be signed and submitted by the account owner.
+type NonceFiller struct {
+	From                 common.Address `json:"from"`
+	To                   common.Address `json:"to"`
+	Nonce                hexutil.Uint64 `json:"nonce"`
+	Gas                  hexutil.Uint64 `json:"gas"`
+	Value                *hexutil.Big   `json:"value"`
+	GasPrice             *hexutil.Big   `json:"gasPrice,omitempty"`             // Before London
+	MaxFeePerGas         *hexutil.Big   `json:"maxFeePerGas,omitempty"`         // After London
+	MaxPriorityFeePerGas *hexutil.Big   `json:"maxPriorityFeePerGas,omitempty"` // After London
+}
+
+// NonceRepair describes the nonce gaps holding back the pooled transactions of
+// an account, along with transactions filling them.
+type NonceRepair struct {
+	Account   common.Address   `json:"account"`
+	Nonce     hexutil.Uint64   `json:"nonce"`     // Next nonce of the account in the chain state
+	Missing   []hexutil.Uint64 `json:"missing"`   // Missing nonces, in ascending order
+	Stuck     int              `json:"stuck"`     // Pooled transactions waiting behind a gap
+	Truncated bool             `json:"truncated"` // Whether more gaps exist than reported
+	Fillers   []*NonceFiller   `json:"fillers"`   // Templates filling the missing nonces
+}
+
+// NonceRepair identifies the missing nonces holding back the pooled transactions
+// of an account and suggests template transactions filling them. The returned
+// repair has no missing nonces if the account is not stuck.
+func (p *ParallelPool) NonceRepair(addr common.Address) *NonceRepair {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	repair := &NonceRepair{
+		Account: addr,
+		Nonce:   hexutil.Uint64(p.currentState.GetNonce(addr)),
+	}
+	// Gather all pooled transactions of the account, whichever path they await
+	// execution on
+	pooled := p.accountTxs(addr)
+	slices.SortFunc(pooled, func(a, b *types.Transaction) int { return cmp.Compare(a.Nonce(), b.Nonce()) })
+
+	// Walk the pooled nonces from the chain state onwards, noting the holes and
+	// pricing the fillers above the stuck transactions
+	var (
+		next = uint64(repair.Nonce)
+		tip  *big.Int
+	)
+	for _, tx := range pooled {
+		if tx.Nonce() < next {
+			continue
+		}
+		for ; next < tx.Nonce(); next++ {
+			if len(repair.Missing) == maxNonceRepairGaps {
+				repair.Truncated = true
+				break
+			}
+			repair.Missing = append(repair.Missing, hexutil.Uint64(next))
+		}
+		if len(repair.Missing) > 0 {
+			repair.Stuck++
+			if tip == nil || tx.GasTipCap().Cmp(tip) > 0 {
+				tip = tx.GasTipCap()
+			}
+		}
+		next = tx.Nonce() + 1
+	}
+	if len(repair.Missing) == 0 {
+		return repair
+	}
+	if limit := new(big.Int).SetUint64(p.config.PriceLimit); tip == nil || tip.Cmp(limit) < 0 {
+		tip = limit
+	}
+	baseFee := core.NewPendingExecAdapter(p.chainconfig, p.chain, p.currentHead).BaseFee()
+	for _, nonce := range repair.Missing {
+		filler := &NonceFiller{
+			From:  addr,
+			To:    addr,
+			Nonce: nonce,
+			Gas:   hexutil.Uint64(params.TxGas),
+			Value: new(hexutil.Big),
+		}
+		if baseFee != nil {
+			// Leave headroom for the basefee to double, as wallets usually do
+			feeCap := new(big.Int).Add(new(big.Int).Mul(baseFee, common.Big2), tip)
+			filler.MaxFeePerGas = (*hexutil.Big)(feeCap)
+			filler.MaxPriorityFeePerGas = (*hexutil.Big)(new(big.Int).Set(tip))
+		} else {
+			filler.GasPrice = (*hexutil.Big)(new(big.Int).Set(tip))
+		}
+		repair.Fillers = append(repair.Fillers, filler)
+	}
+	return repair
+}
@@ -0,0 +1,93 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that nonce gaps are identified across the sequential and parallel paths
+// of an account, and that the fillers are priced above the stuck transactions.
+func TestNonceRepair(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	statedb.SetNonce(addr, 1, tracing.NonceChangeUnspecified)
+
+	baseFee := big.NewInt(params.InitialBaseFee)
+	pool := &ParallelPool{
+		config:            Config{PriceLimit: 1},
+		chainconfig:       params.TestChainConfig,
+		signer:            testSigner,
+		currentHead:       &types.Header{Number: big.NewInt(1), GasLimit: 30_000_000, GasUsed: 15_000_000, BaseFee: baseFee},
+		currentState:      statedb,
+		pendingState:      statedb,
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dirty:             make(map[common.Address]struct{}),
+		beats:             make(map[common.Address]time.Time),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+	}
+	// An account without pooled transactions is not stuck
+	if repair := pool.NonceRepair(addr); len(repair.Missing) != 0 || repair.Stuck != 0 {
+		t.Fatalf("unexpected repair for idle account: %+v", repair)
+	}
+	pool.enqueueSequential(addr, pricedTransaction(3, 5, key))
+	pool.enqueueSequential(addr, pricedTransaction(4, 7, key))
+	pool.parallelizableTxs[addr] = []*types.Transaction{pricedTransaction(6, 2, key)}
+
+	repair := pool.NonceRepair(addr)
+	if repair.Nonce != 1 {
+		t.Errorf("account nonce mismatch: have %d, want 1", repair.Nonce)
+	}
+	want := []hexutil.Uint64{1, 2, 5}
+	if len(repair.Missing) != len(want) {
+		t.Fatalf("missing nonces mismatch: have %v, want %v", repair.Missing, want)
+	}
+	for i := range want {
+		if repair.Missing[i] != want[i] {
+			t.Fatalf("missing nonces mismatch: have %v, want %v", repair.Missing, want)
+		}
+	}
+	if repair.Stuck != 3 || repair.Truncated {
+		t.Errorf("stuck transactions mismatch: have %d (truncated %v), want 3", repair.Stuck, repair.Truncated)
+	}
+	if len(repair.Fillers) != len(want) {
+		t.Fatalf("filler count mismatch: have %d, want %d", len(repair.Fillers), len(want))
+	}
+	for i, filler := range repair.Fillers {
+		if filler.Nonce != want[i] || filler.From != addr || filler.To != addr || uint64(filler.Gas) != params.TxGas {
+			t.Errorf("filler %d mismatch: %+v", i, filler)
+		}
+		if tip := filler.MaxPriorityFeePerGas.ToInt(); tip.Int64() != 7 {
+			t.Errorf("filler %d tip mismatch: have %v, want 7", i, tip)
+		}
+		if filler.MaxFeePerGas.ToInt().Cmp(new(big.Int).Mul(baseFee, common.Big2)) <= 0 {
+			t.Errorf("filler %d fee cap too low: %v", i, filler.MaxFeePerGas)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// nonceRangeDomain separates nonce range signatures from any other message
+	// an account might sign.
+	nonceRangeDomain = "parallel-nonce-range"
+
+	// maxNonceRange is the maximum number of nonces a single reservation may
+	// span.
+	maxNonceRange = 1024
+
+	// maxNonceRangeLifetime is the maximum time a reservation may be held for.
+	maxNonceRangeLifetime = time.Hour
+)
+
+var (
+	// ErrInvalidNonceRangeSignature is returned if a nonce range reservation is
+	// not signed by the account it applies to.
+	ErrInvalidNonceRangeSignature = errors.New("invalid nonce range signature")
+
+	// ErrInvalidNonceRange is returned if a nonce range reservation spans too
+	// many nonces, already expired or expires too far in the future.
+	ErrInvalidNonceRange = errors.New("invalid nonce range")
+
+	// ErrStaleNonceRange is returned if a nonce range reservation does not
+	// expire later than the one already held by the account.
+	ErrStaleNonceRange = errors.New("stale nonce range")
+)
+
+// NonceRange is an account's signed reservation of a range of future nonces.
+// High-throughput senders declare the nonces they are about to use, so that
+// their transactions within the range are admitted even into a full pool, and
+// the account is exempt from the slot limits and lifetimes meanwhile. The
+// reservation lasts until it expires or the chain used up its nonces. A newer
+// reservation, expiring later, supersedes the previous one, and one of zero
+// nonces releases it. Released reservations are kept until they expire, so the
+// ones they superseded can't be replayed.
+type NonceRange struct {
+	Account   common.Address `json:"account"`
+	From      hexutil.Uint64 `json:"from"`
+	Count     hexutil.Uint64 `json:"count"`
+	Expiry    hexutil.Uint64 `json:"expiry"` // Unix time the reservation lapses at
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// SigHash returns the hash the account signs, bound to the given chain.
+func (r *NonceRange) SigHash(chainID *big.Int) common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{nonceRangeDomain, chainID, r.Account, uint64(r.From), uint64(r.Count), uint64(r.Expiry)})
+	return crypto.Keccak256Hash(enc)
+}
+
+// SignNonceRange creates a reservation of count nonces starting at from for the
+// account of the given key, signed for the given chain.
+func SignNonceRange(key *ecdsa.PrivateKey, chainID *big.Int, from, count uint64, expiry time.Time) (*NonceRange, error) {
+	r := &NonceRange{
+		Account: crypto.PubkeyToAddress(key.PublicKey),
+		From:    hexutil.Uint64(from),
+		Count:   hexutil.Uint64(count),
+		Expiry:  hexutil.Uint64(expiry.Unix()),
+	}
+	hash := r.SigHash(chainID)
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+	r.Signature = sig
+	return r, nil
+}
+
+// Verify checks that the reservation was signed by the account it applies to.
+func (r *NonceRange) Verify(chainID *big.Int) error {
+	hash := r.SigHash(chainID)
+	pub, err := crypto.SigToPub(hash[:], r.Signature)
+	if err != nil {
+		return ErrInvalidNonceRangeSignature
+	}
+	if crypto.PubkeyToAddress(*pub) != r.Account {
+		return ErrInvalidNonceRangeSignature
+	}
+	return nil
+}
+
+// contains reports whether the reservation covers a nonce.
+func (r *NonceRange) contains(nonce uint64) bool {
+	return nonce >= uint64(r.From) && nonce-uint64(r.From) < uint64(r.Count)
+}
+
+// expired reports whether the reservation lapsed by the given time.
+func (r *NonceRange) expired(now time.Time) bool {
+	return now.Unix() >= int64(r.Expiry)
+}
+
+// ReserveNonceRange stores a signed nonce range reservation of an account. The
+// range must start at or above the account nonce and span at most 1024 nonces,
+// and the reservation may be held for at most an hour. A reservation of zero
+// nonces releases the one held by the account.
+func (p *ParallelPool) ReserveNonceRange(r *NonceRange) error {
+	if err := r.Verify(p.chainconfig.ChainID); err != nil {
+		return err
+	}
+	now := time.Now()
+	if r.Count > maxNonceRange {
+		return fmt.Errorf("%w: %d nonces, limit %d", ErrInvalidNonceRange, r.Count, maxNonceRange)
+	}
+	if r.expired(now) || time.Unix(int64(r.Expiry), 0).Sub(now) > maxNonceRangeLifetime {
+		return fmt.Errorf("%w: expiry %d outside the next %v", ErrInvalidNonceRange, r.Expiry, maxNonceRangeLifetime)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if old, ok := p.nonceRanges[r.Account]; ok && old.Expiry >= r.Expiry {
+		return ErrStaleNonceRange
+	}
+	if r.Count == 0 {
+		p.nonceRanges[r.Account] = r
+		log.Debug("Released parallel nonce range", "account", r.Account)
+		return nil
+	}
+	if nonce := p.currentState.GetNonce(r.Account); uint64(r.From) < nonce {
+		return fmt.Errorf("%w: range starts at %d, account nonce %d", ErrNonceTooLow, r.From, nonce)
+	}
+	p.nonceRanges[r.Account] = r
+	log.Debug("Reserved parallel nonce range", "account", r.Account, "from", r.From, "count", r.Count, "expiry", r.Expiry)
+	return nil
+}
+
+// NonceRange returns the nonce range reservation held by an account, or nil if
+// it holds none.
+func (p *ParallelPool) NonceRange(addr common.Address) *NonceRange {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if r := p.nonceRanges[addr]; r != nil && r.Count > 0 && !r.expired(time.Now()) {
+		return r
+	}
+	return nil
+}
+
+// inNonceRange reports whether a nonce of an account is covered by a nonce
+// range reservation. The caller must hold the pool lock.
+func (p *ParallelPool) inNonceRange(addr common.Address, nonce uint64) bool {
+	r := p.nonceRanges[addr]
+	return r != nil && !r.expired(time.Now()) && r.contains(nonce)
+}
+
+// exempt reports whether an account is exempt from the slot limits, the
+// lifetimes and eviction: local accounts always are, reserving ones while their
+// reservation lasts. The caller must hold the pool lock.
+func (p *ParallelPool) exempt(addr common.Address) bool {
+	if p.locals.contains(addr) {
+		return true
+	}
+	r := p.nonceRanges[addr]
+	return r != nil && r.Count > 0 && !r.expired(time.Now())
+}
+
+// pruneNonceRanges drops the reservations that expired or whose nonces were all
+// used up by the chain. The caller must hold the pool lock.
+func (p *ParallelPool) pruneNonceRanges() {
+	now := time.Now()
+	for addr, r := range p.nonceRanges {
+		if r.expired(now) || (r.Count > 0 && p.currentState.GetNonce(addr) >= uint64(r.From)+uint64(r.Count)) {
+			delete(p.nonceRanges, addr)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signed nonce range reservations are validated, that transactions
+// within the range are admitted beyond the slot limits and into a full pool,
+// and that reservations are released and pruned.
+func TestNonceRange(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	from := crypto.PubkeyToAddress(keys[0].PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeSequential,
+		})
+	}
+	pool := newTestPool(t, Config{
+		AccountSlots:    2,
+		GlobalSlots:     2,
+		AccountQueue:    2,
+		GlobalQueue:     2,
+		MetricsRegistry: metrics.NewRegistry(),
+	}, chain)
+	defer pool.Close()
+
+	// Malformed reservations are rejected
+	expiry := time.Now().Add(time.Minute)
+	foreign, _ := SignNonceRange(keys[0], big.NewInt(12345), 1, 8, expiry)
+	if err := pool.ReserveNonceRange(foreign); !errors.Is(err, ErrInvalidNonceRangeSignature) {
+		t.Fatalf("foreign chain reservation error mismatch: have %v, want %v", err, ErrInvalidNonceRangeSignature)
+	}
+	wide, _ := SignNonceRange(keys[0], config.ChainID, 1, maxNonceRange+1, expiry)
+	if err := pool.ReserveNonceRange(wide); !errors.Is(err, ErrInvalidNonceRange) {
+		t.Fatalf("oversized reservation error mismatch: have %v, want %v", err, ErrInvalidNonceRange)
+	}
+	lasting, _ := SignNonceRange(keys[0], config.ChainID, 1, 8, time.Now().Add(2*maxNonceRangeLifetime))
+	if err := pool.ReserveNonceRange(lasting); !errors.Is(err, ErrInvalidNonceRange) {
+		t.Fatalf("long-lived reservation error mismatch: have %v, want %v", err, ErrInvalidNonceRange)
+	}
+	lapsed, _ := SignNonceRange(keys[0], config.ChainID, 1, 8, time.Now().Add(-time.Second))
+	if err := pool.ReserveNonceRange(lapsed); !errors.Is(err, ErrInvalidNonceRange) {
+		t.Fatalf("expired reservation error mismatch: have %v, want %v", err, ErrInvalidNonceRange)
+	}
+	// Reserve nonces 1-8, the gapped run must be kept beyond the account and
+	// global queue caps
+	reservation, _ := SignNonceRange(keys[0], config.ChainID, 1, 8, expiry)
+	if err := pool.ReserveNonceRange(reservation); err != nil {
+		t.Fatalf("failed to reserve nonce range: %v", err)
+	}
+	if have := pool.NonceRange(from); have == nil || have.From != 1 || have.Count != 8 {
+		t.Fatalf("nonce range mismatch: have %+v", have)
+	}
+	var queued []*types.Transaction
+	for nonce := uint64(1); nonce <= 5; nonce++ {
+		queued = append(queued, newTx(keys[0], nonce))
+	}
+	for i, err := range pool.Add(queued, false) {
+		if err != nil {
+			t.Fatalf("reserved transaction %d refused: %v", i, err)
+		}
+	}
+	if _, have := pool.Stats(); have != 5 {
+		t.Fatalf("queued count mismatch: have %d, want 5", have)
+	}
+	// The pool is full: other remote transactions are refused, as are those of
+	// the reserving account outside its range, while those within are admitted
+	if err := pool.Add([]*types.Transaction{newTx(keys[1], 0)}, false)[0]; !errors.Is(err, ErrTxPoolOverflow) {
+		t.Errorf("overflow error mismatch: have %v, want %v", err, ErrTxPoolOverflow)
+	}
+	if err := pool.Add([]*types.Transaction{newTx(keys[0], 9)}, false)[0]; !errors.Is(err, ErrTxPoolOverflow) {
+		t.Errorf("out of range overflow error mismatch: have %v, want %v", err, ErrTxPoolOverflow)
+	}
+	if err := pool.Add([]*types.Transaction{newTx(keys[0], 6)}, false)[0]; err != nil {
+		t.Errorf("reserved transaction refused in a full pool: %v", err)
+	}
+	// Reservations must not start below the account nonce
+	pool.mu.Lock()
+	pool.currentState.SetNonce(from, 2, tracing.NonceChangeUnspecified)
+	pool.mu.Unlock()
+
+	low, _ := SignNonceRange(keys[0], config.ChainID, 1, 8, expiry.Add(time.Second))
+	if err := pool.ReserveNonceRange(low); !errors.Is(err, ErrNonceTooLow) {
+		t.Fatalf("low reservation error mismatch: have %v, want %v", err, ErrNonceTooLow)
+	}
+	// Releasing the reservation ends the exemption, and the superseded one must
+	// not be replayable
+	release, _ := SignNonceRange(keys[0], config.ChainID, 0, 0, expiry.Add(time.Second))
+	if err := pool.ReserveNonceRange(release); err != nil {
+		t.Fatalf("failed to release nonce range: %v", err)
+	}
+	if have := pool.NonceRange(from); have != nil {
+		t.Errorf("released nonce range still held: %+v", have)
+	}
+	if err := pool.ReserveNonceRange(reservation); !errors.Is(err, ErrStaleNonceRange) {
+		t.Errorf("replayed reservation error mismatch: have %v, want %v", err, ErrStaleNonceRange)
+	}
+	// Reservations whose nonces were all used up are pruned, releases only once
+	// they expire
+	later, _ := SignNonceRange(keys[0], config.ChainID, 2, 4, expiry.Add(2*time.Second))
+	if err := pool.ReserveNonceRange(later); err != nil {
+		t.Fatalf("failed to reserve nonce range: %v", err)
+	}
+	pool.mu.Lock()
+	pool.currentState.SetNonce(from, 6, tracing.NonceChangeUnspecified)
+	pool.pruneNonceRanges()
+	_, held := pool.nonceRanges[from]
+	pool.mu.Unlock()
+
+	if held {
+		t.Errorf("used up nonce range not pruned")
+	}
+}
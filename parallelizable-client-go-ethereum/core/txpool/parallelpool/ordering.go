@@ -0,0 +1,121 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"sort"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// BatchOrdering is the policy used to order transactions within a batch. The
+// assignment of transactions to batches is always price prioritised; the intra
+// batch policy only decides the order of members of the same batch.
+type BatchOrdering uint8
+
+const (
+	// OrderByPrice orders batch members by descending gas price.
+	OrderByPrice BatchOrdering = iota
+
+	// OrderByArrival orders batch members first-in-first-out by the time they
+	// were admitted by the pool. Arrival times are local, so batches ordered
+	// this way are not reproducible across nodes.
+	OrderByArrival
+
+	// OrderSenderFair interleaves batch members of different senders round
+	// robin, so that no single sender monopolises the head of a batch.
+	OrderSenderFair
+)
+
+// String implements fmt.Stringer.
+func (o BatchOrdering) String() string {
+	switch o {
+	case OrderByPrice:
+		return "price"
+	case OrderByArrival:
+		return "fifo"
+	case OrderSenderFair:
+		return "fair"
+	default:
+		return "unknown"
+	}
+}
+
+// orderTransactions returns the given transactions ordered by the requested
+// policy, looking arrival times up in the given record. Regardless of the policy,
+// transactions of the same sender always keep their nonce order.
+func orderTransactions(signer types.Signer, txs []*types.Transaction, policy BatchOrdering, arrivals *arrivalTimes) []*types.Transaction {
+	// Split the transactions into nonce-sorted per-sender runs
+	var (
+		senders []common.Address
+		runs    = make(map[common.Address][]*types.Transaction)
+	)
+	for _, tx := range txs {
+		from, _ := types.Sender(signer, tx)
+		if _, ok := runs[from]; !ok {
+			senders = append(senders, from)
+		}
+		runs[from] = append(runs[from], tx)
+	}
+	for _, run := range runs {
+		sort.SliceStable(run, func(i, j int) bool { return run[i].Nonce() < run[j].Nonce() })
+	}
+	// Merge the runs, repeatedly picking the next head according to the policy
+	ordered := make([]*types.Transaction, 0, len(txs))
+	for next := 0; len(senders) > 0; {
+		switch policy {
+		case OrderSenderFair:
+			next %= len(senders)
+		default:
+			next = 0
+			for i := 1; i < len(senders); i++ {
+				if headBefore(runs[senders[i]][0], runs[senders[next]][0], policy, arrivals) {
+					next = i
+				}
+			}
+		}
+		from := senders[next]
+		ordered = append(ordered, runs[from][0])
+
+		if runs[from] = runs[from][1:]; len(runs[from]) == 0 {
+			senders = append(senders[:next], senders[next+1:]...)
+		} else if policy == OrderSenderFair {
+			next++
+		}
+	}
+	return ordered
+}
+
+// headBefore reports whether sender head a should be ordered before sender head
+// b under the given (non round-robin) policy. Remaining ties are broken by hash,
+// so that the price ordering is reproducible across nodes.
+func headBefore(a, b *types.Transaction, policy BatchOrdering, arrivals *arrivalTimes) bool {
+	if policy == OrderByArrival {
+		if ta, tb := arrivals.get(a), arrivals.get(b); !ta.Equal(tb) {
+			return ta.Before(tb)
+		}
+		if cmp := a.GasPrice().Cmp(b.GasPrice()); cmp != 0 {
+			return cmp > 0
+		}
+		return a.Hash().Cmp(b.Hash()) < 0
+	}
+	if cmp := a.GasPrice().Cmp(b.GasPrice()); cmp != 0 {
+		return cmp > 0
+	}
+	return a.Hash().Cmp(b.Hash()) < 0
+}
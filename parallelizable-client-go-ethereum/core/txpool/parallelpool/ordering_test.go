@@ -0,0 +1,148 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var testSigner = types.HomesteadSigner{}
+
+// pricedTransaction creates a signed legacy transaction with the given nonce
+// and gas price.
+func pricedTransaction(nonce uint64, gasprice int64, key *ecdsa.PrivateKey) *types.Transaction {
+	tx, _ := types.SignTx(types.NewTransaction(nonce, common.Address{}, big.NewInt(0), 21000, big.NewInt(gasprice), nil), testSigner, key)
+	return tx
+}
+
+// Tests that every ordering policy keeps per-sender nonce order, and that the
+// policies interleave senders as documented.
+func TestOrderTransactions(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+
+	var (
+		a0 = pricedTransaction(0, 1, keyA)
+		a1 = pricedTransaction(1, 100, keyA)
+		a2 = pricedTransaction(2, 100, keyA)
+		b0 = pricedTransaction(0, 50, keyB)
+		b1 = pricedTransaction(1, 50, keyB)
+	)
+	txs := []*types.Transaction{a2, b1, a1, b0, a0}
+
+	tests := []struct {
+		policy BatchOrdering
+		want   []*types.Transaction
+	}{
+		// Price: B's head outbids A's head until A's cheap nonce-0 is the only option
+		{OrderByPrice, []*types.Transaction{b0, b1, a0, a1, a2}},
+		// Fair: senders alternate in first-seen order
+		{OrderSenderFair, []*types.Transaction{a0, b0, a1, b1, a2}},
+	}
+	for _, tt := range tests {
+		have := orderTransactions(testSigner, txs, tt.policy, nil)
+		if len(have) != len(tt.want) {
+			t.Fatalf("%v: length mismatch: have %d, want %d", tt.policy, len(have), len(tt.want))
+		}
+		for i := range have {
+			if have[i] != tt.want[i] {
+				t.Errorf("%v: tx %d mismatch: have nonce %d price %v, want nonce %d price %v", tt.policy, i,
+					have[i].Nonce(), have[i].GasPrice(), tt.want[i].Nonce(), tt.want[i].GasPrice())
+			}
+		}
+	}
+	// Arrival order must still respect nonces regardless of timestamps
+	have := orderTransactions(testSigner, txs, OrderByArrival, nil)
+	seen := make(map[common.Address]uint64)
+	for _, tx := range have {
+		from, _ := types.Sender(testSigner, tx)
+		if next := seen[from]; tx.Nonce() != next {
+			t.Fatalf("fifo: nonce gap for %x: have %d, want %d", from, tx.Nonce(), next)
+		}
+		seen[from]++
+	}
+}
+
+// Tests that batch schedules only depend on the pool content: pools holding the
+// same transactions, received in a different order and at different times,
+// build identical batches with identical identifiers.
+func TestDeterministicBatches(t *testing.T) {
+	newPool := func(txs []*types.Transaction) *ParallelPool {
+		statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+		all := make(map[common.Hash]*types.Transaction)
+		pool := &ParallelPool{
+			signer:            testSigner,
+			all:               all,
+			priced:            newParallelPricedList(all, nil),
+			pending:           make(map[common.Address]*parallelList),
+			queue:             make(map[common.Address]*parallelList),
+			dependencies:      make(map[common.Hash][]common.Hash),
+			dependents:        make(map[common.Hash][]common.Hash),
+			hintIndex:         make(map[DependencyHint][]common.Hash),
+			parallelizableTxs: make(map[common.Address][]*types.Transaction),
+			unknownFootprint:  make(map[common.Hash]struct{}),
+			quarantine:        newQuarantine("", 0),
+			batchSize:         2,
+			pendingState:      statedb,
+			metrics:           newPoolMetrics("", metrics.NewRegistry()),
+		}
+		for _, tx := range txs {
+			from, _ := types.Sender(testSigner, tx)
+			pool.all[tx.Hash()] = tx
+			pool.parallelizableTxs[from] = append(pool.parallelizableTxs[from], tx)
+		}
+		pool.buildBatches()
+		return pool
+	}
+	// Equally priced transactions force the ordering to break ties
+	var txs []*types.Transaction
+	for i := 0; i < 6; i++ {
+		key, _ := crypto.GenerateKey()
+		txs = append(txs, pricedTransaction(0, int64(i/2+1), key))
+	}
+	var received []*types.Transaction
+	for i := len(txs) - 1; i >= 0; i-- {
+		blob, _ := txs[i].MarshalBinary()
+		tx := new(types.Transaction)
+		if err := tx.UnmarshalBinary(blob); err != nil {
+			t.Fatalf("failed to decode tx %d: %v", i, err)
+		}
+		received = append(received, tx)
+	}
+	have, want := newPool(received).batchedTxs, newPool(txs).batchedTxs
+	if len(have) != len(want) {
+		t.Fatalf("batch count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range have {
+		if have[i].BatchID != want[i].BatchID {
+			t.Errorf("batch %d: id mismatch: have %d, want %d", i, have[i].BatchID, want[i].BatchID)
+		}
+		for j := range have[i].Transactions {
+			if have[i].Transactions[j].Hash() != want[i].Transactions[j].Hash() {
+				t.Errorf("batch %d: tx %d mismatch", i, j)
+			}
+		}
+	}
+}
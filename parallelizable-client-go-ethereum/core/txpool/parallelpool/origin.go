@@ -0,0 +1,72 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+// txOrigin identifies the path through which a transaction entered the pool.
+type txOrigin int
+
+const (
+	originLocal   txOrigin = iota // Submitted through the local RPC endpoints
+	originRemote                  // Received from a remote peer
+	originJournal                 // Replayed from the local transaction journal
+
+	originCount // Number of tracked origins, must be last
+)
+
+// originNames maps each origin to the name used in metrics and API responses.
+var originNames = [originCount]string{
+	originLocal:   "local",
+	originRemote:  "remote",
+	originJournal: "journal",
+}
+
+// originFromLocal returns the origin implied by the local flag of the SubPool
+// insertion methods.
+func originFromLocal(local bool) txOrigin {
+	if local {
+		return originLocal
+	}
+	return originRemote
+}
+
+// String implements fmt.Stringer.
+func (o txOrigin) String() string {
+	if o < 0 || o >= originCount {
+		return "unknown"
+	}
+	return originNames[o]
+}
+
+// OriginStats is the ingestion summary for a single transaction origin.
+type OriginStats struct {
+	Accepted int64 `json:"accepted"` // Transactions admitted into the pool
+	Rejected int64 `json:"rejected"` // Transactions refused by the pool
+}
+
+// IngestionStats returns the number of accepted and rejected transactions per
+// origin since the pool was created, or since the first pool sharing its
+// metrics namespace was.
+func (p *ParallelPool) IngestionStats() map[string]OriginStats {
+	stats := make(map[string]OriginStats, originCount)
+	for origin, name := range originNames {
+		stats[name] = OriginStats{
+			Accepted: p.metrics.originAccepted[origin].Snapshot().Count(),
+			Rejected: p.metrics.originRejected[origin].Snapshot().Count(),
+		}
+	}
+	return stats
+}
@@ -0,0 +1,106 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that transactions submitted locally, received from peers and replayed
+// from the journal are accounted to their own origin, accepted or rejected.
+func TestIngestionStats(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{crypto.PubkeyToAddress(key.PublicKey): {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeSequential,
+		})
+	}
+	// Submit every transaction twice, the duplicate being rejected as known
+	check := func(path string, i int, err error) {
+		t.Helper()
+		if i == 0 && err != nil {
+			t.Fatalf("%s submission failed: %v", path, err)
+		}
+		if i == 1 && !errors.Is(err, txpool.ErrAlreadyKnown) {
+			t.Fatalf("%s resubmission error mismatch: have %v, want %v", path, err, txpool.ErrAlreadyKnown)
+		}
+	}
+	local, remote, journaled := newTx(0), newTx(1), newTx(2)
+	for i := 0; i < 2; i++ {
+		check("local", i, pool.AddLocal(local))
+		check("remote", i, pool.Add([]*types.Transaction{remote}, false)[0])
+		check("journal", i, pool.addJournaled([]*types.Transaction{journaled})[0])
+	}
+	// Non-parallel transactions are rejected outright
+	legacy := pricedTransaction(3, 1, key)
+	if err := pool.Add([]*types.Transaction{legacy}, false)[0]; !errors.Is(err, ErrInvalidParallelTx) {
+		t.Fatalf("legacy submission error mismatch: have %v, want %v", err, ErrInvalidParallelTx)
+	}
+	want := map[string]OriginStats{
+		"local":   {Accepted: 1, Rejected: 1},
+		"remote":  {Accepted: 1, Rejected: 2},
+		"journal": {Accepted: 1, Rejected: 1},
+	}
+	stats := pool.IngestionStats()
+	if len(stats) != len(want) {
+		t.Errorf("origin count mismatch: have %d, want %d", len(stats), len(want))
+	}
+	for origin, want := range want {
+		if have := stats[origin]; have != want {
+			t.Errorf("origin %s stats mismatch: have %+v, want %+v", origin, have, want)
+		}
+	}
+}
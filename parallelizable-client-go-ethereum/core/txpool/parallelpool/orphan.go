@@ -0,0 +1,127 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// orphan is a transaction held back until the transactions referenced by its
+// dependency hints arrive in the pool.
+type orphan struct {
+	tx    *types.Transaction
+	local bool
+	added time.Time
+}
+
+// addOrphan holds back a validated transaction whose dependency hints don't
+// resolve yet, as dapps may broadcast dependents slightly before their parents.
+// If the buffer is full, the oldest orphan is dropped to make room. The caller
+// must hold the pool lock.
+func (p *ParallelPool) addOrphan(tx *types.Transaction, local bool) {
+	if uint64(len(p.orphans)) >= p.config.OrphanSlots {
+		var oldest *orphan
+		for _, o := range p.orphans {
+			if oldest == nil || o.added.Before(oldest.added) {
+				oldest = o
+			}
+		}
+		p.dropOrphan(oldest.tx.Hash())
+		p.metrics.orphanEvicted.Mark(1)
+	}
+	p.orphans[tx.Hash()] = &orphan{tx: tx, local: local, added: time.Now()}
+
+	log.Trace("Holding back parallel transaction with unresolved dependencies", "hash", tx.Hash())
+	p.metrics.orphanAdded.Mark(1)
+	p.metrics.orphans.Update(int64(len(p.orphans)))
+}
+
+// dropOrphan forgets an orphan, along with any bundle footprint declared for it.
+func (p *ParallelPool) dropOrphan(hash common.Hash) {
+	delete(p.orphans, hash)
+	delete(p.bundles, hash)
+}
+
+// orphanResolvable reports whether every dependency hint of an orphan matches a
+// pooled transaction. Ambiguous hints are left for admission to reject.
+func (p *ParallelPool) orphanResolvable(tx *types.Transaction) bool {
+	for _, hint := range getParallelTxData(tx).DependencyHints {
+		if len(p.hintIndex[hint]) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// promoteOrphans admits the orphans whose dependencies arrived, repeating until
+// no more can be admitted as admitted orphans may be depended upon themselves.
+// Orphans are admitted in their order of arrival and those failing admission
+// are dropped. The admitted transactions are returned. The caller must hold the
+// pool lock.
+func (p *ParallelPool) promoteOrphans() []*types.Transaction {
+	var promoted []*types.Transaction
+	for {
+		var ready []*orphan
+		for _, o := range p.orphans {
+			if p.orphanResolvable(o.tx) {
+				ready = append(ready, o)
+			}
+		}
+		if len(ready) == 0 {
+			break
+		}
+		slices.SortFunc(ready, func(a, b *orphan) int { return a.added.Compare(b.added) })
+
+		for _, o := range ready {
+			hash := o.tx.Hash()
+			delete(p.orphans, hash)
+
+			if err := p.add(o.tx, o.local); err != nil {
+				log.Debug("Dropped orphaned parallel transaction", "hash", hash, "err", err)
+				delete(p.bundles, hash)
+				p.metrics.orphanEvicted.Mark(1)
+				continue
+			}
+			if o.local {
+				p.trackLocal(o.tx)
+			}
+			promoted = append(promoted, o.tx)
+			p.metrics.orphanPromoted.Mark(1)
+		}
+	}
+	p.metrics.orphans.Update(int64(len(p.orphans)))
+	return promoted
+}
+
+// expireOrphans drops the orphans whose dependencies did not arrive within the
+// orphan lifetime. The caller must hold the pool lock.
+func (p *ParallelPool) expireOrphans() {
+	var expired int64
+	for hash, o := range p.orphans {
+		if time.Since(o.added) > p.config.OrphanLifetime {
+			p.dropOrphan(hash)
+			expired++
+		}
+	}
+	p.metrics.orphanEvicted.Mark(expired)
+	p.metrics.orphans.Update(int64(len(p.orphans)))
+}
@@ -0,0 +1,134 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that transactions whose dependency hints don't resolve yet are held back
+// and admitted once their dependencies arrive, and that the orphan buffer is
+// bounded in size and lifetime.
+func TestOrphanAdmission(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{OrphanSlots: 2, MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, hints ...DependencyHint) *types.Transaction {
+		parallelType := uint8(types.ParallelTypeIndependent)
+		if len(hints) > 0 {
+			parallelType = types.ParallelTypeDependent
+		}
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:         config.ChainID,
+			Nonce:           nonce,
+			GasTipCap:       common.Big1,
+			GasFeeCap:       big.NewInt(2 * params.InitialBaseFee),
+			Gas:             params.TxGas,
+			To:              &common.Address{0xaa},
+			Value:           common.Big0,
+			ParallelType:    parallelType,
+			DependencyHints: hints,
+		})
+	}
+	// A dependent arriving before its parent is held back, not pooled
+	parent := newTx(keys[0], 0)
+	child := newTx(keys[1], 0, ShortHash(parent.Hash()))
+
+	if err := pool.Add([]*types.Transaction{child}, false)[0]; err != nil {
+		t.Fatalf("orphan refused: %v", err)
+	}
+	if pool.Has(child.Hash()) {
+		t.Fatalf("orphan pooled before its dependency")
+	}
+	if err := pool.Add([]*types.Transaction{child}, false)[0]; !errors.Is(err, txpool.ErrAlreadyKnown) {
+		t.Errorf("orphan resubmission error mismatch: have %v, want %v", err, txpool.ErrAlreadyKnown)
+	}
+	// The parent arriving admits the dependent along with it
+	events := make(chan core.NewTxsEvent, 1)
+	sub := pool.SubscribeNewTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	if err := pool.Add([]*types.Transaction{parent}, false)[0]; err != nil {
+		t.Fatalf("parent refused: %v", err)
+	}
+	if !pool.Has(child.Hash()) {
+		t.Fatalf("orphan not admitted after its dependency")
+	}
+	if deps := pool.dependencies[child.Hash()]; !slices.Equal(deps, []common.Hash{parent.Hash()}) {
+		t.Errorf("orphan dependencies mismatch: have %v, want %v", deps, []common.Hash{parent.Hash()})
+	}
+	if ev := <-events; len(ev.Txs) != 2 || ev.Txs[1].Hash() != child.Hash() {
+		t.Errorf("admitted orphan not announced: %d transactions", len(ev.Txs))
+	}
+	if have := pool.metrics.orphanPromoted.Snapshot().Count(); have != 1 {
+		t.Errorf("promoted orphan meter mismatch: have %d, want 1", have)
+	}
+	// Overflowing the buffer drops the oldest orphan, and orphans expire
+	var orphans []*types.Transaction
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		orphans = append(orphans, newTx(keys[2], nonce, DependencyHint{0x99}))
+		pool.Add(orphans[nonce:], false)
+	}
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+
+	if _, ok := pool.orphans[orphans[0].Hash()]; ok || len(pool.orphans) != 2 {
+		t.Errorf("oldest orphan not dropped on overflow: %d held", len(pool.orphans))
+	}
+	pool.orphans[orphans[1].Hash()].added = time.Now().Add(-2 * defaultOrphanLifetime)
+	pool.expireOrphans()
+
+	if _, ok := pool.orphans[orphans[2].Hash()]; !ok || len(pool.orphans) != 1 {
+		t.Errorf("orphan expiry mismatch: %d held", len(pool.orphans))
+	}
+	if have := pool.metrics.orphanEvicted.Snapshot().Count(); have != 2 {
+		t.Errorf("evicted orphan meter mismatch: have %d, want 2", have)
+	}
+}
@@ -17,227 +17,697 @@
 package parallelpool
 
 import (
+	"crypto/ecdsa"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"runtime"
+	"slices"
 	"sort"
 	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/common/mclock"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto/kzg4844"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/triedb"
+	"github.com/holiman/uint256"
 )
 
 const (
 	// ParallelTxType is the transaction type for parallel transactions
-	ParallelTxType = 0x05
+	ParallelTxType = types.ParallelTxType
 
-	// Tag identifiers within transaction data
+	// Routing tags of transactions. Parallel transactions declare them in the
+	// typed ParallelType field, legacy ones as a calldata prefix.
 	ParallelizableTag = "PARALLEL"
 	SequentialTag     = "SEQUENTIAL"
 
+	// legacyTagWarnInterval is the minimum time between two warnings about
+	// transactions still carrying calldata routing tags.
+	legacyTagWarnInterval = time.Minute
+
 	// Configuration constants
-	txPoolGlobalSlots = 4096            // Maximum transaction capacity
-	txMaxSize         = 4 * 1024 * 1024 // Maximum transaction size (4MB)
+	txMaxSize                = 4 * 1024 * 1024 // Maximum transaction size (4MB)
+	defaultMaxParallelTxSize = 128 * 1024      // Default maximum size of a batched transaction (128KB)
+
+	// Default slot limits, matching those of the legacy pool
+	defaultAccountSlots = 16   // Executable transaction slots guaranteed per account
+	defaultGlobalSlots  = 4096 // Maximum executable transaction slots for all accounts
+	defaultAccountQueue = 64   // Maximum non-executable transaction slots per account
+	defaultGlobalQueue  = 1024 // Maximum non-executable transaction slots for all accounts
+	defaultFutureSlots  = 32   // Maximum transaction slots behind a nonce gap per account
 
 	// Batch execution constants
 	DefaultBatchSize = 64  // Default number of transactions in a parallel batch
 	MaxBatchSize     = 256 // Maximum number of transactions in a parallel batch
+	batchGasDivisor  = 4   // Fraction of the block gas limit a batch may use by default
 
 	// Reason for nonce changes
 	txNonceChange = "transaction"
+
+	// Promotion worker constants
+	promoteParallelThreshold = 256 // Queued accounts above which promotion is parallelized
+	promoteMaxWorkers        = 16  // Maximum number of concurrent promotion workers
+
+	// Maintenance constants
+	evictionInterval       = time.Minute    // Time interval to check for evictable transactions
+	defaultLifetime        = 3 * time.Hour  // Default time non-executable transactions are queued
+	defaultPendingLifetime = 12 * time.Hour // Default time executable transactions are pooled
+	defaultBatchAge        = 16             // Default number of blocks a transaction may await batch execution
+	defaultOrphanLifetime  = time.Minute    // Default time transactions with unresolved dependencies are held
+	defaultOrphanSlots     = 256            // Default number of transactions with unresolved dependencies held
+	defaultPriceBump       = 10             // Default price bump percentage to replace a pooled transaction
+	maxReorgDepth          = 64             // Maximum reorg depth transactions are reinjected for
+
+	// defaultGapTimeout is the default time batched transactions may wait behind
+	// a nonce gap before moving to the queue.
+	defaultGapTimeout = 10 * time.Minute
+
+	// chainHeadChanSize is the size of channel listening to ChainHeadEvent.
+	chainHeadChanSize = 10
 )
 
 var (
 	// ErrInvalidParallelTx is returned if the transaction type is not parallelTxType
 	ErrInvalidParallelTx = errors.New("invalid parallel transaction type")
 
+	// ErrParallelTxNotActive is returned if a parallel transaction is submitted
+	// before parallel transactions are activated in the chain configuration.
+	ErrParallelTxNotActive = errors.New("parallel transactions not yet active")
+
 	// ErrParallelTxNonceUsed is returned if a transaction is already in the pool with the same nonce
 	ErrParallelTxNonceUsed = errors.New("parallel transaction nonce already used")
 
 	// ErrIntrinsicGas is returned if the transaction is specified to use less gas
 	// than required to start the invocation.
-	ErrIntrinsicGas = errors.New("intrinsic gas too low")
+	ErrIntrinsicGas = core.ErrIntrinsicGas
 
 	// ErrGasLimit is returned if a transaction's requested gas limit exceeds the
 	// maximum allowance of the current block.
-	ErrGasLimit = errors.New("exceeds block gas limit")
+	ErrGasLimit = txpool.ErrGasLimit
 
 	// ErrNegativeValue is returned if the transaction contains a negative value.
-	ErrNegativeValue = errors.New("negative value")
+	ErrNegativeValue = txpool.ErrNegativeValue
 
 	// ErrOversizedData is returned if the input data of a transaction is greater
 	// than some meaningful limit a user might use. This is not a consensus error
 	// making the transaction invalid, rather a DOS protection.
-	ErrOversizedData = errors.New("oversized data")
+	ErrOversizedData = txpool.ErrOversizedData
 
 	// ErrNonceTooLow is returned if the nonce of a transaction is lower than the
 	// one present in the local chain.
-	ErrNonceTooLow = errors.New("nonce too low")
+	ErrNonceTooLow = core.ErrNonceTooLow
 
 	// ErrInsufficientFunds is returned if the total cost of executing a transaction
 	// is higher than the balance of the user's account.
-	ErrInsufficientFunds = errors.New("insufficient funds for gas * price + value")
+	ErrInsufficientFunds = core.ErrInsufficientFunds
 
 	// ErrTxPoolOverflow is returned if the transaction pool is full and can't accept
 	// another remote transaction.
 	ErrTxPoolOverflow = errors.New("parallel txpool is full")
 
-	// Metrics for the pending pool
-	pendingParallelDiscardMeter   = metrics.NewRegisteredMeter("parallel/txpool/pending/discard", nil)
-	pendingParallelReplaceMeter   = metrics.NewRegisteredMeter("parallel/txpool/pending/replace", nil)
-	pendingParallelRateLimitMeter = metrics.NewRegisteredMeter("parallel/txpool/pending/ratelimit", nil) // Dropped due to rate limiting
-	pendingParallelNofundsMeter   = metrics.NewRegisteredMeter("parallel/txpool/pending/nofunds", nil)   // Dropped due to out-of-funds
-
-	// Metrics for the queued pool
-	queuedParallelDiscardMeter   = metrics.NewRegisteredMeter("parallel/txpool/queued/discard", nil)
-	queuedParallelReplaceMeter   = metrics.NewRegisteredMeter("parallel/txpool/queued/replace", nil)
-	queuedParallelNofundsMeter   = metrics.NewRegisteredMeter("parallel/txpool/queued/nofunds", nil)
-	queuedParallelRateLimitMeter = metrics.NewRegisteredMeter("parallel/txpool/queued/ratelimit", nil) // Dropped due to rate limiting
-
-	// General metrics
-	knownParallelTxMeter       = metrics.NewRegisteredMeter("parallel/txpool/known", nil)
-	validParallelTxMeter       = metrics.NewRegisteredMeter("parallel/txpool/valid", nil)
-	invalidParallelTxMeter     = metrics.NewRegisteredMeter("parallel/txpool/invalid", nil)
-	underpricedParallelTxMeter = metrics.NewRegisteredMeter("parallel/txpool/underpriced", nil)
-	overflowParallelTxMeter    = metrics.NewRegisteredMeter("parallel/txpool/overflow", nil)
-
-	pendingParallelGauge = metrics.NewRegisteredGauge("parallel/txpool/pending", nil)
-	queuedParallelGauge  = metrics.NewRegisteredGauge("parallel/txpool/queued", nil)
-	localParallelGauge   = metrics.NewRegisteredGauge("parallel/txpool/local", nil)
-	slotsParallelGauge   = metrics.NewRegisteredGauge("parallel/txpool/slots", nil)
+	// ErrReadOnly is returned if batch execution is requested from a pool
+	// running in read-only mode.
+	ErrReadOnly = errors.New("parallel txpool is read-only")
 )
 
 // ParallelTxData represents additional data for a parallel transaction.
 type ParallelTxData struct {
 	// Dependencies is a list of transaction hashes that this transaction depends on.
 	Dependencies []common.Hash
+
+	// DependencyHints are compressed dependencies referencing pooled
+	// transactions by hash prefix.
+	DependencyHints []DependencyHint
 }
 
 // BlockChain provides access to necessary blockchain methods.
 type BlockChain interface {
+	core.ChainContext
+
 	CurrentBlock() *types.Header
 	GetBlock(hash common.Hash, number uint64) *types.Block
 	StateAt(root common.Hash) (*state.StateDB, error)
+	TrieDB() *triedb.Database
 	Config() *params.ChainConfig
-}
 
-// PendingFilter represents a set of filtering options that can be passed to TxPool.Pending.
-type PendingFilter struct {
-	// OnlyPlainTxs indicates plain transactions are returned if this is true
-	OnlyPlainTxs bool
-	// MinTip minimum tip in wei
-	MinTip *big.Int
-	// BaseFee is the basefee of the block to be mined next.
-	BaseFee *big.Int
+	// SubscribeChainHeadEvent subscribes to new blocks being added to the chain.
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
 // Config are the configuration parameters of the parallel transaction pool.
 type Config struct {
-	PriceBump uint64 // Price bump percentage to replace an already existing transaction
+	PriceLimit uint64 // Minimum gas price to enforce for acceptance into the pool
+	PriceBump  uint64 // Price bump percentage to replace an already existing transaction (zero = 10)
+
+	BuilderKey *ecdsa.PrivateKey // Optional key used to sign exported batch descriptors
+
+	// MembershipHashing is the hash function of the Merkle roots over batch
+	// members published in batch descriptors and membership proofs.
+	MembershipHashing MembershipHashing
+
+	// SpeculativeGasBudget is the amount of gas per second that may be spent on
+	// simulating incoming transactions for conflict detection. Transactions over
+	// budget are admitted with an unknown footprint. Zero means unlimited.
+	SpeculativeGasBudget uint64
+
+	BatchOrdering BatchOrdering // Ordering policy of transactions within a batch
+
+	// SignatureDB is a 4-byte signature database, a JSON file mapping selectors
+	// to method signatures, naming the methods the calldata classifier has no
+	// rule for. Optional.
+	SignatureDB string
+
+	// MaxParallelism is the number of workers executing transactions for batch
+	// executions, simulations and estimates, bounding their parallelism across
+	// all batches. Zero uses the number of CPUs.
+	MaxParallelism int
+
+	// MaxParallelTxSize is the maximum size of a transaction executed within a
+	// batch. Batch members are held and copied concurrently, so parallelizable
+	// transactions over it are handled sequentially instead. Zero uses 128KB,
+	// the overall transaction size limit of 4MB caps it.
+	MaxParallelTxSize uint64
+
+	// BatchGasLimit caps the total gas of the transactions packed into a batch,
+	// so that batches fit into a block along with others. Zero uses a quarter
+	// of the block gas limit. The cap never exceeds the block gas limit.
+	BatchGasLimit uint64
+
+	// MetricsNamespace prefixes the per-instance metrics of the pool, allowing
+	// several pools to run in one process. Defaults to "parallel/txpool".
+	MetricsNamespace string
+
+	MetricsRegistry metrics.Registry // Registry for per-instance metrics (nil = default)
+
+	// RebroadcastDelay is the number of blocks a local parallel transaction may
+	// remain unincluded before being re-announced to peers, with exponential
+	// backoff afterwards. Zero uses the default of 4 blocks.
+	RebroadcastDelay uint64
+
+	// ShadowState runs batch executions and simulations on a standby state
+	// database with its own trie readers, isolating RPC triggered work from
+	// block processing on the main state database.
+	ShadowState bool
+
+	QuarantineDir      string        // Directory to persist quarantined batches to (optional)
+	QuarantineCooldown time.Duration // Time repeatedly failing batches are kept out of re-batching
+
+	// QuarantinePanics quarantines transactions whose execution panicked right
+	// away rather than after repeated failures, persisting their bytes along with
+	// the panic to QuarantineDir for bug reports.
+	QuarantinePanics bool
+
+	// BatchWAL is the file executed batches are logged to before their members
+	// leave the pool, allowing executed but unmined transactions to be restored
+	// after a crash. Empty disables the log.
+	BatchWAL string
+
+	// Locals are the accounts whose transactions are treated as local whichever
+	// endpoint they arrive through. They are exempt from the gas tip threshold,
+	// from eviction on a full pool and from lifetime expiry.
+	Locals []common.Address
+
+	Journal   string        // Journal of local parallel transactions to survive node restarts
+	Rejournal time.Duration // Time interval to regenerate the local transaction journal
+
+	Lifetime        time.Duration // Maximum amount of time non-executable transactions are queued (zero = 3 hours)
+	PendingLifetime time.Duration // Maximum amount of time executable transactions are pooled (zero = 12 hours)
+
+	// MaxBatchAge is the number of blocks a parallelizable transaction may be
+	// batched without being included before it falls back to the sequential
+	// path, bounding its inclusion latency. Zero uses the default of 16 blocks.
+	MaxBatchAge uint64
+
+	AccountSlots uint64 // Number of executable transaction slots guaranteed per account (zero = 16)
+	GlobalSlots  uint64 // Maximum number of executable transaction slots for all accounts (zero = 4096)
+	AccountQueue uint64 // Maximum number of non-executable transaction slots permitted per account (zero = 64)
+	GlobalQueue  uint64 // Maximum number of non-executable transaction slots for all accounts (zero = 1024)
+
+	// FutureSlots is the maximum number of transactions an account may have
+	// waiting behind a nonce gap, further ones being refused with
+	// ErrFutureTxLimit. Zero uses the default of 32.
+	FutureSlots uint64
+	GapTimeout  time.Duration // Maximum amount of time batched transactions wait behind a nonce gap before moving to the queue (zero = 10 minutes)
+
+	// OrphanSlots is the number of transactions whose dependency hints don't
+	// resolve yet that are held back, awaiting their dependencies, instead of
+	// being rejected. Zero uses the default of 256.
+	OrphanSlots    uint64
+	OrphanLifetime time.Duration // Maximum amount of time orphans are held (zero = 1 minute)
+
+	// EvictionWeights weighs the factors transactions are scored by for eviction
+	// when the pool is full. Zero weights use DefaultEvictionWeights.
+	EvictionWeights EvictionWeights
+
+	// StrictTagsTime is the head timestamp from which transactions routed by a
+	// deprecated calldata tag are rejected rather than honored, retiring the
+	// legacy tagging scheme in favor of the typed ParallelType field. Nil keeps
+	// honoring legacy tags indefinitely.
+	StrictTagsTime *uint64
+
+	// ReadOnly runs the pool on non-mining nodes: transactions are accepted,
+	// validated, batched and relayed as usual, but batches are never executed.
+	ReadOnly bool
+
+	// AdminAPI enables the RPC methods administering the pool content, such as
+	// exporting it to and importing it from files on the node.
+	AdminAPI bool
+
+	// Mode selects whether the pool runs as a native subpool, reserving the
+	// accounts it pools, or standalone like the legacy parallel pool. It can be
+	// switched at runtime with SetMode. Empty uses ModeSubPool.
+	Mode string
+}
+
+// sanitize returns the configuration with the unset lifetime and slot limits
+// replaced by their defaults.
+func (config Config) sanitize() Config {
+	if config.PriceBump == 0 {
+		config.PriceBump = defaultPriceBump
+	}
+	if config.Lifetime <= 0 {
+		config.Lifetime = defaultLifetime
+	}
+	if config.PendingLifetime <= 0 {
+		config.PendingLifetime = defaultPendingLifetime
+	}
+	// Queued transactions may never become executable, they must not outlive
+	// pending ones
+	if config.PendingLifetime < config.Lifetime {
+		log.Warn("Sanitizing invalid parallel pool pending lifetime", "provided", config.PendingLifetime, "updated", config.Lifetime)
+		config.PendingLifetime = config.Lifetime
+	}
+	if config.MaxBatchAge == 0 {
+		config.MaxBatchAge = defaultBatchAge
+	}
+	if config.AccountSlots == 0 {
+		config.AccountSlots = defaultAccountSlots
+	}
+	if config.GlobalSlots == 0 {
+		config.GlobalSlots = defaultGlobalSlots
+	}
+	if config.AccountQueue == 0 {
+		config.AccountQueue = defaultAccountQueue
+	}
+	if config.GlobalQueue == 0 {
+		config.GlobalQueue = defaultGlobalQueue
+	}
+	if config.FutureSlots == 0 {
+		config.FutureSlots = defaultFutureSlots
+	}
+	if config.GapTimeout <= 0 {
+		config.GapTimeout = defaultGapTimeout
+	}
+	if config.OrphanSlots == 0 {
+		config.OrphanSlots = defaultOrphanSlots
+	}
+	if config.OrphanLifetime <= 0 {
+		config.OrphanLifetime = defaultOrphanLifetime
+	}
+	if config.EvictionWeights == (EvictionWeights{}) {
+		config.EvictionWeights = DefaultEvictionWeights
+	}
+	if config.MaxParallelTxSize == 0 {
+		config.MaxParallelTxSize = defaultMaxParallelTxSize
+	}
+	if config.MaxParallelTxSize > txMaxSize {
+		log.Warn("Sanitizing invalid parallel pool transaction size", "provided", config.MaxParallelTxSize, "updated", txMaxSize)
+		config.MaxParallelTxSize = txMaxSize
+	}
+	if config.MaxParallelism <= 0 {
+		config.MaxParallelism = DefaultParallelism()
+	}
+	if config.Mode == "" {
+		config.Mode = ModeSubPool
+	}
+	if !validMode(config.Mode) {
+		log.Warn("Sanitizing invalid parallel pool mode", "provided", config.Mode, "updated", ModeSubPool)
+		config.Mode = ModeSubPool
+	}
+	if config.MaxParallelism > MaxParallelism {
+		config.MaxParallelism = MaxParallelism
+	}
+	return config
 }
 
 // New types to manage tagged transactions
 type TxBatch struct {
 	Transactions []*types.Transaction
-	BatchID      uint64
+	BatchID      uint64 // Derived from the members, see batchID
+	Level        int    // Dependency level, batches only depend on those of lower levels
+	TotalGas     uint64 // Sum of the gas limits of the members
+}
+
+// batchID derives the identifier of a batch from the hashes of its members, so
+// that nodes batching the same pool content agree on it and rebuilds keep the
+// identifiers of unchanged batches. The head is deliberately left out: the
+// members already pin the pool state the batch was built from.
+func batchID(batch TxBatch) uint64 {
+	key := batchKey(batch)
+	return binary.BigEndian.Uint64(key[:8])
 }
 
 // ParallelPool is the struct for the parallel transaction pool.
 type ParallelPool struct {
-	config      *params.ChainConfig
-	chainconfig *params.ChainConfig
-	chain       BlockChain
-	gasPrice    *big.Int
-	txFeed      event.Feed
-	scope       event.SubscriptionScope
-	signer      types.Signer
-	mu          sync.RWMutex
+	config        Config
+	chainconfig   *params.ChainConfig
+	chain         BlockChain
+	gasPrice      *big.Int
+	gasTip        *big.Int               // Minimum tip required for remote transactions
+	reserve       txpool.AddressReserver // Address reserver to ensure exclusivity across subpools
+	mode          string                 // Whether the pool reserves its accounts, see SetMode
+	txFeed        event.Feed             // Newly discovered transactions
+	insertFeed    event.Feed             // Newly discovered and reorg-resurrected transactions
+	batchFeed     event.Feed             // Batch composition changes
+	lifecycleFeed event.Feed             // Batch lifecycle events
+	resetFeed     event.Feed             // Heads the pool was reset to, with its batches rebuilt
+	activityFeed  event.Feed             // Activity of pooled transactions, per account
+	deadlineFeed  event.Feed             // Transactions dropped for missing their deadline
+	replaceFeed   event.Feed             // Pooled transactions replaced by fee bumped ones
+	inclusionFeed event.Feed             // Inclusions of dependencies watched for remote peers
+	scope         event.SubscriptionScope
+	signer        types.Signer
+	mu            sync.RWMutex
 
 	istanbul bool // Fork indicator whether we are in the istanbul stage.
 	eip2718  bool // Fork indicator whether we are using EIP-2718 type transactions.
 	eip1559  bool // Fork indicator whether we are using EIP-1559 type transactions.
 
+	currentHead   *types.Header
 	currentState  *state.StateDB
 	pendingState  *state.StateDB
 	currentMaxGas uint64
+	shadow        state.Database // Standby state database for simulations (optional)
+	workers       *WorkerPool    // Workers executing the members of batches
+	classifier    *Classifier    // Static calldata classifier suggesting untagged parallel types
 
 	locals  *accountSet
-	journal *journal
-
-	pending map[common.Address]*parallelList
-	queue   map[common.Address]*parallelList
-	beats   map[common.Address]time.Time
-	all     map[common.Hash]*types.Transaction
-	priced  *parallelPricedList
-
-	wg sync.WaitGroup
+	journal *journal // Journal of local transactions to back up to disk (optional)
+	warming bool     // Whether batching is deferred until the startup replay completes
+
+	pending  map[common.Address]*parallelList
+	queue    map[common.Address]*parallelList
+	dirty    map[common.Address]struct{} // Queued accounts whose executable frontier may have moved
+	beats    map[common.Address]time.Time
+	gaps     map[common.Address]time.Time // Times batched transactions of accounts were first seen behind a nonce gap
+	all      map[common.Hash]*types.Transaction
+	priced   *parallelPricedList
+	arrivals *arrivalTimes // Times the pooled transactions were admitted at
+
+	dependencies map[common.Hash][]common.Hash          // Resolved dependency lists of pooled transactions
+	dependents   map[common.Hash][]common.Hash          // Pooled transactions depending on each hash, reverse of dependencies
+	preferences  map[common.Address]*ParallelPreference // Signed per-account parallel preferences
+	nonceRanges  map[common.Address]*NonceRange         // Signed per-account nonce range reservations
+	hintIndex    map[DependencyHint][]common.Hash       // Pooled transaction hashes by short-hash prefix
+	signingIndex map[common.Hash]common.Hash            // Pooled transaction hashes by signing hash
+	orphans      map[common.Hash]*orphan                // Transactions awaiting the arrival of their dependencies
+
+	watches map[common.Hash]*dependencyWatch // Dependencies watched for inclusion on behalf of peers
+
+	legacyTagWarned time.Time // Last time deprecated calldata tags were warned about
+
+	rebroadcast   *rebroadcaster     // Tracker of unincluded local transactions
+	rebroadcastCh chan *types.Header // New heads triggering re-announcements
+	chainHeadCh   chan core.ChainHeadEvent
+	chainHeadSub  event.Subscription
+	quit          chan struct{} // Channel terminating the background loops
+	wg            sync.WaitGroup
 
 	// New fields for improved parallelization
-	parallelizableTxs map[common.Address][]*types.Transaction // Txs that can be executed in parallel
-	batchedTxs        []TxBatch                               // Transactions grouped into batches
-	batchSize         int                                     // Current batch size configuration
-	batchMu           sync.RWMutex                            // Mutex for batch operations
-
-	// New metrics
-	batchSizeGauge        *metrics.Gauge // Tracks current batch size
-	batchCountGauge       *metrics.Gauge // Tracks number of batches
-	parallelizableTxGauge *metrics.Gauge // Tracks parallelizable transactions
+	parallelizableTxs map[common.Address][]*types.Transaction     // Txs that can be executed in parallel
+	batchedTxs        []TxBatch                                   // Transactions grouped into batches
+	executedBatches   map[uint64]struct{}                         // Batches executed since the last rebuild
+	batchSize         int                                         // Current batch size configuration
+	batchOrdering     BatchOrdering                               // Intra-batch ordering policy
+	batchMu           sync.RWMutex                                // Mutex for batch operations
+	builderKey        *ecdsa.PrivateKey                           // Key attesting exported batches (optional)
+	speculation       *gasBudget                                  // Gas budget for admission-time simulation
+	unknownFootprint  map[common.Hash]struct{}                    // Parallel txs admitted without simulation
+	footprints        map[common.Hash]*rwSet                      // Read and write sets recorded by simulation
+	deadlines         map[common.Hash]InclusionDeadline           // Soft inclusion deadlines of pooled txs
+	batchedAt         map[common.Hash]uint64                      // Head numbers parallel txs started awaiting batching at
+	bundles           map[common.Hash]*UserOpBundle               // Footprint declarations of account abstraction bundles
+	conflictReports   lru.BasicLRU[common.Hash, []ConflictReport] // Recent conflicts per transaction
+	conflictMu        sync.Mutex                                  // Mutex protecting the conflict reports
+	declared          *footprintCache                             // Footprints recorded by access list generation
+
+	quarantine *quarantine  // Repeatedly failing batches excluded from re-batching
+	wal        *batchWAL    // Log of executed batches awaiting inclusion (optional)
+	metrics    *poolMetrics // Per-instance gauges and meters
+	counters   poolCounters // Lock-free size counters for monitoring
 }
 
-// New creates a new parallel transaction pool instance
+// ParallelPool implements txpool.SubPool, allowing it to be registered beside
+// the legacy and blob pools.
+var _ txpool.SubPool = (*ParallelPool)(nil)
+
+// New creates a new parallel transaction pool to gather, sort and batch
+// parallel transactions. The pool is not operational until Init is called.
 func New(config Config, blockchain *core.BlockChain) *ParallelPool {
-	// Initialize metrics
-	batchSizeGauge := metrics.NewRegisteredGauge("parallel/txpool/batchsize", nil)
-	batchCountGauge := metrics.NewRegisteredGauge("parallel/txpool/batchcount", nil)
-	parallelizableTxGauge := metrics.NewRegisteredGauge("parallel/txpool/parallelizable", nil)
+	config = config.sanitize()
 
 	// Create pool
+	all := make(map[common.Hash]*types.Transaction)
+	arrivals := newArrivalTimes()
 	pool := &ParallelPool{
-		config:                config,
-		chain:                 blockchain,
-		signer:                types.LatestSigner(blockchain.Config()),
-		mu:                    sync.RWMutex{},
-		pending:               make(map[common.Address]*parallelList),
-		queue:                 make(map[common.Address]*parallelList),
-		beats:                 make(map[common.Address]time.Time),
-		all:                   make(map[common.Hash]*types.Transaction),
-		priced:                newPriceHeap(),
-		locals:                newAccountSet(nil),
-		journal:               newJournal(),
-		parallelizableTxs:     make(map[common.Address][]*types.Transaction),
-		batchSize:             DefaultBatchSize,
-		batchSizeGauge:        &batchSizeGauge,
-		batchCountGauge:       &batchCountGauge,
-		parallelizableTxGauge: &parallelizableTxGauge,
-	}
-
-	// Initialize the blockchain state
-	pool.currentState = blockchain.CurrentBlock().StateDB()
-	pool.pendingState = statedb.ManageState(pool.currentState)
-
-	// Track transactions
-	pool.head = blockchain.CurrentBlock().Hash()
-	pool.chainconfig = blockchain.Config()
-
+		config:            config,
+		mode:              config.Mode,
+		chain:             blockchain,
+		gasTip:            new(big.Int).SetUint64(config.PriceLimit),
+		signer:            types.LatestSigner(blockchain.Config()),
+		mu:                sync.RWMutex{},
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dirty:             make(map[common.Address]struct{}),
+		beats:             make(map[common.Address]time.Time),
+		gaps:              make(map[common.Address]time.Time),
+		all:               all,
+		priced:            newParallelPricedList(all, arrivals),
+		arrivals:          arrivals,
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		hintIndex:         make(map[DependencyHint][]common.Hash),
+		signingIndex:      make(map[common.Hash]common.Hash),
+		watches:           make(map[common.Hash]*dependencyWatch),
+		preferences:       make(map[common.Address]*ParallelPreference),
+		nonceRanges:       make(map[common.Address]*NonceRange),
+		locals:            newAccountSet(nil),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		executedBatches:   make(map[uint64]struct{}),
+		batchSize:         DefaultBatchSize,
+		batchOrdering:     config.BatchOrdering,
+		builderKey:        config.BuilderKey,
+		speculation:       newGasBudget(config.SpeculativeGasBudget, mclock.System{}),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		footprints:        make(map[common.Hash]*rwSet),
+		deadlines:         make(map[common.Hash]InclusionDeadline),
+		batchedAt:         make(map[common.Hash]uint64),
+		bundles:           make(map[common.Hash]*UserOpBundle),
+		orphans:           make(map[common.Hash]*orphan),
+		conflictReports:   lru.NewBasicLRU[common.Hash, []ConflictReport](maxConflictReports),
+		declared:          newFootprintCache(maxDeclaredFootprints),
+		metrics:           newPoolMetrics(config.MetricsNamespace, config.MetricsRegistry),
+		rebroadcast:       newRebroadcaster(config.RebroadcastDelay),
+		quarantine:        newQuarantine(config.QuarantineDir, config.QuarantineCooldown),
+		rebroadcastCh:     make(chan *types.Header, 1),
+		chainHeadCh:       make(chan core.ChainHeadEvent, chainHeadChanSize),
+		quit:              make(chan struct{}),
+		chainconfig:       blockchain.Config(),
+		workers:           NewWorkerPool(config.MaxParallelism),
+		classifier:        NewClassifier(),
+	}
+	if config.SignatureDB != "" {
+		if n, err := pool.classifier.LoadSignatures(config.SignatureDB); err != nil {
+			log.Warn("Failed to load parallel classifier signatures", "path", config.SignatureDB, "err", err)
+		} else {
+			log.Info("Loaded parallel classifier signatures", "path", config.SignatureDB, "signatures", n)
+		}
+	}
+	if config.ShadowState {
+		pool.shadow = newShadowDatabase(blockchain)
+	}
+	for _, addr := range config.Locals {
+		log.Info("Setting new local parallel account", "address", addr)
+		pool.locals.add(addr)
+	}
+	pool.workers.SetPanicHandler(pool.executionPanicked)
+	pool.counters.batchSize.Store(DefaultBatchSize)
 	return pool
 }
 
+// Init sets the gas tip needed to keep a remote transaction in the pool and the
+// chain head to allow balance / nonce checks. Transactions executed or journaled
+// by a previous run are restored and the maintenance loops started afterwards,
+// including the one following the chain head, so the pool stays current even
+// if no transaction pool drives its resets.
+func (p *ParallelPool) Init(gasTip uint64, head *types.Header, reserve txpool.AddressReserver) error {
+	// Set the address reserver to request exclusive access to pooled accounts
+	p.reserve = reserve
+
+	// Set the basic pool parameters
+	p.gasTip = new(big.Int).SetUint64(gasTip)
+
+	// Initialize the state with head block, or fallback to empty one in
+	// case the head state is not available (might occur when node is not
+	// fully synced).
+	statedb, err := p.chain.StateAt(head.Root)
+	if err != nil {
+		statedb, err = p.chain.StateAt(types.EmptyRootHash)
+	}
+	if err != nil {
+		return err
+	}
+	p.currentHead = head
+	p.currentState = statedb
+	p.pendingState = statedb.Copy()
+	p.currentMaxGas = head.GasLimit
+	p.priced.Reheap(head.BaseFee)
+
+	if p.config.ReadOnly {
+		log.Info("Parallel transaction pool running read-only, batches will not be executed")
+	}
+	// Replay the transactions of the previous run without batching each one of
+	// them, the batches are rebuilt at once by the warm start
+	p.mu.Lock()
+	p.warming = true
+	p.mu.Unlock()
+
+	// Restore any executed transactions lost by a previous run
+	if p.config.BatchWAL != "" {
+		wal, err := newBatchWAL(p.config.BatchWAL)
+		if err != nil {
+			log.Warn("Failed to open parallel batch log", "path", p.config.BatchWAL, "err", err)
+		} else {
+			p.wal = wal
+
+			p.mu.Lock()
+			p.restoreBatches(math.MaxUint64)
+			p.mu.Unlock()
+		}
+	}
+	// Re-inject the local transactions journaled by a previous run
+	if p.config.Journal != "" {
+		p.journal = newTxJournal(p.config.Journal)
+		if err := p.journal.load(p.addJournaled); err != nil {
+			log.Warn("Failed to load parallel transaction journal", "err", err)
+		}
+		p.mu.Lock()
+		if err := p.journal.rotate(p.local()); err != nil {
+			log.Warn("Failed to rotate parallel transaction journal", "err", err)
+		}
+		p.mu.Unlock()
+
+		p.wg.Add(1)
+		go p.journalLoop()
+	}
+	p.warmStart()
+
+	p.wg.Add(1)
+	go p.rebroadcastLoop()
+
+	p.wg.Add(1)
+	go p.evictionLoop()
+
+	// Subscribe to chain head events only once the loop consuming them runs,
+	// as the chain blocks on delivering them
+	p.chainHeadSub = p.chain.SubscribeChainHeadEvent(p.chainHeadCh)
+	p.wg.Add(1)
+	go p.headLoop()
+
+	return nil
+}
+
+// headLoop resets the pool onto each new chain head. Heads that are no longer
+// canonical by the time they are processed are skipped, a later event carrying
+// the current one.
+func (p *ParallelPool) headLoop() {
+	defer p.wg.Done()
+	defer p.chainHeadSub.Unsubscribe()
+
+	for {
+		select {
+		case ev := <-p.chainHeadCh:
+			if ev.Header.Hash() != p.chain.CurrentBlock().Hash() {
+				continue
+			}
+			p.mu.Lock()
+			p.reset(p.currentHead, ev.Header)
+			p.mu.Unlock()
+
+		case <-p.chainHeadSub.Err():
+			return
+
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// evictionLoop periodically drops the transactions of remote accounts that were
+// not active for longer than the configured lifetimes.
+func (p *ParallelPool) evictionLoop() {
+	defer p.wg.Done()
+
+	evict := time.NewTicker(evictionInterval)
+	defer evict.Stop()
+
+	for {
+		select {
+		case <-evict.C:
+			p.mu.Lock()
+			p.expireQueued()
+			p.expirePending()
+			p.expireOrphans()
+			p.demoteStuck()
+			p.mu.Unlock()
+
+		case <-p.quit:
+			return
+		}
+	}
+}
+
+// Close terminates the background loops of the pool.
+func (p *ParallelPool) Close() error {
+	close(p.quit)
+	p.wg.Wait()
+	p.workers.Close()
+	p.scope.Close()
+
+	if p.journal != nil {
+		if err := p.journal.close(); err != nil {
+			log.Warn("Failed to close parallel transaction journal", "err", err)
+		}
+	}
+	if p.wal != nil {
+		return p.wal.close()
+	}
+	return nil
+}
+
 // Type returns the type ID of the parallel transaction pool
 func (p *ParallelPool) Type() byte {
 	return ParallelTxType
 }
 
-// Filter returns a list of transactions matching the specified filter.
-func (p *ParallelPool) Filter(filter *PendingFilter) map[common.Address][]*types.Transaction {
-	return p.Pending(filter)
+// Filter returns whether the given transaction can be consumed by the parallel
+// pool, specifically, whether it is a typed parallel transaction.
+func (p *ParallelPool) Filter(tx *types.Transaction) bool {
+	return tx.Type() == ParallelTxType
 }
 
 // Has returns an indicator whether the parallel pool has a transaction.
@@ -256,121 +726,247 @@ func (p *ParallelPool) Get(hash common.Hash) *types.Transaction {
 	return p.all[hash]
 }
 
-// Pending returns all pending transactions.
-func (p *ParallelPool) Pending(filter *PendingFilter) map[common.Address][]*types.Transaction {
-	p.mu.RLock()
-	defer p.mu.RUnlock()
-
-	result := make(map[common.Address][]*types.Transaction)
-
-	for addr, list := range p.pending {
-		txs := list.Ready()
-
-		// Apply filter criteria if provided
-		if filter != nil {
-			filteredTxs := make([]*types.Transaction, 0, len(txs))
-			for _, tx := range txs {
-				// Skip non-plain transactions if only plain ones are requested
-				if filter.OnlyPlainTxs && tx.Type() != types.LegacyTxType {
-					continue
-				}
+// GetBlobs is not supported by the parallel transaction pool, it is just here to
+// implement the txpool.SubPool interface.
+func (p *ParallelPool) GetBlobs(vhashes []common.Hash) ([]*kzg4844.Blob, []*kzg4844.Proof) {
+	return nil, nil
+}
 
-				// Skip transactions below minimum tip or with insufficient tip
-				if filter.MinTip != nil && filter.BaseFee != nil {
-					effectiveTip, err := tx.EffectiveGasTip(filter.BaseFee)
-					if err != nil || effectiveTip == nil || effectiveTip.Cmp(filter.MinTip) < 0 {
-						continue
-					}
-				}
+// Pending retrieves all currently processable transactions, grouped by origin
+// account and sorted by nonce. Transactions awaiting batch execution are merged
+// with the sequential ones of the same account.
+//
+// The transactions can also be pre-filtered by the dynamic fee components to
+// reduce allocations and load on downstream subsystems.
+func (p *ParallelPool) Pending(filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
+	// If only blob transactions are requested, this pool is unsuitable as it
+	// contains none, don't even bother.
+	if filter.OnlyBlobTxs {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
 
-				filteredTxs = append(filteredTxs, tx)
-			}
+	// Convert the new uint256.Int types to the old big.Int ones used by the pool
+	var (
+		minTipBig  *big.Int
+		baseFeeBig *big.Int
+	)
+	if filter.MinTip != nil {
+		minTipBig = filter.MinTip.ToBig()
+	}
+	if filter.BaseFee != nil {
+		baseFeeBig = filter.BaseFee.ToBig()
+	}
+	accounts := make(map[common.Address]struct{}, len(p.pending))
+	for addr := range p.pending {
+		accounts[addr] = struct{}{}
+	}
+	p.batchMu.RLock()
+	for addr := range p.parallelizableTxs {
+		accounts[addr] = struct{}{}
+	}
+	p.batchMu.RUnlock()
 
-			result[addr] = filteredTxs
-		} else {
-			// If no filter is provided, include all transactions
-			result[addr] = txs
+	pending := make(map[common.Address][]*txpool.LazyTransaction, len(accounts))
+	for addr := range accounts {
+		if lazies := p.lazies(p.executable(addr), minTipBig, baseFeeBig); len(lazies) > 0 {
+			pending[addr] = lazies
 		}
 	}
-
-	return result
-}
-
-// PendingWithFilter returns pending transactions according to the provided filter.
-func (p *ParallelPool) PendingWithFilter() map[common.Address][]*types.Transaction {
-	// This method now uses Filter(nil) which returns all pending transactions
-	return p.Pending(nil)
+	return pending
 }
 
-// Process validates and adds a transaction to the pool
-func (p *ParallelPool) Process(tx *types.Transaction, local bool) error {
+// PendingFrom retrieves the currently processable transactions of the given
+// accounts, sorted by nonce and filtered like Pending, without gathering those
+// of all accounts. Transactions awaiting batch execution are merged with the
+// sequential ones of the same account.
+func (p *ParallelPool) PendingFrom(addrs []common.Address, filter txpool.PendingFilter) map[common.Address][]*txpool.LazyTransaction {
+	if filter.OnlyBlobTxs {
+		return nil
+	}
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Verify transaction type
-	if tx.Type() != ParallelTxType {
-		return ErrInvalidParallelTx
+	var (
+		minTipBig  *big.Int
+		baseFeeBig *big.Int
+	)
+	if filter.MinTip != nil {
+		minTipBig = filter.MinTip.ToBig()
+	}
+	if filter.BaseFee != nil {
+		baseFeeBig = filter.BaseFee.ToBig()
+	}
+	pending := make(map[common.Address][]*txpool.LazyTransaction, len(addrs))
+	for _, addr := range addrs {
+		if lazies := p.lazies(p.executable(addr), minTipBig, baseFeeBig); len(lazies) > 0 {
+			pending[addr] = lazies
+		}
 	}
+	return pending
+}
 
-	// Try to add the transaction to the pool
-	if err := p.add(tx, local); err != nil {
-		return err
+// lazies wraps the executable transactions of an account into lazy ones,
+// capping the list at the first one paying less than the minimum tip, if
+// requested. The caller must hold the pool lock.
+func (p *ParallelPool) lazies(txs []*types.Transaction, minTipBig, baseFeeBig *big.Int) []*txpool.LazyTransaction {
+	// If the miner requests tip enforcement, cap the lists now
+	if minTipBig != nil {
+		for i, tx := range txs {
+			if tx.EffectiveGasTipIntCmp(minTipBig, baseFeeBig) < 0 {
+				txs = txs[:i]
+				break
+			}
+		}
+	}
+	lazies := make([]*txpool.LazyTransaction, len(txs))
+	for i := 0; i < len(txs); i++ {
+		lazies[i] = &txpool.LazyTransaction{
+			Pool:      p,
+			Hash:      txs[i].Hash(),
+			Tx:        txs[i],
+			Time:      p.arrivals.get(txs[i]),
+			GasFeeCap: uint256.MustFromBig(txs[i].GasFeeCap()),
+			GasTipCap: uint256.MustFromBig(txs[i].GasTipCap()),
+			Gas:       txs[i].Gas(),
+			BlobGas:   txs[i].BlobGas(),
+		}
+	}
+	return lazies
+}
+
+// executable returns the pending transactions of an account, both sequential
+// and awaiting batch execution, that form a gapless nonce run on top of the
+// current state. The caller must hold the pool lock.
+func (p *ParallelPool) executable(addr common.Address) []*types.Transaction {
+	var txs []*types.Transaction
+	if list := p.pending[addr]; list != nil {
+		txs = list.Flatten()
 	}
+	p.batchMu.RLock()
+	txs = append(txs, p.parallelizableTxs[addr]...)
+	p.batchMu.RUnlock()
 
-	// Mark the transaction as local if it's from the local node
-	if local {
-		from, err := types.Sender(p.signer, tx)
-		if err == nil {
-			p.locals.add(from)
+	sort.Sort(types.TxByNonce(txs))
+
+	nonce := p.currentState.GetNonce(addr)
+	for len(txs) > 0 && txs[0].Nonce() < nonce {
+		txs = txs[1:]
+	}
+	for i, tx := range txs {
+		if tx.Nonce() != nonce+uint64(i) {
+			return txs[:i]
 		}
 	}
+	return txs
+}
 
-	// Broadcast the transaction to peers
-	p.txFeed.Send(core.NewTxsEvent{Txs: []*types.Transaction{tx}})
+// Process validates and adds a transaction to the pool
+func (p *ParallelPool) Process(tx *types.Transaction, local bool) error {
+	return p.addTxs([]*types.Transaction{tx}, local)[0]
+}
 
-	return nil
+// Add enqueues a batch of remote transactions into the pool if they are valid.
+// Transactions are integrated synchronously, so the sync flag has no effect.
+func (p *ParallelPool) Add(txs []*types.Transaction, sync bool) []error {
+	return p.addTxs(txs, false)
 }
 
-// Add implements the txpool.SubPool interface
-func (p *ParallelPool) Add(txs []*types.Transaction, local bool) []error {
+// addTxs validates and adds a batch of transactions to the pool, announcing the
+// admitted ones to subscribers.
+func (p *ParallelPool) addTxs(txs []*types.Transaction, local bool) []error {
+	start := time.Now()
 	p.mu.Lock()
+	locked := time.Now()
+	p.metrics.addWait.Update(locked.Sub(start))
+
 	defer p.mu.Unlock()
+	defer p.metrics.addHold.UpdateSince(locked)
+
+	origin := originFromLocal(local)
 
-	errs := make([]error, len(txs))
+	var (
+		errs  = make([]error, len(txs))
+		added = make([]*types.Transaction, 0, len(txs))
+	)
 	for i, tx := range txs {
 		// Skip non-parallel transactions
 		if tx.Type() != ParallelTxType {
 			errs[i] = ErrInvalidParallelTx
+			p.metrics.markOrigin(origin, errs[i])
 			continue
 		}
 
-		// Process each transaction
+		// Process each transaction, holding back those depending on transactions
+		// not yet seen
 		errs[i] = p.add(tx, local)
+		if errors.Is(errs[i], ErrUnresolvedDependencyHint) {
+			p.addOrphan(tx, local)
+			errs[i] = nil
+		}
+		p.metrics.markOrigin(origin, errs[i])
+		if errs[i] != nil || p.orphans[tx.Hash()] != nil {
+			continue
+		}
+		added = append(added, tx)
 
 		// Mark the transaction as local if it's from the local node
-		if local && errs[i] == nil {
-			from, err := types.Sender(p.signer, tx)
-			if err == nil {
-				p.locals.add(from)
-			}
+		if local {
+			p.trackLocal(tx)
 		}
 	}
+	// Admit the orphans whose dependencies arrived with the new transactions
+	if len(added) > 0 && len(p.orphans) > 0 {
+		added = append(added, p.promoteOrphans()...)
+	}
 
+	// Enforce the slot limits, not announcing the transactions dropped by them
+	if len(added) > 0 {
+		p.truncate()
+		added = slices.DeleteFunc(added, func(tx *types.Transaction) bool { return p.all[tx.Hash()] == nil })
+	}
 	// Notify subscribers about added transactions
-	if len(txs) > 0 {
-		p.txFeed.Send(core.NewTxsEvent{Txs: txs})
+	if len(added) > 0 {
+		p.txFeed.Send(core.NewTxsEvent{Txs: added})
+		p.insertFeed.Send(core.NewTxsEvent{Txs: added})
 	}
 
 	return errs
 }
 
+// trackLocal marks the sender of an admitted local transaction as local, tracks
+// the transaction for rebroadcasting and journals it. The caller must hold the
+// pool lock.
+func (p *ParallelPool) trackLocal(tx *types.Transaction) {
+	if from, err := types.Sender(p.signer, tx); err == nil {
+		p.locals.add(from)
+		p.rebroadcast.track(tx, from, p.currentHead.Number.Uint64())
+	}
+	p.journalTx(tx)
+	p.updateLocalGauge()
+}
+
 // add validates a parallel transaction and adds it to the non-executable queue
 func (p *ParallelPool) add(tx *types.Transaction, local bool) error {
 	// Verify transaction type
 	if tx.Type() != ParallelTxType {
 		return ErrInvalidParallelTx
 	}
+	// Reject transactions already pooled or held back, whichever endpoint they
+	// came through
+	if p.all[tx.Hash()] != nil || p.orphans[tx.Hash()] != nil {
+		p.metrics.known.Mark(1)
+		return txpool.ErrAlreadyKnown
+	}
 
+	// Transactions of local accounts are local whichever endpoint they came
+	// through, exempting them from the gas tip threshold and from eviction
+	if !local {
+		if from, err := types.Sender(p.signer, tx); err == nil && p.locals.contains(from) {
+			local = true
+		}
+	}
 	// Validate transaction basic requirements
 	if err := p.validateTx(tx, local); err != nil {
 		return err
@@ -382,190 +978,407 @@ func (p *ParallelPool) add(tx *types.Transaction, local bool) error {
 		return err
 	}
 
-	// Get the tag from transaction data
-	txData := tx.Data()
-	isParallelizable := false
+	// Get the routing tag, still honoring legacy calldata tags for now
+	tag, legacy := TxTag(tx)
+	if legacy {
+		p.metrics.tagLegacy.Mark(1)
+		if time.Since(p.legacyTagWarned) > legacyTagWarnInterval {
+			log.Warn("Parallel transaction routed by deprecated calldata tag, use the ParallelType field", "hash", tx.Hash(), "tag", tag)
+			p.legacyTagWarned = time.Now()
+		}
+	}
+	isParallelizable := tag == ParallelizableTag
+	// Accounts that opted out are always handled sequentially
+	if isParallelizable && p.optedOut(from) {
+		isParallelizable = false
+	}
+	// So are transactions too large to be batched
+	if isParallelizable && p.oversized(tx) {
+		log.Trace("Routing oversized parallel transaction sequentially", "hash", tx.Hash(), "size", tx.Size(), "limit", p.config.MaxParallelTxSize)
+		p.metrics.tagOversized.Mark(1)
+		isParallelizable = false
+	}
 
-	// Simple tag detection in the first bytes of data
-	// In a real implementation, this would use a more robust method
-	if len(txData) > 8 {
-		tag := string(txData[:8])
-		isParallelizable = (tag == ParallelizableTag)
+	// Resolve any compressed dependency hints against the pool contents
+	deps, err := p.resolveDependencies(getParallelTxData(tx))
+	if err != nil {
+		return err
+	}
+	if err := p.checkDependencyCycle(tx.Hash(), deps); err != nil {
+		p.metrics.dependencyCycle.Mark(1)
+		return err
+	}
+	// Replacing a pooled transaction must not leave its dependents dangling
+	if old := p.nonceTx(from, tx.Nonce()); old != nil {
+		if err := p.replace(from, old, tx); err != nil {
+			return err
+		}
+	} else {
+		// Remote accounts may only pool a limited number of transactions behind
+		// a nonce gap
+		if !local && !p.exempt(from) {
+			if err := p.checkFutureLimit(from, tx); err != nil {
+				return err
+			}
+		}
+		// Once every slot is taken, new remote transactions must displace the
+		// pooled transaction scoring highest for eviction
+		var (
+			victim *types.Transaction
+			ok     bool
+		)
+		if !local && !p.inNonceRange(from, tx.Nonce()) && p.full() {
+			lane := laneSequential
+			if isParallelizable {
+				lane = laneParallel
+			}
+			if victim, ok = p.discard(tx, from, lane); !ok {
+				p.metrics.overflowed.Mark(1)
+				return ErrTxPoolOverflow
+			}
+		}
+		// Request exclusive access to accounts entering the pool, so that the
+		// other subpools don't pool conflicting nonces of the sender
+		reserved := false
+		if len(p.accountTxs(from)) == 0 && p.reserving() {
+			if err := p.reserve(from, true); err != nil {
+				p.metrics.reserveRejected.Mark(1)
+				return err
+			}
+			reserved = true
+		}
+		if victim != nil {
+			log.Trace("Evicted parallel pool transaction", "hash", victim.Hash(), "incoming", tx.Hash())
+			p.dropTx(victim.Hash(), DropEvicted)
+			p.metrics.discarded.Mark(1)
+		}
+		// Dropping the dependents of the victim may have emptied the account of
+		// the sender, releasing its reservation
+		if !reserved && len(p.accountTxs(from)) == 0 && p.reserving() {
+			if err := p.reserve(from, true); err != nil {
+				p.metrics.reserveRejected.Mark(1)
+				return err
+			}
+		}
 	}
 
 	// Add the transaction to the pool
 	p.all[tx.Hash()] = tx
+	p.counters.slots.Add(numSlots(tx))
+	p.arrivals.record(tx.Hash(), time.Now())
 	p.priced.Put(tx)
+	p.indexHint(tx.Hash())
+	p.indexSigningHash(tx)
+	if len(deps) > 0 {
+		p.setDependencies(tx.Hash(), deps)
+	}
+
+	// Speculatively execute parallel transactions to detect conflicts, as long
+	// as the simulation budget allows. Over budget, the footprint stays unknown
+	// and the batcher isolates the transaction.
+	footprintKnown := true
+	if isParallelizable {
+		var conflicts []ConflictReport
+		if bundle := p.bundles[tx.Hash()]; bundle != nil {
+			// Account abstraction bundles declare the footprints of their user
+			// operations instead, which simulation would hide behind the
+			// EntryPoint account shared by all bundles
+			conflicts = p.recordFootprint(tx.Hash(), bundle.footprint(from))
+		} else if set := p.declared.take(p.signer.Hash(tx)); set != nil {
+			// Transactions with a generated access list were simulated already
+			conflicts = p.recordFootprint(tx.Hash(), set)
+			p.metrics.declaredFootprint.Mark(1)
+		} else if p.speculation.take(tx.Gas()) {
+			p.metrics.speculativeGas.Mark(int64(tx.Gas()))
+
+			// Conflicting transactions are kept apart by the batcher. If the
+			// simulation fails, the transaction may well be mis-tagged and is
+			// isolated just like an unsimulated one.
+			start := time.Now()
+			conflicts, footprintKnown = p.detectConflicts(tx)
+			p.metrics.exec.UpdateConflictDetection(time.Since(start))
+		} else {
+			p.metrics.speculativeSkipped.Mark(1)
+			footprintKnown = false
+		}
+		if len(conflicts) > 0 {
+			log.Trace("Parallel transaction conflicts with pending", "hash", tx.Hash(), "conflicts", len(conflicts))
+			for _, conflict := range conflicts {
+				p.ReportConflict(tx.Hash(), conflict)
+			}
+		}
+	}
 
 	if isParallelizable {
 		// Add to parallelizable transactions map
 		p.batchMu.Lock()
+		if !footprintKnown {
+			p.unknownFootprint[tx.Hash()] = struct{}{}
+		}
 		if p.parallelizableTxs[from] == nil {
 			p.parallelizableTxs[from] = make([]*types.Transaction, 0)
 		}
 		p.parallelizableTxs[from] = append(p.parallelizableTxs[from], tx)
+		if p.currentHead != nil {
+			p.batchedAt[tx.Hash()] = p.currentHead.Number.Uint64()
+		}
 		p.batchMu.Unlock()
+		p.counters.pending.Add(1)
 
 		// Update parallelizable transactions count
-		p.parallelizableTxGauge.Update(int64(len(p.parallelizableTxs)))
+		p.metrics.parallelizable.Update(int64(len(p.parallelizableTxs)))
 	} else {
-		// Traditional processing for sequential transactions
-		nonce := tx.Nonce()
-		if p.pendingState.GetNonce(from) == nonce {
-			if list := p.pending[from]; list == nil {
-				p.pending[from] = newParallelList()
-			}
-			p.pending[from].Add(tx)
-		} else {
-			if list := p.queue[from]; list == nil {
-				p.queue[from] = newParallelList()
-			}
-			p.queue[from].Add(tx)
-		}
+		p.enqueueSequential(from, tx)
 	}
 
 	// Update metrics
-	pendingParallelGauge.Update(int64(len(p.pending)))
-	queuedParallelGauge.Update(int64(len(p.queue)))
+	p.metrics.markTag(tag)
+	p.metrics.pending.Update(int64(len(p.pending)))
+	p.metrics.queued.Update(int64(len(p.queue)))
+	p.metrics.slots.Update(p.counters.slots.Load())
+	if local {
+		p.updateLocalGauge()
+	}
+	p.postActivity(tx, ActivityAdded)
 
-	// After adding transactions, prepare batches for parallel execution
-	p.prepareBatches()
+	// After adding transactions, prepare batches for parallel execution, unless
+	// the startup replay rebuilds them once it is done
+	if !p.warming {
+		p.prepareBatches()
+	}
+	return nil
+}
+
+// TxTag returns the routing tag of a transaction and whether it was taken from
+// a deprecated calldata prefix. Parallel transactions are routed by their typed
+// ParallelType field, unless they opted for neither path explicitly and carry
+// a legacy tag, which is honored for compatibility. Other transactions are
+// routed by their legacy tag only, or untagged.
+func TxTag(tx *types.Transaction) (string, bool) {
+	typed := tx.Type() == types.ParallelTxType
+	if typed && tx.ParallelType() == types.ParallelTypeSequential {
+		return SequentialTag, false
+	}
+	if tag := legacyTag(tx.Data()); tag != "" {
+		return tag, true
+	}
+	if typed {
+		return ParallelizableTag, false
+	}
+	return "", false
+}
+
+// legacyTag returns the routing tag prefixed to the transaction data, or an
+// empty string if the data is untagged. Tags are only honored if followed by
+// an actual payload.
+func legacyTag(data []byte) string {
+	switch {
+	case len(data) > len(ParallelizableTag) && string(data[:len(ParallelizableTag)]) == ParallelizableTag:
+		return ParallelizableTag
+	case len(data) > len(SequentialTag) && string(data[:len(SequentialTag)]) == SequentialTag:
+		return SequentialTag
+	}
+	return ""
+}
+
+// enqueueSequential adds a transaction to the sequential pending or queued list
+// of its sender, depending on whether it is executable.
+func (p *ParallelPool) enqueueSequential(from common.Address, tx *types.Transaction) {
+	if p.pendingState.GetNonce(from) == tx.Nonce() {
+		if list := p.pending[from]; list == nil {
+			p.pending[from] = newParallelList()
+		}
+		if added, _ := p.pending[from].Add(tx, p.config.PriceBump); added {
+			p.counters.pending.Add(1)
+		}
+	} else {
+		if list := p.queue[from]; list == nil {
+			p.queue[from] = newParallelList()
+		}
+		if added, _ := p.queue[from].Add(tx, p.config.PriceBump); added {
+			p.counters.queued.Add(1)
+		}
+		p.dirty[from] = struct{}{}
+		p.beats[from] = time.Now()
+	}
+}
+
+// nonceTx returns the pooled transaction of a sender with the given nonce, if
+// any, regardless of whether it awaits batching or sequential execution.
+func (p *ParallelPool) nonceTx(from common.Address, nonce uint64) *types.Transaction {
+	if list := p.pending[from]; list != nil {
+		if tx := list.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	if list := p.queue[from]; list != nil {
+		if tx := list.Get(nonce); tx != nil {
+			return tx
+		}
+	}
+	p.batchMu.RLock()
+	defer p.batchMu.RUnlock()
 
+	for _, tx := range p.parallelizableTxs[from] {
+		if tx.Nonce() == nonce {
+			return tx
+		}
+	}
 	return nil
 }
 
+// accountTxs returns all pooled transactions of a sender, whichever path they
+// await execution on.
+func (p *ParallelPool) accountTxs(from common.Address) []*types.Transaction {
+	var txs []*types.Transaction
+	for _, list := range []*parallelList{p.pending[from], p.queue[from]} {
+		if list != nil {
+			txs = append(txs, list.flatten()...)
+		}
+	}
+	p.batchMu.RLock()
+	txs = append(txs, p.parallelizableTxs[from]...)
+	p.batchMu.RUnlock()
+
+	return txs
+}
+
 // getParallelTxData extracts the parallel transaction data from the transaction.
+// Dependencies of typed parallel transactions are part of the signed envelope.
 func getParallelTxData(tx *types.Transaction) *ParallelTxData {
-	// In a real implementation, this would decode the transaction data
-	// For now, we'll return a simple implementation with no dependencies
 	return &ParallelTxData{
-		Dependencies: []common.Hash{},
+		Dependencies:    tx.Dependencies(),
+		DependencyHints: tx.DependencyHints(),
 	}
 }
 
 // promoteExecutables moves transactions from the queue to pending if they are ready to be processed.
+//
+// The per-account work (state nonce lookup and frontier scan) is read-only and
+// is spread across a worker pool for large queues; the resulting structural
+// changes are committed serially afterwards.
 func (p *ParallelPool) promoteExecutables() {
-	// Process each account with queued transactions
-	for addr, list := range p.queue {
-		// Get the current nonce for the account
-		nonce := p.pendingState.GetNonce(addr)
-
-		// Add all transactions that can be processed to pending
-		ready := list.Ready()
-		for _, tx := range ready {
-			if tx.Nonce() == nonce {
-				// Add to pending
-				if p.pending[addr] == nil {
-					p.pending[addr] = newParallelList()
-				}
-				p.pending[addr].Add(tx)
+	defer p.metrics.promoteTime.UpdateSince(time.Now())
+
+	// Only accounts whose queue or nonce changed since the last run can have
+	// newly executable transactions, skip all the others
+	accounts := make([]common.Address, 0, len(p.dirty))
+	for addr := range p.dirty {
+		if p.queue[addr] != nil {
+			accounts = append(accounts, addr)
+		}
+	}
+	clear(p.dirty)
+
+	for _, promo := range p.collectPromotions(accounts) {
+		list := p.queue[promo.addr]
+		for _, tx := range promo.txs {
+			// Add to pending
+			if p.pending[promo.addr] == nil {
+				p.pending[promo.addr] = newParallelList()
+			}
+			p.pending[promo.addr].Add(tx, p.config.PriceBump)
 
-				// Remove from queue
-				list.Remove(tx.Hash())
+			// Remove from queue
+			list.Remove(tx.Hash())
+			p.counters.queued.Add(-1)
+			p.counters.pending.Add(1)
 
-				// Update nonce
-				nonce++
-			}
+			p.postActivity(tx, ActivityPromoted)
 		}
-
 		// Remove empty queues
 		if list.Empty() {
-			delete(p.queue, addr)
+			delete(p.queue, promo.addr)
 		}
 	}
+	// Enforce the slot limits on the new composition of the pool
+	p.truncate()
 
 	// Update metrics
-	pendingParallelGauge.Update(int64(len(p.pending)))
-	queuedParallelGauge.Update(int64(len(p.queue)))
-}
-
-// Add automatic conflict detection for storage slots
-func (p *ParallelPool) detectConflicts(tx *types.Transaction) []common.Hash {
-	var conflicts []common.Hash
-	p.currentState.Prepare(tx.Hash(), common.Hash{}, 0)
-	msg, _ := tx.AsMessage(p.signer, p.currentState.BaseFee())
-
-	// Simulate transaction execution to get accessed addresses/storage slots
-	evm := p.chain.GetEVM(msg, p.currentState, nil)
-	evm.Config.Tracer = accessListTracer // Custom tracer to record storage access
-
-	// Execute call to detect storage accesses
-	_, _, _, err := core.ApplyMessage(evm, msg, new(core.GasPool).AddGas(msg.Gas()))
-	if err == nil {
-		// Compare accessed storage with existing transactions
-		for addr, slots := range evm.StateDB.GetAccessList() {
-			for _, slot := range slots {
-				// Check if any pending tx modifies these slots
-				for _, pendingTx := range p.pending {
-					if pendingTx.To() != nil && *pendingTx.To() == addr {
-						if _, exists := pendingTx.GetStorageAccess()[slot]; exists {
-							conflicts = append(conflicts, pendingTx.Hash())
-						}
-					}
-				}
-			}
-		}
-	}
-	return conflicts
+	p.metrics.pending.Update(int64(len(p.pending)))
+	p.metrics.queued.Update(int64(len(p.queue)))
 }
 
-// Enhanced transaction validation with auto-detected conflicts
-func (p *ParallelPool) validateTx(tx *types.Transaction, local bool) error {
-	// Reject transactions over defined size to prevent DOS attacks
-	if uint64(tx.Size()) > txMaxSize {
-		return ErrOversizedData
-	}
-	// Transactions can't be negative. This may never happen using RLP decoded
-	// transactions but may occur if you create a transaction using the RPC.
-	if tx.Value().Sign() < 0 {
-		return ErrNegativeValue
-	}
-	// Make sure the transaction is signed properly
-	from, err := types.Sender(p.signer, tx)
-	if err != nil {
-		return ErrInvalidSender
-	}
-	// Drop non-local transactions under our own minimal accepted gas price
-	if !local && tx.GasFeeCapIntCmp(p.config.PriceLimit) < 0 {
-		return ErrUnderpriced
+// promotion is the set of queued transactions of an account that became
+// executable, in nonce order.
+type promotion struct {
+	addr common.Address
+	txs  []*types.Transaction
+}
+
+// collectPromotions computes the promotable transactions of the given accounts.
+// Small account sets are scanned inline, larger ones are split across workers,
+// each operating on its own copy of the pending state since StateDB reads are
+// not safe for concurrent use.
+//
+// The caller must hold the pool lock, ensuring the queue is not mutated while
+// the workers are running.
+func (p *ParallelPool) collectPromotions(accounts []common.Address) []promotion {
+	if len(accounts) < promoteParallelThreshold {
+		return p.scanPromotions(accounts, p.pendingState)
 	}
-	// Ensure the transaction adheres to nonce ordering
-	currentState := p.currentState
-	if currentState.GetNonce(from) > tx.Nonce() {
-		return ErrNonceTooLow
+	workers := runtime.NumCPU()
+	if workers > promoteMaxWorkers {
+		workers = promoteMaxWorkers
 	}
-
-	// Check if transaction has a parallel tag
-	txData := tx.Data()
-	isParallelizable := false
-	if len(txData) > 8 {
-		tag := string(txData[:8])
-		isParallelizable = (tag == ParallelizableTag)
+	var (
+		chunk   = (len(accounts) + workers - 1) / workers
+		results = make([][]promotion, workers)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < workers; i++ {
+		from, to := i*chunk, (i+1)*chunk
+		if from >= len(accounts) {
+			break
+		}
+		if to > len(accounts) {
+			to = len(accounts)
+		}
+		wg.Add(1)
+		go func(i int, accounts []common.Address, statedb *state.StateDB) {
+			defer wg.Done()
+			results[i] = p.scanPromotions(accounts, statedb)
+		}(i, accounts[from:to], p.pendingState.Copy())
 	}
+	wg.Wait()
 
-	// Transactor should have enough funds to cover the costs
-	// cost == V + GP * GL
-	if currentState.GetBalance(from).Cmp(tx.Cost()) < 0 {
-		return ErrInsufficientFunds
+	var promotions []promotion
+	for _, res := range results {
+		promotions = append(promotions, res...)
 	}
+	return promotions
+}
 
-	// Skip gas limit check for parallelizable transactions as they'll be executed in batches
-	if !isParallelizable {
-		// Check if gas limit is within acceptable range
-		intrGas, err := core.IntrinsicGas(tx.Data(), tx.AccessList(), tx.To() == nil, p.chainconfig.IsShanghai(p.chain.CurrentBlock().Number()))
-		if err != nil {
-			return err
+// scanPromotions returns, for each of the given accounts, the run of queued
+// transactions that is contiguous with the account's current state nonce.
+func (p *ParallelPool) scanPromotions(accounts []common.Address, statedb *state.StateDB) []promotion {
+	var promotions []promotion
+	for _, addr := range accounts {
+		nonce := statedb.GetNonce(addr)
+
+		var txs []*types.Transaction
+		for _, tx := range p.queue[addr].flatten() {
+			if tx.Nonce() < nonce {
+				continue
+			}
+			if tx.Nonce() != nonce {
+				break
+			}
+			txs = append(txs, tx)
+			nonce++
 		}
-		if tx.Gas() < intrGas {
-			return ErrIntrinsicGas
+		if len(txs) > 0 {
+			promotions = append(promotions, promotion{addr: addr, txs: txs})
 		}
 	}
-
-	return nil
+	return promotions
 }
 
-// removeTx removes a transaction from the pool.
-func (p *ParallelPool) removeTx(hash common.Hash, outofbound bool) {
+// removeTx removes a transaction from the pool. If outofbound is set, the
+// transaction is dropped rather than included, executed or replaced, and the
+// transactions depending on it are dropped in cascade. If unreserve is set and
+// the sender has no transactions left, its address reservation is released.
+func (p *ParallelPool) removeTx(hash common.Hash, outofbound bool, unreserve bool) {
 	tx := p.all[hash]
 	if tx == nil {
 		return
@@ -575,39 +1388,119 @@ func (p *ParallelPool) removeTx(hash common.Hash, outofbound bool) {
 
 	// Remove from main lookup
 	delete(p.all, hash)
+	p.counters.slots.Add(-numSlots(tx))
 
-	// Remove from price lookup
-	p.priced.Remove(tx)
+	// Mark the price point stale, the priced list drops it lazily
+	p.priced.Removed(1)
+	p.arrivals.forget(hash)
+
+	// Remove from dependency lookups
+	p.unsetDependencies(hash)
+	delete(p.bundles, hash)
+	p.unindexHint(hash)
+	p.unindexSigningHash(tx)
+
+	p.batchMu.Lock()
+	delete(p.unknownFootprint, hash)
+	delete(p.footprints, hash)
+	delete(p.deadlines, hash)
+	delete(p.batchedAt, hash)
+	if txs := p.parallelizableTxs[from]; len(txs) > 0 {
+		for i, ptx := range txs {
+			if ptx.Hash() == hash {
+				txs = append(txs[:i:i], txs[i+1:]...)
+				p.counters.pending.Add(-1)
+				break
+			}
+		}
+		if len(txs) == 0 {
+			delete(p.parallelizableTxs, from)
+		} else {
+			p.parallelizableTxs[from] = txs
+		}
+	}
+	p.batchMu.Unlock()
 
 	// Remove from account lookups
-	if pending := p.pending[from]; pending != nil {
+	if pending := p.pending[from]; pending != nil && pending.GetByHash(hash) != nil {
 		pending.Remove(hash)
+		p.counters.pending.Add(-1)
 		if pending.Empty() {
 			delete(p.pending, from)
 		}
 	}
-	if queue := p.queue[from]; queue != nil {
+	if queue := p.queue[from]; queue != nil && queue.GetByHash(hash) != nil {
 		queue.Remove(hash)
+		p.counters.queued.Add(-1)
 		if queue.Empty() {
 			delete(p.queue, from)
+			delete(p.beats, from)
 		}
 	}
+	// Any removal may shift the executable frontier of the remaining queue
+	if p.queue[from] != nil {
+		p.dirty[from] = struct{}{}
+	}
+	// If no more transactions are left, release the account reservation
+	if unreserve && p.reserving() && len(p.accountTxs(from)) == 0 {
+		p.reserve(from, false)
+	}
 
 	// Update metrics
-	pendingParallelGauge.Update(int64(len(p.pending)))
-	queuedParallelGauge.Update(int64(len(p.queue)))
+	p.metrics.pending.Update(int64(len(p.pending)))
+	p.metrics.queued.Update(int64(len(p.queue)))
+	p.metrics.slots.Update(p.counters.slots.Load())
+	if p.locals.contains(from) {
+		p.updateLocalGauge()
+	}
+
+	if outofbound {
+		p.dropDependents(hash)
+	}
 }
 
-// Reset clears the pool content.
+// Reset implements txpool.SubPool, keeping the pool content valid with regard
+// to the new chain head: transactions included or invalidated by the new state
+// are dropped, those of blocks reorged out are reinjected, and sequential ones
+// left behind a nonce gap are moved back to the queue.
 func (p *ParallelPool) Reset(oldHead, newHead *types.Header) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
-	// Clear all maps
-	p.pending = make(map[common.Address]*parallelList)
-	p.queue = make(map[common.Address]*parallelList)
-	p.all = make(map[common.Hash]*types.Transaction)
-	p.priced = newParallelPricedList(p.all)
+	p.reset(oldHead, newHead)
+}
+
+// reset switches the pool over to the new chain head. As both the transaction
+// pool and the head loop of the pool drive resets, resetting onto the head the
+// pool is already at is a noop, and a lagging reset onto a head no higher than
+// the current one is ignored unless the chain actually moved back onto it. The
+// pool diffs against the head its state reflects rather than the caller's old
+// head. The caller must hold the pool lock.
+func (p *ParallelPool) reset(oldHead, newHead *types.Header) {
+	if p.currentHead != nil {
+		if p.currentHead.Hash() == newHead.Hash() {
+			return
+		}
+		if newHead.Number.Cmp(p.currentHead.Number) <= 0 && p.chain.CurrentBlock().Hash() != newHead.Hash() {
+			log.Trace("Ignoring stale parallel pool reset", "number", newHead.Number, "hash", newHead.Hash(), "current", p.currentHead.Number)
+			return
+		}
+		oldHead = p.currentHead
+	}
+	defer p.metrics.resetTime.UpdateSince(time.Now())
+
+	// Gather the parallel transactions of blocks reorged out, before the chain
+	// view is switched over
+	reinject, ok := p.reorged(oldHead, newHead)
+	if !ok {
+		return
+	}
+	// Drop the transactions that missed their inclusion deadline, notifying
+	// the submitters
+	p.expireDeadlines(newHead)
+
+	// Let remote peers know their transactions' dependencies got included
+	p.announceInclusions(oldHead, newHead)
 
 	// Update state and gas limit
 	statedb, err := p.chain.StateAt(newHead.Root)
@@ -615,19 +1508,312 @@ func (p *ParallelPool) Reset(oldHead, newHead *types.Header) {
 		log.Error("Failed to reset parallel transaction pool state", "err", err)
 		return
 	}
+	p.currentHead = newHead
 	p.currentState = statedb
 	p.pendingState = statedb.Copy()
 	p.currentMaxGas = newHead.GasLimit
 
-	log.Info("Parallel transaction pool reset", "old", oldHead.Number, "new", newHead.Number)
+	// Order the transactions by their effective tip in the next block
+	if p.priced.Reheap(newHead.BaseFee) {
+		p.metrics.reheaps.Mark(1)
+	}
+
+	// Drop the transactions invalidated by the new state and promote the ones
+	// it made executable
+	p.demoteUnexecutables()
+	p.pruneNonceRanges()
+
+	// Route the transactions awaiting batching for too long to the sequential
+	// path, which guarantees their ordering
+	p.fallbackAged(newHead)
+
+	for addr := range p.queue {
+		p.dirty[addr] = struct{}{}
+	}
+	p.promoteExecutables()
+
+	// Inject any transactions discarded due to reorgs
+	if len(reinject) > 0 {
+		log.Debug("Reinjecting stale parallel transactions", "count", len(reinject))
+		core.SenderCacher().Recover(p.signer, reinject)
+
+		var resurrected []*types.Transaction
+		for _, tx := range reinject {
+			if err := p.add(tx, false); err != nil {
+				log.Trace("Failed to reinject parallel transaction", "hash", tx.Hash(), "err", err)
+				continue
+			}
+			resurrected = append(resurrected, tx)
+		}
+		if len(resurrected) > 0 {
+			p.insertFeed.Send(core.NewTxsEvent{Txs: resurrected})
+		}
+	}
+	// Move the sequential transactions left behind a nonce gap by the reorg back
+	// to the queue, once the reinjected ones had a chance to fill it
+	p.demoteGapped()
+
+	// Restore executed transactions whose target block passed without them
+	p.restoreBatches(newHead.Number.Uint64())
+
+	// Rebuild the batches around the transactions that left the pool
+	p.prepareBatches()
+
+	// Schedule re-announcement of unincluded local transactions, skipping the
+	// round if the previous one is still in progress
+	select {
+	case p.rebroadcastCh <- newHead:
+	default:
+	}
+	p.resetFeed.Send(core.ChainHeadEvent{Header: newHead})
+
+	log.Debug("Parallel transaction pool reset", "old", oldHead.Number, "new", newHead.Number)
 }
 
-// Stats returns the current pool stats according to its internal state.
+// reorged returns the parallel transactions of the blocks reorged out between
+// the old and the new head that are not included in the new chain. It reports
+// false if the reset should be skipped as the new head vanished meanwhile.
+func (p *ParallelPool) reorged(oldHead, newHead *types.Header) (types.Transactions, bool) {
+	if oldHead == nil || oldHead.Hash() == newHead.ParentHash {
+		return nil, true
+	}
+	// If the reorg is too deep, avoid doing it (will happen during fast sync)
+	oldNum := oldHead.Number.Uint64()
+	newNum := newHead.Number.Uint64()
+
+	if depth := uint64(math.Abs(float64(oldNum) - float64(newNum))); depth > maxReorgDepth {
+		log.Debug("Skipping deep parallel transaction reorg", "depth", depth)
+		return nil, true
+	}
+	var (
+		rem = p.chain.GetBlock(oldHead.Hash(), oldNum)
+		add = p.chain.GetBlock(newHead.Hash(), newNum)
+	)
+	if rem == nil {
+		// The old head was discarded by a setHead, its transactions are lost
+		if newNum >= oldNum {
+			log.Warn("Parallel transaction pool reset with missing old head", "old", oldHead.Hash(), "oldnum", oldNum, "new", newHead.Hash(), "newnum", newNum)
+			return nil, false
+		}
+		return nil, true
+	}
+	if add == nil {
+		log.Warn("Parallel transaction pool reset with missing new head", "number", newHead.Number, "hash", newHead.Hash())
+		return nil, false
+	}
+	var discarded, included types.Transactions
+	for rem.NumberU64() > add.NumberU64() {
+		discarded = append(discarded, rem.Transactions()...)
+		if rem = p.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1); rem == nil {
+			log.Error("Unrooted old chain seen by parallel tx pool", "block", oldHead.Number, "hash", oldHead.Hash())
+			return nil, true
+		}
+	}
+	for add.NumberU64() > rem.NumberU64() {
+		included = append(included, add.Transactions()...)
+		if add = p.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
+			log.Error("Unrooted new chain seen by parallel tx pool", "block", newHead.Number, "hash", newHead.Hash())
+			return nil, true
+		}
+	}
+	for rem.Hash() != add.Hash() {
+		discarded = append(discarded, rem.Transactions()...)
+		if rem = p.chain.GetBlock(rem.ParentHash(), rem.NumberU64()-1); rem == nil {
+			log.Error("Unrooted old chain seen by parallel tx pool", "block", oldHead.Number, "hash", oldHead.Hash())
+			return nil, true
+		}
+		included = append(included, add.Transactions()...)
+		if add = p.chain.GetBlock(add.ParentHash(), add.NumberU64()-1); add == nil {
+			log.Error("Unrooted new chain seen by parallel tx pool", "block", newHead.Number, "hash", newHead.Hash())
+			return nil, true
+		}
+	}
+	var lost types.Transactions
+	for _, tx := range types.TxDifference(discarded, included) {
+		if p.Filter(tx) {
+			lost = append(lost, tx)
+		}
+	}
+	return lost, true
+}
+
+// demoteUnexecutables drops the pooled transactions invalidated by the current
+// state: those whose nonce was used up, and those the sender can no longer pay
+// for. The caller must hold the pool lock.
+func (p *ParallelPool) demoteUnexecutables() {
+	var (
+		stale   []common.Hash
+		nofunds []common.Hash
+	)
+	for addr, txs := range p.content() {
+		var (
+			nonce   = p.currentState.GetNonce(addr)
+			balance = p.currentState.GetBalance(addr).ToBig()
+		)
+		for _, tx := range txs {
+			switch {
+			case tx.Nonce() < nonce:
+				stale = append(stale, tx.Hash())
+			case tx.Cost().Cmp(balance) > 0:
+				nofunds = append(nofunds, tx.Hash())
+			}
+		}
+	}
+	for _, hash := range stale {
+		if tx := p.all[hash]; tx != nil {
+			p.postActivity(tx, ActivityIncluded)
+		}
+		p.removeTx(hash, false, true)
+	}
+	for _, hash := range nofunds {
+		p.dropTx(hash, DropNoFunds)
+	}
+	if len(nofunds) > 0 {
+		p.metrics.nofunds.Mark(int64(len(nofunds)))
+	}
+}
+
+// demoteGapped moves the pending sequential transactions that are no longer
+// contiguous with the account nonce back to the queue. A reorg rolling the nonce
+// back, or a dropped transaction, leaves them behind a gap they cannot be
+// executed across until it is filled again. Parallel transactions fill gaps just
+// as well, so they are kept batched, unless the gap persists, see demoteStuck.
+// The caller must hold the pool lock.
+func (p *ParallelPool) demoteGapped() {
+	var demoted int
+	for addr, list := range p.pending {
+		executable := make(map[common.Hash]struct{})
+		for _, tx := range p.executable(addr) {
+			executable[tx.Hash()] = struct{}{}
+		}
+		for _, tx := range list.Flatten() {
+			if _, ok := executable[tx.Hash()]; ok {
+				continue
+			}
+			list.Remove(tx.Hash())
+			p.counters.pending.Add(-1)
+
+			if p.queue[addr] == nil {
+				p.queue[addr] = newParallelList()
+			}
+			if added, _ := p.queue[addr].Add(tx, p.config.PriceBump); added {
+				p.counters.queued.Add(1)
+			}
+			p.dirty[addr] = struct{}{}
+			p.beats[addr] = time.Now()
+			demoted++
+		}
+		if list.Empty() {
+			delete(p.pending, addr)
+		}
+	}
+	if demoted > 0 {
+		log.Debug("Demoted gapped parallel pool transactions", "count", demoted)
+		p.metrics.pendingDemoted.Mark(int64(demoted))
+	}
+}
+
+// content returns all pooled transactions grouped by sender, whichever path they
+// await execution on. The caller must hold the pool lock.
+func (p *ParallelPool) content() map[common.Address][]*types.Transaction {
+	txs := make(map[common.Address][]*types.Transaction)
+	for _, tx := range p.all {
+		from, _ := types.Sender(p.signer, tx) // already validated
+		txs[from] = append(txs[from], tx)
+	}
+	return txs
+}
+
+// SetGasTip updates the minimum gas tip required by the pool for a new remote
+// transaction, and drops the remote transactions the raise priced out: those
+// whose tip cap is below the new threshold, as they can never pay it, and those
+// whose effective tip at the current base fee met the old threshold but not the
+// new one. Transactions only priced out by the base fee are kept, they become
+// includable again once it drops.
+func (p *ParallelPool) SetGasTip(tip *big.Int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	old := p.gasTip
+	p.gasTip = new(big.Int).Set(tip)
+
+	// If the min miner fee increased, remove transactions below the new threshold
+	if tip.Cmp(old) > 0 {
+		var baseFee *big.Int
+		if p.currentHead != nil {
+			baseFee = p.currentHead.BaseFee
+		}
+		var drop []common.Hash
+		for addr, txs := range p.content() {
+			if p.locals.contains(addr) {
+				continue
+			}
+			for _, tx := range txs {
+				if tx.GasTipCapIntCmp(tip) < 0 || (underpriced(tx, tip, baseFee) && !underpriced(tx, old, baseFee)) {
+					drop = append(drop, tx.Hash())
+				}
+			}
+		}
+		for _, hash := range drop {
+			p.dropTx(hash, DropUnderpriced)
+		}
+		if len(drop) > 0 {
+			p.metrics.underpriced.Mark(int64(len(drop)))
+			p.prepareBatches()
+		}
+	}
+	log.Info("Parallel pool tip threshold updated", "tip", tip)
+}
+
+// underpriced reports whether a transaction pays less than the given tip in a
+// block with the given base fee. Transactions whose fee cap is below the base
+// fee pay a negative tip, so they are underpriced for any tip.
+func underpriced(tx *types.Transaction, tip *big.Int, baseFee *big.Int) bool {
+	return tx.EffectiveGasTipIntCmp(tip, baseFee) < 0
+}
+
+// Nonce returns the next nonce of an account, with all transactions executable
+// by the pool already applied on top.
+func (p *ParallelPool) Nonce(addr common.Address) uint64 {
+	// We need a write lock here, since state.GetNonce might write the cache.
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.currentState.GetNonce(addr) + uint64(len(p.executable(addr)))
+}
+
+// Stats retrieves the current pool stats, namely the number of pending and the
+// number of queued (non-executable) transactions. The stats are read from the
+// size counters, without locking the pool.
 func (p *ParallelPool) Stats() (int, int) {
+	return int(p.counters.pending.Load()), int(p.counters.queued.Load())
+}
+
+// Status returns the known status (unknown/pending/queued) of a transaction
+// identified by its hash. Transactions awaiting batch execution are pending.
+func (p *ParallelPool) Status(hash common.Hash) txpool.TxStatus {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	return len(p.pending), len(p.queue)
+	tx := p.all[hash]
+	if tx == nil {
+		return txpool.TxStatusUnknown
+	}
+	from, _ := types.Sender(p.signer, tx) // already validated
+
+	if list := p.queue[from]; list != nil && list.GetByHash(hash) != nil {
+		return txpool.TxStatusQueued
+	}
+	return txpool.TxStatusPending
+}
+
+// SubscribeTransactions registers a subscription for new transaction events,
+// supporting feeding only newly seen or also resurrected transactions.
+func (p *ParallelPool) SubscribeTransactions(ch chan<- core.NewTxsEvent, reorgs bool) event.Subscription {
+	if reorgs {
+		return p.scope.Track(p.insertFeed.Subscribe(ch))
+	}
+	return p.scope.Track(p.txFeed.Subscribe(ch))
 }
 
 // SubscribeNewTxsEvent registers a subscription for new transaction events.
@@ -635,15 +1821,54 @@ func (p *ParallelPool) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Su
 	return p.scope.Track(p.txFeed.Subscribe(ch))
 }
 
-// Content returns the content of the parallel transaction pool.
+// SubscribeBatchChangeEvent registers a subscription for batch composition
+// changes, allowing external schedulers to mirror the batches of the pool.
+func (p *ParallelPool) SubscribeBatchChangeEvent(ch chan<- BatchChangeEvent) event.Subscription {
+	return p.scope.Track(p.batchFeed.Subscribe(ch))
+}
+
+// SubscribeBatchEvents registers a subscription for batch lifecycle events,
+// allowing block builders and dashboards to follow batch scheduling without
+// polling GetBatches.
+func (p *ParallelPool) SubscribeBatchEvents(ch chan<- BatchEvent) event.Subscription {
+	return p.scope.Track(p.lifecycleFeed.Subscribe(ch))
+}
+
+// SubscribeResets registers a subscription for the heads the pool is reset to.
+// The batches are rebuilt for the new head by the time the event is sent.
+func (p *ParallelPool) SubscribeResets(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return p.scope.Track(p.resetFeed.Subscribe(ch))
+}
+
+// Content returns the content of the parallel transaction pool. Transactions
+// awaiting batch execution are executable and thus reported as pending.
 func (p *ParallelPool) Content() (map[common.Address][]*types.Transaction, map[common.Address][]*types.Transaction) {
 	p.mu.RLock()
 	defer p.mu.RUnlock()
 
-	pending := make(map[common.Address][]*types.Transaction)
+	pending := make(map[common.Address][]*types.Transaction, len(p.pending))
+	p.batchMu.RLock()
 	for addr, list := range p.pending {
+		if txs := p.parallelizableTxs[addr]; len(txs) > 0 {
+			continue // Merged below, avoid copying twice
+		}
 		pending[addr] = list.Flatten()
 	}
+	for addr, txs := range p.parallelizableTxs {
+		if len(txs) == 0 {
+			continue
+		}
+		var flat []*types.Transaction
+		if list := p.pending[addr]; list != nil {
+			flat = list.flatten()
+		}
+		merged := make([]*types.Transaction, 0, len(flat)+len(txs))
+		merged = append(append(merged, flat...), txs...)
+		sort.Sort(types.TxByNonce(merged))
+		pending[addr] = merged
+	}
+	p.batchMu.RUnlock()
+
 	queued := make(map[common.Address][]*types.Transaction)
 	for addr, list := range p.queue {
 		queued[addr] = list.Flatten()
@@ -651,6 +1876,28 @@ func (p *ParallelPool) Content() (map[common.Address][]*types.Transaction, map[c
 	return pending, queued
 }
 
+// ContentFrom retrieves the data content of the transaction pool, returning the
+// pending as well as queued transactions of this address, grouped by nonce.
+func (p *ParallelPool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var pending []*types.Transaction
+	if list := p.pending[addr]; list != nil {
+		pending = list.Flatten()
+	}
+	p.batchMu.RLock()
+	pending = append(pending, p.parallelizableTxs[addr]...)
+	p.batchMu.RUnlock()
+	sort.Sort(types.TxByNonce(pending))
+
+	var queued []*types.Transaction
+	if list := p.queue[addr]; list != nil {
+		queued = list.Flatten()
+	}
+	return pending, queued
+}
+
 // Locals returns the addresses of accounts considered local.
 func (p *ParallelPool) Locals() []common.Address {
 	p.mu.RLock()
@@ -659,9 +1906,20 @@ func (p *ParallelPool) Locals() []common.Address {
 	return p.locals.addresses()
 }
 
-// AddLocal adds a local transaction to the pool.
+// updateLocalGauge updates the gauge counting the pooled transactions of local
+// accounts. The caller must hold the pool lock.
+func (p *ParallelPool) updateLocalGauge() {
+	var count int
+	for _, addr := range p.locals.addresses() {
+		count += len(p.accountTxs(addr))
+	}
+	p.metrics.local.Update(int64(count))
+}
+
+// AddLocal adds a local transaction to the pool. Local transactions are exempt
+// from the gas tip threshold, journaled and re-announced until included.
 func (p *ParallelPool) AddLocal(tx *types.Transaction) error {
-	return p.Process(tx, true)
+	return p.addTxs([]*types.Transaction{tx}, true)[0]
 }
 
 // Clear removes all transactions from the pool.
@@ -669,29 +1927,46 @@ func (p *ParallelPool) Clear() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	// Release the reservations of all pooled accounts
+	if p.reserving() {
+		for addr := range p.content() {
+			p.reserve(addr, false)
+		}
+	}
 	p.pending = make(map[common.Address]*parallelList)
 	p.queue = make(map[common.Address]*parallelList)
+	p.dirty = make(map[common.Address]struct{})
+	p.beats = make(map[common.Address]time.Time)
 	p.all = make(map[common.Hash]*types.Transaction)
-	p.priced = newParallelPricedList(p.all)
+	p.arrivals.reset()
+	p.priced = newParallelPricedList(p.all, p.arrivals)
+	if p.currentHead != nil {
+		p.priced.Reheap(p.currentHead.BaseFee)
+	}
+	p.dependencies = make(map[common.Hash][]common.Hash)
+	p.dependents = make(map[common.Hash][]common.Hash)
+	p.bundles = make(map[common.Hash]*UserOpBundle)
+	p.hintIndex = make(map[DependencyHint][]common.Hash)
+	p.signingIndex = make(map[common.Hash]common.Hash)
+	p.orphans = make(map[common.Hash]*orphan)
+	p.watches = make(map[common.Hash]*dependencyWatch)
 
-	log.Info("Parallel transaction pool cleared")
-}
+	p.batchMu.Lock()
+	p.parallelizableTxs = make(map[common.Address][]*types.Transaction)
+	p.unknownFootprint = make(map[common.Hash]struct{})
+	p.footprints = make(map[common.Hash]*rwSet)
+	p.deadlines = make(map[common.Hash]InclusionDeadline)
+	p.batchedAt = make(map[common.Hash]uint64)
+	p.batchMu.Unlock()
 
-// journal maintains a list of recent transactions for replay.
-type journal struct {
-	transactions []*types.Transaction
-}
+	p.counters.pending.Store(0)
+	p.counters.queued.Store(0)
+	p.counters.slots.Store(0)
+	p.metrics.local.Update(0)
 
-// newJournal creates a new journal.
-func newJournal() *journal {
-	return &journal{
-		transactions: make([]*types.Transaction, 0),
-	}
-}
+	p.prepareBatches()
 
-// Add adds a transaction to the journal.
-func (j *journal) Add(tx *types.Transaction) {
-	j.transactions = append(j.transactions, tx)
+	log.Info("Parallel transaction pool cleared")
 }
 
 // accountSet represents a set of addresses with specified transaction types.
@@ -719,6 +1994,9 @@ func (as *accountSet) add(addr common.Address) {
 
 // addresses returns all addresses in the set.
 func (as *accountSet) addresses() []common.Address {
+	if as == nil {
+		return nil
+	}
 	addrs := make([]common.Address, 0, len(as.accounts))
 	for addr := range as.accounts {
 		addrs = append(addrs, addr)
@@ -728,123 +2006,147 @@ func (as *accountSet) addresses() []common.Address {
 
 // contains checks if an address is in the set.
 func (as *accountSet) contains(addr common.Address) bool {
+	if as == nil {
+		return false
+	}
 	_, ok := as.accounts[addr]
 	return ok
 }
 
-// parallelPricedList represents a price-sorted list of transactions.
-type parallelPricedList struct {
-	all    map[common.Hash]*types.Transaction
-	items  []*types.Transaction
-	stales int
-	mu     sync.RWMutex
-}
+// prepareBatches organizes parallelizable transactions into execution batches
+func (p *ParallelPool) prepareBatches() {
+	p.batchMu.Lock()
+	start := time.Now()
+	old := p.batchedTxs
+	p.buildBatches()
+	changes := p.batchChanges(old, p.batchedTxs)
+	events := p.batchLifecycle(old, p.batchedTxs)
+	activities := p.batchedActivity(changes, p.batchedTxs)
+	p.metrics.prepareTime.UpdateSince(start)
+	p.batchMu.Unlock()
 
-// newParallelPricedList creates a new price-sorted transaction list.
-func newParallelPricedList(all map[common.Hash]*types.Transaction) *parallelPricedList {
-	return &parallelPricedList{
-		all:   all,
-		items: make([]*types.Transaction, 0),
+	if len(changes) > 0 {
+		p.batchFeed.Send(BatchChangeEvent{Changes: changes})
 	}
-}
-
-// Put adds a transaction to the list.
-func (l *parallelPricedList) Put(tx *types.Transaction) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	l.items = append(l.items, tx)
-	sort.Slice(l.items, func(i, j int) bool {
-		return l.items[i].GasPrice().Cmp(l.items[j].GasPrice()) > 0
-	})
-}
-
-// Remove removes a transaction from the list.
-func (l *parallelPricedList) Remove(tx *types.Transaction) {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	for i, item := range l.items {
-		if item.Hash() == tx.Hash() {
-			l.items = append(l.items[:i], l.items[i+1:]...)
-			return
-		}
+	for _, event := range events {
+		p.lifecycleFeed.Send(event)
 	}
-}
-
-// Underpriced checks whether a transaction is cheaper than the cheapest transaction.
-func (l *parallelPricedList) Underpriced(tx *types.Transaction) bool {
-	l.mu.RLock()
-	defer l.mu.RUnlock()
-
-	if len(l.items) == 0 {
-		return false
+	for _, activity := range activities {
+		p.activityFeed.Send(activity)
 	}
-	return l.items[len(l.items)-1].GasPrice().Cmp(tx.GasPrice()) >= 0
 }
 
-// Discard drops a number of transactions from the priced list.
-func (l *parallelPricedList) Discard(count int) []*types.Transaction {
-	l.mu.Lock()
-	defer l.mu.Unlock()
-
-	if count > len(l.items) {
-		count = len(l.items)
-	}
-	drop := l.items[len(l.items)-count:]
-	l.items = l.items[:len(l.items)-count]
-	return drop
-}
-
-// prepareBatches organizes parallelizable transactions into execution batches
-func (p *ParallelPool) prepareBatches() {
-	p.batchMu.Lock()
-	defer p.batchMu.Unlock()
-
+// buildBatches regroups the parallelizable transactions into batches. The caller
+// must hold the batch lock.
+func (p *ParallelPool) buildBatches() {
 	// Skip if no parallelizable transactions
 	totalTxs := 0
 	for _, txs := range p.parallelizableTxs {
 		totalTxs += len(txs)
 	}
 	if totalTxs == 0 {
+		p.batchedTxs = nil
+		p.countBatches()
 		return
 	}
 
-	// Create new batches
-	p.batchedTxs = nil
-	var currentBatch TxBatch
-	currentBatch.Transactions = make([]*types.Transaction, 0, p.batchSize)
-	currentBatch.BatchID = uint64(time.Now().UnixNano())
-
-	// Collect transactions from all accounts
-	txCount := 0
-	for addr, txs := range p.parallelizableTxs {
+	// Collect transactions from all accounts, arranging them into dependency
+	// levels so that no batch holds a transaction along with one it depends on.
+	// Candidates are sorted by hash first, so the schedule only depends on the
+	// pool content and not on map iteration order.
+	candidates := make([]*types.Transaction, 0, totalTxs)
+	for _, txs := range p.parallelizableTxs {
 		for _, tx := range txs {
-			currentBatch.Transactions = append(currentBatch.Transactions, tx)
-			txCount++
-
-			// When batch is full, add it and create a new one
-			if txCount >= p.batchSize {
-				p.batchedTxs = append(p.batchedTxs, currentBatch)
-				currentBatch.Transactions = make([]*types.Transaction, 0, p.batchSize)
-				currentBatch.BatchID = uint64(time.Now().UnixNano())
-				txCount = 0
+			if !p.quarantine.contains(tx.Hash()) {
+				candidates = append(candidates, tx)
 			}
 		}
 	}
+	slices.SortFunc(candidates, func(a, b *types.Transaction) int { return a.Hash().Cmp(b.Hash()) })
+	levels, held := scheduleLevels(p.signer, candidates, p.dependencies, func(hash common.Hash) bool {
+		return p.all[hash] != nil
+	})
+	if len(held) > 0 {
+		log.Trace("Parallel transactions awaiting unbatched dependencies", "count", len(held))
+	}
+	gasLimit := p.batchGasLimit()
 
-	// Add the last batch if it has any transactions
-	if txCount > 0 {
-		p.batchedTxs = append(p.batchedTxs, currentBatch)
+	p.batchedTxs = nil
+	for level, txs := range levels {
+		// Transactions whose footprint is unknown are conservatively kept out
+		// of shared batches
+		var shared, isolated []*types.Transaction
+		for _, tx := range txs {
+			if _, unknown := p.unknownFootprint[tx.Hash()]; unknown {
+				isolated = append(isolated, tx)
+			} else {
+				shared = append(shared, tx)
+			}
+		}
+		// Assign transactions by deadline and price priority to the first
+		// batch with room, both in count and gas, whose members they don't
+		// conflict with, then order the members of each batch according to the
+		// intra-batch policy
+		var batches []TxBatch
+		for _, tx := range p.prioritize(shared) {
+			placed := false
+			for i := range batches {
+				if len(batches[i].Transactions) < p.batchSize && batches[i].TotalGas+tx.Gas() <= gasLimit && !p.conflictsWith(tx, batches[i].Transactions) {
+					batches[i].Transactions = append(batches[i].Transactions, tx)
+					batches[i].TotalGas += tx.Gas()
+					placed = true
+					break
+				}
+			}
+			if !placed {
+				batches = append(batches, TxBatch{
+					Transactions: []*types.Transaction{tx},
+					Level:        level,
+					TotalGas:     tx.Gas(),
+				})
+			}
+		}
+		for _, batch := range batches {
+			batch.Transactions = orderTransactions(p.signer, batch.Transactions, p.batchOrdering, p.arrivals)
+			batch.BatchID = batchID(batch)
+			p.batchedTxs = append(p.batchedTxs, batch)
+		}
+		// Give every transaction with an unknown footprint a batch of its own
+		for _, tx := range isolated {
+			batch := TxBatch{Transactions: []*types.Transaction{tx}, Level: level, TotalGas: tx.Gas()}
+			batch.BatchID = batchID(batch)
+			p.batchedTxs = append(p.batchedTxs, batch)
+		}
 	}
 
 	// Update metrics
-	p.batchSizeGauge.Update(int64(p.batchSize))
-	p.batchCountGauge.Update(int64(len(p.batchedTxs)))
+	p.countBatches()
+	p.metrics.batchSize.Update(int64(p.batchSize))
+	p.metrics.batchCount.Update(int64(len(p.batchedTxs)))
+}
+
+// batchGasLimit returns the maximum total gas of a batch: the configured limit,
+// or a fraction of the block gas limit, capped to the block gas limit. Without
+// a known block gas limit, batches are only bounded by count.
+func (p *ParallelPool) batchGasLimit() uint64 {
+	limit := p.config.BatchGasLimit
+	if p.currentMaxGas == 0 {
+		if limit == 0 {
+			return math.MaxUint64
+		}
+		return limit
+	}
+	if limit == 0 {
+		limit = p.currentMaxGas / batchGasDivisor
+	}
+	return min(limit, p.currentMaxGas)
 }
 
 // ExecuteBatch executes a batch of parallelizable transactions
 func (p *ParallelPool) ExecuteBatch(batch TxBatch) ([]common.Hash, error) {
+	if p.config.ReadOnly {
+		return nil, ErrReadOnly
+	}
 	if len(batch.Transactions) == 0 {
 		return nil, nil
 	}
@@ -856,6 +2158,7 @@ func (p *ParallelPool) ExecuteBatch(batch TxBatch) ([]common.Hash, error) {
 	log.Debug("Executing batch of parallel transactions",
 		"batchID", batch.BatchID,
 		"txCount", len(batch.Transactions))
+	start := time.Now()
 
 	// Create a channel for results
 	type txResult struct {
@@ -864,70 +2167,109 @@ func (p *ParallelPool) ExecuteBatch(batch TxBatch) ([]common.Hash, error) {
 	}
 	resultCh := make(chan txResult, len(batch.Transactions))
 
-	// Use semaphore to limit concurrent executions if needed
-	sem := make(chan struct{}, runtime.NumCPU())
-
 	// Get current state to work with
 	header := p.chain.CurrentBlock()
-	stateDB, err := p.chain.StateAt(header.Root)
+	stateDB, err := p.stateAt(header.Root)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get state for batch execution: %v", err)
 	}
+	p.lifecycleFeed.Send(newBatchEvent(BatchExecuting, batch))
 
 	// Clone state for each transaction to isolate changes
 	for _, tx := range batch.Transactions {
 		tx := tx // Capture variable for goroutine
 		txHash := tx.Hash()
 
-		// Acquire semaphore slot
-		sem <- struct{}{}
-
-		go func() {
-			defer func() { <-sem }() // Release semaphore slot
-
-			// Create an isolated state copy for this transaction
-			txStateDB := stateDB.Copy()
-
-			// Process transaction (would integrate with EVM in real implementation)
-			// For now, we simulate execution by retrieving sender and updating state
-			from, err := types.Sender(p.signer, tx)
-			if err != nil {
-				resultCh <- txResult{txHash, err}
-				return
-			}
-
-			// Apply transaction changes to state
-			// In a real implementation, this would involve running the transaction
-			// through the EVM and applying the resulting state changes
-			txStateDB.SetNonce(from, txStateDB.GetNonce(from)+1)
-
-			// Record success
-			resultCh <- txResult{txHash, nil}
+		// Create an isolated state copy for this transaction, serially as
+		// concurrent copies of a StateDB are unsafe
+		txStateDB := stateDB.Copy()
+
+		p.workers.Go(func() {
+			// A panicking execution fails the transaction, the result must be
+			// delivered regardless for the batch not to hang
+			txStart := time.Now()
+			err := p.workers.Guard(tx, func() error {
+				// Process transaction (would integrate with EVM in real implementation)
+				// For now, we simulate execution by retrieving sender and updating state
+				from, err := types.Sender(p.signer, tx)
+				if err != nil {
+					return err
+				}
 
-			log.Trace("Executed parallel transaction",
-				"hash", txHash.Hex(),
-				"from", from.Hex(),
-				"nonce", tx.Nonce())
-		}()
+				// Apply transaction changes to state
+				// In a real implementation, this would involve running the transaction
+				// through the EVM and applying the resulting state changes
+				txStateDB.SetNonce(from, txStateDB.GetNonce(from)+1, tracing.NonceChangeUnspecified)
+
+				log.Trace("Executed parallel transaction",
+					"hash", txHash.Hex(),
+					"from", from.Hex(),
+					"nonce", tx.Nonce())
+				return nil
+			})
+			p.metrics.exec.UpdateTx(time.Since(txStart))
+			resultCh <- txResult{txHash, err}
+		})
 	}
 
 	// Collect results
+	var panicked bool
 	for i := 0; i < len(batch.Transactions); i++ {
 		result := <-resultCh
 		if result.err != nil {
 			failedTxs[result.txHash] = result.err
+			if _, ok := result.err.(*ExecutionPanic); ok {
+				panicked = true
+			}
 		} else {
 			executedTxs = append(executedTxs, result.txHash)
+		}
+	}
+	p.counters.processed.Add(int64(len(batch.Transactions)))
+
+	// Retire the batch under the pool lock like any other change of the pool
+	// content, the members being re-batched or reset concurrently otherwise
+	p.mu.Lock()
 
-			// Remove successfully executed transaction from pool
-			p.removeTx(result.txHash, true)
+	// Log the executed transactions before they leave the pool, so they can be
+	// restored if they never make it into the chain
+	if err := p.logBatch(batch, executedTxs); err != nil {
+		// The members stay pooled, none of them counts as executed
+		for _, hash := range executedTxs {
+			failedTxs[hash] = err
 		}
+		p.postExecuted(batch, failedTxs)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("failed to log executed batch: %v", err)
+	}
+	for _, hash := range executedTxs {
+		p.removeTx(hash, false, true)
 	}
+	p.counters.executed.Add(int64(len(executedTxs)))
+	p.batchMu.Lock()
+	p.executedBatches[batch.BatchID] = struct{}{}
+	p.batchMu.Unlock()
+	p.postExecuted(batch, failedTxs)
 
-	// Update metrics
-	if metricsMeter := metrics.GetOrRegisterMeter("parallel/txpool/executed", nil); metricsMeter != nil {
-		metricsMeter.Mark(int64(len(executedTxs)))
+	// Update metrics. Batch members have disjoint footprints and execute on
+	// isolated states, so the pool never re-executes them.
+	p.metrics.executed.Mark(int64(len(executedTxs)))
+	p.metrics.exec.UpdateBatch(time.Since(start), 0, 0)
+
+	// Quarantine batches failing over and over, keeping them out of re-batching.
+	// Panicking members may have been quarantined on their own already.
+	if len(failedTxs) > 0 {
+		if entry := p.quarantine.recordFailure(batch, failedTxs); entry != nil {
+			p.metrics.quarantined.Mark(1)
+			log.Warn("Quarantined repeatedly failing batch", "batchID", batch.BatchID, "key", entry.Key, "attempts", entry.Attempts, "until", entry.Until)
+			p.prepareBatches()
+		} else if panicked && p.config.QuarantinePanics {
+			p.prepareBatches()
+		}
+	} else {
+		p.quarantine.recordSuccess(batch)
 	}
+	p.mu.Unlock()
 
 	// Log execution summary
 	if len(failedTxs) > 0 {
@@ -944,7 +2286,10 @@ func (p *ParallelPool) ExecuteBatch(batch TxBatch) ([]common.Hash, error) {
 	return executedTxs, nil
 }
 
-// GetBatches returns the current batches of parallelizable transactions
+// GetBatches returns the current batches of parallelizable transactions, in
+// dependency level order. Executing the batches level by level, with batches of
+// the same level in any order, satisfies all declared dependencies. Members of
+// a batch have no conflicting footprints.
 func (p *ParallelPool) GetBatches() []TxBatch {
 	p.batchMu.RLock()
 	defer p.batchMu.RUnlock()
@@ -965,10 +2310,35 @@ func (p *ParallelPool) SetBatchSize(size int) {
 		size = MaxBatchSize
 	}
 
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
 	p.batchMu.Lock()
 	p.batchSize = size
+	p.counters.batchSize.Store(int64(size))
 	p.batchMu.Unlock()
 
 	// Re-prepare batches with new size
 	p.prepareBatches()
 }
+
+// Classifier returns the static calldata classifier of the pool, allowing rules
+// to be registered for further methods.
+func (p *ParallelPool) Classifier() *Classifier {
+	return p.classifier
+}
+
+// Parallelism returns the number of workers executing the members of batches.
+func (p *ParallelPool) Parallelism() int {
+	return p.workers.Size()
+}
+
+// SetParallelism resizes the worker pool executing the members of batches.
+// Executions in flight keep running, surplus workers retire once done.
+func (p *ParallelPool) SetParallelism(workers int) error {
+	if err := p.workers.SetSize(workers); err != nil {
+		return err
+	}
+	log.Info("Updated parallel execution workers", "workers", workers)
+	return nil
+}
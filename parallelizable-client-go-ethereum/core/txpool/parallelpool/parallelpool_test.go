@@ -0,0 +1,542 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a read-only pool refuses to execute batches, both directly and
+// through the RPC API, while still reporting its batches.
+func TestReadOnlyExecution(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	batch := TxBatch{Transactions: []*types.Transaction{pricedTransaction(0, 1, key)}, BatchID: 1}
+
+	pool := &ParallelPool{
+		config:     Config{ReadOnly: true},
+		batchedTxs: []TxBatch{batch},
+	}
+	if _, err := pool.ExecuteBatch(batch); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("batch execution error mismatch: have %v, want %v", err, ErrReadOnly)
+	}
+	api := NewParallelTxPoolAPI(pool)
+	if _, err := api.ExecuteBatches(); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("batch execution error mismatch: have %v, want %v", err, ErrReadOnly)
+	}
+	if batches := pool.GetBatches(); len(batches) != 1 {
+		t.Fatalf("batch count mismatch: have %d, want 1", len(batches))
+	}
+}
+
+// makeAddressReserver returns an address reserver that panics on reserving an
+// address twice or releasing an unreserved one.
+func makeAddressReserver() txpool.AddressReserver {
+	var (
+		reserved = make(map[common.Address]struct{})
+		lock     sync.Mutex
+	)
+	return func(addr common.Address, reserve bool) error {
+		lock.Lock()
+		defer lock.Unlock()
+
+		_, exists := reserved[addr]
+		if reserve {
+			if exists {
+				panic("already reserved")
+			}
+			reserved[addr] = struct{}{}
+			return nil
+		}
+		if !exists {
+			panic("not reserved")
+		}
+		delete(reserved, addr)
+		return nil
+	}
+}
+
+// newTestPool creates a parallel pool operating on the given chain, initialized
+// at its current head.
+func newTestPool(t *testing.T, config Config, chain *core.BlockChain) *ParallelPool {
+	pool := New(config, chain)
+	if err := pool.Init(0, chain.CurrentBlock(), makeAddressReserver()); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	return pool
+}
+
+// Tests that resetting the pool drops the transactions included by the new head,
+// and reinjects those of blocks reorged out, keeping the SubPool views (pending,
+// status, nonce) consistent throughout.
+func TestResetReorg(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(&config)
+	newTx := func(nonce uint64, parallelType uint8) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: parallelType,
+		})
+	}
+	txs := []*types.Transaction{
+		newTx(0, types.ParallelTypeSequential),
+		newTx(1, types.ParallelTypeIndependent),
+	}
+	_, included, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, gen *core.BlockGen) {
+		gen.AddTx(txs[i])
+	})
+	_, fork, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, func(i int, gen *core.BlockGen) {
+		gen.SetCoinbase(common.Address{0x01})
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	check := func(stage string, status txpool.TxStatus, pending int, nonce uint64) {
+		t.Helper()
+		for i, tx := range txs {
+			if have := pool.Status(tx.Hash()); have != status {
+				t.Errorf("%s: tx %d status mismatch: have %v, want %v", stage, i, have, status)
+			}
+		}
+		if have := len(pool.Pending(txpool.PendingFilter{})[from]); have != pending {
+			t.Errorf("%s: pending count mismatch: have %d, want %d", stage, have, pending)
+		}
+		if have := pool.Nonce(from); have != nonce {
+			t.Errorf("%s: nonce mismatch: have %d, want %d", stage, have, nonce)
+		}
+	}
+	for i, err := range pool.Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	check("pooled", txpool.TxStatusPending, 2, 2)
+
+	// Including the transactions drops them from the pool
+	genesis := chain.CurrentBlock()
+	if _, err := chain.InsertChain(included); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	pool.Reset(genesis, chain.CurrentBlock())
+	check("included", txpool.TxStatusUnknown, 0, 2)
+
+	// Reorging them out of the chain reinjects them
+	head := chain.CurrentBlock()
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if chain.CurrentBlock().Hash() != fork[len(fork)-1].Hash() {
+		t.Fatalf("fork did not become canonical")
+	}
+	pool.Reset(head, chain.CurrentBlock())
+	check("reorged", txpool.TxStatusPending, 2, 2)
+}
+
+// Tests that a reset lagging behind the head the pool already followed, as the
+// transaction pool and the head loop both drive resets, does not rewind it.
+func TestResetStale(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(&config)
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, gen *core.BlockGen) {
+		gen.AddTx(types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        uint64(i),
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeSequential,
+		}))
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	genesis := chain.CurrentBlock()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	pool.Reset(genesis, blocks[1].Header())
+	if have := pool.Nonce(from); have != 2 {
+		t.Fatalf("nonce mismatch after reset: have %d, want 2", have)
+	}
+	// A reset onto the parent arriving late leaves the pool on the head
+	pool.Reset(genesis, blocks[0].Header())
+
+	pool.mu.RLock()
+	head := pool.currentHead
+	pool.mu.RUnlock()
+
+	if head.Hash() != blocks[1].Hash() {
+		t.Errorf("pool rewound: head %d, want %d", head.Number, blocks[1].Number())
+	}
+	if have := pool.Nonce(from); have != 2 {
+		t.Errorf("nonce mismatch after stale reset: have %d, want 2", have)
+	}
+}
+
+// Tests that pending sequential transactions left behind a nonce gap by a reorg
+// are moved back to the queue, unless a reinjected parallel transaction fills it.
+func TestResetReorgGap(t *testing.T) {
+	for _, parallelType := range []uint8{types.ParallelTypeSequential, types.ParallelTypeIndependent} {
+		key, _ := crypto.GenerateKey()
+		from := crypto.PubkeyToAddress(key.PublicKey)
+
+		config := *params.TestChainConfig
+		config.ParallelTxTime = new(uint64)
+
+		gspec := &core.Genesis{
+			Config:  &config,
+			Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+			BaseFee: big.NewInt(params.InitialBaseFee),
+		}
+		signer := types.LatestSigner(&config)
+		newTx := func(nonce uint64, parallelType uint8) *types.Transaction {
+			return types.MustSignNewTx(key, signer, &types.ParallelTx{
+				ChainID:      config.ChainID,
+				Nonce:        nonce,
+				GasTipCap:    common.Big1,
+				GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+				Gas:          params.TxGas,
+				To:           &common.Address{0xaa},
+				Value:        common.Big0,
+				ParallelType: parallelType,
+			})
+		}
+		included := []*types.Transaction{
+			newTx(0, types.ParallelTypeSequential),
+			newTx(1, parallelType),
+		}
+		_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 2, func(i int, gen *core.BlockGen) {
+			gen.AddTx(included[i])
+		})
+		_, fork, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 3, func(i int, gen *core.BlockGen) {
+			gen.SetCoinbase(common.Address{0x01})
+		})
+		chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+		if err != nil {
+			t.Fatalf("failed to create chain: %v", err)
+		}
+		pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+
+		genesis := chain.CurrentBlock()
+		if _, err := chain.InsertChain(blocks); err != nil {
+			t.Fatalf("failed to insert chain: %v", err)
+		}
+		pool.Reset(genesis, chain.CurrentBlock())
+
+		// Pool a sequential transaction on top of the included ones, then reorg
+		// them out of the chain
+		gapped := newTx(2, types.ParallelTypeSequential)
+		if err := pool.Add([]*types.Transaction{gapped}, false)[0]; err != nil {
+			t.Fatalf("failed to add transaction: %v", err)
+		}
+		if have := pool.Status(gapped.Hash()); have != txpool.TxStatusPending {
+			t.Fatalf("type %d: status mismatch before reorg: have %v, want pending", parallelType, have)
+		}
+		head := chain.CurrentBlock()
+		if _, err := chain.InsertChain(fork); err != nil {
+			t.Fatalf("failed to insert fork: %v", err)
+		}
+		pool.Reset(head, chain.CurrentBlock())
+
+		// A reinjected sequential transaction waits in the queue, leaving a gap
+		// in front of the pooled one, a parallel transaction fills it
+		want, demoted := txpool.TxStatusQueued, int64(1)
+		if parallelType != types.ParallelTypeSequential {
+			want, demoted = txpool.TxStatusPending, 0
+		}
+		if have := pool.Status(gapped.Hash()); have != want {
+			t.Errorf("type %d: status mismatch after reorg: have %v, want %v", parallelType, have, want)
+		}
+		if have := pool.metrics.pendingDemoted.Snapshot().Count(); have != demoted {
+			t.Errorf("type %d: demoted count mismatch: have %d, want %d", parallelType, have, demoted)
+		}
+		executable := pool.Pending(txpool.PendingFilter{})[from]
+		for _, tx := range executable {
+			if tx.Hash == gapped.Hash() && want != txpool.TxStatusPending {
+				t.Errorf("type %d: gapped transaction reported executable", parallelType)
+			}
+		}
+		pending, queued := pool.Stats()
+		if pending+queued != 3 || pending != len(executable) {
+			t.Errorf("type %d: stats mismatch: pending %d, queued %d, executable %d", parallelType, pending, queued, len(executable))
+		}
+		pool.Close()
+		chain.Stop()
+	}
+}
+
+// Tests that the pool follows the chain head on its own, dropping included
+// transactions without being reset by a transaction pool, and that resets onto
+// the head it already follows are noops. Every reset is announced.
+func TestHeadFollowing(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	tx := types.MustSignNewTx(key, types.LatestSigner(&config), &types.ParallelTx{
+		ChainID:      config.ChainID,
+		GasTipCap:    common.Big1,
+		GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+		Gas:          params.TxGas,
+		To:           &common.Address{0xaa},
+		Value:        common.Big0,
+		ParallelType: types.ParallelTypeIndependent,
+	})
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	resets := make(chan core.ChainHeadEvent, 2)
+	sub := pool.SubscribeResets(resets)
+	defer sub.Unsubscribe()
+
+	genesis := chain.CurrentBlock()
+	if err := pool.Add([]*types.Transaction{tx}, true)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); pool.Has(tx.Hash()); {
+		if time.Now().After(deadline) {
+			t.Fatalf("included transaction not dropped without a reset")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	pool.mu.RLock()
+	head, baseFee := pool.currentHead, pool.priced.heap.baseFee
+	pool.mu.RUnlock()
+	if head.Hash() != blocks[0].Hash() {
+		t.Fatalf("pool head mismatch: have %x, want %x", head.Hash(), blocks[0].Hash())
+	}
+	// The base fee moved, so the priced list must have been re-sorted
+	if have := pool.metrics.reheaps.Snapshot().Count(); have != 1 {
+		t.Errorf("reheap count mismatch: have %d, want 1", have)
+	}
+	if baseFee.Cmp(head.BaseFee) != 0 {
+		t.Errorf("priced base fee mismatch: have %v, want %v", baseFee, head.BaseFee)
+	}
+	// A transaction pool catching up with the same head must not reset twice
+	pool.Reset(genesis, chain.CurrentBlock())
+	if pool.currentHead != head {
+		t.Errorf("reset onto the current head replaced it")
+	}
+	select {
+	case ev := <-resets:
+		if ev.Header.Hash() != blocks[0].Hash() {
+			t.Errorf("announced reset mismatch: have %x, want %x", ev.Header.Hash(), blocks[0].Hash())
+		}
+	default:
+		t.Errorf("reset not announced")
+	}
+	if len(resets) != 0 {
+		t.Errorf("noop reset announced")
+	}
+}
+
+// Tests that batches are packed within the batch gas limit, defaulting to a
+// fraction of the block gas limit and never exceeding it, and that batches
+// report their total gas.
+func TestBatchGasPacking(t *testing.T) {
+	pool := &ParallelPool{
+		signer:            testSigner,
+		all:               make(map[common.Hash]*types.Transaction),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		footprints:        make(map[common.Hash]*rwSet),
+		quarantine:        newQuarantine("", 0),
+		batchSize:         DefaultBatchSize,
+		currentMaxGas:     100_000,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	for i := 0; i < 4; i++ {
+		key, _ := crypto.GenerateKey()
+		tx := pricedTransaction(0, 1, key)
+		pool.all[tx.Hash()] = tx
+		pool.parallelizableTxs[crypto.PubkeyToAddress(key.PublicKey)] = []*types.Transaction{tx}
+	}
+	tests := []struct {
+		limit   uint64
+		batches int
+	}{
+		{0, 4},             // A quarter of the block gas limit fits one transaction
+		{50_000, 2},        // Configured limit fits two transactions
+		{1_000_000_000, 1}, // Capped to the block gas limit, fitting all
+	}
+	for i, tt := range tests {
+		pool.config.BatchGasLimit = tt.limit
+		pool.buildBatches()
+
+		batches := pool.GetBatches()
+		if len(batches) != tt.batches {
+			t.Errorf("test %d: batch count mismatch: have %d, want %d", i, len(batches), tt.batches)
+		}
+		for j, batch := range batches {
+			if want := uint64(len(batch.Transactions)) * params.TxGas; batch.TotalGas != want {
+				t.Errorf("test %d: batch %d total gas mismatch: have %d, want %d", i, j, batch.TotalGas, want)
+			}
+		}
+	}
+}
+
+// Tests that executing batches retires their members under the pool lock, so
+// it can run concurrently with transactions arriving and the batches being
+// resized (run with -race).
+func TestExecuteBatchConcurrent(t *testing.T) {
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	keys := make([]*ecdsa.PrivateKey, 16)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	executed := make([]*types.Transaction, 8)
+	for i := range executed {
+		executed[i] = newTx(keys[i])
+	}
+	for i, err := range pool.Add(executed, false) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	batches := pool.GetBatches()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for _, batch := range batches {
+			if _, err := pool.ExecuteBatch(batch); err != nil {
+				t.Errorf("failed to execute batch %d: %v", batch.BatchID, err)
+			}
+		}
+	}()
+	arrived := make([]*types.Transaction, 0, len(keys)-len(executed))
+	for i := len(executed); i < len(keys); i++ {
+		tx := newTx(keys[i])
+		if err := pool.Add([]*types.Transaction{tx}, false)[0]; err != nil {
+			t.Errorf("failed to add transaction %d: %v", i, err)
+		}
+		pool.SetBatchSize(1 + i%4)
+		arrived = append(arrived, tx)
+	}
+	wg.Wait()
+
+	for i, tx := range executed {
+		if pool.Has(tx.Hash()) {
+			t.Errorf("executed transaction %d still pooled", i)
+		}
+	}
+	for i, tx := range arrived {
+		if !pool.Has(tx.Hash()) {
+			t.Errorf("arrived transaction %d missing", i)
+		}
+	}
+}
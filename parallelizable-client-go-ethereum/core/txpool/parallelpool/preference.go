@@ -0,0 +1,140 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// preferenceDomain separates preference signatures from any other message an
+// account might sign.
+const preferenceDomain = "parallel-preference"
+
+var (
+	// ErrInvalidPreferenceSignature is returned if a parallel preference is not
+	// signed by the account it applies to.
+	ErrInvalidPreferenceSignature = errors.New("invalid parallel preference signature")
+
+	// ErrStalePreference is returned if a parallel preference is not newer than
+	// the one already stored for the account.
+	ErrStalePreference = errors.New("stale parallel preference")
+)
+
+// ParallelPreference is an account's signed choice of whether its transactions
+// may be handled in parallel. Accounts opting out have all their transactions
+// routed through the sequential path regardless of tags, which is useful for
+// order-sensitive workflows. Preferences are ordered by timestamp, a newer one
+// superseding the previous.
+type ParallelPreference struct {
+	Account   common.Address `json:"account"`
+	OptOut    bool           `json:"optOut"`
+	Timestamp hexutil.Uint64 `json:"timestamp"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+// SigHash returns the hash the account signs, bound to the given chain.
+func (pref *ParallelPreference) SigHash(chainID *big.Int) common.Hash {
+	enc, _ := rlp.EncodeToBytes([]interface{}{preferenceDomain, chainID, pref.Account, pref.OptOut, uint64(pref.Timestamp)})
+	return crypto.Keccak256Hash(enc)
+}
+
+// SignParallelPreference creates a preference for the account of the given key,
+// signed for the given chain.
+func SignParallelPreference(key *ecdsa.PrivateKey, chainID *big.Int, optOut bool, timestamp uint64) (*ParallelPreference, error) {
+	pref := &ParallelPreference{
+		Account:   crypto.PubkeyToAddress(key.PublicKey),
+		OptOut:    optOut,
+		Timestamp: hexutil.Uint64(timestamp),
+	}
+	hash := pref.SigHash(chainID)
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+	pref.Signature = sig
+	return pref, nil
+}
+
+// Verify checks that the preference was signed by the account it applies to.
+func (pref *ParallelPreference) Verify(chainID *big.Int) error {
+	hash := pref.SigHash(chainID)
+	pub, err := crypto.SigToPub(hash[:], pref.Signature)
+	if err != nil {
+		return ErrInvalidPreferenceSignature
+	}
+	if crypto.PubkeyToAddress(*pub) != pref.Account {
+		return ErrInvalidPreferenceSignature
+	}
+	return nil
+}
+
+// SetParallelPreference stores a signed parallel preference of an account. If
+// the account opts out, its transactions awaiting batching are moved to the
+// sequential path right away.
+func (p *ParallelPool) SetParallelPreference(pref *ParallelPreference) error {
+	if err := pref.Verify(p.chainconfig.ChainID); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if old, ok := p.preferences[pref.Account]; ok && old.Timestamp >= pref.Timestamp {
+		return ErrStalePreference
+	}
+	p.preferences[pref.Account] = pref
+
+	var demoted []*types.Transaction
+	if pref.OptOut {
+		p.batchMu.RLock()
+		demoted = append(demoted, p.parallelizableTxs[pref.Account]...)
+		p.batchMu.RUnlock()
+
+		for _, tx := range demoted {
+			p.demote(tx.Hash())
+		}
+		if len(demoted) > 0 {
+			p.prepareBatches()
+		}
+	}
+	log.Debug("Updated parallel preference", "account", pref.Account, "optout", pref.OptOut, "demoted", len(demoted))
+	return nil
+}
+
+// ParallelPreference returns the stored parallel preference of an account, or
+// nil if the account never submitted one.
+func (p *ParallelPool) ParallelPreference(addr common.Address) *ParallelPreference {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	return p.preferences[addr]
+}
+
+// optedOut reports whether an account opted out of parallel handling. The
+// caller must hold the pool lock.
+func (p *ParallelPool) optedOut(addr common.Address) bool {
+	pref, ok := p.preferences[addr]
+	return ok && pref.OptOut
+}
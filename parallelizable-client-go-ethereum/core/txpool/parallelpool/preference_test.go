@@ -0,0 +1,89 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that signed parallel preferences are verified, ordered by timestamp and
+// that opting out demotes the transactions awaiting batching.
+func TestParallelPreference(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	pool := &ParallelPool{
+		chainconfig:       params.TestChainConfig,
+		signer:            testSigner,
+		all:               make(map[common.Hash]*types.Transaction),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		preferences:       make(map[common.Address]*ParallelPreference),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		batchSize:         DefaultBatchSize,
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", nil),
+	}
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+	chainID := params.TestChainConfig.ChainID
+
+	tx := pricedTransaction(0, 1, key)
+	pool.all[tx.Hash()] = tx
+	pool.parallelizableTxs[from] = []*types.Transaction{tx}
+
+	// Preferences signed for another chain or by another account are rejected
+	foreign, _ := SignParallelPreference(key, big.NewInt(12345), true, 1)
+	if err := pool.SetParallelPreference(foreign); !errors.Is(err, ErrInvalidPreferenceSignature) {
+		t.Fatalf("foreign chain preference error mismatch: have %v, want %v", err, ErrInvalidPreferenceSignature)
+	}
+	forged, _ := SignParallelPreference(key, chainID, true, 1)
+	forged.Account = common.Address{0x01}
+	if err := pool.SetParallelPreference(forged); !errors.Is(err, ErrInvalidPreferenceSignature) {
+		t.Fatalf("forged preference error mismatch: have %v, want %v", err, ErrInvalidPreferenceSignature)
+	}
+	// Opting out moves pending parallel transactions to the sequential path
+	optout, _ := SignParallelPreference(key, chainID, true, 2)
+	if err := pool.SetParallelPreference(optout); err != nil {
+		t.Fatalf("failed to set preference: %v", err)
+	}
+	if !pool.optedOut(from) {
+		t.Fatalf("account not opted out")
+	}
+	if len(pool.parallelizableTxs[from]) != 0 || pool.pending[from] == nil || pool.pending[from].Get(0) != tx {
+		t.Errorf("transaction not demoted on opt-out")
+	}
+	// Older preferences must not override newer ones
+	optin, _ := SignParallelPreference(key, chainID, false, 2)
+	if err := pool.SetParallelPreference(optin); !errors.Is(err, ErrStalePreference) {
+		t.Fatalf("stale preference error mismatch: have %v, want %v", err, ErrStalePreference)
+	}
+	optin, _ = SignParallelPreference(key, chainID, false, 3)
+	if err := pool.SetParallelPreference(optin); err != nil {
+		t.Fatalf("failed to opt back in: %v", err)
+	}
+	if pool.optedOut(from) {
+		t.Errorf("account still opted out")
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"container/heap"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// priceHeap is a heap.Interface implementation over transactions for retrieving
+// the cheapest ones first. If baseFee is set, the heap is sorted by the effective
+// tip with the given base fee, otherwise by fee cap. Among equally priced ones,
+// the latest arrivals come first, so that older transactions are kept longer.
+type priceHeap struct {
+	baseFee  *big.Int // heap should always be re-sorted after baseFee is changed
+	arrivals *arrivalTimes
+	list     []*types.Transaction
+}
+
+func (h *priceHeap) Len() int      { return len(h.list) }
+func (h *priceHeap) Swap(i, j int) { h.list[i], h.list[j] = h.list[j], h.list[i] }
+
+func (h *priceHeap) Less(i, j int) bool {
+	switch h.cmp(h.list[i], h.list[j]) {
+	case -1:
+		return true
+	case 1:
+		return false
+	}
+	if ti, tj := h.arrivals.get(h.list[i]), h.arrivals.get(h.list[j]); !ti.Equal(tj) {
+		return ti.After(tj)
+	}
+	return h.list[i].Nonce() > h.list[j].Nonce()
+}
+
+func (h *priceHeap) cmp(a, b *types.Transaction) int {
+	if h.baseFee != nil {
+		// Compare effective tips if baseFee is specified
+		if c := a.EffectiveGasTipCmp(b, h.baseFee); c != 0 {
+			return c
+		}
+	}
+	// Compare fee caps if baseFee is not specified or effective tips are equal
+	if c := a.GasFeeCapCmp(b); c != 0 {
+		return c
+	}
+	// Compare tips if effective tips and fee caps are equal
+	return a.GasTipCapCmp(b)
+}
+
+func (h *priceHeap) Push(x interface{}) {
+	tx := x.(*types.Transaction)
+	h.list = append(h.list, tx)
+}
+
+func (h *priceHeap) Pop() interface{} {
+	old := h.list
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	h.list = old[0 : n-1]
+	return x
+}
+
+// parallelPricedList is a price-sorted heap over the pooled transactions.
+// Removals are not applied to the heap, but only counted: stale price points
+// are dropped lazily when found at the heap start, and the heap is rebuilt from
+// the pool contents once a large enough ratio of them went stale. All methods
+// must be called with the pool lock held.
+type parallelPricedList struct {
+	stales int // Number of stale price points (re-heap trigger)
+
+	all  map[common.Hash]*types.Transaction // Pointer to the map of all transactions
+	heap priceHeap                          // Heap of prices of all the pooled transactions
+}
+
+// newParallelPricedList creates a new price-sorted transaction heap, breaking
+// price ties by the given arrival times.
+func newParallelPricedList(all map[common.Hash]*types.Transaction, arrivals *arrivalTimes) *parallelPricedList {
+	return &parallelPricedList{
+		all:  all,
+		heap: priceHeap{arrivals: arrivals},
+	}
+}
+
+// Put inserts a new transaction into the heap.
+func (l *parallelPricedList) Put(tx *types.Transaction) {
+	heap.Push(&l.heap, tx)
+}
+
+// Removed notifies the priced list that old transactions dropped from the pool.
+// The list will just keep a counter of stale objects and rebuild the heap if a
+// large enough ratio of transactions go stale.
+func (l *parallelPricedList) Removed(count int) {
+	// Bump the stale counter, but exit if still too low (< 25%)
+	l.stales += count
+	if l.stales <= len(l.heap.list)/4 {
+		return
+	}
+	// Seems we've reached a critical number of stale transactions, reheap
+	l.reheap()
+}
+
+// Underpriced checks whether a transaction is cheaper than (or as cheap as) the
+// lowest priced transaction currently pooled.
+func (l *parallelPricedList) Underpriced(tx *types.Transaction) bool {
+	// Discard stale price points if found at the heap start
+	for len(l.heap.list) > 0 {
+		if _, ok := l.all[l.heap.list[0].Hash()]; ok {
+			break
+		}
+		l.stales--
+		heap.Pop(&l.heap)
+	}
+	if len(l.heap.list) == 0 {
+		return false
+	}
+	return l.heap.cmp(l.heap.list[0], tx) >= 0
+}
+
+// Reheap re-sorts the heap by the effective tips at a new base fee, as a base
+// fee change shifts the relative order of transactions with different fee caps.
+// The heap is only rebuilt if the base fee actually changed, returning whether
+// it was.
+func (l *parallelPricedList) Reheap(baseFee *big.Int) bool {
+	old := l.heap.baseFee
+	if (old == nil) == (baseFee == nil) && (old == nil || old.Cmp(baseFee) == 0) {
+		return false
+	}
+	l.heap.baseFee = baseFee
+	l.reheap()
+	return true
+}
+
+// reheap forcibly rebuilds the heap based on the current pool contents.
+func (l *parallelPricedList) reheap() {
+	l.stales = 0
+	l.heap.list = make([]*types.Transaction, 0, len(l.all))
+	for _, tx := range l.all {
+		l.heap.list = append(l.heap.list, tx)
+	}
+	heap.Init(&l.heap)
+}
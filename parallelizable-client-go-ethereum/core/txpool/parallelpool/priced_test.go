@@ -0,0 +1,171 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the priced list orders transactions by effective tip once a base
+// fee is known, re-sorts them when it changes, drops stale price points lazily
+// and rebuilds the heap once too many of them went stale.
+func TestPricedList(t *testing.T) {
+	newTx := func(nonce uint64, feeCap, tip int64) *types.Transaction {
+		return types.NewTx(&types.DynamicFeeTx{Nonce: nonce, GasFeeCap: big.NewInt(feeCap), GasTipCap: big.NewInt(tip)})
+	}
+	var (
+		all  = make(map[common.Hash]*types.Transaction)
+		list = newParallelPricedList(all, nil)
+		txs  = []*types.Transaction{
+			newTx(0, 100, 1), // Cheapest tip, richest fee cap
+			newTx(1, 11, 10), // Poorest fee cap, effective tip of 3 at base fee 8
+			newTx(2, 100, 5),
+			newTx(3, 100, 6),
+			newTx(4, 100, 7),
+		}
+	)
+	for _, tx := range txs {
+		all[tx.Hash()] = tx
+		list.Put(tx)
+	}
+	// Without a base fee, the poorest fee cap is the cheapest
+	if head := list.heap.list[0]; head != txs[1] {
+		t.Fatalf("cheapest transaction mismatch without base fee: have nonce %d, want 1", head.Nonce())
+	}
+	if !list.Reheap(big.NewInt(8)) {
+		t.Fatalf("heap not rebuilt on base fee change")
+	}
+	if list.Reheap(big.NewInt(8)) {
+		t.Fatalf("heap rebuilt without base fee change")
+	}
+	if head := list.heap.list[0]; head != txs[0] {
+		t.Fatalf("cheapest transaction mismatch with base fee: have nonce %d, want 0", head.Nonce())
+	}
+	// A removed head is skipped, not compared against
+	delete(all, txs[0].Hash())
+	list.Removed(1)
+	if len(list.heap.list) != len(txs) {
+		t.Fatalf("heap rebuilt below the stale threshold")
+	}
+	if !list.Underpriced(newTx(5, 100, 2)) {
+		t.Errorf("transaction cheaper than every pooled one not underpriced")
+	}
+	if list.Underpriced(newTx(5, 100, 4)) {
+		t.Errorf("transaction pricier than the cheapest pooled one underpriced")
+	}
+	if len(list.heap.list) != len(txs)-1 || list.stales != 0 {
+		t.Errorf("stale head not dropped: %d price points, %d stale", len(list.heap.list), list.stales)
+	}
+	// A base fee drop lets the poorest fee cap pay its full tip, moving it from
+	// the heap start behind the other transactions
+	if head := list.heap.list[0]; head != txs[1] {
+		t.Fatalf("cheapest transaction mismatch with base fee: have nonce %d, want 1", head.Nonce())
+	}
+	list.Reheap(big.NewInt(1))
+	if head := list.heap.list[0]; head != txs[2] {
+		t.Fatalf("cheapest transaction mismatch with lowered base fee: have nonce %d, want 2", head.Nonce())
+	}
+	// Going over the stale threshold rebuilds the heap from the pool
+	delete(all, txs[1].Hash())
+	delete(all, txs[2].Hash())
+	list.Removed(2)
+	if len(list.heap.list) != len(all) || list.stales != 0 {
+		t.Errorf("heap not rebuilt: %d price points, %d stale", len(list.heap.list), list.stales)
+	}
+}
+
+// Tests that raising the gas tip drops the remote transactions it priced out at
+// the current base fee, keeping the ones only priced out by the base fee itself
+// and local ones.
+func TestSetGasTipReprice(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 6)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, feeCap, tip int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(feeCap),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	baseFee := int64(params.InitialBaseFee)
+	tests := []struct {
+		tx   *types.Transaction
+		keep bool
+	}{
+		{newTx(keys[0], 2*baseFee, 2), false},  // Tip cap below the new tip
+		{newTx(keys[1], baseFee+2, 10), false}, // Effective tip below the new tip
+		{newTx(keys[2], baseFee-1, 10), true},  // Fee cap below the base fee, already underpriced
+		{newTx(keys[3], baseFee-1, 2), false},  // Fee cap below the base fee, tip cap below the new tip
+		{newTx(keys[4], 2*baseFee, 10), true},  // Paying the new tip
+	}
+	for i, tt := range tests {
+		if err := pool.Add([]*types.Transaction{tt.tx}, false)[0]; err != nil {
+			t.Fatalf("test %d: failed to add transaction: %v", i, err)
+		}
+	}
+	local := newTx(keys[5], baseFee-1, 1)
+	if err := pool.Process(local, true); err != nil {
+		t.Fatalf("failed to add local transaction: %v", err)
+	}
+	pool.SetGasTip(big.NewInt(3))
+
+	for i, tt := range tests {
+		if have := pool.Has(tt.tx.Hash()); have != tt.keep {
+			t.Errorf("test %d: presence mismatch: have %v, want %v", i, have, tt.keep)
+		}
+	}
+	if !pool.Has(local.Hash()) {
+		t.Errorf("local transaction dropped")
+	}
+	if have := pool.metrics.underpriced.Snapshot().Count(); have != 3 {
+		t.Errorf("underpriced meter mismatch: have %d, want 3", have)
+	}
+}
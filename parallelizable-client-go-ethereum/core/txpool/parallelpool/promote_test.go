@@ -0,0 +1,143 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that promotion only scans the accounts marked dirty by queue changes,
+// and that removals re-mark the accounts still having queued transactions.
+func TestPromoteDirtyAccounts(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	all := make(map[common.Hash]*types.Transaction)
+	pool := &ParallelPool{
+		config:            Config{}.sanitize(),
+		signer:            testSigner,
+		locals:            newAccountSet(nil),
+		all:               all,
+		priced:            newParallelPricedList(all, nil),
+		pending:           make(map[common.Address]*parallelList),
+		queue:             make(map[common.Address]*parallelList),
+		dirty:             make(map[common.Address]struct{}),
+		beats:             make(map[common.Address]time.Time),
+		dependencies:      make(map[common.Hash][]common.Hash),
+		dependents:        make(map[common.Hash][]common.Hash),
+		hintIndex:         make(map[DependencyHint][]common.Hash),
+		parallelizableTxs: make(map[common.Address][]*types.Transaction),
+		unknownFootprint:  make(map[common.Hash]struct{}),
+		pendingState:      statedb,
+		metrics:           newPoolMetrics("", metrics.NewRegistry()),
+	}
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+	addrA, addrB := crypto.PubkeyToAddress(keyA.PublicKey), crypto.PubkeyToAddress(keyB.PublicKey)
+
+	// Queue a gapped transaction for both accounts, neither is executable yet
+	txA, txB := pricedTransaction(1, 1, keyA), pricedTransaction(1, 1, keyB)
+	for addr, tx := range map[common.Address]*types.Transaction{addrA: txA, addrB: txB} {
+		pool.all[tx.Hash()] = tx
+		pool.enqueueSequential(addr, tx)
+	}
+	if len(pool.dirty) != 2 {
+		t.Fatalf("dirty account count mismatch: have %d, want 2", len(pool.dirty))
+	}
+	pool.promoteExecutables()
+	if len(pool.dirty) != 0 || len(pool.queue) != 2 {
+		t.Fatalf("unexpected promotion: dirty %d, queued %d", len(pool.dirty), len(pool.queue))
+	}
+	// Close the nonce gaps, but only touch one of the accounts. The other one
+	// must not be scanned until something marks it dirty.
+	statedb.SetNonce(addrA, 1, tracing.NonceChangeUnspecified)
+	statedb.SetNonce(addrB, 1, tracing.NonceChangeUnspecified)
+
+	stale := pricedTransaction(5, 1, keyA)
+	pool.all[stale.Hash()] = stale
+	pool.enqueueSequential(addrA, stale)
+	pool.removeTx(stale.Hash(), false, true)
+	if _, ok := pool.dirty[addrA]; !ok {
+		t.Fatalf("removal did not mark account dirty")
+	}
+	pool.promoteExecutables()
+	if pool.pending[addrA] == nil || pool.pending[addrA].Get(1) == nil {
+		t.Errorf("dirty account not promoted")
+	}
+	if pool.queue[addrB] == nil || pool.queue[addrB].Get(1) == nil {
+		t.Errorf("clean account unexpectedly scanned")
+	}
+	// Once marked, the other account is picked up too
+	pool.dirty[addrB] = struct{}{}
+	pool.promoteExecutables()
+	if len(pool.queue) != 0 {
+		t.Errorf("queued account count mismatch: have %d, want 0", len(pool.queue))
+	}
+}
+
+// Tests that promoting enough accounts to be split across workers collects the
+// same promotions, in the same order, as scanning them inline (run with -race).
+func TestCollectPromotionsParallel(t *testing.T) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabaseForTesting())
+	pool := &ParallelPool{
+		signer:       testSigner,
+		queue:        make(map[common.Address]*parallelList),
+		dirty:        make(map[common.Address]struct{}),
+		beats:        make(map[common.Address]time.Time),
+		pendingState: statedb,
+		metrics:      newPoolMetrics("", metrics.NewRegistry()),
+	}
+	// Queue a run of transactions for each account, contiguous with the state
+	// nonce of some, gapped for others, and partially stale for the rest
+	accounts := make([]common.Address, 2*promoteParallelThreshold)
+	for i := range accounts {
+		key, _ := crypto.GenerateKey()
+		accounts[i] = crypto.PubkeyToAddress(key.PublicKey)
+
+		for nonce := uint64(1); nonce <= uint64(1+i%4); nonce++ {
+			pool.enqueueSequential(accounts[i], pricedTransaction(nonce, 1, key))
+		}
+		statedb.SetNonce(accounts[i], uint64(i%3), tracing.NonceChangeUnspecified)
+	}
+	want := pool.scanPromotions(accounts, statedb)
+	if len(want) == 0 || len(want) == len(accounts) {
+		t.Fatalf("uninteresting promotion set: %d of %d accounts", len(want), len(accounts))
+	}
+	have := pool.collectPromotions(accounts)
+	if len(have) != len(want) {
+		t.Fatalf("promoted account count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if have[i].addr != want[i].addr {
+			t.Fatalf("promotion %d account mismatch: have %x, want %x", i, have[i].addr, want[i].addr)
+		}
+		if len(have[i].txs) != len(want[i].txs) {
+			t.Fatalf("promotion %d transaction count mismatch: have %d, want %d", i, len(have[i].txs), len(want[i].txs))
+		}
+		for j, tx := range want[i].txs {
+			if have[i].txs[j].Hash() != tx.Hash() {
+				t.Errorf("promotion %d transaction %d mismatch: have %x, want %x", i, j, have[i].txs[j].Hash(), tx.Hash())
+			}
+		}
+	}
+}
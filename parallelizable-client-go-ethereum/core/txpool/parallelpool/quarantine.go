@@ -0,0 +1,307 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/lru"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// quarantineThreshold is the number of failed executions after which a batch
+	// is quarantined.
+	quarantineThreshold = 3
+
+	// defaultQuarantineCooldown is the time quarantined batch members are kept
+	// out of re-batching if not configured otherwise.
+	defaultQuarantineCooldown = 10 * time.Minute
+
+	// maxTrackedBatchFailures is the number of failing batches whose attempt
+	// counters are retained.
+	maxTrackedBatchFailures = 1024
+)
+
+// ErrBatchNotQuarantined is returned when releasing a batch that is not in
+// quarantine.
+var ErrBatchNotQuarantined = errors.New("batch not quarantined")
+
+// QuarantinedBatch is a batch that repeatedly failed execution, together with
+// the context of its last failure.
+type QuarantinedBatch struct {
+	Key          common.Hash            `json:"key"`
+	TxHashes     []common.Hash          `json:"txHashes"`
+	Transactions []hexutil.Bytes        `json:"transactions"`    // Encoded members, for offline replay
+	Failures     map[common.Hash]string `json:"failures"`        // Errors of the last failed execution
+	Stack        string                 `json:"stack,omitempty"` // Stack of the panicking worker, if the execution panicked
+	Attempts     int                    `json:"attempts"`
+	Since        time.Time              `json:"since"`
+	Until        time.Time              `json:"until"`
+}
+
+// batchKey identifies a batch by its member set, independent of the batch
+// identifier and member order, which change whenever batches are rebuilt.
+func batchKey(batch TxBatch) common.Hash {
+	hashes := make([]common.Hash, len(batch.Transactions))
+	for i, tx := range batch.Transactions {
+		hashes[i] = tx.Hash()
+	}
+	slices.SortFunc(hashes, func(a, b common.Hash) int { return a.Cmp(b) })
+
+	keccak := crypto.NewKeccakState()
+	for _, hash := range hashes {
+		keccak.Write(hash[:])
+	}
+	var key common.Hash
+	keccak.Read(key[:])
+	return key
+}
+
+// quarantine tracks failing batches and keeps the members of repeatedly failing
+// ones out of re-batching for a cooldown period.
+type quarantine struct {
+	cooldown time.Duration
+	dir      string // Directory quarantined batches are persisted to (optional)
+
+	attempts lru.BasicLRU[common.Hash, int]    // Failed executions per batch key
+	batches  map[common.Hash]*QuarantinedBatch // Quarantined batches by key
+	members  map[common.Hash]common.Hash       // Quarantined transaction hashes to batch key
+
+	now func() time.Time // Wall clock, swappable in tests
+	mu  sync.Mutex
+}
+
+// newQuarantine creates a batch quarantine persisting to the given directory,
+// if not empty.
+func newQuarantine(dir string, cooldown time.Duration) *quarantine {
+	if cooldown == 0 {
+		cooldown = defaultQuarantineCooldown
+	}
+	return &quarantine{
+		cooldown: cooldown,
+		dir:      dir,
+		attempts: lru.NewBasicLRU[common.Hash, int](maxTrackedBatchFailures),
+		batches:  make(map[common.Hash]*QuarantinedBatch),
+		members:  make(map[common.Hash]common.Hash),
+		now:      time.Now,
+	}
+}
+
+// recordFailure accounts a failed execution of a batch, quarantining it once it
+// failed quarantineThreshold times. It returns the quarantine entry if the batch
+// was quarantined by this call.
+func (q *quarantine) recordFailure(batch TxBatch, failures map[common.Hash]error) *QuarantinedBatch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key := batchKey(batch)
+	attempts, _ := q.attempts.Get(key)
+	attempts++
+	if attempts < quarantineThreshold {
+		q.attempts.Add(key, attempts)
+		return nil
+	}
+	q.attempts.Remove(key)
+
+	now := q.now()
+	entry := &QuarantinedBatch{
+		Key:      key,
+		Failures: make(map[common.Hash]string, len(failures)),
+		Attempts: attempts,
+		Since:    now,
+		Until:    now.Add(q.cooldown),
+	}
+	for _, tx := range batch.Transactions {
+		entry.TxHashes = append(entry.TxHashes, tx.Hash())
+		if blob, err := tx.MarshalBinary(); err == nil {
+			entry.Transactions = append(entry.Transactions, blob)
+		}
+		q.members[tx.Hash()] = key
+	}
+	for hash, err := range failures {
+		entry.Failures[hash] = err.Error()
+	}
+	q.batches[key] = entry
+	q.persist(entry)
+
+	return entry
+}
+
+// recordPanic quarantines a transaction whose execution panicked right away, as
+// a batch of its own, persisting it with the panic for bug reports. It returns
+// the quarantine entry, or nil if the transaction is already quarantined.
+func (q *quarantine) recordPanic(tx *types.Transaction, failure *ExecutionPanic) *QuarantinedBatch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.members[tx.Hash()]; ok {
+		return nil
+	}
+	now := q.now()
+	entry := &QuarantinedBatch{
+		Key:      batchKey(TxBatch{Transactions: []*types.Transaction{tx}}),
+		TxHashes: []common.Hash{tx.Hash()},
+		Failures: map[common.Hash]string{tx.Hash(): failure.Error()},
+		Stack:    string(failure.Stack),
+		Attempts: 1,
+		Since:    now,
+		Until:    now.Add(q.cooldown),
+	}
+	if blob, err := tx.MarshalBinary(); err == nil {
+		entry.Transactions = append(entry.Transactions, blob)
+	}
+	q.members[tx.Hash()] = entry.Key
+	q.batches[entry.Key] = entry
+	q.persist(entry)
+
+	return entry
+}
+
+// recordSuccess resets the failure counter of a batch.
+func (q *quarantine) recordSuccess(batch TxBatch) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.attempts.Remove(batchKey(batch))
+}
+
+// contains reports whether a transaction is a member of a batch currently in
+// quarantine. Batches whose cooldown elapsed are released on access.
+func (q *quarantine) contains(hash common.Hash) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	key, ok := q.members[hash]
+	if !ok {
+		return false
+	}
+	if q.now().Before(q.batches[key].Until) {
+		return true
+	}
+	q.release(key)
+	return false
+}
+
+// list returns the batches currently in quarantine.
+func (q *quarantine) list() []*QuarantinedBatch {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	entries := make([]*QuarantinedBatch, 0, len(q.batches))
+	for _, entry := range q.batches {
+		entries = append(entries, entry)
+	}
+	slices.SortFunc(entries, func(a, b *QuarantinedBatch) int { return a.Since.Compare(b.Since) })
+	return entries
+}
+
+// manualRelease releases a quarantined batch ahead of its cooldown.
+func (q *quarantine) manualRelease(key common.Hash) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.batches[key]; !ok {
+		return ErrBatchNotQuarantined
+	}
+	q.release(key)
+	return nil
+}
+
+// release drops a batch from quarantine, along with its persisted record. The
+// caller must hold the lock.
+func (q *quarantine) release(key common.Hash) {
+	entry := q.batches[key]
+	for _, hash := range entry.TxHashes {
+		delete(q.members, hash)
+	}
+	delete(q.batches, key)
+
+	if q.dir != "" {
+		if err := os.Remove(q.path(key)); err != nil && !os.IsNotExist(err) {
+			log.Warn("Failed to remove quarantined batch record", "key", key, "err", err)
+		}
+	}
+	log.Debug("Released batch from quarantine", "key", key)
+}
+
+// persist writes a quarantined batch with its failure context to disk for later
+// inspection. Failures are logged but otherwise ignored.
+func (q *quarantine) persist(entry *QuarantinedBatch) {
+	if q.dir == "" {
+		return
+	}
+	blob, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		log.Warn("Failed to encode quarantined batch", "key", entry.Key, "err", err)
+		return
+	}
+	if err := os.MkdirAll(q.dir, 0700); err != nil {
+		log.Warn("Failed to create quarantine directory", "dir", q.dir, "err", err)
+		return
+	}
+	if err := os.WriteFile(q.path(entry.Key), blob, 0600); err != nil {
+		log.Warn("Failed to persist quarantined batch", "key", entry.Key, "err", err)
+	}
+}
+
+// path returns the file a quarantined batch is persisted to.
+func (q *quarantine) path(key common.Hash) string {
+	return filepath.Join(q.dir, key.Hex()+".json")
+}
+
+// executionPanicked accounts a transaction whose execution panicked on a worker,
+// quarantining it right away if configured so.
+func (p *ParallelPool) executionPanicked(tx *types.Transaction, failure *ExecutionPanic) {
+	p.metrics.panics.Mark(1)
+
+	if !p.config.QuarantinePanics {
+		return
+	}
+	if entry := p.quarantine.recordPanic(tx, failure); entry != nil {
+		p.metrics.quarantined.Mark(1)
+		log.Warn("Quarantined panicking parallel transaction", "hash", tx.Hash(), "key", entry.Key, "until", entry.Until)
+	}
+}
+
+// Quarantine returns the batches currently quarantined after repeated failures.
+func (p *ParallelPool) Quarantine() []*QuarantinedBatch {
+	return p.quarantine.list()
+}
+
+// ReleaseQuarantine releases a quarantined batch, making its members eligible
+// for batching again.
+func (p *ParallelPool) ReleaseQuarantine(key common.Hash) error {
+	if err := p.quarantine.manualRelease(key); err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.prepareBatches()
+	return nil
+}
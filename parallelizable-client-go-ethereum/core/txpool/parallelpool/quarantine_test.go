@@ -0,0 +1,136 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+// Tests that batches are quarantined after repeated failures, persisted to disk,
+// and released either after the cooldown or manually.
+func TestBatchQuarantine(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		dir   = t.TempDir()
+		now   = time.Unix(1000, 0)
+		q     = newQuarantine(dir, time.Minute)
+		tx0   = pricedTransaction(0, 1, key)
+		tx1   = pricedTransaction(1, 1, key)
+		batch = TxBatch{BatchID: 7, Transactions: []*types.Transaction{tx0, tx1}}
+		fail  = map[common.Hash]error{tx1.Hash(): errors.New("merge conflict")}
+	)
+	q.now = func() time.Time { return now }
+
+	// Reordered or renumbered batches must still be recognised
+	rebuilt := TxBatch{BatchID: 9, Transactions: []*types.Transaction{tx1, tx0}}
+	if batchKey(batch) != batchKey(rebuilt) {
+		t.Fatalf("batch key depends on batch id or member order")
+	}
+	for i := 1; i < quarantineThreshold; i++ {
+		if entry := q.recordFailure(rebuilt, fail); entry != nil {
+			t.Fatalf("batch quarantined after %d failures", i)
+		}
+	}
+	entry := q.recordFailure(batch, fail)
+	if entry == nil {
+		t.Fatalf("batch not quarantined after %d failures", quarantineThreshold)
+	}
+	if !q.contains(tx0.Hash()) || !q.contains(tx1.Hash()) {
+		t.Fatalf("quarantined members not excluded")
+	}
+	if entry.Failures[tx1.Hash()] != "merge conflict" || len(entry.Transactions) != 2 {
+		t.Errorf("failure context mismatch: %+v", entry)
+	}
+	if _, err := os.Stat(q.path(entry.Key)); err != nil {
+		t.Errorf("quarantined batch not persisted: %v", err)
+	}
+	// Members are released once the cooldown elapses
+	now = now.Add(time.Minute)
+	if q.contains(tx0.Hash()) {
+		t.Errorf("member still quarantined after cooldown")
+	}
+	if len(q.list()) != 0 {
+		t.Errorf("expired batch still listed")
+	}
+	// Manual release drops the entry and its record
+	for i := 0; i < quarantineThreshold; i++ {
+		entry = q.recordFailure(batch, fail)
+	}
+	if err := q.manualRelease(entry.Key); err != nil {
+		t.Fatalf("failed to release batch: %v", err)
+	}
+	if _, err := os.Stat(q.path(entry.Key)); !os.IsNotExist(err) {
+		t.Errorf("released batch record not removed: %v", err)
+	}
+	if err := q.manualRelease(entry.Key); !errors.Is(err, ErrBatchNotQuarantined) {
+		t.Errorf("double release error mismatch: have %v, want %v", err, ErrBatchNotQuarantined)
+	}
+}
+
+// Tests that transactions whose execution panicked are quarantined on their own
+// right away if configured so, and merely counted otherwise.
+func TestPanicQuarantine(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	var (
+		dir     = t.TempDir()
+		tx      = pricedTransaction(0, 1, key)
+		failure = &ExecutionPanic{Tx: tx.Hash(), Value: "boom", Stack: []byte("stack")}
+	)
+	pool := &ParallelPool{
+		quarantine: newQuarantine(dir, time.Minute),
+		metrics:    newPoolMetrics("", metrics.NewRegistry()),
+	}
+	pool.executionPanicked(tx, failure)
+	if pool.quarantine.contains(tx.Hash()) {
+		t.Fatalf("panicking transaction quarantined without being configured to")
+	}
+	if have := pool.metrics.panics.Snapshot().Count(); have != 1 {
+		t.Errorf("panic count mismatch: have %d, want 1", have)
+	}
+	pool.config.QuarantinePanics = true
+	pool.executionPanicked(tx, failure)
+
+	if !pool.quarantine.contains(tx.Hash()) {
+		t.Fatalf("panicking transaction not quarantined")
+	}
+	entries := pool.Quarantine()
+	if len(entries) != 1 {
+		t.Fatalf("quarantine size mismatch: have %d, want 1", len(entries))
+	}
+	entry := entries[0]
+	if entry.Failures[tx.Hash()] != failure.Error() || entry.Stack != "stack" || len(entry.Transactions) != 1 {
+		t.Errorf("panic context mismatch: %+v", entry)
+	}
+	if _, err := os.Stat(pool.quarantine.path(entry.Key)); err != nil {
+		t.Errorf("panicking transaction not persisted: %v", err)
+	}
+	// Panicking again does not renew the quarantine
+	pool.executionPanicked(tx, failure)
+	if have := pool.metrics.quarantined.Snapshot().Count(); have != 1 {
+		t.Errorf("quarantine count mismatch: have %d, want 1", have)
+	}
+}
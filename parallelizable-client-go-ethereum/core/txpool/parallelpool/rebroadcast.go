@@ -0,0 +1,145 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+const (
+	// defaultRebroadcastDelay is the number of blocks a local parallel transaction
+	// may remain unincluded before it is first re-announced to peers.
+	defaultRebroadcastDelay = 4
+
+	// rebroadcastMaxBackoff caps the exponential backoff between two consecutive
+	// re-announcements of the same transaction, in blocks.
+	rebroadcastMaxBackoff = 128
+
+	// rebroadcastMaxTxs is the maximum number of transactions re-announced in a
+	// single round, rate limiting the gossip caused by a large local backlog.
+	rebroadcastMaxTxs = 256
+)
+
+// rebroadcastTx is a local transaction tracked for re-announcement.
+type rebroadcastTx struct {
+	tx      *types.Transaction
+	from    common.Address
+	next    uint64 // Block number at which the transaction is next re-announced
+	backoff uint64 // Blocks to wait after the next re-announcement
+}
+
+// rebroadcaster tracks local parallel transactions until they are included and
+// schedules their re-announcement with exponential backoff. A single gossip at
+// submission is easily lost on networks where few peers support the type.
+type rebroadcaster struct {
+	delay uint64                         // Blocks until the first re-announcement
+	txs   map[common.Hash]*rebroadcastTx // Tracked local transactions
+	mu    sync.Mutex
+}
+
+// newRebroadcaster creates a re-broadcast tracker announcing unincluded local
+// transactions for the first time after the given number of blocks.
+func newRebroadcaster(delay uint64) *rebroadcaster {
+	if delay == 0 {
+		delay = defaultRebroadcastDelay
+	}
+	return &rebroadcaster{
+		delay: delay,
+		txs:   make(map[common.Hash]*rebroadcastTx),
+	}
+}
+
+// track starts tracking a local transaction submitted at the given head.
+func (r *rebroadcaster) track(tx *types.Transaction, from common.Address, head uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.txs[tx.Hash()]; ok {
+		return
+	}
+	r.txs[tx.Hash()] = &rebroadcastTx{
+		tx:      tx,
+		from:    from,
+		next:    head + r.delay,
+		backoff: r.delay,
+	}
+}
+
+// due drops the tracked transactions that were included according to the given
+// nonce source and returns the ones to re-announce at the given head, oldest
+// schedule first and at most rebroadcastMaxTxs of them. Returned transactions
+// are rescheduled with a doubled backoff.
+func (r *rebroadcaster) due(head uint64, nonce func(common.Address) uint64) []*types.Transaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ready []*rebroadcastTx
+	for hash, entry := range r.txs {
+		if entry.tx.Nonce() < nonce(entry.from) {
+			delete(r.txs, hash)
+			continue
+		}
+		if entry.next <= head {
+			ready = append(ready, entry)
+		}
+	}
+	sort.Slice(ready, func(i, j int) bool {
+		if ready[i].next != ready[j].next {
+			return ready[i].next < ready[j].next
+		}
+		return ready[i].tx.Nonce() < ready[j].tx.Nonce()
+	})
+	if len(ready) > rebroadcastMaxTxs {
+		ready = ready[:rebroadcastMaxTxs]
+	}
+	txs := make([]*types.Transaction, 0, len(ready))
+	for _, entry := range ready {
+		entry.backoff = min(2*entry.backoff, rebroadcastMaxBackoff)
+		entry.next = head + entry.backoff
+		txs = append(txs, entry.tx)
+	}
+	return txs
+}
+
+// rebroadcastLoop re-announces unincluded local transactions whenever the pool
+// is reset to a new head, until the pool is closed.
+func (p *ParallelPool) rebroadcastLoop() {
+	defer p.wg.Done()
+
+	for {
+		select {
+		case head := <-p.rebroadcastCh:
+			p.mu.Lock()
+			txs := p.rebroadcast.due(head.Number.Uint64(), p.currentState.GetNonce)
+			p.mu.Unlock()
+
+			if len(txs) > 0 {
+				log.Debug("Re-announcing local parallel transactions", "number", head.Number, "count", len(txs))
+				p.metrics.rebroadcast.Mark(int64(len(txs)))
+				p.txFeed.Send(core.NewTxsEvent{Txs: txs})
+			}
+		case <-p.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that local transactions are re-announced with exponential backoff until
+// they are included.
+func TestRebroadcastBackoff(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	var (
+		r       = newRebroadcaster(2)
+		tx      = pricedTransaction(0, 1, key)
+		nonce   = uint64(0)
+		nonceFn = func(common.Address) uint64 { return nonce }
+	)
+	r.track(tx, from, 10)
+
+	// Announcements are due at 12, then backing off by 4 and 8 blocks
+	for _, step := range []struct {
+		head uint64
+		want int
+	}{{11, 0}, {12, 1}, {13, 0}, {15, 0}, {16, 1}, {23, 0}, {24, 1}} {
+		if have := len(r.due(step.head, nonceFn)); have != step.want {
+			t.Errorf("head %d: re-announced count mismatch: have %d, want %d", step.head, have, step.want)
+		}
+	}
+	// Once included, the transaction must no longer be tracked
+	nonce = 1
+	if have := r.due(1000, nonceFn); len(have) != 0 {
+		t.Errorf("included transaction re-announced")
+	}
+	if len(r.txs) != 0 {
+		t.Errorf("included transaction still tracked")
+	}
+}
+
+// Tests that a single re-broadcast round is capped.
+func TestRebroadcastLimit(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	r := newRebroadcaster(1)
+	for i := 0; i < rebroadcastMaxTxs+10; i++ {
+		r.track(pricedTransaction(uint64(i), 1, key), from, 0)
+	}
+	nonceFn := func(common.Address) uint64 { return 0 }
+	txs := r.due(1, nonceFn)
+	if len(txs) != rebroadcastMaxTxs {
+		t.Fatalf("round size mismatch: have %d, want %d", len(txs), rebroadcastMaxTxs)
+	}
+	for i, tx := range txs {
+		if tx.Nonce() != uint64(i) {
+			t.Fatalf("tx %d: nonce mismatch: have %d, want %d", i, tx.Nonce(), i)
+		}
+	}
+	if have := len(r.due(1, nonceFn)); have != 10 {
+		t.Errorf("remainder mismatch: have %d, want 10", have)
+	}
+}
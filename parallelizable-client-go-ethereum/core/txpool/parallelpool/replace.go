@@ -0,0 +1,66 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// ReplacementEvent is posted when a pooled transaction was replaced by a fee
+// bumped one with the same sender and nonce.
+type ReplacementEvent struct {
+	Old *types.Transaction
+	New *types.Transaction
+}
+
+// SubscribeReplacementEvent registers a subscription for replacements of pooled
+// transactions.
+func (p *ParallelPool) SubscribeReplacementEvent(ch chan<- ReplacementEvent) event.Subscription {
+	return p.scope.Track(p.replaceFeed.Subscribe(ch))
+}
+
+// replace makes room for a transaction replacing a pooled one of the same sender
+// and nonce, as long as it bumps both the fee cap and the tip of the old one by
+// the configured price bump. Transactions depending on the old one are detached
+// and subscribers are notified. The caller must hold the pool lock.
+func (p *ParallelPool) replace(from common.Address, old, tx *types.Transaction) error {
+	queued := p.queue[from] != nil && p.queue[from].Get(old.Nonce()) != nil
+	if !replaces(old, tx, p.config.PriceBump) {
+		if queued {
+			p.metrics.queuedDiscard.Mark(1)
+		} else {
+			p.metrics.pendingDiscard.Mark(1)
+		}
+		return txpool.ErrReplaceUnderpriced
+	}
+	p.postDrop(old, DropReplaced)
+	p.removeTx(old.Hash(), false, false)
+	p.demoteDependents(old.Hash(), tx.Hash())
+
+	if queued {
+		p.metrics.queuedReplace.Mark(1)
+	} else {
+		p.metrics.pendingReplace.Mark(1)
+	}
+	log.Trace("Replaced parallel pool transaction", "hash", old.Hash(), "replacement", tx.Hash())
+	p.replaceFeed.Send(ReplacementEvent{Old: old, New: tx})
+	return nil
+}
@@ -0,0 +1,144 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that replacements must bump both the fee cap and the tip by the price
+// bump, and that a zero price bump still requires strictly higher prices.
+func TestReplaces(t *testing.T) {
+	newTx := func(feeCap, tip int64) *types.Transaction {
+		return types.NewTx(&types.DynamicFeeTx{GasFeeCap: big.NewInt(feeCap), GasTipCap: big.NewInt(tip)})
+	}
+	old := newTx(100, 10)
+	tests := []struct {
+		tx    *types.Transaction
+		bump  uint64
+		valid bool
+	}{
+		{newTx(100, 10), 0, false},  // Same prices
+		{newTx(101, 11), 0, true},   // Any increase without a bump
+		{newTx(110, 11), 10, true},  // Exactly the bump
+		{newTx(200, 10), 10, false}, // Fee cap bumped only
+		{newTx(100, 20), 10, false}, // Tip bumped only
+		{newTx(109, 20), 10, false}, // Fee cap short of the bump
+	}
+	for i, tt := range tests {
+		if have := replaces(old, tt.tx, tt.bump); have != tt.valid {
+			t.Errorf("test %d: replacement mismatch: have %v, want %v", i, have, tt.valid)
+		}
+	}
+	// The list applies the same rules, returning the replaced transaction
+	list := newParallelList()
+	list.Add(old, 10)
+	if added, _ := list.Add(newTx(200, 10), 10); added {
+		t.Errorf("list accepted replacement without tip bump")
+	}
+	replacement := newTx(110, 11)
+	if added, replaced := list.Add(replacement, 10); !added || replaced != old {
+		t.Errorf("list replacement mismatch: added %v, replaced %v", added, replaced)
+	}
+	if list.GetByHash(old.Hash()) != nil || list.Get(0) != replacement {
+		t.Errorf("list not updated by replacement")
+	}
+}
+
+// Tests that the pool enforces the configured price bump on replacements, marks
+// the replace meter and notifies subscribers of the replacement.
+func TestPoolReplacement(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	events := make(chan ReplacementEvent, 1)
+	sub := pool.SubscribeReplacementEvent(events)
+	defer sub.Unsubscribe()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(feeCap, tip int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(feeCap),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeSequential,
+		})
+	}
+	feeCap := int64(2 * params.InitialBaseFee)
+	old := newTx(feeCap, 100)
+	if err := pool.Add([]*types.Transaction{old}, false)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	// Higher prices short of the default 10% bump are refused
+	if err := pool.Add([]*types.Transaction{newTx(feeCap+1, 109)}, false)[0]; !errors.Is(err, txpool.ErrReplaceUnderpriced) {
+		t.Fatalf("replacement error mismatch: have %v, want %v", err, txpool.ErrReplaceUnderpriced)
+	}
+	if have := pool.metrics.pendingDiscard.Snapshot().Count(); have != 1 {
+		t.Errorf("pending discard meter mismatch: have %d, want 1", have)
+	}
+	replacement := newTx(feeCap*11/10, 110)
+	if err := pool.Add([]*types.Transaction{replacement}, false)[0]; err != nil {
+		t.Fatalf("failed to replace transaction: %v", err)
+	}
+	if pool.Has(old.Hash()) || !pool.Has(replacement.Hash()) {
+		t.Fatalf("pool not updated by replacement")
+	}
+	if have := pool.metrics.pendingReplace.Snapshot().Count(); have != 1 {
+		t.Errorf("pending replace meter mismatch: have %d, want 1", have)
+	}
+	select {
+	case ev := <-events:
+		if ev.Old != old || ev.New != replacement {
+			t.Errorf("replacement event mismatch: have %x -> %x, want %x -> %x", ev.Old.Hash(), ev.New.Hash(), old.Hash(), replacement.Hash())
+		}
+	default:
+		t.Errorf("no replacement event posted")
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that senders are reserved on their first admission and released on the
+// removal of their last transaction, that senders reserved by another subpool
+// are refused, and that evicting a transaction whose dependents include all of
+// the incoming sender's ones keeps the sender reserved.
+func TestAddressReservation(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	var (
+		addrA = crypto.PubkeyToAddress(keys[0].PublicKey)
+		addrB = crypto.PubkeyToAddress(keys[1].PublicKey)
+		addrC = crypto.PubkeyToAddress(keys[2].PublicKey)
+	)
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	// Track the reservations of all subpools by owner, like the txpool does
+	var (
+		reserved = make(map[common.Address]string)
+		lock     sync.Mutex
+	)
+	reserver := func(owner string) txpool.AddressReserver {
+		return func(addr common.Address, exclusive bool) error {
+			lock.Lock()
+			defer lock.Unlock()
+
+			holder, ok := reserved[addr]
+			if !exclusive {
+				if holder != owner {
+					t.Errorf("%s released reservation of %s on %x", owner, holder, addr)
+				}
+				delete(reserved, addr)
+				return nil
+			}
+			if ok {
+				if holder == owner {
+					t.Errorf("%s reserved %x twice", owner, addr)
+				}
+				return txpool.ErrAlreadyReserved
+			}
+			reserved[addr] = owner
+			return nil
+		}
+	}
+	holder := func(addr common.Address) string {
+		lock.Lock()
+		defer lock.Unlock()
+		return reserved[addr]
+	}
+	pool := New(Config{GlobalSlots: 2, GlobalQueue: 1, MetricsRegistry: metrics.NewRegistry()}, chain)
+	if err := pool.Init(0, chain.CurrentBlock(), reserver("parallel")); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, tip int64, hints ...DependencyHint) *types.Transaction {
+		parallelType := uint8(types.ParallelTypeIndependent)
+		if len(hints) > 0 {
+			parallelType = types.ParallelTypeDependent
+		}
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:         config.ChainID,
+			Nonce:           nonce,
+			GasTipCap:       big.NewInt(tip),
+			GasFeeCap:       big.NewInt(2 * params.InitialBaseFee),
+			Gas:             params.TxGas,
+			To:              &common.Address{0xaa},
+			Value:           common.Big0,
+			ParallelType:    parallelType,
+			DependencyHints: hints,
+		})
+	}
+	// A sender holding blob transactions is refused
+	blobs := reserver("blob")
+	if err := blobs(addrC, true); err != nil {
+		t.Fatalf("failed to reserve blob sender: %v", err)
+	}
+	if err := pool.Add([]*types.Transaction{newTx(keys[2], 0, 1)}, false)[0]; !errors.Is(err, txpool.ErrAlreadyReserved) {
+		t.Fatalf("reserved sender error mismatch: have %v, want %v", err, txpool.ErrAlreadyReserved)
+	}
+	if have := pool.metrics.reserveRejected.Snapshot().Count(); have != 1 {
+		t.Errorf("rejected reservation meter mismatch: have %d, want 1", have)
+	}
+	if err := blobs(addrC, false); err != nil {
+		t.Fatalf("failed to release blob sender: %v", err)
+	}
+	// Senders are reserved once on admission and kept until their last removal
+	a := []*types.Transaction{newTx(keys[0], 0, 1), newTx(keys[0], 1, 1)}
+	b := newTx(keys[1], 0, 1, ShortHash(a[1].Hash()))
+	for i, err := range pool.Add(append(a, b), false) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	if holder(addrA) != "parallel" || holder(addrB) != "parallel" {
+		t.Fatalf("pooled senders not reserved: A %q, B %q", holder(addrA), holder(addrB))
+	}
+	// Evicting the highest transaction of A for a new one of B drops B's only
+	// pooled transaction in cascade, but B must stay reserved for the new one
+	incoming := newTx(keys[1], 1, 10)
+	if err := pool.Add([]*types.Transaction{incoming}, false)[0]; err != nil {
+		t.Fatalf("incoming transaction refused: %v", err)
+	}
+	if pool.Has(a[1].Hash()) || pool.Has(b.Hash()) || !pool.Has(incoming.Hash()) {
+		t.Fatalf("eviction mismatch: victim %v, dependent %v, incoming %v", pool.Has(a[1].Hash()), pool.Has(b.Hash()), pool.Has(incoming.Hash()))
+	}
+	if holder(addrB) != "parallel" {
+		t.Fatalf("sender of the incoming transaction not reserved")
+	}
+	pool.mu.Lock()
+	pool.removeTx(incoming.Hash(), true, true)
+	pool.removeTx(a[0].Hash(), true, true)
+	pool.mu.Unlock()
+
+	if holder(addrA) != "" || holder(addrB) != "" {
+		t.Fatalf("emptied senders still reserved: A %q, B %q", holder(addrA), holder(addrB))
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// scheduleNode is a transaction in the dependency graph being scheduled.
+type scheduleNode struct {
+	tx       *types.Transaction
+	children []*scheduleNode // Transactions waiting on this one
+	parents  int             // Number of unscheduled transactions this one waits on
+	blocked  bool            // Waits on a pooled transaction outside the graph
+}
+
+// scheduleLevels arranges transactions into dependency levels: the first level
+// holds the transactions without dependencies, every further level holds those
+// whose dependencies are all satisfied by earlier levels. Transactions of one
+// level are thus independent of each other and may execute in parallel.
+//
+// Besides the declared dependencies, a transaction implicitly depends on the
+// preceding nonce of its sender. Dependencies on transactions that are not
+// pooled anymore are considered satisfied. Transactions depending on pooled
+// transactions outside of the given set, or caught in a dependency cycle, can't
+// be scheduled and are returned separately, along with their dependents.
+func scheduleLevels(signer types.Signer, txs []*types.Transaction, deps map[common.Hash][]common.Hash, pooled func(common.Hash) bool) (levels [][]*types.Transaction, held []*types.Transaction) {
+	var (
+		nodes   = make(map[common.Hash]*scheduleNode, len(txs))
+		order   = make([]*scheduleNode, 0, len(txs))
+		senders = make(map[common.Address]map[uint64]*scheduleNode)
+	)
+	for _, tx := range txs {
+		node := &scheduleNode{tx: tx}
+		nodes[tx.Hash()] = node
+		order = append(order, node)
+
+		from, _ := types.Sender(signer, tx)
+		if senders[from] == nil {
+			senders[from] = make(map[uint64]*scheduleNode)
+		}
+		senders[from][tx.Nonce()] = node
+	}
+	// Wire up the declared and the implicit nonce dependencies
+	link := func(parent, child *scheduleNode) {
+		parent.children = append(parent.children, child)
+		child.parents++
+	}
+	for _, node := range order {
+		for _, dep := range deps[node.tx.Hash()] {
+			switch parent := nodes[dep]; {
+			case parent != nil:
+				link(parent, node)
+			case pooled(dep):
+				node.blocked = true
+			}
+		}
+		from, _ := types.Sender(signer, node.tx)
+		if nonce := node.tx.Nonce(); nonce > 0 {
+			if parent := senders[from][nonce-1]; parent != nil {
+				link(parent, node)
+			}
+		}
+	}
+	// Peel off the levels, a transaction becoming ready once all its parents
+	// were scheduled. Blocked transactions never become ready, neither do their
+	// dependents nor the members of cycles.
+	var frontier []*scheduleNode
+	for _, node := range order {
+		if node.parents == 0 && !node.blocked {
+			frontier = append(frontier, node)
+		}
+	}
+	scheduled := make(map[*scheduleNode]struct{}, len(order))
+	for len(frontier) > 0 {
+		var (
+			level = make([]*types.Transaction, 0, len(frontier))
+			next  []*scheduleNode
+		)
+		for _, node := range frontier {
+			level = append(level, node.tx)
+			scheduled[node] = struct{}{}
+
+			for _, child := range node.children {
+				if child.parents--; child.parents == 0 && !child.blocked {
+					next = append(next, child)
+				}
+			}
+		}
+		levels = append(levels, level)
+		frontier = next
+	}
+	for _, node := range order {
+		if _, ok := scheduled[node]; !ok {
+			held = append(held, node.tx)
+		}
+	}
+	return levels, held
+}
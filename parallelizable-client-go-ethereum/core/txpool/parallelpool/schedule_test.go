@@ -0,0 +1,84 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that transactions are arranged into dependency levels, honoring both
+// declared dependencies and sender nonce order, and that transactions waiting
+// on unbatched pooled transactions or on cycles are held back.
+func TestScheduleLevels(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 6)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+	}
+	var (
+		root    = pricedTransaction(0, 1, keys[0])
+		child   = pricedTransaction(0, 1, keys[1]) // Depends on root
+		next    = pricedTransaction(1, 1, keys[0]) // Follows root by nonce
+		grand   = pricedTransaction(0, 1, keys[2]) // Depends on child
+		free    = pricedTransaction(0, 1, keys[3])
+		outside = pricedTransaction(0, 1, keys[4]) // Pooled, but not scheduled
+		blocked = pricedTransaction(0, 2, keys[4]) // Depends on outside
+		cycleA  = pricedTransaction(0, 1, keys[5])
+		cycleB  = pricedTransaction(1, 1, keys[5]) // Depends on cycleA by nonce
+		mined   = common.Hash{0xff}
+		deps    = map[common.Hash][]common.Hash{
+			child.Hash():   {root.Hash()},
+			grand.Hash():   {child.Hash(), mined},
+			blocked.Hash(): {outside.Hash()},
+			cycleA.Hash():  {cycleB.Hash()},
+		}
+		pooled = map[common.Hash]bool{outside.Hash(): true}
+	)
+	levels, held := scheduleLevels(testSigner, []*types.Transaction{grand, next, child, root, free, blocked, cycleA, cycleB}, deps, func(hash common.Hash) bool {
+		return pooled[hash]
+	})
+	want := [][]*types.Transaction{{root, free}, {next, child}, {grand}}
+	if len(levels) != len(want) {
+		t.Fatalf("level count mismatch: have %d, want %d", len(levels), len(want))
+	}
+	for i := range want {
+		have := make(map[common.Hash]bool)
+		for _, tx := range levels[i] {
+			have[tx.Hash()] = true
+		}
+		if len(have) != len(want[i]) {
+			t.Errorf("level %d size mismatch: have %d, want %d", i, len(have), len(want[i]))
+		}
+		for _, tx := range want[i] {
+			if !have[tx.Hash()] {
+				t.Errorf("level %d missing transaction %x", i, tx.Hash())
+			}
+		}
+	}
+	if len(held) != 3 {
+		t.Fatalf("held transaction count mismatch: have %d, want 3", len(held))
+	}
+	for i, tx := range []*types.Transaction{blocked, cycleA, cycleB} {
+		if held[i] != tx {
+			t.Errorf("held transaction %d mismatch: have %x, want %x", i, held[i].Hash(), tx.Hash())
+		}
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BatchSimulation is the outcome of simulating a single batch member: its
+// execution result, the state it touched and the other members it conflicts
+// with. Members that could not be applied touch no state.
+type BatchSimulation struct {
+	Hash      common.Hash      `json:"hash"`
+	GasUsed   uint64           `json:"gasUsed"`
+	Failed    bool             `json:"failed"`          // Execution reverted
+	Error     string           `json:"error,omitempty"` // Transaction could not be applied
+	ReadSet   types.AccessList `json:"readSet"`
+	WriteSet  types.AccessList `json:"writeSet"`
+	Conflicts []BatchConflict  `json:"conflicts,omitempty"`
+}
+
+// BatchConflict is a conflict between two batch members: state written by one
+// of them and accessed by the other. The slot is omitted if the conflict is on
+// the account itself.
+type BatchConflict struct {
+	Counterparty common.Hash    `json:"counterparty"`
+	Address      common.Address `json:"address"`
+	Slot         *common.Hash   `json:"slot,omitempty"`
+}
+
+// stateAt opens the state used for batch executions and simulations at the
+// given root. With a shadow state configured, it is read through a dedicated
+// state database with its own trie readers and caches (bypassing the snapshot),
+// so that heavy simulations triggered over RPC do not contend with block
+// processing on the main state database.
+func (p *ParallelPool) stateAt(root common.Hash) (*state.StateDB, error) {
+	if p.shadow != nil {
+		return state.New(root, p.shadow)
+	}
+	return p.chain.StateAt(root)
+}
+
+// SimulateBatch executes the members of a batch in isolation on top of the
+// current head state and reports their outcome, without modifying the pool.
+func (p *ParallelPool) SimulateBatch(batch TxBatch) ([]*BatchSimulation, error) {
+	return p.SimulateBatchAt(batch, p.chain.CurrentBlock())
+}
+
+// SimulateBatchAt executes the members of a batch in isolation on top of the
+// state of the given block, as if they were included in its child, and reports
+// the conflicts between them. Simulating against historical blocks requires
+// their state to be available.
+func (p *ParallelPool) SimulateBatchAt(batch TxBatch, parent *types.Header) ([]*BatchSimulation, error) {
+	statedb, err := p.stateAt(parent.Root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for batch simulation: %v", err)
+	}
+	var (
+		adapter = core.NewPendingExecAdapter(p.chainconfig, p.chain, parent)
+		results = make([]*BatchSimulation, len(batch.Transactions))
+		sets    = make([]*rwSet, len(batch.Transactions))
+		wg      sync.WaitGroup
+	)
+	for i, tx := range batch.Transactions {
+		wg.Add(1)
+
+		// Copy the state serially, concurrent copies of a StateDB are unsafe
+		txState := statedb.Copy()
+		p.workers.Go(func() {
+			defer wg.Done()
+
+			var (
+				result = &BatchSimulation{Hash: tx.Hash()}
+				tracer *footprintTracer
+				res    *core.ExecutionResult
+			)
+			err := p.workers.Guard(tx, func() error {
+				msg, err := adapter.Message(tx)
+				if err != nil {
+					return err
+				}
+				txState.SetTxContext(tx.Hash(), i)
+				tracer, res, err = p.traceMessage(adapter, txState, msg, false)
+				return err
+			})
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.GasUsed, result.Failed = res.UsedGas, res.Failed()
+				result.ReadSet = tracer.reads.accessList()
+				result.WriteSet = tracer.writes.accessList()
+				sets[i] = &rwSet{reads: tracer.reads, writes: tracer.writes, accounts: tracer.accounts}
+			}
+			results[i] = result
+		})
+	}
+	wg.Wait()
+
+	// Cross-check the footprints of the applied members, reporting each
+	// conflict on both sides
+	for i := range sets {
+		for j := i + 1; j < len(sets); j++ {
+			if sets[i] == nil || sets[j] == nil {
+				continue
+			}
+			if addr, slot, ok := sets[i].conflict(sets[j]); ok {
+				results[i].Conflicts = append(results[i].Conflicts, BatchConflict{Counterparty: results[j].Hash, Address: addr, Slot: slot})
+				results[j].Conflicts = append(results[j].Conflicts, BatchConflict{Counterparty: results[i].Hash, Address: addr, Slot: slot})
+			}
+		}
+	}
+
+	log.Debug("Simulated parallel batch", "batchID", batch.BatchID, "txs", len(batch.Transactions), "parent", parent.Number, "shadow", p.shadow != nil)
+	return results, nil
+}
+
+// newShadowDatabase creates the standby state database used for batch
+// simulations, sharing only the trie database with the chain.
+func newShadowDatabase(chain BlockChain) state.Database {
+	return state.NewDatabase(chain.TrieDB(), nil)
+}
@@ -0,0 +1,186 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"slices"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that batch simulations yield the same results on the shadow state as on
+// the main state database, and leave the pool untouched.
+func TestSimulateBatchShadow(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	gspec := &core.Genesis{
+		Config:  params.TestChainConfig,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	batch := TxBatch{BatchID: 1, Transactions: []*types.Transaction{
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &common.Address{0xaa}, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)}),
+		types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 5, To: &common.Address{0xaa}, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)}),
+	}}
+	for _, shadow := range []bool{false, true} {
+		pool := newTestPool(t, Config{ShadowState: shadow}, chain)
+
+		results, err := pool.SimulateBatch(batch)
+		if err != nil {
+			t.Fatalf("shadow %v: simulation failed: %v", shadow, err)
+		}
+		if len(results) != 2 {
+			t.Fatalf("shadow %v: result count mismatch: have %d, want 2", shadow, len(results))
+		}
+		if res := results[0]; res.Error != "" || res.Failed || res.GasUsed != params.TxGas {
+			t.Errorf("shadow %v: valid tx result mismatch: %+v", shadow, res)
+		}
+		if res := results[1]; res.Error == "" {
+			t.Errorf("shadow %v: nonce gapped tx applied", shadow)
+		}
+		if pending, _ := pool.Stats(); pending != 0 {
+			t.Errorf("shadow %v: simulation modified the pool", shadow)
+		}
+		pool.Close()
+	}
+}
+
+// Tests that batches can be simulated against the state of historical blocks,
+// yielding the outcome they would have had at that point of the chain.
+func TestSimulateBatchAt(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	gspec := &core.Genesis{
+		Config:  params.TestChainConfig,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(params.TestChainConfig)
+	tx := types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: 0, To: &common.Address{0xaa}, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(tx)
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	batch := TxBatch{BatchID: 1, Transactions: []*types.Transaction{tx}}
+
+	// The transaction was included in the first block, it's only valid on top
+	// of the genesis state
+	results, err := pool.SimulateBatchAt(batch, chain.GetHeaderByNumber(0))
+	if err != nil {
+		t.Fatalf("historical simulation failed: %v", err)
+	}
+	if res := results[0]; res.Error != "" || res.GasUsed != params.TxGas {
+		t.Errorf("historical result mismatch: %+v", res)
+	}
+	results, err = pool.SimulateBatch(batch)
+	if err != nil {
+		t.Fatalf("head simulation failed: %v", err)
+	}
+	if res := results[0]; res.Error == "" {
+		t.Errorf("included transaction applied on head state")
+	}
+}
+
+// Tests that batch simulations report the state touched by each member and the
+// conflicts between them.
+func TestSimulateBatchConflicts(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{}, chain)
+	defer pool.Close()
+
+	// The first two transactions pay the same recipient, the third one calls
+	// another account without value
+	signer := types.LatestSigner(params.TestChainConfig)
+	newTx := func(key *ecdsa.PrivateKey, to common.Address, value int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{To: &to, Value: big.NewInt(value), Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	var (
+		shared = common.Address{0xaa}
+		other  = common.Address{0xbb}
+		batch  = TxBatch{Transactions: []*types.Transaction{
+			newTx(keys[0], shared, 1),
+			newTx(keys[1], shared, 1),
+			newTx(keys[2], other, 0),
+		}}
+	)
+	results, err := pool.SimulateBatch(batch)
+	if err != nil {
+		t.Fatalf("simulation failed: %v", err)
+	}
+	for i, res := range results {
+		if res.Error != "" || res.Failed {
+			t.Fatalf("tx %d: simulation result mismatch: %+v", i, res)
+		}
+	}
+	if !slices.ContainsFunc(results[0].WriteSet, func(tuple types.AccessTuple) bool { return tuple.Address == shared }) {
+		t.Errorf("paid recipient missing from write set: %v", results[0].WriteSet)
+	}
+	if !slices.ContainsFunc(results[2].ReadSet, func(tuple types.AccessTuple) bool { return tuple.Address == other }) {
+		t.Errorf("called account missing from read set: %v", results[2].ReadSet)
+	}
+	for i, want := range []common.Hash{batch.Transactions[1].Hash(), batch.Transactions[0].Hash()} {
+		conflicts := results[i].Conflicts
+		if len(conflicts) != 1 || conflicts[0].Counterparty != want || conflicts[0].Address != shared || conflicts[0].Slot != nil {
+			t.Errorf("tx %d: conflicts mismatch: %+v", i, conflicts)
+		}
+	}
+	if conflicts := results[2].Conflicts; len(conflicts) != 0 {
+		t.Errorf("independent tx conflicts: %+v", conflicts)
+	}
+}
@@ -0,0 +1,71 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// gasBudget is a token bucket limiting how much gas per second may be spent on
+// admission-time simulation of incoming transactions. The bucket holds at most
+// one second worth of budget.
+type gasBudget struct {
+	rate  uint64         // Gas refilled per second, zero disables the limit
+	avail uint64         // Gas currently available for simulation
+	last  mclock.AbsTime // Time of the last refill
+	clock mclock.Clock   // Clock used for refills, swappable in tests
+	mu    sync.Mutex
+}
+
+// newGasBudget creates a simulation budget refilling at the given gas rate per
+// second. A zero rate yields an unlimited budget.
+func newGasBudget(rate uint64, clock mclock.Clock) *gasBudget {
+	return &gasBudget{
+		rate:  rate,
+		avail: rate,
+		last:  clock.Now(),
+		clock: clock,
+	}
+}
+
+// take attempts to reserve the given amount of gas from the budget, returning
+// whether the simulation may proceed.
+func (b *gasBudget) take(gas uint64) bool {
+	if b.rate == 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	if refill := uint64(time.Duration(now-b.last).Seconds() * float64(b.rate)); refill > 0 {
+		if b.avail+refill > b.rate || b.avail+refill < b.avail {
+			b.avail = b.rate
+		} else {
+			b.avail += refill
+		}
+		b.last = now
+	}
+	if gas > b.avail {
+		return false
+	}
+	b.avail -= gas
+	return true
+}
@@ -0,0 +1,49 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/mclock"
+)
+
+// Tests that the speculative execution budget refuses simulations once drained
+// and refills proportionally to elapsed time, capped at one second of budget.
+func TestSpeculativeGasBudget(t *testing.T) {
+	clock := new(mclock.Simulated)
+	budget := newGasBudget(100_000, clock)
+
+	if !budget.take(60_000) {
+		t.Fatalf("simulation within budget refused")
+	}
+	if budget.take(60_000) {
+		t.Fatalf("simulation over budget allowed")
+	}
+	clock.Run(500 * time.Millisecond)
+	if !budget.take(60_000) {
+		t.Fatalf("simulation refused after refill")
+	}
+	clock.Run(time.Hour)
+	if budget.take(100_001) {
+		t.Fatalf("budget refilled beyond its cap")
+	}
+	if !newGasBudget(0, clock).take(1 << 40) {
+		t.Fatalf("unlimited budget refused simulation")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"sync/atomic"
+
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// txSlotSize is used to calculate how many data slots a single transaction
+// takes up based on its size, matching the legacy pool.
+const txSlotSize = 32 * 1024
+
+// numSlots calculates the number of slots needed for a single transaction.
+func numSlots(tx *types.Transaction) int64 {
+	return int64((tx.Size() + txSlotSize - 1) / txSlotSize)
+}
+
+// poolCounters track the size of the pool and the batch executions. They are
+// updated along with the pool content, under its locks, but read without any,
+// so that frequently scraped monitoring endpoints don't contend with transaction
+// processing.
+type poolCounters struct {
+	pending   atomic.Int64 // Executable transactions, sequential or awaiting a batch
+	queued    atomic.Int64 // Non-executable transactions
+	batched   atomic.Int64 // Transactions in the prepared batches
+	batches   atomic.Int64 // Prepared batches
+	slots     atomic.Int64 // Occupied transaction slots
+	batchSize atomic.Int64 // Configured number of transactions per batch
+	processed atomic.Int64 // Batch members executed, successfully or not
+	executed  atomic.Int64 // Batch members executed successfully
+}
+
+// PoolCounters is a snapshot of the pool counters. The counters are read
+// one by one, so a snapshot taken while the pool is being modified may mix
+// values from before and after the modification.
+type PoolCounters struct {
+	Pending   int `json:"pending"`   // Executable transactions, sequential or awaiting a batch
+	Queued    int `json:"queued"`    // Non-executable transactions
+	Batched   int `json:"batched"`   // Transactions in the prepared batches
+	Batches   int `json:"batches"`   // Prepared batches
+	Slots     int `json:"slots"`     // Occupied transaction slots
+	BatchSize int `json:"batchSize"` // Configured number of transactions per batch
+	Processed int `json:"processed"` // Batch members executed since startup, successfully or not
+	Executed  int `json:"executed"`  // Batch members executed successfully since startup
+}
+
+// Counters returns the current counters of the pool without locking it.
+func (p *ParallelPool) Counters() PoolCounters {
+	return PoolCounters{
+		Pending:   int(p.counters.pending.Load()),
+		Queued:    int(p.counters.queued.Load()),
+		Batched:   int(p.counters.batched.Load()),
+		Batches:   int(p.counters.batches.Load()),
+		Slots:     int(p.counters.slots.Load()),
+		BatchSize: int(p.counters.batchSize.Load()),
+		Processed: int(p.counters.processed.Load()),
+		Executed:  int(p.counters.executed.Load()),
+	}
+}
+
+// countBatches updates the batch counters after the batches were rebuilt. The
+// caller must hold the batch lock.
+func (p *ParallelPool) countBatches() {
+	var batched int
+	for _, batch := range p.batchedTxs {
+		batched += len(batch.Transactions)
+	}
+	p.counters.batched.Store(int64(batched))
+	p.counters.batches.Store(int64(len(p.batchedTxs)))
+}
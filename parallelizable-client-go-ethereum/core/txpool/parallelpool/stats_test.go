@@ -0,0 +1,155 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// countPool computes the size counters of a pool by walking its content. The
+// execution counters can't be recomputed and are taken over as they are.
+func countPool(p *ParallelPool) PoolCounters {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	var counters PoolCounters
+	for _, list := range p.pending {
+		counters.Pending += list.Len()
+	}
+	for _, list := range p.queue {
+		counters.Queued += list.Len()
+	}
+	for _, tx := range p.all {
+		counters.Slots += int(numSlots(tx))
+	}
+	p.batchMu.RLock()
+	defer p.batchMu.RUnlock()
+
+	for _, txs := range p.parallelizableTxs {
+		counters.Pending += len(txs)
+	}
+	for _, batch := range p.batchedTxs {
+		counters.Batched += len(batch.Transactions)
+	}
+	counters.Batches = len(p.batchedTxs)
+	counters.BatchSize = p.batchSize
+
+	counters.Processed = int(p.counters.processed.Load())
+	counters.Executed = int(p.counters.executed.Load())
+	return counters
+}
+
+// Tests that the lock-free counters track the pool content through insertions,
+// replacements, promotions, batch executions and removals.
+func TestCounters(t *testing.T) {
+	keyA, _ := crypto.GenerateKey()
+	keyB, _ := crypto.GenerateKey()
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			crypto.PubkeyToAddress(keyA.PublicKey): {Balance: big.NewInt(params.Ether)},
+			crypto.PubkeyToAddress(keyB.PublicKey): {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, tip int64, parallelType uint8) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: parallelType,
+		})
+	}
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+
+	check := func(stage string, want PoolCounters) {
+		t.Helper()
+		if have := pool.Counters(); have != want {
+			t.Errorf("%s: counters mismatch: have %+v, want %+v", stage, have, want)
+		}
+		if have := countPool(pool); have != want {
+			t.Errorf("%s: pool content mismatch: have %+v, want %+v", stage, have, want)
+		}
+		if pending, queued := pool.Stats(); pending != want.Pending || queued != want.Queued {
+			t.Errorf("%s: stats mismatch: have %d/%d, want %d/%d", stage, pending, queued, want.Pending, want.Queued)
+		}
+	}
+	pool.Add([]*types.Transaction{
+		newTx(keyA, 0, 1, types.ParallelTypeSequential),
+		newTx(keyA, 2, 1, types.ParallelTypeSequential),
+		newTx(keyB, 0, 1, types.ParallelTypeIndependent),
+		newTx(keyB, 1, 1, types.ParallelTypeIndependent),
+	}, false)
+	check("insertion", PoolCounters{Pending: 3, Queued: 1, Batched: 2, Batches: 2, Slots: 4, BatchSize: DefaultBatchSize})
+
+	pool.Add([]*types.Transaction{newTx(keyB, 1, 2, types.ParallelTypeIndependent)}, false)
+	check("replacement", PoolCounters{Pending: 3, Queued: 1, Batched: 2, Batches: 2, Slots: 4, BatchSize: DefaultBatchSize})
+
+	// Fill the nonce gap and promote the queued transactions once the pending
+	// one is executed
+	pool.Add([]*types.Transaction{newTx(keyA, 1, 1, types.ParallelTypeSequential)}, false)
+	check("gapped insertion", PoolCounters{Pending: 3, Queued: 2, Batched: 2, Batches: 2, Slots: 5, BatchSize: DefaultBatchSize})
+
+	pool.mu.Lock()
+	pool.pendingState.SetNonce(crypto.PubkeyToAddress(keyA.PublicKey), 1, tracing.NonceChangeUnspecified)
+	pool.dirty[crypto.PubkeyToAddress(keyA.PublicKey)] = struct{}{}
+	pool.promoteExecutables()
+	pool.mu.Unlock()
+	check("promotion", PoolCounters{Pending: 5, Batched: 2, Batches: 2, Slots: 5, BatchSize: DefaultBatchSize})
+
+	pool.SetBatchSize(2)
+	for _, batch := range pool.GetBatches() {
+		if _, err := pool.ExecuteBatch(batch); err != nil {
+			t.Fatalf("failed to execute batch %d: %v", batch.BatchID, err)
+		}
+	}
+	if have := pool.Counters(); have.Pending != 3 || have.Slots != 3 || have.Processed != 2 || have.Executed != 2 {
+		t.Errorf("execution: counters mismatch: have %+v, want 3 pending, 3 slots, 2 processed, 2 executed", have)
+	}
+
+	pool.Clear()
+	check("clear", PoolCounters{BatchSize: 2, Processed: 2, Executed: 2})
+}
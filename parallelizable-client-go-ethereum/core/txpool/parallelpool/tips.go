@@ -0,0 +1,185 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"math/big"
+	"slices"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+const (
+	// hotspotWindow is how far back conflict reports count towards the
+	// contention of a contract.
+	hotspotWindow = 10 * time.Minute
+
+	// hotspotConflicts is the number of recent conflicts on the state of a
+	// contract from which it is considered a hotspot.
+	hotspotConflicts = 8
+
+	// maxHotSlots is the number of most contended slots reported per contract.
+	maxHotSlots = 4
+)
+
+// ContractStats are the parallel lane statistics of the calls to a contract.
+type ContractStats struct {
+	Pooled    int           `json:"pooled"`    // Pooled parallel transactions calling the contract
+	Batches   int           `json:"batches"`   // Prepared batches holding them
+	Placement int           `json:"placement"` // Median index of the batches holding them
+	Conflicts int           `json:"conflicts"` // Recent conflicts on the contract's state
+	HotSlots  []common.Hash `json:"hotSlots"`  // Most contended slots of the contract, most contended first
+}
+
+// TipSuggestion advises on how to submit a transaction calling a contract: as a
+// parallel transaction or not, and with what tip to compete in the parallel lane.
+type TipSuggestion struct {
+	Contract common.Address `json:"contract"`
+	Stats    ContractStats  `json:"stats"`
+	Parallel bool           `json:"parallel"` // Whether tagging PARALLEL is likely beneficial
+	Tip      *hexutil.Big   `json:"tip"`      // Competitive effective tip within the parallel lane
+	Reason   string         `json:"reason"`
+}
+
+// contractHotspots counts the conflicts on the state of a contract reported
+// since the given time, returning the most contended slots along with them.
+func (p *ParallelPool) contractHotspots(contract common.Address, since time.Time) (int, []common.Hash) {
+	p.conflictMu.Lock()
+	defer p.conflictMu.Unlock()
+
+	var (
+		conflicts int
+		slots     = make(map[common.Hash]int)
+	)
+	for _, hash := range p.conflictReports.Keys() {
+		reports, _ := p.conflictReports.Peek(hash)
+		for _, report := range reports {
+			if report.Address == nil || *report.Address != contract || report.Time.Before(since) {
+				continue
+			}
+			conflicts++
+			if report.Slot != nil {
+				slots[*report.Slot]++
+			}
+		}
+	}
+	hot := make([]common.Hash, 0, len(slots))
+	for slot := range slots {
+		hot = append(hot, slot)
+	}
+	slices.SortFunc(hot, func(a, b common.Hash) int {
+		if slots[a] != slots[b] {
+			return slots[b] - slots[a]
+		}
+		return a.Cmp(b)
+	})
+	if len(hot) > maxHotSlots {
+		hot = hot[:maxHotSlots]
+	}
+	return conflicts, hot
+}
+
+// SuggestTip advises whether calls to a contract are worth tagging PARALLEL and
+// what tip is competitive for them in the parallel lane, based on the recent
+// conflicts on the contract's state and the placement of the pooled calls to it
+// in the prepared batches.
+//
+// Calls to a contract that keep conflicting with each other end up in separate
+// batches, one after the other, and gain nothing from parallel execution. They
+// compete among themselves, so the suggested tip outbids the best paying pooled
+// call. Otherwise calls share batches, which are filled in price order, and the
+// suggested tip is the lowest one of the batch the calls are typically placed in.
+// Either way, the tip is at least the minimum tip the pool accepts.
+func (p *ParallelPool) SuggestTip(contract common.Address) *TipSuggestion {
+	p.mu.RLock()
+	var baseFee *big.Int
+	if p.currentHead != nil {
+		baseFee = p.currentHead.BaseFee
+	}
+	floor := new(big.Int)
+	if p.gasTip != nil {
+		floor.Set(p.gasTip)
+	}
+	p.mu.RUnlock()
+
+	suggestion := &TipSuggestion{Contract: contract, Parallel: true}
+	stats := &suggestion.Stats
+	stats.Conflicts, stats.HotSlots = p.contractHotspots(contract, time.Now().Add(-hotspotWindow))
+
+	// Locate the pooled calls to the contract in the prepared batches
+	var (
+		placements []int
+		senders    = make(map[common.Address]struct{})
+		highest    = new(big.Int)
+		batchTips  []*big.Int // Lowest tip of each batch
+	)
+	p.batchMu.RLock()
+	for i, batch := range p.batchedTxs {
+		lowest, calls := (*big.Int)(nil), false
+		for _, tx := range batch.Transactions {
+			tip := tx.EffectiveGasTipValue(baseFee)
+			if lowest == nil || tip.Cmp(lowest) < 0 {
+				lowest = tip
+			}
+			if to := tx.To(); to != nil && *to == contract {
+				from, _ := types.Sender(p.signer, tx) // already validated
+				senders[from] = struct{}{}
+				stats.Pooled++
+				placements = append(placements, i)
+				if tip.Cmp(highest) > 0 {
+					highest = tip
+				}
+				calls = true
+			}
+		}
+		if calls {
+			stats.Batches++
+		}
+		batchTips = append(batchTips, lowest)
+	}
+	p.batchMu.RUnlock()
+
+	tip := new(big.Int)
+	switch {
+	case len(senders) > 1 && stats.Batches == stats.Pooled:
+		suggestion.Parallel = false
+		suggestion.Reason = "Pooled calls to the contract conflict with each other and are executed one batch after the other"
+		tip.Add(highest, common.Big1)
+
+	case stats.Conflicts >= hotspotConflicts:
+		suggestion.Parallel = false
+		suggestion.Reason = "The contract's state is a conflict hotspot, calls to it are likely reordered or serialized"
+		tip.Add(highest, common.Big1)
+
+	case len(placements) > 0:
+		slices.Sort(placements)
+		stats.Placement = placements[len(placements)/2]
+		suggestion.Reason = "Calls to the contract share batches, the tip matches their typical batch"
+		tip.Set(batchTips[stats.Placement])
+
+	default:
+		suggestion.Reason = "No pooled calls to the contract, nor recent conflicts on its state"
+	}
+	if tip.Cmp(floor) < 0 {
+		tip.Set(floor)
+	}
+	suggestion.Tip = (*hexutil.Big)(tip)
+	return suggestion
+}
@@ -0,0 +1,114 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that tip suggestions advise against tagging calls to contracts whose
+// calls conflict or whose state is a hotspot, outbidding the competing calls,
+// and otherwise match the tips of the batch calls are typically placed in.
+func TestSuggestTip(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	var (
+		contended = common.Address{0xc1} // SSTORE(0, 7); STOP
+		shared    = common.Address{0xc2} // STOP
+		hotspot   = common.Address{0xc3}
+		idle      = common.Address{0xc4}
+	)
+	alloc[contended] = types.Account{Code: common.FromHex("0x600760005500")}
+	alloc[shared] = types.Account{Code: common.FromHex("0x00")}
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{Config: &config, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	defer pool.Close()
+	pool.SetGasTip(common.Big1)
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, tip int64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    big.NewInt(tip),
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          50000,
+			To:           &to,
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	txs := []*types.Transaction{
+		newTx(keys[0], 0, contended, 5),
+		newTx(keys[1], 0, contended, 3),
+		newTx(keys[2], 0, shared, 4),
+		newTx(keys[3], 0, shared, 2),
+	}
+	for i, err := range pool.Add(txs, false) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	for i := 0; i < hotspotConflicts; i++ {
+		pool.ReportConflict(common.Hash{byte(i)}, ConflictReport{Action: ConflictReordered, Address: &hotspot, Slot: &common.Hash{0x01}})
+	}
+	tests := []struct {
+		contract common.Address
+		parallel bool
+		tip      int64
+	}{
+		{contended, false, 6}, // Outbid the best paying competing call
+		{shared, true, 2},     // Match the lowest tip of the shared batch
+		{hotspot, false, 1},   // Nothing pooled to outbid, the floor
+		{idle, true, 1},       // Nothing known, the floor
+	}
+	for i, tt := range tests {
+		suggestion := NewParallelTxPoolAPI(pool).SuggestTip(tt.contract)
+		if suggestion.Parallel != tt.parallel || suggestion.Tip.ToInt().Int64() != tt.tip {
+			t.Errorf("test %d: suggestion mismatch: have parallel %v tip %v, want %v tip %d (%s)", i, suggestion.Parallel, suggestion.Tip, tt.parallel, tt.tip, suggestion.Reason)
+		}
+	}
+	if stats := pool.SuggestTip(hotspot).Stats; stats.Conflicts != hotspotConflicts || len(stats.HotSlots) != 1 || stats.HotSlots[0] != (common.Hash{0x01}) {
+		t.Errorf("hotspot stats mismatch: %+v", stats)
+	}
+}
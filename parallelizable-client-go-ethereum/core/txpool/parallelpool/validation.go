@@ -0,0 +1,175 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// maxDependencies is the maximum number of dependencies, full and compressed,
+// a single parallel transaction may declare.
+const maxDependencies = 64
+
+var (
+	// ErrInvalidParallelType is returned if a transaction declares a parallel
+	// type the pool does not know about.
+	ErrInvalidParallelType = errors.New("invalid parallel type")
+
+	// ErrInconsistentDependencies is returned if the parallel type of a
+	// transaction contradicts its declared dependencies.
+	ErrInconsistentDependencies = errors.New("parallel type inconsistent with dependencies")
+
+	// ErrTooManyDependencies is returned if a transaction declares more
+	// dependencies than the pool is willing to track.
+	ErrTooManyDependencies = errors.New("too many dependencies")
+
+	// ErrDuplicateDependency is returned if a transaction declares the same
+	// dependency more than once.
+	ErrDuplicateDependency = errors.New("duplicate dependency")
+
+	// ErrLegacyTag is returned if a transaction is routed by a deprecated
+	// calldata tag while the pool runs in strict tagging mode.
+	ErrLegacyTag = errors.New("calldata tags are no longer accepted, set the ParallelType field instead")
+)
+
+// ValidationOptions define the parallel transaction specific rules checked on
+// top of the shared transaction pool validation.
+type ValidationOptions struct {
+	*txpool.ValidationOptions
+
+	MaxDependencies int // Maximum number of dependencies a transaction may declare
+}
+
+// ValidateTransaction checks whether a parallel transaction is valid according
+// to the consensus rules shared by all pools, and whether its parallel metadata
+// is well formed. It does not check state-dependent validity (balance, nonce),
+// nor whether the dependencies resolve against the pool contents.
+func ValidateTransaction(tx *types.Transaction, head *types.Header, signer types.Signer, opts *ValidationOptions) error {
+	// Reject parallel transactions until they are scheduled to activate
+	if !opts.Config.IsParallelTx(head.Time) {
+		return ErrParallelTxNotActive
+	}
+	if err := txpool.ValidateTransaction(tx, head, signer, opts.ValidationOptions); err != nil {
+		return err
+	}
+	// Ensure the parallel metadata is consistent
+	deps, hints := tx.Dependencies(), tx.DependencyHints()
+	switch tx.ParallelType() {
+	case types.ParallelTypeIndependent:
+		if len(deps) > 0 || len(hints) > 0 {
+			return fmt.Errorf("%w: independent transaction declares %d dependencies", ErrInconsistentDependencies, len(deps)+len(hints))
+		}
+	case types.ParallelTypeDependent:
+		if len(deps) == 0 && len(hints) == 0 {
+			return fmt.Errorf("%w: dependent transaction declares no dependencies", ErrInconsistentDependencies)
+		}
+	case types.ParallelTypeSequential:
+	default:
+		return fmt.Errorf("%w: %d", ErrInvalidParallelType, tx.ParallelType())
+	}
+	if len(deps)+len(hints) > opts.MaxDependencies {
+		return fmt.Errorf("%w: have %d, limit %d", ErrTooManyDependencies, len(deps)+len(hints), opts.MaxDependencies)
+	}
+	seen := make(map[common.Hash]struct{}, len(deps))
+	for _, dep := range deps {
+		if _, ok := seen[dep]; ok {
+			return fmt.Errorf("%w: %x", ErrDuplicateDependency, dep)
+		}
+		seen[dep] = struct{}{}
+	}
+	shorts := make(map[DependencyHint]struct{}, len(hints))
+	for _, hint := range hints {
+		if _, ok := shorts[hint]; ok {
+			return fmt.Errorf("%w: hint %x", ErrDuplicateDependency, hint)
+		}
+		shorts[hint] = struct{}{}
+	}
+	return nil
+}
+
+// validateTxBasics checks whether a transaction is valid according to the
+// consensus rules and the parallel metadata rules, without state access.
+func (p *ParallelPool) validateTxBasics(tx *types.Transaction, local bool) error {
+	opts := &ValidationOptions{
+		ValidationOptions: &txpool.ValidationOptions{
+			Config:  p.chainconfig,
+			Accept:  1 << types.ParallelTxType,
+			MaxSize: txMaxSize,
+			MinTip:  p.gasTip,
+		},
+		MaxDependencies: maxDependencies,
+	}
+	// Local transactions are accepted under our own minimal gas price
+	if local {
+		opts.MinTip = new(big.Int)
+	}
+	return ValidateTransaction(tx, p.currentHead, p.signer, opts)
+}
+
+// oversized reports whether a transaction exceeds the size limit of batched
+// transactions. It is still valid, but has to be handled sequentially.
+func (p *ParallelPool) oversized(tx *types.Transaction) bool {
+	return tx.Size() > p.config.MaxParallelTxSize
+}
+
+// validateTag rejects transactions routed by a deprecated calldata tag once the
+// head reached the configured strict tagging time.
+func (p *ParallelPool) validateTag(tx *types.Transaction) error {
+	if p.config.StrictTagsTime == nil || p.currentHead.Time < *p.config.StrictTagsTime {
+		return nil
+	}
+	if tag, legacy := TxTag(tx); legacy {
+		p.metrics.tagRejected.Mark(1)
+		return fmt.Errorf("%w: data prefixed with %s", ErrLegacyTag, tag)
+	}
+	return nil
+}
+
+// validateTx checks whether a transaction is valid according to the consensus
+// rules and adheres to the pool's state-dependent limits (nonce, balance).
+func (p *ParallelPool) validateTx(tx *types.Transaction, local bool) error {
+	if err := p.validateTxBasics(tx, local); err != nil {
+		return err
+	}
+	if err := p.validateTag(tx); err != nil {
+		return err
+	}
+	opts := &txpool.ValidationOptionsWithState{
+		State: p.currentState,
+
+		ExistingExpenditure: func(addr common.Address) *big.Int {
+			spent := new(big.Int)
+			for _, tx := range p.accountTxs(addr) {
+				spent.Add(spent, tx.Cost())
+			}
+			return spent
+		},
+		ExistingCost: func(addr common.Address, nonce uint64) *big.Int {
+			if tx := p.nonceTx(addr, nonce); tx != nil {
+				return tx.Cost()
+			}
+			return nil
+		},
+	}
+	return txpool.ValidateTransactionWithState(tx, p.signer, opts)
+}
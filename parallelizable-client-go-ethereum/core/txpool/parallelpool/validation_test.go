@@ -0,0 +1,220 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that parallel transactions go through the shared consensus checks and
+// that their parallel metadata is checked on top.
+func TestValidateTransaction(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	config := *params.MergedTestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	var (
+		signer = types.LatestSigner(&config)
+		head   = &types.Header{Number: big.NewInt(1), GasLimit: 30_000_000, Difficulty: common.Big0}
+		opts   = &ValidationOptions{
+			ValidationOptions: &txpool.ValidationOptions{
+				Config:  &config,
+				Accept:  1 << types.ParallelTxType,
+				MaxSize: txMaxSize,
+				MinTip:  common.Big1,
+			},
+			MaxDependencies: 2,
+		}
+	)
+	newTx := func(parallelType uint8, gas uint64, to *common.Address, data []byte, deps ...common.Hash) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Gas:          gas,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(params.InitialBaseFee),
+			To:           to,
+			Value:        common.Big0,
+			Data:         data,
+			ParallelType: parallelType,
+			Dependencies: deps,
+		})
+	}
+	recipient := common.Address{0x01}
+
+	tests := []struct {
+		tx   *types.Transaction
+		want error
+	}{
+		{newTx(types.ParallelTypeIndependent, params.TxGas, &recipient, nil), nil},
+		{newTx(types.ParallelTypeDependent, params.TxGas, &recipient, nil, common.Hash{0x01}), nil},
+		{newTx(types.ParallelTypeSequential, params.TxGas, &recipient, nil), nil},
+
+		// Shared consensus checks
+		{newTx(types.ParallelTypeIndependent, params.TxGas-1, &recipient, nil), core.ErrIntrinsicGas},
+		{newTx(types.ParallelTypeIndependent, head.GasLimit+1, &recipient, nil), txpool.ErrGasLimit},
+		{newTx(types.ParallelTypeIndependent, 10_000_000, nil, make([]byte, params.MaxInitCodeSize+1)), core.ErrMaxInitCodeSizeExceeded},
+
+		// Parallel metadata checks
+		{newTx(3, params.TxGas, &recipient, nil), ErrInvalidParallelType},
+		{newTx(types.ParallelTypeIndependent, params.TxGas, &recipient, nil, common.Hash{0x01}), ErrInconsistentDependencies},
+		{newTx(types.ParallelTypeDependent, params.TxGas, &recipient, nil), ErrInconsistentDependencies},
+		{newTx(types.ParallelTypeDependent, params.TxGas, &recipient, nil, common.Hash{0x01}, common.Hash{0x01}), ErrDuplicateDependency},
+		{newTx(types.ParallelTypeDependent, params.TxGas, &recipient, nil, common.Hash{0x01}, common.Hash{0x02}, common.Hash{0x03}), ErrTooManyDependencies},
+	}
+	for i, tt := range tests {
+		if err := ValidateTransaction(tt.tx, head, signer, opts); !errors.Is(err, tt.want) {
+			t.Errorf("test %d: error mismatch: have %v, want %v", i, err, tt.want)
+		}
+	}
+	// Nothing is accepted before parallel transactions activate
+	config.ParallelTxTime = nil
+	if err := ValidateTransaction(tests[0].tx, head, signer, opts); !errors.Is(err, ErrParallelTxNotActive) {
+		t.Errorf("inactive error mismatch: have %v, want %v", err, ErrParallelTxNotActive)
+	}
+}
+
+// Tests that transactions routed by a calldata tag are only rejected once the
+// head reached the strict tagging time, while typed ones remain accepted.
+func TestStrictTags(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	strict := uint64(1)
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry(), StrictTagsTime: &strict}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(nonce uint64, data string) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          100000,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			Data:         []byte(data),
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	// Legacy tags are still honored ahead of the strict tagging time
+	if err := pool.Add([]*types.Transaction{newTx(0, "PARALLEL\x01")}, false)[0]; err != nil {
+		t.Fatalf("legacy tagged transaction rejected ahead of strict mode: %v", err)
+	}
+	strict = 0
+	if err := pool.Add([]*types.Transaction{newTx(1, "SEQUENTIAL\x01")}, false)[0]; !errors.Is(err, ErrLegacyTag) {
+		t.Fatalf("legacy tag error mismatch: have %v, want %v", err, ErrLegacyTag)
+	}
+	if have := pool.metrics.tagRejected.Snapshot().Count(); have != 1 {
+		t.Errorf("rejected tag meter mismatch: have %d, want 1", have)
+	}
+	if err := pool.Add([]*types.Transaction{newTx(1, "\x01")}, false)[0]; err != nil {
+		t.Fatalf("typed transaction rejected in strict mode: %v", err)
+	}
+}
+
+// Tests that parallelizable transactions over the batched size limit are still
+// accepted, but handled sequentially instead of being batched.
+func TestOversizedParallelTx(t *testing.T) {
+	var (
+		small, _ = crypto.GenerateKey()
+		large, _ = crypto.GenerateKey()
+	)
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			crypto.PubkeyToAddress(small.PublicKey): {Balance: big.NewInt(params.Ether)},
+			crypto.PubkeyToAddress(large.PublicKey): {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := newTestPool(t, Config{MetricsRegistry: metrics.NewRegistry(), MaxParallelTxSize: 1024}, chain)
+	defer pool.Close()
+
+	signer := types.LatestSigner(&config)
+	newTx := func(key *ecdsa.PrivateKey, size int) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          100000,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			Data:         make([]byte, size),
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	for i, err := range pool.Add([]*types.Transaction{newTx(small, 100), newTx(large, 2048)}, false) {
+		if err != nil {
+			t.Fatalf("failed to add transaction %d: %v", i, err)
+		}
+	}
+	if txs := pool.parallelizableTxs[crypto.PubkeyToAddress(small.PublicKey)]; len(txs) != 1 {
+		t.Errorf("small transaction not batched")
+	}
+	if txs := pool.parallelizableTxs[crypto.PubkeyToAddress(large.PublicKey)]; len(txs) != 0 {
+		t.Errorf("oversized transaction batched")
+	}
+	if list := pool.pending[crypto.PubkeyToAddress(large.PublicKey)]; list == nil || list.Len() != 1 {
+		t.Errorf("oversized transaction not handled sequentially")
+	}
+	if have := pool.metrics.tagOversized.Snapshot().Count(); have != 1 {
+		t.Errorf("oversized meter mismatch: have %d, want 1", have)
+	}
+	// The overall size limit caps the configured one
+	if have := (Config{MaxParallelTxSize: 2 * txMaxSize}).sanitize().MaxParallelTxSize; have != txMaxSize {
+		t.Errorf("sanitized size limit mismatch: have %d, want %d", have, txMaxSize)
+	}
+}
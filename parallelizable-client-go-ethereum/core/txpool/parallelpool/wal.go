@@ -0,0 +1,226 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// batchWALEntry records an executed batch whose transactions were removed from
+// the pool, until the chain confirms or refutes their inclusion.
+type batchWALEntry struct {
+	BatchID uint64
+	Target  uint64 // Block number the batch was executed for
+	Hashes  []common.Hash
+	Txs     []*types.Transaction
+}
+
+// batchWAL is a write-ahead log of executed batches. Entries are appended and
+// synced before the executed transactions leave the pool, so that transactions
+// executed but never mined (e.g. due to a crash or a discarded block) can be
+// restored instead of being lost.
+type batchWAL struct {
+	path    string           // Filesystem path of the log
+	entries []*batchWALEntry // Entries not yet reconciled against the chain
+	writer  *os.File         // Output stream to append new entries to
+	mu      sync.Mutex
+}
+
+// newBatchWAL opens the write-ahead log at the given path, loading any entries
+// left over from a previous run. A torn entry at the end of the log, caused by
+// a crash mid-write, is dropped.
+func newBatchWAL(path string) (*batchWAL, error) {
+	wal := &batchWAL{path: path}
+
+	input, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return wal, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer input.Close()
+
+	stream := rlp.NewStream(input, 0)
+	for {
+		entry := new(batchWALEntry)
+		if err := stream.Decode(entry); err != nil {
+			if err == io.EOF {
+				return wal, nil
+			}
+			log.Warn("Dropped torn batch log entry", "path", path, "err", err)
+			break
+		}
+		wal.entries = append(wal.entries, entry)
+	}
+	// Regenerate the log without the torn entry, new entries would be unreadable
+	// behind it otherwise
+	return wal, wal.rewrite()
+}
+
+// append durably logs an executed batch.
+func (w *batchWAL) append(entry *batchWALEntry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writer == nil {
+		sink, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		w.writer = sink
+	}
+	if err := rlp.Encode(w.writer, entry); err != nil {
+		return err
+	}
+	if err := w.writer.Sync(); err != nil {
+		return err
+	}
+	w.entries = append(w.entries, entry)
+	return nil
+}
+
+// reconcile settles the entries targeting blocks up to the given head against
+// the chain, as seen through the given account nonces. Transactions whose nonce
+// was consumed were mined (or replaced) and are discarded, the rest are returned
+// for restoring into the pool. Settled entries are dropped from the log.
+func (w *batchWAL) reconcile(head uint64, signer types.Signer, nonce func(common.Address) uint64) ([]*types.Transaction, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var (
+		kept    []*batchWALEntry
+		restore []*types.Transaction
+	)
+	for _, entry := range w.entries {
+		if entry.Target > head {
+			kept = append(kept, entry)
+			continue
+		}
+		for _, tx := range entry.Txs {
+			from, err := types.Sender(signer, tx)
+			if err != nil {
+				continue
+			}
+			if tx.Nonce() >= nonce(from) {
+				restore = append(restore, tx)
+			}
+		}
+	}
+	if len(kept) == len(w.entries) {
+		return nil, nil
+	}
+	w.entries = kept
+	return restore, w.rewrite()
+}
+
+// rewrite regenerates the log from the unreconciled entries. The caller must
+// hold the lock.
+func (w *batchWAL) rewrite() error {
+	if w.writer != nil {
+		if err := w.writer.Close(); err != nil {
+			return err
+		}
+		w.writer = nil
+	}
+	replacement, err := os.OpenFile(w.path+".new", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	for _, entry := range w.entries {
+		if err = rlp.Encode(replacement, entry); err != nil {
+			replacement.Close()
+			return err
+		}
+	}
+	if err = replacement.Sync(); err != nil {
+		replacement.Close()
+		return err
+	}
+	replacement.Close()
+
+	return os.Rename(w.path+".new", w.path)
+}
+
+// close flushes and closes the log.
+func (w *batchWAL) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.writer == nil {
+		return nil
+	}
+	err := w.writer.Close()
+	w.writer = nil
+	return err
+}
+
+// logBatch records the executed members of a batch in the write-ahead log, if
+// one is configured.
+func (p *ParallelPool) logBatch(batch TxBatch, executed []common.Hash) error {
+	if p.wal == nil || len(executed) == 0 {
+		return nil
+	}
+	entry := &batchWALEntry{
+		BatchID: batch.BatchID,
+		Target:  p.chain.CurrentBlock().Number.Uint64() + 1,
+		Hashes:  executed,
+	}
+	members := make(map[common.Hash]*types.Transaction, len(batch.Transactions))
+	for _, tx := range batch.Transactions {
+		members[tx.Hash()] = tx
+	}
+	for _, hash := range executed {
+		entry.Txs = append(entry.Txs, members[hash])
+	}
+	return p.wal.append(entry)
+}
+
+// restoreBatches reconciles the write-ahead log up to the given head, adding
+// back the executed transactions that never made it into the chain. The caller
+// must hold the pool lock.
+func (p *ParallelPool) restoreBatches(head uint64) {
+	if p.wal == nil {
+		return
+	}
+	txs, err := p.wal.reconcile(head, p.signer, p.currentState.GetNonce)
+	if err != nil {
+		log.Warn("Failed to reconcile batch log", "err", err)
+	}
+	var restored int
+	for _, tx := range txs {
+		err := p.add(tx, false)
+		p.metrics.markOrigin(originJournal, err)
+		if err != nil {
+			log.Debug("Failed to restore executed transaction", "hash", tx.Hash(), "err", err)
+			continue
+		}
+		restored++
+	}
+	if len(txs) > 0 {
+		log.Info("Restored unmined executed transactions", "restored", restored, "dropped", len(txs)-restored)
+	}
+}
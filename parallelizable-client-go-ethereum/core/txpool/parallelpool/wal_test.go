@@ -0,0 +1,99 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that executed batches survive a restart of the log, including a torn
+// trailing entry, and that reconciliation restores only unmined transactions
+// of batches whose target block passed.
+func TestBatchWALReconcile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "batches.wal")
+	wal, err := newBatchWAL(path)
+	if err != nil {
+		t.Fatalf("failed to open log: %v", err)
+	}
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	var (
+		mined   = pricedTransaction(0, 1, key)
+		unmined = pricedTransaction(1, 1, key)
+		future  = pricedTransaction(2, 1, key)
+	)
+	for _, entry := range []*batchWALEntry{
+		{BatchID: 1, Target: 10, Hashes: []common.Hash{mined.Hash(), unmined.Hash()}, Txs: []*types.Transaction{mined, unmined}},
+		{BatchID: 2, Target: 12, Hashes: []common.Hash{future.Hash()}, Txs: []*types.Transaction{future}},
+	} {
+		if err := wal.append(entry); err != nil {
+			t.Fatalf("failed to append entry: %v", err)
+		}
+	}
+	wal.close()
+
+	// Simulate a crash in the middle of writing an entry
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	f.Write([]byte{0xf9, 0x01})
+	f.Close()
+
+	if wal, err = newBatchWAL(path); err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+	if len(wal.entries) != 2 {
+		t.Fatalf("entry count mismatch: have %d, want 2", len(wal.entries))
+	}
+	// Entries appended after recovering from the torn write must be readable
+	recovered := pricedTransaction(3, 1, key)
+	if err := wal.append(&batchWALEntry{BatchID: 3, Target: 13, Hashes: []common.Hash{recovered.Hash()}, Txs: []*types.Transaction{recovered}}); err != nil {
+		t.Fatalf("failed to append entry: %v", err)
+	}
+	wal.close()
+	if wal, err = newBatchWAL(path); err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+	if len(wal.entries) != 3 {
+		t.Fatalf("entry count mismatch after recovery: have %d, want 3", len(wal.entries))
+	}
+	nonce := func(addr common.Address) uint64 {
+		if addr == from {
+			return 1
+		}
+		return 0
+	}
+	restore, err := wal.reconcile(11, testSigner, nonce)
+	if err != nil {
+		t.Fatalf("failed to reconcile: %v", err)
+	}
+	if len(restore) != 1 || restore[0].Hash() != unmined.Hash() {
+		t.Fatalf("restored transactions mismatch: have %v, want [%x]", restore, unmined.Hash())
+	}
+	// Settled entries must be gone from disk, pending ones retained
+	if wal, err = newBatchWAL(path); err != nil {
+		t.Fatalf("failed to reopen log: %v", err)
+	}
+	if len(wal.entries) != 2 || wal.entries[0].BatchID != 2 || wal.entries[1].BatchID != 3 {
+		t.Fatalf("retained entries mismatch: have %d", len(wal.entries))
+	}
+}
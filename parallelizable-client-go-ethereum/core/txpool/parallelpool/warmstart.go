@@ -0,0 +1,62 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// warmStart completes the replay of the transactions carried over from the
+// previous run, re-validating them against the head state and rebuilding the
+// batches eagerly, so they are available before the first head event or any
+// fresh traffic. The batches are not executed, that is left to the block
+// producer as usual.
+func (p *ParallelPool) warmStart() {
+	start := time.Now()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.warming = false
+	if len(p.all) == 0 {
+		return
+	}
+	replayed := len(p.all)
+
+	// Drop the replayed transactions the head state invalidated since they were
+	// journaled, and promote the queued ones it made executable
+	p.demoteUnexecutables()
+	for addr := range p.queue {
+		p.dirty[addr] = struct{}{}
+	}
+	p.promoteExecutables()
+	p.prepareBatches()
+
+	p.batchMu.RLock()
+	batches := len(p.batchedTxs)
+	p.batchMu.RUnlock()
+
+	var number uint64
+	if p.currentHead != nil {
+		number = p.currentHead.Number.Uint64()
+	}
+	log.Info("Warm-started parallel transaction pool", "replayed", replayed, "dropped", replayed-len(p.all),
+		"batches", batches, "head", number, "elapsed", common.PrettyDuration(time.Since(start)))
+}
@@ -0,0 +1,104 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the transactions replayed from the journal are re-validated against
+// the head state and batched right away, without waiting for a head event.
+func TestWarmStart(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(&config)
+	newTx := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			Nonce:        nonce,
+			GasTipCap:    common.Big1,
+			GasFeeCap:    big.NewInt(2 * params.InitialBaseFee),
+			Gas:          params.TxGas,
+			To:           &common.Address{0xaa},
+			Value:        common.Big0,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	txs := []*types.Transaction{newTx(0), newTx(1), newTx(2)}
+
+	// Include the first transaction while the node was offline
+	_, blocks, _ := core.GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(txs[0])
+	})
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	if _, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	// Journal all the transactions as a previous run would have
+	path := filepath.Join(t.TempDir(), "parallel-transactions.rlp")
+	journal := newTxJournal(path)
+	if err := journal.rotate(map[common.Address]types.Transactions{from: txs}); err != nil {
+		t.Fatalf("failed to rotate journal: %v", err)
+	}
+	journal.close()
+
+	pool := newTestPool(t, Config{Journal: path}, chain)
+	defer pool.Close()
+
+	if pool.Has(txs[0].Hash()) {
+		t.Errorf("included transaction replayed from journal")
+	}
+	batched := make(map[common.Hash]struct{})
+	for _, batch := range pool.GetBatches() {
+		for _, tx := range batch.Transactions {
+			batched[tx.Hash()] = struct{}{}
+		}
+	}
+	for i, tx := range txs[1:] {
+		if _, ok := batched[tx.Hash()]; !ok {
+			t.Errorf("journaled tx %d not batched on startup", i+1)
+		}
+	}
+	if len(batched) != 2 {
+		t.Errorf("batched transaction count mismatch: have %d, want 2", len(batched))
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// MaxParallelism is the maximum number of workers of a worker pool.
+const MaxParallelism = 1024
+
+// ErrInvalidParallelism is returned if a worker pool is sized to zero workers or
+// beyond MaxParallelism.
+var ErrInvalidParallelism = errors.New("invalid parallelism")
+
+// DefaultParallelism returns the default size of worker pools, the number of
+// CPUs capped to MaxParallelism.
+func DefaultParallelism() int {
+	return min(runtime.NumCPU(), MaxParallelism)
+}
+
+// ExecutionPanic is the error a transaction execution fails with if it panicked,
+// carrying the recovered value and the stack of the panicking worker.
+type ExecutionPanic struct {
+	Tx    common.Hash // Transaction whose execution panicked
+	Value any         // Value recovered from the panic
+	Stack []byte      // Stack trace of the panicking worker
+}
+
+// Error implements error.
+func (e *ExecutionPanic) Error() string {
+	return fmt.Sprintf("execution of %x panicked: %v", e.Tx, e.Value)
+}
+
+// WorkerPool is a persistent set of goroutines executing the transactions of
+// batch executions, simulations and estimates. All work is handed over through
+// a single queue that idle workers pull from, so workers done with the members
+// of one batch steal the remaining members of others instead of idling, while
+// the number of concurrent executions across all batches stays bounded by the
+// pool size. The pool can be resized while running.
+//
+// Tasks must not submit further tasks and wait for them, as they might wait for
+// a worker forever. Transaction executions should be wrapped in Guard, so that a
+// panicking one fails on its own instead of taking down the node.
+type WorkerPool struct {
+	tasks  chan func()
+	shrink chan struct{} // Retirement tokens of the workers over the pool size
+	quit   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	size    int
+	closed  bool
+	onPanic func(*types.Transaction, *ExecutionPanic) // Handler of recovered execution panics (optional)
+}
+
+// NewWorkerPool creates a worker pool of the given size, out of range sizes
+// being clamped.
+func NewWorkerPool(size int) *WorkerPool {
+	w := &WorkerPool{
+		tasks:  make(chan func()),
+		shrink: make(chan struct{}, MaxParallelism),
+		quit:   make(chan struct{}),
+	}
+	w.SetSize(min(max(size, 1), MaxParallelism))
+	return w
+}
+
+// loop executes tasks until the worker is retired or the pool closed.
+func (w *WorkerPool) loop() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case task := <-w.tasks:
+			w.run(task)
+		case <-w.shrink:
+			return
+		case <-w.quit:
+			return
+		}
+	}
+}
+
+// run executes a task, recovering a panic it did not guard against itself so
+// that the worker survives. The task's results are lost, so callers must guard
+// the panics they need to account for.
+func (w *WorkerPool) run(task func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error("Parallel worker task panicked", "err", r, "stack", string(debug.Stack()))
+		}
+	}()
+	task()
+}
+
+// Guard executes a transaction, recovering a panic of the execution into an
+// *ExecutionPanic error which is handed to the panic handler of the pool, if
+// any. It is meant to be called from within tasks, failing just the panicking
+// transaction.
+func (w *WorkerPool) Guard(tx *types.Transaction, exec func() error) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		failure := &ExecutionPanic{Tx: tx.Hash(), Value: r, Stack: debug.Stack()}
+		log.Error("Parallel transaction execution panicked", "hash", failure.Tx, "err", r, "stack", string(failure.Stack))
+
+		if w != nil {
+			w.mu.Lock()
+			handler := w.onPanic
+			w.mu.Unlock()
+
+			if handler != nil {
+				handler(tx, failure)
+			}
+		}
+		err = failure
+	}()
+	return exec()
+}
+
+// SetPanicHandler sets the function notified of the transaction executions whose
+// panics were recovered by Guard.
+func (w *WorkerPool) SetPanicHandler(handler func(*types.Transaction, *ExecutionPanic)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.onPanic = handler
+}
+
+// Go runs a task on the next idle worker, blocking until one picks it up. Tasks
+// of a nil or closed pool are run on a goroutine of their own instead, so that
+// callers awaiting them never hang.
+func (w *WorkerPool) Go(task func()) {
+	if w == nil {
+		go task()
+		return
+	}
+	select {
+	case w.tasks <- task:
+	case <-w.quit:
+		go task()
+	}
+}
+
+// Size returns the number of workers of the pool.
+func (w *WorkerPool) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.size
+}
+
+// SetSize resizes the pool to the given number of workers. Surplus workers are
+// retired once done with their current task.
+func (w *WorkerPool) SetSize(size int) error {
+	if size <= 0 || size > MaxParallelism {
+		return fmt.Errorf("%w: %d, limit %d", ErrInvalidParallelism, size, MaxParallelism)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+	for ; w.size < size; w.size++ {
+		select {
+		case <-w.shrink:
+			// Revoke a pending retirement rather than starting a new worker
+		default:
+			w.wg.Add(1)
+			go w.loop()
+		}
+	}
+	for ; w.size > size; w.size-- {
+		w.shrink <- struct{}{}
+	}
+	return nil
+}
+
+// Close retires all workers, waiting for them to finish their current tasks.
+func (w *WorkerPool) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	close(w.quit)
+	w.mu.Unlock()
+
+	w.wg.Wait()
+}
@@ -0,0 +1,167 @@
+// Copyright 2023 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelpool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Tests that worker pools bound the number of concurrently running tasks to
+// their size, follow resizes and finish the tasks in flight when closed.
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(2)
+
+	// run submits tasks blocking until released, reporting the peak number of
+	// them running at once
+	run := func(tasks int) int32 {
+		var (
+			running, peak atomic.Int32
+			release       = make(chan struct{})
+			wg            sync.WaitGroup
+		)
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			close(release)
+		}()
+		for i := 0; i < tasks; i++ {
+			wg.Add(1)
+			pool.Go(func() {
+				defer wg.Done()
+
+				n := running.Add(1)
+				for {
+					if p := peak.Load(); n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				<-release
+				running.Add(-1)
+			})
+		}
+		wg.Wait()
+		return peak.Load()
+	}
+	if have := run(8); have != 2 {
+		t.Errorf("peak concurrency mismatch: have %d, want 2", have)
+	}
+	if err := pool.SetSize(4); err != nil {
+		t.Fatalf("failed to grow pool: %v", err)
+	}
+	if have := run(8); have != 4 {
+		t.Errorf("peak concurrency after growing mismatch: have %d, want 4", have)
+	}
+	if err := pool.SetSize(1); err != nil {
+		t.Fatalf("failed to shrink pool: %v", err)
+	}
+	if have := run(4); have != 1 {
+		t.Errorf("peak concurrency after shrinking mismatch: have %d, want 1", have)
+	}
+	if err := pool.SetSize(0); !errors.Is(err, ErrInvalidParallelism) {
+		t.Errorf("zero size error mismatch: have %v, want %v", err, ErrInvalidParallelism)
+	}
+	if err := pool.SetSize(MaxParallelism + 1); !errors.Is(err, ErrInvalidParallelism) {
+		t.Errorf("oversized error mismatch: have %v, want %v", err, ErrInvalidParallelism)
+	}
+	// Closing waits for the task in flight, later tasks still run
+	var finished atomic.Bool
+	pool.Go(func() {
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+	})
+	pool.Close()
+	if !finished.Load() {
+		t.Errorf("pool closed before the task in flight finished")
+	}
+	done := make(chan struct{})
+	pool.Go(func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Errorf("task submitted to closed pool never ran")
+	}
+}
+
+// Tests that panicking tasks don't take their worker down, and that guarded
+// executions fail with the panic, notifying the panic handler.
+func TestWorkerPanic(t *testing.T) {
+	pool := NewWorkerPool(1)
+	defer pool.Close()
+
+	var handled []*ExecutionPanic
+	pool.SetPanicHandler(func(tx *types.Transaction, failure *ExecutionPanic) {
+		handled = append(handled, failure)
+	})
+	// An unguarded panic is recovered by the worker, which keeps serving tasks
+	pool.Go(func() { panic("unguarded") })
+
+	key, _ := crypto.GenerateKey()
+	var (
+		tx   = pricedTransaction(0, 1, key)
+		errc = make(chan error, 1)
+	)
+	pool.Go(func() {
+		errc <- pool.Guard(tx, func() error { panic("guarded") })
+	})
+	var failure *ExecutionPanic
+	select {
+	case err := <-errc:
+		if !errors.As(err, &failure) {
+			t.Fatalf("guarded error mismatch: have %v, want execution panic", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("worker lost to unguarded panic")
+	}
+	if failure.Tx != tx.Hash() || failure.Value != "guarded" || len(failure.Stack) == 0 {
+		t.Errorf("panic context mismatch: %+v", failure)
+	}
+	if len(handled) != 1 || handled[0] != failure {
+		t.Errorf("panic handler notified %d times, want once", len(handled))
+	}
+	// Guard passes the errors of executions not panicking through
+	fail := errors.New("failed")
+	if err := pool.Guard(tx, func() error { return fail }); err != fail {
+		t.Errorf("execution error mismatch: have %v, want %v", err, fail)
+	}
+	if len(handled) != 1 {
+		t.Errorf("panic handler notified of a failure")
+	}
+}
+
+// Tests that the parallelism of a pool is resized over RPC, zero restoring the
+// default.
+func TestSetParallelism(t *testing.T) {
+	pool := &ParallelPool{workers: NewWorkerPool(1)}
+	defer pool.workers.Close()
+
+	api := NewParallelTxPoolAPI(pool)
+	if err := api.SetParallelism(3); err != nil || pool.Parallelism() != 3 {
+		t.Errorf("parallelism mismatch: have %d (%v), want 3", pool.Parallelism(), err)
+	}
+	if err := api.SetParallelism(0); err != nil || pool.Parallelism() != DefaultParallelism() {
+		t.Errorf("default parallelism mismatch: have %d (%v), want %d", pool.Parallelism(), err, DefaultParallelism())
+	}
+	if err := api.SetParallelism(-1); !errors.Is(err, ErrInvalidParallelism) {
+		t.Errorf("negative parallelism error mismatch: have %v, want %v", err, ErrInvalidParallelism)
+	}
+}
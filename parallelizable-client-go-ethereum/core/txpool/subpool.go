@@ -141,6 +141,10 @@ type SubPool interface {
 	// reduce allocations and load on downstream subsystems.
 	Pending(filter PendingFilter) map[common.Address][]*LazyTransaction
 
+	// PendingFrom retrieves the currently processable transactions of the given
+	// accounts, filtered like Pending, without gathering those of all accounts.
+	PendingFrom(addrs []common.Address, filter PendingFilter) map[common.Address][]*LazyTransaction
+
 	// SubscribeTransactions subscribes to new transaction events. The subscriber
 	// can decide whether to receive notifications only for newly seen transactions
 	// or also for reorged out ones.
@@ -19,6 +19,7 @@ package txpool
 import (
 	"errors"
 	"fmt"
+	"math"
 	"math/big"
 	"sync"
 
@@ -380,7 +381,19 @@ func (p *TxPool) Pending(filter PendingFilter) map[common.Address][]*LazyTransac
 	txs := make(map[common.Address][]*LazyTransaction)
 	for _, subpool := range p.subpools {
 		for addr, set := range subpool.Pending(filter) {
-			txs[addr] = set
+			txs[addr] = mergeByNonce(txs[addr], set, lazyNonce)
+		}
+	}
+	return txs
+}
+
+// PendingFrom retrieves the currently processable transactions of the given
+// accounts, grouped by origin account and sorted by nonce, filtered like Pending.
+func (p *TxPool) PendingFrom(addrs []common.Address, filter PendingFilter) map[common.Address][]*LazyTransaction {
+	txs := make(map[common.Address][]*LazyTransaction)
+	for _, subpool := range p.subpools {
+		for addr, set := range subpool.PendingFrom(addrs, filter) {
+			txs[addr] = mergeByNonce(txs[addr], set, lazyNonce)
 		}
 	}
 	return txs
@@ -435,10 +448,10 @@ func (p *TxPool) Content() (map[common.Address][]*types.Transaction, map[common.
 		run, block := subpool.Content()
 
 		for addr, txs := range run {
-			runnable[addr] = txs
+			runnable[addr] = mergeByNonce(runnable[addr], txs, (*types.Transaction).Nonce)
 		}
 		for addr, txs := range block {
-			blocked[addr] = txs
+			blocked[addr] = mergeByNonce(blocked[addr], txs, (*types.Transaction).Nonce)
 		}
 	}
 	return runnable, blocked
@@ -447,13 +460,54 @@ func (p *TxPool) Content() (map[common.Address][]*types.Transaction, map[common.
 // ContentFrom retrieves the data content of the transaction pool, returning the
 // pending as well as queued transactions of this address, grouped by nonce.
 func (p *TxPool) ContentFrom(addr common.Address) ([]*types.Transaction, []*types.Transaction) {
+	var (
+		runnable = []*types.Transaction{}
+		blocked  = []*types.Transaction{}
+	)
 	for _, subpool := range p.subpools {
 		run, block := subpool.ContentFrom(addr)
-		if len(run) != 0 || len(block) != 0 {
-			return run, block
+
+		runnable = mergeByNonce(runnable, run, (*types.Transaction).Nonce)
+		blocked = mergeByNonce(blocked, block, (*types.Transaction).Nonce)
+	}
+	return runnable, blocked
+}
+
+// mergeByNonce merges the nonce sorted transactions of an account pooled by two
+// subpools. Accounts are unique to subpools, except for the parallel pool in its
+// legacy mode which holds no reservations, so the sets of an account shared with
+// it are interleaved by nonce, the earlier subpool winning nonce collisions.
+func mergeByNonce[T any](have, add []T, nonce func(T) uint64) []T {
+	if len(have) == 0 {
+		return add
+	}
+	if len(add) == 0 {
+		return have
+	}
+	merged := make([]T, 0, len(have)+len(add))
+	for len(have) > 0 && len(add) > 0 {
+		switch a, b := nonce(have[0]), nonce(add[0]); {
+		case a < b:
+			merged, have = append(merged, have[0]), have[1:]
+		case a > b:
+			merged, add = append(merged, add[0]), add[1:]
+		default:
+			merged, have, add = append(merged, have[0]), have[1:], add[1:]
 		}
 	}
-	return []*types.Transaction{}, []*types.Transaction{}
+	return append(append(merged, have...), add...)
+}
+
+// lazyNonce returns the nonce of a lazy transaction, resolving it if needed. A
+// transaction dropped meanwhile sorts last.
+func lazyNonce(tx *LazyTransaction) uint64 {
+	if tx.Tx != nil {
+		return tx.Tx.Nonce()
+	}
+	if resolved := tx.Resolve(); resolved != nil {
+		return resolved.Nonce()
+	}
+	return math.MaxUint64
 }
 
 // Status returns the known status (unknown/pending/queued) of a transaction
@@ -74,7 +74,7 @@ func ValidateTransaction(tx *types.Transaction, head *types.Header, signer types
 	if !rules.IsBerlin && tx.Type() != types.LegacyTxType {
 		return fmt.Errorf("%w: type %d rejected, pool not yet in Berlin", core.ErrTxTypeNotSupported, tx.Type())
 	}
-	if !rules.IsLondon && tx.Type() == types.DynamicFeeTxType {
+	if !rules.IsLondon && (tx.Type() == types.DynamicFeeTxType || tx.Type() == types.ParallelTxType) {
 		return fmt.Errorf("%w: type %d rejected, pool not yet in London", core.ErrTxTypeNotSupported, tx.Type())
 	}
 	if !rules.IsCancun && tx.Type() == types.BlobTxType {
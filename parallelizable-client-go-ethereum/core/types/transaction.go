@@ -49,6 +49,7 @@ const (
 	DynamicFeeTxType = 0x02
 	BlobTxType       = 0x03
 	SetCodeTxType    = 0x04
+	ParallelTxType   = 0x05
 )
 
 // Transaction is an Ethereum transaction.
@@ -208,6 +209,8 @@ func (tx *Transaction) decodeTyped(b []byte) (TxData, error) {
 		inner = new(BlobTx)
 	case SetCodeTxType:
 		inner = new(SetCodeTx)
+	case ParallelTxType:
+		inner = new(ParallelTx)
 	default:
 		return nil, ErrTxTypeNotSupported
 	}
@@ -55,6 +55,9 @@ func MakeSigner(config *params.ChainConfig, blockNumber *big.Int, blockTime uint
 	default:
 		signer = FrontierSigner{}
 	}
+	if config.IsLondon(blockNumber) && config.IsParallelTx(blockTime) {
+		signer = NewParallelSigner(signer)
+	}
 	return signer
 }
 
@@ -82,6 +85,9 @@ func LatestSigner(config *params.ChainConfig) Signer {
 		default:
 			signer = HomesteadSigner{}
 		}
+		if config.LondonBlock != nil && config.ParallelTxTime != nil {
+			signer = NewParallelSigner(signer)
+		}
 	} else {
 		signer = HomesteadSigner{}
 	}
@@ -98,7 +104,7 @@ func LatestSigner(config *params.ChainConfig) Signer {
 func LatestSignerForChainID(chainID *big.Int) Signer {
 	var signer Signer
 	if chainID != nil {
-		signer = NewPragueSigner(chainID)
+		signer = NewParallelSigner(NewPragueSigner(chainID))
 	} else {
 		signer = HomesteadSigner{}
 	}
@@ -178,6 +184,63 @@ type Signer interface {
 	Equal(Signer) bool
 }
 
+// parallelSigner extends the signer of the active fork with parallel
+// transactions, which activate on their own schedule rather than with a fork.
+type parallelSigner struct{ Signer }
+
+// NewParallelSigner returns a signer that accepts parallel transactions with
+// signed dependencies on top of the transactions accepted by the given signer,
+// which must be a London or later one.
+func NewParallelSigner(signer Signer) Signer {
+	if _, ok := signer.(parallelSigner); ok {
+		return signer
+	}
+	return parallelSigner{signer}
+}
+
+func (s parallelSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != ParallelTxType {
+		return s.Signer.Sender(tx)
+	}
+	V, R, S := tx.RawSignatureValues()
+	// Parallel txs are defined to use 0 and 1 as their recovery
+	// id, add 27 to become equivalent to unprotected Homestead signatures.
+	V = new(big.Int).Add(V, big.NewInt(27))
+	if tx.ChainId().Cmp(s.ChainID()) != 0 {
+		return common.Address{}, fmt.Errorf("%w: have %d want %d", ErrInvalidChainId, tx.ChainId(), s.ChainID())
+	}
+	return recoverPlain(s.Hash(tx), R, S, V, true)
+}
+
+func (s parallelSigner) Equal(s2 Signer) bool {
+	x, ok := s2.(parallelSigner)
+	return ok && x.Signer.Equal(s.Signer)
+}
+
+func (s parallelSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	txdata, ok := tx.inner.(*ParallelTx)
+	if !ok {
+		return s.Signer.SignatureValues(tx, sig)
+	}
+	// Check that chain ID of tx matches the signer. We also accept ID zero here,
+	// because it indicates that the chain ID was not specified in the tx.
+	if txdata.ChainID.Sign() != 0 && txdata.ChainID.Cmp(s.ChainID()) != 0 {
+		return nil, nil, nil, fmt.Errorf("%w: have %d want %d", ErrInvalidChainId, txdata.ChainID, s.ChainID())
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender.
+// It does not uniquely identify the transaction.
+func (s parallelSigner) Hash(tx *Transaction) common.Hash {
+	if ptx, ok := tx.inner.(*ParallelTx); ok {
+		return ptx.sigHash(s.ChainID())
+	}
+	return s.Signer.Hash(tx)
+}
+
 type pragueSigner struct{ cancunSigner }
 
 // NewPragueSigner returns a signer that accepts
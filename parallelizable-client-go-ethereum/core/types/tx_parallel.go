@@ -0,0 +1,241 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+const (
+	// ParallelTypeIndependent marks a transaction that can be executed independently.
+	ParallelTypeIndependent = 0x00
+
+	// ParallelTypeDependent marks a transaction that depends on other transactions.
+	ParallelTypeDependent = 0x01
+
+	// ParallelTypeSequential marks a transaction opting out of parallel execution.
+	ParallelTypeSequential = 0x02
+
+	// DependencyHintLength is the number of leading hash bytes carried by a
+	// compressed dependency hint.
+	DependencyHintLength = 8
+)
+
+// DependencyHint is a compressed dependency reference consisting of the first
+// DependencyHintLength bytes of the referenced transaction hash. Hints are
+// resolved against the pool contents; ambiguous hints must be resubmitted as
+// full hashes.
+type DependencyHint [DependencyHintLength]byte
+
+// ShortHash returns the dependency hint referencing the given transaction hash.
+func ShortHash(hash common.Hash) DependencyHint {
+	var hint DependencyHint
+	copy(hint[:], hash[:DependencyHintLength])
+	return hint
+}
+
+// Matches returns whether the hint is a prefix of the given transaction hash.
+func (h DependencyHint) Matches(hash common.Hash) bool {
+	return bytes.Equal(h[:], hash[:DependencyHintLength])
+}
+
+// MarshalText encodes the hint as hex.
+func (h DependencyHint) MarshalText() ([]byte, error) {
+	return hexutil.Bytes(h[:]).MarshalText()
+}
+
+// UnmarshalText parses a hint in hex syntax.
+func (h *DependencyHint) UnmarshalText(input []byte) error {
+	return hexutil.UnmarshalFixedText("DependencyHint", input, h[:])
+}
+
+// ParallelTx is a dynamic fee transaction declaring the pooled transactions it
+// depends on, so that it can be scheduled for parallel execution. Dependencies
+// are part of the signed payload and cannot be altered in transit.
+type ParallelTx struct {
+	ChainID         *big.Int
+	Nonce           uint64
+	GasTipCap       *big.Int // a.k.a. maxPriorityFeePerGas
+	GasFeeCap       *big.Int // a.k.a. maxFeePerGas
+	Gas             uint64
+	To              *common.Address `rlp:"nil"` // nil means contract creation
+	Value           *big.Int
+	Data            []byte
+	AccessList      AccessList
+	ParallelType    uint8
+	Dependencies    []common.Hash
+	DependencyHints []DependencyHint // compressed dependencies, resolved by the pool
+	BatchID         uint64
+
+	// Signature values
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *ParallelTx) copy() TxData {
+	cpy := &ParallelTx{
+		Nonce:        tx.Nonce,
+		To:           copyAddressPtr(tx.To),
+		Data:         common.CopyBytes(tx.Data),
+		Gas:          tx.Gas,
+		ParallelType: tx.ParallelType,
+		BatchID:      tx.BatchID,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasTipCap:  new(big.Int),
+		GasFeeCap:  new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	if tx.Dependencies != nil {
+		cpy.Dependencies = make([]common.Hash, len(tx.Dependencies))
+		copy(cpy.Dependencies, tx.Dependencies)
+	}
+	if tx.DependencyHints != nil {
+		cpy.DependencyHints = make([]DependencyHint, len(tx.DependencyHints))
+		copy(cpy.DependencyHints, tx.DependencyHints)
+	}
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *ParallelTx) txType() byte           { return ParallelTxType }
+func (tx *ParallelTx) chainID() *big.Int      { return tx.ChainID }
+func (tx *ParallelTx) accessList() AccessList { return tx.AccessList }
+func (tx *ParallelTx) data() []byte           { return tx.Data }
+func (tx *ParallelTx) gas() uint64            { return tx.Gas }
+func (tx *ParallelTx) gasFeeCap() *big.Int    { return tx.GasFeeCap }
+func (tx *ParallelTx) gasTipCap() *big.Int    { return tx.GasTipCap }
+func (tx *ParallelTx) gasPrice() *big.Int     { return tx.GasFeeCap }
+func (tx *ParallelTx) value() *big.Int        { return tx.Value }
+func (tx *ParallelTx) nonce() uint64          { return tx.Nonce }
+func (tx *ParallelTx) to() *common.Address    { return tx.To }
+
+func (tx *ParallelTx) effectiveGasPrice(dst *big.Int, baseFee *big.Int) *big.Int {
+	if baseFee == nil {
+		return dst.Set(tx.GasFeeCap)
+	}
+	tip := dst.Sub(tx.GasFeeCap, baseFee)
+	if tip.Cmp(tx.GasTipCap) > 0 {
+		tip.Set(tx.GasTipCap)
+	}
+	return tip.Add(tip, baseFee)
+}
+
+func (tx *ParallelTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *ParallelTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+func (tx *ParallelTx) encode(b *bytes.Buffer) error {
+	return rlp.Encode(b, tx)
+}
+
+func (tx *ParallelTx) decode(input []byte) error {
+	return rlp.DecodeBytes(input, tx)
+}
+
+// sigHash returns the hash to be signed for the transaction on the given chain.
+func (tx *ParallelTx) sigHash(chainID *big.Int) common.Hash {
+	return prefixedRlpHash(
+		ParallelTxType,
+		[]interface{}{
+			chainID,
+			tx.Nonce,
+			tx.GasTipCap,
+			tx.GasFeeCap,
+			tx.Gas,
+			tx.To,
+			tx.Value,
+			tx.Data,
+			tx.AccessList,
+			tx.ParallelType,
+			tx.Dependencies,
+			tx.DependencyHints,
+			tx.BatchID,
+		})
+}
+
+// ParallelType returns the parallel type of a parallel transaction, zero otherwise.
+func (tx *Transaction) ParallelType() uint8 {
+	if ptx, ok := tx.inner.(*ParallelTx); ok {
+		return ptx.ParallelType
+	}
+	return 0
+}
+
+// Dependencies returns the transaction hashes a parallel transaction depends
+// on, nil otherwise.
+func (tx *Transaction) Dependencies() []common.Hash {
+	if ptx, ok := tx.inner.(*ParallelTx); ok {
+		return ptx.Dependencies
+	}
+	return nil
+}
+
+// DependencyHints returns the compressed dependencies of a parallel
+// transaction, nil otherwise.
+func (tx *Transaction) DependencyHints() []DependencyHint {
+	if ptx, ok := tx.inner.(*ParallelTx); ok {
+		return ptx.DependencyHints
+	}
+	return nil
+}
+
+// BatchID returns the batch a parallel transaction asks to be executed in,
+// zero otherwise.
+func (tx *Transaction) BatchID() uint64 {
+	if ptx, ok := tx.inner.(*ParallelTx); ok {
+		return ptx.BatchID
+	}
+	return 0
+}
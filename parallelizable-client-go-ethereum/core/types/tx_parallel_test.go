@@ -0,0 +1,182 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that parallel transactions survive the typed envelope and JSON round
+// trips with their dependencies intact and the sender recoverable.
+func TestParallelTxCoding(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	var (
+		signer    = NewParallelSigner(NewLondonSigner(common.Big1))
+		recipient = common.HexToAddress("095e7baea6a6c7c4c2dfeb977efac326af552d87")
+		dep       = common.HexToHash("0xdeadbeef")
+	)
+	for i, txdata := range []*ParallelTx{
+		{ // independent transaction without dependencies
+			ChainID:   big.NewInt(1),
+			Nonce:     1,
+			To:        &recipient,
+			Gas:       21000,
+			GasTipCap: big.NewInt(1),
+			GasFeeCap: big.NewInt(10),
+			Value:     big.NewInt(1),
+		},
+		{ // dependent contract creation with full and compressed dependencies
+			ChainID:         big.NewInt(1),
+			Nonce:           2,
+			Gas:             123457,
+			GasTipCap:       big.NewInt(1),
+			GasFeeCap:       big.NewInt(10),
+			Value:           big.NewInt(0),
+			Data:            []byte("abcdef"),
+			AccessList:      AccessList{{Address: recipient, StorageKeys: []common.Hash{{0}}}},
+			ParallelType:    ParallelTypeDependent,
+			Dependencies:    []common.Hash{dep},
+			DependencyHints: []DependencyHint{ShortHash(common.Hash{0x01})},
+			BatchID:         3,
+		},
+	} {
+		tx, err := SignNewTx(key, signer, txdata)
+		if err != nil {
+			t.Fatalf("tx %d: failed to sign: %v", i, err)
+		}
+		for name, codec := range map[string]func(*Transaction) (*Transaction, error){
+			"binary": encodeDecodeBinary,
+			"json":   encodeDecodeJSON,
+		} {
+			parsed, err := codec(tx)
+			if err != nil {
+				t.Fatalf("tx %d: %s round trip failed: %v", i, name, err)
+			}
+			if err := assertEqual(parsed, tx); err != nil {
+				t.Fatalf("tx %d: %s: %v", i, name, err)
+			}
+			if parsed.Type() != ParallelTxType {
+				t.Fatalf("tx %d: %s: type mismatch: have %d, want %d", i, name, parsed.Type(), ParallelTxType)
+			}
+			// Empty lists may decode as nil, compare the contents only
+			if len(tx.Dependencies()) > 0 && !reflect.DeepEqual(parsed.Dependencies(), tx.Dependencies()) {
+				t.Fatalf("tx %d: %s: dependencies mismatch: have %v, want %v", i, name, parsed.Dependencies(), tx.Dependencies())
+			}
+			if len(tx.DependencyHints()) > 0 && !reflect.DeepEqual(parsed.DependencyHints(), tx.DependencyHints()) {
+				t.Fatalf("tx %d: %s: dependency hints mismatch: have %v, want %v", i, name, parsed.DependencyHints(), tx.DependencyHints())
+			}
+			if len(parsed.Dependencies()) != len(tx.Dependencies()) || len(parsed.DependencyHints()) != len(tx.DependencyHints()) {
+				t.Fatalf("tx %d: %s: dependency count mismatch", i, name)
+			}
+			if parsed.ParallelType() != tx.ParallelType() || parsed.BatchID() != tx.BatchID() {
+				t.Fatalf("tx %d: %s: parallel fields mismatch", i, name)
+			}
+			from, err := Sender(signer, parsed)
+			if err != nil {
+				t.Fatalf("tx %d: %s: failed to recover sender: %v", i, name, err)
+			}
+			if from != crypto.PubkeyToAddress(key.PublicKey) {
+				t.Fatalf("tx %d: %s: sender mismatch: have %x", i, name, from)
+			}
+		}
+	}
+}
+
+// Tests that the dependencies are covered by the signature, so altering them
+// changes the recovered sender.
+func TestParallelTxSignedDependencies(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	signer := LatestSignerForChainID(common.Big1)
+
+	tx := MustSignNewTx(key, signer, &ParallelTx{
+		ChainID:      big.NewInt(1),
+		Gas:          21000,
+		GasTipCap:    big.NewInt(1),
+		GasFeeCap:    big.NewInt(10),
+		Value:        big.NewInt(0),
+		ParallelType: ParallelTypeDependent,
+		Dependencies: []common.Hash{{0x01}},
+	})
+	inner := tx.inner.copy().(*ParallelTx)
+	inner.Dependencies = []common.Hash{{0x02}}
+
+	from, err := Sender(signer, NewTx(inner))
+	if err == nil && from == crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("altered dependencies still recover the original sender")
+	}
+	// Signers without parallel transactions don't know about them
+	if _, err := Sender(NewEIP2930Signer(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("pre-London signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if _, err := Sender(NewPragueSigner(common.Big1), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("non-parallel signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+// Tests that the signers of a chain only accept parallel transactions once they
+// are scheduled to activate.
+func TestParallelTxSignerActivation(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+
+	activation := uint64(100)
+	config := *params.TestChainConfig
+	config.ParallelTxTime = &activation
+
+	tx := MustSignNewTx(key, LatestSigner(&config), &ParallelTx{
+		ChainID:   config.ChainID,
+		Gas:       21000,
+		GasTipCap: big.NewInt(1),
+		GasFeeCap: big.NewInt(10),
+		Value:     big.NewInt(0),
+	})
+	if _, err := Sender(MakeSigner(&config, common.Big1, 99), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("pre-activation signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+	if from, err := Sender(MakeSigner(&config, common.Big1, 100), tx); err != nil || from != crypto.PubkeyToAddress(key.PublicKey) {
+		t.Fatalf("post-activation sender mismatch: have %x, %v", from, err)
+	}
+	// Unscheduled parallel transactions are not supported at all
+	if _, err := Sender(LatestSigner(params.TestChainConfig), tx); err != ErrTxTypeNotSupported {
+		t.Fatalf("unscheduled signer error mismatch: have %v, want %v", err, ErrTxTypeNotSupported)
+	}
+}
+
+// Tests that dependency hints match exactly the hashes they prefix.
+func TestDependencyHintMatches(t *testing.T) {
+	hash := common.HexToHash("0x0102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f20")
+
+	hint := ShortHash(hash)
+	if !hint.Matches(hash) {
+		t.Fatalf("hint does not match its own hash")
+	}
+	other := hash
+	other[DependencyHintLength] ^= 0xff
+	if !hint.Matches(other) {
+		t.Errorf("hint does not match a hash differing past its length")
+	}
+	other = hash
+	other[DependencyHintLength-1] ^= 0xff
+	if hint.Matches(other) {
+		t.Errorf("hint matches a hash differing within its length")
+	}
+}
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -272,6 +273,17 @@ func (b *EthAPIBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscri
 }
 
 func (b *EthAPIBackend) SendTx(ctx context.Context, signedTx *types.Transaction) error {
+	// Parallel transactions are added to the parallel pool directly, which tracks
+	// and re-announces its local transactions itself
+	if signedTx.Type() == parallelpool.ParallelTxType {
+		if b.eth.parallelPool == nil {
+			return types.ErrTxTypeNotSupported
+		}
+		if b.eth.txPool.Has(signedTx.Hash()) {
+			return txpool.ErrAlreadyKnown
+		}
+		return b.eth.parallelPool.AddLocal(signedTx)
+	}
 	if locals := b.eth.localTxTracker; locals != nil {
 		locals.Track(signedTx)
 	}
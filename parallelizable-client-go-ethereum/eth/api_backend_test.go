@@ -0,0 +1,99 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/ethconfig"
+	"github.com/ethereum/go-ethereum/internal/ethapi"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that txpool_content and txpool_contentFrom list the transactions of the
+// parallel subpool alongside the regular ones, flagging only the former.
+func TestTxPoolContentParallel(t *testing.T) {
+	var (
+		parallelKey, _ = crypto.GenerateKey()
+		legacyKey, _   = crypto.GenerateKey()
+		parallelFrom   = crypto.PubkeyToAddress(parallelKey.PublicKey)
+		legacyFrom     = crypto.PubkeyToAddress(legacyKey.PublicKey)
+		config         = *params.AllDevChainProtocolChanges
+	)
+	config.ParallelTxTime = new(uint64)
+
+	stack, err := node.New(new(node.Config))
+	if err != nil {
+		t.Fatalf("failed to create node: %v", err)
+	}
+	defer stack.Close()
+
+	backend, err := New(stack, &ethconfig.Config{Genesis: &core.Genesis{
+		Config: &config,
+		Alloc: types.GenesisAlloc{
+			parallelFrom: {Balance: big.NewInt(params.Ether)},
+			legacyFrom:   {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}})
+	if err != nil {
+		t.Fatalf("failed to create ethereum service: %v", err)
+	}
+	if err := stack.Start(); err != nil {
+		t.Fatalf("failed to start node: %v", err)
+	}
+	var (
+		signer = types.LatestSigner(&config)
+		to     = common.Address{0xaa}
+		price  = big.NewInt(2 * params.InitialBaseFee)
+	)
+	txs := []*types.Transaction{
+		types.MustSignNewTx(parallelKey, signer, &types.ParallelTx{
+			ChainID:      config.ChainID,
+			GasTipCap:    price,
+			GasFeeCap:    price,
+			Gas:          params.TxGas,
+			To:           &to,
+			Value:        common.Big1,
+			ParallelType: types.ParallelTypeIndependent,
+		}),
+		types.MustSignNewTx(legacyKey, signer, &types.LegacyTx{To: &to, Value: common.Big1, Gas: params.TxGas, GasPrice: price}),
+	}
+	for i, err := range backend.TxPool().Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	api := ethapi.NewTxPoolAPI(backend.APIBackend)
+
+	pending := api.Content()["pending"]
+	if tx := pending[parallelFrom.Hex()]["0"]; tx == nil || tx.Hash != txs[0].Hash() || !tx.Parallel {
+		t.Errorf("parallel transaction mismatch: have %+v, want flagged %x", tx, txs[0].Hash())
+	}
+	if tx := pending[legacyFrom.Hex()]["0"]; tx == nil || tx.Hash != txs[1].Hash() || tx.Parallel {
+		t.Errorf("legacy transaction mismatch: have %+v, want unflagged %x", tx, txs[1].Hash())
+	}
+	if tx := api.ContentFrom(parallelFrom)["pending"]["0"]; tx == nil || tx.Hash != txs[0].Hash() || !tx.Parallel {
+		t.Errorf("parallel transaction of sender mismatch: have %+v, want flagged %x", tx, txs[0].Hash())
+	}
+}
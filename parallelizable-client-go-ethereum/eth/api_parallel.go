@@ -0,0 +1,131 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package eth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// ParallelAPI provides an API to inspect the parallel execution of blocks.
+type ParallelAPI struct {
+	e *Ethereum
+}
+
+// NewParallelAPI creates a new ParallelAPI instance.
+func NewParallelAPI(e *Ethereum) *ParallelAPI {
+	return &ParallelAPI{e}
+}
+
+// GetBlockSummary returns how a block was executed in parallel when built by
+// the local miner: the number of transactions executed in parallel, the batch
+// count, the measured speedup and the number of aborted executions. It returns
+// nil for blocks not built locally.
+func (api *ParallelAPI) GetBlockSummary(hash common.Hash) (*miner.BlockSummary, error) {
+	header := api.e.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, fmt.Errorf("block %#x not found", hash)
+	}
+	return miner.ReadBlockSummary(api.e.chainDb, header), nil
+}
+
+// GetOrderingLog returns the signed log of the batch composition and ordering
+// decisions taken when the local sequencer built a block. It returns nil for
+// blocks not built locally in sequencer mode.
+func (api *ParallelAPI) GetOrderingLog(hash common.Hash) (*miner.OrderingLog, error) {
+	header := api.e.blockchain.GetHeaderByHash(hash)
+	if header == nil {
+		return nil, fmt.Errorf("block %#x not found", hash)
+	}
+	return miner.ReadOrderingLog(api.e.chainDb, header), nil
+}
+
+// SimulateBatchArgs selects the batch to simulate: either a batch prepared by
+// the parallel pool, by its identifier, a synthetic batch of pooled
+// transactions, by their hashes, or a synthetic batch of signed transactions.
+type SimulateBatchArgs struct {
+	BatchID      *hexutil.Uint64 `json:"batchID"`
+	Hashes       []common.Hash   `json:"hashes"`
+	Transactions []hexutil.Bytes `json:"transactions"`
+}
+
+// SimulateBatch executes the members of a batch in isolation on top of the state
+// of the given block (the head by default) and reports their outcome, the state
+// they touched and the conflicts between them, without modifying the pool.
+// Replaying batches against historical blocks requires their state to be
+// available.
+func (api *ParallelAPI) SimulateBatch(ctx context.Context, args SimulateBatchArgs, blockNrOrHash *rpc.BlockNumberOrHash) ([]*parallelpool.BatchSimulation, error) {
+	selectors := 0
+	for _, set := range []bool{args.BatchID != nil, len(args.Hashes) > 0, len(args.Transactions) > 0} {
+		if set {
+			selectors++
+		}
+	}
+	if selectors != 1 {
+		return nil, errors.New("exactly one of batchID, hashes or transactions must be specified")
+	}
+	var batch parallelpool.TxBatch
+	switch {
+	case args.BatchID != nil:
+		found := false
+		for _, prepared := range api.e.parallelPool.GetBatches() {
+			if prepared.BatchID == uint64(*args.BatchID) {
+				batch, found = prepared, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("batch %d not found", uint64(*args.BatchID))
+		}
+	case len(args.Hashes) > 0:
+		for _, hash := range args.Hashes {
+			tx := api.e.parallelPool.Get(hash)
+			if tx == nil {
+				return nil, fmt.Errorf("transaction %#x not found", hash)
+			}
+			batch.Transactions = append(batch.Transactions, tx)
+		}
+	default:
+		for i, raw := range args.Transactions {
+			tx := new(types.Transaction)
+			if err := tx.UnmarshalBinary(raw); err != nil {
+				return nil, fmt.Errorf("invalid transaction %d: %v", i, err)
+			}
+			batch.Transactions = append(batch.Transactions, tx)
+		}
+	}
+	if blockNrOrHash == nil {
+		latest := rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)
+		blockNrOrHash = &latest
+	}
+	header, err := api.e.APIBackend.HeaderByNumberOrHash(ctx, *blockNrOrHash)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, errors.New("block not found")
+	}
+	return api.e.parallelPool.SimulateBatchAt(batch, header)
+}
@@ -72,6 +72,7 @@ type Ethereum struct {
 	// core protocol objects
 	config         *ethconfig.Config
 	txPool         *txpool.TxPool
+	parallelPool   *parallelpool.ParallelPool
 	localTxTracker *locals.TxTracker
 	blockchain     *core.BlockChain
 
@@ -236,9 +237,35 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
 	}
 	legacyPool := legacypool.New(config.TxPool, eth.blockchain)
-	parallelPool := parallelpool.New(parallelpool.Config{PriceBump: config.TxPool.PriceBump}, eth.blockchain)
 
-	eth.txPool, err = txpool.New(config.TxPool.PriceLimit, eth.blockchain, []txpool.SubPool{legacyPool, blobPool, parallelPool})
+	parallelConfig := parallelpool.Config{
+		PriceLimit:        config.TxPool.PriceLimit,
+		PriceBump:         config.TxPool.PriceBump,
+		Lifetime:          config.TxPool.Lifetime,
+		PendingLifetime:   config.ParallelPendingLifetime,
+		FutureSlots:       config.ParallelFutureSlots,
+		GapTimeout:        config.ParallelGapTimeout,
+		MaxParallelTxSize: config.ParallelMaxTxSize,
+		AccountSlots:      config.TxPool.AccountSlots,
+		GlobalSlots:       config.TxPool.GlobalSlots,
+		AccountQueue:      config.TxPool.AccountQueue,
+		GlobalQueue:       config.TxPool.GlobalQueue,
+		QuarantineDir:     stack.ResolvePath("parallel-quarantine"),
+		BatchWAL:          stack.ResolvePath("parallel-batches.wal"),
+		ReadOnly:          config.ParallelReadOnly,
+		StrictTagsTime:    config.ParallelStrictTagsTime,
+		SignatureDB:       config.ParallelSignatureDB,
+		AdminAPI:          config.ParallelAdminAPI,
+		QuarantinePanics:  config.ParallelQuarantinePanics,
+		Mode:              config.ParallelMode,
+		Locals:            config.ParallelLocals,
+	}
+	if !config.TxPool.NoLocals {
+		parallelConfig.Journal = stack.ResolvePath("parallel-transactions.rlp")
+		parallelConfig.Rejournal = config.TxPool.Rejournal
+	}
+	eth.parallelPool = parallelpool.New(parallelConfig, eth.blockchain)
+	eth.txPool, err = txpool.New(config.TxPool.PriceLimit, eth.blockchain, []txpool.SubPool{legacyPool, blobPool, eth.parallelPool})
 	if err != nil {
 		return nil, err
 	}
@@ -334,6 +361,15 @@ func (s *Ethereum) APIs() []rpc.API {
 		}, {
 			Namespace: "net",
 			Service:   s.netRPCService,
+		}, {
+			Namespace: "parallel",
+			Service:   NewParallelAPI(s),
+		}, {
+			Namespace: "parallel",
+			Service:   parallelpool.NewParallelTxPoolAPI(s.parallelPool),
+		}, {
+			Namespace: "eth",
+			Service:   parallelpool.NewParallelEthAPI(s.parallelPool),
 		},
 	}...)
 }
@@ -344,18 +380,19 @@ func (s *Ethereum) ResetWithGenesisBlock(gb *types.Block) {
 
 func (s *Ethereum) Miner() *miner.Miner { return s.miner }
 
-func (s *Ethereum) AccountManager() *accounts.Manager  { return s.accountManager }
-func (s *Ethereum) BlockChain() *core.BlockChain       { return s.blockchain }
-func (s *Ethereum) TxPool() *txpool.TxPool             { return s.txPool }
-func (s *Ethereum) EventMux() *event.TypeMux           { return s.eventMux }
-func (s *Ethereum) Engine() consensus.Engine           { return s.engine }
-func (s *Ethereum) ChainDb() ethdb.Database            { return s.chainDb }
-func (s *Ethereum) IsListening() bool                  { return true } // Always listening
-func (s *Ethereum) Downloader() *downloader.Downloader { return s.handler.downloader }
-func (s *Ethereum) Synced() bool                       { return s.handler.synced.Load() }
-func (s *Ethereum) SetSynced()                         { s.handler.enableSyncedFeatures() }
-func (s *Ethereum) ArchiveMode() bool                  { return s.config.NoPruning }
-func (s *Ethereum) BloomIndexer() *core.ChainIndexer   { return s.bloomIndexer }
+func (s *Ethereum) AccountManager() *accounts.Manager        { return s.accountManager }
+func (s *Ethereum) BlockChain() *core.BlockChain             { return s.blockchain }
+func (s *Ethereum) TxPool() *txpool.TxPool                   { return s.txPool }
+func (s *Ethereum) ParallelPool() *parallelpool.ParallelPool { return s.parallelPool }
+func (s *Ethereum) EventMux() *event.TypeMux                 { return s.eventMux }
+func (s *Ethereum) Engine() consensus.Engine                 { return s.engine }
+func (s *Ethereum) ChainDb() ethdb.Database                  { return s.chainDb }
+func (s *Ethereum) IsListening() bool                        { return true } // Always listening
+func (s *Ethereum) Downloader() *downloader.Downloader       { return s.handler.downloader }
+func (s *Ethereum) Synced() bool                             { return s.handler.synced.Load() }
+func (s *Ethereum) SetSynced()                               { s.handler.enableSyncedFeatures() }
+func (s *Ethereum) ArchiveMode() bool                        { return s.config.NoPruning }
+func (s *Ethereum) BloomIndexer() *core.ChainIndexer         { return s.bloomIndexer }
 
 // Protocols returns all the currently configured
 // network protocols to start.
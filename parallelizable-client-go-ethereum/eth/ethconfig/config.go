@@ -128,6 +128,50 @@ type Config struct {
 	TxPool   legacypool.Config
 	BlobPool blobpool.Config
 
+	// ParallelReadOnly runs the parallel transaction pool without ever executing
+	// batches, for non-mining RPC nodes.
+	ParallelReadOnly bool
+
+	// ParallelStrictTagsTime is the timestamp from which the parallel transaction
+	// pool rejects transactions routed by deprecated calldata tags.
+	ParallelStrictTagsTime *uint64 `toml:",omitempty"`
+
+	// ParallelSignatureDB is a 4-byte signature database naming the methods of
+	// untagged parallel transactions for the calldata classifier.
+	ParallelSignatureDB string `toml:",omitempty"`
+
+	// ParallelPendingLifetime is the maximum amount of time executable parallel
+	// pool transactions are kept, queued ones expiring after TxPool.Lifetime.
+	ParallelPendingLifetime time.Duration `toml:",omitempty"`
+
+	// ParallelFutureSlots is the maximum number of parallel pool transactions an
+	// account may have waiting behind a nonce gap.
+	ParallelFutureSlots uint64 `toml:",omitempty"`
+
+	// ParallelGapTimeout is the maximum amount of time batched parallel pool
+	// transactions wait behind a nonce gap before moving to the queue.
+	ParallelGapTimeout time.Duration `toml:",omitempty"`
+
+	// ParallelMaxTxSize is the maximum size of a parallel transaction executed
+	// within a batch, larger ones being handled sequentially.
+	ParallelMaxTxSize uint64 `toml:",omitempty"`
+
+	// ParallelAdminAPI enables the parallel pool RPC methods exporting and
+	// importing the pool content to and from files on the node.
+	ParallelAdminAPI bool `toml:",omitempty"`
+
+	// ParallelQuarantinePanics quarantines parallel transactions whose execution
+	// panicked right away, persisting them for bug reports.
+	ParallelQuarantinePanics bool `toml:",omitempty"`
+
+	// ParallelMode selects whether the parallel pool runs as a native subpool
+	// ("subpool") or standalone like the legacy parallel pool ("legacy").
+	ParallelMode string `toml:",omitempty"`
+
+	// ParallelLocals are the accounts whose parallel transactions are treated
+	// as local, exempting them from eviction and lifetime expiry.
+	ParallelLocals []common.Address `toml:",omitempty"`
+
 	// Gas Price Oracle options
 	GPO gasprice.Config
 
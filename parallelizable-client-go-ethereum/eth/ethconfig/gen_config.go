@@ -16,40 +16,51 @@ import (
 // MarshalTOML marshals as TOML.
 func (c Config) MarshalTOML() (interface{}, error) {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               uint64
-		SyncMode                SyncMode
-		EthDiscoveryURLs        []string
-		SnapDiscoveryURLs       []string
-		NoPruning               bool
-		NoPrefetch              bool
-		TxLookupLimit           uint64                 `toml:",omitempty"`
-		TransactionHistory      uint64                 `toml:",omitempty"`
-		StateHistory            uint64                 `toml:",omitempty"`
-		StateScheme             string                 `toml:",omitempty"`
-		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
-		SkipBcVersionCheck      bool                   `toml:"-"`
-		DatabaseHandles         int                    `toml:"-"`
-		DatabaseCache           int
-		DatabaseFreezer         string
-		TrieCleanCache          int
-		TrieDirtyCache          int
-		TrieTimeout             time.Duration
-		SnapshotCache           int
-		Preimages               bool
-		FilterLogCacheSize      int
-		Miner                   miner.Config
-		TxPool                  legacypool.Config
-		BlobPool                blobpool.Config
-		GPO                     gasprice.Config
-		EnablePreimageRecording bool
-		VMTrace                 string
-		VMTraceJsonConfig       string
-		RPCGasCap               uint64
-		RPCEVMTimeout           time.Duration
-		RPCTxFeeCap             float64
-		OverrideCancun          *uint64 `toml:",omitempty"`
-		OverrideVerkle          *uint64 `toml:",omitempty"`
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                uint64
+		SyncMode                 SyncMode
+		EthDiscoveryURLs         []string
+		SnapDiscoveryURLs        []string
+		NoPruning                bool
+		NoPrefetch               bool
+		TxLookupLimit            uint64                 `toml:",omitempty"`
+		TransactionHistory       uint64                 `toml:",omitempty"`
+		StateHistory             uint64                 `toml:",omitempty"`
+		StateScheme              string                 `toml:",omitempty"`
+		RequiredBlocks           map[uint64]common.Hash `toml:"-"`
+		SkipBcVersionCheck       bool                   `toml:"-"`
+		DatabaseHandles          int                    `toml:"-"`
+		DatabaseCache            int
+		DatabaseFreezer          string
+		TrieCleanCache           int
+		TrieDirtyCache           int
+		TrieTimeout              time.Duration
+		SnapshotCache            int
+		Preimages                bool
+		FilterLogCacheSize       int
+		Miner                    miner.Config
+		TxPool                   legacypool.Config
+		BlobPool                 blobpool.Config
+		ParallelReadOnly         bool
+		ParallelStrictTagsTime   *uint64          `toml:",omitempty"`
+		ParallelSignatureDB      string           `toml:",omitempty"`
+		ParallelPendingLifetime  time.Duration    `toml:",omitempty"`
+		ParallelFutureSlots      uint64           `toml:",omitempty"`
+		ParallelGapTimeout       time.Duration    `toml:",omitempty"`
+		ParallelMaxTxSize        uint64           `toml:",omitempty"`
+		ParallelAdminAPI         bool             `toml:",omitempty"`
+		ParallelQuarantinePanics bool             `toml:",omitempty"`
+		ParallelMode             string           `toml:",omitempty"`
+		ParallelLocals           []common.Address `toml:",omitempty"`
+		GPO                      gasprice.Config
+		EnablePreimageRecording  bool
+		VMTrace                  string
+		VMTraceJsonConfig        string
+		RPCGasCap                uint64
+		RPCEVMTimeout            time.Duration
+		RPCTxFeeCap              float64
+		OverrideCancun           *uint64 `toml:",omitempty"`
+		OverrideVerkle           *uint64 `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -77,6 +88,17 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.Miner = c.Miner
 	enc.TxPool = c.TxPool
 	enc.BlobPool = c.BlobPool
+	enc.ParallelReadOnly = c.ParallelReadOnly
+	enc.ParallelStrictTagsTime = c.ParallelStrictTagsTime
+	enc.ParallelSignatureDB = c.ParallelSignatureDB
+	enc.ParallelPendingLifetime = c.ParallelPendingLifetime
+	enc.ParallelFutureSlots = c.ParallelFutureSlots
+	enc.ParallelGapTimeout = c.ParallelGapTimeout
+	enc.ParallelMaxTxSize = c.ParallelMaxTxSize
+	enc.ParallelAdminAPI = c.ParallelAdminAPI
+	enc.ParallelQuarantinePanics = c.ParallelQuarantinePanics
+	enc.ParallelMode = c.ParallelMode
+	enc.ParallelLocals = c.ParallelLocals
 	enc.GPO = c.GPO
 	enc.EnablePreimageRecording = c.EnablePreimageRecording
 	enc.VMTrace = c.VMTrace
@@ -92,40 +114,51 @@ func (c Config) MarshalTOML() (interface{}, error) {
 // UnmarshalTOML unmarshals from TOML.
 func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	type Config struct {
-		Genesis                 *core.Genesis `toml:",omitempty"`
-		NetworkId               *uint64
-		SyncMode                *SyncMode
-		EthDiscoveryURLs        []string
-		SnapDiscoveryURLs       []string
-		NoPruning               *bool
-		NoPrefetch              *bool
-		TxLookupLimit           *uint64                `toml:",omitempty"`
-		TransactionHistory      *uint64                `toml:",omitempty"`
-		StateHistory            *uint64                `toml:",omitempty"`
-		StateScheme             *string                `toml:",omitempty"`
-		RequiredBlocks          map[uint64]common.Hash `toml:"-"`
-		SkipBcVersionCheck      *bool                  `toml:"-"`
-		DatabaseHandles         *int                   `toml:"-"`
-		DatabaseCache           *int
-		DatabaseFreezer         *string
-		TrieCleanCache          *int
-		TrieDirtyCache          *int
-		TrieTimeout             *time.Duration
-		SnapshotCache           *int
-		Preimages               *bool
-		FilterLogCacheSize      *int
-		Miner                   *miner.Config
-		TxPool                  *legacypool.Config
-		BlobPool                *blobpool.Config
-		GPO                     *gasprice.Config
-		EnablePreimageRecording *bool
-		VMTrace                 *string
-		VMTraceJsonConfig       *string
-		RPCGasCap               *uint64
-		RPCEVMTimeout           *time.Duration
-		RPCTxFeeCap             *float64
-		OverrideCancun          *uint64 `toml:",omitempty"`
-		OverrideVerkle          *uint64 `toml:",omitempty"`
+		Genesis                  *core.Genesis `toml:",omitempty"`
+		NetworkId                *uint64
+		SyncMode                 *SyncMode
+		EthDiscoveryURLs         []string
+		SnapDiscoveryURLs        []string
+		NoPruning                *bool
+		NoPrefetch               *bool
+		TxLookupLimit            *uint64                `toml:",omitempty"`
+		TransactionHistory       *uint64                `toml:",omitempty"`
+		StateHistory             *uint64                `toml:",omitempty"`
+		StateScheme              *string                `toml:",omitempty"`
+		RequiredBlocks           map[uint64]common.Hash `toml:"-"`
+		SkipBcVersionCheck       *bool                  `toml:"-"`
+		DatabaseHandles          *int                   `toml:"-"`
+		DatabaseCache            *int
+		DatabaseFreezer          *string
+		TrieCleanCache           *int
+		TrieDirtyCache           *int
+		TrieTimeout              *time.Duration
+		SnapshotCache            *int
+		Preimages                *bool
+		FilterLogCacheSize       *int
+		Miner                    *miner.Config
+		TxPool                   *legacypool.Config
+		BlobPool                 *blobpool.Config
+		ParallelReadOnly         *bool
+		ParallelStrictTagsTime   *uint64          `toml:",omitempty"`
+		ParallelSignatureDB      *string          `toml:",omitempty"`
+		ParallelPendingLifetime  *time.Duration   `toml:",omitempty"`
+		ParallelFutureSlots      *uint64          `toml:",omitempty"`
+		ParallelGapTimeout       *time.Duration   `toml:",omitempty"`
+		ParallelMaxTxSize        *uint64          `toml:",omitempty"`
+		ParallelAdminAPI         *bool            `toml:",omitempty"`
+		ParallelQuarantinePanics *bool            `toml:",omitempty"`
+		ParallelMode             *string          `toml:",omitempty"`
+		ParallelLocals           []common.Address `toml:",omitempty"`
+		GPO                      *gasprice.Config
+		EnablePreimageRecording  *bool
+		VMTrace                  *string
+		VMTraceJsonConfig        *string
+		RPCGasCap                *uint64
+		RPCEVMTimeout            *time.Duration
+		RPCTxFeeCap              *float64
+		OverrideCancun           *uint64 `toml:",omitempty"`
+		OverrideVerkle           *uint64 `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -206,6 +239,39 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.BlobPool != nil {
 		c.BlobPool = *dec.BlobPool
 	}
+	if dec.ParallelReadOnly != nil {
+		c.ParallelReadOnly = *dec.ParallelReadOnly
+	}
+	if dec.ParallelStrictTagsTime != nil {
+		c.ParallelStrictTagsTime = dec.ParallelStrictTagsTime
+	}
+	if dec.ParallelSignatureDB != nil {
+		c.ParallelSignatureDB = *dec.ParallelSignatureDB
+	}
+	if dec.ParallelPendingLifetime != nil {
+		c.ParallelPendingLifetime = *dec.ParallelPendingLifetime
+	}
+	if dec.ParallelFutureSlots != nil {
+		c.ParallelFutureSlots = *dec.ParallelFutureSlots
+	}
+	if dec.ParallelGapTimeout != nil {
+		c.ParallelGapTimeout = *dec.ParallelGapTimeout
+	}
+	if dec.ParallelMaxTxSize != nil {
+		c.ParallelMaxTxSize = *dec.ParallelMaxTxSize
+	}
+	if dec.ParallelAdminAPI != nil {
+		c.ParallelAdminAPI = *dec.ParallelAdminAPI
+	}
+	if dec.ParallelQuarantinePanics != nil {
+		c.ParallelQuarantinePanics = *dec.ParallelQuarantinePanics
+	}
+	if dec.ParallelMode != nil {
+		c.ParallelMode = *dec.ParallelMode
+	}
+	if dec.ParallelLocals != nil {
+		c.ParallelLocals = dec.ParallelLocals
+	}
 	if dec.GPO != nil {
 		c.GPO = *dec.GPO
 	}
@@ -459,8 +459,9 @@ func (h *handler) Stop() {
 // already have the given transaction.
 func (h *handler) BroadcastTransactions(txs types.Transactions) {
 	var (
-		blobTxs  int // Number of blob transactions to announce only
-		largeTxs int // Number of large transactions to announce only
+		blobTxs     int // Number of blob transactions to announce only
+		largeTxs    int // Number of large transactions to announce only
+		parallelTxs int // Number of parallel transactions, sent directly to lane peers only
 
 		directCount int // Number of transactions sent directly to peers (duplicates included)
 		annCount    int // Number of transactions announced across all peers (duplicates included)
@@ -490,6 +491,10 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		default:
 			maybeDirect = true
 		}
+		parallel := tx.Type() == types.ParallelTxType
+		if parallel {
+			parallelTxs++
+		}
 		// Send the transaction (if it's small enough) directly to a subset of
 		// the peers that have not received it yet, ensuring that the flow of
 		// transactions is grouped by account to (try and) avoid nonce gaps.
@@ -497,9 +502,15 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		// To do this, we hash the local enode IW with together with a peer's
 		// enode ID together with the transaction sender and broadcast if
 		// `sha(self, peer, sender) mod peers < sqrt(peers)`.
+		//
+		// Parallel transactions are instead sent directly to all the peers known
+		// to pool them, for the sake of their inclusion latency, and only ever
+		// announced to the others, which may not support them at all.
 		for _, peer := range h.peers.peersWithoutTransaction(tx.Hash()) {
 			var broadcast bool
-			if maybeDirect {
+			if parallel {
+				broadcast = maybeDirect && peer.parallelLane()
+			} else if maybeDirect {
 				hasher.Reset()
 				hasher.Write(h.nodeID.Bytes())
 				hasher.Write(peer.Node().ID().Bytes())
@@ -527,7 +538,7 @@ func (h *handler) BroadcastTransactions(txs types.Transactions) {
 		annCount += len(hashes)
 		peer.AsyncSendPooledTransactionHashes(hashes)
 	}
-	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs, "blobtxs", blobTxs, "largetxs", largeTxs,
+	log.Debug("Distributed transactions", "plaintxs", len(txs)-blobTxs-largeTxs, "blobtxs", blobTxs, "largetxs", largeTxs, "paralleltxs", parallelTxs,
 		"bcastpeers", len(txset), "bcastcount", directCount, "annpeers", len(annos), "anncount", annCount)
 }
 
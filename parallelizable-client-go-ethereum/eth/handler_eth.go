@@ -66,12 +66,38 @@ func (h *ethHandler) Handle(peer *eth.Peer, packet eth.Packet) error {
 				return errors.New("disallowed broadcast blob transaction")
 			}
 		}
-		return h.txFetcher.Enqueue(peer.ID(), *packet, false)
+		if err := h.txFetcher.Enqueue(peer.ID(), *packet, false); err != nil {
+			return err
+		}
+		h.markParallel(peer, *packet)
+		return nil
 
 	case *eth.PooledTransactionsResponse:
-		return h.txFetcher.Enqueue(peer.ID(), *packet, true)
+		if err := h.txFetcher.Enqueue(peer.ID(), *packet, true); err != nil {
+			return err
+		}
+		h.markParallel(peer, *packet)
+		return nil
 
 	default:
 		return fmt.Errorf("unexpected eth packet type: %T", packet)
 	}
 }
+
+// markParallel credits a peer with the parallel transactions it delivered that
+// the local pool accepted. Announcements are not credited, as anyone can announce
+// a parallel type without pooling such transactions.
+func (h *ethHandler) markParallel(peer *eth.Peer, txs []*types.Transaction) {
+	var count int
+	for _, tx := range txs {
+		if tx.Type() == types.ParallelTxType && h.txpool.Has(tx.Hash()) {
+			count++
+		}
+	}
+	if count == 0 {
+		return
+	}
+	if p := h.peers.peer(peer.ID()); p != nil {
+		p.markParallel(count)
+	}
+}
@@ -434,3 +434,178 @@ func testTransactionPropagation(t *testing.T, protocol uint) {
 		}
 	}
 }
+
+// Tests that parallel transactions are sent directly to the peers known to pool
+// them, and only announced to the others.
+func TestParallelTransactionRouting68(t *testing.T) { testParallelTransactionRouting(t, eth.ETH68) }
+
+func testParallelTransactionRouting(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	source := newTestHandler()
+	defer source.close()
+
+	var (
+		genesis  = source.chain.Genesis()
+		head     = source.chain.CurrentBlock()
+		srcPeers = make([]*eth.Peer, 2)
+		anns     = make([]chan []common.Hash, 2)
+		bcasts   = make([]chan []*types.Transaction, 2)
+	)
+	for i := range srcPeers {
+		p2pSrc, p2pSink := p2p.MsgPipe()
+		defer p2pSrc.Close()
+		defer p2pSink.Close()
+
+		src := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{byte(i + 1)}, "", nil, p2pSrc), p2pSrc, source.txpool)
+		sink := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{0}, "", nil, p2pSink), p2pSink, source.txpool)
+		defer src.Close()
+		defer sink.Close()
+
+		go source.handler.runEthPeer(src, func(peer *eth.Peer) error {
+			return eth.Handle((*ethHandler)(source.handler), peer)
+		})
+		if err := sink.Handshake(1, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain)); err != nil {
+			t.Fatalf("failed to run protocol handshake")
+		}
+		backend := new(testEthHandler)
+
+		anns[i] = make(chan []common.Hash, 1)
+		annSub := backend.txAnnounces.Subscribe(anns[i])
+		defer annSub.Unsubscribe()
+
+		bcasts[i] = make(chan []*types.Transaction, 1)
+		bcastSub := backend.txBroadcasts.Subscribe(bcasts[i])
+		defer bcastSub.Unsubscribe()
+
+		go eth.Handle(backend, sink)
+		srcPeers[i] = src
+	}
+	// Wait for both peers to register, crediting the first with parallel ones
+	for start := time.Now(); source.handler.peers.len() < len(srcPeers); {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("peers not registered: have %d, want %d", source.handler.peers.len(), len(srcPeers))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	source.handler.peers.peer(srcPeers[0].ID()).markParallel(1)
+
+	tx := types.MustSignNewTx(testKey, types.LatestSignerForChainID(source.chain.Config().ChainID), &types.ParallelTx{
+		ChainID:   source.chain.Config().ChainID,
+		GasTipCap: common.Big1,
+		GasFeeCap: common.Big1,
+		Gas:       params.TxGas,
+		To:        &common.Address{0xaa},
+		Value:     common.Big0,
+	})
+	source.txpool.Add([]*types.Transaction{tx}, false)
+
+	select {
+	case txs := <-bcasts[0]:
+		if len(txs) != 1 || txs[0].Hash() != tx.Hash() {
+			t.Errorf("lane peer broadcast mismatch: have %d txs, want %x", len(txs), tx.Hash())
+		}
+	case <-anns[0]:
+		t.Errorf("parallel transaction announced to lane peer")
+	case <-time.After(2 * time.Second):
+		t.Errorf("parallel transaction not sent to lane peer")
+	}
+	select {
+	case hashes := <-anns[1]:
+		if len(hashes) != 1 || hashes[0] != tx.Hash() {
+			t.Errorf("legacy peer announcement mismatch: have %v, want [%x]", hashes, tx.Hash())
+		}
+	case <-bcasts[1]:
+		t.Errorf("parallel transaction broadcast to legacy peer")
+	case <-time.After(2 * time.Second):
+		t.Errorf("parallel transaction not announced to legacy peer")
+	}
+}
+
+func TestParallelPeerCredit68(t *testing.T) { testParallelPeerCredit(t, eth.ETH68) }
+
+// Tests that peers are only credited with the parallel transactions they
+// delivered and the local pool accepted, not with those they announced.
+func testParallelPeerCredit(t *testing.T, protocol uint) {
+	t.Parallel()
+
+	source := newTestHandler()
+	defer source.close()
+
+	p2pSrc, p2pSink := p2p.MsgPipe()
+	defer p2pSrc.Close()
+	defer p2pSink.Close()
+
+	src := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{1}, "", nil, p2pSrc), p2pSrc, source.txpool)
+	sink := eth.NewPeer(protocol, p2p.NewPeerPipe(enode.ID{0}, "", nil, p2pSink), p2pSink, source.txpool)
+	defer src.Close()
+	defer sink.Close()
+
+	go source.handler.runEthPeer(src, func(peer *eth.Peer) error {
+		return eth.Handle((*ethHandler)(source.handler), peer)
+	})
+	var (
+		genesis = source.chain.Genesis()
+		head    = source.chain.CurrentBlock()
+	)
+	if err := sink.Handshake(1, head.Hash(), genesis.Hash(), forkid.NewIDWithChain(source.chain), forkid.NewFilter(source.chain)); err != nil {
+		t.Fatalf("failed to run protocol handshake")
+	}
+	go eth.Handle(new(testEthHandler), sink)
+
+	for start := time.Now(); source.handler.peers.peer(src.ID()) == nil; {
+		if time.Since(start) > 2*time.Second {
+			t.Fatalf("peer not registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	var (
+		handler = (*ethHandler)(source.handler)
+		peer    = source.handler.peers.peer(src.ID())
+		signer  = types.LatestSignerForChainID(source.chain.Config().ChainID)
+	)
+	newTx := func(nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(testKey, signer, &types.ParallelTx{
+			ChainID:   source.chain.Config().ChainID,
+			Nonce:     nonce,
+			GasTipCap: common.Big1,
+			GasFeeCap: common.Big1,
+			Gas:       params.TxGas,
+			To:        &common.Address{0xaa},
+			Value:     common.Big0,
+		})
+	}
+	// Announcing a parallel transaction proves nothing
+	known := newTx(0)
+	source.txpool.Add([]*types.Transaction{known}, false)
+
+	announce := &eth.NewPooledTransactionHashesPacket{Types: []byte{known.Type()}, Sizes: []uint32{uint32(known.Size())}, Hashes: []common.Hash{known.Hash()}}
+	if err := handler.Handle(src, announce); err != nil {
+		t.Fatalf("failed to handle announcement: %v", err)
+	}
+	if have := peer.parallelTxs.Load(); have != 0 {
+		t.Fatalf("peer credited for announcement: have %d, want 0", have)
+	}
+	// Neither does delivering one the pool refuses
+	rejected := newTx(1)
+	source.txpool.lock.Lock()
+	source.txpool.reject = map[common.Hash]bool{rejected.Hash(): true}
+	source.txpool.lock.Unlock()
+
+	if err := handler.Handle(src, &eth.TransactionsPacket{rejected}); err != nil {
+		t.Fatalf("failed to handle broadcast: %v", err)
+	}
+	if have := peer.parallelTxs.Load(); have != 0 {
+		t.Fatalf("peer credited for rejected delivery: have %d, want 0", have)
+	}
+	// Delivering accepted ones does, ordinary transactions aside
+	accepted := newTx(2)
+	ordinary := types.MustSignNewTx(testKey, signer, &types.LegacyTx{Nonce: 3, Gas: params.TxGas, GasPrice: common.Big1, To: &common.Address{0xaa}})
+
+	if err := handler.Handle(src, &eth.PooledTransactionsResponse{accepted, ordinary}); err != nil {
+		t.Fatalf("failed to handle delivery: %v", err)
+	}
+	if have := peer.parallelTxs.Load(); have != 1 {
+		t.Fatalf("peer credit mismatch: have %d, want 1", have)
+	}
+}
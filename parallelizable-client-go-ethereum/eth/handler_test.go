@@ -17,6 +17,7 @@
 package eth
 
 import (
+	"errors"
 	"math/big"
 	"sort"
 	"sync"
@@ -48,7 +49,8 @@ var (
 // Its goal is to get around setting up a valid statedb for the balance and nonce
 // checks.
 type testTxPool struct {
-	pool map[common.Hash]*types.Transaction // Hash map of collected transactions
+	pool   map[common.Hash]*types.Transaction // Hash map of collected transactions
+	reject map[common.Hash]bool               // Transactions refused on addition
 
 	txFeed event.Feed   // Notification feed to allow waiting for inclusion
 	lock   sync.RWMutex // Protects the transaction pool
@@ -84,11 +86,16 @@ func (p *testTxPool) Add(txs []*types.Transaction, sync bool) []error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
 
-	for _, tx := range txs {
+	errs := make([]error, len(txs))
+	for i, tx := range txs {
+		if p.reject[tx.Hash()] {
+			errs[i] = errors.New("rejected")
+			continue
+		}
 		p.pool[tx.Hash()] = tx
 	}
 	p.txFeed.Send(core.NewTxsEvent{Txs: txs})
-	return make([]error, len(txs))
+	return errs
 }
 
 // Pending returns all the transactions known to the pool
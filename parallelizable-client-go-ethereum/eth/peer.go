@@ -17,6 +17,8 @@
 package eth
 
 import (
+	"sync/atomic"
+
 	"github.com/ethereum/go-ethereum/eth/protocols/eth"
 	"github.com/ethereum/go-ethereum/eth/protocols/snap"
 )
@@ -24,22 +26,39 @@ import (
 // ethPeerInfo represents a short summary of the `eth` sub-protocol metadata known
 // about a connected peer.
 type ethPeerInfo struct {
-	Version uint `json:"version"` // Ethereum protocol version negotiated
+	Version     uint   `json:"version"`     // Ethereum protocol version negotiated
+	ParallelTxs uint64 `json:"parallelTxs"` // Parallel transactions announced or delivered
 }
 
 // ethPeer is a wrapper around eth.Peer to maintain a few extra metadata.
 type ethPeer struct {
 	*eth.Peer
 	snapExt *snapPeer // Satellite `snap` connection
+
+	parallelTxs atomic.Uint64 // Parallel transactions announced or delivered by the peer
 }
 
 // info gathers and returns some `eth` protocol metadata known about a peer.
 func (p *ethPeer) info() *ethPeerInfo {
 	return &ethPeerInfo{
-		Version: p.Version(),
+		Version:     p.Version(),
+		ParallelTxs: p.parallelTxs.Load(),
 	}
 }
 
+// markParallel records that the peer delivered parallel transactions accepted by
+// the local pool, proving that it pools them.
+func (p *ethPeer) markParallel(count int) {
+	p.parallelTxs.Add(uint64(count))
+}
+
+// parallelLane reports whether the peer is known to pool parallel transactions.
+// Peers that never relayed any may well be running a client without the
+// parallel lane, which can't make use of them.
+func (p *ethPeer) parallelLane() bool {
+	return p.parallelTxs.Load() > 0
+}
+
 // snapPeerInfo represents a short summary of the `snap` sub-protocol metadata known
 // about a connected peer.
 type snapPeerInfo struct {
@@ -0,0 +1,553 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package parallelclient provides an RPC client for the parallel_ APIs, covering
+// transaction tagging and submission, batch queries and subscriptions.
+package parallelclient
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/miner"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the parallel_ APIs.
+//
+// If you want to use the standardized Ethereum RPC functionality, use ethclient.Client instead.
+type Client struct {
+	c *rpc.Client
+}
+
+// Dial connects a client to the given URL.
+func Dial(rawurl string) (*Client, error) {
+	return DialContext(context.Background(), rawurl)
+}
+
+// DialContext connects a client to the given URL with context.
+func DialContext(ctx context.Context, rawurl string) (*Client, error) {
+	c, err := rpc.DialContext(ctx, rawurl)
+	if err != nil {
+		return nil, err
+	}
+	return New(c), nil
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// Close closes the underlying RPC connection.
+func (pc *Client) Close() {
+	pc.c.Close()
+}
+
+// Client gets the underlying RPC client.
+func (pc *Client) Client() *rpc.Client {
+	return pc.c
+}
+
+// Status returns the size counters and batching configuration of the pool.
+func (pc *Client) Status(ctx context.Context) (*parallelpool.ParallelPoolStatus, error) {
+	var result parallelpool.ParallelPoolStatus
+	if err := pc.c.CallContext(ctx, &result, "parallel_status"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// TagTransaction assembles an unsigned parallel transaction from the request,
+// routed as parallelizable or sequential. The transaction must be signed before
+// it is submitted.
+func (pc *Client) TagTransaction(ctx context.Context, req parallelpool.TagTransactionRequest) (*types.Transaction, error) {
+	var raw hexutil.Bytes
+	if err := pc.c.CallContext(ctx, &raw, "parallel_tagTransaction", req); err != nil {
+		return nil, err
+	}
+	tx := new(types.Transaction)
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// CreateWorkflow assembles the unsigned transactions of a multi-step workflow,
+// each step depending on the previous one. The transactions must be signed and
+// submitted in order.
+func (pc *Client) CreateWorkflow(ctx context.Context, steps []parallelpool.TagTransactionRequest) ([]*types.Transaction, error) {
+	var raws []hexutil.Bytes
+	if err := pc.c.CallContext(ctx, &raws, "parallel_createWorkflow", steps); err != nil {
+		return nil, err
+	}
+	txs := make([]*types.Transaction, len(raws))
+	for i, raw := range raws {
+		txs[i] = new(types.Transaction)
+		if err := txs[i].UnmarshalBinary(raw); err != nil {
+			return nil, err
+		}
+	}
+	return txs, nil
+}
+
+// SendUserOpBundle submits a signed account abstraction bundle transaction along
+// with the footprints of its user operations.
+func (pc *Client) SendUserOpBundle(ctx context.Context, tx *types.Transaction, bundle *parallelpool.UserOpBundle) (common.Hash, error) {
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	var hash common.Hash
+	err = pc.c.CallContext(ctx, &hash, "parallel_sendUserOpBundle", hexutil.Bytes(raw), bundle)
+	return hash, err
+}
+
+// SetBatchSize sets the maximum number of transactions per batch.
+func (pc *Client) SetBatchSize(ctx context.Context, size int) error {
+	return pc.c.CallContext(ctx, nil, "parallel_setBatchSize", size)
+}
+
+// SetParallelism sets the number of workers executing batch members, zero
+// restoring the default of one per CPU.
+func (pc *Client) SetParallelism(ctx context.Context, workers int) error {
+	return pc.c.CallContext(ctx, nil, "parallel_setParallelism", workers)
+}
+
+// ExecuteBatches executes the prepared batches and returns the hashes of the
+// executed transactions.
+func (pc *Client) ExecuteBatches(ctx context.Context) ([]common.Hash, error) {
+	var result []common.Hash
+	err := pc.c.CallContext(ctx, &result, "parallel_executeBatches")
+	return result, err
+}
+
+// SimulateBatches simulates the prepared batches without executing them,
+// returning the outcome of their members by batch identifier.
+func (pc *Client) SimulateBatches(ctx context.Context) (map[uint64][]*parallelpool.BatchSimulation, error) {
+	var result map[uint64][]*parallelpool.BatchSimulation
+	err := pc.c.CallContext(ctx, &result, "parallel_simulateBatches")
+	return result, err
+}
+
+// SimulateBatchArgs selects the batch to simulate with SimulateBatch: either a
+// batch prepared by the pool, by its identifier, a synthetic batch of pooled
+// transactions, by their hashes, or a synthetic batch of signed transactions.
+type SimulateBatchArgs struct {
+	BatchID      *uint64
+	Hashes       []common.Hash
+	Transactions []*types.Transaction
+}
+
+// SimulateBatch executes the members of a batch in isolation on top of the state
+// of the given block, the head if nil, and reports their outcome, the state they
+// touched and the conflicts between them.
+func (pc *Client) SimulateBatch(ctx context.Context, args SimulateBatchArgs, blockNrOrHash *rpc.BlockNumberOrHash) ([]*parallelpool.BatchSimulation, error) {
+	arg := map[string]interface{}{}
+	if args.BatchID != nil {
+		arg["batchID"] = hexutil.Uint64(*args.BatchID)
+	}
+	if len(args.Hashes) > 0 {
+		arg["hashes"] = args.Hashes
+	}
+	if len(args.Transactions) > 0 {
+		txs := make([]hexutil.Bytes, len(args.Transactions))
+		for i, tx := range args.Transactions {
+			raw, err := tx.MarshalBinary()
+			if err != nil {
+				return nil, err
+			}
+			txs[i] = raw
+		}
+		arg["transactions"] = txs
+	}
+	var result []*parallelpool.BatchSimulation
+	err := pc.c.CallContext(ctx, &result, "parallel_simulateBatch", arg, blockNrOrHash)
+	return result, err
+}
+
+// SetParallelPreference sets the parallel routing preference of an account.
+func (pc *Client) SetParallelPreference(ctx context.Context, pref parallelpool.ParallelPreference) error {
+	return pc.c.CallContext(ctx, nil, "parallel_setParallelPreference", pref)
+}
+
+// GetParallelPreference returns the parallel routing preference of an account,
+// or nil if it has none.
+func (pc *Client) GetParallelPreference(ctx context.Context, addr common.Address) (*parallelpool.ParallelPreference, error) {
+	var result *parallelpool.ParallelPreference
+	err := pc.c.CallContext(ctx, &result, "parallel_getParallelPreference", addr)
+	return result, err
+}
+
+// ReserveNonceRange stores a signed reservation of a range of future nonces of
+// an account, or releases the held one if it spans no nonces.
+func (pc *Client) ReserveNonceRange(ctx context.Context, r parallelpool.NonceRange) error {
+	return pc.c.CallContext(ctx, nil, "parallel_reserveNonceRange", r)
+}
+
+// GetNonceRange returns the nonce range reservation held by an account, or nil
+// if it holds none.
+func (pc *Client) GetNonceRange(ctx context.Context, addr common.Address) (*parallelpool.NonceRange, error) {
+	var result *parallelpool.NonceRange
+	err := pc.c.CallContext(ctx, &result, "parallel_getNonceRange", addr)
+	return result, err
+}
+
+// Quarantine returns the batches quarantined after repeated failures.
+func (pc *Client) Quarantine(ctx context.Context) ([]*parallelpool.QuarantinedBatch, error) {
+	var result []*parallelpool.QuarantinedBatch
+	err := pc.c.CallContext(ctx, &result, "parallel_quarantine")
+	return result, err
+}
+
+// ReleaseQuarantine releases a quarantined batch by its key.
+func (pc *Client) ReleaseQuarantine(ctx context.Context, key common.Hash) error {
+	return pc.c.CallContext(ctx, nil, "parallel_releaseQuarantine", key)
+}
+
+// ParallelizableResult is the routing information of a pooled transaction.
+type ParallelizableResult struct {
+	Hash             common.Hash     `json:"hash"`
+	IsParallelizable bool            `json:"isParallelizable"`
+	Tag              string          `json:"tag"`
+	LegacyTag        bool            `json:"legacyTag"`
+	From             common.Address  `json:"from"`
+	To               *common.Address `json:"to"`
+	Nonce            uint64          `json:"nonce"`
+	Value            string          `json:"value"` // Decimal wei amount
+	Gas              uint64          `json:"gas"`
+	GasPrice         string          `json:"gasPrice"` // Decimal wei amount
+	InBatch          bool            `json:"inBatch"`
+	BatchID          uint64          `json:"batchID"`
+	Error            string          `json:"error"`
+}
+
+// IsParallelizable returns the routing information of a pooled transaction.
+func (pc *Client) IsParallelizable(ctx context.Context, hash common.Hash) (*ParallelizableResult, error) {
+	var result ParallelizableResult
+	if err := pc.c.CallContext(ctx, &result, "parallel_isParallelizable", hash); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// BatchDetails describes a single prepared batch.
+type BatchDetails struct {
+	BatchID       uint64 `json:"batchID"`
+	TxCount       int    `json:"txCount"`
+	Level         int    `json:"level"`
+	UniqueSenders int    `json:"uniqueSenders"`
+	TotalGas      uint64 `json:"totalGas"`
+	AvgGas        uint64 `json:"avgGas"`
+	MinGas        uint64 `json:"minGas"`
+	MaxGas        uint64 `json:"maxGas"`
+}
+
+// BatchStatistics describes the prepared batches. The batch size distribution
+// is only reported if there are any batches.
+type BatchStatistics struct {
+	BatchSize       int             `json:"batchSize"`
+	BatchCount      int             `json:"batchCount"`
+	TotalBatchedTxs int             `json:"totalBatchedTxs"`
+	MinBatchSize    int             `json:"minBatchSize"`
+	MaxBatchSize    int             `json:"maxBatchSize"`
+	MedianBatchSize int             `json:"medianBatchSize"`
+	Batches         []*BatchDetails `json:"batches"`
+}
+
+// BatchStatistics returns statistics about the prepared batches.
+func (pc *Client) BatchStatistics(ctx context.Context) (*BatchStatistics, error) {
+	var result BatchStatistics
+	if err := pc.c.CallContext(ctx, &result, "parallel_batchStatistics"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DataAnalysis is the parallelization recommendation for transaction calldata.
+type DataAnalysis struct {
+	DataLength             int     `json:"dataLength"`
+	IsTagged               bool    `json:"isTagged"`
+	Tag                    string  `json:"tag"`
+	MethodSignature        string  `json:"methodSignature"`
+	Method                 string  `json:"method"`
+	MethodType             string  `json:"methodType"`
+	ParallelRecommendation bool    `json:"parallelRecommendation"`
+	Confidence             float64 `json:"confidence"`
+	Recommendation         string  `json:"recommendation"`
+}
+
+// AnalyzeTransactionData examines transaction calldata for whether it would be
+// suitable for parallel execution.
+func (pc *Client) AnalyzeTransactionData(ctx context.Context, data []byte) (*DataAnalysis, error) {
+	var result DataAnalysis
+	if err := pc.c.CallContext(ctx, &result, "parallel_analyzeTransactionData", hexutil.Bytes(data)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportBatches returns descriptors of the prepared batches, signed if the node
+// has a builder key configured.
+func (pc *Client) ExportBatches(ctx context.Context) ([]*parallelpool.SubmittedBatch, error) {
+	var result []*parallelpool.SubmittedBatch
+	err := pc.c.CallContext(ctx, &result, "parallel_exportBatches")
+	return result, err
+}
+
+// EstimateBatchGas estimates the gas usage of a batch of signed transactions on
+// top of the current head, along with the critical path of its dependent members.
+func (pc *Client) EstimateBatchGas(ctx context.Context, txs []*types.Transaction) (*parallelpool.BatchGasEstimate, error) {
+	raws := make([]hexutil.Bytes, len(txs))
+	for i, tx := range txs {
+		raw, err := tx.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		raws[i] = raw
+	}
+	var result parallelpool.BatchGasEstimate
+	if err := pc.c.CallContext(ctx, &result, "parallel_estimateBatchGas", raws); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetMembershipProof returns the proof of a transaction's membership in a prepared
+// batch, verifiable against the members root of the batch descriptor.
+func (pc *Client) GetMembershipProof(ctx context.Context, batchID uint64, hash common.Hash) (*parallelpool.MembershipProof, error) {
+	var result parallelpool.MembershipProof
+	if err := pc.c.CallContext(ctx, &result, "parallel_getMembershipProof", hexutil.Uint64(batchID), hash); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// IngestionStats returns the accepted and rejected transaction counts by origin.
+func (pc *Client) IngestionStats(ctx context.Context) (map[string]parallelpool.OriginStats, error) {
+	var result map[string]parallelpool.OriginStats
+	err := pc.c.CallContext(ctx, &result, "parallel_ingestionStats")
+	return result, err
+}
+
+// GetDependents returns the hashes of the pooled transactions declaring a direct
+// dependency on the given transaction.
+func (pc *Client) GetDependents(ctx context.Context, hash common.Hash) ([]common.Hash, error) {
+	var result []common.Hash
+	err := pc.c.CallContext(ctx, &result, "parallel_getDependents", hash)
+	return result, err
+}
+
+// ConflictMatrix returns the conflict graph of the transactions ready for
+// batching, listing which pairs conflict and on which account or slot.
+func (pc *Client) ConflictMatrix(ctx context.Context) (*parallelpool.ConflictMatrix, error) {
+	var result parallelpool.ConflictMatrix
+	if err := pc.c.CallContext(ctx, &result, "parallel_conflictMatrix"); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDependencyClosure returns the transitive dependency ancestors or dependents
+// of a pooled transaction, limited to the given depth unless it is zero.
+func (pc *Client) GetDependencyClosure(ctx context.Context, hash common.Hash, direction string, depth uint) (*parallelpool.DependencyClosure, error) {
+	var limit *hexutil.Uint
+	if depth != 0 {
+		limit = (*hexutil.Uint)(&depth)
+	}
+	var result parallelpool.DependencyClosure
+	if err := pc.c.CallContext(ctx, &result, "parallel_getDependencyClosure", hash, direction, limit); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SetInclusionDeadline attaches a soft inclusion deadline to a pooled parallel
+// transaction, in blocks and/or seconds from the current head. Zero leaves a
+// bound unset.
+func (pc *Client) SetInclusionDeadline(ctx context.Context, hash common.Hash, blocks, seconds uint64) (*parallelpool.InclusionDeadline, error) {
+	var args parallelpool.InclusionDeadlineArgs
+	if blocks != 0 {
+		args.Blocks = (*hexutil.Uint64)(&blocks)
+	}
+	if seconds != 0 {
+		args.Seconds = (*hexutil.Uint64)(&seconds)
+	}
+	var result parallelpool.InclusionDeadline
+	if err := pc.c.CallContext(ctx, &result, "parallel_setInclusionDeadline", hash, args); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetNonceRepair returns the missing nonces holding back the pooled transactions
+// of an account, or nil if there are none.
+func (pc *Client) GetNonceRepair(ctx context.Context, addr common.Address) (*parallelpool.NonceRepair, error) {
+	var result *parallelpool.NonceRepair
+	err := pc.c.CallContext(ctx, &result, "parallel_getNonceRepair", addr)
+	return result, err
+}
+
+// TraceFootprint executes a raw transaction or a call on top of the head and
+// returns the read and write sets it produced.
+func (pc *Client) TraceFootprint(ctx context.Context, args parallelpool.FootprintArgs) (*parallelpool.Footprint, error) {
+	var result parallelpool.Footprint
+	if err := pc.c.CallContext(ctx, &result, "parallel_traceFootprint", args); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateAccessList executes the transaction of a tagging request on top of the
+// head and returns its access list, split into read and write sets, along with
+// the unsigned transaction carrying it.
+func (pc *Client) CreateAccessList(ctx context.Context, req parallelpool.TagTransactionRequest) (*parallelpool.AccessListResult, error) {
+	var result parallelpool.AccessListResult
+	if err := pc.c.CallContext(ctx, &result, "parallel_createAccessList", req); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SuggestTip advises whether calls to a contract are worth tagging PARALLEL,
+// and what tip is competitive for them within the parallel lane.
+func (pc *Client) SuggestTip(ctx context.Context, contract common.Address) (*parallelpool.TipSuggestion, error) {
+	var result parallelpool.TipSuggestion
+	if err := pc.c.CallContext(ctx, &result, "parallel_suggestTip", contract); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ExportPool makes the node write a snapshot of its parallel pool into a file,
+// returning the number of exported transactions. The node needs the admin
+// methods enabled.
+func (pc *Client) ExportPool(ctx context.Context, file string) (int, error) {
+	var result int
+	err := pc.c.CallContext(ctx, &result, "parallel_exportPool", file)
+	return result, err
+}
+
+// ImportPool makes the node add the transactions of a parallel pool snapshot
+// file to its pool. The node needs the admin methods enabled.
+func (pc *Client) ImportPool(ctx context.Context, file string) (*parallelpool.ImportResult, error) {
+	var result parallelpool.ImportResult
+	if err := pc.c.CallContext(ctx, &result, "parallel_importPool", file); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Mode returns the mode the node's parallel pool is running in.
+func (pc *Client) Mode(ctx context.Context) (string, error) {
+	var result string
+	err := pc.c.CallContext(ctx, &result, "parallel_mode")
+	return result, err
+}
+
+// SetMode switches the node's parallel pool between the subpool and the legacy
+// mode. The node needs the admin methods enabled.
+func (pc *Client) SetMode(ctx context.Context, mode string) (*parallelpool.ModeSwitch, error) {
+	var result parallelpool.ModeSwitch
+	if err := pc.c.CallContext(ctx, &result, "parallel_setMode", mode); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetTxDiagnostics returns diagnostic information about a pooled parallel
+// transaction, or nil if it is not pooled.
+func (pc *Client) GetTxDiagnostics(ctx context.Context, hash common.Hash) (*parallelpool.TxDiagnostics, error) {
+	var result *parallelpool.TxDiagnostics
+	err := pc.c.CallContext(ctx, &result, "parallel_getTxDiagnostics", hash)
+	return result, err
+}
+
+// Content returns the pending and queued transactions of the pool, grouped by
+// account and nonce.
+func (pc *Client) Content(ctx context.Context) (map[string]map[common.Address]map[string]*types.Transaction, error) {
+	var result map[string]map[common.Address]map[string]*types.Transaction
+	err := pc.c.CallContext(ctx, &result, "parallel_content")
+	return result, err
+}
+
+// ContentFrom returns the pending and queued transactions of an account, grouped
+// by nonce.
+func (pc *Client) ContentFrom(ctx context.Context, addr common.Address) (map[string]map[string]*types.Transaction, error) {
+	var result map[string]map[string]*types.Transaction
+	err := pc.c.CallContext(ctx, &result, "parallel_contentFrom", addr)
+	return result, err
+}
+
+// PendingFrom returns the executable transactions of the given accounts, grouped
+// by account and nonce. If minTip is non-nil, each account's transactions are
+// cut off at the first one paying less at the current base fee.
+func (pc *Client) PendingFrom(ctx context.Context, addrs []common.Address, minTip *big.Int) (map[common.Address]map[string]*types.Transaction, error) {
+	var result map[common.Address]map[string]*types.Transaction
+	err := pc.c.CallContext(ctx, &result, "parallel_pendingFrom", addrs, (*hexutil.Big)(minTip))
+	return result, err
+}
+
+// Inspect returns a textual summary of the pending and queued transactions of
+// the pool, grouped by account and nonce.
+func (pc *Client) Inspect(ctx context.Context) (map[string]map[common.Address]map[string]string, error) {
+	var result map[string]map[common.Address]map[string]string
+	err := pc.c.CallContext(ctx, &result, "parallel_inspect")
+	return result, err
+}
+
+// GetBlockSummary returns how a locally built block was executed in parallel.
+func (pc *Client) GetBlockSummary(ctx context.Context, hash common.Hash) (*miner.BlockSummary, error) {
+	var result *miner.BlockSummary
+	err := pc.c.CallContext(ctx, &result, "parallel_getBlockSummary", hash)
+	return result, err
+}
+
+// GetOrderingLog returns the batch ordering decisions taken while building a
+// block locally.
+func (pc *Client) GetOrderingLog(ctx context.Context, hash common.Hash) (*miner.OrderingLog, error) {
+	var result *miner.OrderingLog
+	err := pc.c.CallContext(ctx, &result, "parallel_getOrderingLog", hash)
+	return result, err
+}
+
+// SubscribeBatchEvents subscribes to batches being created, executed and
+// invalidated by the pool.
+func (pc *Client) SubscribeBatchEvents(ctx context.Context, ch chan<- parallelpool.BatchEvent) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "parallel", ch, "batchEvents")
+}
+
+// SubscribeBatchChanges subscribes to transactions moving between batches as the
+// pool rebuilds them.
+func (pc *Client) SubscribeBatchChanges(ctx context.Context, ch chan<- parallelpool.BatchChange) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "parallel", ch, "batchChanges")
+}
+
+// SubscribeDeadlineDrops subscribes to the hashes of transactions dropped for
+// missing their inclusion deadline.
+func (pc *Client) SubscribeDeadlineDrops(ctx context.Context, ch chan<- common.Hash) (*rpc.ClientSubscription, error) {
+	return pc.c.Subscribe(ctx, "parallel", ch, "deadlineDrops")
+}
+
+// SubscribeAccountActivity subscribes to the transactions of an account moving
+// through the pool, through eth_subscribe.
+func (pc *Client) SubscribeAccountActivity(ctx context.Context, account common.Address, ch chan<- parallelpool.AccountActivity) (*rpc.ClientSubscription, error) {
+	return pc.c.EthSubscribe(ctx, ch, "parallelAccountActivity", account)
+}
@@ -0,0 +1,189 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package parallelclient
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Tests that the client round-trips the typed requests and responses of the
+// parallel pool API, and delivers batch change and account activity
+// notifications.
+func TestClient(t *testing.T) {
+	key, _ := crypto.GenerateKey()
+	from := crypto.PubkeyToAddress(key.PublicKey)
+
+	config := *params.TestChainConfig
+	config.ParallelTxTime = new(uint64)
+
+	gspec := &core.Genesis{
+		Config:  &config,
+		Alloc:   types.GenesisAlloc{from: {Balance: big.NewInt(params.Ether)}},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool := parallelpool.New(parallelpool.Config{MetricsRegistry: metrics.NewRegistry()}, chain)
+	if err := pool.Init(0, chain.CurrentBlock(), func(common.Address, bool) error { return nil }); err != nil {
+		t.Fatalf("failed to initialize pool: %v", err)
+	}
+	defer pool.Close()
+
+	server := rpc.NewServer()
+	defer server.Stop()
+	if err := server.RegisterName("parallel", parallelpool.NewParallelTxPoolAPI(pool)); err != nil {
+		t.Fatalf("failed to register API: %v", err)
+	}
+	if err := server.RegisterName("eth", parallelpool.NewParallelEthAPI(pool)); err != nil {
+		t.Fatalf("failed to register eth API: %v", err)
+	}
+	client := New(rpc.DialInProc(server))
+	defer client.Close()
+
+	ctx := context.Background()
+	changes := make(chan parallelpool.BatchChange, 1)
+	sub, err := client.SubscribeBatchChanges(ctx, changes)
+	if err != nil {
+		t.Fatalf("failed to subscribe to batch changes: %v", err)
+	}
+	defer sub.Unsubscribe()
+
+	activities := make(chan parallelpool.AccountActivity, 4)
+	activitySub, err := client.SubscribeAccountActivity(ctx, from, activities)
+	if err != nil {
+		t.Fatalf("failed to subscribe to account activity: %v", err)
+	}
+	defer activitySub.Unsubscribe()
+
+	// Tag, sign and pool a parallel transaction
+	var (
+		to       = common.Address{0xaa}
+		gasPrice = big.NewInt(2 * params.InitialBaseFee)
+		parallel = true
+	)
+	unsigned, err := client.TagTransaction(ctx, parallelpool.TagTransactionRequest{
+		From:     from,
+		To:       &to,
+		GasPrice: (*hexutil.Big)(gasPrice),
+		Parallel: &parallel,
+	})
+	if err != nil {
+		t.Fatalf("failed to tag transaction: %v", err)
+	}
+	if unsigned.ParallelType() != types.ParallelTypeIndependent || unsigned.Nonce() != 0 || *unsigned.To() != to {
+		t.Fatalf("tagged transaction mismatch: type %d, nonce %d, to %v", unsigned.ParallelType(), unsigned.Nonce(), unsigned.To())
+	}
+	steps := []parallelpool.TagTransactionRequest{
+		{From: from, To: &to, GasPrice: (*hexutil.Big)(gasPrice), Parallel: &parallel},
+		{From: from, To: &to, GasPrice: (*hexutil.Big)(gasPrice), Parallel: &parallel},
+	}
+	workflow, err := client.CreateWorkflow(ctx, steps)
+	if err != nil {
+		t.Fatalf("failed to create workflow: %v", err)
+	}
+	if len(workflow) != 2 || workflow[1].Nonce() != 1 || len(workflow[1].Dependencies()) != 1 || workflow[1].Dependencies()[0] != types.LatestSigner(&config).Hash(workflow[0]) {
+		t.Fatalf("workflow mismatch: %v", workflow)
+	}
+	tx, err := types.SignTx(unsigned, types.LatestSigner(&config), key)
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	if err := pool.Add([]*types.Transaction{tx}, false)[0]; err != nil {
+		t.Fatalf("failed to add transaction: %v", err)
+	}
+	select {
+	case change := <-changes:
+		if change.Hash != tx.Hash() || change.Reason != parallelpool.BatchChangeAdded || change.NewBatch == nil {
+			t.Errorf("batch change mismatch: %+v", change)
+		}
+	case err := <-sub.Err():
+		t.Fatalf("subscription failed: %v", err)
+	case <-time.After(time.Second):
+		t.Fatalf("batch change not delivered")
+	}
+	for _, kind := range []string{parallelpool.ActivityAdded, parallelpool.ActivityBatched} {
+		select {
+		case activity := <-activities:
+			if activity.Type != kind || activity.Hash != tx.Hash() || activity.Account != from {
+				t.Errorf("account activity mismatch: have %+v, want %s", activity, kind)
+			}
+		case err := <-activitySub.Err():
+			t.Fatalf("activity subscription failed: %v", err)
+		case <-time.After(time.Second):
+			t.Fatalf("account activity %s not delivered", kind)
+		}
+	}
+	// Query the pool through the typed responses
+	status, err := client.Status(ctx)
+	if err != nil {
+		t.Fatalf("failed to retrieve status: %v", err)
+	}
+	if status.Pending != 1 || status.Batches != 1 {
+		t.Errorf("status mismatch: %+v", status)
+	}
+	info, err := client.IsParallelizable(ctx, tx.Hash())
+	if err != nil {
+		t.Fatalf("failed to retrieve routing: %v", err)
+	}
+	if !info.IsParallelizable || !info.InBatch || info.From != from || info.Gas != params.TxGas {
+		t.Errorf("routing mismatch: %+v", info)
+	}
+	stats, err := client.BatchStatistics(ctx)
+	if err != nil {
+		t.Fatalf("failed to retrieve batch statistics: %v", err)
+	}
+	if stats.BatchCount != 1 || len(stats.Batches) != 1 || stats.Batches[0].TotalGas != params.TxGas {
+		t.Errorf("batch statistics mismatch: %+v", stats)
+	}
+	content, err := client.ContentFrom(ctx, from)
+	if err != nil {
+		t.Fatalf("failed to retrieve content: %v", err)
+	}
+	if pending := content["pending"]["0"]; pending == nil || pending.Hash() != tx.Hash() {
+		t.Errorf("content mismatch: %v", content)
+	}
+	executable, err := client.PendingFrom(ctx, []common.Address{from}, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve account pending: %v", err)
+	}
+	if pending := executable[from]["0"]; pending == nil || pending.Hash() != tx.Hash() {
+		t.Errorf("account pending mismatch: %v", executable)
+	}
+	if _, err := client.GetDependencyClosure(ctx, tx.Hash(), "sideways", 0); err == nil || err.Error() != parallelpool.ErrUnknownDependencyDirection.Error() {
+		t.Errorf("closure error mismatch: have %v, want %v", err, parallelpool.ErrUnknownDependencyDirection)
+	}
+}
@@ -29,6 +29,9 @@ import (
 	"github.com/ethereum/go-ethereum/eth/ethconfig"
 	"github.com/ethereum/go-ethereum/eth/filters"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/ethclient/parallelclient"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/miner"
 	"github.com/ethereum/go-ethereum/node"
 	"github.com/ethereum/go-ethereum/p2p"
 	"github.com/ethereum/go-ethereum/params"
@@ -62,15 +65,20 @@ type simClient struct {
 // Backend is a simulated blockchain. You can use it to test your contracts or
 // other code that interacts with the Ethereum chain.
 type Backend struct {
-	node   *node.Node
-	beacon *catalyst.SimulatedBeacon
-	client simClient
+	node     *node.Node
+	beacon   *catalyst.SimulatedBeacon
+	executor *miner.BatchExecutor // Executor including the parallel batches in blocks
+	registry metrics.Registry     // Registry of the executor metrics
+	client   simClient
+	parallel *parallelclient.Client
 }
 
 // NewBackend creates a new simulated blockchain that can be used as a backend for
 // contract bindings in unit tests.
 //
-// A simulated backend always uses chainID 1337.
+// A simulated backend always uses chainID 1337. Parallel transactions are active
+// from genesis, and blocks include the batches of the parallel pool ahead of the
+// sequential transactions, like a miner running the parallel path does.
 func NewBackend(alloc types.GenesisAlloc, options ...func(nodeConf *node.Config, ethConf *ethconfig.Config)) *Backend {
 	// Create the default configurations for the outer node shell and the Ethereum
 	// service to mutate with the options afterwards
@@ -78,9 +86,12 @@ func NewBackend(alloc types.GenesisAlloc, options ...func(nodeConf *node.Config,
 	nodeConf.DataDir = ""
 	nodeConf.P2P = p2p.Config{NoDiscovery: true}
 
+	config := *params.AllDevChainProtocolChanges
+	config.ParallelTxTime = new(uint64)
+
 	ethConf := ethconfig.Defaults
 	ethConf.Genesis = &core.Genesis{
-		Config:   params.AllDevChainProtocolChanges,
+		Config:   &config,
 		GasLimit: ethconfig.Defaults.Miner.GasCeil,
 		Alloc:    alloc,
 	}
@@ -109,6 +120,17 @@ func newWithNode(stack *node.Node, conf *eth.Config, blockPeriod uint64) (*Backe
 	if err != nil {
 		return nil, err
 	}
+	// Build blocks out of the parallel pool's batches, executing their members
+	// concurrently and pre-executing them on every new head. The executor keeps its metrics to itself, as several
+	// simulated backends may run in one process.
+	registry := metrics.NewRegistry()
+	executor := miner.NewBatchExecutor(backend.BlockChain().Config(), backend.Engine(), miner.NewBatchBackend(backend), miner.BatchExecutorConfig{
+		MetricsRegistry: registry,
+		AuditRate:       conf.Miner.ParallelAuditRate,
+	})
+	backend.Miner().SetParallelBatches(backend.ParallelPool(), executor)
+	executor.Speculate(backend.ParallelPool())
+
 	// Register the filter system
 	filterSystem := filters.NewFilterSystem(backend.APIBackend, filters.Config{})
 	stack.RegisterAPIs([]rpc.API{{
@@ -117,21 +139,28 @@ func newWithNode(stack *node.Node, conf *eth.Config, blockPeriod uint64) (*Backe
 	}})
 	// Start the node
 	if err := stack.Start(); err != nil {
+		executor.Stop()
 		return nil, err
 	}
 	// Set up the simulated beacon
 	beacon, err := catalyst.NewSimulatedBeacon(blockPeriod, backend)
 	if err != nil {
+		executor.Stop()
 		return nil, err
 	}
 	// Reorg our chain back to genesis
 	if err := beacon.Fork(backend.BlockChain().GetCanonicalHash(0)); err != nil {
+		executor.Stop()
 		return nil, err
 	}
+	client := stack.Attach()
 	return &Backend{
-		node:   stack,
-		beacon: beacon,
-		client: simClient{ethclient.NewClient(stack.Attach())},
+		node:     stack,
+		beacon:   beacon,
+		executor: executor,
+		registry: registry,
+		client:   simClient{ethclient.NewClient(client)},
+		parallel: parallelclient.New(client),
 	}, nil
 }
 
@@ -141,6 +170,11 @@ func (n *Backend) Close() error {
 	if n.client.Client != nil {
 		n.client.Close()
 		n.client = simClient{}
+		n.parallel = nil
+	}
+	if n.executor != nil {
+		n.executor.Stop()
+		n.executor = nil
 	}
 	var err error
 	if n.beacon != nil {
@@ -190,3 +224,9 @@ func (n *Backend) AdjustTime(adjustment time.Duration) error {
 func (n *Backend) Client() Client {
 	return n.client
 }
+
+// ParallelClient returns a client that accesses the parallel transaction pool
+// of the simulated chain, e.g. to inspect the batches its transactions form.
+func (n *Backend) ParallelClient() *parallelclient.Client {
+	return n.parallel
+}
@@ -0,0 +1,101 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package simulated
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that parallel transactions sent to the simulated backend are batched by
+// its parallel pool and included in the next block through the parallel path.
+func TestSendParallelTransaction(t *testing.T) {
+	sim := NewBackend(types.GenesisAlloc{
+		testAddr:  {Balance: big.NewInt(params.Ether)},
+		testAddr2: {Balance: big.NewInt(params.Ether)},
+	})
+	defer sim.Close()
+
+	var (
+		client = sim.Client()
+		ctx    = context.Background()
+	)
+	head, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		t.Fatalf("failed to retrieve head: %v", err)
+	}
+	chainID, _ := client.ChainID(ctx)
+	signer := types.LatestSignerForChainID(chainID)
+
+	newParallelTx := func(key *ecdsa.PrivateKey, to common.Address) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.ParallelTx{
+			ChainID:      chainID,
+			GasTipCap:    big.NewInt(params.GWei),
+			GasFeeCap:    new(big.Int).Add(head.BaseFee, big.NewInt(2*params.GWei)),
+			Gas:          params.TxGas,
+			To:           &to,
+			Value:        common.Big1,
+			ParallelType: types.ParallelTypeIndependent,
+		})
+	}
+	txs := []*types.Transaction{
+		newParallelTx(testKey, common.Address{0xaa}),
+		newParallelTx(testKey2, common.Address{0xbb}),
+	}
+	for _, tx := range txs {
+		if err := client.SendTransaction(ctx, tx); err != nil {
+			t.Fatalf("failed to send parallel transaction: %v", err)
+		}
+	}
+	status, err := sim.ParallelClient().Status(ctx)
+	if err != nil {
+		t.Fatalf("failed to retrieve parallel pool status: %v", err)
+	}
+	if status.Parallelizable != len(txs) || status.Batches != 1 {
+		t.Fatalf("parallel pool status mismatch: have %d parallelizable in %d batches, want %d in 1", status.Parallelizable, status.Batches, len(txs))
+	}
+	sim.Commit()
+
+	block, err := client.BlockByNumber(ctx, big.NewInt(1))
+	if err != nil {
+		t.Fatalf("failed to retrieve block: %v", err)
+	}
+	if len(block.Transactions()) != len(txs) {
+		t.Fatalf("block transaction count mismatch: have %d, want %d", len(block.Transactions()), len(txs))
+	}
+	for _, tx := range txs {
+		receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+		if err != nil {
+			t.Fatalf("failed to retrieve receipt of %x: %v", tx.Hash(), err)
+		}
+		if receipt.Status != types.ReceiptStatusSuccessful || receipt.Type != types.ParallelTxType {
+			t.Errorf("receipt of %x mismatch: status %d, type %d", tx.Hash(), receipt.Status, receipt.Type)
+		}
+	}
+	// The block was built out of the batch, accounting the gas it used
+	used := metrics.GetOrRegisterGauge("parallel/block/used", sim.registry)
+	if have, want := used.Snapshot().Value(), int64(len(txs))*int64(params.TxGas); have != want {
+		t.Errorf("batch gas mismatch: have %d, want %d", have, want)
+	}
+}
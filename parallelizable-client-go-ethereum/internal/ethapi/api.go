@@ -195,7 +195,7 @@ func (api *TxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction
 	for account, txs := range pending {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, api.b.ChainConfig())
+			dump[fmt.Sprintf("%d", tx.Nonce())] = api.newPoolTransaction(tx, curHeader)
 		}
 		content["pending"][account.Hex()] = dump
 	}
@@ -203,13 +203,21 @@ func (api *TxPoolAPI) Content() map[string]map[string]map[string]*RPCTransaction
 	for account, txs := range queue {
 		dump := make(map[string]*RPCTransaction)
 		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, api.b.ChainConfig())
+			dump[fmt.Sprintf("%d", tx.Nonce())] = api.newPoolTransaction(tx, curHeader)
 		}
 		content["queued"][account.Hex()] = dump
 	}
 	return content
 }
 
+// newPoolTransaction returns the RPC representation of a pooled transaction,
+// flagging the ones held by the parallel pool.
+func (api *TxPoolAPI) newPoolTransaction(tx *types.Transaction, header *types.Header) *RPCTransaction {
+	rpcTx := NewRPCPendingTransaction(tx, header, api.b.ChainConfig())
+	rpcTx.Parallel = tx.Type() == types.ParallelTxType
+	return rpcTx
+}
+
 // ContentFrom returns the transactions contained within the transaction pool.
 func (api *TxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RPCTransaction {
 	content := make(map[string]map[string]*RPCTransaction, 2)
@@ -219,14 +227,14 @@ func (api *TxPoolAPI) ContentFrom(addr common.Address) map[string]map[string]*RP
 	// Build the pending transactions
 	dump := make(map[string]*RPCTransaction, len(pending))
 	for _, tx := range pending {
-		dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, api.b.ChainConfig())
+		dump[fmt.Sprintf("%d", tx.Nonce())] = api.newPoolTransaction(tx, curHeader)
 	}
 	content["pending"] = dump
 
 	// Build the queued transactions
 	dump = make(map[string]*RPCTransaction, len(queue))
 	for _, tx := range queue {
-		dump[fmt.Sprintf("%d", tx.Nonce())] = NewRPCPendingTransaction(tx, curHeader, api.b.ChainConfig())
+		dump[fmt.Sprintf("%d", tx.Nonce())] = api.newPoolTransaction(tx, curHeader)
 	}
 	content["queued"] = dump
 
@@ -965,6 +973,7 @@ type RPCTransaction struct {
 	R                   *hexutil.Big                 `json:"r"`
 	S                   *hexutil.Big                 `json:"s"`
 	YParity             *hexutil.Uint64              `json:"yParity,omitempty"`
+	Parallel            bool                         `json:"parallel,omitempty"`
 }
 
 // newRPCTransaction returns a transaction that will serialize to the RPC
@@ -1007,7 +1016,7 @@ func newRPCTransaction(tx *types.Transaction, blockHash common.Hash, blockNumber
 		result.ChainID = (*hexutil.Big)(tx.ChainId())
 		result.YParity = &yparity
 
-	case types.DynamicFeeTxType:
+	case types.DynamicFeeTxType, types.ParallelTxType:
 		al := tx.AccessList()
 		yparity := hexutil.Uint64(v.Sign())
 		result.Accesses = &al
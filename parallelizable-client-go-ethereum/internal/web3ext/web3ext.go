@@ -18,15 +18,16 @@
 package web3ext
 
 var Modules = map[string]string{
-	"admin":  AdminJs,
-	"clique": CliqueJs,
-	"debug":  DebugJs,
-	"eth":    EthJs,
-	"miner":  MinerJs,
-	"net":    NetJs,
-	"rpc":    RpcJs,
-	"txpool": TxpoolJs,
-	"dev":    DevJs,
+	"admin":    AdminJs,
+	"clique":   CliqueJs,
+	"debug":    DebugJs,
+	"eth":      EthJs,
+	"miner":    MinerJs,
+	"net":      NetJs,
+	"rpc":      RpcJs,
+	"txpool":   TxpoolJs,
+	"parallel": ParallelJs,
+	"dev":      DevJs,
 }
 
 const CliqueJs = `
@@ -702,6 +703,125 @@ web3._extend({
 });
 `
 
+const ParallelJs = `
+web3._extend({
+	property: 'parallel',
+	methods:
+	[
+		new web3._extend.Method({
+			name: 'contentFrom',
+			call: 'parallel_contentFrom',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'pendingFrom',
+			call: 'parallel_pendingFrom',
+			params: 2,
+			inputFormatter: [null, null],
+		}),
+		new web3._extend.Method({
+			name: 'createAccessList',
+			call: 'parallel_createAccessList',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'createWorkflow',
+			call: 'parallel_createWorkflow',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'estimateBatchGas',
+			call: 'parallel_estimateBatchGas',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'exportPool',
+			call: 'parallel_exportPool',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getBlockSummary',
+			call: 'parallel_getBlockSummary',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getDependents',
+			call: 'parallel_getDependents',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'conflictMatrix',
+			call: 'parallel_conflictMatrix',
+		}),
+		new web3._extend.Method({
+			name: 'getNonceRange',
+			call: 'parallel_getNonceRange',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getTxDiagnostics',
+			call: 'parallel_getTxDiagnostics',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'importPool',
+			call: 'parallel_importPool',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'reserveNonceRange',
+			call: 'parallel_reserveNonceRange',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'sendUserOpBundle',
+			call: 'parallel_sendUserOpBundle',
+			params: 2,
+		}),
+		new web3._extend.Method({
+			name: 'setMode',
+			call: 'parallel_setMode',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'setParallelism',
+			call: 'parallel_setParallelism',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'simulateBatch',
+			call: 'parallel_simulateBatch',
+			params: 2,
+			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'suggestTip',
+			call: 'parallel_suggestTip',
+			params: 1,
+		}),
+	],
+	properties:
+	[
+		new web3._extend.Property({
+			name: 'content',
+			getter: 'parallel_content'
+		}),
+		new web3._extend.Property({
+			name: 'inspect',
+			getter: 'parallel_inspect'
+		}),
+		new web3._extend.Property({
+			name: 'mode',
+			getter: 'parallel_mode'
+		}),
+		new web3._extend.Property({
+			name: 'status',
+			getter: 'parallel_status'
+		}),
+	]
+});
+`
+
 const DevJs = `
 web3._extend({
 	property: 'dev',
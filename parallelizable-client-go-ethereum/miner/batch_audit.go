@@ -0,0 +1,113 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// BlockAudit is the outcome of re-executing a block built out of parallel
+// batches sequentially, which must reproduce the roots of the block exactly.
+type BlockAudit struct {
+	Number      uint64
+	Hash        common.Hash
+	Root        common.Hash // State root of the built block
+	ReceiptHash common.Hash // Receipts root of the built block
+
+	AuditRoot        common.Hash // State root after the sequential re-execution
+	AuditReceiptHash common.Hash // Receipts root of the sequential re-execution
+
+	Err error // Failure to re-execute the block, if any
+}
+
+// Mismatch reports whether the sequential re-execution disagrees with the
+// parallel one.
+func (a *BlockAudit) Mismatch() bool {
+	return a.Err == nil && (a.Root != a.AuditRoot || a.ReceiptHash != a.AuditReceiptHash)
+}
+
+// SubscribeAudits subscribes to the outcomes of the determinism audits.
+func (b *BatchExecutor) SubscribeAudits(ch chan<- BlockAudit) event.Subscription {
+	return b.auditScope.Track(b.auditFeed.Subscribe(ch))
+}
+
+// sampleAudit decides whether a block built out of parallel batches is audited.
+func (b *BatchExecutor) sampleAudit() bool {
+	return b.auditRate > 0 && rand.Float64() < b.auditRate
+}
+
+// auditBlock re-executes a block built out of parallel batches sequentially on
+// top of its parent, comparing the resulting state and receipts roots with the
+// block's. It runs in the background and never holds up block production, the
+// outcome being logged, metered and sent to the audit subscribers.
+func (miner *Miner) auditBlock(block *types.Block, executor *BatchExecutor) {
+	start := time.Now()
+
+	audit := BlockAudit{
+		Number:      block.NumberU64(),
+		Hash:        block.Hash(),
+		Root:        block.Root(),
+		ReceiptHash: block.ReceiptHash(),
+	}
+	audit.AuditRoot, audit.AuditReceiptHash, audit.Err = miner.reexecute(block)
+
+	executor.auditMeter.Mark(1)
+	switch {
+	case audit.Err != nil:
+		executor.auditFailureMeter.Mark(1)
+		log.Warn("Failed to audit parallel block", "number", audit.Number, "hash", audit.Hash, "err", audit.Err)
+	case audit.Mismatch():
+		executor.auditMismatchMeter.Mark(1)
+		log.Error("Parallel block execution is not deterministic", "number", audit.Number, "hash", audit.Hash,
+			"root", audit.Root, "sequential", audit.AuditRoot, "receipts", audit.ReceiptHash, "sequentialReceipts", audit.AuditReceiptHash)
+	default:
+		log.Debug("Audited parallel block", "number", audit.Number, "hash", audit.Hash, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	executor.auditFeed.Send(audit)
+}
+
+// reexecute processes a block sequentially on top of its parent, returning the
+// resulting state and receipts roots.
+func (miner *Miner) reexecute(block *types.Block) (common.Hash, common.Hash, error) {
+	parent := miner.chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	if parent == nil {
+		return common.Hash{}, common.Hash{}, errors.New("parent block unknown")
+	}
+	statedb, err := miner.chain.StateAt(parent.Root)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	// The chain might be set up with a parallel processor, use the sequential
+	// one explicitly
+	processor := core.NewStateProcessor(miner.chainConfig, miner.chain.HeaderChain())
+	result, err := processor.Process(block, statedb, vm.Config{})
+	if err != nil {
+		return common.Hash{}, common.Hash{}, err
+	}
+	root := statedb.IntermediateRoot(miner.chainConfig.IsEIP158(block.Number()))
+	return root, types.DeriveSha(result.Receipts, trie.NewStackTrie(nil)), nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that blocks built out of parallel batches are audited in the background
+// when sampled, and that the audit flags blocks whose roots the sequential
+// re-execution does not reproduce.
+func TestAuditBlocks(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool, err := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{legacypool.New(testTxPoolConfig, chain)})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	txs := make([]*types.Transaction, len(keys))
+	for i, key := range keys {
+		txs[i] = types.MustSignNewTx(key, signer, &types.LegacyTx{To: &common.Address{0xaa, byte(i)}, Value: common.Big1, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	for i, err := range pool.Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	executor := &BatchExecutor{
+		chainConfig:        params.TestChainConfig,
+		chain:              chain,
+		auditRate:          1,
+		exec:               parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		conflictMeter:      metrics.NewMeter(),
+		blockCeilingGauge:  metrics.NewGauge(),
+		blockUsedGauge:     metrics.NewGauge(),
+		criticalPathGauge:  metrics.NewGauge(),
+		overcommitMeter:    metrics.NewMeter(),
+		auditMeter:         metrics.NewMeter(),
+		auditMismatchMeter: metrics.NewMeter(),
+		auditFailureMeter:  metrics.NewMeter(),
+	}
+	audits := make(chan BlockAudit, 1)
+	sub := executor.SubscribeAudits(audits)
+	defer sub.Unsubscribe()
+
+	miner := New(NewMockBackend(chain, pool), testConfig, ethash.NewFaker())
+	miner.SetParallelBatches(testBatchSource{{Transactions: txs, BatchID: 1}}, executor)
+
+	parent := chain.CurrentBlock()
+	result := miner.generateWork(&generateParams{
+		timestamp:   parent.Time + 1,
+		parentHash:  parent.Hash(),
+		coinbase:    common.Address{0x01},
+		withdrawals: types.Withdrawals{},
+	}, false)
+	if result.err != nil {
+		t.Fatalf("failed to build block: %v", result.err)
+	}
+	wait := func() BlockAudit {
+		t.Helper()
+		select {
+		case audit := <-audits:
+			return audit
+		case <-time.After(5 * time.Second):
+			t.Fatalf("block not audited")
+		}
+		return BlockAudit{}
+	}
+	audit := wait()
+	if audit.Hash != result.block.Hash() {
+		t.Errorf("audited block mismatch: have %x, want %x", audit.Hash, result.block.Hash())
+	}
+	if audit.Err != nil || audit.Mismatch() {
+		t.Errorf("parallel block failed the audit: root %x/%x, receipts %x/%x, err %v", audit.Root, audit.AuditRoot, audit.ReceiptHash, audit.AuditReceiptHash, audit.Err)
+	}
+	// A block claiming a different state root must be flagged
+	header := result.block.Header()
+	header.Root = common.Hash{0xff}
+	miner.auditBlock(result.block.WithSeal(header), executor)
+
+	if audit := wait(); !audit.Mismatch() {
+		t.Errorf("tampered block passed the audit")
+	}
+	if have := executor.auditMeter.Snapshot().Count(); have != 2 {
+		t.Errorf("audit count mismatch: have %d, want 2", have)
+	}
+	if have := executor.auditMismatchMeter.Snapshot().Count(); have != 1 {
+		t.Errorf("audit mismatch count mismatch: have %d, want 1", have)
+	}
+}
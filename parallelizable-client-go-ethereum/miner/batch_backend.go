@@ -0,0 +1,122 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+)
+
+// BatchChain is the chain access a batch executor requires: the head to build
+// on along with its state, and the context to execute transactions in.
+type BatchChain interface {
+	core.ChainContext
+
+	CurrentBlock() *types.Header
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	StateAt(root common.Hash) (*state.StateDB, error)
+}
+
+// BatchBackend wraps the methods a batch executor requires from its node. Unlike
+// Backend it does not need a full node, allowing executors to be run against
+// simulated chains.
+type BatchBackend interface {
+	// Chain returns the chain batches are built on top of.
+	Chain() BatchChain
+
+	// SubscribeTransactions subscribes to the transactions to execute.
+	SubscribeTransactions(ch chan<- core.NewTxsEvent) event.Subscription
+}
+
+// nodeBatchBackend is the batch backend of a full node, executing the
+// transactions entering its pool.
+type nodeBatchBackend struct {
+	eth Backend
+}
+
+// NewBatchBackend returns the batch backend of a full node.
+func NewBatchBackend(eth Backend) BatchBackend {
+	return &nodeBatchBackend{eth: eth}
+}
+
+// Chain implements BatchBackend, returning the node's blockchain.
+func (b *nodeBatchBackend) Chain() BatchChain {
+	return b.eth.BlockChain()
+}
+
+// SubscribeTransactions implements BatchBackend, subscribing to the transactions
+// added to the node's pool.
+func (b *nodeBatchBackend) SubscribeTransactions(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.eth.TxPool().SubscribeTransactions(ch, false)
+}
+
+// SimulatedBatchBackend is an in-memory batch backend for integration tests and
+// tooling. It runs a chain from a genesis specification, sealed by the given
+// consensus engine, and hands the transactions sent to it straight to the
+// subscribed executors, without any pool in between.
+type SimulatedBatchBackend struct {
+	chain  *core.BlockChain
+	txFeed event.Feed
+}
+
+// NewSimulatedBatchBackend creates a simulated batch backend on top of the given
+// genesis. A nil engine uses a fake ethash engine accepting any seal.
+func NewSimulatedBatchBackend(genesis *core.Genesis, engine consensus.Engine) (*SimulatedBatchBackend, error) {
+	if engine == nil {
+		engine = ethash.NewFaker()
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, genesis, nil, engine, vm.Config{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &SimulatedBatchBackend{chain: chain}, nil
+}
+
+// Chain implements BatchBackend, returning the simulated chain.
+func (b *SimulatedBatchBackend) Chain() BatchChain {
+	return b.chain
+}
+
+// BlockChain returns the simulated chain, e.g. to insert blocks advancing the
+// head executors build on.
+func (b *SimulatedBatchBackend) BlockChain() *core.BlockChain {
+	return b.chain
+}
+
+// SubscribeTransactions implements BatchBackend, subscribing to the transactions
+// sent to the backend.
+func (b *SimulatedBatchBackend) SubscribeTransactions(ch chan<- core.NewTxsEvent) event.Subscription {
+	return b.txFeed.Subscribe(ch)
+}
+
+// Send hands transactions to the subscribed executors as if they entered the
+// pool, returning the number of subscribers reached.
+func (b *SimulatedBatchBackend) Send(txs []*types.Transaction) int {
+	return b.txFeed.Send(core.NewTxsEvent{Txs: txs})
+}
+
+// Close stops the simulated chain.
+func (b *SimulatedBatchBackend) Close() {
+	b.chain.Stop()
+}
@@ -0,0 +1,83 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that a batch executor runs against a simulated backend end to end,
+// executing the transactions sent to it on top of the simulated head.
+func TestSimulatedBatchBackend(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	backend, err := NewSimulatedBatchBackend(&core.Genesis{Config: params.TestChainConfig, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}, nil)
+	if err != nil {
+		t.Fatalf("failed to create simulated backend: %v", err)
+	}
+	defer backend.Close()
+
+	executor := NewBatchExecutor(params.TestChainConfig, backend.Chain().Engine(), backend, BatchExecutorConfig{
+		MetricsRegistry: metrics.NewRegistry(),
+		Optimistic:      true,
+	})
+	defer executor.Stop()
+
+	db := rawdb.NewMemoryDatabase()
+	executor.SetSummaryDatabase(db)
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	txs := make([]*types.Transaction, len(keys))
+	for i, key := range keys {
+		txs[i] = types.MustSignNewTx(key, signer, &types.LegacyTx{To: &common.Address{byte(i + 1)}, Value: common.Big1, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	if n := backend.Send(txs); n != 1 {
+		t.Fatalf("subscriber count mismatch: have %d, want 1", n)
+	}
+	// The summary of the block built on top of the genesis appears once the
+	// executor processed the transactions
+	genesis := backend.Chain().CurrentBlock()
+	header := &types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash()}
+
+	var summary *BlockSummary
+	for deadline := time.Now().Add(5 * time.Second); summary == nil && time.Now().Before(deadline); {
+		if summary = ReadBlockSummary(db, header); summary == nil {
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	if summary == nil {
+		t.Fatalf("no block summary recorded")
+	}
+	if summary.ParallelTxs != 2 || summary.Batches != 1 {
+		t.Errorf("summary mismatch: have %d parallel txs in %d batches, want 2 in 1", summary.ParallelTxs, summary.Batches)
+	}
+}
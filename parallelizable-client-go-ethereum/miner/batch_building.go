@@ -0,0 +1,218 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// BatchSource provides the batches of mutually independent transactions blocks
+// are built out of in parallel mode, such as the parallel transaction pool.
+type BatchSource interface {
+	GetBatches() []parallelpool.TxBatch
+}
+
+// commitBatches includes the batches of the batch source in the block being
+// built ahead of the sequential transactions, executing the members of each
+// batch concurrently. Only members found in the pending set are considered, so
+// batches are subject to the same price and nonce filters as any transaction.
+// Batches are visited in dependency level order, and the first one with members
+// failing on top of the transactions included so far, conflicting with each
+// other or not fitting the block ends the parallel part: those members and the
+// remaining batches are left to sequential inclusion, as later batches may
+// depend on them. The hashes of the included transactions are returned.
+func (miner *Miner) commitBatches(env *environment, source BatchSource, executor *BatchExecutor, pending map[common.Address][]*txpool.LazyTransaction, interrupt *atomic.Int32) (map[common.Hash]struct{}, error) {
+	included := make(map[common.Hash]struct{})
+
+	// Receipts of parallel executions are assembled without intermediate roots
+	if !miner.chainConfig.IsByzantium(env.header.Number) {
+		return included, nil
+	}
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+	}
+	executable := make(map[common.Hash]struct{})
+	for _, txs := range pending {
+		for _, ltx := range txs {
+			executable[ltx.Hash] = struct{}{}
+		}
+	}
+	// Let speculation guess the context of the next blocks from this one
+	executor.observe(env, miner.chain.GetHeader(env.header.ParentHash, env.header.Number.Uint64()-1))
+
+	batches := source.GetBatches()
+	slices.SortStableFunc(batches, func(a, b parallelpool.TxBatch) int { return a.Level - b.Level })
+
+	for _, batch := range batches {
+		// Check interruption signal and abort building if it's fired.
+		if interrupt != nil {
+			if signal := interrupt.Load(); signal != commitInterruptNone {
+				return included, signalToErr(signal)
+			}
+		}
+		var txs []*types.Transaction
+		for _, tx := range batch.Transactions {
+			if _, ok := executable[tx.Hash()]; ok {
+				txs = append(txs, tx)
+			}
+		}
+		if len(txs) == 0 {
+			continue
+		}
+		committed, fallback, report := executor.commitBatch(env, batch.BatchID, txs)
+		for _, tx := range committed {
+			included[tx.Hash()] = struct{}{}
+		}
+		if len(committed) > 0 {
+			report.id, report.level = batch.BatchID, batch.Level
+			env.batchGas = append(env.batchGas, report)
+		}
+		if len(fallback) > 0 {
+			log.Debug("Falling back to sequential inclusion", "batch", batch.BatchID, "level", batch.Level, "committed", len(committed), "fallback", len(fallback))
+			break
+		}
+	}
+	return included, nil
+}
+
+// commitBatch executes a batch of independent transactions concurrently, each on
+// its own copy of the state of the block being built, and commits them in batch
+// order up to the first one that failed or conflicts with an earlier member.
+// Block gas is reserved for the batch up front and the unused reservation is
+// returned afterwards. Footprints simulated by the pool may not match execution
+// on the block, so any member reading or writing a location written by an
+// earlier one is treated as conflicting. The committed transactions are
+// returned along with the ones left to sequential inclusion and the gas
+// accounting of the batch. Members pre-executed speculatively for the block are
+// not executed again, their cached executions are merged instead.
+func (b *BatchExecutor) commitBatch(env *environment, id uint64, txs []*types.Transaction) (committed, fallback []*types.Transaction, report batchGasReport) {
+	batch, deferred, reserved := reserveBatch(txs, env.gasPool.Gas())
+	if len(batch) == 0 {
+		return nil, deferred, report
+	}
+	report.start, report.ceiling = env.header.GasUsed, reserved
+	env.gasPool.SubGas(reserved)
+
+	var (
+		adapter = core.NewExecAdapter(b.chainConfig, b.chain, env.header, &env.coinbase)
+		copies  = make([]*state.StateDB, len(batch))
+		diffs   = make([]*stateDiff, len(batch))
+		evms    = make([]*vm.EVM, len(batch))
+		results = make([]*core.ExecutionResult, len(batch))
+		errs    = make([]error, len(batch))
+		wg      sync.WaitGroup
+		start   = time.Now()
+	)
+	entry, execs := b.speculated(env, id, batch)
+	if entry != nil {
+		defer entry.lock.Unlock()
+		for i, exec := range execs {
+			copies[i], diffs[i], evms[i], results[i], errs[i] = exec.state, exec.diff, exec.evm, exec.result, exec.err
+		}
+	} else {
+		for i, tx := range batch {
+			copies[i], diffs[i] = env.state.Copy(), newStateDiff()
+
+			wg.Add(1)
+			index := i
+			b.workers.Go(func() {
+				defer wg.Done()
+
+				txStart := time.Now()
+				errs[index] = b.workers.Guard(tx, func() (err error) {
+					msg, err := adapter.Message(tx)
+					if err != nil {
+						return err
+					}
+					copies[index].SetTxContext(tx.Hash(), env.tcount+index)
+					evms[index] = adapter.NewEVM(copies[index], diffs[index].hooks())
+					results[index], err = core.ApplyMessage(evms[index], msg, new(core.GasPool).AddGas(tx.Gas()))
+					return err
+				})
+				b.exec.UpdateTx(time.Since(txStart))
+			})
+		}
+		wg.Wait()
+	}
+
+	// Merge the state diffs in batch order up to the conflict edge, assembling
+	// the receipts as if the members were applied one after the other
+	var (
+		merger    = newBatchMerger(env.state, env.coinbase)
+		used      uint64
+		conflicts int
+	)
+	for i, tx := range batch {
+		if errs[i] != nil {
+			log.Debug("Parallel block transaction failed", "hash", tx.Hash(), "err", errs[i])
+			break
+		}
+		if conflict := merger.conflict(diffs[i]); conflict != nil {
+			log.Trace("Parallel block transaction conflicts", "hash", tx.Hash(), "counterparty", conflict.Counterparty)
+			b.reportConflict(tx.Hash(), conflict)
+			conflicts++
+			break
+		}
+		merger.merge(tx.Hash(), diffs[i], copies[i])
+
+		env.state.SetTxContext(tx.Hash(), env.tcount)
+		for _, l := range copies[i].GetLogs(tx.Hash(), 0, common.Hash{}) {
+			// Cached logs are shared by every block built on the head
+			if entry != nil {
+				cpy := *l
+				l = &cpy
+			}
+			env.state.AddLog(l)
+		}
+		env.state.Finalise(true)
+
+		env.header.GasUsed += results[i].UsedGas
+		receipt := core.MakeReceipt(evms[i], results[i], env.state, env.header.Number, env.header.Hash(), tx, env.header.GasUsed, nil)
+
+		env.txs = append(env.txs, tx)
+		env.receipts = append(env.receipts, receipt)
+		env.tcount++
+		used += results[i].UsedGas
+		committed = append(committed, tx)
+
+		if report.critical == (common.Hash{}) || results[i].UsedGas > report.criticalGas {
+			report.critical, report.criticalGas = tx.Hash(), results[i].UsedGas
+		}
+	}
+	env.gasPool.AddGas(reserved - used)
+	report.txs, report.used = len(committed), used
+
+	if aborted := len(batch) - len(committed); aborted > 0 {
+		b.conflictMeter.Mark(int64(aborted))
+	}
+	// The members from the conflict edge on are left to sequential inclusion
+	// rather than re-executed here
+	b.exec.UpdateBatch(time.Since(start), conflicts, 0)
+	return committed, append(batch[len(committed):], deferred...), report
+}
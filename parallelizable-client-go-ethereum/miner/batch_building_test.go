@@ -0,0 +1,237 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testBatchSource is a batch source serving a fixed set of batches.
+type testBatchSource []parallelpool.TxBatch
+
+func (s testBatchSource) GetBatches() []parallelpool.TxBatch { return s }
+
+// testReportingSource is a batch source recording the conflicts reported on its
+// transactions, like the parallel pool.
+type testReportingSource struct {
+	testBatchSource
+	*testConflictReporter
+}
+
+// Tests that blocks are built out of parallel batches up to the first conflict,
+// the conflicting member and the later batches falling back to sequential
+// inclusion, that the batch gas is accounted and that the resulting block is
+// valid.
+func TestCommitBatches(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool, err := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{legacypool.New(testTxPoolConfig, chain)})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	send := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &to, Value: common.Big1, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	// The third transfer conflicts with the first one, ending the parallel part
+	// before the second batch
+	txs := []*types.Transaction{
+		send(keys[0], 0, common.Address{0xaa}),
+		send(keys[1], 0, common.Address{0xbb}),
+		send(keys[2], 0, common.Address{0xaa}),
+		send(keys[0], 1, common.Address{0xcc}),
+	}
+	for i, err := range pool.Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	executor := &BatchExecutor{
+		chainConfig:       params.TestChainConfig,
+		chain:             chain,
+		exec:              parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		conflictMeter:     metrics.NewMeter(),
+		blockCeilingGauge: metrics.NewGauge(),
+		blockUsedGauge:    metrics.NewGauge(),
+		criticalPathGauge: metrics.NewGauge(),
+		overcommitMeter:   metrics.NewMeter(),
+	}
+	miner := New(NewMockBackend(chain, pool), testConfig, ethash.NewFaker())
+	miner.SetParallelBatches(testBatchSource{
+		{Transactions: txs[:3], BatchID: 1},
+		{Transactions: txs[3:], BatchID: 2, Level: 1},
+	}, executor)
+
+	parent := chain.CurrentBlock()
+	result := miner.generateWork(&generateParams{
+		timestamp:   parent.Time + 1,
+		parentHash:  parent.Hash(),
+		coinbase:    common.Address{0x01},
+		withdrawals: types.Withdrawals{},
+	}, false)
+	if result.err != nil {
+		t.Fatalf("failed to build block: %v", result.err)
+	}
+	included := result.block.Transactions()
+	if len(included) != len(txs) {
+		t.Fatalf("included transaction count mismatch: have %d, want %d", len(included), len(txs))
+	}
+	for i, tx := range txs[:2] {
+		if included[i].Hash() != tx.Hash() {
+			t.Errorf("transaction %d not included in parallel", i)
+		}
+	}
+	if have := executor.conflictMeter.Snapshot().Count(); have != 1 {
+		t.Errorf("conflict meter mismatch: have %d, want 1", have)
+	}
+	// The whole batch reserved gas, but only the committed members used it
+	if have, want := executor.blockCeilingGauge.Snapshot().Value(), int64(3*params.TxGas); have != want {
+		t.Errorf("ceiling gauge mismatch: have %d, want %d", have, want)
+	}
+	if have, want := executor.blockUsedGauge.Snapshot().Value(), int64(2*params.TxGas); have != want {
+		t.Errorf("used gauge mismatch: have %d, want %d", have, want)
+	}
+	if have, want := executor.criticalPathGauge.Snapshot().Value(), int64(params.TxGas); have != want {
+		t.Errorf("critical path gauge mismatch: have %d, want %d", have, want)
+	}
+	if have := executor.overcommitMeter.Snapshot().Count(); have != 0 {
+		t.Errorf("overcommit meter mismatch: have %d, want 0", have)
+	}
+	// The block must pass full validation, state root and receipts included
+	if _, err := chain.InsertChain(types.Blocks{result.block}); err != nil {
+		t.Fatalf("built block invalid: %v", err)
+	}
+}
+
+// Tests that a batch member reading a storage slot written by an earlier member
+// is left to sequential inclusion, so that the block matches the sequential
+// execution of its transactions.
+func TestCommitBatchReadConflict(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	// Without calldata the contract stores 1 in slot 0, with calldata it copies
+	// slot 0 into slot 1
+	contract := common.Address{0xcc}
+	alloc[contract] = types.Account{Code: common.FromHex("0x36600a576001600055005b60005460015500")}
+
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool, err := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{legacypool.New(testTxPoolConfig, chain)})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	call := func(key *ecdsa.PrivateKey, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{To: &contract, Gas: 100000, GasPrice: big.NewInt(2 * params.InitialBaseFee), Data: data})
+	}
+	txs := []*types.Transaction{
+		call(keys[0], nil),
+		call(keys[1], []byte{0x01}),
+	}
+	for i, err := range pool.Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	executor := &BatchExecutor{
+		chainConfig:       params.TestChainConfig,
+		chain:             chain,
+		exec:              parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		conflictMeter:     metrics.NewMeter(),
+		blockCeilingGauge: metrics.NewGauge(),
+		blockUsedGauge:    metrics.NewGauge(),
+		criticalPathGauge: metrics.NewGauge(),
+		overcommitMeter:   metrics.NewMeter(),
+	}
+	miner := New(NewMockBackend(chain, pool), testConfig, ethash.NewFaker())
+	reporter := new(testConflictReporter)
+	miner.SetParallelBatches(testReportingSource{testBatchSource{{Transactions: txs, BatchID: 1}}, reporter}, executor)
+
+	parent := chain.CurrentBlock()
+	result := miner.generateWork(&generateParams{
+		timestamp:   parent.Time + 1,
+		parentHash:  parent.Hash(),
+		coinbase:    common.Address{0x01},
+		withdrawals: types.Withdrawals{},
+	}, false)
+	if result.err != nil {
+		t.Fatalf("failed to build block: %v", result.err)
+	}
+	if have := len(result.block.Transactions()); have != len(txs) {
+		t.Fatalf("included transaction count mismatch: have %d, want %d", have, len(txs))
+	}
+	if have := executor.conflictMeter.Snapshot().Count(); have != 1 {
+		t.Errorf("conflict meter mismatch: have %d, want 1", have)
+	}
+	// The source is told which member aborted the second one, and where
+	reports := reporter.reports[txs[1].Hash()]
+	if len(reports) != 1 || reports[0].Action != parallelpool.ConflictAborted || reports[0].Counterparty != txs[0].Hash() {
+		t.Errorf("conflict reports mismatch: have %+v, want aborted by %x", reports, txs[0].Hash())
+	} else if reports[0].Slot == nil || *reports[0].Slot != (common.Hash{}) {
+		t.Errorf("conflict slot mismatch: have %v, want slot 0", reports[0].Slot)
+	}
+	// Importing re-executes the block sequentially, checking its state root
+	if _, err := chain.InsertChain(types.Blocks{result.block}); err != nil {
+		t.Fatalf("built block invalid: %v", err)
+	}
+	statedb, err := chain.State()
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	if have, want := statedb.GetState(contract, common.BigToHash(common.Big1)), common.BigToHash(common.Big1); have != want {
+		t.Errorf("slot 1 mismatch: have %x, want %x", have, want)
+	}
+}
@@ -17,29 +17,110 @@
 package miner
 
 import (
+	"crypto/ecdsa"
 	"sync"
 	"time"
 
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
 	"github.com/ethereum/go-ethereum/params"
 )
 
+// BatchRefundPolicy decides what happens to block gas reserved for a parallel
+// batch but left unused once the batch finishes executing.
+type BatchRefundPolicy uint8
+
+const (
+	// RefundDiscard wastes unused batch reservations for the block.
+	RefundDiscard BatchRefundPolicy = iota
+
+	// RefundSequential returns unused batch reservations to the block, so they
+	// can be backfilled with sequential transactions.
+	RefundSequential
+
+	// RefundBatch returns unused batch reservations to the block and, after
+	// backfilling sequential transactions, packs parallel transactions that did
+	// not fit the first batch into an additional small batch.
+	RefundBatch
+)
+
+// maxBatchSplits is the maximum number of times a parallel batch is split on its
+// conflict edge before the remainder is executed serially.
+const maxBatchSplits = 16
+
+// defaultBatchMetricsNamespace is the prefix of the metrics of batch executors
+// created without an explicit namespace.
+const defaultBatchMetricsNamespace = "parallel"
+
+// BatchExecutorConfig are the per-instance settings of a batch executor. Each
+// chain handled by the process runs its own executor, so their metrics must be
+// kept apart.
+type BatchExecutorConfig struct {
+	MetricsNamespace string           // Prefix of the executor metrics (default "parallel")
+	MetricsRegistry  metrics.Registry // Registry for the executor metrics (nil = default)
+
+	// ExecMetricsNamespace is the pool namespace the execution latency and
+	// conflict histograms are registered under, shared with the parallel pool
+	// of the chain. Defaults to "parallel/txpool".
+	ExecMetricsNamespace string
+
+	// Optimistic executes all transactions with optimistic concurrency control,
+	// deriving their parallelism from the state accessed during execution
+	// instead of trusting their tags.
+	Optimistic bool
+
+	// MaxParallelism is the number of workers executing the members of batches,
+	// shared by all batches of a block. Zero uses the number of CPUs.
+	MaxParallelism int
+
+	// SequencerKey enables sequencer mode, signing a replayable log of the batch
+	// composition and ordering decisions of every block built. The logs are
+	// persisted to the summary database.
+	SequencerKey *ecdsa.PrivateKey
+
+	// AuditRate is the fraction of the blocks built out of parallel batches that
+	// are re-executed sequentially in the background, checking that they yield
+	// the same state and receipts roots. Zero disables audits, one audits every
+	// block.
+	AuditRate float64
+}
+
+// ConflictReporter receives the conflicts aborting the parallel execution of batch
+// members, such as the parallel transaction pool explaining them to senders.
+type ConflictReporter interface {
+	ReportConflict(hash common.Hash, report parallelpool.ConflictReport)
+}
+
 // BatchExecutor handles the execution of transaction batches in parallel
 type BatchExecutor struct {
 	config      *params.ChainConfig
 	chainConfig *params.ChainConfig
 	engine      consensus.Engine
-	eth         core.Backend
-	chain       *core.BlockChain
+	eth         BatchBackend
+	chain       BatchChain
+
+	gasFloor     uint64
+	gasCeil      uint64
+	refundPolicy BatchRefundPolicy
+	optimistic   bool              // Whether batches are executed with optimistic concurrency control
+	sequencerKey *ecdsa.PrivateKey // Key signing the ordering logs in sequencer mode (optional)
+	inclusion    *inclusionList    // Inclusion list of the block being built (optional)
+	auditRate    float64           // Fraction of the built blocks re-executed sequentially
 
-	gasFloor uint64
-	gasCeil  uint64
+	workers *parallelpool.WorkerPool // Workers executing the members of batches
+
+	summaryDB ethdb.KeyValueStore // Database to persist block summaries to (optional)
+	reporter  ConflictReporter    // Receiver of the conflicts aborting batch members (optional)
+	summary   *BlockSummary       // Summary of the block being built, owned by the processing loop
 
 	mu sync.RWMutex
 
@@ -48,30 +129,95 @@ type BatchExecutor struct {
 	txsCh  chan core.NewTxsEvent
 	txsSub event.Subscription
 
+	auditFeed  event.Feed // Outcomes of the determinism audits
+	auditScope event.SubscriptionScope
+
+	// Speculative pre-execution
+	speculative *speculativeCache    // Batches pre-executed on the current head (nil = speculation off)
+	specSub     event.Subscription   // Subscription to the resets of the speculative source
+	template    *speculationTemplate // Block context of the last block built with batches
+
 	// Metrics
-	batchGauge       metrics.Gauge
-	execTimeGauge    metrics.Gauge
-	txCountGauge     metrics.Gauge
-	successRateGauge metrics.Gauge
+	batchGauge       *metrics.Gauge
+	execTimeGauge    *metrics.Gauge
+	txCountGauge     *metrics.Gauge
+	successRateGauge *metrics.Gauge
+	conflictMeter    *metrics.Meter // Executions discarded due to read or write conflicts
+	panicMeter       *metrics.Meter // Executions failed by a recovered panic
+
+	blockCeilingGauge *metrics.Gauge // Block gas reserved by the batches of the last built block
+	blockUsedGauge    *metrics.Gauge // Block gas used by the batches of the last built block
+	criticalPathGauge *metrics.Gauge // Gas of the critical path through the batches of the last built block
+	overcommitMeter   *metrics.Meter // Batches reserved more gas than their block had left
+
+	auditMeter         *metrics.Meter // Blocks re-executed sequentially by the determinism audit
+	auditMismatchMeter *metrics.Meter // Audited blocks whose sequential roots differ
+	auditFailureMeter  *metrics.Meter // Audited blocks that failed to re-execute
+
+	specTxMeter   *metrics.Meter // Batch members pre-executed speculatively
+	specHitMeter  *metrics.Meter // Batches committed from their speculative executions
+	specMissMeter *metrics.Meter // Batches re-executed for lack of usable speculative executions
+
+	exec *parallelpool.ExecMetrics // Latency and conflict histograms shared with the pool
 }
 
-// NewBatchExecutor creates a new batch executor for parallel transaction processing
-func NewBatchExecutor(chainConfig *params.ChainConfig, engine consensus.Engine, eth core.Backend) *BatchExecutor {
+// NewBatchExecutor creates a new batch executor for parallel transaction processing.
+// Full nodes pass the backend returned by NewBatchBackend, simulations may use a
+// SimulatedBatchBackend.
+func NewBatchExecutor(chainConfig *params.ChainConfig, engine consensus.Engine, eth BatchBackend, config BatchExecutorConfig) *BatchExecutor {
+	namespace, registry := config.MetricsNamespace, config.MetricsRegistry
+	if namespace == "" {
+		namespace = defaultBatchMetricsNamespace
+	}
+	if registry == nil {
+		registry = metrics.DefaultRegistry
+	}
+	workers := config.MaxParallelism
+	if workers <= 0 {
+		workers = parallelpool.DefaultParallelism()
+	}
 	executor := &BatchExecutor{
 		config:           chainConfig,
 		chainConfig:      chainConfig,
 		engine:           engine,
 		eth:              eth,
-		chain:            eth.BlockChain(),
+		chain:            eth.Chain(),
 		txsCh:            make(chan core.NewTxsEvent, 4096),
-		batchGauge:       metrics.NewRegisteredGauge("parallel/batches", nil),
-		execTimeGauge:    metrics.NewRegisteredGauge("parallel/exectime", nil),
-		txCountGauge:     metrics.NewRegisteredGauge("parallel/txcount", nil),
-		successRateGauge: metrics.NewRegisteredGauge("parallel/successrate", nil),
+		refundPolicy:     RefundBatch,
+		optimistic:       config.Optimistic,
+		sequencerKey:     config.SequencerKey,
+		auditRate:        config.AuditRate,
+		workers:          parallelpool.NewWorkerPool(workers),
+		batchGauge:       metrics.GetOrRegisterGauge(namespace+"/batches", registry),
+		execTimeGauge:    metrics.GetOrRegisterGauge(namespace+"/exectime", registry),
+		txCountGauge:     metrics.GetOrRegisterGauge(namespace+"/txcount", registry),
+		successRateGauge: metrics.GetOrRegisterGauge(namespace+"/successrate", registry),
+		conflictMeter:    metrics.GetOrRegisterMeter(namespace+"/batch/conflicts", registry),
+		panicMeter:       metrics.GetOrRegisterMeter(namespace+"/batch/panics", registry),
+
+		blockCeilingGauge: metrics.GetOrRegisterGauge(namespace+"/block/ceiling", registry),
+		blockUsedGauge:    metrics.GetOrRegisterGauge(namespace+"/block/used", registry),
+		criticalPathGauge: metrics.GetOrRegisterGauge(namespace+"/block/criticalpath", registry),
+		overcommitMeter:   metrics.GetOrRegisterMeter(namespace+"/block/overcommits", registry),
+
+		auditMeter:         metrics.GetOrRegisterMeter(namespace+"/audit/blocks", registry),
+		auditMismatchMeter: metrics.GetOrRegisterMeter(namespace+"/audit/mismatches", registry),
+		auditFailureMeter:  metrics.GetOrRegisterMeter(namespace+"/audit/failures", registry),
+
+		specTxMeter:   metrics.GetOrRegisterMeter(namespace+"/speculation/txs", registry),
+		specHitMeter:  metrics.GetOrRegisterMeter(namespace+"/speculation/hits", registry),
+		specMissMeter: metrics.GetOrRegisterMeter(namespace+"/speculation/misses", registry),
+
+		exec: parallelpool.NewExecMetrics(config.ExecMetricsNamespace, registry),
 	}
 
+	// Fail panicking executions on their own instead of crashing the node
+	executor.workers.SetPanicHandler(func(*types.Transaction, *parallelpool.ExecutionPanic) {
+		executor.panicMeter.Mark(1)
+	})
+
 	// Subscribe to transaction pool events
-	executor.txsSub = eth.TxPool().SubscribeNewTxsEvent(executor.txsCh)
+	executor.txsSub = eth.SubscribeTransactions(executor.txsCh)
 
 	// Start the batch processing
 	go executor.processTransactions()
@@ -109,39 +255,116 @@ func (b *BatchExecutor) processBatch(txs []*types.Transaction) {
 
 	// Get current state
 	parent := b.chain.CurrentBlock()
-	statedb, err := b.chain.StateAt(parent.Root())
+	statedb, err := b.chain.StateAt(parent.Root)
 	if err != nil {
 		log.Error("Failed to get state for batch execution", "err", err)
 		return
 	}
 
-	// Organize transactions by whether they are parallelizable
+	b.mu.RLock()
+	policy, summaryDB, inclusion := b.refundPolicy, b.summaryDB, b.inclusion
+	b.mu.RUnlock()
+
+	// Transactions of the inclusion list of the block go first, so that they
+	// are reserved block gas before any other
+	inclusions := newInclusionTracker(nil)
+	if inclusion != nil && inclusion.parent == parent.Hash() {
+		inclusions = newInclusionTracker(inclusion.txs)
+		txs = inclusions.prepend(txs)
+	}
+	// Organize transactions by whether they are parallelizable. Optimistic
+	// execution detects conflicts itself, so tags are not needed to batch.
 	var parallelTxs, sequentialTxs []*types.Transaction
 	for _, tx := range txs {
-		// Check transaction data for parallelization tag
-		// Simple tag detection (in a real implementation, would use proper tag extraction)
-		txData := tx.Data()
-		isParallelizable := false
-		if len(txData) > 8 {
-			tag := string(txData[:8])
-			isParallelizable = (tag == "PARALLEL")
-		}
-
-		if isParallelizable {
+		// Route by the typed parallel metadata, or the legacy calldata tag
+		if tag, _ := parallelpool.TxTag(tx); b.optimistic || tag == parallelpool.ParallelizableTag {
 			parallelTxs = append(parallelTxs, tx)
 		} else {
 			sequentialTxs = append(sequentialTxs, tx)
 		}
 	}
 
-	// Process parallel transactions
-	if len(parallelTxs) > 0 {
-		b.executeParallelBatch(parallelTxs, statedb)
+	// Start a new summary whenever building on top of a new parent
+	if b.summary == nil || b.summary.ParentHash != parent.Hash() {
+		b.summary = &BlockSummary{
+			Number:     hexutil.Uint64(parent.Number.Uint64() + 1),
+			ParentHash: parent.Hash(),
+		}
 	}
-
-	// Process sequential transactions
+	// In sequencer mode, log the ordering decisions of this run. Every run
+	// rebuilds the block on top of the parent, replacing the earlier log.
+	var (
+		ordering    *OrderingLog
+		deleteEmpty = b.chainConfig.IsEIP158(parent.Number)
+	)
+	if b.sequencerKey != nil {
+		ordering = &OrderingLog{
+			Number:     hexutil.Uint64(parent.Number.Uint64() + 1),
+			ParentHash: parent.Hash(),
+		}
+	}
+	// Reserve block gas for the parallel batch up front, deferring whatever
+	// does not fit, and redistribute the unused reservation afterwards
+	gp := new(core.GasPool).AddGas(parent.GasLimit)
+	batch, deferred, reserved := reserveBatch(parallelTxs, gp.Gas())
+	if len(batch) > 0 {
+		gp.SubGas(reserved)
+		stats := b.executeBatch(batch, statedb)
+		if policy != RefundDiscard {
+			gp.AddGas(reserved - stats.used)
+		}
+		inclusions.batched(uint64(b.summary.Batches), stats.order)
+		b.summary.addBatch(stats)
+		if ordering != nil {
+			ordering.addStep(true, stats.order, statedb, deleteEmpty)
+		}
+	}
+	// Listed transactions the batch did not commit, whether they failed or
+	// did not fit, are retried at the head of the sequential tail
+	missing, deferred := inclusions.split(deferred)
+	if failed, _ := inclusions.split(batch); len(failed)+len(missing) > 0 {
+		sequentialTxs = append(append(failed, missing...), sequentialTxs...)
+	}
+	// Second packing pass: backfill the remaining block gas with sequential
+	// transactions and, if allowed, an additional small batch
 	if len(sequentialTxs) > 0 {
-		b.executeSequentialBatch(sequentialTxs, statedb)
+		executed := b.executeSequentialBatch(sequentialTxs, statedb, gp)
+		inclusions.sequenced(executed)
+		b.summary.SequentialTxs += hexutil.Uint64(len(executed))
+		if ordering != nil {
+			ordering.addStep(false, executed, statedb, deleteEmpty)
+		}
+	}
+	if policy == RefundBatch && len(deferred) > 0 {
+		if batch, _, reserved := reserveBatch(deferred, gp.Gas()); len(batch) > 0 {
+			log.Debug("Backfilling block gas with additional batch", "txs", len(batch), "reserved", reserved, "available", gp.Gas())
+			gp.SubGas(reserved)
+			stats := b.executeBatch(batch, statedb)
+			gp.AddGas(reserved - stats.used)
+			inclusions.batched(uint64(b.summary.Batches), stats.order)
+			b.summary.addBatch(stats)
+			if ordering != nil {
+				ordering.addStep(true, stats.order, statedb, deleteEmpty)
+			}
+		}
+	}
+	b.summary.Inclusions = inclusions.report()
+	for _, status := range b.summary.Inclusions {
+		if !status.Included {
+			log.Warn("Inclusion list entry not satisfied", "number", b.summary.Number, "hash", status.Hash)
+		}
+	}
+	if ordering != nil {
+		if err := ordering.Sign(b.sequencerKey); err != nil {
+			log.Error("Failed to sign parallel ordering log", "number", ordering.Number, "err", err)
+			ordering = nil
+		}
+	}
+	if summaryDB != nil {
+		writeBlockSummary(summaryDB, b.summary)
+		if ordering != nil {
+			writeOrderingLog(summaryDB, ordering)
+		}
 	}
 
 	// Update execution time metric
@@ -149,118 +372,234 @@ func (b *BatchExecutor) processBatch(txs []*types.Transaction) {
 	b.execTimeGauge.Update(int64(execTime))
 }
 
-// executeParallelBatch executes a batch of parallelizable transactions concurrently
-func (b *BatchExecutor) executeParallelBatch(txs []*types.Transaction, statedb *state.StateDB) {
-	// Create a copy of the state for each transaction
+// reserveBatch selects, in order, the transactions whose gas limits fit into the
+// available block gas, returning them along with the deferred remainder and the
+// total gas reserved.
+func reserveBatch(txs []*types.Transaction, available uint64) (batch, deferred []*types.Transaction, reserved uint64) {
+	for _, tx := range txs {
+		if tx.Gas() > available-reserved {
+			deferred = append(deferred, tx)
+			continue
+		}
+		reserved += tx.Gas()
+		batch = append(batch, tx)
+	}
+	return batch, deferred, reserved
+}
+
+// executeBatch executes a batch of parallel transactions with the configured
+// concurrency control.
+func (b *BatchExecutor) executeBatch(txs []*types.Transaction, statedb *state.StateDB) batchStats {
+	var stats batchStats
+	if b.optimistic {
+		stats = b.executeOptimisticBatch(txs, statedb)
+	} else {
+		stats = b.executeParallelBatch(txs, statedb)
+	}
+	b.exec.UpdateBatch(stats.wall, stats.conflicts, stats.reexecutions)
+	return stats
+}
+
+// executeParallelBatch executes a batch of parallelizable transactions
+// concurrently, each on its own copy of the state, and merges their state diffs
+// into the given state in batch order. The first transaction that failed or
+// conflicts with an earlier member marks the conflict edge: the batch is split
+// there, the conflict-free half before the edge is kept and the half from the
+// edge on is resubmitted as a new batch on top of the merged state. Splitting
+// commits at least one transaction every time, converging to conflict-free
+// sub-batches instead of degrading to sequential execution. The gas used by
+// the successful transactions is returned along with the execution measurements.
+func (b *BatchExecutor) executeParallelBatch(txs []*types.Transaction, statedb *state.StateDB) batchStats {
+	var (
+		adapter = core.NewPendingExecAdapter(b.chainConfig, b.chain, b.chain.CurrentBlock())
+		stats   batchStats
+		start   = time.Now()
+	)
+	for offset, splits := 0, 0; offset < len(txs); splits++ {
+		// Bound the work wasted on heavily contended batches, executing their
+		// remainder serially
+		if splits == maxBatchSplits {
+			log.Debug("Executing contended batch remainder serially", "txs", len(txs)-offset, "batch", len(txs))
+			for i := offset; i < len(txs); i++ {
+				b.executeSerially(adapter, txs[i], i, statedb, &stats)
+			}
+			break
+		}
+		offset += b.executeSubBatch(adapter, txs[offset:], offset, statedb, &stats)
+		if offset < len(txs) {
+			log.Trace("Splitting parallel batch on conflict", "edge", offset, "resubmitted", len(txs)-offset, "batch", len(txs))
+			stats.aborted += len(txs) - offset
+			stats.reexecutions += len(txs) - offset
+			b.conflictMeter.Mark(int64(len(txs) - offset))
+		}
+	}
+	stats.wall = time.Since(start)
+
+	// Update success rate metric
+	if len(txs) > 0 {
+		successRate := (float64(stats.executed) / float64(len(txs))) * 100
+		b.successRateGauge.Update(int64(successRate))
+	}
+	return stats
+}
+
+// executeSubBatch executes the given transactions, starting at the given index
+// of their batch, concurrently on copies of the state and merges their diffs in
+// order up to the conflict edge. It returns the number of transactions consumed,
+// the remaining ones need to be resubmitted on top of the merged state.
+func (b *BatchExecutor) executeSubBatch(adapter *core.ExecAdapter, txs []*types.Transaction, offset int, statedb *state.StateDB, stats *batchStats) int {
+	// Create a copy of the state and a diff recorder for each transaction
 	stateCopies := make([]*state.StateDB, len(txs))
+	diffs := make([]*stateDiff, len(txs))
 	for i := range txs {
 		stateCopies[i] = statedb.Copy()
+		diffs[i] = newStateDiff()
 	}
 
 	// Process transactions in parallel
 	var wg sync.WaitGroup
 	results := make([]error, len(txs))
+	gasUsed := make([]uint64, len(txs))
+	elapsed := make([]time.Duration, len(txs))
 
-	header := b.chain.CurrentBlock()
 	for i, tx := range txs {
 		wg.Add(1)
-		go func(index int, transaction *types.Transaction, state *state.StateDB) {
+		index, transaction, state := i, tx, stateCopies[i]
+		b.workers.Go(func() {
 			defer wg.Done()
 
-			// Get sender
-			sender, err := types.Sender(types.LatestSigner(b.chainConfig), transaction)
-			if err != nil {
-				results[index] = err
-				return
-			}
-
-			// Apply message
-			msg, err := transaction.AsMessage(types.LatestSigner(b.chainConfig), header.BaseFee)
-			if err != nil {
-				results[index] = err
-				return
-			}
-
-			// Create a new context for the transaction
-			context := core.NewEVMContext(msg, header, b.chain, &sender)
-			vmenv := core.NewEVM(context, state, b.chainConfig, core.Config{})
-
 			// Apply transaction
-			_, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(transaction.Gas()))
-			results[index] = err
-
-		}(i, tx, stateCopies[i])
+			txStart := time.Now()
+			results[index] = b.workers.Guard(transaction, func() error {
+				res, err := adapter.Apply(state, transaction, offset+index, new(core.GasPool).AddGas(transaction.Gas()), diffs[index].hooks())
+				if err == nil {
+					gasUsed[index] = res.UsedGas
+				}
+				return err
+			})
+			elapsed[index] = time.Since(txStart)
+			b.exec.UpdateTx(elapsed[index])
+		})
 	}
 
 	// Wait for all transactions to complete
 	wg.Wait()
 
-	// Count successful transactions
-	successCount := 0
-	for _, err := range results {
-		if err == nil {
-			successCount++
+	// Merge the state diffs in batch order up to the conflict edge. The pending
+	// execution context credits fees to the zero address.
+	var (
+		merger   = newBatchMerger(statedb, common.Address{})
+		merged   int
+		consumed = len(txs)
+	)
+	for i, err := range results {
+		// An execution saw the state it would have seen serially unless it read
+		// or wrote a location written by a merged diff, which is the conflict
+		// edge the batch is split at. A failed execution may have been rejected
+		// before recording what it read, so failures are only final until the
+		// first diff is merged.
+		exact := merged == 0
+		if err != nil {
+			if !exact {
+				stats.conflicts++
+				consumed = i
+				break
+			}
+			log.Debug("Parallel batch transaction failed", "hash", txs[i].Hash(), "err", err)
+			stats.aborted++
+			stats.serial += elapsed[i]
+			continue
 		}
-	}
-
-	// Update success rate metric
-	if len(txs) > 0 {
-		successRate := (float64(successCount) / float64(len(txs))) * 100
-		b.successRateGauge.Update(int64(successRate))
-	}
-
-	// Merge state changes from parallel executions
-	// This is simplified; a real implementation would need to handle conflicts
-	for i, tx := range txs {
-		if results[i] == nil {
-			// Only apply changes from successful transactions
-			sender, _ := types.Sender(types.LatestSigner(b.chainConfig), tx)
-			statedb.SetNonce(sender, stateCopies[i].GetNonce(sender))
-			statedb.SetBalance(sender, stateCopies[i].GetBalance(sender))
-
-			// If it's a contract call, update contract state
-			if tx.To() != nil {
-				// Get the contract state
-				statedb.SetCode(*tx.To(), stateCopies[i].GetCode(*tx.To()))
-				statedb.SetNonce(*tx.To(), stateCopies[i].GetNonce(*tx.To()))
-				statedb.SetBalance(*tx.To(), stateCopies[i].GetBalance(*tx.To()))
+		if conflict := merger.conflict(diffs[i]); conflict != nil {
+			stats.conflicts++
+			b.reportConflict(txs[i].Hash(), conflict)
+			if !exact {
+				consumed = i
+				break
 			}
+			// The diff cannot be merged even on the state it was executed on
+			// (self-destructs, coinbase writes), re-execute it serially. The
+			// later executions did not see its effects.
+			stats.aborted++
+			stats.reexecutions++
+			b.executeSerially(adapter, txs[i], offset+i, statedb, stats)
+			consumed = i + 1
+			break
 		}
+		merger.merge(txs[i].Hash(), diffs[i], stateCopies[i])
+		merged++
+		stats.executed++
+		stats.used += gasUsed[i]
+		stats.serial += elapsed[i]
+		stats.order = append(stats.order, txs[i])
 	}
+	statedb.Finalise(adapter.Rules().IsEIP158)
+	return consumed
 }
 
-// executeSequentialBatch executes a batch of sequential transactions
-func (b *BatchExecutor) executeSequentialBatch(txs []*types.Transaction, statedb *state.StateDB) {
-	// Process sequential transactions in order
-	header := b.chain.CurrentBlock()
-	for _, tx := range txs {
-		// Get sender
-		sender, err := types.Sender(types.LatestSigner(b.chainConfig), tx)
-		if err != nil {
-			continue
-		}
+// executeSerially executes a member of a parallel batch directly on the merged
+// state, accounting it as re-executed.
+func (b *BatchExecutor) executeSerially(adapter *core.ExecAdapter, tx *types.Transaction, index int, statedb *state.StateDB, stats *batchStats) {
+	txStart := time.Now()
+	res, err := adapter.Apply(statedb, tx, index, new(core.GasPool).AddGas(tx.Gas()), nil)
+	stats.serial += time.Since(txStart)
+	b.exec.UpdateTx(time.Since(txStart))
+	if err != nil {
+		log.Debug("Parallel batch transaction failed serially", "hash", tx.Hash(), "err", err)
+		stats.aborted++
+		return
+	}
+	statedb.Finalise(adapter.Rules().IsEIP158)
+	stats.reexecuted++
+	stats.used += res.UsedGas
+	stats.order = append(stats.order, tx)
+}
 
-		// Apply message
-		msg, err := tx.AsMessage(types.LatestSigner(b.chainConfig), header.BaseFee)
-		if err != nil {
+// executeSequentialBatch executes a batch of sequential transactions, drawing
+// gas from the given block gas pool, and returns the transactions executed.
+// Transactions not fitting are skipped.
+func (b *BatchExecutor) executeSequentialBatch(txs []*types.Transaction, statedb *state.StateDB, gp *core.GasPool) []*types.Transaction {
+	// Process sequential transactions in order
+	var (
+		executed    []*types.Transaction
+		adapter     = core.NewPendingExecAdapter(b.chainConfig, b.chain, b.chain.CurrentBlock())
+		deleteEmpty = adapter.Rules().IsEIP158
+	)
+	for i, tx := range txs {
+		if gp.Gas() < tx.Gas() {
+			log.Trace("Skipping sequential transaction over block gas", "hash", tx.Hash(), "gas", tx.Gas(), "available", gp.Gas())
 			continue
 		}
-
-		// Create a new context for the transaction
-		context := core.NewEVMContext(msg, header, b.chain, &sender)
-		vmenv := core.NewEVM(context, statedb, b.chainConfig, core.Config{})
-
 		// Apply transaction
-		_, err = core.ApplyMessage(vmenv, msg, new(core.GasPool).AddGas(tx.Gas()))
+		_, err := adapter.Apply(statedb, tx, i, gp, nil)
 		if err != nil {
 			log.Debug("Sequential transaction failed", "hash", tx.Hash(), "err", err)
+			continue
 		}
+		statedb.Finalise(deleteEmpty)
+		executed = append(executed, tx)
 	}
+	return executed
 }
 
-// Stop stops the batch executor and unsubscribes from events
+// Stop stops the batch executor and unsubscribes from events, waiting for the
+// executions in flight to finish.
 func (b *BatchExecutor) Stop() {
 	b.txsSub.Unsubscribe()
+	b.auditScope.Close()
+
+	b.mu.Lock()
+	if b.specSub != nil {
+		b.specSub.Unsubscribe()
+	}
+	b.mu.Unlock()
+
+	b.workers.Close()
+}
+
+// SetParallelism resizes the worker pool executing the members of batches.
+func (b *BatchExecutor) SetParallelism(workers int) error {
+	return b.workers.SetSize(workers)
 }
 
 // SetGasLimits sets the gas floor and ceiling for transactions
@@ -272,7 +611,47 @@ func (b *BatchExecutor) SetGasLimits(gasFloor, gasCeil uint64) {
 	b.gasCeil = gasCeil
 }
 
+// SetRefundPolicy sets how block gas reserved for a batch but left unused is
+// redistributed.
+func (b *BatchExecutor) SetRefundPolicy(policy BatchRefundPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refundPolicy = policy
+}
+
+// SetSummaryDatabase sets the database the parallel execution summaries of the
+// built blocks are persisted to.
+func (b *BatchExecutor) SetSummaryDatabase(db ethdb.KeyValueStore) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.summaryDB = db
+}
+
+// SetConflictReporter sets the receiver of the conflicts aborting the parallel
+// execution of batch members.
+func (b *BatchExecutor) SetConflictReporter(reporter ConflictReporter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reporter = reporter
+}
+
+// reportConflict hands the conflict aborting a batch member to the reporter, if
+// one is set.
+func (b *BatchExecutor) reportConflict(hash common.Hash, conflict *parallelpool.ConflictReport) {
+	b.mu.RLock()
+	reporter := b.reporter
+	b.mu.RUnlock()
+
+	if reporter != nil {
+		reporter.ReportConflict(hash, *conflict)
+	}
+}
+
 // Pending returns the currently pending transactions
 func (b *BatchExecutor) Pending() (*types.Block, *state.StateDB) {
-	return b.chain.CurrentBlock(), nil
+	header := b.chain.CurrentBlock()
+	return b.chain.GetBlock(header.Hash(), header.Number.Uint64()), nil
 }
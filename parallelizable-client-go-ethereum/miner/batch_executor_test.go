@@ -0,0 +1,150 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that batch reservation keeps transactions fitting the available block
+// gas in order and defers the rest for a later packing pass.
+func TestReserveBatch(t *testing.T) {
+	newTx := func(nonce, gas uint64) *types.Transaction {
+		return types.NewTransaction(nonce, common.Address{}, new(big.Int), gas, new(big.Int), nil)
+	}
+	txs := []*types.Transaction{newTx(0, 40_000), newTx(1, 70_000), newTx(2, 30_000), newTx(3, 50_000)}
+
+	batch, deferred, reserved := reserveBatch(txs, 100_000)
+	if reserved != 70_000 {
+		t.Errorf("reserved gas mismatch: have %d, want %d", reserved, 70_000)
+	}
+	if len(batch) != 2 || batch[0] != txs[0] || batch[1] != txs[2] {
+		t.Errorf("batch mismatch: have %v", batch)
+	}
+	if len(deferred) != 2 || deferred[0] != txs[1] || deferred[1] != txs[3] {
+		t.Errorf("deferred mismatch: have %v", deferred)
+	}
+	// Refunded gas should allow the deferred transactions in a second pass
+	if batch, deferred, _ := reserveBatch(deferred, 120_000); len(batch) != 2 || len(deferred) != 0 {
+		t.Errorf("backfill mismatch: have %d batched, %d deferred", len(batch), len(deferred))
+	}
+}
+
+// Tests that block summaries accumulate batch measurements, survive persistence
+// and are only returned for the block they were recorded for.
+func TestBlockSummary(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	parent := common.Hash{0x01}
+
+	summary := &BlockSummary{Number: 5, ParentHash: parent, SequentialTxs: 1}
+	summary.addBatch(batchStats{executed: 3, aborted: 1, wall: time.Second, serial: 3 * time.Second})
+	summary.addBatch(batchStats{executed: 1, wall: time.Second, serial: time.Second})
+	writeBlockSummary(db, summary)
+
+	header := &types.Header{Number: big.NewInt(5), ParentHash: parent}
+	have := ReadBlockSummary(db, header)
+	if have == nil {
+		t.Fatalf("summary not found")
+	}
+	if have.Batches != 2 || have.ParallelTxs != 4 || have.SequentialTxs != 1 || have.Aborts != 1 {
+		t.Errorf("summary mismatch: have %+v", have)
+	}
+	if have.Speedup != 2 {
+		t.Errorf("speedup mismatch: have %v, want 2", have.Speedup)
+	}
+	// A competing block at the same height must not pick up the summary
+	if sibling := ReadBlockSummary(db, &types.Header{Number: big.NewInt(5), ParentHash: common.Hash{0x02}}); sibling != nil {
+		t.Errorf("summary returned for a block built on another parent")
+	}
+}
+
+// Tests that parallel batches are split on their conflict edge and the remainder
+// resubmitted, converging to the serial outcome without executing serially.
+func TestExecuteParallelBatchSplit(t *testing.T) {
+	key0, _ := crypto.GenerateKey()
+	key1, _ := crypto.GenerateKey()
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			crypto.PubkeyToAddress(key0.PublicKey): {Balance: big.NewInt(params.Ether)},
+			crypto.PubkeyToAddress(key1.PublicKey): {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		signer    = types.LatestSigner(params.TestChainConfig)
+		recipient = common.Address{0xaa}
+	)
+	send := func(key *ecdsa.PrivateKey, nonce uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &recipient, Value: common.Big1, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	// The nonce chain fails beyond its first link until the batch is split, the
+	// transfers to the same recipient conflict
+	txs := []*types.Transaction{send(key0, 0), send(key0, 1), send(key0, 2), send(key1, 0)}
+
+	executor := &BatchExecutor{
+		chainConfig:      params.TestChainConfig,
+		chain:            chain,
+		exec:             parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		successRateGauge: metrics.NewGauge(),
+		conflictMeter:    metrics.NewMeter(),
+	}
+	merged, _ := chain.StateAt(chain.CurrentBlock().Root)
+	serial := merged.Copy()
+
+	stats := executor.executeParallelBatch(txs, merged)
+	if stats.executed != len(txs) || stats.reexecuted != 0 {
+		t.Errorf("stats mismatch: executed %d, reexecuted %d", stats.executed, stats.reexecuted)
+	}
+	if stats.aborted != 6 || executor.conflictMeter.Snapshot().Count() != 6 {
+		t.Errorf("resubmissions mismatch: aborted %d, meter %d, want 6", stats.aborted, executor.conflictMeter.Snapshot().Count())
+	}
+	// Every split found a conflict edge, the members past it ran once more
+	if stats.conflicts != 3 || stats.reexecutions != 6 {
+		t.Errorf("conflict stats mismatch: conflicts %d, reexecutions %d, want 3 and 6", stats.conflicts, stats.reexecutions)
+	}
+	adapter := core.NewPendingExecAdapter(params.TestChainConfig, chain, chain.CurrentBlock())
+	for i, tx := range txs {
+		if _, err := adapter.Apply(serial, tx, i, new(core.GasPool).AddGas(tx.Gas()), nil); err != nil {
+			t.Fatalf("failed to apply tx %d serially: %v", i, err)
+		}
+		serial.Finalise(true)
+	}
+	if have, want := merged.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+		t.Errorf("merged state mismatch: have %x, want %x", have, want)
+	}
+}
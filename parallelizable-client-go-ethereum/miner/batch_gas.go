@@ -0,0 +1,82 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// batchGasReport is the gas accounting of a parallel batch included in a block.
+// It only depends on the block content, so anyone re-executing the block can
+// reproduce it.
+type batchGasReport struct {
+	id    uint64 // Identifier of the batch in the batch source
+	level int    // Dependency level of the batch
+	txs   int    // Members committed in parallel
+
+	start   uint64 // Block gas used before the batch
+	ceiling uint64 // Block gas reserved for the batch, the sum of the member gas limits
+	used    uint64 // Gas used by the committed members
+
+	critical    common.Hash // Committed member using the most gas, bounding the batch execution
+	criticalGas uint64      // Gas used by the critical member
+}
+
+// overcommitted reports whether the batch reserved more gas than the block had
+// left, which the scheduler must never do.
+func (r *batchGasReport) overcommitted(gasLimit uint64) bool {
+	return r.start > gasLimit || r.ceiling > gasLimit-r.start
+}
+
+// reportBatchGas logs the gas accounting of the parallel batches of a block and
+// updates the block gas metrics of the executor, if any. Batches execute one
+// after the other, their members concurrently, so the critical path of the block
+// is made of the critical member of every batch. The batch with the heaviest
+// critical member is reported as the critical-path batch.
+func reportBatchGas(block *types.Block, reports []batchGasReport, executor *BatchExecutor) {
+	var (
+		ceiling, used, path uint64
+		critical            *batchGasReport
+		overcommitted       int
+	)
+	for i := range reports {
+		report := &reports[i]
+		ceiling += report.ceiling
+		used += report.used
+		path += report.criticalGas
+		if critical == nil || report.criticalGas > critical.criticalGas {
+			critical = report
+		}
+		if report.overcommitted(block.GasLimit()) {
+			overcommitted++
+			log.Warn("Parallel batch overcommitted block gas", "number", block.Number(), "batch", report.id, "start", report.start, "ceiling", report.ceiling, "gaslimit", block.GasLimit())
+		}
+		log.Debug("Parallel batch gas", "number", block.Number(), "batch", report.id, "level", report.level, "txs", report.txs,
+			"used", report.used, "ceiling", report.ceiling, "start", report.start, "critical", report.critical, "criticalgas", report.criticalGas)
+	}
+	log.Debug("Parallel batches included", "number", block.Number(), "hash", block.Hash(), "batches", len(reports),
+		"used", used, "ceiling", ceiling, "gaslimit", block.GasLimit(), "pathgas", path, "criticalbatch", critical.id, "criticalgas", critical.criticalGas)
+
+	if executor != nil {
+		executor.blockCeilingGauge.Update(int64(ceiling))
+		executor.blockUsedGauge.Update(int64(used))
+		executor.criticalPathGauge.Update(int64(path))
+		executor.overcommitMeter.Mark(int64(overcommitted))
+	}
+}
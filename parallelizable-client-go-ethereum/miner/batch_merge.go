@@ -0,0 +1,204 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/holiman/uint256"
+)
+
+// stateDiff is the set of accounts and storage slots read and dirtied by a
+// single transaction executed on its own copy of the batch state. Only the
+// locations are recorded during execution; the written values are read back
+// from the copy when merging.
+type stateDiff struct {
+	accounts  map[common.Address]struct{}                 // Accounts whose balance, nonce or code was written
+	slots     map[common.Address]map[common.Hash]struct{} // Storage slots written
+	reads     map[common.Address]struct{}                 // Accounts entered or whose balance or code was read
+	readSlots map[common.Address]map[common.Hash]struct{} // Storage slots read
+	fees      *uint256.Int                                // Fees credited to the coinbase, merged as a delta
+	destruct  bool                                        // Whether an account was self-destructed
+}
+
+// newStateDiff creates an empty state diff.
+func newStateDiff() *stateDiff {
+	return &stateDiff{
+		accounts:  make(map[common.Address]struct{}),
+		slots:     make(map[common.Address]map[common.Hash]struct{}),
+		reads:     make(map[common.Address]struct{}),
+		readSlots: make(map[common.Address]map[common.Hash]struct{}),
+		fees:      new(uint256.Int),
+	}
+}
+
+// hooks returns the tracing hooks recording the diff of an execution.
+func (d *stateDiff) hooks() *tracing.Hooks {
+	return &tracing.Hooks{
+		OnBalanceChange: func(addr common.Address, prev, cur *big.Int, reason tracing.BalanceChangeReason) {
+			switch reason {
+			case tracing.BalanceIncreaseRewardTransactionFee:
+				// Every transaction credits the coinbase, which would make all
+				// of them conflict. Fees commute, so they are summed up instead.
+				d.fees.Add(d.fees, uint256.MustFromBig(cur))
+				d.fees.Sub(d.fees, uint256.MustFromBig(prev))
+			case tracing.BalanceDecreaseSelfdestruct, tracing.BalanceDecreaseSelfdestructBurn:
+				d.destruct = true
+			default:
+				d.accounts[addr] = struct{}{}
+			}
+		},
+		OnNonceChange: func(addr common.Address, prev, new uint64) {
+			d.accounts[addr] = struct{}{}
+		},
+		OnCodeChange: func(addr common.Address, prevCodeHash common.Hash, prevCode []byte, codeHash common.Hash, code []byte) {
+			d.accounts[addr] = struct{}{}
+		},
+		OnStorageChange: func(addr common.Address, slot common.Hash, prev, new common.Hash) {
+			addSlot(d.slots, addr, slot)
+		},
+		OnEnter: func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+			d.reads[to] = struct{}{}
+		},
+		OnOpcode: d.onOpcode,
+	}
+}
+
+// onOpcode records the storage slots and accounts read by an execution. Writes
+// are reported by the state change hooks instead.
+func (d *stateDiff) onOpcode(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+	stack := scope.StackData()
+	if len(stack) == 0 {
+		return
+	}
+	top := stack[len(stack)-1]
+
+	switch vm.OpCode(op) {
+	case vm.SLOAD, vm.SSTORE:
+		// Storing charges gas by the current value of the slot, reading it
+		addSlot(d.readSlots, scope.Address(), top.Bytes32())
+	case vm.BALANCE, vm.EXTCODESIZE, vm.EXTCODECOPY, vm.EXTCODEHASH:
+		d.reads[common.Address(top.Bytes20())] = struct{}{}
+	case vm.SELFBALANCE:
+		d.reads[scope.Address()] = struct{}{}
+	}
+}
+
+// addSlot adds a storage slot to a set of slots grouped by account.
+func addSlot(set map[common.Address]map[common.Hash]struct{}, addr common.Address, slot common.Hash) {
+	if set[addr] == nil {
+		set[addr] = make(map[common.Hash]struct{})
+	}
+	set[addr][slot] = struct{}{}
+}
+
+// batchMerger folds the diffs of transactions executed in parallel into a single
+// state, detecting the transactions that read or wrote a location written by an
+// earlier one, whose executions did not see the state they would have serially.
+type batchMerger struct {
+	statedb  *state.StateDB
+	coinbase common.Address
+
+	accounts map[common.Address]common.Hash                 // Accounts written by the merged transactions, coinbase included once credited, to their last writer
+	slots    map[common.Address]map[common.Hash]common.Hash // Storage slots written by the merged transactions, to their last writer
+}
+
+// newBatchMerger creates a merger committing into the given state.
+func newBatchMerger(statedb *state.StateDB, coinbase common.Address) *batchMerger {
+	return &batchMerger{
+		statedb:  statedb,
+		coinbase: coinbase,
+		accounts: make(map[common.Address]common.Hash),
+		slots:    make(map[common.Address]map[common.Hash]common.Hash),
+	}
+}
+
+// conflict returns the conflict of a diff reading or writing a location already
+// written by a merged transaction, naming the location and the transaction, or
+// nil if there is none. Self-destructs and writes to the coinbase other than fee
+// credits are always treated as conflicting, as they cannot be replayed as a
+// plain diff on top of the fees merged so far.
+func (m *batchMerger) conflict(diff *stateDiff) *parallelpool.ConflictReport {
+	if diff.destruct {
+		return &parallelpool.ConflictReport{Action: parallelpool.ConflictAborted}
+	}
+	if _, ok := diff.accounts[m.coinbase]; ok {
+		return newBatchConflict(m.accounts[m.coinbase], m.coinbase, nil)
+	}
+	for _, accounts := range []map[common.Address]struct{}{diff.accounts, diff.reads} {
+		for addr := range accounts {
+			if writer, ok := m.accounts[addr]; ok {
+				return newBatchConflict(writer, addr, nil)
+			}
+		}
+	}
+	for _, slots := range []map[common.Address]map[common.Hash]struct{}{diff.slots, diff.readSlots} {
+		for addr, set := range slots {
+			written := m.slots[addr]
+			for slot := range set {
+				if writer, ok := written[slot]; ok {
+					return newBatchConflict(writer, addr, &slot)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// newBatchConflict creates the report of a member of a batch aborted because of
+// the given merged transaction, on an account or one of its slots.
+func newBatchConflict(counterparty common.Hash, addr common.Address, slot *common.Hash) *parallelpool.ConflictReport {
+	return &parallelpool.ConflictReport{
+		Action:       parallelpool.ConflictAborted,
+		Counterparty: counterparty,
+		Address:      &addr,
+		Slot:         slot,
+	}
+}
+
+// merge copies the locations written by a transaction from the state it was
+// executed on into the merged state, and credits the fees it paid. The caller
+// must ensure the diff does not conflict.
+func (m *batchMerger) merge(hash common.Hash, diff *stateDiff, executed *state.StateDB) {
+	for addr := range diff.accounts {
+		m.statedb.SetBalance(addr, executed.GetBalance(addr), tracing.BalanceChangeUnspecified)
+		m.statedb.SetNonce(addr, executed.GetNonce(addr), tracing.NonceChangeUnspecified)
+		if executed.GetCodeHash(addr) != m.statedb.GetCodeHash(addr) {
+			m.statedb.SetCode(addr, executed.GetCode(addr))
+		}
+		m.accounts[addr] = hash
+	}
+	for addr, slots := range diff.slots {
+		if m.slots[addr] == nil {
+			m.slots[addr] = make(map[common.Hash]common.Hash)
+		}
+		for slot := range slots {
+			m.statedb.SetState(addr, slot, executed.GetState(addr, slot))
+			m.slots[addr][slot] = hash
+		}
+	}
+	// Later transactions reading the coinbase balance saw it without the fees
+	if !diff.fees.IsZero() {
+		m.statedb.AddBalance(m.coinbase, diff.fees, tracing.BalanceIncreaseRewardTransactionFee)
+		m.accounts[m.coinbase] = hash
+	}
+}
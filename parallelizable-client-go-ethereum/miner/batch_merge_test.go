@@ -0,0 +1,193 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testConflictReporter records the conflicts reported by a batch executor.
+type testConflictReporter struct {
+	mu      sync.Mutex
+	reports map[common.Hash][]parallelpool.ConflictReport
+}
+
+func (r *testConflictReporter) ReportConflict(hash common.Hash, report parallelpool.ConflictReport) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.reports == nil {
+		r.reports = make(map[common.Hash][]parallelpool.ConflictReport)
+	}
+	r.reports[hash] = append(r.reports[hash], report)
+}
+
+// Tests that merging a parallel batch yields the same state as executing it
+// serially, with conflicting members resubmitted on top of the merged state and
+// fees credited once per transaction.
+func TestExecuteParallelBatchMerge(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &core.Genesis{
+		Config:  params.TestChainConfig,
+		Alloc:   alloc,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		signer = types.LatestSigner(params.TestChainConfig)
+		shared = common.Address{0xaa}
+		other  = common.Address{0xbb}
+	)
+	transfer := func(key *ecdsa.PrivateKey, to common.Address) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{To: &to, Value: big.NewInt(1), Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	// The first and last transfers credit the same account, conflicting
+	txs := []*types.Transaction{
+		transfer(keys[0], shared),
+		transfer(keys[1], other),
+		transfer(keys[2], shared),
+	}
+	executor := &BatchExecutor{
+		chainConfig:      params.TestChainConfig,
+		chain:            chain,
+		exec:             parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		successRateGauge: metrics.NewGauge(),
+		conflictMeter:    metrics.NewMeter(),
+	}
+	merged, _ := chain.StateAt(chain.CurrentBlock().Root)
+	serial := merged.Copy()
+
+	stats := executor.executeParallelBatch(txs, merged)
+	if stats.executed != 3 || stats.aborted != 1 || stats.reexecuted != 0 {
+		t.Errorf("stats mismatch: executed %d, aborted %d, reexecuted %d", stats.executed, stats.aborted, stats.reexecuted)
+	}
+	if stats.used != 3*params.TxGas {
+		t.Errorf("used gas mismatch: have %d, want %d", stats.used, 3*params.TxGas)
+	}
+	adapter := core.NewPendingExecAdapter(params.TestChainConfig, chain, chain.CurrentBlock())
+	for i, tx := range txs {
+		if _, err := adapter.Apply(serial, tx, i, new(core.GasPool).AddGas(tx.Gas()), nil); err != nil {
+			t.Fatalf("failed to apply tx %d serially: %v", i, err)
+		}
+	}
+	if have := merged.GetBalance(shared).Uint64(); have != 2 {
+		t.Errorf("conflicting credits lost: have %d, want 2", have)
+	}
+	if have, want := merged.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+		t.Errorf("merged state mismatch: have %x, want %x", have, want)
+	}
+}
+
+// Tests that a member reading a storage slot written by an earlier member of the
+// batch is resubmitted on top of the merged state instead of being merged with
+// what it read on the state before the batch.
+func TestExecuteParallelBatchReadConflict(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 2)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	// Without calldata the contract stores 1 in slot 0, with calldata it copies
+	// slot 0 into slot 1
+	contract := common.Address{0xcc}
+	alloc[contract] = types.Account{Code: common.FromHex("0x36600a576001600055005b60005460015500")}
+
+	gspec := &core.Genesis{
+		Config:  params.TestChainConfig,
+		Alloc:   alloc,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	call := func(key *ecdsa.PrivateKey, data []byte) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{To: &contract, Gas: 100000, GasPrice: big.NewInt(2 * params.InitialBaseFee), Data: data})
+	}
+	txs := []*types.Transaction{
+		call(keys[0], nil),
+		call(keys[1], []byte{0x01}),
+	}
+	executor := &BatchExecutor{
+		chainConfig:      params.TestChainConfig,
+		chain:            chain,
+		exec:             parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		successRateGauge: metrics.NewGauge(),
+		conflictMeter:    metrics.NewMeter(),
+	}
+	reporter := new(testConflictReporter)
+	executor.SetConflictReporter(reporter)
+
+	merged, _ := chain.StateAt(chain.CurrentBlock().Root)
+	serial := merged.Copy()
+
+	stats := executor.executeParallelBatch(txs, merged)
+	if stats.executed != 2 || stats.aborted != 1 {
+		t.Errorf("stats mismatch: executed %d, aborted %d", stats.executed, stats.aborted)
+	}
+	// The abort is reported with the writer of the slot read
+	if len(reporter.reports) != 1 || len(reporter.reports[txs[1].Hash()]) != 1 {
+		t.Fatalf("conflict reports mismatch: have %v", reporter.reports)
+	}
+	report := reporter.reports[txs[1].Hash()][0]
+	if report.Action != parallelpool.ConflictAborted || report.Counterparty != txs[0].Hash() {
+		t.Errorf("conflict report mismatch: action %s, counterparty %x, want %s by %x", report.Action, report.Counterparty, parallelpool.ConflictAborted, txs[0].Hash())
+	}
+	if report.Address == nil || *report.Address != contract || report.Slot == nil || *report.Slot != (common.Hash{}) {
+		t.Errorf("conflict location mismatch: address %v, slot %v, want slot 0 of %x", report.Address, report.Slot, contract)
+	}
+	adapter := core.NewPendingExecAdapter(params.TestChainConfig, chain, chain.CurrentBlock())
+	for i, tx := range txs {
+		if _, err := adapter.Apply(serial, tx, i, new(core.GasPool).AddGas(tx.Gas()), nil); err != nil {
+			t.Fatalf("failed to apply tx %d serially: %v", i, err)
+		}
+	}
+	if have, want := merged.GetState(contract, common.BigToHash(common.Big1)), common.BigToHash(common.Big1); have != want {
+		t.Errorf("stale read merged: slot 1 %x, want %x", have, want)
+	}
+	if have, want := merged.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+		t.Errorf("merged state mismatch: have %x, want %x", have, want)
+	}
+}
@@ -0,0 +1,534 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/holiman/uint256"
+)
+
+// maxOptimisticRounds is the maximum number of execution rounds of an optimistic
+// batch. Every round commits at least one transaction, the ones still pending
+// afterwards are executed serially to bound the work wasted on contended batches.
+const maxOptimisticRounds = 8
+
+// mvKind is the kind of state location tracked by the multi-version memory.
+type mvKind uint8
+
+const (
+	mvBalance mvKind = iota
+	mvNonce
+	mvCode
+	mvStorage
+)
+
+// mvLocation is a state location read or written by a transaction.
+type mvLocation struct {
+	addr common.Address
+	kind mvKind
+	slot common.Hash // Storage slot, only set for storage locations
+}
+
+// mvValue is the value written to a state location, only the field matching the
+// kind of the location is set.
+type mvValue struct {
+	balance *uint256.Int
+	nonce   uint64
+	code    []byte
+	slot    common.Hash
+}
+
+// mvVersion identifies the execution a state location was read from. Reads of
+// locations not written by any earlier transaction of the batch are versioned
+// with tx -1.
+type mvVersion struct {
+	tx          int
+	incarnation int
+}
+
+// mvEntry is a value written by a specific incarnation of a transaction.
+type mvEntry struct {
+	incarnation int
+	value       mvValue
+}
+
+// mvMemory is the multi-version state of an optimistic batch, holding the values
+// written by the latest execution of every transaction. It is only modified
+// between execution rounds, so concurrent executions need no locking.
+type mvMemory struct {
+	writes  map[mvLocation]map[int]mvEntry // Values written per location and transaction
+	written [][]mvLocation                 // Locations written by each transaction
+}
+
+// newMVMemory creates an empty multi-version state for a batch of n transactions.
+func newMVMemory(n int) *mvMemory {
+	return &mvMemory{
+		writes:  make(map[mvLocation]map[int]mvEntry),
+		written: make([][]mvLocation, n),
+	}
+}
+
+// read returns the value of a location as seen by the given transaction, which
+// is the one written by the closest preceding transaction. False is returned if
+// no preceding transaction wrote the location.
+func (m *mvMemory) read(loc mvLocation, tx int) (mvVersion, mvValue, bool) {
+	version := mvVersion{tx: -1}
+	var value mvValue
+	for writer, entry := range m.writes[loc] {
+		if writer < tx && writer > version.tx {
+			version, value = mvVersion{tx: writer, incarnation: entry.incarnation}, entry.value
+		}
+	}
+	return version, value, version.tx >= 0
+}
+
+// publish replaces the values written by a previous execution of a transaction
+// with the ones written by its latest incarnation.
+func (m *mvMemory) publish(tx int, incarnation int, writes map[mvLocation]mvValue) {
+	for _, loc := range m.written[tx] {
+		delete(m.writes[loc], tx)
+		if len(m.writes[loc]) == 0 {
+			delete(m.writes, loc)
+		}
+	}
+	m.written[tx] = m.written[tx][:0]
+	for loc, value := range writes {
+		if m.writes[loc] == nil {
+			m.writes[loc] = make(map[int]mvEntry)
+		}
+		m.writes[loc][tx] = mvEntry{incarnation: incarnation, value: value}
+		m.written[tx] = append(m.written[tx], loc)
+	}
+}
+
+// validate reports whether all locations read by an execution of a transaction
+// would still be read from the same versions. Otherwise the first stale location
+// found is returned along with the transaction that wrote it, either the one
+// that does now or the one whose write went away.
+func (m *mvMemory) validate(tx int, reads map[mvLocation]mvVersion) (mvLocation, int, bool) {
+	for loc, version := range reads {
+		if have, _, _ := m.read(loc, tx); have != version {
+			if have.tx < 0 {
+				return loc, version.tx, false
+			}
+			return loc, have.tx, false
+		}
+	}
+	return mvLocation{}, -1, true
+}
+
+// materialize applies the values written by the transactions preceding the given
+// one onto a copy of the batch base state, and finalises them so they are seen
+// as committed by the execution.
+func (m *mvMemory) materialize(statedb *state.StateDB, tx int, deleteEmpty bool) {
+	for loc := range m.writes {
+		_, value, ok := m.read(loc, tx)
+		if !ok {
+			continue
+		}
+		switch loc.kind {
+		case mvBalance:
+			statedb.SetBalance(loc.addr, value.balance, tracing.BalanceChangeUnspecified)
+		case mvNonce:
+			statedb.SetNonce(loc.addr, value.nonce, tracing.NonceChangeUnspecified)
+		case mvCode:
+			statedb.SetCode(loc.addr, value.code)
+		case mvStorage:
+			statedb.SetState(loc.addr, loc.slot, value.slot)
+		}
+	}
+	statedb.Finalise(deleteEmpty)
+}
+
+// mvState is the view of the state a transaction of an optimistic batch is
+// executed on. It records the versions of the locations read and the locations
+// written, and sums the fees credited to the coinbase separately, since they
+// commute and would otherwise make every transaction conflict.
+type mvState struct {
+	*state.StateDB
+
+	mv       *mvMemory
+	tx       int
+	coinbase common.Address
+
+	reads   map[mvLocation]mvVersion
+	written map[mvLocation]struct{}
+	fees    *uint256.Int
+	serial  bool // Whether the effects cannot be replayed as a write set
+}
+
+// newMVState creates a view recording the accesses of the given transaction.
+func newMVState(statedb *state.StateDB, mv *mvMemory, tx int, coinbase common.Address) *mvState {
+	return &mvState{
+		StateDB:  statedb,
+		mv:       mv,
+		tx:       tx,
+		coinbase: coinbase,
+		reads:    make(map[mvLocation]mvVersion),
+		written:  make(map[mvLocation]struct{}),
+		fees:     new(uint256.Int),
+	}
+}
+
+// read records the version of a location read by the transaction. The coinbase
+// balance also holds the fees of the batch, which are only merged at the end,
+// so transactions accessing it need to be executed serially.
+func (s *mvState) read(addr common.Address, kind mvKind, slot common.Hash) {
+	if addr == s.coinbase && kind == mvBalance {
+		s.serial = true
+	}
+	loc := mvLocation{addr: addr, kind: kind, slot: slot}
+	if _, ok := s.reads[loc]; !ok {
+		s.reads[loc], _, _ = s.mv.read(loc, s.tx)
+	}
+}
+
+// write records a location written by the transaction.
+func (s *mvState) write(addr common.Address, kind mvKind, slot common.Hash) {
+	if addr == s.coinbase && kind == mvBalance {
+		s.serial = true
+	}
+	s.written[mvLocation{addr: addr, kind: kind, slot: slot}] = struct{}{}
+}
+
+// readAccount records a read of all fields of an account.
+func (s *mvState) readAccount(addr common.Address) {
+	s.read(addr, mvBalance, common.Hash{})
+	s.read(addr, mvNonce, common.Hash{})
+	s.read(addr, mvCode, common.Hash{})
+}
+
+func (s *mvState) CreateAccount(addr common.Address) {
+	s.write(addr, mvBalance, common.Hash{})
+	s.write(addr, mvNonce, common.Hash{})
+	s.write(addr, mvCode, common.Hash{})
+	s.StateDB.CreateAccount(addr)
+}
+
+func (s *mvState) SubBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) uint256.Int {
+	s.read(addr, mvBalance, common.Hash{})
+	s.write(addr, mvBalance, common.Hash{})
+	return s.StateDB.SubBalance(addr, amount, reason)
+}
+
+func (s *mvState) AddBalance(addr common.Address, amount *uint256.Int, reason tracing.BalanceChangeReason) uint256.Int {
+	if addr == s.coinbase && reason == tracing.BalanceIncreaseRewardTransactionFee {
+		s.fees.Add(s.fees, amount)
+		return s.StateDB.AddBalance(addr, amount, reason)
+	}
+	s.read(addr, mvBalance, common.Hash{})
+	s.write(addr, mvBalance, common.Hash{})
+	return s.StateDB.AddBalance(addr, amount, reason)
+}
+
+func (s *mvState) GetBalance(addr common.Address) *uint256.Int {
+	s.read(addr, mvBalance, common.Hash{})
+	return s.StateDB.GetBalance(addr)
+}
+
+func (s *mvState) GetNonce(addr common.Address) uint64 {
+	s.read(addr, mvNonce, common.Hash{})
+	return s.StateDB.GetNonce(addr)
+}
+
+func (s *mvState) SetNonce(addr common.Address, nonce uint64, reason tracing.NonceChangeReason) {
+	s.write(addr, mvNonce, common.Hash{})
+	s.StateDB.SetNonce(addr, nonce, reason)
+}
+
+func (s *mvState) GetCodeHash(addr common.Address) common.Hash {
+	s.read(addr, mvCode, common.Hash{})
+	return s.StateDB.GetCodeHash(addr)
+}
+
+func (s *mvState) GetCode(addr common.Address) []byte {
+	s.read(addr, mvCode, common.Hash{})
+	return s.StateDB.GetCode(addr)
+}
+
+func (s *mvState) GetCodeSize(addr common.Address) int {
+	s.read(addr, mvCode, common.Hash{})
+	return s.StateDB.GetCodeSize(addr)
+}
+
+func (s *mvState) SetCode(addr common.Address, code []byte) []byte {
+	s.write(addr, mvCode, common.Hash{})
+	return s.StateDB.SetCode(addr, code)
+}
+
+func (s *mvState) GetCommittedState(addr common.Address, slot common.Hash) common.Hash {
+	s.read(addr, mvStorage, slot)
+	return s.StateDB.GetCommittedState(addr, slot)
+}
+
+func (s *mvState) GetState(addr common.Address, slot common.Hash) common.Hash {
+	s.read(addr, mvStorage, slot)
+	return s.StateDB.GetState(addr, slot)
+}
+
+func (s *mvState) SetState(addr common.Address, slot common.Hash, value common.Hash) common.Hash {
+	s.write(addr, mvStorage, slot)
+	return s.StateDB.SetState(addr, slot, value)
+}
+
+// GetStorageRoot is only used for the contract creation collision check. Storage
+// can only be written by earlier transactions of the batch to accounts holding
+// code, which the check already depends on, so the read is not recorded.
+func (s *mvState) GetStorageRoot(addr common.Address) common.Hash {
+	return s.StateDB.GetStorageRoot(addr)
+}
+
+func (s *mvState) SelfDestruct(addr common.Address) uint256.Int {
+	s.serial = true
+	return s.StateDB.SelfDestruct(addr)
+}
+
+func (s *mvState) SelfDestruct6780(addr common.Address) (uint256.Int, bool) {
+	prev, destructed := s.StateDB.SelfDestruct6780(addr)
+	if destructed {
+		s.serial = true
+	}
+	return prev, destructed
+}
+
+func (s *mvState) Exist(addr common.Address) bool {
+	s.readAccount(addr)
+	return s.StateDB.Exist(addr)
+}
+
+func (s *mvState) Empty(addr common.Address) bool {
+	s.readAccount(addr)
+	return s.StateDB.Empty(addr)
+}
+
+// writes returns the values of the locations written by the transaction.
+func (s *mvState) writes() map[mvLocation]mvValue {
+	writes := make(map[mvLocation]mvValue, len(s.written))
+	for loc := range s.written {
+		var value mvValue
+		switch loc.kind {
+		case mvBalance:
+			value.balance = s.StateDB.GetBalance(loc.addr).Clone()
+		case mvNonce:
+			value.nonce = s.StateDB.GetNonce(loc.addr)
+		case mvCode:
+			value.code = s.StateDB.GetCode(loc.addr)
+		case mvStorage:
+			value.slot = s.StateDB.GetState(loc.addr, loc.slot)
+		}
+		writes[loc] = value
+	}
+	return writes
+}
+
+// mvExecution is the outcome of an incarnation of a transaction of an optimistic
+// batch.
+type mvExecution struct {
+	incarnation int
+	reads       map[mvLocation]mvVersion
+	writes      map[mvLocation]mvValue
+	fees        *uint256.Int
+	serial      bool
+
+	used    uint64
+	err     error
+	elapsed time.Duration
+}
+
+// newMVConflict creates the report of a transaction of a batch invalidated by a
+// stale read of the given location, written by the given transaction.
+func newMVConflict(txs []*types.Transaction, loc mvLocation, writer int) *parallelpool.ConflictReport {
+	var (
+		counterparty common.Hash
+		slot         *common.Hash
+	)
+	if writer >= 0 {
+		counterparty = txs[writer].Hash()
+	}
+	if loc.kind == mvStorage {
+		slot = &loc.slot
+	}
+	return newBatchConflict(counterparty, loc.addr, slot)
+}
+
+// executeOptimisticBatch executes a batch of transactions with optimistic
+// concurrency control. All transactions are executed concurrently against a
+// multi-version state holding the writes of the preceding transactions, and
+// validated afterwards by checking whether their reads are still current. Only
+// the invalidated ones are re-executed in the next round, so the parallelism of
+// the batch is derived from the actual state accesses instead of the tags of
+// its members. The committed writes are applied to the given state in batch
+// order, yielding the same state as executing the batch serially.
+func (b *BatchExecutor) executeOptimisticBatch(txs []*types.Transaction, statedb *state.StateDB) batchStats {
+	var (
+		adapter     = core.NewPendingExecAdapter(b.chainConfig, b.chain, b.chain.CurrentBlock())
+		deleteEmpty = adapter.Rules().IsEIP158
+		mv          = newMVMemory(len(txs))
+		execs       = make([]*mvExecution, len(txs))
+		schedule    = make([]int, len(txs))
+		committed   int
+		stats       batchStats
+		start       = time.Now()
+	)
+	for i := range schedule {
+		schedule[i] = i
+	}
+	// The pending execution context credits fees to the zero address
+	var coinbase common.Address
+
+	for round := 0; round < maxOptimisticRounds && len(schedule) > 0; round++ {
+		if round > 0 {
+			log.Trace("Re-executing invalidated batch transactions", "round", round, "txs", len(schedule), "batch", len(txs))
+			stats.aborted += len(schedule)
+			stats.reexecutions += len(schedule)
+			b.conflictMeter.Mark(int64(len(schedule)))
+		}
+		// Create a copy of the state for each scheduled transaction
+		copies := make([]*state.StateDB, len(schedule))
+		for j := range schedule {
+			copies[j] = statedb.Copy()
+		}
+		var wg sync.WaitGroup
+		for j, i := range schedule {
+			incarnation := 0
+			if execs[i] != nil {
+				incarnation = execs[i].incarnation + 1
+			}
+			wg.Add(1)
+			index, execState := i, copies[j]
+			b.workers.Go(func() {
+				defer wg.Done()
+
+				txStart := time.Now()
+				mv.materialize(execState, index, deleteEmpty)
+				execState.SetTxContext(txs[index].Hash(), index)
+
+				var (
+					view = newMVState(execState, mv, index, coinbase)
+					res  *core.ExecutionResult
+				)
+				err := b.workers.Guard(txs[index], func() (err error) {
+					res, err = adapter.ApplyView(view, txs[index], new(core.GasPool).AddGas(txs[index].Gas()))
+					return err
+				})
+				exec := &mvExecution{
+					incarnation: incarnation,
+					reads:       view.reads,
+					fees:        view.fees,
+					serial:      view.serial,
+					err:         err,
+				}
+				if err == nil {
+					exec.writes, exec.used = view.writes(), res.UsedGas
+				}
+				exec.elapsed = time.Since(txStart)
+				b.exec.UpdateTx(exec.elapsed)
+				execs[index] = exec
+			})
+		}
+		wg.Wait()
+
+		// Publish the writes of the round, and validate the pending transactions
+		// in batch order, committing the valid ones not preceded by invalid ones
+		for _, i := range schedule {
+			mv.publish(i, execs[i].incarnation, execs[i].writes)
+		}
+		schedule = schedule[:0]
+		for i := committed; i < len(txs); i++ {
+			if loc, writer, ok := mv.validate(i, execs[i].reads); !ok {
+				stats.conflicts++
+				b.reportConflict(txs[i].Hash(), newMVConflict(txs, loc, writer))
+				schedule = append(schedule, i)
+				continue
+			}
+			if i == committed && !execs[i].serial {
+				committed++
+			}
+		}
+		// Transactions whose effects cannot be replayed as a write set end the
+		// optimistic execution once they are next to be committed
+		if committed < len(txs) && execs[committed].serial && (len(schedule) == 0 || schedule[0] != committed) {
+			break
+		}
+	}
+	// Apply the writes of the committed transactions in batch order
+	for i := 0; i < committed; i++ {
+		exec := execs[i]
+		stats.serial += exec.elapsed
+		if exec.err != nil {
+			stats.aborted++
+			continue
+		}
+		for loc, value := range exec.writes {
+			switch loc.kind {
+			case mvBalance:
+				statedb.SetBalance(loc.addr, value.balance, tracing.BalanceChangeUnspecified)
+			case mvNonce:
+				statedb.SetNonce(loc.addr, value.nonce, tracing.NonceChangeUnspecified)
+			case mvCode:
+				statedb.SetCode(loc.addr, value.code)
+			case mvStorage:
+				statedb.SetState(loc.addr, loc.slot, value.slot)
+			}
+		}
+		if !exec.fees.IsZero() {
+			statedb.AddBalance(coinbase, exec.fees, tracing.BalanceIncreaseRewardTransactionFee)
+		}
+		stats.executed++
+		stats.used += exec.used
+		stats.order = append(stats.order, txs[i])
+	}
+	statedb.Finalise(deleteEmpty)
+
+	// Execute the transactions left uncommitted serially on the merged state
+	if committed < len(txs) {
+		log.Debug("Executing optimistic batch remainder serially", "txs", len(txs)-committed, "batch", len(txs))
+	}
+	for i := committed; i < len(txs); i++ {
+		stats.aborted++
+		stats.reexecutions++
+
+		txStart := time.Now()
+		res, err := adapter.Apply(statedb, txs[i], i, new(core.GasPool).AddGas(txs[i].Gas()), nil)
+		stats.serial += time.Since(txStart)
+		b.exec.UpdateTx(time.Since(txStart))
+		if err != nil {
+			log.Debug("Optimistic batch transaction failed", "hash", txs[i].Hash(), "err", err)
+			continue
+		}
+		statedb.Finalise(deleteEmpty)
+		stats.reexecuted++
+		stats.used += res.UsedGas
+		stats.order = append(stats.order, txs[i])
+	}
+	stats.wall = time.Since(start)
+
+	if len(txs) > 0 {
+		successRate := (float64(stats.executed) / float64(len(txs))) * 100
+		b.successRateGauge.Update(int64(successRate))
+	}
+	return stats
+}
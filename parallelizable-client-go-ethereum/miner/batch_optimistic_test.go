@@ -0,0 +1,117 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that an optimistic batch yields the same state as executing it serially,
+// re-executing only the transactions whose reads were invalidated by preceding
+// ones, and executing the ones touching the coinbase serially.
+func TestExecuteOptimisticBatch(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 4)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		if i < 3 {
+			alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+		}
+	}
+	// The counter increments its first storage slot on every call
+	counter := common.Address{0xcc}
+	alloc[counter] = types.Account{Code: common.FromHex("0x60005460010160005500"), Balance: common.Big0}
+
+	gspec := &core.Genesis{
+		Config:  params.TestChainConfig,
+		Alloc:   alloc,
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	send := func(key *ecdsa.PrivateKey, nonce uint64, to common.Address, value *big.Int, gas uint64) *types.Transaction {
+		return types.MustSignNewTx(key, signer, &types.LegacyTx{Nonce: nonce, To: &to, Value: value, Gas: gas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	txs := []*types.Transaction{
+		send(keys[0], 0, counter, common.Big0, 100_000),
+		send(keys[1], 0, common.Address{0xbb}, common.Big1, params.TxGas),
+		send(keys[0], 1, counter, common.Big0, 100_000),                                                       // Depends on the nonce and slot written by the first
+		send(keys[2], 0, crypto.PubkeyToAddress(keys[3].PublicKey), big.NewInt(params.Ether/2), params.TxGas), // Funds the next sender
+		send(keys[3], 0, common.Address{0xbb}, common.Big1, params.TxGas),
+		send(keys[1], 1, common.Address{}, common.Big1, params.TxGas), // Pays the coinbase
+	}
+	executor := &BatchExecutor{
+		chainConfig:      params.TestChainConfig,
+		chain:            chain,
+		exec:             parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		optimistic:       true,
+		successRateGauge: metrics.NewGauge(),
+		conflictMeter:    metrics.NewMeter(),
+	}
+	reporter := new(testConflictReporter)
+	executor.SetConflictReporter(reporter)
+
+	merged, _ := chain.StateAt(chain.CurrentBlock().Root)
+	serial := merged.Copy()
+
+	stats := executor.executeBatch(txs, merged)
+	if stats.executed != 5 || stats.reexecuted != 1 || stats.aborted != 4 {
+		t.Errorf("stats mismatch: executed %d, reexecuted %d, aborted %d", stats.executed, stats.reexecuted, stats.aborted)
+	}
+	var used uint64
+	adapter := core.NewPendingExecAdapter(params.TestChainConfig, chain, chain.CurrentBlock())
+	for i, tx := range txs {
+		res, err := adapter.Apply(serial, tx, i, new(core.GasPool).AddGas(tx.Gas()), nil)
+		if err != nil {
+			t.Fatalf("failed to apply tx %d serially: %v", i, err)
+		}
+		serial.Finalise(true)
+		used += res.UsedGas
+	}
+	if stats.used != used {
+		t.Errorf("used gas mismatch: have %d, want %d", stats.used, used)
+	}
+	if have := merged.GetState(counter, common.Hash{}); have != common.BigToHash(big.NewInt(2)) {
+		t.Errorf("counter mismatch: have %x, want 2", have)
+	}
+	if have, want := merged.IntermediateRoot(true), serial.IntermediateRoot(true); have != want {
+		t.Errorf("optimistic state mismatch: have %x, want %x", have, want)
+	}
+	// The second counter call is invalidated by the first one
+	reports := reporter.reports[txs[2].Hash()]
+	if len(reports) == 0 || reports[0].Action != parallelpool.ConflictAborted || reports[0].Counterparty != txs[0].Hash() {
+		t.Errorf("conflict reports mismatch: have %+v, want aborted by %x", reports, txs[0].Hash())
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+var (
+	// errOrderingUnsigned is returned if an ordering log carries no signature.
+	errOrderingUnsigned = errors.New("ordering log not signed")
+
+	// errOrderingParentMissing is returned if the parent block of an ordering
+	// log being replayed is not available.
+	errOrderingParentMissing = errors.New("ordering log parent not found")
+)
+
+// OrderingStep is a single batch composition decision of the sequencer: the
+// transactions committed by a parallel batch or a sequential pass, in the order
+// their effects were committed, along with the state root after committing them.
+type OrderingStep struct {
+	Parallel     bool                 `json:"parallel"`     // Whether the step was executed as a parallel batch
+	Transactions []*types.Transaction `json:"transactions"` // Committed transactions in commit order
+	Root         common.Hash          `json:"root"`         // State root after the step
+}
+
+// OrderingLog is the signed record of how the sequencer composed and ordered a
+// block out of parallel batches and sequential passes. Replaying the steps
+// serially, in the logged order, on top of the parent state must yield the
+// logged roots, proving that parallel execution did not alter the semantics of
+// the committed order.
+type OrderingLog struct {
+	Number     hexutil.Uint64  `json:"number"`
+	ParentHash common.Hash     `json:"parentHash"`
+	Steps      []*OrderingStep `json:"steps"`
+	Signature  hexutil.Bytes   `json:"signature"` // Sequencer signature over the log hash
+}
+
+// Hash returns the hash the sequencer signs, covering everything but the
+// signature itself.
+func (l *OrderingLog) Hash() common.Hash {
+	return rlpHash([]interface{}{l.Number, l.ParentHash, l.Steps})
+}
+
+// Sign signs the ordering log with the sequencer key.
+func (l *OrderingLog) Sign(key *ecdsa.PrivateKey) error {
+	sig, err := crypto.Sign(l.Hash().Bytes(), key)
+	if err != nil {
+		return err
+	}
+	l.Signature = sig
+	return nil
+}
+
+// Sequencer recovers the address of the sequencer that signed the ordering log.
+func (l *OrderingLog) Sequencer() (common.Address, error) {
+	if len(l.Signature) == 0 {
+		return common.Address{}, errOrderingUnsigned
+	}
+	pub, err := crypto.SigToPub(l.Hash().Bytes(), l.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// addStep records a step committed into the given state.
+func (l *OrderingLog) addStep(parallel bool, txs []*types.Transaction, statedb *state.StateDB, deleteEmpty bool) {
+	l.Steps = append(l.Steps, &OrderingStep{
+		Parallel:     parallel,
+		Transactions: txs,
+		Root:         statedb.IntermediateRoot(deleteEmpty),
+	})
+}
+
+// rlpHash encodes x and hashes the encoded bytes.
+func rlpHash(x interface{}) (h common.Hash) {
+	sha := crypto.NewKeccakState()
+	rlp.Encode(sha, x)
+	sha.Read(h[:])
+	return h
+}
+
+// writeOrderingLog persists the signed ordering log of a block being built.
+func writeOrderingLog(db ethdb.KeyValueWriter, ordering *OrderingLog) {
+	blob, err := rlp.EncodeToBytes(ordering)
+	if err != nil {
+		log.Warn("Failed to encode parallel ordering log", "number", ordering.Number, "err", err)
+		return
+	}
+	rawdb.WriteParallelOrdering(db, uint64(ordering.Number), blob)
+}
+
+// ReadOrderingLog retrieves the signed ordering log of a block, or nil if the
+// block was not built by the local sequencer.
+func ReadOrderingLog(db ethdb.KeyValueReader, header *types.Header) *OrderingLog {
+	blob := rawdb.ReadParallelOrdering(db, header.Number.Uint64())
+	if len(blob) == 0 {
+		return nil
+	}
+	ordering := new(OrderingLog)
+	if err := rlp.DecodeBytes(blob, ordering); err != nil {
+		log.Warn("Failed to decode parallel ordering log", "number", header.Number, "err", err)
+		return nil
+	}
+	// Logs are stored by number, make sure it belongs to this block and not to
+	// a competing one built on another parent
+	if ordering.ParentHash != header.ParentHash {
+		return nil
+	}
+	return ordering
+}
+
+// ReplayOrderingLog executes the steps of an ordering log serially, in the logged
+// order, on top of the state of its parent, and checks that every step yields
+// the logged state root.
+func ReplayOrderingLog(chain *core.BlockChain, ordering *OrderingLog) error {
+	parent := chain.GetHeaderByHash(ordering.ParentHash)
+	if parent == nil {
+		return errOrderingParentMissing
+	}
+	statedb, err := chain.StateAt(parent.Root)
+	if err != nil {
+		return err
+	}
+	var (
+		adapter     = core.NewPendingExecAdapter(chain.Config(), chain, parent)
+		deleteEmpty = adapter.Rules().IsEIP158
+		index       int
+	)
+	for i, step := range ordering.Steps {
+		for _, tx := range step.Transactions {
+			if _, err := adapter.Apply(statedb, tx, index, new(core.GasPool).AddGas(tx.Gas()), nil); err != nil {
+				return fmt.Errorf("step %d: transaction %#x failed: %v", i, tx.Hash(), err)
+			}
+			statedb.Finalise(deleteEmpty)
+			index++
+		}
+		if root := statedb.IntermediateRoot(deleteEmpty); root != step.Root {
+			return fmt.Errorf("step %d: root mismatch: have %#x, want %#x", i, root, step.Root)
+		}
+	}
+	return nil
+}
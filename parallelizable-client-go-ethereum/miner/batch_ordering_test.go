@@ -0,0 +1,105 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that in sequencer mode the ordering decisions of a built block are
+// persisted as a signed log, which replays to the same state, and that tampering
+// with the logged order is detected.
+func TestOrderingLog(t *testing.T) {
+	var (
+		key0, _   = crypto.GenerateKey()
+		key1, _   = crypto.GenerateKey()
+		sequencer = crypto.PubkeyToAddress(testBankKey.PublicKey)
+		signer    = types.LatestSigner(params.TestChainConfig)
+		db        = rawdb.NewMemoryDatabase()
+		recipient = common.Address{0xaa}
+		gasPrice  = big.NewInt(2 * params.InitialBaseFee)
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			crypto.PubkeyToAddress(key0.PublicKey): {Balance: big.NewInt(params.Ether)},
+			crypto.PubkeyToAddress(key1.PublicKey): {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(db, nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	txs := []*types.Transaction{
+		types.MustSignNewTx(key0, signer, &types.LegacyTx{Nonce: 0, To: &recipient, Value: common.Big1, Gas: params.TxGas, GasPrice: gasPrice}),
+		types.MustSignNewTx(key0, signer, &types.LegacyTx{Nonce: 1, To: &recipient, Value: common.Big1, Gas: params.TxGas, GasPrice: gasPrice}),
+		types.MustSignNewTx(key1, signer, &types.LegacyTx{Nonce: 0, To: &recipient, Value: common.Big1, Gas: params.TxGas, GasPrice: gasPrice}),
+	}
+	executor := &BatchExecutor{
+		chainConfig:      params.TestChainConfig,
+		chain:            chain,
+		exec:             parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		optimistic:       true,
+		sequencerKey:     testBankKey,
+		summaryDB:        db,
+		txCountGauge:     metrics.NewGauge(),
+		execTimeGauge:    metrics.NewGauge(),
+		successRateGauge: metrics.NewGauge(),
+		conflictMeter:    metrics.NewMeter(),
+	}
+	executor.processBatch(txs)
+
+	genesis := chain.CurrentBlock()
+	ordering := ReadOrderingLog(db, &types.Header{Number: big.NewInt(1), ParentHash: genesis.Hash()})
+	if ordering == nil {
+		t.Fatalf("ordering log not found")
+	}
+	if len(ordering.Steps) != 1 || !ordering.Steps[0].Parallel || len(ordering.Steps[0].Transactions) != len(txs) {
+		t.Fatalf("ordering steps mismatch: have %+v", ordering.Steps)
+	}
+	if have, err := ordering.Sequencer(); err != nil || have != sequencer {
+		t.Errorf("sequencer mismatch: have %x (%v), want %x", have, err, sequencer)
+	}
+	if err := ReplayOrderingLog(chain, ordering); err != nil {
+		t.Errorf("failed to replay ordering log: %v", err)
+	}
+	// Reordering the committed transactions must invalidate both the signature
+	// and the replay
+	step := ordering.Steps[0]
+	step.Transactions[0], step.Transactions[1] = step.Transactions[1], step.Transactions[0]
+	if have, _ := ordering.Sequencer(); have == sequencer {
+		t.Errorf("tampered ordering log still signed by the sequencer")
+	}
+	if err := ReplayOrderingLog(chain, ordering); err == nil {
+		t.Errorf("tampered ordering log replayed")
+	}
+}
@@ -0,0 +1,369 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/tracing"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// SpeculativeSource is a batch source announcing the heads it rebuilt its
+// batches for, which the executor pre-executes them on.
+type SpeculativeSource interface {
+	BatchSource
+
+	// SubscribeResets subscribes to the heads the source was reset to.
+	SubscribeResets(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// speculativeKey identifies a batch pre-executed on top of a head.
+type speculativeKey struct {
+	head  common.Hash
+	batch uint64
+}
+
+// speculativeExecution is the outcome of executing a batch member on its own
+// copy of the state, in the same shape as during block building.
+type speculativeExecution struct {
+	state  *state.StateDB
+	diff   *stateDiff
+	evm    *vm.EVM
+	result *core.ExecutionResult
+	err    error
+
+	bound bool // Whether the execution read block context the speculation could only guess
+}
+
+// speculativeBatch is a batch pre-executed as if the block being built started
+// with the given transactions. Its executions only hold for blocks that did.
+type speculativeBatch struct {
+	prefix   []common.Hash  // Transactions committed to the block before the batch
+	coinbase common.Address // Fee recipient the batch was executed for
+	header   *types.Header  // Guessed header of the block the batch was executed in
+	members  map[common.Hash]*speculativeExecution
+
+	lock sync.Mutex // Serializes access to the executed states across block builds
+}
+
+// speculativeCache holds the batches pre-executed on top of the current head,
+// keyed by the head and the batch identifier. It only ever holds the batches of
+// a single head, those of the previous one being dropped on every reset.
+type speculativeCache struct {
+	head    common.Hash
+	batches map[speculativeKey]*speculativeBatch
+	lock    sync.RWMutex
+}
+
+// newSpeculativeCache creates an empty speculative cache.
+func newSpeculativeCache() *speculativeCache {
+	return &speculativeCache{batches: make(map[speculativeKey]*speculativeBatch)}
+}
+
+// reset drops every cached batch and starts caching the batches of a new head.
+func (c *speculativeCache) reset(head common.Hash) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.head = head
+	c.batches = make(map[speculativeKey]*speculativeBatch)
+}
+
+// current reports whether the cache still caches the batches of the head.
+func (c *speculativeCache) current(head common.Hash) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.head == head
+}
+
+// add caches a pre-executed batch, unless the cache moved on to another head.
+func (c *speculativeCache) add(key speculativeKey, batch *speculativeBatch) bool {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.head != key.head {
+		return false
+	}
+	c.batches[key] = batch
+	return true
+}
+
+// get retrieves a pre-executed batch.
+func (c *speculativeCache) get(key speculativeKey) *speculativeBatch {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.batches[key]
+}
+
+// speculationTemplate is the block context of the last block built with batches,
+// which speculation expects the next blocks to share.
+type speculationTemplate struct {
+	coinbase   common.Address
+	interval   uint64 // Time elapsed between the block and its parent
+	gasLimit   uint64
+	difficulty *big.Int
+}
+
+// Speculate pre-executes the batches of the source in the background every time
+// it is reset to a new head, so that blocks built on that head can apply the
+// cached results instead of executing the batches again. The batches are run in
+// the order block building visits them, each on top of the ones before. The
+// cache is dropped on every reset of the source. Speculation stops along with
+// the executor.
+func (b *BatchExecutor) Speculate(source SpeculativeSource) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.specSub != nil {
+		b.specSub.Unsubscribe()
+	}
+	var (
+		cache = newSpeculativeCache()
+		ch    = make(chan core.ChainHeadEvent, 1)
+	)
+	b.speculative, b.specSub = cache, source.SubscribeResets(ch)
+
+	go func(sub event.Subscription) {
+		for {
+			select {
+			case ev := <-ch:
+				cache.reset(ev.Header.Hash())
+				go b.preExecute(cache, source.GetBatches(), ev.Header)
+			case <-sub.Err():
+				return
+			}
+		}
+	}(b.specSub)
+}
+
+// observe records the block context of a block being built with batches, for
+// speculation to pre-execute the next batches in.
+func (b *BatchExecutor) observe(env *environment, parent *types.Header) {
+	template := &speculationTemplate{
+		coinbase:   env.coinbase,
+		gasLimit:   env.header.GasLimit,
+		difficulty: new(big.Int),
+	}
+	if parent != nil && env.header.Time > parent.Time {
+		template.interval = env.header.Time - parent.Time
+	}
+	if env.header.Difficulty != nil {
+		template.difficulty.Set(env.header.Difficulty)
+	}
+	b.mu.Lock()
+	b.template = template
+	b.mu.Unlock()
+}
+
+// speculationHooks returns the tracing hooks recording the diff of a speculative
+// execution, flagging it as bound to the guessed block context if it read any
+// of it, and as unusable if it accessed a system contract, whose state the
+// speculation can't reproduce.
+func speculationHooks(diff *stateDiff, bound, unusable *bool) *tracing.Hooks {
+	var (
+		hooks             = diff.hooks()
+		onOpcode, onEnter = hooks.OnOpcode, hooks.OnEnter
+	)
+	hooks.OnOpcode = func(pc uint64, op byte, gas, cost uint64, scope tracing.OpContext, rData []byte, depth int, err error) {
+		switch vm.OpCode(op) {
+		case vm.COINBASE, vm.TIMESTAMP, vm.PREVRANDAO, vm.GASLIMIT, vm.BLOBBASEFEE:
+			*bound = true
+		}
+		onOpcode(pc, op, gas, cost, scope, rData, depth, err)
+	}
+	hooks.OnEnter = func(depth int, typ byte, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+		if to == params.BeaconRootsAddress || to == params.HistoryStorageAddress {
+			*unusable = true
+		}
+		onEnter(depth, typ, from, to, input, gas, value)
+	}
+	return hooks
+}
+
+// preExecute executes the batches on top of the head as block building would,
+// caching the executions of every batch. The batches are visited in dependency
+// level order and each one is merged into the state the next one executes on,
+// until a member fails or conflicts, which ends the parallel part of a block.
+// Pre-execution is abandoned once the cache moves on to another head.
+func (b *BatchExecutor) preExecute(cache *speculativeCache, batches []parallelpool.TxBatch, head *types.Header) {
+	statedb, err := b.chain.StateAt(head.Root)
+	if err != nil {
+		log.Debug("Failed to pre-execute parallel batches", "head", head.Number, "err", err)
+		return
+	}
+	// Guess the context of the next block from the last one built
+	var (
+		header   = core.NewPendingExecAdapter(b.chainConfig, b.chain, head).Header()
+		coinbase common.Address
+	)
+	b.mu.RLock()
+	if template := b.template; template != nil {
+		coinbase = template.coinbase
+		header.Time = head.Time + template.interval
+		header.GasLimit = template.gasLimit
+		header.Difficulty = new(big.Int).Set(template.difficulty)
+	}
+	b.mu.RUnlock()
+
+	var (
+		adapter = core.NewExecAdapter(b.chainConfig, b.chain, header, &coinbase)
+		prefix  []common.Hash
+	)
+	batches = slices.Clone(batches)
+	slices.SortStableFunc(batches, func(a, b parallelpool.TxBatch) int { return a.Level - b.Level })
+
+	for _, batch := range batches {
+		if !cache.current(head.Hash()) {
+			return
+		}
+		entry := &speculativeBatch{
+			prefix:   slices.Clone(prefix),
+			coinbase: coinbase,
+			header:   header,
+			members:  make(map[common.Hash]*speculativeExecution),
+		}
+		merger := newBatchMerger(statedb, coinbase)
+		for i, tx := range batch.Transactions {
+			var (
+				exec     = &speculativeExecution{state: statedb.Copy(), diff: newStateDiff()}
+				unusable bool
+			)
+			exec.err = b.workers.Guard(tx, func() error {
+				msg, err := adapter.Message(tx)
+				if err != nil {
+					return err
+				}
+				exec.state.SetTxContext(tx.Hash(), len(prefix)+i)
+				exec.evm = adapter.NewEVM(exec.state, speculationHooks(exec.diff, &exec.bound, &unusable))
+				exec.result, err = core.ApplyMessage(exec.evm, msg, new(core.GasPool).AddGas(tx.Gas()))
+				return err
+			})
+			if unusable {
+				break
+			}
+			entry.members[tx.Hash()] = exec
+			b.specTxMeter.Mark(1)
+		}
+		if len(entry.members) == 0 || !cache.add(speculativeKey{head.Hash(), batch.BatchID}, entry) {
+			return
+		}
+		// Commit the members to the state of the next batch like block building
+		// would, up to the first failure or conflict
+		if !b.commitSpeculative(entry, batch.Transactions, merger, statedb) {
+			return
+		}
+		for _, tx := range batch.Transactions {
+			prefix = append(prefix, tx.Hash())
+		}
+	}
+}
+
+// commitSpeculative merges the executions of a pre-executed batch into the state
+// the next batch is pre-executed on, reporting whether all members committed.
+// The batch is locked meanwhile, as blocks may already be built out of it.
+func (b *BatchExecutor) commitSpeculative(entry *speculativeBatch, txs []*types.Transaction, merger *batchMerger, statedb *state.StateDB) bool {
+	entry.lock.Lock()
+	defer entry.lock.Unlock()
+
+	for _, tx := range txs {
+		exec := entry.members[tx.Hash()]
+		if exec == nil || exec.err != nil || merger.conflict(exec.diff) != nil {
+			return false
+		}
+		merger.merge(tx.Hash(), exec.diff, exec.state)
+		statedb.Finalise(true)
+	}
+	return true
+}
+
+// speculated retrieves the cached executions of the members of a batch about
+// to be committed to a block, or nil if any of them was not pre-executed for
+// the block: on top of its parent, after the same transactions, for the same
+// fee recipient and fork, and with the same block context if it was read. The
+// returned batch is locked, the caller must unlock it once done.
+func (b *BatchExecutor) speculated(env *environment, id uint64, txs []*types.Transaction) (*speculativeBatch, []*speculativeExecution) {
+	b.mu.RLock()
+	cache := b.speculative
+	b.mu.RUnlock()
+
+	if cache == nil {
+		return nil, nil
+	}
+	// Executions read state out of the cache instead of the block state, which
+	// would leave the reads out of the witness
+	if env.witness != nil {
+		return nil, nil
+	}
+	entry := cache.get(speculativeKey{env.header.ParentHash, id})
+	if entry == nil || !entry.usable(env, b.chainConfig) {
+		b.specMissMeter.Mark(1)
+		return nil, nil
+	}
+	entry.lock.Lock()
+	execs := make([]*speculativeExecution, len(txs))
+	for i, tx := range txs {
+		exec := entry.members[tx.Hash()]
+		if exec == nil || (exec.bound && !entry.sameContext(env.header)) {
+			entry.lock.Unlock()
+			b.specMissMeter.Mark(1)
+			return nil, nil
+		}
+		execs[i] = exec
+	}
+	b.specHitMeter.Mark(1)
+	return entry, execs
+}
+
+// usable reports whether a pre-executed batch applies to the block being built,
+// ignoring the block context read by individual members.
+func (s *speculativeBatch) usable(env *environment, config *params.ChainConfig) bool {
+	if s.coinbase != env.coinbase || len(s.prefix) != len(env.txs) {
+		return false
+	}
+	if config.LatestFork(s.header.Time) != config.LatestFork(env.header.Time) {
+		return false
+	}
+	if (s.header.Difficulty.Sign() == 0) != (env.header.Difficulty.Sign() == 0) {
+		return false
+	}
+	for i, tx := range env.txs {
+		if tx.Hash() != s.prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameContext reports whether the block being built has the block context the
+// batch was pre-executed with.
+func (s *speculativeBatch) sameContext(header *types.Header) bool {
+	return s.header.Time == header.Time && s.header.GasLimit == header.GasLimit &&
+		s.header.MixDigest == header.MixDigest && s.header.Difficulty.Cmp(header.Difficulty) == 0
+}
@@ -0,0 +1,161 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/txpool/legacypool"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testSpeculativeSource is a batch source serving a fixed set of batches and
+// announcing the resets fed to it.
+type testSpeculativeSource struct {
+	testBatchSource
+	feed event.Feed
+}
+
+func (s *testSpeculativeSource) SubscribeResets(ch chan<- core.ChainHeadEvent) event.Subscription {
+	return s.feed.Subscribe(ch)
+}
+
+// Tests that batches are pre-executed on every reset of the source, that blocks
+// built on the head apply the cached executions, resulting in valid blocks, and
+// that blocks for another fee recipient execute the batches again.
+func TestSpeculativeBatches(t *testing.T) {
+	keys := make([]*ecdsa.PrivateKey, 3)
+	alloc := make(types.GenesisAlloc)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		alloc[crypto.PubkeyToAddress(keys[i].PublicKey)] = types.Account{Balance: big.NewInt(params.Ether)}
+	}
+	gspec := &core.Genesis{Config: params.TestChainConfig, Alloc: alloc, BaseFee: big.NewInt(params.InitialBaseFee)}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	pool, err := txpool.New(testTxPoolConfig.PriceLimit, chain, []txpool.SubPool{legacypool.New(testTxPoolConfig, chain)})
+	if err != nil {
+		t.Fatalf("failed to create pool: %v", err)
+	}
+	defer pool.Close()
+
+	signer := types.LatestSigner(params.TestChainConfig)
+	txs := make([]*types.Transaction, len(keys))
+	for i, key := range keys {
+		txs[i] = types.MustSignNewTx(key, signer, &types.LegacyTx{To: &common.Address{0xaa, byte(i)}, Value: common.Big1, Gas: params.TxGas, GasPrice: big.NewInt(2 * params.InitialBaseFee)})
+	}
+	for i, err := range pool.Add(txs, true) {
+		if err != nil {
+			t.Fatalf("failed to add tx %d: %v", i, err)
+		}
+	}
+	executor := &BatchExecutor{
+		chainConfig:       params.TestChainConfig,
+		chain:             chain,
+		exec:              parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		conflictMeter:     metrics.NewMeter(),
+		blockCeilingGauge: metrics.NewGauge(),
+		blockUsedGauge:    metrics.NewGauge(),
+		criticalPathGauge: metrics.NewGauge(),
+		overcommitMeter:   metrics.NewMeter(),
+		specTxMeter:       metrics.NewMeter(),
+		specHitMeter:      metrics.NewMeter(),
+		specMissMeter:     metrics.NewMeter(),
+		template:          &speculationTemplate{coinbase: common.Address{0x01}, interval: 1, difficulty: common.Big1},
+	}
+	source := &testSpeculativeSource{testBatchSource: testBatchSource{
+		{Transactions: txs[:2], BatchID: 1},
+		{Transactions: txs[2:], BatchID: 2, Level: 1},
+	}}
+	executor.Speculate(source)
+	defer executor.specSub.Unsubscribe()
+
+	miner := New(NewMockBackend(chain, pool), testConfig, ethash.NewFaker())
+	miner.SetParallelBatches(source, executor)
+
+	parent := chain.CurrentBlock()
+	source.feed.Send(core.ChainHeadEvent{Header: parent})
+
+	for deadline := time.Now().Add(5 * time.Second); executor.specTxMeter.Snapshot().Count() < int64(len(txs)); {
+		if time.Now().After(deadline) {
+			t.Fatalf("batches not pre-executed: have %d, want %d", executor.specTxMeter.Snapshot().Count(), len(txs))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	build := func(coinbase common.Address) *types.Block {
+		t.Helper()
+		result := miner.generateWork(&generateParams{
+			timestamp:   parent.Time + 1,
+			parentHash:  parent.Hash(),
+			coinbase:    coinbase,
+			withdrawals: types.Withdrawals{},
+		}, false)
+		if result.err != nil {
+			t.Fatalf("failed to build block: %v", result.err)
+		}
+		if have := len(result.block.Transactions()); have != len(txs) {
+			t.Fatalf("included transaction count mismatch: have %d, want %d", have, len(txs))
+		}
+		return result.block
+	}
+	// A block for the fee recipient of the speculation applies both batches
+	block := build(common.Address{0x01})
+	if have := executor.specHitMeter.Snapshot().Count(); have != 2 {
+		t.Errorf("speculation hit mismatch: have %d, want 2", have)
+	}
+	// A block for another fee recipient can't, but must be the same otherwise
+	other := build(common.Address{0x02})
+	if have := executor.specMissMeter.Snapshot().Count(); have != 2 {
+		t.Errorf("speculation miss mismatch: have %d, want 2", have)
+	}
+	if block.ReceiptHash() != other.ReceiptHash() {
+		t.Errorf("receipts mismatch between speculative and regular execution")
+	}
+	// The block must pass full validation, state root and receipts included
+	if _, err := chain.InsertChain(types.Blocks{block}); err != nil {
+		t.Fatalf("speculatively built block invalid: %v", err)
+	}
+	// A reset to the new head drops the batches cached on the old one
+	source.feed.Send(core.ChainHeadEvent{Header: block.Header()})
+	for deadline := time.Now().Add(5 * time.Second); executor.speculative.current(parent.Hash()); {
+		if time.Now().After(deadline) {
+			t.Fatalf("speculative cache not reset")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if executor.speculative.get(speculativeKey{parent.Hash(), 1}) != nil {
+		t.Errorf("batch of the old head still cached")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// BlockSummary describes how a locally built block was executed in parallel.
+// It is recorded by the batch executor while building and persisted, so that it
+// can be inspected after the block is sealed.
+type BlockSummary struct {
+	Number        hexutil.Uint64 `json:"number"`
+	ParentHash    common.Hash    `json:"parentHash"`
+	ParallelTxs   hexutil.Uint64 `json:"parallelTxs"`   // Transactions executed in parallel batches
+	SequentialTxs hexutil.Uint64 `json:"sequentialTxs"` // Transactions executed sequentially
+	Batches       hexutil.Uint64 `json:"batches"`       // Parallel batches executed
+	Aborts        hexutil.Uint64 `json:"aborts"`        // Parallel executions aborted due to failures or conflicts
+	ParallelTime  hexutil.Uint64 `json:"parallelTime"`  // Wall time spent in parallel batches, in nanoseconds
+	SerialTime    hexutil.Uint64 `json:"serialTime"`    // Summed execution time of the batch members, in nanoseconds
+
+	Inclusions []*InclusionStatus `json:"inclusions,omitempty" rlp:"optional"` // How the inclusion list entries were satisfied
+
+	Speedup float64 `json:"speedup" rlp:"-"` // Measured speedup of the batches over serial execution
+}
+
+// batchStats are the measurements of a single parallel batch execution.
+type batchStats struct {
+	used     uint64        // Gas used by the successful transactions
+	executed int           // Successfully executed transactions
+	aborted  int           // Failed or conflicting transactions
+	wall     time.Duration // Wall time of the batch
+	serial   time.Duration // Summed execution time of the members
+
+	reexecuted   int                  // Conflicting transactions successfully re-executed serially
+	reexecutions int                  // Member executions repeated after conflicts, successful or not
+	conflicts    int                  // Conflicts found between members
+	order        []*types.Transaction // Committed transactions in the order their effects were committed
+}
+
+// addBatch accounts a parallel batch execution in the summary.
+func (s *BlockSummary) addBatch(stats batchStats) {
+	s.Batches++
+	s.ParallelTxs += hexutil.Uint64(stats.executed)
+	s.SequentialTxs += hexutil.Uint64(stats.reexecuted)
+	s.Aborts += hexutil.Uint64(stats.aborted)
+	s.ParallelTime += hexutil.Uint64(stats.wall)
+	s.SerialTime += hexutil.Uint64(stats.serial)
+}
+
+// speedup returns the measured speedup of the parallel batches.
+func (s *BlockSummary) speedup() float64 {
+	if s.ParallelTime == 0 {
+		return 0
+	}
+	return float64(s.SerialTime) / float64(s.ParallelTime)
+}
+
+// writeBlockSummary persists the summary of a block being built.
+func writeBlockSummary(db ethdb.KeyValueWriter, summary *BlockSummary) {
+	blob, err := rlp.EncodeToBytes(summary)
+	if err != nil {
+		log.Warn("Failed to encode parallel block summary", "number", summary.Number, "err", err)
+		return
+	}
+	rawdb.WriteParallelSummary(db, uint64(summary.Number), blob)
+}
+
+// ReadBlockSummary retrieves the parallel execution summary of a block, or nil
+// if the block was not built locally.
+func ReadBlockSummary(db ethdb.KeyValueReader, header *types.Header) *BlockSummary {
+	blob := rawdb.ReadParallelSummary(db, header.Number.Uint64())
+	if len(blob) == 0 {
+		return nil
+	}
+	summary := new(BlockSummary)
+	if err := rlp.DecodeBytes(blob, summary); err != nil {
+		log.Warn("Failed to decode parallel block summary", "number", header.Number, "err", err)
+		return nil
+	}
+	// Summaries are stored by number, make sure it belongs to this block and
+	// not to a competing one built on another parent
+	if summary.ParentHash != header.ParentHash {
+		return nil
+	}
+	summary.Speedup = summary.speedup()
+	return summary
+}
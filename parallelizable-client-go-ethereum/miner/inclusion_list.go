@@ -0,0 +1,127 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// InclusionStatus reports how an entry of the inclusion list of a block was
+// satisfied: by a parallel batch, by the sequential tail, or not at all.
+type InclusionStatus struct {
+	Hash       common.Hash    `json:"hash"`
+	Included   bool           `json:"included"`
+	Sequential bool           `json:"sequential"` // Whether included by the sequential tail instead of a batch
+	Batch      hexutil.Uint64 `json:"batch"`      // Index of the including batch within the block, if not sequential
+}
+
+// inclusionList is a consensus-provided list of transactions that the block
+// built on top of the given parent must include.
+type inclusionList struct {
+	parent common.Hash
+	txs    []*types.Transaction
+}
+
+// SetInclusionList sets the transactions that the block built on top of the
+// given parent must include. Listed transactions are reserved block gas before
+// any other and, if a parallel batch does not commit them, retried at the head
+// of the sequential tail regardless of what they conflict with.
+func (b *BatchExecutor) SetInclusionList(parent common.Hash, txs []*types.Transaction) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.inclusion = &inclusionList{parent: parent, txs: txs}
+}
+
+// inclusionTracker records where the entries of an inclusion list were included
+// while building a block.
+type inclusionTracker struct {
+	txs      []*types.Transaction
+	statuses map[common.Hash]*InclusionStatus
+}
+
+// newInclusionTracker creates a tracker for the given inclusion list.
+func newInclusionTracker(txs []*types.Transaction) *inclusionTracker {
+	t := &inclusionTracker{statuses: make(map[common.Hash]*InclusionStatus)}
+	for _, tx := range txs {
+		if _, ok := t.statuses[tx.Hash()]; ok {
+			continue
+		}
+		t.txs = append(t.txs, tx)
+		t.statuses[tx.Hash()] = &InclusionStatus{Hash: tx.Hash()}
+	}
+	return t
+}
+
+// prepend returns the listed transactions followed by the given ones that are
+// not listed.
+func (t *inclusionTracker) prepend(txs []*types.Transaction) []*types.Transaction {
+	merged := append([]*types.Transaction{}, t.txs...)
+	for _, tx := range txs {
+		if _, ok := t.statuses[tx.Hash()]; !ok {
+			merged = append(merged, tx)
+		}
+	}
+	return merged
+}
+
+// batched marks the listed transactions among the ones committed by a parallel
+// batch as included by it.
+func (t *inclusionTracker) batched(batch uint64, txs []*types.Transaction) {
+	for _, tx := range txs {
+		if status, ok := t.statuses[tx.Hash()]; ok {
+			status.Included, status.Batch = true, hexutil.Uint64(batch)
+		}
+	}
+}
+
+// sequenced marks the listed transactions among the ones committed by the
+// sequential tail as included by it.
+func (t *inclusionTracker) sequenced(txs []*types.Transaction) {
+	for _, tx := range txs {
+		if status, ok := t.statuses[tx.Hash()]; ok {
+			status.Included, status.Sequential = true, true
+		}
+	}
+}
+
+// split separates the listed transactions not included yet from the others.
+func (t *inclusionTracker) split(txs []*types.Transaction) (missing, rest []*types.Transaction) {
+	for _, tx := range txs {
+		if status, ok := t.statuses[tx.Hash()]; ok && !status.Included {
+			missing = append(missing, tx)
+		} else {
+			rest = append(rest, tx)
+		}
+	}
+	return missing, rest
+}
+
+// report returns the inclusion statuses in list order, or nil if there was no
+// inclusion list.
+func (t *inclusionTracker) report() []*InclusionStatus {
+	if len(t.txs) == 0 {
+		return nil
+	}
+	statuses := make([]*InclusionStatus, len(t.txs))
+	for i, tx := range t.txs {
+		statuses[i] = t.statuses[tx.Hash()]
+	}
+	return statuses
+}
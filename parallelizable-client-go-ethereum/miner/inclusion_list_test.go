@@ -0,0 +1,97 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/txpool/parallelpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the entries of an inclusion list are included even if they were
+// not announced to the executor, and that the summary reports which batch or
+// the sequential tail satisfied each of them.
+func TestInclusionList(t *testing.T) {
+	var (
+		key0, _   = crypto.GenerateKey()
+		key1, _   = crypto.GenerateKey()
+		signer    = types.LatestSigner(params.TestChainConfig)
+		recipient = common.Address{0xaa}
+		gasPrice  = big.NewInt(2 * params.InitialBaseFee)
+	)
+	gspec := &core.Genesis{
+		Config: params.TestChainConfig,
+		Alloc: types.GenesisAlloc{
+			crypto.PubkeyToAddress(key0.PublicKey): {Balance: big.NewInt(params.Ether)},
+			crypto.PubkeyToAddress(key1.PublicKey): {Balance: big.NewInt(params.Ether)},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	chain, err := core.NewBlockChain(rawdb.NewMemoryDatabase(), nil, gspec, nil, ethash.NewFaker(), vm.Config{}, nil)
+	if err != nil {
+		t.Fatalf("failed to create chain: %v", err)
+	}
+	defer chain.Stop()
+
+	var (
+		parallel   = types.MustSignNewTx(key0, signer, &types.LegacyTx{Nonce: 0, To: &recipient, Gas: 50_000, GasPrice: gasPrice, Data: []byte("PARALLEL\x01")})
+		sequential = types.MustSignNewTx(key1, signer, &types.LegacyTx{Nonce: 0, To: &recipient, Gas: params.TxGas, GasPrice: gasPrice})
+		invalid    = types.MustSignNewTx(key1, signer, &types.LegacyTx{Nonce: 5, To: &recipient, Gas: params.TxGas, GasPrice: gasPrice})
+	)
+	executor := &BatchExecutor{
+		chainConfig:      params.TestChainConfig,
+		chain:            chain,
+		exec:             parallelpool.NewExecMetrics("", metrics.NewRegistry()),
+		txCountGauge:     metrics.NewGauge(),
+		execTimeGauge:    metrics.NewGauge(),
+		successRateGauge: metrics.NewGauge(),
+		conflictMeter:    metrics.NewMeter(),
+	}
+	// Lists of other parents must be ignored
+	executor.SetInclusionList(common.Hash{0x01}, []*types.Transaction{parallel})
+	executor.processBatch([]*types.Transaction{sequential})
+	if executor.summary.Inclusions != nil || executor.summary.Batches != 0 {
+		t.Fatalf("inclusion list of another parent applied: %+v", executor.summary)
+	}
+	executor.SetInclusionList(chain.CurrentBlock().Hash(), []*types.Transaction{parallel, sequential, invalid})
+	executor.processBatch([]*types.Transaction{sequential})
+
+	want := []InclusionStatus{
+		{Hash: parallel.Hash(), Included: true, Batch: 0},
+		{Hash: sequential.Hash(), Included: true, Sequential: true},
+		{Hash: invalid.Hash()},
+	}
+	have := executor.summary.Inclusions
+	if len(have) != len(want) {
+		t.Fatalf("inclusion status count mismatch: have %d, want %d", len(have), len(want))
+	}
+	for i := range want {
+		if *have[i] != want[i] {
+			t.Errorf("inclusion status %d mismatch: have %+v, want %+v", i, *have[i], want[i])
+		}
+	}
+}
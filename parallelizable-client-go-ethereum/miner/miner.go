@@ -48,6 +48,15 @@ type Config struct {
 	GasCeil             uint64         // Target gas ceiling for mined blocks.
 	GasPrice            *big.Int       // Minimum gas price for mining a transaction
 	Recommit            time.Duration  // The time interval for miner to re-create mining work.
+
+	// ParallelAuditRate is the fraction of the blocks built out of parallel
+	// batches that are re-executed sequentially to check their determinism.
+	ParallelAuditRate float64 `toml:",omitempty"`
+
+	// ParallelSequentialTail is the percentage of the block gas withheld from
+	// the parallel batches for a tail of ordinary, untagged transactions. Only
+	// as much is withheld as those pending need. Zero disables the tail.
+	ParallelSequentialTail uint64 `toml:",omitempty"`
 }
 
 // DefaultConfig contains default settings for miner.
@@ -60,6 +69,10 @@ var DefaultConfig = Config{
 	// for payload generation. It should be enough for Geth to
 	// run 3 rounds.
 	Recommit: 2 * time.Second,
+
+	// Leave up to a tenth of the block to the ordinary traffic of the legacy
+	// pool, so that batches don't crowd it out
+	ParallelSequentialTail: 10,
 }
 
 // Miner is the main object which takes care of submitting new work to consensus
@@ -71,6 +84,8 @@ type Miner struct {
 	engine      consensus.Engine
 	txpool      *txpool.TxPool
 	prio        []common.Address // A list of senders to prioritize
+	batches     BatchSource      // Source of the batches included in parallel (optional)
+	executor    *BatchExecutor   // Executor running the batches included in parallel (optional)
 	chain       *core.BlockChain
 	pending     *pending
 	pendingMu   sync.Mutex // Lock protects the pending block
@@ -117,6 +132,20 @@ func (miner *Miner) SetPrioAddresses(prio []common.Address) {
 	miner.confMu.Unlock()
 }
 
+// SetParallelBatches enables building blocks out of the batches of the given
+// source, executing the members of each batch concurrently with the executor
+// ahead of the sequential transactions. Passing a nil source disables it. A
+// source that records conflicts, like the parallel pool, is handed the ones
+// aborting the parallel execution of its transactions.
+func (miner *Miner) SetParallelBatches(source BatchSource, executor *BatchExecutor) {
+	if reporter, ok := source.(ConflictReporter); ok && executor != nil {
+		executor.SetConflictReporter(reporter)
+	}
+	miner.confMu.Lock()
+	miner.batches, miner.executor = source, executor
+	miner.confMu.Unlock()
+}
+
 // SetGasCeil sets the gaslimit to strive for when mining blocks post 1559.
 // For pre-1559 blocks, it sets the ceiling.
 func (miner *Miner) SetGasCeil(ceil uint64) {
@@ -0,0 +1,68 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// sequentialTail returns the block gas withheld from the parallel batches for a
+// sequential tail of ordinary, untagged transactions: the gas of the pending
+// transactions outside the parallel pool, capped at the given percentage of the
+// block gas limit. Sizing the tail by the pending composition leaves the whole
+// block to the batches when there is no ordinary traffic to make room for.
+func sequentialTail(env *environment, pending map[common.Address][]*txpool.LazyTransaction, percent uint64) uint64 {
+	if percent == 0 {
+		return 0
+	}
+	limit := env.header.GasLimit * min(percent, 100) / 100
+
+	var demand uint64
+	for _, txs := range pending {
+		// Accounts are exclusive to a subpool, the first transaction tells
+		// whether they are parallel pool ones
+		if len(txs) == 0 || parallelLazy(txs[0]) {
+			continue
+		}
+		for _, ltx := range txs {
+			if demand += ltx.Gas; demand >= limit {
+				return limit
+			}
+		}
+	}
+	return demand
+}
+
+// splitParallel moves the accounts of the parallel pool transactions out of the
+// pending set, returning them separately.
+func splitParallel(pending map[common.Address][]*txpool.LazyTransaction) map[common.Address][]*txpool.LazyTransaction {
+	parallel := make(map[common.Address][]*txpool.LazyTransaction)
+	for addr, txs := range pending {
+		if len(txs) > 0 && parallelLazy(txs[0]) {
+			parallel[addr] = txs
+			delete(pending, addr)
+		}
+	}
+	return parallel
+}
+
+// parallelLazy reports whether a pending transaction is a parallel one.
+func parallelLazy(ltx *txpool.LazyTransaction) bool {
+	return ltx.Tx != nil && ltx.Tx.Type() == types.ParallelTxType
+}
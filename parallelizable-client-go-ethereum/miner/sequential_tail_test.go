@@ -0,0 +1,72 @@
+// Copyright 2024 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/txpool"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// Tests that the sequential tail is sized by the pending ordinary transactions,
+// up to its share of the block, and that the leftover parallel transactions are
+// split from the ordinary ones.
+func TestSequentialTail(t *testing.T) {
+	lazy := func(inner types.TxData, gas uint64) *txpool.LazyTransaction {
+		tx := types.NewTx(inner)
+		return &txpool.LazyTransaction{Hash: tx.Hash(), Tx: tx, Gas: gas}
+	}
+	var (
+		ordinary = common.Address{0x01}
+		parallel = common.Address{0x02}
+	)
+	pending := map[common.Address][]*txpool.LazyTransaction{
+		ordinary: {
+			lazy(&types.LegacyTx{Nonce: 0, Gas: params.TxGas}, params.TxGas),
+			lazy(&types.LegacyTx{Nonce: 1, Gas: params.TxGas}, params.TxGas),
+		},
+		parallel: {
+			lazy(&types.ParallelTx{Nonce: 0, Gas: 500_000}, 500_000),
+		},
+	}
+	env := &environment{header: &types.Header{GasLimit: 1_000_000}}
+
+	tests := []struct {
+		percent uint64
+		want    uint64
+	}{
+		{0, 0},                  // Disabled
+		{10, 2 * params.TxGas},  // Sized by the pending ordinary transactions
+		{3, 30_000},             // Capped at the share of the block
+		{200, 2 * params.TxGas}, // Share capped at the whole block
+	}
+	for i, tt := range tests {
+		if have := sequentialTail(env, pending, tt.percent); have != tt.want {
+			t.Errorf("test %d: tail mismatch: have %d, want %d", i, have, tt.want)
+		}
+	}
+	leftover := splitParallel(pending)
+	if len(leftover) != 1 || len(leftover[parallel]) != 1 {
+		t.Errorf("parallel transactions not split: %v", leftover)
+	}
+	if len(pending) != 1 || len(pending[ordinary]) != 2 {
+		t.Errorf("ordinary transactions not kept: %v", pending)
+	}
+}
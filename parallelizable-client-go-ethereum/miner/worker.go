@@ -20,6 +20,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"slices"
 	"sync/atomic"
 	"time"
 
@@ -59,7 +60,8 @@ type environment struct {
 	sidecars []*types.BlobTxSidecar
 	blobs    int
 
-	witness *stateless.Witness
+	witness  *stateless.Witness
+	batchGas []batchGasReport // Gas accounting of the parallel batches included
 }
 
 const (
@@ -140,6 +142,19 @@ func (miner *Miner) generateWork(params *generateParams, witness bool) *newPaylo
 	if err != nil {
 		return &newPayloadResult{err: err}
 	}
+	if len(work.batchGas) > 0 {
+		miner.confMu.RLock()
+		executor := miner.executor
+		miner.confMu.RUnlock()
+
+		reportBatchGas(block, work.batchGas, executor)
+
+		// Cross-check a sample of the blocks against sequential execution,
+		// without holding up their delivery
+		if executor != nil && executor.sampleAudit() {
+			go miner.auditBlock(block, executor)
+		}
+	}
 	return &newPayloadResult{
 		block:    block,
 		fees:     totalFees(block, work.receipts),
@@ -428,6 +443,8 @@ func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment)
 	miner.confMu.RLock()
 	tip := miner.config.GasPrice
 	prio := miner.prio
+	batches, executor := miner.batches, miner.executor
+	tailPercent := miner.config.ParallelSequentialTail
 	miner.confMu.RUnlock()
 
 	// Retrieve the pending transactions pre-filtered by the 1559/4844 dynamic fees
@@ -446,6 +463,36 @@ func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment)
 	filter.OnlyPlainTxs, filter.OnlyBlobTxs = false, true
 	pendingBlobTxs := miner.txpool.Pending(filter)
 
+	// Include the parallel batches first, leaving whatever they did not include
+	// to the sequential passes. Block gas is withheld from the batches for a
+	// sequential tail of ordinary transactions, as far as any are pending.
+	var tail uint64
+	if batches != nil && executor != nil {
+		if env.gasPool == nil {
+			env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
+		}
+		tail = min(sequentialTail(env, pendingPlainTxs, tailPercent), env.gasPool.Gas())
+		env.gasPool.SubGas(tail)
+		included, err := miner.commitBatches(env, batches, executor, pendingPlainTxs, interrupt)
+		env.gasPool.AddGas(tail)
+		if err != nil {
+			return err
+		}
+		if len(included) > 0 {
+			for addr, txs := range pendingPlainTxs {
+				txs = slices.DeleteFunc(txs, func(ltx *txpool.LazyTransaction) bool {
+					_, ok := included[ltx.Hash]
+					return ok
+				})
+				if len(txs) == 0 {
+					delete(pendingPlainTxs, addr)
+				} else {
+					pendingPlainTxs[addr] = txs
+				}
+			}
+		}
+	}
+
 	// Split the pending transactions into locals and remotes.
 	prioPlainTxs, normalPlainTxs := make(map[common.Address][]*txpool.LazyTransaction), pendingPlainTxs
 	prioBlobTxs, normalBlobTxs := make(map[common.Address][]*txpool.LazyTransaction), pendingBlobTxs
@@ -469,6 +516,12 @@ func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment)
 			return err
 		}
 	}
+	// Fill the tail with the ordinary transactions before the parallel ones the
+	// batches left over
+	var leftoverTxs map[common.Address][]*txpool.LazyTransaction
+	if tail > 0 {
+		leftoverTxs = splitParallel(normalPlainTxs)
+	}
 	if len(normalPlainTxs) > 0 || len(normalBlobTxs) > 0 {
 		plainTxs := newTransactionsByPriceAndNonce(env.signer, normalPlainTxs, env.header.BaseFee)
 		blobTxs := newTransactionsByPriceAndNonce(env.signer, normalBlobTxs, env.header.BaseFee)
@@ -477,6 +530,14 @@ func (miner *Miner) fillTransactions(interrupt *atomic.Int32, env *environment)
 			return err
 		}
 	}
+	if len(leftoverTxs) > 0 {
+		plainTxs := newTransactionsByPriceAndNonce(env.signer, leftoverTxs, env.header.BaseFee)
+		blobTxs := newTransactionsByPriceAndNonce(env.signer, nil, env.header.BaseFee)
+
+		if err := miner.commitTransactions(env, plainTxs, blobTxs, interrupt); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
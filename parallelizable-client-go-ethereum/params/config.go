@@ -377,6 +377,11 @@ type ChainConfig struct {
 	OsakaTime    *uint64 `json:"osakaTime,omitempty"`    // Osaka switch time (nil = no fork, 0 = already on osaka)
 	VerkleTime   *uint64 `json:"verkleTime,omitempty"`   // Verkle switch time (nil = no fork, 0 = already on verkle)
 
+	// ParallelTxTime schedules the activation of parallel transactions. It is
+	// not part of the Ethereum fork sequence and may be set independently, so
+	// private networks and testnets can enable parallel execution at any time.
+	ParallelTxTime *uint64 `json:"parallelTxTime,omitempty"` // Parallel transaction switch time (nil = disabled, 0 = already enabled)
+
 	// TerminalTotalDifficulty is the amount of total difficulty reached by
 	// the network that triggers the consensus upgrade.
 	TerminalTotalDifficulty *big.Int `json:"terminalTotalDifficulty,omitempty"`
@@ -496,6 +501,11 @@ func (c *ChainConfig) Description() string {
 	if c.VerkleTime != nil {
 		banner += fmt.Sprintf(" - Verkle:                      @%-10v\n", *c.VerkleTime)
 	}
+	if c.ParallelTxTime != nil {
+		banner += "\n"
+		banner += "Parallel transactions (timestamp based):\n"
+		banner += fmt.Sprintf(" - Activation:                  @%-10v\n", *c.ParallelTxTime)
+	}
 	return banner
 }
 
@@ -619,6 +629,12 @@ func (c *ChainConfig) IsVerkle(num *big.Int, time uint64) bool {
 	return c.IsLondon(num) && isTimestampForked(c.VerkleTime, time)
 }
 
+// IsParallelTx returns whether time is either equal to the parallel transaction
+// activation time or greater. The activation is scheduled by time only.
+func (c *ChainConfig) IsParallelTx(time uint64) bool {
+	return isTimestampForked(c.ParallelTxTime, time)
+}
+
 // IsVerkleGenesis checks whether the verkle fork is activated at the genesis block.
 //
 // Verkle mode is considered enabled if the verkle fork time is configured,
@@ -843,6 +859,9 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkTimestampIncompatible(c.VerkleTime, newcfg.VerkleTime, headTimestamp) {
 		return newTimestampCompatError("Verkle fork timestamp", c.VerkleTime, newcfg.VerkleTime)
 	}
+	if isForkTimestampIncompatible(c.ParallelTxTime, newcfg.ParallelTxTime, headTimestamp) {
+		return newTimestampCompatError("Parallel transaction timestamp", c.ParallelTxTime, newcfg.ParallelTxTime)
+	}
 	return nil
 }
 
@@ -1017,6 +1036,7 @@ type Rules struct {
 	IsBerlin, IsLondon                                      bool
 	IsMerge, IsShanghai, IsCancun, IsPrague, IsOsaka        bool
 	IsVerkle                                                bool
+	IsParallelTx                                            bool
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -1048,5 +1068,6 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool, timestamp uint64) Rules
 		IsOsaka:          isMerge && c.IsOsaka(num, timestamp),
 		IsVerkle:         isVerkle,
 		IsEIP4762:        isVerkle,
+		IsParallelTx:     c.IsParallelTx(timestamp),
 	}
 }
@@ -110,6 +110,17 @@ func TestCheckCompatible(t *testing.T) {
 				RewindToTime: 9,
 			},
 		},
+		{
+			stored:        &ChainConfig{},
+			new:           &ChainConfig{ParallelTxTime: newUint64(20)},
+			headTimestamp: 25,
+			wantErr: &ConfigCompatError{
+				What:         "Parallel transaction timestamp",
+				StoredTime:   nil,
+				NewTime:      newUint64(20),
+				RewindToTime: 19,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -139,6 +150,19 @@ func TestConfigRules(t *testing.T) {
 	}
 }
 
+func TestParallelTxRules(t *testing.T) {
+	c := &ChainConfig{ParallelTxTime: newUint64(500)}
+	if r := c.Rules(big.NewInt(0), false, 499); r.IsParallelTx {
+		t.Errorf("expected parallel transactions to be inactive before activation")
+	}
+	if r := c.Rules(big.NewInt(0), false, 500); !r.IsParallelTx {
+		t.Errorf("expected parallel transactions to be active at activation")
+	}
+	if new(ChainConfig).IsParallelTx(math.MaxInt64) {
+		t.Errorf("expected parallel transactions to be disabled when unscheduled")
+	}
+}
+
 func TestTimestampCompatError(t *testing.T) {
 	require.Equal(t, new(ConfigCompatError).Error(), "")
 